@@ -0,0 +1,937 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/kostyay/claude-status/internal/bar"
+	"github.com/kostyay/claude-status/internal/cache"
+	"github.com/kostyay/claude-status/internal/ci"
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/doctor"
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/github"
+	"github.com/kostyay/claude-status/internal/install"
+	"github.com/kostyay/claude-status/internal/mirror"
+	"github.com/kostyay/claude-status/internal/sessions"
+	"github.com/kostyay/claude-status/internal/state"
+	"github.com/kostyay/claude-status/internal/status"
+	"github.com/kostyay/claude-status/internal/tasks"
+	"github.com/kostyay/claude-status/internal/telemetry"
+	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/theme"
+	"github.com/kostyay/claude-status/internal/tokens"
+)
+
+// runSubcommand dispatches positional subcommands like "ci rerun".
+func runSubcommand(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "bar":
+		return runBar(ctx, args[1:], os.Stdout)
+	case "ci":
+		return runCI(ctx, args[1:])
+	case "config":
+		return runConfig(args[1:], os.Stdout)
+	case "hook":
+		return runHook(ctx, os.Stdin)
+	case "prefetch":
+		return runPrefetch(ctx, args[1:], os.Stdin)
+	case "daemon":
+		return runDaemon(ctx)
+	case "doctor":
+		return runDoctor(ctx, args[1:], os.Stdout)
+	case "dump":
+		return runDump(ctx, args[1:], os.Stdout)
+	case "perf":
+		return runPerf(args[1:], os.Stdout)
+	case "preview":
+		return runPreview(args[1:], os.Stdout)
+	case "sessions":
+		return runSessions(args[1:], os.Stdout, time.Now())
+	case "template":
+		return runTemplate(args[1:], os.Stdout)
+	case "theme":
+		return runTheme(args[1:], os.Stdout)
+	case "usage":
+		return runUsage(args[1:], os.Stdout)
+	case "wip":
+		return runWIP(ctx, args[1:], os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runCI handles "ci <action>" for the repository in the current directory.
+func runCI(ctx context.Context, args []string) error {
+	if len(args) != 1 || args[0] != "rerun" {
+		return errors.New("usage: claude-status ci rerun")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	cfg := config.Load(cwd)
+
+	owner, repo, branch, err := currentGitHubRepo(ctx, cwd, cfg.GitHubHost)
+	if err != nil {
+		return err
+	}
+
+	gh, err := github.NewClientForHost(ctx, cfg.GitHubWorkflow, cfg.CABundle, cfg.GitHubHost, cfg.GitHubAPIURL, cfg.GitHubTokenCommand)
+	if err != nil {
+		return fmt.Errorf("failed to create GitHub client: %w", err)
+	}
+	gh.SetPathFilter(cfg.GitHubPathFilter)
+
+	return ci.Rerun(ctx, os.Stdout, os.Stdin, gh, owner, repo, branch)
+}
+
+// runConfig handles "config get KEY", "config set KEY VALUE", and "config
+// list" against the user config file, so settings can be changed without
+// hand-editing JSON.
+func runConfig(args []string, w io.Writer) error {
+	usage := errors.New("usage: claude-status config get KEY | set KEY VALUE | list")
+	if len(args) == 0 {
+		return usage
+	}
+	path := config.ConfigPath()
+	switch {
+	case args[0] == "get" && len(args) == 2:
+		value, err := config.Get(path, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, value)
+	case args[0] == "set" && len(args) == 3:
+		if err := config.Set(path, args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Set %s in %s\n", args[1], path)
+	case args[0] == "list" && len(args) == 1:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, s := range config.List(path) {
+			fmt.Fprintf(tw, "%s\t%s\n", s.Key, strings.ReplaceAll(s.Value, "\n", `\n`))
+		}
+		return tw.Flush()
+	default:
+		return usage
+	}
+	return nil
+}
+
+// runDoctor handles "doctor": it checks everything the status line depends
+// on in the current directory and prints what to fix.
+func runDoctor(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	transcript := fs.String("transcript", "", "Transcript to check (default: newest for the current directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	env := doctor.DefaultEnv(cwd)
+	env.TranscriptPath = *transcript
+	return doctor.Run(ctx, w, env)
+}
+
+// debugDump is what "dump" prints: everything that decides what the status
+// line shows for a directory, in one JSON document for bug reports.
+type debugDump struct {
+	Dir       string              `json:"dir"`
+	Files     dumpFiles           `json:"files"`
+	Config    config.Config       `json:"config"`
+	Providers dumpProviders       `json:"providers"`
+	Status    template.StatusData `json:"status"`
+}
+
+// dumpFiles lists the files claude-status reads and writes.
+type dumpFiles struct {
+	Config        string `json:"config"`
+	ProjectConfig string `json:"project_config,omitempty"`
+	Cache         string `json:"cache"`
+	State         string `json:"state"`
+	Log           string `json:"log"`
+	Socket        string `json:"socket"`
+	Settings      string `json:"settings"`
+}
+
+// dumpProviders names the sources chosen for the directory; empty means none.
+type dumpProviders struct {
+	GitDir     string `json:"git_dir"`
+	GitHubRepo string `json:"github_repo"`
+	Tasks      string `json:"tasks"`
+}
+
+// runDump handles "dump": it prints the resolved config, file locations,
+// chosen providers, and fully populated status data for a directory.
+func runDump(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Directory to dump (default: the current directory)")
+	transcript := fs.String("transcript", "", "Transcript to read token metrics from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	workDir := *dir
+	if workDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		workDir = cwd
+	}
+
+	cfg := config.Load(workDir)
+	builder, err := status.NewBuilder(ctx, &cfg, workDir)
+	if err != nil {
+		return fmt.Errorf("failed to create builder: %w", err)
+	}
+
+	dump := debugDump{
+		Dir: workDir,
+		Files: dumpFiles{
+			Config:        config.ConfigPath(),
+			ProjectConfig: config.FindProjectConfig(workDir),
+			Cache:         config.CachePath(),
+			State:         config.StatePath(),
+			Log:           statusLogPath(cfg),
+			Socket:        config.SocketPath(),
+			Settings:      install.GetSettingsPath(),
+		},
+		Config: cfg,
+		Providers: dumpProviders{
+			GitDir: builder.GitDir(),
+		},
+		Status: builder.Build(ctx, status.Input{
+			Workspace:      status.WorkspaceInfo{CurrentDir: workDir},
+			TranscriptPath: *transcript,
+		}),
+	}
+	if dump.Providers.GitDir != "" {
+		if owner, repo, _, err := currentGitHubRepo(ctx, workDir, cfg.GitHubHost); err == nil {
+			dump.Providers.GitHubRepo = owner + "/" + repo
+		}
+	}
+	if provider := tasks.FindProvider(workDir, ""); provider != nil {
+		dump.Providers.Tasks = provider.Name()
+	}
+
+	out, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dump: %w", err)
+	}
+	fmt.Fprintln(w, string(out))
+	return nil
+}
+
+// runBar handles "bar": it prints the status of a directory as one JSON
+// object for desktop bars (SketchyBar, Übersicht), summarized as an icon,
+// a label, and a state color. Context usage comes from the directory's
+// newest session transcript.
+func runBar(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("bar", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Directory to report on (default: the current directory)")
+	transcript := fs.String("transcript", "", "Transcript to read context usage from (default: newest for the directory)")
+	label := fs.String("label", bar.DefaultLabel, "Template for the label; colors are stripped")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	workDir := *dir
+	if workDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		workDir = cwd
+	}
+	transcriptPath := *transcript
+	if transcriptPath == "" {
+		transcriptPath = tokens.NewestTranscript(filepath.Dir(install.GetSettingsPath()), workDir)
+	}
+
+	cfg := config.Load(workDir)
+	engine, err := template.Compile(*label, cfg.IconSymbols(), nil)
+	if err != nil {
+		return fmt.Errorf("invalid label template: %w", err)
+	}
+	builder, err := status.NewBuilder(ctx, &cfg, workDir)
+	if err != nil {
+		return fmt.Errorf("failed to create builder: %w", err)
+	}
+
+	data := builder.Build(ctx, status.Input{
+		Workspace:      status.WorkspaceInfo{CurrentDir: workDir},
+		TranscriptPath: transcriptPath,
+	})
+	text, err := engine.Render(data)
+	if err != nil {
+		return fmt.Errorf("failed to render label: %w", err)
+	}
+
+	out, err := json.Marshal(bar.New(data, mirror.StripANSI(text), cfg.IconSymbols()))
+	if err != nil {
+		return fmt.Errorf("failed to encode bar item: %w", err)
+	}
+	fmt.Fprintln(w, string(out))
+	return nil
+}
+
+// runPerf handles "perf": it prints the render and segment latency recorded
+// while telemetry is enabled, or with -reset clears it.
+func runPerf(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("perf", flag.ContinueOnError)
+	reset := fs.Bool("reset", false, "Delete the recorded telemetry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store := telemetry.NewStore(config.TelemetryPath())
+	if *reset {
+		if err := store.Reset(); err != nil {
+			return fmt.Errorf("failed to reset telemetry: %w", err)
+		}
+		fmt.Fprintln(w, "Telemetry cleared.")
+		return nil
+	}
+
+	telemetry.Report(w, store.Load())
+	if cwd, err := os.Getwd(); err == nil && !config.Load(cwd).Telemetry {
+		fmt.Fprintln(w, "\nTelemetry is off; set \"telemetry\": true in "+config.ConfigPath()+" to record renders.")
+	}
+	return nil
+}
+
+// hookInput is the part of the Claude Code hook payload runHook uses.
+type hookInput struct {
+	HookEventName  string `json:"hook_event_name"`
+	Cwd            string `json:"cwd"`
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+}
+
+// runPreview renders the configured template, or one passed with -template
+// or -template-file, against sample data. Unlike the status line itself it
+// reports template errors instead of falling back to the default template.
+func runPreview(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	tmpl := fs.String("template", "", "Template to render (default: the configured template)")
+	tmplFile := fs.String("template-file", "", "File to read the template to render from")
+	themeName := fs.String("theme", "", "Color theme to render with: dark, light, or auto (default: the configured theme)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Preview with the current project's config, if it has one
+	cwd, _ := os.Getwd()
+	cfg := config.Load(cwd)
+	if *formatFlag != "" {
+		cfg.OutputFormat = *formatFlag
+	}
+	switch {
+	case *tmplFile != "":
+		content, err := os.ReadFile(*tmplFile)
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		// Editors end files with a newline the status line shouldn't get
+		cfg.Template = strings.TrimSuffix(string(content), "\n")
+	case *tmpl != "":
+		cfg.Template = *tmpl
+	}
+	if *themeName != "" {
+		cfg.Theme = *themeName
+	}
+
+	if cfg.OutputFormat != config.FormatJSON {
+		if _, err := template.Compile(cfg.Template, cfg.IconSymbols(), nil); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+
+	data := template.SampleData()
+	if *prefixFlag != "" {
+		data.Prefix = *prefixFlag
+		data.PrefixColor = prefixColorCode(*prefixColorFlag)
+	}
+
+	output, err := render(cfg, data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, output)
+	return nil
+}
+
+// errTemplateTestsFailed is returned by "template test" when a fixture
+// didn't render to its expected output.
+var errTemplateTestsFailed = errors.New("template tests failed")
+
+// runTemplate handles "template test": it renders the configured template
+// against each fixture in the template test directory and reports the ones
+// whose output differs from what is expected.
+func runTemplate(args []string, w io.Writer) error {
+	if len(args) == 0 || args[0] != "test" {
+		return errors.New("usage: claude-status template test [-dir DIR] [-update]")
+	}
+	fs := flag.NewFlagSet("template test", flag.ContinueOnError)
+	dir := fs.String("dir", config.TemplateTestDir(), "Directory of NAME.json fixtures and NAME.expected outputs")
+	update := fs.Bool("update", false, "Write each fixture's expected output from what it renders now")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cwd, _ := os.Getwd()
+	cfg := config.Load(cwd)
+	// Fixtures must render the same at any time of day, so "auto" renders dark
+	engine, err := template.Compile(cfg.Template, cfg.IconSymbols(), template.Palettes[cfg.Theme])
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	results, err := template.RunFixtures(engine, *dir, *update)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %v\n", r.Name, r.Err)
+		case !r.Passed():
+			failed++
+			fmt.Fprintf(w, "FAIL %s\n    want: %q\n    got:  %q\n", r.Name, r.Want, r.Got)
+		case *update:
+			fmt.Fprintf(w, "updated %s\n", r.Name)
+		default:
+			fmt.Fprintf(w, "ok   %s\n", r.Name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w (%d of %d)", errTemplateTestsFailed, failed, len(results))
+	}
+	if !*update {
+		fmt.Fprintf(w, "\nAll %d template tests passed.\n", len(results))
+	}
+	return nil
+}
+
+// runTheme handles "theme": it prints the color theme the status line
+// renders with and why. "theme detect" asks the terminal for its background
+// with an OSC 11 query and saves it for the "auto" theme; run it in the
+// terminal Claude Code runs in, outside of Claude Code.
+func runTheme(args []string, w io.Writer) error {
+	if len(args) > 0 && args[0] == "detect" {
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			return fmt.Errorf("no terminal to query: %w", err)
+		}
+		defer tty.Close()
+
+		background, err := theme.Query(tty, 500*time.Millisecond)
+		if err != nil {
+			return fmt.Errorf("failed to detect the terminal background: %w", err)
+		}
+		if err := theme.Save(config.BackgroundPath(), background); err != nil {
+			return fmt.Errorf("failed to save the terminal background: %w", err)
+		}
+		fmt.Fprintf(w, "Detected a %s background; saved to %s\n", background, config.BackgroundPath())
+		return nil
+	}
+	if len(args) > 0 {
+		return errors.New("usage: claude-status theme [detect]")
+	}
+
+	cwd, _ := os.Getwd()
+	cfg := config.Load(cwd)
+	configured := cfg.Theme
+	if configured == "" {
+		configured = template.ThemeDark
+	}
+	resolved, source := theme.Resolve(cfg.Theme, cfg.ThemeSchedule, theme.DefaultEnv(config.BackgroundPath()))
+	fmt.Fprintf(w, "theme: %s\nrenders: %s (from %s)\n", configured, resolved, source)
+	return nil
+}
+
+// nearLimitPct is the usable context percentage from which `usage context`
+// counts a turn as near the compaction limit.
+const nearLimitPct = 80
+
+// runUsage handles "usage", which reports on what the status line recorded
+// about each project's sessions.
+func runUsage(args []string, w io.Writer) error {
+	usage := errors.New("usage: claude-status usage context [-reset] | cost [-since 7d]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "context":
+		return runUsageContext(args[1:], w)
+	case "cost":
+		return runUsageCost(args[1:], w, time.Now())
+	default:
+		return usage
+	}
+}
+
+// runUsageContext handles "usage context": it prints how full the usable
+// context ran per project, projects most often near the compaction limit
+// first.
+func runUsageContext(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("usage context", flag.ContinueOnError)
+	reset := fs.Bool("reset", false, "Delete the recorded context usage")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store := state.NewStore(config.StatePath())
+	if *reset {
+		store.ResetContext()
+		fmt.Fprintln(w, "Context usage cleared.")
+		return nil
+	}
+
+	usage := store.ContextUsage()
+	if len(usage) == 0 {
+		fmt.Fprintln(w, "No context usage recorded yet.")
+		return nil
+	}
+	projects := slices.Collect(maps.Keys(usage))
+	slices.SortFunc(projects, func(a, b string) int {
+		return cmp.Or(
+			cmp.Compare(usage[b].ShareAbove(nearLimitPct), usage[a].ShareAbove(nearLimitPct)),
+			cmp.Compare(usage[b].Turns, usage[a].Turns),
+			strings.Compare(a, b),
+		)
+	})
+
+	fmt.Fprintf(w, "Usable context per turn; turns at %d%% or more are near compaction.\n\n", nearLimitPct)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "project\tturns\tp50\tp90\tpeak\tnear limit\t0-100%")
+	for _, project := range projects {
+		u := usage[project]
+		fmt.Fprintf(tw, "%s\t%d\t≤%.0f%%\t≤%.0f%%\t%.0f%%\t%.0f%%\t%s\n", project, u.Turns,
+			u.Percentile(50), u.Percentile(90), u.Peak, 100*u.ShareAbove(nearLimitPct), bucketBars(u.Buckets[:]))
+	}
+	return tw.Flush()
+}
+
+// runUsageCost handles "usage cost": it prints the estimated cost of each
+// model in each project from the day -since ago through now, costliest first.
+func runUsageCost(args []string, w io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("usage cost", flag.ContinueOnError)
+	sinceFlag := fs.String("since", "7d", "How far back to report, in days (7d) or as a duration (12h)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	window, err := parseSince("since", *sinceFlag)
+	if err != nil {
+		return err
+	}
+	since := now.Add(-window)
+
+	type key struct{ model, project string }
+	totals := make(map[key]state.CostUsage)
+	for _, c := range state.NewStore(config.StatePath()).Costs(since) {
+		k := key{c.Model, c.Project}
+		t := totals[k]
+		t.Tokens += c.Tokens
+		t.CostUSD += c.CostUSD
+		totals[k] = t
+	}
+	if len(totals) == 0 {
+		fmt.Fprintf(w, "No usage recorded since %s.\n", since.Format(time.DateOnly))
+		return nil
+	}
+	keys := slices.Collect(maps.Keys(totals))
+	slices.SortFunc(keys, func(a, b key) int {
+		return cmp.Or(
+			cmp.Compare(totals[b].CostUSD, totals[a].CostUSD),
+			cmp.Compare(totals[b].Tokens, totals[a].Tokens),
+			strings.Compare(a.model, b.model),
+			strings.Compare(a.project, b.project),
+		)
+	})
+
+	fmt.Fprintf(w, "Estimated cost at API list prices since %s\n\n", since.Format(time.DateOnly))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "model\tproject\ttokens\tcost")
+	var total state.CostUsage
+	for _, k := range keys {
+		t := totals[k]
+		total.Tokens += t.Tokens
+		total.CostUSD += t.CostUSD
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", cmp.Or(k.model, "unknown"), k.project, tokens.FormatTokens(t.Tokens), tokens.FormatCost(t.CostUSD))
+	}
+	fmt.Fprintf(tw, "total\t\t%s\t%s\n", tokens.FormatTokens(total.Tokens), tokens.FormatCost(total.CostUSD))
+	return tw.Flush()
+}
+
+// parseSince parses the window given to flag name: a number of days like
+// "7d", or a Go duration like "12h".
+func parseSince(name, s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid -%s %q: want days like 7d or a duration like 12h", name, s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid -%s %q: want days like 7d or a duration like 12h", name, s)
+	}
+	return d, nil
+}
+
+// bucketBars draws counts as bars scaled to the largest, leaving empty
+// buckets blank: [1 0 4 2] -> "▂ █▄".
+func bucketBars(counts []int64) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	top := slices.Max(counts)
+	var b strings.Builder
+	for _, n := range counts {
+		if n == 0 || top == 0 {
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteRune(levels[(n*int64(len(levels))-1)/top])
+	}
+	return b.String()
+}
+
+// runSessions handles "sessions gc": it reports the disk taken by each
+// project's transcripts under ~/.claude/projects and, with -archive or
+// -delete, removes the sessions idle for -older-than. Without either it only
+// reports what would be removed.
+func runSessions(args []string, w io.Writer, now time.Time) error {
+	if len(args) == 0 || args[0] != "gc" {
+		return errors.New("usage: claude-status sessions gc [-older-than 30d] [-orphaned] [-archive DIR | -delete]")
+	}
+	fs := flag.NewFlagSet("sessions gc", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "30d", "Remove sessions idle this long, in days (30d) or as a duration (12h)")
+	orphaned := fs.Bool("orphaned", false, "Only remove sessions of projects whose directory no longer exists")
+	archiveDir := fs.String("archive", "", "Move old sessions to this directory as .tar.gz files")
+	del := fs.Bool("delete", false, "Delete old sessions")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *archiveDir != "" && *del {
+		return errors.New("-archive and -delete can't be used together")
+	}
+	window, err := parseSince("older-than", *olderThan)
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-window)
+
+	claudeDir := filepath.Dir(install.GetSettingsPath())
+	projects, err := sessions.Scan(claudeDir)
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		fmt.Fprintf(w, "No transcripts in %s.\n", filepath.Join(claudeDir, "projects"))
+		return nil
+	}
+
+	type candidate struct {
+		project sessions.Project
+		session sessions.Session
+	}
+	var old []candidate
+	var oldSize, total int64
+
+	fmt.Fprintf(w, "Transcripts in %s; sessions idle %s or more are old.\n\n", filepath.Join(claudeDir, "projects"), *olderThan)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "project\tsessions\tsize\tlast active\told")
+	for _, p := range projects {
+		var n int
+		var size int64
+		for _, s := range p.Sessions {
+			if s.ModTime.Before(cutoff) && (!*orphaned || p.Missing) {
+				old = append(old, candidate{p, s})
+				n++
+				size += s.Size
+			}
+		}
+		oldSize += size
+		total += p.Size()
+
+		name := p.Name()
+		if p.Missing {
+			name += " (missing)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d (%s)\n", name, len(p.Sessions), sessions.FormatBytes(p.Size()),
+			p.LastActive().Format(time.DateOnly), n, sessions.FormatBytes(size))
+	}
+	fmt.Fprintf(tw, "total\t\t%s\t\t%d (%s)\n", sessions.FormatBytes(total), len(old), sessions.FormatBytes(oldSize))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	switch {
+	case len(old) == 0:
+		fmt.Fprintln(w, "Nothing to remove.")
+	case *del:
+		for _, c := range old {
+			if err := sessions.Delete(c.session); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(w, "Deleted %d sessions (%s).\n", len(old), sessions.FormatBytes(oldSize))
+	case *archiveDir != "":
+		for _, c := range old {
+			if _, err := sessions.Archive(c.project, c.session, *archiveDir); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(w, "Archived %d sessions (%s) to %s.\n", len(old), sessions.FormatBytes(oldSize), *archiveDir)
+	default:
+		fmt.Fprintf(w, "Rerun with -archive DIR or -delete to remove %d sessions (%s).\n", len(old), sessions.FormatBytes(oldSize))
+	}
+	return nil
+}
+
+// runWIP handles "wip": it saves uncommitted changes in the current
+// repository as a WIP commit, or with -stash as a stash entry that leaves the
+// working tree untouched, and prints where they went.
+func runWIP(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("wip", flag.ContinueOnError)
+	stash := fs.Bool("stash", false, "Save a snapshot stash instead of committing; the working tree is left as is")
+	message := fs.String("m", "", "Commit or stash message (default: \"WIP: <date>\")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	client, err := git.NewClient(ctx, cwd)
+	if err != nil {
+		return err
+	}
+
+	msg := *message
+	if msg == "" {
+		msg = "WIP: " + time.Now().Format("2006-01-02 15:04")
+	}
+
+	if *stash {
+		hash, err := client.StashSnapshot(ctx, msg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Saved snapshot as stash@{0} (%s). Restore with `git stash apply %s`.\n", hash, hash)
+		return nil
+	}
+
+	hash, err := client.CommitWIP(ctx, msg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Committed WIP as %s. Undo with `git reset --soft HEAD~1`.\n", hash)
+	return nil
+}
+
+// runHook handles "hook", registered by "-install -hooks" for SessionStart
+// and Stop. It builds the status for the session's directory, discarding the
+// output, so the cache is warm when the status line next renders. At Stop,
+// renders pause with the turn, so it also flushes what they left spooled.
+func runHook(ctx context.Context, r io.Reader) error {
+	in, err := parseHookInput(r)
+	if err != nil {
+		return err
+	}
+	if in.HookEventName == "Stop" {
+		defer flushStatusLog(config.Load(in.Cwd))
+	}
+	return warmCache(ctx, in)
+}
+
+// runPrefetch handles "prefetch", meant for the SessionStart hook. It hands
+// the hook payload to a background copy of itself and returns at once, so
+// session startup never waits on git or the GitHub API while the first
+// render still finds every segment cached. With -wait it warms the cache in
+// the foreground instead.
+func runPrefetch(ctx context.Context, args []string, r io.Reader) error {
+	fs := flag.NewFlagSet("prefetch", flag.ContinueOnError)
+	wait := fs.Bool("wait", false, "Warm the cache in the foreground")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read hook input: %w", err)
+	}
+	in, err := parseHookInput(bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if *wait {
+		return warmCacheOnce(ctx, in)
+	}
+	return startPrefetch(payload)
+}
+
+// refreshLockPath is held by the process warming the cache, so a burst of
+// renders that all find the GitHub status stale start one refresh, not many.
+func refreshLockPath() string {
+	return filepath.Join(config.CacheDir(), "refresh.lock")
+}
+
+// warmCacheOnce runs warmCache unless another process is already warming.
+func warmCacheOnce(ctx context.Context, in hookInput) error {
+	if err := os.MkdirAll(config.CacheDir(), 0755); err != nil {
+		return err
+	}
+	lock := flock.New(refreshLockPath())
+	locked, err := lock.TryLock()
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", refreshLockPath(), err)
+	}
+	if !locked {
+		slog.Debug("cache is already being warmed", "cwd", in.Cwd)
+		return nil
+	}
+	defer lock.Unlock()
+	return warmCache(ctx, in)
+}
+
+// revalidateInBackground starts a background "prefetch -wait" for in, the
+// stale-while-revalidate refresh for one-shot renders. It does nothing while
+// another refresh holds the lock.
+func revalidateInBackground(in hookInput) {
+	lock := flock.New(refreshLockPath())
+	if locked, err := lock.TryLock(); err != nil || !locked {
+		return
+	}
+	_ = lock.Unlock()
+
+	payload, err := json.Marshal(in)
+	if err == nil {
+		err = startPrefetch(payload)
+	}
+	if err != nil {
+		slog.Debug("failed to start background refresh", "err", err)
+	}
+}
+
+// startPrefetch hands a hook payload to a detached "prefetch -wait" and
+// returns without waiting for it.
+func startPrefetch(payload []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find executable: %w", err)
+	}
+
+	// The child gets no stdout/stderr, so the hook runner isn't left waiting
+	// on pipes it inherited
+	cmd := exec.Command(exe, "prefetch", "-wait")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start background prefetch: %w", err)
+	}
+	// The payload fits in the pipe buffer, so this doesn't wait on the child
+	_, err = stdin.Write(payload)
+	stdin.Close()
+	if err != nil {
+		return fmt.Errorf("failed to pass hook input to background prefetch: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// parseHookInput decodes a Claude Code hook payload.
+func parseHookInput(r io.Reader) (hookInput, error) {
+	var in hookInput
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return in, fmt.Errorf("failed to parse hook input: %w", err)
+	}
+	if in.Cwd == "" {
+		return in, errors.New("hook input has no cwd")
+	}
+	return in, nil
+}
+
+// warmCache builds the status for the hook's session, discarding the output,
+// so git, GitHub, task, and context data are cached for the next render.
+// Render deadlines are lifted: a slow fetch should finish and be cached
+// rather than be abandoned.
+func warmCache(ctx context.Context, in hookInput) error {
+	cfg := config.Load(in.Cwd)
+	cfg.RenderDeadline = 0
+	cfg.SegmentTimeouts = nil
+
+	cacheManager := cache.NewManager(config.CacheDir())
+	if err := cacheManager.EnsureDir(); err != nil {
+		return err
+	}
+	cacheManager.Batch()
+	defer cacheManager.Flush()
+	builder := status.NewBuilderWithCache(ctx, &cfg, cacheManager, in.Cwd)
+
+	slog.Debug("warming cache from hook", "event", in.HookEventName, "cwd", in.Cwd)
+	builder.Build(ctx, status.Input{
+		Workspace:      status.WorkspaceInfo{CurrentDir: in.Cwd},
+		SessionID:      in.SessionID,
+		TranscriptPath: in.TranscriptPath,
+	})
+	return nil
+}
+
+// currentGitHubRepo resolves the GitHub owner/repo and branch for workDir,
+// whose origin is on host (empty means github.com).
+func currentGitHubRepo(ctx context.Context, workDir, host string) (owner, repo, branch string, err error) {
+	gitClient, err := git.NewClient(ctx, workDir)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	branch, err = gitClient.Branch(ctx)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	remoteURL, err := gitClient.RemoteURL(ctx)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to get remote URL: %w", err)
+	}
+
+	owner, repo, ok := git.ParseGitHubRepoHost(remoteURL, host)
+	if !ok {
+		return "", "", "", fmt.Errorf("not a GitHub repository: %s", remoteURL)
+	}
+
+	return owner, repo, branch, nil
+}
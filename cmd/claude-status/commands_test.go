@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/gofrs/flock"
+	"github.com/kostyay/claude-status/internal/bar"
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/state"
+	"github.com/kostyay/claude-status/internal/status"
+	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/tokens"
+)
+
+func TestRunHook_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"malformed", "not json", "failed to parse hook input"},
+		{"missing cwd", `{"hook_event_name": "Stop"}`, "no cwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runHook(t.Context(), strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runHook() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookSubcommand_WarmsCache(t *testing.T) {
+	tmp := t.TempDir()
+	cacheHome := filepath.Join(tmp, "cache")
+	env := append(os.Environ(),
+		"XDG_CACHE_HOME="+cacheHome,
+		"XDG_CONFIG_HOME="+filepath.Join(tmp, "config"),
+		"XDG_DATA_HOME="+filepath.Join(tmp, "data"),
+	)
+
+	workDir := filepath.Join(tmp, "project")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "./cmd/claude-status", "hook")
+	cmd.Dir = filepath.Clean("../..")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(`{"hook_event_name": "SessionStart", "cwd": "` + workDir + `"}`)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("hook failed: %v (output: %s)", err, out)
+	}
+	if len(out) != 0 {
+		t.Errorf("hook should print nothing, got: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(cacheHome, "claude-status")); err != nil {
+		t.Errorf("expected cache directory to be created: %v", err)
+	}
+}
+
+func TestRunPrefetch_InvalidInput(t *testing.T) {
+	err := runPrefetch(t.Context(), nil, strings.NewReader(`{"hook_event_name": "SessionStart"}`))
+	if err == nil || !strings.Contains(err.Error(), "no cwd") {
+		t.Errorf("runPrefetch() error = %v, want it to contain %q", err, "no cwd")
+	}
+}
+
+func TestRunPrefetch_Wait(t *testing.T) {
+	tmp := t.TempDir()
+	cacheHome := filepath.Join(tmp, "cache")
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	workDir := filepath.Join(tmp, "project")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"hook_event_name": "SessionStart", "cwd": "` + workDir + `"}`
+	if err := runPrefetch(t.Context(), []string{"-wait"}, strings.NewReader(input)); err != nil {
+		t.Fatalf("runPrefetch(-wait) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheHome, "claude-status")); err != nil {
+		t.Errorf("expected cache directory to be created: %v", err)
+	}
+}
+
+func TestRunPrefetch_WaitSkipsWhileRefreshing(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	if err := os.MkdirAll(config.CacheDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lock := flock.New(refreshLockPath())
+	if locked, err := lock.TryLock(); err != nil || !locked {
+		t.Fatalf("TryLock() = %v, %v", locked, err)
+	}
+	defer lock.Unlock()
+
+	// Another process is refreshing, so this one returns without building
+	input := `{"cwd": "` + filepath.Join(tmp, "project") + `"}`
+	if err := runPrefetch(t.Context(), []string{"-wait"}, strings.NewReader(input)); err != nil {
+		t.Fatalf("runPrefetch(-wait) error = %v", err)
+	}
+	if _, err := os.Stat(config.CachePath()); !os.IsNotExist(err) {
+		t.Errorf("cache written while another refresh held the lock: %v", err)
+	}
+}
+
+func TestRunPreview(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var out bytes.Buffer
+	if err := runPreview([]string{"-template", "{{.Model}} {{.GitBranch}} {{.Cost}}"}, &out); err != nil {
+		t.Fatalf("runPreview() error = %v", err)
+	}
+	if got, want := out.String(), "Opus 4 feature/preview $1.87\n"; got != want {
+		t.Errorf("runPreview() = %q, want %q", got, want)
+	}
+
+	// Template files lose the newline editors add
+	file := filepath.Join(tmp, "statusline.tmpl")
+	if err := os.WriteFile(file, []byte("{{.TasksReady}} ready\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runPreview([]string{"-template-file", file}, &out); err != nil {
+		t.Fatalf("runPreview(-template-file) error = %v", err)
+	}
+	if got, want := out.String(), "4 ready\n"; got != want {
+		t.Errorf("runPreview(-template-file) = %q, want %q", got, want)
+	}
+
+	// The configured (default) template renders too
+	out.Reset()
+	if err := runPreview(nil, &out); err != nil {
+		t.Fatalf("runPreview() with default template error = %v", err)
+	}
+	if !strings.Contains(out.String(), "feature/preview") {
+		t.Errorf("runPreview() = %q, want the sample branch", out.String())
+	}
+}
+
+func TestRunPreview_Theme(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var out bytes.Buffer
+	if err := runPreview([]string{"-template", "{{yellow}}{{.Model}}", "-theme", "light"}, &out); err != nil {
+		t.Fatalf("runPreview() error = %v", err)
+	}
+	if want := template.Palettes[template.ThemeLight]["yellow"] + "Opus 4\n"; out.String() != want {
+		t.Errorf("runPreview(-theme light) = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunTheme(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+	t.Setenv("COLORFGBG", "")
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var out bytes.Buffer
+	if err := runTheme(nil, &out); err != nil {
+		t.Fatalf("runTheme() error = %v", err)
+	}
+	if want := "theme: dark\nrenders: dark (from default)\n"; out.String() != want {
+		t.Errorf("runTheme() = %q, want %q", out.String(), want)
+	}
+
+	if err := os.MkdirAll(config.ConfigDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.ConfigPath(), []byte(`{"theme": "auto"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COLORFGBG", "0;15")
+	out.Reset()
+	if err := runTheme(nil, &out); err != nil {
+		t.Fatalf("runTheme() error = %v", err)
+	}
+	if want := "theme: auto\nrenders: light (from COLORFGBG)\n"; out.String() != want {
+		t.Errorf("runTheme() = %q, want %q", out.String(), want)
+	}
+
+	if err := runTheme([]string{"bogus"}, &out); err == nil {
+		t.Error("runTheme(bogus) error = nil, want usage")
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var out bytes.Buffer
+	if err := runConfig([]string{"set", "github_ttl", "120"}, &out); err != nil {
+		t.Fatalf("runConfig(set) error = %v", err)
+	}
+	if _, err := os.Stat(config.ConfigPath()); err != nil {
+		t.Fatalf("config set didn't create the config file: %v", err)
+	}
+
+	out.Reset()
+	if err := runConfig([]string{"get", "github_ttl"}, &out); err != nil {
+		t.Fatalf("runConfig(get) error = %v", err)
+	}
+	if out.String() != "120\n" {
+		t.Errorf("config get github_ttl = %q, want %q", out.String(), "120\n")
+	}
+
+	out.Reset()
+	if err := runConfig([]string{"list"}, &out); err != nil {
+		t.Fatalf("runConfig(list) error = %v", err)
+	}
+	if !strings.Contains(out.String(), "github_ttl ") || strings.Count(out.String(), "\n") != len(config.Keys()) {
+		t.Errorf("config list = %q, want one line per key", out.String())
+	}
+
+	if err := runConfig([]string{"set", "github_ttl"}, &out); err == nil {
+		t.Error("runConfig(set without a value) error = nil, want usage")
+	}
+}
+
+func TestRunUsageContext(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", filepath.Join(t.TempDir(), "data"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var out bytes.Buffer
+	if err := runUsage([]string{"context"}, &out); err != nil {
+		t.Fatalf("runUsage() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No context usage recorded") {
+		t.Errorf("usage context with nothing recorded = %q", out.String())
+	}
+
+	store := state.NewStore(config.StatePath())
+	for i, pct := range []float64{20, 85, 90, 95} {
+		store.RecordContext("/heavy", fmt.Sprint(i), pct)
+	}
+	for i, pct := range []float64{10, 20, 30, 85} {
+		store.RecordContext("/light", fmt.Sprint(i), pct)
+	}
+
+	out.Reset()
+	if err := runUsage([]string{"context"}, &out); err != nil {
+		t.Fatalf("runUsage() error = %v", err)
+	}
+	got := out.String()
+	// The project most often near the limit comes first
+	if heavy, light := strings.Index(got, "/heavy"), strings.Index(got, "/light"); heavy < 0 || light < heavy {
+		t.Errorf("usage context = %q, want /heavy listed before /light", got)
+	}
+	if !strings.Contains(got, "75%") {
+		t.Errorf("usage context = %q, want /heavy near the limit 75%% of turns", got)
+	}
+
+	out.Reset()
+	if err := runUsage([]string{"context", "-reset"}, &out); err != nil {
+		t.Fatalf("runUsage(-reset) error = %v", err)
+	}
+	if len(store.ContextUsage()) != 0 {
+		t.Error("usage context -reset left context usage behind")
+	}
+
+	if err := runUsage(nil, &out); err == nil {
+		t.Error("runUsage() without a report error = nil, want usage")
+	}
+}
+
+func TestRunUsageCost(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", filepath.Join(t.TempDir(), "data"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.Local)
+	record := func(at time.Time, project, session string, models map[string]state.ModelUsage) {
+		state.NewStoreWithClock(config.StatePath(), func() time.Time { return at }).RecordCost(project, session, models)
+	}
+	record(now, "/api", "s1", map[string]state.ModelUsage{"claude-opus-4-5": {Tokens: 2_000_000, CostUSD: 12.5}})
+	record(now.Add(-24*time.Hour), "/api", "s2", map[string]state.ModelUsage{"claude-opus-4-5": {Tokens: 1_000_000, CostUSD: 5}})
+	record(now, "/web", "s3", map[string]state.ModelUsage{"claude-haiku-4-5": {Tokens: 500_000, CostUSD: 0.5}})
+	record(now.Add(-30*24*time.Hour), "/old", "s4", map[string]state.ModelUsage{"claude-opus-4-5": {Tokens: 1_000_000, CostUSD: 99}})
+
+	var out bytes.Buffer
+	if err := runUsageCost([]string{"-since", "7d"}, &out, now); err != nil {
+		t.Fatalf("runUsageCost() error = %v", err)
+	}
+	got := out.String()
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	// Days within the window add up per model and project, costliest first
+	if len(lines) != 6 || !strings.Contains(lines[3], "/api") || !strings.Contains(lines[3], "$17.50") ||
+		!strings.Contains(lines[4], "/web") {
+		t.Fatalf("usage cost = %q, want /api ($17.50) then /web", got)
+	}
+	if strings.Contains(got, "/old") {
+		t.Errorf("usage cost = %q, want usage older than -since left out", got)
+	}
+
+	out.Reset()
+	if err := runUsageCost([]string{"-since", "1h"}, &out, now.Add(48*time.Hour)); err != nil {
+		t.Fatalf("runUsageCost() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "No usage recorded") {
+		t.Errorf("usage cost with nothing in range = %q", out.String())
+	}
+
+	if err := runUsageCost([]string{"-since", "week"}, &out, now); err == nil {
+		t.Error("runUsageCost(-since week) error = nil, want an invalid -since")
+	}
+}
+
+func TestRunSessionsGC(t *testing.T) {
+	claudeDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", claudeDir)
+
+	now := time.Now()
+	workDir := t.TempDir()
+	write := func(project, id, cwd string, age time.Duration) string {
+		dir := filepath.Join(claudeDir, "projects", project)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(path, []byte(`{"type":"user","cwd":"`+cwd+`"}`+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	recent := write("-work", "recent", workDir, time.Hour)
+	stale := write("-work", "stale", workDir, 40*24*time.Hour)
+	orphan := write("-gone", "orphan", filepath.Join(workDir, "gone"), 40*24*time.Hour)
+
+	var out bytes.Buffer
+	if err := runSessions([]string{"gc"}, &out, now); err != nil {
+		t.Fatalf("runSessions(gc) error = %v", err)
+	}
+	got := out.String()
+	if !strings.Contains(got, "gone (missing)") || !strings.Contains(got, "remove 2 sessions") {
+		t.Errorf("sessions gc = %q, want the missing project flagged and 2 old sessions", got)
+	}
+	for _, path := range []string{recent, stale, orphan} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("sessions gc without -delete removed %s", path)
+		}
+	}
+
+	out.Reset()
+	if err := runSessions([]string{"gc", "-orphaned", "-delete"}, &out, now); err != nil {
+		t.Fatalf("runSessions(gc -orphaned -delete) error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Deleted 1 sessions") {
+		t.Errorf("sessions gc -orphaned -delete = %q", out.String())
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("orphaned session not deleted")
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Error("-orphaned deleted a session of a project that still exists")
+	}
+
+	archiveDir := t.TempDir()
+	out.Reset()
+	if err := runSessions([]string{"gc", "-archive", archiveDir}, &out, now); err != nil {
+		t.Fatalf("runSessions(gc -archive) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(archiveDir, "-work", "stale.tar.gz")); err != nil {
+		t.Errorf("stale session not archived: %v", err)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("-archive removed a recent session")
+	}
+
+	if err := runSessions([]string{"gc", "-archive", archiveDir, "-delete"}, &out, now); err == nil {
+		t.Error("runSessions(-archive -delete) error = nil, want an error")
+	}
+	if err := runSessions(nil, &out, now); err == nil {
+		t.Error("runSessions() without gc error = nil, want usage")
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"7d", 7 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"12h", 12 * time.Hour, false},
+		{"-1d", 0, true},
+		{"d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSince("since", tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSince(%q) = %v, %v; want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBucketBars(t *testing.T) {
+	if got := bucketBars([]int64{1, 0, 4, 2}); got != "▂ █▄" {
+		t.Errorf("bucketBars() = %q, want %q", got, "▂ █▄")
+	}
+}
+
+func TestRunPreview_InvalidTemplate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	err := runPreview([]string{"-template", "{{.Model"}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "invalid template") {
+		t.Errorf("runPreview() error = %v, want an invalid template error", err)
+	}
+}
+
+func TestRunTemplateTest(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	dir := config.TemplateTestDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.ConfigPath(), []byte(`{"template": "{{.Model}} {{.GitBranch}}"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.json"), []byte(`{"Model": "Opus 4", "GitBranch": "main"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bootstrap the expected output, then check against it
+	var out bytes.Buffer
+	if err := runTemplate([]string{"test", "-update"}, &out); err != nil {
+		t.Fatalf("runTemplate(-update) error = %v", err)
+	}
+	out.Reset()
+	if err := runTemplate([]string{"test"}, &out); err != nil {
+		t.Fatalf("runTemplate() error = %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "ok   main") {
+		t.Errorf("runTemplate() output = %q, want main to pass", out.String())
+	}
+
+	// A template change that alters the output fails the suite
+	if err := os.WriteFile(config.ConfigPath(), []byte(`{"template": "[{{.Model}}]"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	err := runTemplate([]string{"test"}, &out)
+	if !errors.Is(err, errTemplateTestsFailed) {
+		t.Errorf("runTemplate() error = %v, want errTemplateTestsFailed", err)
+	}
+	if !strings.Contains(out.String(), `got:  "[Opus 4]"`) {
+		t.Errorf("runTemplate() output = %q, want the rendered line", out.String())
+	}
+}
+
+func TestRunDump(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	dir := filepath.Join(tmp, "project")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.ProjectFile), []byte(`{"github_workflow": "ci"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runDump(t.Context(), []string{"-dir", dir}, &out); err != nil {
+		t.Fatalf("runDump() error = %v", err)
+	}
+
+	var dump debugDump
+	if err := json.Unmarshal(out.Bytes(), &dump); err != nil {
+		t.Fatalf("runDump() output is not JSON: %v\n%s", err, out.String())
+	}
+	if dump.Dir != dir || dump.Status.Dir != "project" {
+		t.Errorf("dir = %q, status dir = %q; want %q and project", dump.Dir, dump.Status.Dir, dir)
+	}
+	if !slices.Equal(dump.Config.GitHubWorkflow, config.Workflows{"ci"}) || dump.Files.ProjectConfig != filepath.Join(dir, config.ProjectFile) {
+		t.Errorf("project config not reflected: workflow = %q, file = %q", dump.Config.GitHubWorkflow, dump.Files.ProjectConfig)
+	}
+	if dump.Files.Cache != config.CachePath() {
+		t.Errorf("cache = %q, want %q", dump.Files.Cache, config.CachePath())
+	}
+	if dump.Providers.GitDir != "" || dump.Providers.Tasks != "" {
+		t.Errorf("providers = %+v, want none outside a repository", dump.Providers)
+	}
+}
+
+func TestRunBar(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	t.Setenv("CLAUDE_CONFIG_DIR", filepath.Join(tmp, "claude"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	dir := filepath.Join(tmp, "project")
+	transcriptDir := tokens.ProjectDir(filepath.Join(tmp, "claude"), dir)
+	for _, d := range []string{dir, transcriptDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	usage := `{"type":"assistant","message":{"role":"assistant","usage":{"input_tokens":150000,"output_tokens":10}}}` + "\n"
+	if err := os.WriteFile(filepath.Join(transcriptDir, "session.jsonl"), []byte(usage), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runBar(t.Context(), []string{"-dir", dir}, &out); err != nil {
+		t.Fatalf("runBar() error = %v", err)
+	}
+	var item bar.Item
+	if err := json.Unmarshal(out.Bytes(), &item); err != nil {
+		t.Fatalf("runBar() output is not JSON: %v\n%s", err, out.String())
+	}
+	// 150k of the 160k usable before auto-compact
+	if item.Label != "project 94%" || item.State != bar.StateWarning || item.ColorARGB == "" {
+		t.Errorf("runBar() = %+v, want the newest transcript's context as a warning", item)
+	}
+
+	out.Reset()
+	if err := runBar(t.Context(), []string{"-dir", dir, "-label", "{{red}}{{.Dir}}"}, &out); err != nil {
+		t.Fatalf("runBar(-label) error = %v", err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &item); err != nil || item.Label != "project" {
+		t.Errorf("runBar(-label) label = %q, %v; want the template without colors", item.Label, err)
+	}
+
+	if err := runBar(t.Context(), []string{"-dir", dir, "-label", "{{.Nope"}, &out); err == nil {
+		t.Error("runBar() with an invalid label template succeeded")
+	}
+}
+
+func TestRunWIP(t *testing.T) {
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if err := exec.Command("git", append([]string{"-C", dir}, args...)...).Run(); err != nil {
+			t.Skipf("git %v failed: %v", args, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("draft\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	var out bytes.Buffer
+	if err := runWIP(t.Context(), []string{"-m", "WIP: notes"}, &out); err != nil {
+		t.Fatalf("runWIP() error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Committed WIP as ") {
+		t.Errorf("runWIP() = %q, want the commit hash", out.String())
+	}
+
+	// Nothing left to save
+	if err := runWIP(t.Context(), nil, &out); !errors.Is(err, git.ErrNothingToSave) {
+		t.Errorf("runWIP() on a clean tree error = %v, want git.ErrNothingToSave", err)
+	}
+}
+
+func TestFlushStatusLog(t *testing.T) {
+	cfg := config.Default()
+	cfg.LoggingEnabled = true
+	cfg.LogPath = filepath.Join(t.TempDir(), "status_line.json")
+
+	// The first render writes the log; the next, right after, is spooled
+	logStatusLine(cfg, status.Input{SessionID: "one"}, "first")
+	logStatusLine(cfg, status.Input{SessionID: "two"}, "second")
+	if _, err := os.Stat(cfg.LogPath + ".pending"); err != nil {
+		t.Fatalf("second entry not spooled: %v", err)
+	}
+
+	flushStatusLog(cfg)
+	var entries []LogEntry
+	data, err := os.ReadFile(cfg.LogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil || len(entries) != 2 || entries[1].StatusLineOutput != "second" {
+		t.Errorf("log after flush = %s, %v; want both entries", data, err)
+	}
+}
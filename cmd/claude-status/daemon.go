@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/cache"
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/crash"
+	"github.com/kostyay/claude-status/internal/daemon"
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/renderhook"
+	"github.com/kostyay/claude-status/internal/status"
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// runDaemon handles "daemon": it serves status lines on config.SocketPath()
+// until ctx is cancelled. Normal invocations render through it when it's
+// running.
+func runDaemon(ctx context.Context) error {
+	socketPath := config.SocketPath()
+	ln, err := daemon.Listen(socketPath)
+	if err != nil {
+		return err
+	}
+
+	cacheManager := cache.NewManager(config.CacheDir())
+	if err := cacheManager.EnsureDir(); err != nil {
+		ln.Close()
+		return err
+	}
+
+	// Project configs are merged per working directory by each builder
+	d := newDaemonState(ctx, config.Load(""), cacheManager)
+	go func() {
+		// Watch the config directory even before a config file exists
+		_ = os.MkdirAll(config.ConfigDir(), 0755)
+		if err := config.Watch(ctx, config.ConfigPath(), d.setConfig); err != nil {
+			slog.Warn("config changes won't be picked up until restart", "err", err)
+		}
+	}()
+
+	go d.flushLogs()
+
+	fmt.Fprintf(os.Stderr, "claude-status daemon listening on %s\n", socketPath)
+	return daemon.Serve(ctx, ln, d.handle)
+}
+
+// daemonState is the warm state shared by daemon requests: the config, one
+// in-memory cache, and a builder per working directory. mu guards the
+// config and the maps; requests build and render without it, so a slow
+// repository holds up only its own requests.
+type daemonState struct {
+	ctx      context.Context
+	cache    *cache.Manager
+	hooks    chan func() // Post-render hook runs, in order, for runHooks
+	mu       sync.Mutex
+	cfg      *config.Config          // user config; replaced, never mutated, so builders keep a consistent view
+	builders map[string]*dirBuilder  // keyed by working directory
+	inputs   map[string]status.Input // latest input per git dir, replayed to pre-warm on changes
+	watched  map[string]bool         // git dirs with a running watcher
+	stale    map[string]bool         // working dirs whose stale GitHub status is being refreshed
+}
+
+// maxQueuedHooks bounds the post-render hook runs waiting for the hook
+// worker; renders past it skip their hooks.
+const maxQueuedHooks = 16
+
+func newDaemonState(ctx context.Context, cfg config.Config, cache *cache.Manager) *daemonState {
+	d := &daemonState{
+		ctx:      ctx,
+		cache:    cache,
+		hooks:    make(chan func(), maxQueuedHooks),
+		cfg:      &cfg,
+		builders: make(map[string]*dirBuilder),
+		inputs:   make(map[string]status.Input),
+		watched:  make(map[string]bool),
+		stale:    make(map[string]bool),
+	}
+	go d.runHooks()
+	return d
+}
+
+// runHooks runs queued post-render hooks one at a time until the daemon
+// stops.
+func (d *daemonState) runHooks() {
+	for {
+		select {
+		case run := <-d.hooks:
+			run()
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// logFlushInterval is how often the daemon folds the entries renders left
+// spooled into the status line log, so the last renders of a burst reach it
+// without waiting for the next one.
+const logFlushInterval = 5 * time.Second
+
+// flushLogs flushes the status line log every logFlushInterval until the
+// daemon stops.
+func (d *daemonState) flushLogs() {
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.mu.Lock()
+			cfg := *d.cfg
+			d.mu.Unlock()
+			flushStatusLog(cfg)
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// handle renders a status line request. A panic fails only this request,
+// so the client renders the line itself, and leaves a crash report.
+//
+// Segments are fetched on the daemon's context rather than the request's,
+// so one that misses its soft deadline still finishes, and is cached, after
+// the reply; the render deadline bounds it.
+func (d *daemonState) handle(_ context.Context, req daemon.Request) (output string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			output, err = "", crash.Panic(config.CrashDir(), r)
+		}
+	}()
+
+	input, err := status.ParseInput(req.Input)
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	db := d.builder(input.Workspace.CurrentDir)
+	if gitDir := db.builder.GitDir(); gitDir != "" {
+		d.inputs[gitDir] = input
+	}
+	d.mu.Unlock()
+
+	// Pick up what the CLI, hooks, and prefetches cached since the last request
+	d.cache.Reload()
+
+	start := time.Now()
+	data, timings := db.builder.BuildWithOptions(d.ctx, input, status.BuildOptions{
+		Revalidate: func() { d.revalidate(db.cfg, input) },
+	})
+	if req.Prefix != "" {
+		data.Prefix = req.Prefix
+		data.PrefixColor = prefixColorCode(req.PrefixColor)
+	}
+	cfg := *db.cfg
+	if req.Format != "" {
+		cfg.OutputFormat = req.Format
+	}
+	renderhook.Run(d.ctx, renderhook.PreRender, cfg.PreRenderHooks, data, "", hookTimeout(cfg))
+	output, err = render(cfg, data)
+	if err == nil {
+		elapsed := time.Since(start)
+		recordTelemetry(cfg, elapsed, timings)
+		writeMirror(cfg, data, output)
+		writeMetrics(cfg, input.SessionID, data, elapsed)
+		// The client is waiting on the line, not on what mirrors it
+		d.queueHooks(cfg, data, output)
+	}
+	return output, err
+}
+
+// queueHooks hands the post-render hooks for a render to the hook worker,
+// or skips them if it is too far behind.
+func (d *daemonState) queueHooks(cfg config.Config, data template.StatusData, output string) {
+	if len(cfg.PostRenderHooks) == 0 {
+		return
+	}
+	select {
+	case d.hooks <- func() {
+		renderhook.Run(d.ctx, renderhook.PostRender, cfg.PostRenderHooks, data, output, hookTimeout(cfg))
+	}:
+	default:
+		slog.Warn("post-render hooks are falling behind, skipping", "queued", maxQueuedHooks)
+	}
+}
+
+// dirBuilder is the builder for one working directory, with the config it
+// was built from: the user config merged with the project's own, if any.
+type dirBuilder struct {
+	builder *status.Builder
+	cfg     *config.Config
+	project string    // project config file merged into cfg ("" if none)
+	modTime time.Time // project config mtime when cfg was loaded
+}
+
+// builder returns the builder for workDir, creating it (and a watcher on its
+// repository) on first use, and again whenever the project config changes.
+// Callers must hold mu.
+func (d *daemonState) builder(workDir string) *dirBuilder {
+	project := config.FindProjectConfig(workDir)
+	var modTime time.Time
+	if project != "" {
+		if info, err := os.Stat(project); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+	if db, ok := d.builders[workDir]; ok && db.project == project && db.modTime.Equal(modTime) {
+		return db
+	}
+
+	cfg := config.WithProject(*d.cfg, workDir)
+	b := status.NewBuilderWithCache(d.ctx, &cfg, d.cache, workDir)
+	db := &dirBuilder{builder: b, cfg: &cfg, project: project, modTime: modTime}
+	d.builders[workDir] = db
+
+	if gitDir := b.GitDir(); gitDir != "" && !d.watched[gitDir] {
+		d.watched[gitDir] = true
+		go func() {
+			if err := git.Watch(d.ctx, gitDir, func() { d.warm(gitDir) }); err != nil {
+				slog.Warn("failed to watch repository", "gitDir", gitDir, "err", err)
+			}
+		}()
+	}
+	return db
+}
+
+// warm rebuilds the status for gitDir's latest input after a repository
+// change, so the next render finds fresh cache entries.
+func (d *daemonState) warm(gitDir string) {
+	d.mu.Lock()
+	input, ok := d.inputs[gitDir]
+	var db *dirBuilder
+	if ok {
+		db = d.builder(input.Workspace.CurrentDir)
+	}
+	d.mu.Unlock()
+
+	if ok {
+		db.builder.Build(d.ctx, input)
+	}
+}
+
+// revalidate refreshes the stale GitHub status for input in the background,
+// on a builder of its own so requests aren't held up. It is called from the
+// GitHub segment, which may still be running after its request was answered.
+func (d *daemonState) revalidate(cfg *config.Config, input status.Input) {
+	workDir := input.Workspace.CurrentDir
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stale[workDir] {
+		return
+	}
+	d.stale[workDir] = true
+
+	// Like warmCache, let the refresh finish rather than hit render deadlines
+	refresh := *cfg
+	refresh.RenderDeadline = 0
+	refresh.SegmentTimeouts = nil
+	go func() {
+		status.NewBuilderWithCache(d.ctx, &refresh, d.cache, workDir).Build(d.ctx, input)
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.stale, workDir)
+	}()
+}
+
+// setConfig swaps in a reloaded config. Builders are recreated on next use
+// because git and GitHub clients capture config when they are built.
+func (d *daemonState) setConfig(cfg config.Config) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cfg = &cfg
+	clear(d.builders)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/cache"
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/daemon"
+	"github.com/kostyay/claude-status/internal/status"
+)
+
+func newTestDaemon(t *testing.T, cfg config.Config) *daemonState {
+	t.Helper()
+	cacheManager := cache.NewManager(t.TempDir())
+	if err := cacheManager.EnsureDir(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return newDaemonState(ctx, cfg, cacheManager)
+}
+
+func TestDaemonState_Handle(t *testing.T) {
+	cfg := config.Default()
+	cfg.Template = `{{.Prefix}}[{{.Model}}] {{.Dir}}`
+	d := newTestDaemon(t, cfg)
+
+	workDir := filepath.Join(t.TempDir(), "myproject")
+	req := daemon.Request{
+		Input:  []byte(`{"model": {"display_name": "Opus"}, "workspace": {"current_dir": "` + workDir + `"}}`),
+		Prefix: "dev",
+	}
+
+	output, err := d.handle(t.Context(), req)
+	if err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+	if output != "dev[Opus] myproject" {
+		t.Errorf("handle() = %q, want %q", output, "dev[Opus] myproject")
+	}
+
+	// The builder is reused across requests for the same directory
+	first := d.builders[workDir]
+	if _, err := d.handle(t.Context(), req); err != nil {
+		t.Fatal(err)
+	}
+	if d.builders[workDir] != first {
+		t.Error("builder was recreated for the same directory")
+	}
+}
+
+func TestDaemonState_InvalidInput(t *testing.T) {
+	d := newTestDaemon(t, config.Default())
+
+	_, err := d.handle(t.Context(), daemon.Request{Input: []byte(`{}`)})
+	var inputErr *status.InputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("handle() error = %v, want *status.InputError", err)
+	}
+}
+
+func TestDaemonState_SetConfig(t *testing.T) {
+	d := newTestDaemon(t, config.Default())
+
+	workDir := t.TempDir()
+	req := daemon.Request{Input: []byte(`{"workspace": {"current_dir": "` + workDir + `"}}`)}
+	if _, err := d.handle(t.Context(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Template = "reloaded {{.Model}}"
+	d.setConfig(cfg)
+
+	if len(d.builders) != 0 {
+		t.Errorf("builders should be dropped on config change, have %d", len(d.builders))
+	}
+	output, err := d.handle(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(output, "reloaded") {
+		t.Errorf("handle() = %q, want the reloaded template", output)
+	}
+}
+
+func TestDaemonState_ProjectConfig(t *testing.T) {
+	d := newTestDaemon(t, config.Default())
+
+	workDir := t.TempDir()
+	projectPath := filepath.Join(workDir, config.ProjectFile)
+	if err := os.WriteFile(projectPath, []byte(`{"template": "project {{.Model}}"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	req := daemon.Request{Input: []byte(`{"model": {"display_name": "Opus"}, "workspace": {"current_dir": "` + workDir + `"}}`)}
+
+	output, err := d.handle(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "project Opus" {
+		t.Errorf("handle() = %q, want the project template", output)
+	}
+
+	// Editing the project config takes effect without a restart
+	if err := os.WriteFile(projectPath, []byte(`{"template": "edited {{.Model}}"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(projectPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	output, err = d.handle(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "edited Opus" {
+		t.Errorf("handle() = %q, want the edited project template", output)
+	}
+}
+
+func TestDaemonState_ConcurrentRequests(t *testing.T) {
+	cfg := config.Default()
+	cfg.Template = `{{.Model}}`
+	d := newTestDaemon(t, cfg)
+
+	dirs := []string{t.TempDir(), t.TempDir()}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		workDir := dirs[i%len(dirs)]
+		input := status.Input{Workspace: status.WorkspaceInfo{CurrentDir: workDir}}
+		wg.Go(func() {
+			req := daemon.Request{Input: []byte(`{"model": {"display_name": "Opus"}, "workspace": {"current_dir": "` + workDir + `"}}`)}
+			if output, err := d.handle(t.Context(), req); err != nil || output != "Opus" {
+				t.Errorf("handle() = %q, %v; want %q", output, err, "Opus")
+			}
+		})
+		// As a GitHub segment still running after its reply would
+		wg.Go(func() { d.revalidate(&cfg, input) })
+	}
+	wg.Wait()
+}
+
+func TestDaemonState_PostRenderHooksInOrder(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	cfg := config.Default()
+	cfg.Template = `{{.Model}}`
+	cfg.PostRenderHooks = []string{`printf '%s\n' "$CLAUDE_STATUS_OUTPUT" >> ` + out}
+	d := newTestDaemon(t, cfg)
+
+	workDir := t.TempDir()
+	models := []string{"one", "two", "three"}
+	for _, model := range models {
+		req := daemon.Request{Input: []byte(`{"model": {"display_name": "` + model + `"}, "workspace": {"current_dir": "` + workDir + `"}}`)}
+		if _, err := d.handle(t.Context(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := strings.Join(models, "\n") + "\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := os.ReadFile(out)
+		if string(got) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("hook output = %q, want %q", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
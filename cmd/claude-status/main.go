@@ -1,32 +1,65 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/kostyay/claude-status/internal/cache"
 	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/crash"
+	"github.com/kostyay/claude-status/internal/daemon"
 	"github.com/kostyay/claude-status/internal/install"
+	"github.com/kostyay/claude-status/internal/metrics"
+	"github.com/kostyay/claude-status/internal/mirror"
+	"github.com/kostyay/claude-status/internal/renderhook"
 	"github.com/kostyay/claude-status/internal/status"
+	"github.com/kostyay/claude-status/internal/statuslog"
+	"github.com/kostyay/claude-status/internal/telemetry"
 	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/theme"
 )
 
 var prefixFlag = flag.String("prefix", "", "Prefix to display at the start of the status line")
-var prefixColorFlag = flag.String("prefix-color", "", "Color for the prefix (cyan, blue, green, yellow, red, magenta, gray)")
+var prefixColorFlag = flag.String("prefix-color", "", "Color for the prefix (cyan, blue, green, yellow, red, magenta, gray, or #rrggbb)")
+
+var formatFlag = flag.String("format", "", `Output format: "line" (rendered template) or "json" (all status fields); overrides output_format`)
 
 var installFlag = flag.Bool("install", false, "Run installation wizard")
+var uninstallFlag = flag.Bool("uninstall", false, "Remove claude-status from Claude Code settings, restoring the statusLine it replaced")
+var dryRunFlag = flag.Bool("dry-run", false, "With -install or -uninstall: print the settings diff and exit without prompting or writing; exits 1 if there are changes")
+var yesFlag = flag.Bool("y", false, "With -install or -uninstall: apply the changes without prompting")
+var hooksFlag = flag.Bool("hooks", false, "With -install: also register SessionStart and Stop hooks that keep cached data warm")
 var testFlag = flag.Bool("test", false, "Test mode: use current directory, skip stdin")
 
 func main() {
 	flag.Parse()
 
-	// Handle -install flag
-	if *installFlag {
-		if err := install.Run(os.Stdout, os.Stdin); err != nil {
+	// Handle -install and -uninstall flags
+	if *installFlag || *uninstallFlag {
+		os.Exit(runInstaller())
+	}
+
+	// Interrupting stops in-flight git commands and GitHub API calls
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Handle subcommands (e.g. "ci rerun")
+	if flag.NArg() > 0 {
+		if err := runSubcommand(ctx, flag.Args()); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -34,26 +67,112 @@ func main() {
 	}
 
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
-	os.Exit(runMain())
+	os.Exit(runMain(ctx))
+}
+
+// runInstaller handles -install and -uninstall and returns the exit code.
+// With -dry-run it follows diff(1): 0 if settings are up to date, 1 if they
+// would change, and 2 on errors.
+func runInstaller() int {
+	opts := install.Options{Hooks: *hooksFlag, Yes: *yesFlag}
+	var err error
+	switch {
+	case *uninstallFlag && *dryRunFlag:
+		err = install.UninstallDryRun(os.Stdout)
+	case *uninstallFlag:
+		err = install.Uninstall(os.Stdout, os.Stdin, opts)
+	case *dryRunFlag:
+		err = install.DryRun(os.Stdout, opts)
+	default:
+		err = install.Run(os.Stdout, os.Stdin, opts)
+	}
+
+	if errors.Is(err, install.ErrChangesPending) {
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if *dryRunFlag {
+			return 2
+		}
+		return 1
+	}
+	return 0
 }
 
-func runMain() int {
-	if err := run(); err != nil {
-		// Log error to stderr for debugging
-		slog.Error("error", "err", err)
+// fallbackLine is printed when no status line could be rendered.
+const fallbackLine = "\033[31m[Claude] 📁 Unknown\033[0m"
+
+// lineWritten is set once a line has been printed, so a late panic or the
+// watchdog doesn't print a second one.
+var lineWritten atomic.Bool
+
+// writeLine prints line as the status line, unless one was already printed.
+func writeLine(line string) {
+	if lineWritten.CompareAndSwap(false, true) {
+		fmt.Println(line)
+	}
+}
+
+func runMain(ctx context.Context) (code int) {
+	// A panic anywhere on this goroutine still leaves a status line, with
+	// the details in a crash report instead of on stderr
+	defer func() {
+		if r := recover(); r != nil {
+			crash.Panic(config.CrashDir(), r)
+			writeLine(fallbackLine)
+			code = 1
+		}
+	}()
+
+	if err := run(ctx); err != nil {
+		// Log error to stderr for debugging; input errors are multi-line
+		// explanations meant for users wiring up the statusLine hook
+		var inputErr *status.InputError
+		if errors.As(err, &inputErr) {
+			fmt.Fprintln(os.Stderr, inputErr)
+		} else {
+			slog.Error("error", "err", err)
+		}
 		// Graceful degradation - output minimal status
-		fmt.Println("\033[31m[Claude] 📁 Unknown\033[0m")
+		writeLine(fallbackLine)
 		return 1
 	}
 
 	return 0
 }
 
-func run() error {
-	// Load configuration
-	cfg := config.Load()
+// watchdogGrace is how long a render may run past its time budget (the
+// render deadline plus every hook's time limit) before the watchdog gives up.
+const watchdogGrace = 3 * time.Second
+
+// watchdogMax bounds the budget when there is no render deadline.
+const watchdogMax = 30 * time.Second
 
+// watchdogTimeout returns how long a render with cfg may take.
+func watchdogTimeout(cfg config.Config) time.Duration {
+	if cfg.RenderDeadline <= 0 {
+		return watchdogMax
+	}
+	hooks := time.Duration(len(cfg.PreRenderHooks)+len(cfg.PostRenderHooks)) * hookTimeout(cfg)
+	return time.Duration(cfg.RenderDeadline)*time.Millisecond + hooks + watchdogGrace
+}
+
+// startWatchdog exits the process after timeout, printing the fallback line
+// if no line was printed yet, with a crash report showing where it hung
+// (e.g. a stuck git command or lock). Stop the returned timer when done.
+func startWatchdog(timeout time.Duration) *time.Timer {
+	return time.AfterFunc(timeout, func() {
+		path, err := crash.Write(config.CrashDir(), fmt.Sprintf("watchdog: render still running after %s", timeout))
+		slog.Error("render hung, exiting", "timeout", timeout, "report", path, "report_err", err)
+		writeLine(fallbackLine)
+		os.Exit(1)
+	})
+}
+
+func run(ctx context.Context) error {
 	var input status.Input
+	var rawInput []byte
 
 	if *testFlag {
 		// Test mode: use current directory
@@ -68,61 +187,251 @@ func run() error {
 		}
 	} else {
 		// Parse input from stdin
-		if err := json.NewDecoder(os.Stdin).Decode(&input); err != nil {
-			return fmt.Errorf("failed to parse input: %w", err)
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		input, err = status.ParseInput(data)
+		if err != nil {
+			return err
 		}
+		rawInput = data
 	}
 
-	// Build status data
-	builder, err := status.NewBuilder(&cfg, input.Workspace.CurrentDir)
-	if err != nil {
-		return fmt.Errorf("failed to create builder: %w", err)
+	// Load configuration, including the project's own config file
+	cfg := config.Load(input.Workspace.CurrentDir)
+	if *formatFlag != "" {
+		cfg.OutputFormat = *formatFlag
+	}
+	defer startWatchdog(watchdogTimeout(cfg)).Stop()
+
+	// Read the cache file once, on first use, and write it once on the way out
+	cacheManager := cache.NewManager(config.CacheDir())
+	if err := cacheManager.EnsureDir(); err != nil {
+		return err
+	}
+	cacheManager.Batch()
+	defer cacheManager.Flush()
+
+	// Within min_render_interval_ms of the last render, or memoize_window_ms
+	// of one from the very same input, repeat its line
+	var renders *cache.Manager
+	inputHash := hashInput(rawInput)
+	if rawInput != nil && (cfg.MinRenderInterval > 0 || cfg.MemoizeWindow > 0) {
+		renders = cacheManager
+		key := renderKey(cfg, input)
+		interval := time.Duration(cfg.MinRenderInterval) * time.Millisecond
+		if output, ok := renders.RecentRender(key, interval); ok {
+			writeLine(output)
+			return nil
+		}
+		window := time.Duration(cfg.MemoizeWindow) * time.Millisecond
+		if output, ok := renders.RepeatedRender(key, inputHash, window); ok {
+			writeLine(output)
+			return nil
+		}
+	}
+
+	// A running daemon answers from warm caches; fall back to a one-shot
+	// render if there isn't one
+	if rawInput != nil {
+		if output, ok := renderViaDaemon(cfg, rawInput); ok {
+			printStatusLine(cfg, input, output)
+			recordRender(renders, cfg, input, inputHash, output)
+			return nil
+		}
 	}
 
+	// Build status data
+	builder := status.NewBuilderWithCache(ctx, &cfg, cacheManager, input.Workspace.CurrentDir)
+	// Let fetches past their soft deadline finish before the cache is flushed
+	defer builder.Wait()
+
 	// Set prefix if provided
 	if *prefixFlag != "" {
 		builder.SetPrefix(*prefixFlag)
+		builder.SetPrefixColor(prefixColorCode(*prefixColorFlag))
+	}
 
-		// Set prefix color (default to cyan if not specified)
-		colorName := *prefixColorFlag
-		if colorName == "" {
-			colorName = "cyan"
-		}
-		colorCode, ok := template.ColorMap[colorName]
-		if !ok {
-			slog.Warn("unknown prefix color, using cyan", "color", colorName)
-			colorCode = template.ColorMap["cyan"]
-		}
-		builder.SetPrefixColor(colorCode)
+	start := time.Now()
+	data, timings := builder.BuildWithOptions(ctx, input, status.BuildOptions{
+		// Serve a stale GitHub status now and refresh it after we exit
+		Revalidate: func() {
+			revalidateInBackground(hookInput{
+				Cwd:            input.Workspace.CurrentDir,
+				SessionID:      input.SessionID,
+				TranscriptPath: input.TranscriptPath,
+			})
+		},
+	})
+	renderhook.Run(ctx, renderhook.PreRender, cfg.PreRenderHooks, data, "", hookTimeout(cfg))
+	output, err := render(cfg, data)
+	if err != nil {
+		return err
 	}
 
-	data := builder.Build(input)
+	printStatusLine(cfg, input, output)
+	recordRender(renders, cfg, input, inputHash, output)
+	writeMirror(cfg, data, output)
+	elapsed := time.Since(start)
+	recordTelemetry(cfg, elapsed, timings)
+	writeMetrics(cfg, input.SessionID, data, elapsed)
+	runPostRenderHooks(ctx, cfg, data, output)
+	return nil
+}
+
+// runPostRenderHooks runs the post-render hooks of a one-shot render. The
+// line is already printed, but Claude Code waits for the process to exit, so
+// together they get only as long as one hook may take.
+func runPostRenderHooks(ctx context.Context, cfg config.Config, data template.StatusData, output string) {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout(cfg))
+	defer cancel()
+	renderhook.Run(ctx, renderhook.PostRender, cfg.PostRenderHooks, data, output, hookTimeout(cfg))
+}
+
+// renderKey identifies the renders min_render_interval_ms and
+// memoize_window_ms compare: those for the same session, directory, and
+// output format.
+func renderKey(cfg config.Config, input status.Input) string {
+	return strings.Join([]string{input.SessionID, input.Workspace.CurrentDir, cfg.OutputFormat}, "|")
+}
 
-	// Render template
-	engine, err := template.NewEngine(cfg.Template)
+// hashInput returns a hash identifying the raw stdin payload.
+func hashInput(rawInput []byte) string {
+	sum := sha256.Sum256(rawInput)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordRender saves output for throttling and memoizing later renders, if
+// renders is set.
+func recordRender(renders *cache.Manager, cfg config.Config, input status.Input, inputHash, output string) {
+	if renders != nil {
+		renders.RecordRender(renderKey(cfg, input), inputHash, output)
+	}
+}
+
+// writeMirror copies the render to cfg's mirror file, if one is set.
+func writeMirror(cfg config.Config, data template.StatusData, output string) {
+	if cfg.MirrorPath == "" {
+		return
+	}
+	if err := mirror.Write(cfg.MirrorPath, cfg.MirrorFormat, data, output); err != nil {
+		slog.Warn("failed to write status mirror", "path", cfg.MirrorPath, "err", err)
+	}
+}
+
+// writeMetrics writes session's render metrics next to cfg's metrics file,
+// if one is set.
+func writeMetrics(cfg config.Config, session string, data template.StatusData, elapsed time.Duration) {
+	if cfg.MetricsPath == "" {
+		return
+	}
+	if err := metrics.Write(cfg.MetricsPath, session, data, elapsed); err != nil {
+		slog.Warn("failed to write metrics", "path", cfg.MetricsPath, "err", err)
+	}
+}
+
+// hookTimeout returns how long each of cfg's render hooks may run.
+func hookTimeout(cfg config.Config) time.Duration {
+	return cmp.Or(time.Duration(cfg.RenderHookTimeout)*time.Millisecond, renderhook.DefaultTimeout)
+}
+
+// recordTelemetry adds a render to the local telemetry file, if enabled.
+func recordTelemetry(cfg config.Config, total time.Duration, timings []status.SegmentTiming) {
+	if !cfg.Telemetry {
+		return
+	}
+	segments := make([]telemetry.Segment, len(timings))
+	for i, t := range timings {
+		segments[i] = telemetry.Segment(t)
+	}
+	telemetry.NewStore(config.TelemetryPath()).Record(total, segments)
+}
+
+// renderViaDaemon asks a running daemon to render the status line for the
+// raw stdin payload. Returns false if no daemon answered.
+func renderViaDaemon(cfg config.Config, rawInput []byte) (string, bool) {
+	req := daemon.Request{Input: rawInput, Prefix: *prefixFlag, PrefixColor: *prefixColorFlag, Format: *formatFlag}
+	// Allow the daemon its full render deadline; a missing daemon fails at dial
+	timeout := time.Duration(cfg.RenderDeadline)*time.Millisecond + time.Second
+	output, err := daemon.Render(config.SocketPath(), req, timeout)
+	if err != nil {
+		slog.Debug("daemon unavailable, rendering directly", "err", err)
+		return "", false
+	}
+	return output, true
+}
+
+// prefixColorCode resolves a -prefix-color name to its ANSI code,
+// defaulting to cyan.
+func prefixColorCode(colorName string) string {
+	if colorName == "" {
+		colorName = "cyan"
+	}
+	colorCode, ok := template.ColorCode(colorName)
+	if !ok {
+		slog.Warn("unknown prefix color, using cyan", "color", colorName)
+		colorCode = template.ColorMap["cyan"]
+	}
+	return colorCode
+}
+
+// render formats data for output. The "line" format renders the configured
+// template, falling back to the default template if the configured one is
+// invalid; the "json" format emits data itself.
+func render(cfg config.Config, data template.StatusData) (string, error) {
+	switch cfg.OutputFormat {
+	case config.FormatJSON:
+		out, err := json.Marshal(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode status data: %w", err)
+		}
+		return string(out), nil
+	case config.FormatLine, "":
+	default:
+		return "", fmt.Errorf("unknown output format %q (want %q or %q)", cfg.OutputFormat, config.FormatLine, config.FormatJSON)
+	}
+
+	palette := cfg.Palette(theme.DefaultEnv(config.BackgroundPath()))
+	engine, err := template.Compile(cfg.Template, cfg.IconSymbols(), palette)
 	if err != nil {
 		// Log the template error and fall back to default
 		slog.Warn("invalid template, using default", "err", err)
-		engine, err = template.NewEngine(config.DefaultTemplate)
+		engine, err = template.Compile(config.DefaultTemplate, cfg.IconSymbols(), palette)
 		if err != nil {
-			return fmt.Errorf("failed to create template engine: %w", err)
+			return "", fmt.Errorf("failed to create template engine: %w", err)
 		}
 	}
 
 	output, err := engine.Render(data)
 	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return fitWidth(output, cfg.MaxWidth), nil
+}
+
+// fitWidth cuts each line of output to maxWidth terminal columns, or leaves
+// it alone if maxWidth is 0.
+func fitWidth(output string, maxWidth int) string {
+	if maxWidth <= 0 {
+		return output
 	}
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = template.TruncateWidth(line, maxWidth)
+	}
+	return strings.Join(lines, "\n")
+}
 
+// printStatusLine writes the rendered line to stdout and optionally logs it.
+func printStatusLine(cfg config.Config, input status.Input, output string) {
 	// Output the status line
-	fmt.Println(output)
+	writeLine(output)
 
 	// Optional logging
 	if cfg.LoggingEnabled {
 		logStatusLine(cfg, input, output)
 	}
-
-	return nil
 }
 
 // LogEntry represents a log entry in the status line log.
@@ -132,43 +441,31 @@ type LogEntry struct {
 	StatusLineOutput string       `json:"status_line_output"`
 }
 
+// logStatusLine records the render in the status line log. Entries reach the
+// log file at most once per statuslog.FlushInterval.
 func logStatusLine(cfg config.Config, input status.Input, output string) {
-	logPath := cfg.LogPath
-	if logPath == "" {
-		logPath = config.LogPath()
-	}
-
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
-		slog.Error("failed to create log directory", "err", err)
-		return
-	}
-
-	// Read existing log data
-	var logData []LogEntry
-	if data, err := os.ReadFile(logPath); err == nil {
-		if err := json.Unmarshal(data, &logData); err != nil {
-			// Log file corrupted, start fresh
-			slog.Warn("log file corrupted, starting fresh", "err", err)
-			logData = nil
-		}
-	}
-
-	// Append new entry
-	logData = append(logData, LogEntry{
+	err := statuslog.New(statusLogPath(cfg)).Append(LogEntry{
 		Timestamp:        time.Now().Format(time.RFC3339),
 		InputData:        input,
 		StatusLineOutput: output,
 	})
-
-	// Write back
-	data, err := json.MarshalIndent(logData, "", "  ")
 	if err != nil {
-		slog.Error("failed to marshal log data", "err", err)
-		return
+		slog.Error("failed to write log", "err", err)
 	}
+}
 
-	if err := os.WriteFile(logPath, data, 0644); err != nil {
-		slog.Error("failed to write log file", "err", err)
+// flushStatusLog folds the entries renders left spooled into the status
+// line log, if logging is enabled.
+func flushStatusLog(cfg config.Config) {
+	if !cfg.LoggingEnabled {
+		return
 	}
+	if err := statuslog.New(statusLogPath(cfg)).Flush(); err != nil {
+		slog.Warn("failed to flush log", "err", err)
+	}
+}
+
+// statusLogPath returns the status line log cfg writes to.
+func statusLogPath(cfg config.Config) string {
+	return cmp.Or(cfg.LogPath, config.LogPath())
 }
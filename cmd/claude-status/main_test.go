@@ -1,11 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/template"
 )
 
 func TestMain_ExitNonZeroOnError(t *testing.T) {
@@ -36,4 +41,132 @@ func TestMain_ExitNonZeroOnError(t *testing.T) {
 	if !strings.Contains(string(out), "[Claude]") {
 		t.Fatalf("expected fallback output to contain [Claude], got: %s", string(out))
 	}
+	if !strings.Contains(string(out), "invalid status line input") || !strings.Contains(string(out), `received (14 bytes): "not valid json"`) {
+		t.Fatalf("expected structured input error on stderr, got: %s", string(out))
+	}
+}
+
+func TestMain_MinRenderInterval(t *testing.T) {
+	render, _ := buildRenderer(t, `{"template": "{{.Model}}", "min_render_interval_ms": 60000}`)
+
+	if got := render("s1", "Opus"); got != "Opus" {
+		t.Fatalf("first render = %q, want Opus", got)
+	}
+	if got := render("s1", "Sonnet"); got != "Opus" {
+		t.Errorf("render within the interval = %q, want the previous line", got)
+	}
+	if got := render("s2", "Sonnet"); got != "Sonnet" {
+		t.Errorf("render for another session = %q, want a fresh line", got)
+	}
+}
+
+func TestMain_MemoizeWindow(t *testing.T) {
+	render, configPath := buildRenderer(t, `{"template": "{{.Model}}", "memoize_window_ms": 60000}`)
+
+	if got := render("s1", "Opus"); got != "Opus" {
+		t.Fatalf("first render = %q, want Opus", got)
+	}
+	if got := render("s1", "Sonnet"); got != "Sonnet" {
+		t.Errorf("render of new input = %q, want a fresh line", got)
+	}
+
+	// The same stdin again repeats the memoized line even though the
+	// template changed, so nothing was rendered
+	if err := os.WriteFile(configPath, []byte(`{"template": "changed", "memoize_window_ms": 60000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := render("s1", "Sonnet"); got != "Sonnet" {
+		t.Errorf("render of the same input = %q, want the memoized line", got)
+	}
+	if got := render("s1", "Opus"); got != "changed" {
+		t.Errorf("render of other input = %q, want a fresh line", got)
+	}
+}
+
+// buildRenderer builds the binary with cfg as its user config and returns a
+// function that runs it on a minimal stdin payload for a session and model,
+// returning the printed line, along with the config file's path.
+func buildRenderer(t *testing.T, cfg string) (func(session, model string) string, string) {
+	t.Helper()
+	tmp := t.TempDir()
+	configDir := filepath.Join(tmp, "config", "claude-status")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(configDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := filepath.Join(tmp, "claude-status")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	return func(session, model string) string {
+		t.Helper()
+		cmd := exec.Command(bin)
+		cmd.Env = append(os.Environ(),
+			"XDG_CACHE_HOME="+filepath.Join(tmp, "cache"),
+			"XDG_CONFIG_HOME="+filepath.Join(tmp, "config"),
+			"XDG_DATA_HOME="+filepath.Join(tmp, "data"),
+			"XDG_RUNTIME_DIR="+filepath.Join(tmp, "run"),
+		)
+		cmd.Stdin = strings.NewReader(`{"session_id": "` + session + `", "model": {"display_name": "` + model + `"}, "workspace": {"current_dir": "` + tmp + `"}}`)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("render: %v\n%s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}, configPath
+}
+
+func TestRender_Formats(t *testing.T) {
+	data := template.StatusData{Model: "Opus", GitBranch: "main"}
+
+	cfg := config.Default()
+	cfg.Template = `{{.Model}}@{{.GitBranch}}`
+	line, err := render(cfg, data)
+	if err != nil || line != "Opus@main" {
+		t.Errorf("render(line) = %q, %v; want %q", line, err, "Opus@main")
+	}
+
+	cfg.Template = "{{.Model}} on {{.GitBranch}}\n{{.GitBranch}}"
+	cfg.MaxWidth = 6
+	if line, err := render(cfg, data); err != nil || line != "Opus …\nmain" {
+		t.Errorf("render(max_width) = %q, %v; want each line cut to 6 columns", line, err)
+	}
+
+	cfg.OutputFormat = config.FormatJSON
+	out, err := render(cfg, data)
+	if err != nil {
+		t.Fatalf("render(json) error = %v", err)
+	}
+	var decoded template.StatusData
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("render(json) output is not JSON: %v\n%s", err, out)
+	}
+	if decoded.Model != "Opus" || decoded.GitBranch != "main" {
+		t.Errorf("decoded = %+v, want Model and GitBranch round-tripped", decoded)
+	}
+
+	cfg.OutputFormat = "xml"
+	if _, err := render(cfg, data); err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("render(xml) error = %v, want unknown output format", err)
+	}
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.RenderDeadline = 2000
+	cfg.RenderHookTimeout = 500
+	cfg.PreRenderHooks = []string{"a"}
+	cfg.PostRenderHooks = []string{"b", "c"}
+	if got, want := watchdogTimeout(cfg), 3500*time.Millisecond+watchdogGrace; got != want {
+		t.Errorf("watchdogTimeout() = %v, want %v", got, want)
+	}
+
+	cfg.RenderDeadline = 0
+	if got := watchdogTimeout(cfg); got != watchdogMax {
+		t.Errorf("watchdogTimeout() without a deadline = %v, want %v", got, watchdogMax)
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/kostyay/claude-status/internal/beads"
 	"github.com/kostyay/claude-status/internal/cache"
+	"github.com/kostyay/claude-status/internal/cistatus"
 	"github.com/kostyay/claude-status/internal/config"
 	"github.com/kostyay/claude-status/internal/github"
 	"github.com/kostyay/claude-status/internal/status"
@@ -93,7 +95,7 @@ func TestE2E_FullFlow(t *testing.T) {
 	cacheManager := cache.NewManager(cacheDir)
 	cacheManager.EnsureDir()
 
-	builder, err := status.NewBuilder(&cfg, gitDir)
+	builder, err := status.NewBuilder(t.Context(), &cfg, gitDir)
 	if err != nil {
 		t.Fatalf("NewBuilder() error = %v", err)
 	}
@@ -104,7 +106,7 @@ func TestE2E_FullFlow(t *testing.T) {
 		Version:   "1.0.0",
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Verify data
 	if data.Model != "Claude" {
@@ -169,13 +171,13 @@ func TestE2E_RealGitRepo(t *testing.T) {
 	cacheManager := cache.NewManager(cacheDir)
 	cacheManager.EnsureDir()
 
-	builder, _ := status.NewBuilder(&cfg, gitDir)
+	builder, _ := status.NewBuilder(t.Context(), &cfg, gitDir)
 	input := status.Input{
 		Model:     status.ModelInfo{DisplayName: "Claude"},
 		Workspace: status.WorkspaceInfo{CurrentDir: gitDir},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.GitBranch != "feature/test-branch" {
 		t.Errorf("GitBranch = %q, want %q", data.GitBranch, "feature/test-branch")
@@ -227,14 +229,14 @@ func TestE2E_CachingPerformance(t *testing.T) {
 
 	// First call - populates cache
 	start1 := time.Now()
-	builder1, _ := status.NewBuilder(&cfg, gitDir)
-	builder1.Build(input)
+	builder1, _ := status.NewBuilder(t.Context(), &cfg, gitDir)
+	builder1.Build(t.Context(), input)
 	duration1 := time.Since(start1)
 
 	// Second call - should use cache
 	start2 := time.Now()
-	builder2, _ := status.NewBuilder(&cfg, gitDir)
-	builder2.Build(input)
+	builder2, _ := status.NewBuilder(t.Context(), &cfg, gitDir)
+	builder2.Build(t.Context(), input)
 	duration2 := time.Since(start2)
 
 	// Cache hit should be faster (though both might be fast)
@@ -338,7 +340,7 @@ func TestE2E_GracefulDegradation(t *testing.T) {
 	cacheManager.EnsureDir()
 
 	// Use a non-git directory
-	builder, _ := status.NewBuilder(&cfg, tmpDir)
+	builder, _ := status.NewBuilder(t.Context(), &cfg, tmpDir)
 
 	input := status.Input{
 		Model:     status.ModelInfo{DisplayName: "Claude"},
@@ -346,7 +348,7 @@ func TestE2E_GracefulDegradation(t *testing.T) {
 		Version:   "1.0.0",
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Should still have model, dir, and version
 	if data.Model != "Claude" {
@@ -377,7 +379,7 @@ func TestE2E_Logging(t *testing.T) {
 
 	cfg := config.Config{
 		Template:       config.DefaultTemplate,
-		GitHubWorkflow: "build_and_test",
+		GitHubWorkflow: config.Workflows{"build_and_test"},
 		GitHubTTL:      60,
 		LoggingEnabled: true,
 		LogPath:        logPath,
@@ -440,7 +442,7 @@ func TestE2E_GitHubStatus_Success(t *testing.T) {
 	}
 	client.SetBaseURL(server.URL)
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -448,7 +450,7 @@ func TestE2E_GitHubStatus_Success(t *testing.T) {
 		t.Errorf("status = %q, want success", status)
 	}
 
-	emoji := github.StatusToEmoji(status)
+	emoji := cistatus.Symbol(status, nil)
 	if emoji != "✅" {
 		t.Errorf("emoji = %q, want ✅", emoji)
 	}
@@ -459,7 +461,7 @@ type mockBeadsCommander struct {
 	output string
 }
 
-func (m *mockBeadsCommander) Output(name string, args ...string) ([]byte, error) {
+func (m *mockBeadsCommander) Output(_ context.Context, name string, args ...string) ([]byte, error) {
 	return []byte(m.output), nil
 }
 
@@ -519,7 +521,7 @@ func TestE2E_BeadsClient(t *testing.T) {
 	cmd := &mockBeadsCommander{output: mockOutput}
 	client := beads.NewClientWithCommander(cmd, "/test")
 
-	stats, err := client.GetStats()
+	stats, err := client.GetStats(t.Context())
 	if err != nil {
 		t.Fatalf("GetStats() error = %v", err)
 	}
@@ -683,7 +685,7 @@ func TestE2E_BeadsRealCLI(t *testing.T) {
 		t.Fatal("Available() = false, want true")
 	}
 
-	stats, err := client.GetStats()
+	stats, err := client.GetStats(t.Context())
 	if err != nil {
 		t.Fatalf("GetStats() error = %v", err)
 	}
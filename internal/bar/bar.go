@@ -0,0 +1,116 @@
+// Package bar summarizes a status for desktop bars like SketchyBar and
+// Übersicht: an icon, a short label, and a color for the overall state.
+package bar
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kostyay/claude-status/internal/cistatus"
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// DefaultLabel is the template for Item.Label: the directory, branch,
+// changed files, and usable context, e.g. "claude-status main ±3 42%".
+const DefaultLabel = `{{.Dir}}{{if .GitBranch}} {{.GitBranch}}{{end}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{if .ContextLength}} {{printf "%.0f" .ContextPctUse}}%{{end}}`
+
+// States of an Item, from best to worst.
+const (
+	StateOK      = "ok"
+	StateWarning = "warning" // Needs attention soon: CI running, context filling up, a rebase in progress
+	StateError   = "error"   // Needs attention now: CI failing, merge conflicts
+)
+
+// Colors maps each state to its "#rrggbb" color.
+var Colors = map[string]string{
+	StateOK:      "#2ecc71",
+	StateWarning: "#f1c40f",
+	StateError:   "#e74c3c",
+}
+
+// ContextWarnPct is the usable context percentage at which the state
+// becomes a warning, as auto-compact approaches.
+const ContextWarnPct = 80
+
+// defaultIcon is shown when there is no CI status to show instead.
+const defaultIcon = "●"
+
+// Item is what "claude-status bar" prints.
+type Item struct {
+	Icon      string              `json:"icon"`              // CI status symbol, or a dot to color outside GitHub
+	Label     string              `json:"label"`             // Rendered label, without colors
+	State     string              `json:"state"`             // StateOK, StateWarning, or StateError
+	Color     string              `json:"color"`             // State color as "#rrggbb", for Übersicht and CSS
+	ColorARGB string              `json:"color_argb"`        // State color as "0xffrrggbb", for SketchyBar
+	Reasons   []string            `json:"reasons,omitempty"` // Why the state isn't ok, e.g. "CI failing"
+	Status    template.StatusData `json:"status"`            // Every field, for scripts that want more
+}
+
+// New summarizes data with the rendered label. symbols are the configured
+// icon overrides, used to recognize the CI status symbol.
+func New(data template.StatusData, label string, symbols map[string]string) Item {
+	state, reasons := assess(data, symbols)
+	color := Colors[state]
+	item := Item{
+		Icon:      data.GitHubStatus,
+		Label:     label,
+		State:     state,
+		Color:     color,
+		ColorARGB: "0xff" + strings.TrimPrefix(color, "#"),
+		Reasons:   reasons,
+		Status:    data,
+	}
+	if item.Icon == "" {
+		item.Icon = defaultIcon
+	}
+	return item
+}
+
+// assess returns the worst state data is in and every reason for it.
+func assess(data template.StatusData, symbols map[string]string) (string, []string) {
+	var errs, warnings []string
+
+	switch data.GitHubStatus {
+	case "":
+	case cistatus.Symbol(cistatus.Failure, symbols):
+		errs = append(errs, "CI failing")
+	case cistatus.Symbol(cistatus.Pending, symbols):
+		warnings = append(warnings, "CI running")
+	}
+	if data.GitConflicts > 0 {
+		errs = append(errs, fmt.Sprintf("%d conflicted %s", data.GitConflicts, plural(data.GitConflicts, "file", "files")))
+	}
+
+	if data.ContextPctUse >= ContextWarnPct {
+		warnings = append(warnings, fmt.Sprintf("context %.0f%% full", data.ContextPctUse))
+	}
+	if data.GitOperation != "" {
+		warnings = append(warnings, data.GitOperation)
+	}
+	if data.ProtectedBranchDirty {
+		warnings = append(warnings, "uncommitted changes on "+data.GitBranch)
+	}
+	if data.ForcePushNeeded {
+		warnings = append(warnings, "diverged from upstream")
+	}
+	if data.InstallWarning != "" {
+		warnings = append(warnings, data.InstallWarning)
+	}
+
+	switch {
+	case len(errs) > 0:
+		return StateError, append(errs, warnings...)
+	case len(warnings) > 0:
+		return StateWarning, warnings
+	default:
+		return StateOK, nil
+	}
+}
+
+// plural picks singular when n is 1.
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
@@ -0,0 +1,92 @@
+package bar
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+func TestNew_States(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        template.StatusData
+		symbols     map[string]string
+		wantState   string
+		wantReasons []string
+	}{
+		{"clean", template.StatusData{GitHubStatus: "✅", ContextPctUse: 40}, nil, StateOK, nil},
+		{"ci failing", template.StatusData{GitHubStatus: "❌"}, nil, StateError, []string{"CI failing"}},
+		{"ci running", template.StatusData{GitHubStatus: "🔄"}, nil, StateWarning, []string{"CI running"}},
+		{"custom failure symbol", template.StatusData{GitHubStatus: "FAIL"}, map[string]string{"failure": "FAIL"}, StateError, []string{"CI failing"}},
+		{"context filling up", template.StatusData{ContextPctUse: 85.4}, nil, StateWarning, []string{"context 85% full"}},
+		{
+			"errors before warnings",
+			template.StatusData{GitConflicts: 2, GitOperation: "rebasing", GitHubStatus: "🔄"},
+			nil, StateError, []string{"2 conflicted files", "CI running", "rebasing"},
+		},
+		{
+			"protected branch",
+			template.StatusData{GitBranch: "main", ProtectedBranchDirty: true, ForcePushNeeded: true},
+			nil, StateWarning, []string{"uncommitted changes on main", "diverged from upstream"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := New(tt.data, "label", tt.symbols)
+			if item.State != tt.wantState || !slices.Equal(item.Reasons, tt.wantReasons) {
+				t.Errorf("New() state = %q %q, want %q %q", item.State, item.Reasons, tt.wantState, tt.wantReasons)
+			}
+			if item.Color != Colors[tt.wantState] {
+				t.Errorf("Color = %q, want %q", item.Color, Colors[tt.wantState])
+			}
+		})
+	}
+}
+
+func TestNew_Fields(t *testing.T) {
+	item := New(template.StatusData{Dir: "proj"}, "proj main", nil)
+	if item.Icon != defaultIcon {
+		t.Errorf("Icon without CI = %q, want %q", item.Icon, defaultIcon)
+	}
+	if item.ColorARGB != "0xff2ecc71" {
+		t.Errorf("ColorARGB = %q, want 0xff2ecc71", item.ColorARGB)
+	}
+
+	out, err := json.Marshal(New(template.StatusData{GitHubStatus: "✅"}, "proj main", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"icon", "label", "state", "color", "color_argb", "status"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("JSON has no %q field: %s", key, out)
+		}
+	}
+	if fields["icon"] != "✅" {
+		t.Errorf("icon = %v, want the CI symbol", fields["icon"])
+	}
+}
+
+func TestDefaultLabel(t *testing.T) {
+	engine, err := template.NewEngine(DefaultLabel)
+	if err != nil {
+		t.Fatalf("DefaultLabel doesn't parse: %v", err)
+	}
+	tests := []struct {
+		data template.StatusData
+		want string
+	}{
+		{template.StatusData{Dir: "proj"}, "proj"},
+		{template.StatusData{Dir: "proj", GitBranch: "main", GitStatus: "±3", ContextLength: 1000, ContextPctUse: 41.6}, "proj main ±3 42%"},
+	}
+	for _, tt := range tests {
+		if got, err := engine.Render(tt.data); err != nil || got != tt.want {
+			t.Errorf("Render() = %q, %v; want %q", got, err, tt.want)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 package beads
 
 import (
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/kostyay/claude-status/internal/tasks"
 )
@@ -19,6 +23,7 @@ type statsResponse struct {
 type Client struct {
 	cmd     tasks.Commander
 	workDir string
+	next    tasks.NextTaskOptions
 }
 
 // NewClient creates a new beads client for the given working directory.
@@ -52,8 +57,8 @@ func (c *Client) Available() bool {
 }
 
 // GetStats runs `bd stats --json` and returns the parsed stats.
-func (c *Client) GetStats() (tasks.Stats, error) {
-	output, err := c.cmd.Output("bd", "stats", "--json")
+func (c *Client) GetStats(ctx context.Context) (tasks.Stats, error) {
+	output, err := c.cmd.Output(ctx, "bd", "stats", "--json")
 	if err != nil {
 		return tasks.Stats{}, fmt.Errorf("failed to run bd stats: %w", err)
 	}
@@ -68,13 +73,21 @@ func (c *Client) GetStats() (tasks.Stats, error) {
 
 // Issue represents a beads issue from bd ready --json.
 type Issue struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Priority  int       `json:"priority"` // 0 is the highest
+	CreatedAt time.Time `json:"created_at"`
+	Labels    []string  `json:"labels"`
+}
+
+// SetNextTaskOptions sets how GetNextTask picks among ready issues.
+func (c *Client) SetNextTaskOptions(opts tasks.NextTaskOptions) {
+	c.next = opts
 }
 
 // GetNextTask returns the title of the next ready task, or empty if none.
-func (c *Client) GetNextTask() (string, error) {
-	output, err := c.cmd.Output("bd", "ready", "--json")
+func (c *Client) GetNextTask(ctx context.Context) (string, error) {
+	output, err := c.cmd.Output(ctx, "bd", "ready", "--json")
 	if err != nil {
 		return "", fmt.Errorf("failed to run bd ready: %w", err)
 	}
@@ -84,9 +97,38 @@ func (c *Client) GetNextTask() (string, error) {
 		return "", fmt.Errorf("failed to parse bd ready output: %w", err)
 	}
 
-	if len(issues) == 0 {
+	issue, ok := pickNext(issues, c.next)
+	if !ok {
 		return "", nil
 	}
+	return issue.Title, nil
+}
 
-	return issues[0].Title, nil
+// pickNext returns the ready issue opts selects from issues, which are in
+// bd's own order.
+func pickNext(issues []Issue, opts tasks.NextTaskOptions) (Issue, bool) {
+	if opts.Label != "" {
+		issues = slices.DeleteFunc(slices.Clone(issues), func(issue Issue) bool {
+			return !slices.Contains(issue.Labels, opts.Label)
+		})
+	}
+	if len(issues) == 0 {
+		return Issue{}, false
+	}
+
+	var compare func(a, b Issue) int
+	switch opts.Order {
+	case tasks.OrderPriority:
+		compare = func(a, b Issue) int {
+			return cmp.Or(cmp.Compare(a.Priority, b.Priority), a.CreatedAt.Compare(b.CreatedAt))
+		}
+	case tasks.OrderOldest:
+		compare = func(a, b Issue) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	case tasks.OrderNewest:
+		compare = func(a, b Issue) int { return b.CreatedAt.Compare(a.CreatedAt) }
+	default:
+		return issues[0], true
+	}
+	// MinFunc keeps the first of equal issues, so ties fall back to bd's order
+	return slices.MinFunc(issues, compare), true
 }
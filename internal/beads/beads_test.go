@@ -1,6 +1,7 @@
 package beads
 
 import (
+	"context"
 	"errors"
 	"os"
 	"testing"
@@ -14,7 +15,7 @@ type mockCommander struct {
 	err    error
 }
 
-func (m *mockCommander) Output(name string, args ...string) ([]byte, error) {
+func (m *mockCommander) Output(_ context.Context, name string, args ...string) ([]byte, error) {
 	return m.output, m.err
 }
 
@@ -74,7 +75,7 @@ func TestClient_GetStats(t *testing.T) {
 			cmd := &mockCommander{output: []byte(tt.output)}
 			client := NewClientWithCommander(cmd, "/test")
 
-			got, err := client.GetStats()
+			got, err := client.GetStats(t.Context())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetStats() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -92,7 +93,7 @@ func TestClient_GetStats_CommandError(t *testing.T) {
 	cmd := &mockCommander{err: errors.New("command failed")}
 	client := NewClientWithCommander(cmd, "/test")
 
-	_, err := client.GetStats()
+	_, err := client.GetStats(t.Context())
 	if err == nil {
 		t.Error("GetStats() expected error for command failure")
 	}
@@ -173,7 +174,7 @@ func TestClient_GetNextTask(t *testing.T) {
 			cmd := &mockCommander{output: []byte(tt.output)}
 			client := NewClientWithCommander(cmd, "/test")
 
-			got, err := client.GetNextTask()
+			got, err := client.GetNextTask(t.Context())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetNextTask() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -185,11 +186,50 @@ func TestClient_GetNextTask(t *testing.T) {
 	}
 }
 
+func TestClient_GetNextTask_Options(t *testing.T) {
+	output := `[
+		{"id": "a", "title": "Docs", "priority": 2, "created_at": "2025-03-02T10:00:00Z", "labels": ["docs"]},
+		{"id": "b", "title": "Old bug", "priority": 1, "created_at": "2025-01-05T10:00:00Z", "labels": ["bug"]},
+		{"id": "c", "title": "New bug", "priority": 1, "created_at": "2025-04-01T10:00:00Z", "labels": ["bug", "urgent"]},
+		{"id": "d", "title": "Idea", "priority": 3, "created_at": "2025-02-01T10:00:00Z"}
+	]`
+
+	tests := []struct {
+		name string
+		opts tasks.NextTaskOptions
+		want string
+	}{
+		{"default", tasks.NextTaskOptions{}, "Docs"},
+		{"first", tasks.NextTaskOptions{Order: tasks.OrderFirst}, "Docs"},
+		{"priority breaks ties by age", tasks.NextTaskOptions{Order: tasks.OrderPriority}, "Old bug"},
+		{"oldest", tasks.NextTaskOptions{Order: tasks.OrderOldest}, "Old bug"},
+		{"newest", tasks.NextTaskOptions{Order: tasks.OrderNewest}, "New bug"},
+		{"label", tasks.NextTaskOptions{Label: "urgent"}, "New bug"},
+		{"label with order", tasks.NextTaskOptions{Order: tasks.OrderNewest, Label: "bug"}, "New bug"},
+		{"no issue with label", tasks.NextTaskOptions{Label: "ui"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithCommander(&mockCommander{output: []byte(output)}, "/test")
+			client.SetNextTaskOptions(tt.opts)
+
+			got, err := client.GetNextTask(t.Context())
+			if err != nil {
+				t.Fatalf("GetNextTask() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetNextTask() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestClient_GetNextTask_CommandError(t *testing.T) {
 	cmd := &mockCommander{err: errors.New("command failed")}
 	client := NewClientWithCommander(cmd, "/test")
 
-	_, err := client.GetNextTask()
+	_, err := client.GetNextTask(t.Context())
 	if err == nil {
 		t.Error("GetNextTask() expected error for command failure")
 	}
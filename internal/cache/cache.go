@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/kostyay/claude-status/internal/git"
 	"github.com/kostyay/claude-status/internal/github"
 	"github.com/kostyay/claude-status/internal/tasks"
+	"github.com/kostyay/claude-status/internal/tokens"
 )
 
 // Clock is an interface for time operations, allowing for testing.
@@ -28,48 +30,72 @@ func (RealClock) Now() time.Time {
 	return time.Now()
 }
 
-// CachedValue holds a cached value with its invalidation metadata.
-type CachedValue struct {
-	Value     string    `json:"value"`
-	FileMtime int64     `json:"file_mtime"` // mtime in nanoseconds
-	CachedAt  time.Time `json:"cached_at"`
+// CachedBuildHistory holds recent completed build results for a branch,
+// oldest first.
+type CachedBuildHistory struct {
+	Results   []github.BuildStatus `json:"results"`
+	RefMtime  int64                `json:"ref_mtime"` // ref mtime when the last result was recorded
+	UpdatedAt time.Time            `json:"updated_at"`
+	Branch    string               `json:"branch"`
 }
 
-// CachedGitHubBuild holds cached GitHub build status.
-type CachedGitHubBuild struct {
-	Status    github.BuildStatus `json:"status"`
-	FileMtime int64              `json:"file_mtime"`
-	CachedAt  time.Time          `json:"cached_at"`
-	Branch    string             `json:"branch"`
+// maxBuildHistory is how many build results are kept per branch.
+const maxBuildHistory = 10
+
+// CachedContextHistory holds context length samples for a session, oldest first.
+type CachedContextHistory struct {
+	Samples   []int64   `json:"samples"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// CachedDiffStats holds cached git diff statistics.
-type CachedDiffStats struct {
-	Stats     git.DiffStats `json:"stats"`
-	FileMtime int64         `json:"file_mtime"`
-	CachedAt  time.Time     `json:"cached_at"`
+// CachedTranscript holds the token metrics of a transcript up to Offset, so
+// later renders only parse what was appended since.
+type CachedTranscript struct {
+	Metrics   tokens.Metrics `json:"metrics"`
+	Offset    int64          `json:"offset"` // bytes parsed
+	UpdatedAt time.Time      `json:"updated_at"`
 }
 
-// CachedTaskStats holds cached task statistics.
-type CachedTaskStats struct {
-	Stats    tasks.Stats `json:"stats"`
-	CachedAt time.Time   `json:"cached_at"`
+// maxContextSamples is how many context length samples are kept per session.
+const maxContextSamples = 20
+
+// CachedRender holds the last line rendered for a session, for
+// min_render_interval_ms throttling and memoize_window_ms memoization.
+type CachedRender struct {
+	Output     string    `json:"output"`
+	RenderedAt time.Time `json:"rendered_at"`
+	InputHash  string    `json:"input_hash,omitempty"` // Hash of the stdin the line was rendered from
 }
 
-// CachedNextTask holds cached next task title.
-type CachedNextTask struct {
-	Title    string    `json:"title"`
+// upstreamTTL bounds how long ahead/behind counts are trusted. A push moves
+// the remote-tracking ref without touching the branch ref or FETCH_HEAD, so
+// mtimes alone would miss it.
+const upstreamTTL = 30 * time.Second
+
+// gitSummaryTTL bounds how long a summary is trusted. Editing or creating
+// files in the working tree doesn't touch the index or HEAD.
+const gitSummaryTTL = 10 * time.Second
+
+// installCheckTTL bounds how long an install check is trusted. Moving or
+// deleting the binary doesn't touch settings.json, so its mtime alone would
+// miss it.
+const installCheckTTL = 10 * time.Minute
+
+// CachedRateLimit records that a GitHub API host refuses requests until a
+// rate limit resets.
+type CachedRateLimit struct {
+	Until    time.Time `json:"until"`
 	CachedAt time.Time `json:"cached_at"`
 }
 
 // CacheFile is the structure of the cache file on disk.
 type CacheFile struct {
-	GitBranch    *CachedValue                `json:"git_branch,omitempty"`
-	GitStatus    *CachedValue                `json:"git_status,omitempty"`
-	GitDiffStats *CachedDiffStats            `json:"git_diff_stats,omitempty"`
-	GitHubBuild  *CachedGitHubBuild          `json:"github_build,omitempty"`
-	TaskStatsMap map[string]*CachedTaskStats `json:"task_stats_map,omitempty"` // keyed by workDir
-	NextTaskMap  map[string]*CachedNextTask  `json:"next_task_map,omitempty"`  // keyed by workDir
+	Entries      map[string]*CachedEntry          `json:"entries,omitempty"`              // values cached by Get, keyed by kind and key
+	BuildHistory map[string]*CachedBuildHistory   `json:"github_build_history,omitempty"` // keyed by refPath
+	RateLimits   map[string]*CachedRateLimit      `json:"github_rate_limits,omitempty"`   // keyed by GitHub host
+	ContextMap   map[string]*CachedContextHistory `json:"context_history,omitempty"`      // keyed by session
+	Transcripts  map[string]*CachedTranscript     `json:"transcripts,omitempty"`          // keyed by transcript path
+	Renders      map[string]*CachedRender         `json:"renders,omitempty"`              // keyed by session and directory
 }
 
 // Manager handles cache operations with file-based persistence.
@@ -78,9 +104,13 @@ type Manager struct {
 	cachePath   string
 	clock       Clock
 	mu          sync.RWMutex
+	lockMu      sync.Mutex // Serializes withFileLock across goroutines
 	fileLock    *flock.Flock
-	memCache    *CacheFile // In-memory cache to reduce disk I/O
-	cacheLoaded bool       // Whether memCache is populated
+	fileLocked  bool               // Whether fileLock is currently held (guarded by lockMu)
+	memCache    *CacheFile         // In-memory cache to reduce disk I/O
+	cacheLoaded bool               // Whether memCache is populated
+	batch       bool               // Whether updates wait for Flush (see Batch)
+	pending     []func(*CacheFile) // Updates made since the last Flush, oldest first (guarded by mu)
 }
 
 // NewManager creates a new cache manager.
@@ -99,379 +129,510 @@ func NewManagerWithClock(cacheDir string, clock Clock) *Manager {
 	}
 }
 
+// Batch makes m keep updates in memory until Flush instead of writing the
+// cache file after each one, and skip the file lock until then, so a
+// one-shot render reads and writes the file once. Flush replays the updates
+// onto the file as it is then, keeping what other processes wrote meanwhile.
+// Call it before using m.
+func (m *Manager) Batch() {
+	m.batch = true
+}
+
+// Flush writes the updates held since Batch to the cache file.
+func (m *Manager) Flush() {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.pending) == 0 {
+		return
+	}
+	m.lockFile()
+	defer m.unlockFile()
+
+	cache := m.read()
+	for _, fn := range m.pending {
+		fn(cache)
+	}
+	m.pending = nil
+	m.save(cache)
+}
+
 // EnsureDir creates the cache directory if it doesn't exist.
 func (m *Manager) EnsureDir() error {
 	return os.MkdirAll(m.cacheDir, 0755)
 }
 
 // withFileLock acquires an exclusive file lock before executing fn.
-// This ensures multi-process safety when multiple instances access the same cache.
+// This ensures multi-process safety when multiple instances access the same cache,
+// and goroutine safety when segments are fetched concurrently.
 // On lock timeout, it proceeds without locking (graceful degradation).
 func (m *Manager) withFileLock(fn func()) {
+	m.acquire()
+	defer m.release()
+
+	fn()
+}
+
+// acquire takes the in-process lock, then the cache file lock unless m is
+// batching, when the file is only touched by Flush.
+func (m *Manager) acquire() {
+	m.lockMu.Lock()
+	if !m.batch {
+		m.lockFile()
+	}
+}
+
+// release drops the locks taken by acquire.
+func (m *Manager) release() {
+	m.unlockFile()
+	m.lockMu.Unlock()
+}
+
+// lockFile takes the cache file lock. The caller holds lockMu.
+func (m *Manager) lockFile() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	locked, err := m.fileLock.TryLockContext(ctx, 100*time.Millisecond)
 	if err != nil || !locked {
 		slog.Warn("cache lock timeout, proceeding without lock", "err", err)
-		fn()
-		return
 	}
-	defer func() {
+	m.fileLocked = locked
+}
+
+// unlockFile drops the cache file lock, if held. The caller holds lockMu.
+func (m *Manager) unlockFile() {
+	if m.fileLocked {
 		_ = m.fileLock.Unlock()
-	}()
+		m.fileLocked = false
+	}
+}
 
-	fn()
+// fetchUnlocked calls fetchFn from inside withFileLock with the locks
+// released, so a slow fetch (e.g. a GitHub API call) doesn't block other
+// cache lookups. Callers re-check the cache after it returns.
+func fetchUnlocked[T any](m *Manager, fetchFn func() (T, error)) (T, error) {
+	m.release()
+	defer m.acquire()
+
+	return fetchFn()
 }
 
 // GetGitBranch returns the cached git branch or fetches it if the cache is invalid.
 func (m *Manager) GetGitBranch(headPath string, fetchFn func() (string, error)) (string, error) {
-	var result string
-	var resultErr error
+	return Get(m, "git_branch:"+headPath, FileMtime(headPath), fetchFn)
+}
 
-	m.withFileLock(func() {
-		// Get current file mtime
-		mtime, err := getFileMtime(headPath)
-		if err != nil {
-			// Can't stat file, just fetch
-			result, resultErr = fetchFn()
-			return
-		}
+// GetGitHeadName returns the cached name of a detached HEAD or fetches it if
+// the cache is invalid. Checking out another commit rewrites HEAD, so its
+// mtime invalidates the cache as it does for GetGitBranch.
+func (m *Manager) GetGitHeadName(headPath string, fetchFn func() (string, error)) (string, error) {
+	return Get(m, "git_head_name:"+headPath, FileMtime(headPath), fetchFn)
+}
 
-		// Check cache
-		m.mu.RLock()
-		cache := m.load()
-		m.mu.RUnlock()
+// GetGitStatus returns the cached git status or fetches it if the cache is invalid.
+func (m *Manager) GetGitStatus(indexPath string, fetchFn func() (string, error)) (string, error) {
+	// An index that can't be stat'ed (maybe no commits yet) is fetched every time
+	return Get(m, "git_status:"+indexPath, FileMtime(indexPath), fetchFn)
+}
 
-		if cache.GitBranch != nil && cache.GitBranch.FileMtime == mtime {
-			result = cache.GitBranch.Value
-			return
-		}
+// GetGitDiffStats returns the cached git diff stats or fetches them if the cache is invalid.
+func (m *Manager) GetGitDiffStats(indexPath string, fetchFn func() (git.DiffStats, error)) (git.DiffStats, error) {
+	return Get(m, "git_diff_stats:"+indexPath, FileMtime(indexPath), fetchFn)
+}
 
-		// Cache miss - fetch and store
-		value, err := fetchFn()
-		if err != nil {
-			resultErr = err
-			return
-		}
+// GetGitDiffSince returns the cached lines changed since base or fetches them
+// if the cache is invalid. Like GetGitDiffStats, the cache is invalidated if
+// the index mtime changes.
+func (m *Manager) GetGitDiffSince(indexPath, base string, fetchFn func() (git.LineStats, error)) (git.LineStats, error) {
+	return Get(m, "git_diff_since:"+indexPath+":"+base, FileMtime(indexPath), fetchFn)
+}
 
-		m.mu.Lock()
-		defer m.mu.Unlock()
+// GetGitUpstream returns cached ahead/behind counts or fetches them if invalid.
+// The cache is invalidated when the branch ref or FETCH_HEAD changes, or after
+// upstreamTTL.
+func (m *Manager) GetGitUpstream(refPath, fetchHeadPath string, fetchFn func() (git.UpstreamStatus, error)) (git.UpstreamStatus, error) {
+	// FETCH_HEAD is missing until the first fetch
+	return Get(m, "git_upstream:"+refPath, All(RefMtime(refPath), OptionalFileMtime(fetchHeadPath), TTL(upstreamTTL)), fetchFn)
+}
 
-		// Re-check cache after acquiring write lock (TOCTOU protection)
-		cache = m.load()
-		if cache.GitBranch != nil && cache.GitBranch.FileMtime == mtime {
-			result = cache.GitBranch.Value
-			return
-		}
+// GetInstallWarning returns the cached install drift warning for binaryPath or
+// re-checks the settings at settingsPath. The cache is invalidated when the
+// settings file changes, or after installCheckTTL.
+func (m *Manager) GetInstallWarning(settingsPath, binaryPath string, fetchFn func() (string, error)) (string, error) {
+	return Get(m, "install_check:"+settingsPath, All(Equals(binaryPath), OptionalFileMtime(settingsPath), TTL(installCheckTTL)), fetchFn)
+}
 
-		cache.GitBranch = &CachedValue{
-			Value:     value,
-			FileMtime: mtime,
-			CachedAt:  m.clock.Now(),
-		}
-		m.save(cache)
+// GetGitSummary returns the cached summary of the repository at gitDir or
+// fetches it if invalid. The cache is invalidated if the index or HEAD mtime
+// changes, or the TTL expires.
+func (m *Manager) GetGitSummary(gitDir string, fetchFn func() (git.Summary, error)) (git.Summary, error) {
+	// The index is missing before the first commit
+	validity := All(OptionalFileMtime(filepath.Join(gitDir, "index")), OptionalFileMtime(filepath.Join(gitDir, "HEAD")), TTL(gitSummaryTTL))
+	return Get(m, "git_summary:"+gitDir, validity, fetchFn)
+}
+
+// GetGitStashCount returns the cached stash entry count or fetches it if
+// invalid. The cache is invalidated if the stash reflog mtime changes; a
+// missing reflog (no stashes yet) is cached too, until one appears.
+func (m *Manager) GetGitStashCount(stashLogPath string, fetchFn func() (int, error)) (int, error) {
+	return Get(m, "git_stash_count:"+stashLogPath, OptionalFileMtime(stashLogPath), fetchFn)
+}
+
+// GetGitLastCommit returns the cached commit HEAD points at or fetches it if
+// invalid. The cache is invalidated if the HEAD or branch ref mtime changes:
+// checking out moves HEAD, while committing, amending, or resetting moves the
+// branch ref. Pass an empty refPath on a detached HEAD.
+func (m *Manager) GetGitLastCommit(headPath, refPath string, fetchFn func() (git.Commit, error)) (git.Commit, error) {
+	return Get(m, "git_last_commit:"+headPath, All(OptionalFileMtime(headPath), RefMtime(refPath)), fetchFn)
+}
+
+// GetGitCommitsSince returns the cached count of commits made since since or
+// fetches it if invalid. Like GetGitLastCommit, the cache is invalidated if
+// the HEAD or branch ref mtime changes. Pass an empty refPath on a detached
+// HEAD.
+func (m *Manager) GetGitCommitsSince(headPath, refPath string, since time.Time, fetchFn func() (int, error)) (int, error) {
+	validity := All(Equals(since.UTC().Format(time.RFC3339Nano)), OptionalFileMtime(headPath), RefMtime(refPath))
+	return Get(m, "git_commits_since:"+headPath, validity, fetchFn)
+}
 
-		result = value
+// GetGitHubBuild returns the cached GitHub build status or fetches it if invalid.
+// The cache is invalidated if either the ref mtime changes OR the TTL expires.
+// Entries are kept per ref path (i.e. per repository and branch), so switching
+// branches doesn't evict the other branch's status.
+func (m *Manager) GetGitHubBuild(refPath, branch string, ttl time.Duration, fetchFn func() (github.BuildStatus, error)) (github.BuildStatus, error) {
+	fetched := false
+	status, err := Get(m, "github_build:"+refPath, githubRefValidity(refPath, branch, ttl), func() (github.BuildStatus, error) {
+		fetched = true
+		return fetchFn()
 	})
+	if err != nil {
+		return github.StatusError, err
+	}
 
-	return result, resultErr
+	if fetched {
+		mtime := getRefMtime(refPath)
+		m.withFileLock(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			m.update(func(cache *CacheFile) {
+				m.recordBuild(cache, refPath, branch, status, mtime)
+			})
+		})
+	}
+	return status, nil
 }
 
-// GetGitStatus returns the cached git status or fetches it if the cache is invalid.
-func (m *Manager) GetGitStatus(indexPath string, fetchFn func() (string, error)) (string, error) {
-	var result string
-	var resultErr error
+// GitHubBuildAge returns how long ago the cached build status for branch was
+// fetched, or false if there is none or the ref has changed since, i.e. the
+// entry is invalid regardless of TTL.
+func (m *Manager) GitHubBuildAge(refPath, branch string) (time.Duration, bool) {
+	return m.Age("github_build:"+refPath, githubRefValidity(refPath, branch, 0))
+}
 
-	m.withFileLock(func() {
-		// Get current file mtime
-		mtime, err := getFileMtime(indexPath)
-		if err != nil {
-			// Can't stat file (maybe no commits yet), just fetch
-			result, resultErr = fetchFn()
-			return
-		}
+// githubRefValidity is how long a branch's GitHub entries are valid: until
+// the branch ref moves (a commit or push) or ttl expires.
+func githubRefValidity(refPath, branch string, ttl time.Duration) Invalidator {
+	return All(Equals(branch), RefMtime(refPath), TTL(ttl))
+}
+
+// GitHubRateLimit returns when the rate limit recorded for host resets, or
+// false if none is in effect.
+func (m *Manager) GitHubRateLimit(host string) (time.Time, bool) {
+	var until time.Time
+	var ok bool
 
-		// Check cache
+	m.withFileLock(func() {
 		m.mu.RLock()
-		cache := m.load()
-		m.mu.RUnlock()
+		defer m.mu.RUnlock()
 
-		if cache.GitStatus != nil && cache.GitStatus.FileMtime == mtime {
-			result = cache.GitStatus.Value
-			return
+		if entry, found := m.load().RateLimits[host]; found && m.clock.Now().Before(entry.Until) {
+			until, ok = entry.Until, true
 		}
+	})
 
-		// Cache miss - fetch and store
-		value, err := fetchFn()
-		if err != nil {
-			resultErr = err
-			return
-		}
+	return until, ok
+}
 
+// SetGitHubRateLimit records that host refuses requests until until, so
+// renders before then can skip asking.
+func (m *Manager) SetGitHubRateLimit(host string, until time.Time) {
+	m.withFileLock(func() {
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
-		// Re-check cache after acquiring write lock (TOCTOU protection)
-		cache = m.load()
-		if cache.GitStatus != nil && cache.GitStatus.FileMtime == mtime {
-			result = cache.GitStatus.Value
-			return
-		}
+		entry := &CachedRateLimit{Until: until, CachedAt: m.clock.Now()}
+		m.update(func(cache *CacheFile) {
+			if cache.RateLimits == nil {
+				cache.RateLimits = make(map[string]*CachedRateLimit)
+			}
+			cache.RateLimits[host] = entry
+		})
+	})
+}
 
-		cache.GitStatus = &CachedValue{
-			Value:     value,
-			FileMtime: mtime,
-			CachedAt:  m.clock.Now(),
-		}
-		m.save(cache)
+// recordBuild appends a completed build result to the branch's history.
+// Results are told apart by ref mtime and status: the same result for an
+// unchanged ref is the same run seen again on a TTL refresh, so it is not
+// recorded twice. Pending and error results are skipped.
+func (m *Manager) recordBuild(cache *CacheFile, refPath, branch string, status github.BuildStatus, mtime int64) {
+	if status != github.StatusSuccess && status != github.StatusFailure {
+		return
+	}
 
-		result = value
-	})
+	if cache.BuildHistory == nil {
+		cache.BuildHistory = make(map[string]*CachedBuildHistory)
+	}
+	history, ok := cache.BuildHistory[refPath]
+	if !ok || history.Branch != branch {
+		history = &CachedBuildHistory{Branch: branch}
+		cache.BuildHistory[refPath] = history
+	}
+	history.UpdatedAt = m.clock.Now()
 
-	return result, resultErr
+	if n := len(history.Results); n > 0 && history.Results[n-1] == status && history.RefMtime == mtime {
+		return
+	}
+	history.Results = append(history.Results, status)
+	if len(history.Results) > maxBuildHistory {
+		history.Results = history.Results[len(history.Results)-maxBuildHistory:]
+	}
+	history.RefMtime = mtime
 }
 
-// GetGitDiffStats returns the cached git diff stats or fetches them if the cache is invalid.
-func (m *Manager) GetGitDiffStats(indexPath string, fetchFn func() (git.DiffStats, error)) (git.DiffStats, error) {
-	var result git.DiffStats
-	var resultErr error
+// GetGitHubBuildHistory returns up to the last 10 completed build results
+// recorded for branch by GetGitHubBuild, oldest first.
+func (m *Manager) GetGitHubBuildHistory(refPath, branch string) []github.BuildStatus {
+	var result []github.BuildStatus
 
 	m.withFileLock(func() {
-		// Get current file mtime
-		mtime, err := getFileMtime(indexPath)
-		if err != nil {
-			// Can't stat file (maybe no commits yet), just fetch
-			result, resultErr = fetchFn()
-			return
-		}
-
-		// Check cache
 		m.mu.RLock()
-		cache := m.load()
-		m.mu.RUnlock()
+		defer m.mu.RUnlock()
 
-		if cache.GitDiffStats != nil && cache.GitDiffStats.FileMtime == mtime {
-			result = cache.GitDiffStats.Stats
+		history, ok := m.load().BuildHistory[refPath]
+		if !ok || history.Branch != branch {
 			return
 		}
+		result = slices.Clone(history.Results)
+	})
 
-		// Cache miss - fetch and store
-		stats, err := fetchFn()
-		if err != nil {
-			resultErr = err
-			return
-		}
+	return result
+}
 
+// RecordContextSample appends length to the session's context history and
+// returns the history, oldest first. A sample equal to the previous one is
+// not repeated, so re-rendering without a new turn leaves the history alone.
+func (m *Manager) RecordContextSample(session string, length int64) []int64 {
+	var result []int64
+
+	m.withFileLock(func() {
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
-		// Re-check cache after acquiring write lock (TOCTOU protection)
-		cache = m.load()
-		if cache.GitDiffStats != nil && cache.GitDiffStats.FileMtime == mtime {
-			result = cache.GitDiffStats.Stats
-			return
-		}
-
-		cache.GitDiffStats = &CachedDiffStats{
-			Stats:     stats,
-			FileMtime: mtime,
-			CachedAt:  m.clock.Now(),
+		if history := m.load().ContextMap[session]; !history.endsWith(length) {
+			now := m.clock.Now()
+			m.update(func(cache *CacheFile) {
+				addContextSample(cache, session, length, now)
+			})
 		}
-		m.save(cache)
 
-		result = stats
+		result = slices.Clone(m.load().ContextMap[session].Samples)
 	})
 
-	return result, resultErr
+	return result
 }
 
-// GetGitHubBuild returns the cached GitHub build status or fetches it if invalid.
-// The cache is invalidated if either the ref mtime changes OR the TTL expires.
-func (m *Manager) GetGitHubBuild(refPath, branch string, ttl time.Duration, fetchFn func() (github.BuildStatus, error)) (github.BuildStatus, error) {
-	var result github.BuildStatus
-	var resultErr error
+// endsWith reports whether length is the last sample in h, which may be nil.
+func (h *CachedContextHistory) endsWith(length int64) bool {
+	return h != nil && len(h.Samples) > 0 && h.Samples[len(h.Samples)-1] == length
+}
+
+// addContextSample appends length to the session's history in cache, unless
+// it is the last sample already.
+func addContextSample(cache *CacheFile, session string, length int64, now time.Time) {
+	if cache.ContextMap == nil {
+		cache.ContextMap = make(map[string]*CachedContextHistory)
+	}
+	history, ok := cache.ContextMap[session]
+	if !ok {
+		history = &CachedContextHistory{}
+		cache.ContextMap[session] = history
+	}
+	if history.endsWith(length) {
+		return
+	}
+
+	history.Samples = append(history.Samples, length)
+	if len(history.Samples) > maxContextSamples {
+		history.Samples = history.Samples[len(history.Samples)-maxContextSamples:]
+	}
+	history.UpdatedAt = now
+}
+
+// RecentRender returns the line last recorded for key if it was rendered
+// less than within ago.
+func (m *Manager) RecentRender(key string, within time.Duration) (string, bool) {
+	var output string
+	var ok bool
 
 	m.withFileLock(func() {
-		// Get current ref file mtime; fall back to packed-refs if branch ref file is packed.
-		mtime, err := getFileMtime(refPath)
-		if err != nil {
-			if packedMtime, packedErr := getPackedRefsMtime(refPath); packedErr == nil {
-				mtime = packedMtime
-			} else {
-				// Missing ref file entirely; use a sentinel so we still cache and rely on TTL.
-				mtime = 0
-			}
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		if render, found := m.load().Renders[key]; found && m.clock.Now().Sub(render.RenderedAt) < within {
+			output, ok = render.Output, true
 		}
+	})
 
-		// Check cache
-		m.mu.RLock()
-		cache := m.load()
-		m.mu.RUnlock()
+	return output, ok
+}
 
-		if cache.GitHubBuild != nil && cache.GitHubBuild.Branch == branch {
-			refMtimeMatches := cache.GitHubBuild.FileMtime == mtime
-			ttlValid := m.clock.Now().Sub(cache.GitHubBuild.CachedAt) < ttl
+// RepeatedRender returns the line last recorded for key if it was rendered
+// from the input with hash inputHash less than within ago.
+func (m *Manager) RepeatedRender(key, inputHash string, within time.Duration) (string, bool) {
+	var output string
+	var ok bool
 
-			if refMtimeMatches && ttlValid {
-				result = cache.GitHubBuild.Status
-				return
-			}
-		}
+	m.withFileLock(func() {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
 
-		// Cache miss - fetch and store
-		status, err := fetchFn()
-		if err != nil {
-			result = github.StatusError
-			resultErr = err
-			return
+		render, found := m.load().Renders[key]
+		if found && render.InputHash == inputHash && m.clock.Now().Sub(render.RenderedAt) < within {
+			output, ok = render.Output, true
 		}
+	})
 
+	return output, ok
+}
+
+// RecordRender records output, rendered from the input with hash inputHash,
+// as the line last rendered for key.
+func (m *Manager) RecordRender(key, inputHash, output string) {
+	m.withFileLock(func() {
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
-		// Re-check cache after acquiring write lock (TOCTOU protection)
-		cache = m.load()
-		if cache.GitHubBuild != nil && cache.GitHubBuild.Branch == branch {
-			refMtimeMatches := cache.GitHubBuild.FileMtime == mtime
-			ttlValid := m.clock.Now().Sub(cache.GitHubBuild.CachedAt) < ttl
-
-			if refMtimeMatches && ttlValid {
-				result = cache.GitHubBuild.Status
-				return
+		render := &CachedRender{Output: output, RenderedAt: m.clock.Now(), InputHash: inputHash}
+		m.update(func(cache *CacheFile) {
+			if cache.Renders == nil {
+				cache.Renders = make(map[string]*CachedRender)
 			}
-		}
-
-		cache.GitHubBuild = &CachedGitHubBuild{
-			Status:    status,
-			FileMtime: mtime,
-			CachedAt:  m.clock.Now(),
-			Branch:    branch,
-		}
-		m.save(cache)
-
-		result = status
+			cache.Renders[key] = render
+		})
 	})
-
-	return result, resultErr
 }
 
-// GetTaskStats returns cached task stats or fetches them if the cache is invalid.
-// The cache is invalidated when the TTL expires. Stats are cached per workDir.
-func (m *Manager) GetTaskStats(workDir string, ttl time.Duration, fetchFn func() (tasks.Stats, error)) (tasks.Stats, error) {
-	var result tasks.Stats
+// GetTranscriptMetrics returns the token metrics of the transcript at path,
+// parsing only what was appended since the last call. parseFn continues from
+// a byte offset given the metrics up to it (see tokens.ParseTranscriptFrom).
+func (m *Manager) GetTranscriptMetrics(path string, parseFn func(offset int64, prev tokens.Metrics) (tokens.Metrics, int64, error)) (tokens.Metrics, error) {
+	var result tokens.Metrics
 	var resultErr error
 
 	m.withFileLock(func() {
-		// Check cache
 		m.mu.RLock()
 		cache := m.load()
 		m.mu.RUnlock()
 
-		if cache.TaskStatsMap != nil {
-			if cached, ok := cache.TaskStatsMap[workDir]; ok {
-				ttlValid := m.clock.Now().Sub(cached.CachedAt) < ttl
-				if ttlValid {
-					result = cached.Stats
-					return
-				}
-			}
+		var start int64
+		var prev tokens.Metrics
+		if entry := cache.Transcripts[path]; entry != nil {
+			start, prev = entry.Offset, entry.Metrics
 		}
 
-		// Cache miss - fetch and store
-		stats, err := fetchFn()
-		if err != nil {
-			resultErr = err
+		// Parse unlocked: the first pass over a long transcript takes a while
+		var offset int64
+		result, resultErr = fetchUnlocked(m, func() (tokens.Metrics, error) {
+			metrics, end, err := parseFn(start, prev)
+			offset = end
+			return metrics, err
+		})
+		if resultErr != nil || offset == start {
 			return
 		}
 
 		m.mu.Lock()
 		defer m.mu.Unlock()
 
-		// Re-check cache after acquiring write lock (TOCTOU protection)
+		// Another render may have parsed further meanwhile; keep its result
 		cache = m.load()
-		if cache.TaskStatsMap != nil {
-			if cached, ok := cache.TaskStatsMap[workDir]; ok {
-				ttlValid := m.clock.Now().Sub(cached.CachedAt) < ttl
-				if ttlValid {
-					result = cached.Stats
-					return
-				}
-			}
+		if entry := cache.Transcripts[path]; entry != nil && entry.Offset != start && entry.Offset >= offset {
+			result = entry.Metrics
+			return
 		}
 
-		if cache.TaskStatsMap == nil {
-			cache.TaskStatsMap = make(map[string]*CachedTaskStats)
-		}
-		cache.TaskStatsMap[workDir] = &CachedTaskStats{
-			Stats:    stats,
-			CachedAt: m.clock.Now(),
+		transcript := &CachedTranscript{
+			Metrics:   result,
+			Offset:    offset,
+			UpdatedAt: m.clock.Now(),
 		}
-		m.save(cache)
-
-		result = stats
+		m.update(func(cache *CacheFile) {
+			if entry := cache.Transcripts[path]; entry != nil && entry.Offset > offset {
+				return
+			}
+			if cache.Transcripts == nil {
+				cache.Transcripts = make(map[string]*CachedTranscript)
+			}
+			cache.Transcripts[path] = transcript
+		})
 	})
 
 	return result, resultErr
 }
 
-// GetNextTask returns cached next task or fetches it if the cache is invalid.
-// The cache is invalidated when the TTL expires. Tasks are cached per workDir.
-func (m *Manager) GetNextTask(workDir string, ttl time.Duration, fetchFn func() (string, error)) (string, error) {
-	var result string
-	var resultErr error
-
-	m.withFileLock(func() {
-		// Check cache
-		m.mu.RLock()
-		cache := m.load()
-		m.mu.RUnlock()
+// GetGitHubFailedJob returns the cached failing job or fetches it if invalid.
+// Invalidation matches GetGitHubBuild: ref mtime change OR TTL expiry.
+func (m *Manager) GetGitHubFailedJob(refPath, branch string, ttl time.Duration, fetchFn func() (github.FailedJob, error)) (github.FailedJob, error) {
+	return Get(m, "github_failed_job:"+refPath, githubRefValidity(refPath, branch, ttl), fetchFn)
+}
 
-		if cache.NextTaskMap != nil {
-			if cached, ok := cache.NextTaskMap[workDir]; ok {
-				ttlValid := m.clock.Now().Sub(cached.CachedAt) < ttl
-				if ttlValid {
-					result = cached.Title
-					return
-				}
-			}
-		}
+// GetGitHubWorkflows returns the cached per-workflow build statuses or fetches
+// them if invalid. Invalidation matches GetGitHubBuild: ref mtime change OR
+// TTL expiry.
+func (m *Manager) GetGitHubWorkflows(refPath, branch string, ttl time.Duration, fetchFn func() ([]github.WorkflowStatus, error)) ([]github.WorkflowStatus, error) {
+	return Get(m, "github_workflows:"+refPath, githubRefValidity(refPath, branch, ttl), fetchFn)
+}
 
-		// Cache miss - fetch and store
-		title, err := fetchFn()
-		if err != nil {
-			resultErr = err
-			return
-		}
+// GetGitHubChecks returns cached PR check counts or fetches them if invalid.
+// Invalidation matches GetGitHubBuild: ref mtime change OR TTL expiry.
+func (m *Manager) GetGitHubChecks(refPath, branch string, ttl time.Duration, fetchFn func() (github.ChecksSummary, error)) (github.ChecksSummary, error) {
+	return Get(m, "github_checks:"+refPath, githubRefValidity(refPath, branch, ttl), fetchFn)
+}
 
-		m.mu.Lock()
-		defer m.mu.Unlock()
+// GetGitHubPR returns the cached pull request for branch or fetches it if
+// invalid. Invalidation matches GetGitHubBuild: ref mtime change OR TTL expiry.
+func (m *Manager) GetGitHubPR(refPath, branch string, ttl time.Duration, fetchFn func() (github.PullRequest, error)) (github.PullRequest, error) {
+	return Get(m, "github_pr:"+refPath, githubRefValidity(refPath, branch, ttl), fetchFn)
+}
 
-		// Re-check cache after acquiring write lock (TOCTOU protection)
-		cache = m.load()
-		if cache.NextTaskMap != nil {
-			if cached, ok := cache.NextTaskMap[workDir]; ok {
-				ttlValid := m.clock.Now().Sub(cached.CachedAt) < ttl
-				if ttlValid {
-					result = cached.Title
-					return
-				}
-			}
-		}
+// GetGitHubDefaultBuild returns the cached default-branch build status for repo
+// ("owner/name") or fetches it if the TTL has expired. The default branch's ref
+// may not exist locally, so only the TTL is used for invalidation.
+func (m *Manager) GetGitHubDefaultBuild(repo string, ttl time.Duration, fetchFn func() (github.BranchStatus, error)) (github.BranchStatus, error) {
+	return Get(m, "github_default_build:"+repo, TTL(ttl), fetchFn)
+}
 
-		if cache.NextTaskMap == nil {
-			cache.NextTaskMap = make(map[string]*CachedNextTask)
-		}
-		cache.NextTaskMap[workDir] = &CachedNextTask{
-			Title:    title,
-			CachedAt: m.clock.Now(),
-		}
-		m.save(cache)
+// GetActionsUsage returns cached Actions minutes for repo ("owner/name") or
+// fetches them if the TTL has expired.
+func (m *Manager) GetActionsUsage(repo string, ttl time.Duration, fetchFn func() (github.ActionsUsage, error)) (github.ActionsUsage, error) {
+	return Get(m, "github_actions_usage:"+repo, TTL(ttl), fetchFn)
+}
 
-		result = title
-	})
+// GetTaskStats returns cached task stats or fetches them if the cache is invalid.
+// The cache is invalidated when the TTL expires. Stats are cached per workDir.
+func (m *Manager) GetTaskStats(workDir string, ttl time.Duration, fetchFn func() (tasks.Stats, error)) (tasks.Stats, error) {
+	return Get(m, "task_stats:"+workDir, TTL(ttl), fetchFn)
+}
 
-	return result, resultErr
+// GetNextTask returns cached next task or fetches it if the cache is invalid.
+// The cache is invalidated when the TTL expires. Tasks are cached per workDir.
+func (m *Manager) GetNextTask(workDir string, ttl time.Duration, fetchFn func() (string, error)) (string, error) {
+	return Get(m, "next_task:"+workDir, TTL(ttl), fetchFn)
 }
 
 // load reads the cache file from disk or returns the in-memory cache.
@@ -481,31 +642,57 @@ func (m *Manager) load() *CacheFile {
 		return m.memCache
 	}
 
+	m.memCache = m.read()
+	m.cacheLoaded = true
+	return m.memCache
+}
+
+// read reads the cache file from disk, returning an empty cache if it is
+// missing or corrupted.
+func (m *Manager) read() *CacheFile {
 	data, err := os.ReadFile(m.cachePath)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			slog.Warn("failed to read cache file", "path", m.cachePath, "err", err)
 		}
-		cache := &CacheFile{}
-		m.memCache = cache
-		m.cacheLoaded = true
-		return cache
+		return &CacheFile{}
 	}
 
 	var cache CacheFile
 	if err := json.Unmarshal(data, &cache); err != nil {
 		slog.Warn("cache file corrupted, resetting", "err", err)
-		cache := &CacheFile{}
-		m.memCache = cache
-		m.cacheLoaded = true
-		return cache
+		return &CacheFile{}
 	}
-
-	m.memCache = &cache
-	m.cacheLoaded = true
 	return &cache
 }
 
+// update applies fn to the cache and saves it, or when batching keeps fn for
+// Flush to replay onto the file. fn must give the same result applied to a
+// cache another process has changed since. The caller holds m.mu, and the
+// file lock unless batching.
+func (m *Manager) update(fn func(*CacheFile)) {
+	if m.batch {
+		fn(m.load())
+		m.pending = append(m.pending, fn)
+		return
+	}
+
+	// Apply fn to the file as it is now, so what other processes wrote
+	// since m loaded it isn't overwritten
+	cache := m.read()
+	fn(cache)
+	m.save(cache)
+}
+
+// Reload drops m's in-memory copy of the cache file, so the next lookup
+// reads what other processes have written since. A long-lived process calls
+// it before each render.
+func (m *Manager) Reload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheLoaded = false
+}
+
 // maxCacheAge is the maximum age for cached beads stats entries before eviction.
 const maxCacheAge = 7 * 24 * time.Hour // 1 week
 
@@ -536,25 +723,24 @@ func (m *Manager) save(cache *CacheFile) {
 	}
 }
 
-// cleanupOldEntries removes entries older than maxAge from TaskStatsMap and NextTaskMap.
+// cleanupOldEntries removes entries older than maxAge from the per-key maps.
 func (m *Manager) cleanupOldEntries(cache *CacheFile, maxAge time.Duration) {
 	now := m.clock.Now()
 
-	// Clean up old TaskStatsMap entries
-	if cache.TaskStatsMap != nil {
-		for key, entry := range cache.TaskStatsMap {
-			if now.Sub(entry.CachedAt) > maxAge {
-				delete(cache.TaskStatsMap, key)
-			}
-		}
-	}
+	// Clean up entries of repositories and sessions that haven't been rendered lately
+	pruneOld(cache.Entries, now, maxAge, func(e *CachedEntry) time.Time { return e.CachedAt })
+	pruneOld(cache.BuildHistory, now, maxAge, func(e *CachedBuildHistory) time.Time { return e.UpdatedAt })
+	pruneOld(cache.RateLimits, now, maxAge, func(e *CachedRateLimit) time.Time { return e.Until })
+	pruneOld(cache.ContextMap, now, maxAge, func(e *CachedContextHistory) time.Time { return e.UpdatedAt })
+	pruneOld(cache.Transcripts, now, maxAge, func(e *CachedTranscript) time.Time { return e.UpdatedAt })
+	pruneOld(cache.Renders, now, maxAge, func(e *CachedRender) time.Time { return e.RenderedAt })
+}
 
-	// Clean up old NextTaskMap entries
-	if cache.NextTaskMap != nil {
-		for key, entry := range cache.NextTaskMap {
-			if now.Sub(entry.CachedAt) > maxAge {
-				delete(cache.NextTaskMap, key)
-			}
+// pruneOld deletes entries last updated more than maxAge before now.
+func pruneOld[V any](entries map[string]V, now time.Time, maxAge time.Duration, updatedAt func(V) time.Time) {
+	for key, entry := range entries {
+		if now.Sub(updatedAt(entry)) > maxAge {
+			delete(entries, key)
 		}
 	}
 }
@@ -568,6 +754,18 @@ func getFileMtime(path string) (int64, error) {
 	return info.ModTime().UnixNano(), nil
 }
 
+// getRefMtime returns the mtime of a branch ref, falling back to packed-refs when
+// the ref is packed. Returns 0 if neither exists so callers can rely on TTL alone.
+func getRefMtime(refPath string) int64 {
+	if mtime, err := getFileMtime(refPath); err == nil {
+		return mtime
+	}
+	if mtime, err := getPackedRefsMtime(refPath); err == nil {
+		return mtime
+	}
+	return 0
+}
+
 // getPackedRefsMtime tries to read the packed-refs file mtime for repos where branch refs are packed.
 func getPackedRefsMtime(refPath string) (int64, error) {
 	// refPath is .../.git/refs/heads/<branch>; packed-refs lives under .git
@@ -583,5 +781,6 @@ func (m *Manager) Clear() error {
 	// Invalidate in-memory cache
 	m.memCache = nil
 	m.cacheLoaded = false
+	m.pending = nil
 	return os.Remove(m.cachePath)
 }
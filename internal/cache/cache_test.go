@@ -1,14 +1,19 @@
 package cache
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/kostyay/claude-status/internal/git"
 	"github.com/kostyay/claude-status/internal/github"
 	"github.com/kostyay/claude-status/internal/tasks"
+	"github.com/kostyay/claude-status/internal/tokens"
 )
 
 // mockClock is a test double for Clock.
@@ -110,6 +115,102 @@ func TestGetGitBranch_CacheHit(t *testing.T) {
 	}
 }
 
+func TestGetGitBranch_PerRepository(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	// Two repositories whose HEAD files share an mtime must not share a branch
+	mtime := time.Now().Add(-time.Hour)
+	heads := map[string]string{}
+	for _, name := range []string{"api", "web"} {
+		path := filepath.Join(dir, name, "HEAD")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("ref: refs/heads/"+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		heads[name] = path
+	}
+
+	fetchCalls := 0
+	fetchFor := func(branch string) func() (string, error) {
+		return func() (string, error) {
+			fetchCalls++
+			return branch, nil
+		}
+	}
+
+	// Alternating between sessions keeps both entries warm
+	for range 2 {
+		for name, path := range heads {
+			branch, err := manager.GetGitBranch(path, fetchFor(name))
+			if err != nil {
+				t.Fatalf("GetGitBranch(%s) error = %v", name, err)
+			}
+			if branch != name {
+				t.Errorf("GetGitBranch(%s) = %q, want %q", name, branch, name)
+			}
+		}
+	}
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2 (one per repository)", fetchCalls)
+	}
+}
+
+func TestSave_PrunesIdleRepositories(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	oldHead := filepath.Join(dir, "old", "HEAD")
+	newHead := filepath.Join(dir, "new", "HEAD")
+	for _, path := range []string{oldHead, newHead} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("ref: refs/heads/main"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fetchFn := func() (string, error) { return "main", nil }
+
+	manager.GetGitBranch(oldHead, fetchFn)
+	clock.Advance(maxCacheAge + time.Hour)
+	manager.GetGitBranch(newHead, fetchFn)
+
+	cache := manager.load()
+	if _, ok := cache.Entries["git_branch:"+oldHead]; ok {
+		t.Error("entry for a repository idle past maxCacheAge was kept")
+	}
+	if _, ok := cache.Entries["git_branch:"+newHead]; !ok {
+		t.Error("entry for the current repository was pruned")
+	}
+}
+
+func TestGetGitHubDefaultBuild_PerRepository(t *testing.T) {
+	manager, _, _ := setupTestCache(t)
+
+	fetchCalls := 0
+	fetchFor := func(status github.BuildStatus) func() (github.BranchStatus, error) {
+		return func() (github.BranchStatus, error) {
+			fetchCalls++
+			return github.BranchStatus{Branch: "main", Status: status}, nil
+		}
+	}
+
+	for range 2 {
+		a, _ := manager.GetGitHubDefaultBuild("owner/a", time.Minute, fetchFor(github.StatusSuccess))
+		b, _ := manager.GetGitHubDefaultBuild("owner/b", time.Minute, fetchFor(github.StatusFailure))
+		if a.Status != github.StatusSuccess || b.Status != github.StatusFailure {
+			t.Errorf("default builds = %v, %v; want each repository's own", a.Status, b.Status)
+		}
+	}
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2 (one per repository)", fetchCalls)
+	}
+}
+
 func TestGetGitBranch_Invalidate(t *testing.T) {
 	manager, dir, _ := setupTestCache(t)
 
@@ -150,6 +251,364 @@ func TestGetGitBranch_Invalidate(t *testing.T) {
 	}
 }
 
+func TestGetGitUpstream(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	fetchHead := filepath.Join(dir, "FETCH_HEAD")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	for _, p := range []string{refPath, fetchHead} {
+		if err := os.WriteFile(p, []byte("abc123"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetchCalls := 0
+	fetchFn := func() (git.UpstreamStatus, error) {
+		fetchCalls++
+		return git.UpstreamStatus{Ahead: 2, Behind: fetchCalls}, nil
+	}
+
+	got, err := manager.GetGitUpstream(refPath, fetchHead, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitUpstream() error = %v", err)
+	}
+	if got != (git.UpstreamStatus{Ahead: 2, Behind: 1}) {
+		t.Errorf("GetGitUpstream() = %+v, want ahead 2 behind 1", got)
+	}
+
+	manager.GetGitUpstream(refPath, fetchHead, fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// A fetch rewrites FETCH_HEAD
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(fetchHead, future, future)
+	manager.GetGitUpstream(refPath, fetchHead, fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2 (FETCH_HEAD changed)", fetchCalls)
+	}
+
+	// A push changes neither file; the TTL catches it
+	clock.Advance(upstreamTTL + time.Second)
+	manager.GetGitUpstream(refPath, fetchHead, fetchFn)
+	if fetchCalls != 3 {
+		t.Errorf("fetchFn called %d times, want 3 (TTL expired)", fetchCalls)
+	}
+}
+
+func TestGetInstallWarning(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	settingsPath := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(settingsPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCalls := 0
+	fetchFn := func() (string, error) {
+		fetchCalls++
+		return "statusLine binary /old/claude-status is missing", nil
+	}
+
+	got, err := manager.GetInstallWarning(settingsPath, "/bin/claude-status", fetchFn)
+	if err != nil {
+		t.Fatalf("GetInstallWarning() error = %v", err)
+	}
+	if got != "statusLine binary /old/claude-status is missing" {
+		t.Errorf("GetInstallWarning() = %q", got)
+	}
+
+	manager.GetInstallWarning(settingsPath, "/bin/claude-status", fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// Reinstalling rewrites settings.json
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(settingsPath, future, future)
+	manager.GetInstallWarning(settingsPath, "/bin/claude-status", fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2 (settings changed)", fetchCalls)
+	}
+
+	// A different binary (e.g. after an upgrade) checks again
+	manager.GetInstallWarning(settingsPath, "/opt/claude-status", fetchFn)
+	if fetchCalls != 3 {
+		t.Errorf("fetchFn called %d times, want 3 (binary changed)", fetchCalls)
+	}
+
+	// Moving the binary changes no file; the TTL catches it
+	clock.Advance(installCheckTTL + time.Second)
+	manager.GetInstallWarning(settingsPath, "/opt/claude-status", fetchFn)
+	if fetchCalls != 4 {
+		t.Errorf("fetchFn called %d times, want 4 (TTL expired)", fetchCalls)
+	}
+}
+
+func TestGetGitSummary(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	gitDir := filepath.Join(dir, "repo", ".git")
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(gitDir, "index")
+	if err := os.WriteFile(indexPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCalls := 0
+	fetchFn := func() (git.Summary, error) {
+		fetchCalls++
+		return git.Summary{Branch: "main", Changed: 2}, nil
+	}
+
+	got, err := manager.GetGitSummary(gitDir, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitSummary() error = %v", err)
+	}
+	if got != (git.Summary{Branch: "main", Changed: 2}) {
+		t.Errorf("GetGitSummary() = %+v", got)
+	}
+
+	manager.GetGitSummary(gitDir, fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// Other repositories are cached separately
+	manager.GetGitSummary(filepath.Join(dir, "other", ".git"), fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2 (other repo)", fetchCalls)
+	}
+
+	// Staging rewrites the index
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(indexPath, future, future)
+	manager.GetGitSummary(gitDir, fetchFn)
+	if fetchCalls != 3 {
+		t.Errorf("fetchFn called %d times, want 3 (index changed)", fetchCalls)
+	}
+
+	// Editing files touches neither; the TTL catches it
+	clock.Advance(gitSummaryTTL + time.Second)
+	manager.GetGitSummary(gitDir, fetchFn)
+	if fetchCalls != 4 {
+		t.Errorf("fetchFn called %d times, want 4 (TTL expired)", fetchCalls)
+	}
+}
+
+func TestGetGitStashCount(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	stashLog := filepath.Join(dir, ".git", "logs", "refs", "stash")
+	count := 0
+	fetchCalls := 0
+	fetchFn := func() (int, error) {
+		fetchCalls++
+		return count, nil
+	}
+
+	// No stashes yet: the missing reflog is cached too
+	if got, err := manager.GetGitStashCount(stashLog, fetchFn); err != nil || got != 0 {
+		t.Fatalf("GetGitStashCount() = %d, %v; want 0", got, err)
+	}
+	manager.GetGitStashCount(stashLog, fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// git stash creates the reflog
+	if err := os.MkdirAll(filepath.Dir(stashLog), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stashLog, []byte("entry\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	count = 1
+	if got, _ := manager.GetGitStashCount(stashLog, fetchFn); got != 1 {
+		t.Errorf("GetGitStashCount() after stash = %d, want 1", got)
+	}
+
+	// Dropping an entry rewrites it
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(stashLog, future, future)
+	count = 0
+	if got, _ := manager.GetGitStashCount(stashLog, fetchFn); got != 0 {
+		t.Errorf("GetGitStashCount() after drop = %d, want 0", got)
+	}
+	if fetchCalls != 3 {
+		t.Errorf("fetchFn called %d times, want 3", fetchCalls)
+	}
+}
+
+func TestGetGitLastCommit(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	headPath := filepath.Join(dir, ".git", "HEAD")
+	refPath := filepath.Join(dir, ".git", "refs", "heads", "main")
+	for _, path := range []string{headPath, refPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("ref\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit := git.Commit{SHA: "abc1234", Subject: "first"}
+	fetchCalls := 0
+	fetchFn := func() (git.Commit, error) {
+		fetchCalls++
+		return commit, nil
+	}
+
+	if got, err := manager.GetGitLastCommit(headPath, refPath, fetchFn); err != nil || got != commit {
+		t.Fatalf("GetGitLastCommit() = %+v, %v; want %+v", got, err, commit)
+	}
+	manager.GetGitLastCommit(headPath, refPath, fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// Committing moves the branch ref, not HEAD
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(refPath, future, future)
+	commit = git.Commit{SHA: "def5678", Subject: "second"}
+	if got, _ := manager.GetGitLastCommit(headPath, refPath, fetchFn); got != commit {
+		t.Errorf("GetGitLastCommit() after commit = %+v, want %+v", got, commit)
+	}
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2", fetchCalls)
+	}
+
+	// Fetch errors aren't cached
+	failing := func() (git.Commit, error) { return git.Commit{}, errors.New("no commits") }
+	if _, err := manager.GetGitLastCommit(filepath.Join(dir, "other", "HEAD"), "", failing); err == nil {
+		t.Error("GetGitLastCommit() error = nil, want the fetch error")
+	}
+}
+
+func TestGetGitDiffSince(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	indexPath := filepath.Join(dir, "index")
+	if err := os.WriteFile(indexPath, []byte("index data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := git.LineStats{Additions: 5, Deletions: 1}
+	fetchCalls := 0
+	fetchFn := func() (git.LineStats, error) {
+		fetchCalls++
+		return stats, nil
+	}
+
+	if got, err := manager.GetGitDiffSince(indexPath, "aaa", fetchFn); err != nil || got != stats {
+		t.Fatalf("GetGitDiffSince() = %+v, %v; want %+v", got, err, stats)
+	}
+	manager.GetGitDiffSince(indexPath, "aaa", fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// Each base is cached separately
+	manager.GetGitDiffSince(indexPath, "bbb", fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times for a second base, want 2", fetchCalls)
+	}
+
+	// Staging or committing rewrites the index
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(indexPath, future, future)
+	stats = git.LineStats{Additions: 9, Deletions: 3}
+	if got, _ := manager.GetGitDiffSince(indexPath, "aaa", fetchFn); got != stats {
+		t.Errorf("GetGitDiffSince() after index change = %+v, want %+v", got, stats)
+	}
+}
+
+func TestGetGitCommitsSince(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	headPath := filepath.Join(dir, ".git", "HEAD")
+	refPath := filepath.Join(dir, ".git", "refs", "heads", "main")
+	for _, path := range []string{headPath, refPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("ref\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := 1
+	fetchCalls := 0
+	fetchFn := func() (int, error) {
+		fetchCalls++
+		return count, nil
+	}
+	since := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if got, err := manager.GetGitCommitsSince(headPath, refPath, since, fetchFn); err != nil || got != 1 {
+		t.Fatalf("GetGitCommitsSince() = %d, %v; want 1", got, err)
+	}
+	manager.GetGitCommitsSince(headPath, refPath, since, fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// Committing moves the branch ref
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(refPath, future, future)
+	count = 2
+	if got, _ := manager.GetGitCommitsSince(headPath, refPath, since, fetchFn); got != 2 {
+		t.Errorf("GetGitCommitsSince() after commit = %d, want 2", got)
+	}
+
+	// A different start time (a new session) is counted afresh
+	count = 0
+	if got, _ := manager.GetGitCommitsSince(headPath, refPath, since.Add(time.Hour), fetchFn); got != 0 {
+		t.Errorf("GetGitCommitsSince() for a new session = %d, want 0", got)
+	}
+	if fetchCalls != 3 {
+		t.Errorf("fetchFn called %d times, want 3", fetchCalls)
+	}
+}
+
+func TestGetGitHeadName(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	headPath := filepath.Join(dir, "HEAD")
+	if err := os.WriteFile(headPath, []byte("abc1234\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "abc1234"
+	fetchCalls := 0
+	fetchFn := func() (string, error) {
+		fetchCalls++
+		return name, nil
+	}
+
+	if got, err := manager.GetGitHeadName(headPath, fetchFn); err != nil || got != "abc1234" {
+		t.Fatalf("GetGitHeadName() = %q, %v; want abc1234", got, err)
+	}
+	manager.GetGitHeadName(headPath, fetchFn)
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1 (cache hit)", fetchCalls)
+	}
+
+	// Checking out a tag rewrites HEAD
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(headPath, future, future)
+	name = "v1.2.0"
+	if got, _ := manager.GetGitHeadName(headPath, fetchFn); got != "v1.2.0" {
+		t.Errorf("GetGitHeadName() after checkout = %q, want v1.2.0", got)
+	}
+}
+
 func TestGetGitStatus_CacheMiss(t *testing.T) {
 	manager, dir, _ := setupTestCache(t)
 
@@ -294,6 +753,374 @@ func TestGetGitHubBuild_CacheHit(t *testing.T) {
 	}
 }
 
+func TestGetGitHubBuildHistory(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(status github.BuildStatus) {
+		t.Helper()
+		clock.Advance(2 * time.Minute) // expire the TTL so each call fetches
+		if _, err := manager.GetGitHubBuild(refPath, "main", 60*time.Second, func() (github.BuildStatus, error) {
+			return status, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetch(github.StatusPending) // not recorded
+	fetch(github.StatusSuccess)
+	fetch(github.StatusSuccess) // same run seen again: not recorded
+	fetch(github.StatusFailure)
+	fetch(github.StatusSuccess)
+
+	want := []github.BuildStatus{github.StatusSuccess, github.StatusFailure, github.StatusSuccess}
+	if got := manager.GetGitHubBuildHistory(refPath, "main"); !slices.Equal(got, want) {
+		t.Errorf("GetGitHubBuildHistory() = %v, want %v", got, want)
+	}
+	if got := manager.GetGitHubBuildHistory(refPath, "other"); got != nil {
+		t.Errorf("GetGitHubBuildHistory(other branch) = %v, want nil", got)
+	}
+}
+
+func TestGetGitHubBuildHistory_KeepsLastTen(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 15 {
+		status := github.StatusSuccess
+		if i%2 == 0 {
+			status = github.StatusFailure
+		}
+		clock.Advance(2 * time.Minute)
+		manager.GetGitHubBuild(refPath, "main", 60*time.Second, func() (github.BuildStatus, error) {
+			return status, nil
+		})
+	}
+
+	history := manager.GetGitHubBuildHistory(refPath, "main")
+	if len(history) != maxBuildHistory {
+		t.Fatalf("len(history) = %d, want %d", len(history), maxBuildHistory)
+	}
+	if history[len(history)-1] != github.StatusFailure {
+		t.Errorf("last result = %q, want the most recent (%q)", history[len(history)-1], github.StatusFailure)
+	}
+}
+
+func TestRecordContextSample(t *testing.T) {
+	manager, _, _ := setupTestCache(t)
+
+	manager.RecordContextSample("s1", 1000)
+	manager.RecordContextSample("s1", 1000) // re-render without a new turn
+	manager.RecordContextSample("s2", 9999)
+	got := manager.RecordContextSample("s1", 5000)
+
+	if want := []int64{1000, 5000}; !slices.Equal(got, want) {
+		t.Errorf("RecordContextSample() = %v, want %v", got, want)
+	}
+
+	for i := range 30 {
+		got = manager.RecordContextSample("s1", int64(i))
+	}
+	if len(got) != maxContextSamples || got[len(got)-1] != 29 {
+		t.Errorf("history = %v, want the last %d samples", got, maxContextSamples)
+	}
+}
+
+func TestRecentRender(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	if _, ok := manager.RecentRender("s1", time.Second); ok {
+		t.Error("RecentRender() before any render = true")
+	}
+
+	manager.RecordRender("s1", "h1", "line one")
+	clock.Advance(500 * time.Millisecond)
+	if got, ok := manager.RecentRender("s1", time.Second); !ok || got != "line one" {
+		t.Errorf("RecentRender() = %q, %v; want the recorded line", got, ok)
+	}
+	if _, ok := manager.RecentRender("s2", time.Second); ok {
+		t.Error("RecentRender() for another session = true")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if _, ok := manager.RecentRender("s1", time.Second); ok {
+		t.Error("RecentRender() after the interval = true")
+	}
+}
+
+func TestRepeatedRender(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	manager.RecordRender("s1", "h1", "line one")
+	clock.Advance(500 * time.Millisecond)
+	if got, ok := manager.RepeatedRender("s1", "h1", time.Second); !ok || got != "line one" {
+		t.Errorf("RepeatedRender() = %q, %v; want the recorded line", got, ok)
+	}
+	if _, ok := manager.RepeatedRender("s1", "h2", time.Second); ok {
+		t.Error("RepeatedRender() for a different input = true")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if _, ok := manager.RepeatedRender("s1", "h1", time.Second); ok {
+		t.Error("RepeatedRender() after the window = true")
+	}
+}
+
+func TestGetTranscriptMetrics_Incremental(t *testing.T) {
+	manager, _, _ := setupTestCache(t)
+
+	type call struct {
+		offset int64
+		prev   tokens.Metrics
+	}
+	var calls []call
+	end := int64(100)
+	parse := func(offset int64, prev tokens.Metrics) (tokens.Metrics, int64, error) {
+		calls = append(calls, call{offset, prev})
+		return tokens.Metrics{InputTokens: prev.InputTokens + end - offset}, end, nil
+	}
+
+	got, err := manager.GetTranscriptMetrics("/t.jsonl", parse)
+	if err != nil || got.InputTokens != 100 {
+		t.Fatalf("first GetTranscriptMetrics() = %+v, %v; want 100 input tokens", got, err)
+	}
+
+	// Appended lines: the next parse resumes from the cached offset
+	end = 150
+	got, _ = manager.GetTranscriptMetrics("/t.jsonl", parse)
+	if got.InputTokens != 150 {
+		t.Errorf("second GetTranscriptMetrics() = %+v, want 150 input tokens", got)
+	}
+	if want := (call{100, tokens.Metrics{InputTokens: 100}}); !reflect.DeepEqual(calls[1], want) {
+		t.Errorf("second parse called with %+v, want %+v", calls[1], want)
+	}
+
+	// Nothing appended: the cached metrics come back unchanged
+	got, _ = manager.GetTranscriptMetrics("/t.jsonl", parse)
+	if got.InputTokens != 150 || calls[2].offset != 150 {
+		t.Errorf("third GetTranscriptMetrics() = %+v from offset %d, want 150 from 150", got, calls[2].offset)
+	}
+}
+
+func TestGetGitHubBuild_PerBranchEntries(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	mainRef := filepath.Join(dir, "refs", "heads", "main")
+	featureRef := filepath.Join(dir, "refs", "heads", "feature")
+	os.MkdirAll(filepath.Dir(mainRef), 0755)
+	for _, ref := range []string{mainRef, featureRef} {
+		if err := os.WriteFile(ref, []byte("abc123"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetchCalls := 0
+	fetchFor := func(status github.BuildStatus) func() (github.BuildStatus, error) {
+		return func() (github.BuildStatus, error) {
+			fetchCalls++
+			return status, nil
+		}
+	}
+
+	manager.GetGitHubBuild(mainRef, "main", 60*time.Second, fetchFor(github.StatusSuccess))
+	manager.GetGitHubBuild(featureRef, "feature", 60*time.Second, fetchFor(github.StatusFailure))
+
+	// Flip back and forth: both entries must stay warm
+	mainStatus, _ := manager.GetGitHubBuild(mainRef, "main", 60*time.Second, fetchFor(github.StatusError))
+	featureStatus, _ := manager.GetGitHubBuild(featureRef, "feature", 60*time.Second, fetchFor(github.StatusError))
+
+	if mainStatus != github.StatusSuccess {
+		t.Errorf("main status = %q, want %q", mainStatus, github.StatusSuccess)
+	}
+	if featureStatus != github.StatusFailure {
+		t.Errorf("feature status = %q, want %q", featureStatus, github.StatusFailure)
+	}
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times, want 2", fetchCalls)
+	}
+}
+
+func TestGetGitHubFailedJob_CacheHitAndTTL(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCalls := 0
+	fetchFn := func() (github.FailedJob, error) {
+		fetchCalls++
+		return github.FailedJob{Name: "unit-tests", RunURL: "https://example.com/run"}, nil
+	}
+
+	manager.GetGitHubFailedJob(refPath, "main", 60*time.Second, fetchFn)
+	job, err := manager.GetGitHubFailedJob(refPath, "main", 60*time.Second, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitHubFailedJob() error = %v", err)
+	}
+	if job.Name != "unit-tests" {
+		t.Errorf("GetGitHubFailedJob().Name = %q, want %q", job.Name, "unit-tests")
+	}
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1", fetchCalls)
+	}
+
+	// Expire TTL
+	clock.Advance(61 * time.Second)
+	manager.GetGitHubFailedJob(refPath, "main", 60*time.Second, fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times after TTL expiry, want 2", fetchCalls)
+	}
+}
+
+func TestGetGitHubWorkflows_CacheHitAndTTL(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCalls := 0
+	fetchFn := func() ([]github.WorkflowStatus, error) {
+		fetchCalls++
+		return []github.WorkflowStatus{{Name: "ci", Status: github.StatusSuccess}, {Name: "lint", Status: github.StatusFailure}}, nil
+	}
+
+	manager.GetGitHubWorkflows(refPath, "main", 60*time.Second, fetchFn)
+	statuses, err := manager.GetGitHubWorkflows(refPath, "main", 60*time.Second, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitHubWorkflows() error = %v", err)
+	}
+	if len(statuses) != 2 || statuses[1].Name != "lint" || statuses[1].Status != github.StatusFailure {
+		t.Errorf("GetGitHubWorkflows() = %+v", statuses)
+	}
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1", fetchCalls)
+	}
+
+	// Expire TTL
+	clock.Advance(61 * time.Second)
+	manager.GetGitHubWorkflows(refPath, "main", 60*time.Second, fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times after TTL expiry, want 2", fetchCalls)
+	}
+}
+
+func TestGetGitHubPR(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "feature")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCalls := 0
+	fetchFn := func() (github.PullRequest, error) {
+		fetchCalls++
+		return github.PullRequest{Number: 123, ReviewState: "approved"}, nil
+	}
+
+	manager.GetGitHubPR(refPath, "feature", 60*time.Second, fetchFn)
+	pr, err := manager.GetGitHubPR(refPath, "feature", 60*time.Second, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitHubPR() error = %v", err)
+	}
+	if pr.Number != 123 || pr.ReviewState != "approved" {
+		t.Errorf("GetGitHubPR() = %+v, want PR 123 approved", pr)
+	}
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1", fetchCalls)
+	}
+
+	clock.Advance(61 * time.Second)
+	manager.GetGitHubPR(refPath, "feature", 60*time.Second, fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times after TTL expiry, want 2", fetchCalls)
+	}
+}
+
+func TestGetGitHubChecks_BranchChange(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchCalls := 0
+	fetchFn := func() (github.ChecksSummary, error) {
+		fetchCalls++
+		return github.ChecksSummary{Passed: 3, Total: 3}, nil
+	}
+
+	manager.GetGitHubChecks(refPath, "main", 60*time.Second, fetchFn)
+	summary, err := manager.GetGitHubChecks(refPath, "main", 60*time.Second, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitHubChecks() error = %v", err)
+	}
+	if summary.Total != 3 {
+		t.Errorf("GetGitHubChecks().Total = %d, want 3", summary.Total)
+	}
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1", fetchCalls)
+	}
+
+	// Different branch must not reuse the cached entry
+	manager.GetGitHubChecks(refPath, "other", 60*time.Second, fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times after branch change, want 2", fetchCalls)
+	}
+}
+
+func TestGetGitHubDefaultBuild_KeyedByRepo(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	fetchCalls := 0
+	fetchFn := func() (github.BranchStatus, error) {
+		fetchCalls++
+		return github.BranchStatus{Branch: "main", Status: github.StatusSuccess}, nil
+	}
+
+	manager.GetGitHubDefaultBuild("owner/repo", 60*time.Second, fetchFn)
+	result, err := manager.GetGitHubDefaultBuild("owner/repo", 60*time.Second, fetchFn)
+	if err != nil {
+		t.Fatalf("GetGitHubDefaultBuild() error = %v", err)
+	}
+	if result.Branch != "main" || result.Status != github.StatusSuccess {
+		t.Errorf("GetGitHubDefaultBuild() = %+v, want main/success", result)
+	}
+	if fetchCalls != 1 {
+		t.Errorf("fetchFn called %d times, want 1", fetchCalls)
+	}
+
+	manager.GetGitHubDefaultBuild("owner/other", 60*time.Second, fetchFn)
+	if fetchCalls != 2 {
+		t.Errorf("fetchFn called %d times after repo change, want 2", fetchCalls)
+	}
+
+	clock.Advance(61 * time.Second)
+	manager.GetGitHubDefaultBuild("owner/other", 60*time.Second, fetchFn)
+	if fetchCalls != 3 {
+		t.Errorf("fetchFn called %d times after TTL expiry, want 3", fetchCalls)
+	}
+}
+
 func TestGetGitHubBuild_PackedRefs(t *testing.T) {
 	manager, dir, _ := setupTestCache(t)
 
@@ -439,6 +1266,63 @@ func TestGetGitHubBuild_RefChanged(t *testing.T) {
 	}
 }
 
+func TestGitHubRateLimit(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	if _, ok := manager.GitHubRateLimit("github.com"); ok {
+		t.Error("GitHubRateLimit() ok before any was recorded")
+	}
+
+	until := clock.Now().Add(5 * time.Minute)
+	manager.SetGitHubRateLimit("github.com", until)
+	if got, ok := manager.GitHubRateLimit("github.com"); !ok || !got.Equal(until) {
+		t.Errorf("GitHubRateLimit() = %v, %v; want %v, true", got, ok, until)
+	}
+	// Other hosts have their own limits
+	if _, ok := manager.GitHubRateLimit("github.example.com"); ok {
+		t.Error("GitHubRateLimit() ok for another host")
+	}
+
+	clock.Advance(5 * time.Minute)
+	if _, ok := manager.GitHubRateLimit("github.com"); ok {
+		t.Error("GitHubRateLimit() ok once the limit reset")
+	}
+}
+
+func TestGitHubBuildAge(t *testing.T) {
+	manager, dir, clock := setupTestCache(t)
+
+	refPath := filepath.Join(dir, "refs", "heads", "main")
+	os.MkdirAll(filepath.Dir(refPath), 0755)
+	if err := os.WriteFile(refPath, []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := manager.GitHubBuildAge(refPath, "main"); ok {
+		t.Error("GitHubBuildAge() ok before anything was cached")
+	}
+
+	manager.GetGitHubBuild(refPath, "main", 60*time.Second, func() (github.BuildStatus, error) {
+		return github.StatusSuccess, nil
+	})
+	clock.Advance(90 * time.Second)
+
+	if age, ok := manager.GitHubBuildAge(refPath, "main"); !ok || age != 90*time.Second {
+		t.Errorf("GitHubBuildAge() = %v, %v; want 1m30s, true", age, ok)
+	}
+	if _, ok := manager.GitHubBuildAge(refPath, "feature"); ok {
+		t.Error("GitHubBuildAge() ok for another branch")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(refPath, []byte("def456"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manager.GitHubBuildAge(refPath, "main"); ok {
+		t.Error("GitHubBuildAge() ok after the ref changed")
+	}
+}
+
 func TestCachePersistence(t *testing.T) {
 	dir := t.TempDir()
 	clock := &mockClock{now: time.Now()}
@@ -479,6 +1363,85 @@ func TestCachePersistence(t *testing.T) {
 	}
 }
 
+func TestBatch_WritesOnFlush(t *testing.T) {
+	dir := t.TempDir()
+	clock := &mockClock{now: time.Now()}
+	headPath := filepath.Join(dir, "HEAD")
+	if err := os.WriteFile(headPath, []byte("ref"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManagerWithClock(dir, clock)
+	manager.Batch()
+	manager.GetGitBranch(headPath, func() (string, error) { return "main", nil })
+	manager.RecordRender("s1", "h1", "line")
+	manager.RecordContextSample("s1", 1000)
+
+	if _, err := os.Stat(filepath.Join(dir, "cache.json")); !os.IsNotExist(err) {
+		t.Fatalf("cache file written before Flush: %v", err)
+	}
+
+	// Another process writes meanwhile
+	other := NewManagerWithClock(dir, clock)
+	other.RecordContextSample("s2", 2000)
+
+	manager.Flush()
+
+	reloaded := NewManagerWithClock(dir, clock)
+	branch, _ := reloaded.GetGitBranch(headPath, func() (string, error) {
+		t.Error("fetchFn called, want the branch flushed by the batch")
+		return "", nil
+	})
+	if branch != "main" {
+		t.Errorf("GetGitBranch() = %q, want %q", branch, "main")
+	}
+	if got, ok := reloaded.RecentRender("s1", time.Minute); !ok || got != "line" {
+		t.Errorf("RecentRender() = %q, %v; want the flushed render", got, ok)
+	}
+	if got := reloaded.RecordContextSample("s1", 1000); !slices.Equal(got, []int64{1000}) {
+		t.Errorf("s1 history = %v, want [1000]", got)
+	}
+	if got := reloaded.RecordContextSample("s2", 2000); !slices.Equal(got, []int64{2000}) {
+		t.Errorf("s2 history = %v, want the other process's write kept", got)
+	}
+
+	// Nothing pending, nothing written
+	info, err := os.Stat(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.Flush()
+	if after, _ := os.Stat(filepath.Join(dir, "cache.json")); !after.ModTime().Equal(info.ModTime()) {
+		t.Error("Flush() without updates rewrote the cache file")
+	}
+}
+
+func TestLongLivedManager_KeepsOtherWrites(t *testing.T) {
+	dir := t.TempDir()
+	clock := &mockClock{now: time.Now()}
+
+	// A daemon's manager has the file loaded when another process writes
+	daemon := NewManagerWithClock(dir, clock)
+	daemon.RecordRender("s1", "h1", "daemon")
+	other := NewManagerWithClock(dir, clock)
+	other.RecordRender("s2", "h2", "cli")
+
+	daemon.RecordRender("s3", "h3", "daemon")
+	reloaded := NewManagerWithClock(dir, clock)
+	if got, ok := reloaded.RecentRender("s2", time.Minute); !ok || got != "cli" {
+		t.Errorf("RecentRender(s2) = %q, %v; want the other process's write kept", got, ok)
+	}
+
+	if _, ok := daemon.RecentRender("s2", time.Minute); !ok {
+		t.Error("RecentRender(s2) missing after the daemon's own write reread the file")
+	}
+	other.RecordRender("s4", "h4", "cli")
+	daemon.Reload()
+	if got, ok := daemon.RecentRender("s4", time.Minute); !ok || got != "cli" {
+		t.Errorf("RecentRender(s4) after Reload = %q, %v; want the other process's write", got, ok)
+	}
+}
+
 func TestCacheCorruption(t *testing.T) {
 	manager, dir, _ := setupTestCache(t)
 
@@ -545,6 +1508,48 @@ func TestConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestSlowFetchDoesNotBlockOtherLookups(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+
+	headPath := filepath.Join(dir, "HEAD")
+	indexPath := filepath.Join(dir, "index")
+	for _, p := range []string{headPath, indexPath} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.GetGitBranch(headPath, func() (string, error) {
+			close(started)
+			<-release
+			return "main", nil
+		})
+	}()
+	<-started
+
+	// The branch fetch is still running; a status lookup must not wait on it
+	status, err := manager.GetGitStatus(indexPath, func() (string, error) { return "±", nil })
+	if err != nil || status != "±" {
+		t.Errorf("GetGitStatus() = %q, %v; want %q, nil", status, err, "±")
+	}
+
+	close(release)
+	<-done
+
+	branch, err := manager.GetGitBranch(headPath, func() (string, error) {
+		t.Error("branch should be cached after the slow fetch completed")
+		return "", nil
+	})
+	if err != nil || branch != "main" {
+		t.Errorf("GetGitBranch() = %q, %v; want %q, nil", branch, err, "main")
+	}
+}
+
 func TestClear(t *testing.T) {
 	manager, dir, _ := setupTestCache(t)
 
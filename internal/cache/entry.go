@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachedEntry holds a value cached by Get, JSON-encoded, with the stamp of
+// what it was derived from.
+type CachedEntry struct {
+	Value    json.RawMessage `json:"value"`
+	Stamp    string          `json:"stamp"`
+	CachedAt time.Time       `json:"cached_at"`
+}
+
+// Invalidator decides when a cached value must be fetched again.
+type Invalidator interface {
+	// Stamp describes what the value depends on right now, such as a file's
+	// mtime; a value cached under another stamp is stale. ok is false when
+	// that can't be told, and the value is then fetched without caching.
+	Stamp() (stamp string, ok bool)
+	// Expired reports whether a value cached at cachedAt is stale at now
+	// whatever its stamp.
+	Expired(cachedAt, now time.Time) bool
+}
+
+// FileMtime invalidates when the file at path is modified. Values are not
+// cached while the file can't be stat'ed.
+func FileMtime(path string) Invalidator { return fileMtime{path: path} }
+
+// OptionalFileMtime invalidates when the file at path is created, modified,
+// or removed. A missing file is a state like any other.
+func OptionalFileMtime(path string) Invalidator { return fileMtime{path: path, optional: true} }
+
+type fileMtime struct {
+	path     string
+	optional bool
+}
+
+func (f fileMtime) Stamp() (string, bool) {
+	mtime, err := getFileMtime(f.path)
+	if err != nil && !f.optional {
+		return "", false
+	}
+	return strconv.FormatInt(mtime, 10), true
+}
+
+func (fileMtime) Expired(time.Time, time.Time) bool { return false }
+
+// RefMtime invalidates when the branch ref at refPath moves, looking at
+// packed-refs when the ref is packed. An empty refPath (a detached HEAD) has
+// no ref to watch.
+func RefMtime(refPath string) Invalidator { return refMtime(refPath) }
+
+type refMtime string
+
+func (r refMtime) Stamp() (string, bool) {
+	if r == "" {
+		return "", true
+	}
+	return strconv.FormatInt(getRefMtime(string(r)), 10), true
+}
+
+func (refMtime) Expired(time.Time, time.Time) bool { return false }
+
+// TTL invalidates values once they are d old.
+func TTL(d time.Duration) Invalidator { return ttl(d) }
+
+type ttl time.Duration
+
+func (ttl) Stamp() (string, bool) { return "", true }
+
+func (d ttl) Expired(cachedAt, now time.Time) bool {
+	return now.Sub(cachedAt) >= time.Duration(d)
+}
+
+// Equals invalidates when value changes, for inputs that aren't part of the
+// key but decide the result, such as the branch a ref was resolved for.
+func Equals(value string) Invalidator { return equals(value) }
+
+type equals string
+
+func (e equals) Stamp() (string, bool) { return string(e), true }
+
+func (equals) Expired(time.Time, time.Time) bool { return false }
+
+// All invalidates when any of invs does.
+func All(invs ...Invalidator) Invalidator { return all(invs) }
+
+type all []Invalidator
+
+func (a all) Stamp() (string, bool) {
+	stamps := make([]string, len(a))
+	for i, inv := range a {
+		stamp, ok := inv.Stamp()
+		if !ok {
+			return "", false
+		}
+		stamps[i] = stamp
+	}
+	return strings.Join(stamps, "\x00"), true
+}
+
+func (a all) Expired(cachedAt, now time.Time) bool {
+	for _, inv := range a {
+		if inv.Expired(cachedAt, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value cached under key while inv considers it valid, and
+// otherwise calls fetch and caches what it returns. Errors are not cached.
+// Keys share one namespace, so callers prefix them with what they hold,
+// e.g. "git_branch:" + headPath.
+func Get[T any](m *Manager, key string, inv Invalidator, fetch func() (T, error)) (T, error) {
+	var result T
+	var resultErr error
+
+	m.withFileLock(func() {
+		stamp, ok := inv.Stamp()
+		if !ok {
+			result, resultErr = fetchUnlocked(m, fetch)
+			return
+		}
+
+		// Check cache
+		m.mu.RLock()
+		value, ok := lookup[T](m.load(), key, stamp, inv, m.clock.Now())
+		m.mu.RUnlock()
+		if ok {
+			result = value
+			return
+		}
+
+		// Cache miss - fetch and store
+		value, err := fetchUnlocked(m, fetch)
+		if err != nil {
+			resultErr = err
+			return
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		// Re-check cache after acquiring write lock (TOCTOU protection)
+		cache := m.load()
+		if cached, ok := lookup[T](cache, key, stamp, inv, m.clock.Now()); ok {
+			result = cached
+			return
+		}
+
+		store(m, key, stamp, value)
+		result = value
+	})
+
+	return result, resultErr
+}
+
+// Set caches value under key as if Get had just fetched it.
+func Set[T any](m *Manager, key string, inv Invalidator, value T) {
+	m.withFileLock(func() {
+		stamp, ok := inv.Stamp()
+		if !ok {
+			return
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		store(m, key, stamp, value)
+	})
+}
+
+// Age returns how long ago the value under key was cached, or false if there
+// is none or inv's stamp has changed since, i.e. the entry is invalid
+// whether or not it has expired.
+func (m *Manager) Age(key string, inv Invalidator) (time.Duration, bool) {
+	var age time.Duration
+	var ok bool
+
+	m.withFileLock(func() {
+		stamp, stampOK := inv.Stamp()
+		if !stampOK {
+			return
+		}
+
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		if entry := m.load().Entries[key]; entry != nil && entry.Stamp == stamp {
+			age, ok = m.clock.Now().Sub(entry.CachedAt), true
+		}
+	})
+
+	return age, ok
+}
+
+// lookup returns the value cached under key if it was stamped with stamp and
+// hasn't expired. Entries that no longer decode as T are misses.
+func lookup[T any](cache *CacheFile, key, stamp string, inv Invalidator, now time.Time) (T, bool) {
+	var value T
+	entry := cache.Entries[key]
+	if entry == nil || entry.Stamp != stamp || inv.Expired(entry.CachedAt, now) {
+		return value, false
+	}
+	if err := json.Unmarshal(entry.Value, &value); err != nil {
+		return value, false
+	}
+	return value, true
+}
+
+// store caches value under key. The caller holds m.mu.
+func store[T any](m *Manager, key, stamp string, value T) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("failed to marshal cache entry", "key", key, "err", err)
+		return
+	}
+	entry := &CachedEntry{Value: data, Stamp: stamp, CachedAt: m.clock.Now()}
+	m.update(func(cache *CacheFile) {
+		if cache.Entries == nil {
+			cache.Entries = make(map[string]*CachedEntry)
+		}
+		cache.Entries[key] = entry
+	})
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testSegment struct {
+	Name  string
+	Count int
+}
+
+func TestGet_CachesUntilTTLExpires(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	calls := 0
+	fetch := func() (testSegment, error) {
+		calls++
+		return testSegment{Name: "seg", Count: calls}, nil
+	}
+
+	for range 2 {
+		got, err := Get(manager, "test_segment:a", TTL(time.Minute), fetch)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got != (testSegment{Name: "seg", Count: 1}) {
+			t.Errorf("Get() = %+v, want cached first fetch", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fetch called %d times, want 1", calls)
+	}
+
+	clock.Advance(time.Minute)
+	got, _ := Get(manager, "test_segment:a", TTL(time.Minute), fetch)
+	if got.Count != 2 || calls != 2 {
+		t.Errorf("after TTL Get() = %+v (calls %d), want refetch", got, calls)
+	}
+}
+
+func TestGet_FileMtime(t *testing.T) {
+	manager, dir, _ := setupTestCache(t)
+	path := filepath.Join(dir, "watched")
+	if err := os.WriteFile(path, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	Get(manager, "test_mtime:"+path, FileMtime(path), fetch)
+	Get(manager, "test_mtime:"+path, FileMtime(path), fetch)
+	if calls != 1 {
+		t.Fatalf("fetch called %d times, want 1", calls)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Get(manager, "test_mtime:"+path, FileMtime(path), fetch); got != 2 {
+		t.Errorf("after modification Get() = %d, want 2", got)
+	}
+
+	// A missing file can't be stamped, so nothing is cached.
+	missing := filepath.Join(dir, "missing")
+	Get(manager, "test_mtime:"+missing, FileMtime(missing), fetch)
+	Get(manager, "test_mtime:"+missing, FileMtime(missing), fetch)
+	if calls != 4 {
+		t.Errorf("fetch called %d times for missing file, want 4", calls)
+	}
+
+	// Unless the file is optional.
+	Get(manager, "test_optional:"+missing, OptionalFileMtime(missing), fetch)
+	Get(manager, "test_optional:"+missing, OptionalFileMtime(missing), fetch)
+	if calls != 5 {
+		t.Errorf("fetch called %d times for optional file, want 5", calls)
+	}
+}
+
+func TestGet_ErrorsNotCached(t *testing.T) {
+	manager, _, _ := setupTestCache(t)
+
+	wantErr := errors.New("boom")
+	if _, err := Get(manager, "test_err", TTL(time.Hour), func() (string, error) {
+		return "", wantErr
+	}); !errors.Is(err, wantErr) {
+		t.Fatalf("Get() error = %v, want %v", err, wantErr)
+	}
+
+	got, err := Get(manager, "test_err", TTL(time.Hour), func() (string, error) {
+		return "ok", nil
+	})
+	if err != nil || got != "ok" {
+		t.Errorf("Get() = %q, %v; want refetch after error", got, err)
+	}
+}
+
+func TestGet_All(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	validity := func(branch string) Invalidator {
+		return All(Equals(branch), TTL(time.Minute))
+	}
+
+	Get(manager, "test_all", validity("main"), fetch)
+	Get(manager, "test_all", validity("main"), fetch)
+	if calls != 1 {
+		t.Fatalf("fetch called %d times, want 1", calls)
+	}
+
+	Get(manager, "test_all", validity("feature"), fetch)
+	if calls != 2 {
+		t.Errorf("fetch called %d times after stamp change, want 2", calls)
+	}
+
+	clock.Advance(time.Minute)
+	Get(manager, "test_all", validity("feature"), fetch)
+	if calls != 3 {
+		t.Errorf("fetch called %d times after expiry, want 3", calls)
+	}
+}
+
+func TestGet_TypeMismatchIsMiss(t *testing.T) {
+	manager, _, _ := setupTestCache(t)
+
+	Set(manager, "test_type", TTL(time.Hour), "not a number")
+
+	got, err := Get(manager, "test_type", TTL(time.Hour), func() (int, error) {
+		return 42, nil
+	})
+	if err != nil || got != 42 {
+		t.Errorf("Get() = %d, %v; want refetch of mismatched entry", got, err)
+	}
+}
+
+func TestSetAndAge(t *testing.T) {
+	manager, _, clock := setupTestCache(t)
+
+	if _, ok := manager.Age("test_set", Equals("v1")); ok {
+		t.Error("Age() ok = true before Set")
+	}
+
+	Set(manager, "test_set", Equals("v1"), testSegment{Name: "set"})
+	clock.Advance(30 * time.Second)
+
+	if age, ok := manager.Age("test_set", Equals("v1")); !ok || age != 30*time.Second {
+		t.Errorf("Age() = %v, %v; want 30s, true", age, ok)
+	}
+	if _, ok := manager.Age("test_set", Equals("v2")); ok {
+		t.Error("Age() ok = true with a different stamp")
+	}
+
+	got, _ := Get(manager, "test_set", Equals("v1"), func() (testSegment, error) {
+		t.Error("fetch called for a value stored by Set")
+		return testSegment{}, nil
+	})
+	if got.Name != "set" {
+		t.Errorf("Get() = %+v, want value stored by Set", got)
+	}
+
+	// A fresh manager reads the entry back from disk.
+	reloaded := NewManagerWithClock(manager.cacheDir, clock)
+	if _, ok := reloaded.Age("test_set", Equals("v1")); !ok {
+		t.Error("entry not persisted to disk")
+	}
+}
@@ -0,0 +1,44 @@
+// Package ci provides CI actions that can be triggered from the command line.
+package ci
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/kostyay/claude-status/internal/github"
+	"github.com/kostyay/claude-status/internal/install"
+)
+
+// Rerunner finds and re-runs workflow runs. Implemented by github.Client.
+type Rerunner interface {
+	GetLatestRun(ctx context.Context, owner, repo, branch string) (github.Run, error)
+	RerunFailedJobs(ctx context.Context, owner, repo string, runID int64) error
+}
+
+// Rerun re-runs the failed jobs of the latest workflow run on branch after
+// asking for confirmation. It does nothing if the latest run did not fail.
+func Rerun(ctx context.Context, w io.Writer, r io.Reader, gh Rerunner, owner, repo, branch string) error {
+	run, err := gh.GetLatestRun(ctx, owner, repo, branch)
+	if err != nil {
+		return fmt.Errorf("failed to get latest run: %w", err)
+	}
+
+	if run.Status != github.StatusFailure {
+		fmt.Fprintf(w, "Latest run on %s is %s, nothing to re-run.\n", branch, run.Status)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Latest run on %s failed: %s\n", branch, run.URL)
+	if !install.Confirm(w, r, "Re-run failed jobs?") {
+		fmt.Fprintln(w, "Re-run cancelled.")
+		return nil
+	}
+
+	if err := gh.RerunFailedJobs(ctx, owner, repo, run.ID); err != nil {
+		return fmt.Errorf("failed to re-run workflow: %w", err)
+	}
+
+	fmt.Fprintln(w, "Re-run requested.")
+	return nil
+}
@@ -0,0 +1,88 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kostyay/claude-status/internal/github"
+)
+
+// mockRerunner is a test double for Rerunner.
+type mockRerunner struct {
+	run      github.Run
+	runErr   error
+	rerunErr error
+	rerunID  int64
+}
+
+func (m *mockRerunner) GetLatestRun(_ context.Context, owner, repo, branch string) (github.Run, error) {
+	return m.run, m.runErr
+}
+
+func (m *mockRerunner) RerunFailedJobs(_ context.Context, owner, repo string, runID int64) error {
+	m.rerunID = runID
+	return m.rerunErr
+}
+
+func TestRerun_Confirmed(t *testing.T) {
+	gh := &mockRerunner{run: github.Run{ID: 42, Status: github.StatusFailure, URL: "https://example.com/run/42"}}
+	var out bytes.Buffer
+
+	if err := Rerun(t.Context(), &out, strings.NewReader("y\n"), gh, "owner", "repo", "main"); err != nil {
+		t.Fatalf("Rerun() error = %v", err)
+	}
+	if gh.rerunID != 42 {
+		t.Errorf("RerunFailedJobs called with run %d, want 42", gh.rerunID)
+	}
+	if !strings.Contains(out.String(), "Re-run requested") {
+		t.Errorf("output = %q, want confirmation message", out.String())
+	}
+}
+
+func TestRerun_Declined(t *testing.T) {
+	gh := &mockRerunner{run: github.Run{ID: 42, Status: github.StatusFailure}}
+	var out bytes.Buffer
+
+	if err := Rerun(t.Context(), &out, strings.NewReader("n\n"), gh, "owner", "repo", "main"); err != nil {
+		t.Fatalf("Rerun() error = %v", err)
+	}
+	if gh.rerunID != 0 {
+		t.Errorf("RerunFailedJobs called after declining")
+	}
+	if !strings.Contains(out.String(), "cancelled") {
+		t.Errorf("output = %q, want cancellation message", out.String())
+	}
+}
+
+func TestRerun_NotFailed(t *testing.T) {
+	gh := &mockRerunner{run: github.Run{ID: 42, Status: github.StatusSuccess}}
+	var out bytes.Buffer
+
+	if err := Rerun(t.Context(), &out, strings.NewReader("y\n"), gh, "owner", "repo", "main"); err != nil {
+		t.Fatalf("Rerun() error = %v", err)
+	}
+	if gh.rerunID != 0 {
+		t.Errorf("RerunFailedJobs called for a successful run")
+	}
+	if !strings.Contains(out.String(), "nothing to re-run") {
+		t.Errorf("output = %q, want nothing-to-do message", out.String())
+	}
+}
+
+func TestRerun_Errors(t *testing.T) {
+	gh := &mockRerunner{runErr: errors.New("API error")}
+	if err := Rerun(t.Context(), &bytes.Buffer{}, strings.NewReader("y\n"), gh, "owner", "repo", "main"); err == nil {
+		t.Error("Rerun() expected error when latest run lookup fails")
+	}
+
+	gh = &mockRerunner{
+		run:      github.Run{ID: 42, Status: github.StatusFailure},
+		rerunErr: errors.New("forbidden"),
+	}
+	if err := Rerun(t.Context(), &bytes.Buffer{}, strings.NewReader("y\n"), gh, "owner", "repo", "main"); err == nil {
+		t.Error("Rerun() expected error when re-run request fails")
+	}
+}
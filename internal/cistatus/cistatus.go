@@ -0,0 +1,59 @@
+// Package cistatus defines the build states CI providers report and the
+// symbols they are shown with, so every provider renders the same way and
+// one "symbols" config entry restyles them all.
+package cistatus
+
+// Status is the state of a CI run.
+type Status string
+
+const (
+	Success Status = "success"
+	Failure Status = "failure"
+	Pending Status = "pending"
+	Error   Status = "error"
+
+	// RateLimited means the provider refused to answer until a rate limit
+	// resets, so the real state is unknown for a while.
+	RateLimited Status = "rate_limited"
+)
+
+// DefaultSymbols maps each Status to the emoji shown when no override is configured.
+var DefaultSymbols = map[Status]string{
+	Success: "✅",
+	Failure: "❌",
+	Pending: "🔄",
+	Error:   "⚠️",
+
+	RateLimited: "⏳",
+}
+
+// Symbol converts a Status to its symbol, preferring entries in overrides
+// (keyed by the status string, e.g. "success": "PASS") over DefaultSymbols.
+// Unknown statuses render as the error symbol.
+func Symbol(status Status, overrides map[string]string) string {
+	if _, known := DefaultSymbols[status]; !known {
+		status = Error
+	}
+	if sym, ok := overrides[string(status)]; ok {
+		return sym
+	}
+	return DefaultSymbols[status]
+}
+
+// Combine merges the statuses of several runs into one: any failure fails,
+// otherwise anything unfinished is pending. Error statuses don't count
+// unless no run reported anything else.
+func Combine(statuses ...Status) Status {
+	combined := Error
+	for _, status := range statuses {
+		switch {
+		case status == Failure:
+			return Failure
+		case status == Pending:
+			combined = Pending
+		case status == Success && combined == Error:
+			combined = Success
+		}
+	}
+	return combined
+}
@@ -0,0 +1,70 @@
+package cistatus
+
+import "testing"
+
+func TestSymbol(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{Success, "✅"},
+		{Failure, "❌"},
+		{Pending, "🔄"},
+		{Error, "⚠️"},
+		{Status("unknown"), "⚠️"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := Symbol(tt.status, nil); got != tt.want {
+				t.Errorf("Symbol(%q) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSymbol_Overrides(t *testing.T) {
+	overrides := map[string]string{"success": "PASS", "failure": "FAIL", "error": "?"}
+
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{Success, "PASS"},
+		{Failure, "FAIL"},
+		{Pending, "🔄"},
+		{Error, "?"},
+		{Status("unknown"), "?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := Symbol(tt.status, overrides); got != tt.want {
+				t.Errorf("Symbol(%q) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []Status
+		want     Status
+	}{
+		{"none", nil, Error},
+		{"all pass", []Status{Success, Success}, Success},
+		{"any failure", []Status{Success, Pending, Failure}, Failure},
+		{"any pending", []Status{Success, Pending}, Pending},
+		{"errors ignored", []Status{Error, Success}, Success},
+		{"only errors", []Status{Error, Error}, Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Combine(tt.statuses...); got != tt.want {
+				t.Errorf("Combine(%v) = %q, want %q", tt.statuses, got, tt.want)
+			}
+		})
+	}
+}
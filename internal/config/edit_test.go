@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSet_CreatesFileWithDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-status", "config.json")
+
+	if err := Set(path, "github_ttl", "120"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("written config is not JSON: %v\n%s", err, data)
+	}
+	if string(raw["github_ttl"]) != "120" {
+		t.Errorf("github_ttl = %s, want 120", raw["github_ttl"])
+	}
+	if _, ok := raw["github_workflow"]; !ok {
+		t.Error("new config is missing the github_workflow default")
+	}
+	if _, ok := raw["template"]; ok {
+		t.Error("new config pins the template, want it to follow the default")
+	}
+	if err := Validate(path); err != nil {
+		t.Errorf("Validate() of the written config = %v", err)
+	}
+	if cfg := LoadFrom(path); cfg.GitHubTTL != 120 {
+		t.Errorf("GitHubTTL = %d, want 120", cfg.GitHubTTL)
+	}
+}
+
+func TestSet_KeepsOtherKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"github_workflow": "ci"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Set(path, "beads_next_label", "backend"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Set(path, "symbols", `{"success": "OK"}`); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	cfg := LoadFrom(path)
+	if !slices.Equal(cfg.GitHubWorkflow, Workflows{"ci"}) || cfg.BeadsNextLabel != "backend" || cfg.Symbols["success"] != "OK" {
+		t.Errorf("config = workflow %q, label %q, symbols %v", cfg.GitHubWorkflow, cfg.BeadsNextLabel, cfg.Symbols)
+	}
+}
+
+func TestSet_Workflows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	if err := Set(path, "github_workflow", "ci"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := Get(path, "github_workflow"); got != "ci" {
+		t.Errorf("Get() = %q, want a bare name", got)
+	}
+
+	if err := Set(path, "github_workflow", `["test", "lint"]`); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if cfg := LoadFrom(path); !slices.Equal(cfg.GitHubWorkflow, Workflows{"test", "lint"}) {
+		t.Errorf("GitHubWorkflow = %q, want both workflows", cfg.GitHubWorkflow)
+	}
+	if got, _ := Get(path, "github_workflow"); got != `["test","lint"]` {
+		t.Errorf("Get() = %q, want the list", got)
+	}
+}
+
+func TestSet_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"github_ttl": 60}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"github_tll", "120", "unknown config key"},
+		{"github_ttl", "soon", "not valid JSON"},
+		{"github_ttl", `"120"`, "github_ttl"},
+		{"template", "{{.Model", "template"},
+	}
+	for _, tt := range tests {
+		err := Set(path, tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Set(%q, %q) error = %v, want it to mention %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != `{"github_ttl": 60}` {
+		t.Errorf("config after failed Sets = %s, want it untouched", data)
+	}
+}
+
+func TestGetAndList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"github_ttl": 90, "icons": "nerdfont"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"github_ttl": "90",
+		"icons":      "nerdfont",
+		"tasks_ttl":  "5",
+	} {
+		if got, err := Get(path, key); err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if _, err := Get(path, "nope"); err == nil {
+		t.Error("Get(nope) error = nil, want unknown key")
+	}
+
+	settings := List(path)
+	if len(settings) != len(Keys()) {
+		t.Fatalf("List() returned %d settings, want one per key (%d)", len(settings), len(Keys()))
+	}
+	if settings[0].Key != Keys()[0] {
+		t.Errorf("List()[0] = %q, want keys in declaration order", settings[0].Key)
+	}
+}
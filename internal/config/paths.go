@@ -28,6 +28,12 @@ func ConfigPath() string {
 	return filepath.Join(ConfigDir(), "config.json")
 }
 
+// TemplateTestDir returns the directory of template fixtures run by
+// "claude-status template test".
+func TemplateTestDir() string {
+	return filepath.Join(ConfigDir(), "template-tests")
+}
+
 // CachePath returns the full path to the cache file.
 func CachePath() string {
 	return filepath.Join(CacheDir(), "cache.json")
@@ -37,3 +43,29 @@ func CachePath() string {
 func LogPath() string {
 	return filepath.Join(DataDir(), "status_line.json")
 }
+
+// TelemetryPath returns the path to the local performance telemetry file.
+func TelemetryPath() string {
+	return filepath.Join(DataDir(), "perf.json")
+}
+
+// BackgroundPath returns where `claude-status theme detect` saves the
+// terminal background for the "auto" theme.
+func BackgroundPath() string {
+	return filepath.Join(DataDir(), "background")
+}
+
+// CrashDir returns where crash reports for panics and hung renders go.
+func CrashDir() string {
+	return filepath.Join(DataDir(), "crashes")
+}
+
+// StatePath returns the path to the persistent per-repository state file.
+func StatePath() string {
+	return filepath.Join(DataDir(), "state.json")
+}
+
+// SocketPath returns the unix socket the daemon listens on.
+func SocketPath() string {
+	return filepath.Join(xdg.RuntimeDir, appName+".sock")
+}
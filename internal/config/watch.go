@@ -0,0 +1,51 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch reloads the config at path whenever it is written, created, or
+// replaced, and passes the result to onChange. It watches the parent directory
+// so editors that save by renaming a temp file are still detected.
+// Blocks until ctx is cancelled.
+func Watch(ctx context.Context, path string, onChange func(Config)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	name := filepath.Clean(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != name {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			slog.Debug("config changed, reloading", "path", path, "op", event.Op.String())
+			onChange(LoadFrom(path))
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("config watcher error", "err", err)
+		}
+	}
+}
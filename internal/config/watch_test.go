@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch_ReloadsOnWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"github_ttl": 30}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloaded := make(chan Config, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, path, func(cfg Config) { reloaded <- cfg })
+	}()
+
+	// Give the watcher time to register before writing
+	time.Sleep(100 * time.Millisecond)
+
+	// Unrelated files in the same directory must not trigger a reload
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"github_ttl": 90}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cfg := <-reloaded:
+		if cfg.GitHubTTL != 90 {
+			t.Errorf("reloaded GitHubTTL = %d, want 90", cfg.GitHubTTL)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not report the config change")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not return after cancel")
+	}
+}
@@ -0,0 +1,92 @@
+// Package crash writes crash reports for panics and hung renders, so a bug
+// turns into a file to attach to an issue instead of a blank status line or
+// a stack trace in Claude Code's UI.
+package crash
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxReports is how many reports are kept; older ones are deleted.
+const MaxReports = 10
+
+// reportPrefix starts every report's file name.
+const reportPrefix = "crash-"
+
+// Write saves a report of reason, with the stacks of all goroutines, to a
+// new file in dir and returns its path.
+func Write(dir, reason string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create crash report dir: %w", err)
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	fmt.Fprintf(&b, "claude-status %s crashed at %s\n", version(), now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "%s %s/%s, pid %d\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, os.Getpid())
+	fmt.Fprintf(&b, "%s\n\n", reason)
+	b.Write(stacks())
+
+	path := filepath.Join(dir, reportPrefix+now.Format("20060102-150405.000000000")+".log")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	prune(dir)
+	return path, nil
+}
+
+// Panic writes a report for a value recovered from a panic, logs a single
+// line saying where it went, and returns the panic as an error.
+func Panic(dir string, recovered any) error {
+	err := fmt.Errorf("panic: %v", recovered)
+	path, writeErr := Write(dir, err.Error())
+	if writeErr != nil {
+		slog.Error("recovered from a panic", "err", err, "report_err", writeErr)
+		return err
+	}
+	slog.Error("recovered from a panic", "err", err, "report", path)
+	return fmt.Errorf("%w (crash report: %s)", err, path)
+}
+
+// Reports returns the paths of the reports in dir, oldest first.
+func Reports(dir string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, reportPrefix+"*.log"))
+	slices.Sort(paths) // Names sort by time
+	return paths
+}
+
+// prune deletes all but the newest MaxReports reports in dir.
+func prune(dir string) {
+	paths := Reports(dir)
+	for _, path := range paths[:max(len(paths)-MaxReports, 0)] {
+		os.Remove(path)
+	}
+}
+
+// stacks returns the stack traces of all goroutines.
+func stacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 8<<20 {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// version returns the module version the binary was built from.
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
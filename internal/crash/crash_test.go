@@ -0,0 +1,71 @@
+package crash
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := Write(dir, "panic: boom")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"claude-status", "panic: boom", "goroutine", "TestWrite"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("report doesn't mention %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestWrite_KeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+
+	var paths []string
+	for range MaxReports + 3 {
+		path, err := Write(dir, "panic")
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	reports := Reports(dir)
+	if len(reports) != MaxReports {
+		t.Fatalf("Reports() = %d files, want %d", len(reports), MaxReports)
+	}
+	if last := paths[len(paths)-1]; reports[len(reports)-1] != last {
+		t.Errorf("newest report = %q, want %q", reports[len(reports)-1], last)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("oldest report still exists: %v", err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	dir := t.TempDir()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = Panic(dir, r)
+			}
+		}()
+		var m map[string]int
+		m["x"] = 1
+		return nil
+	}()
+
+	if err == nil || !strings.Contains(err.Error(), "assignment to entry in nil map") || !strings.Contains(err.Error(), dir) {
+		t.Errorf("Panic() = %v, want the panic and the report path", err)
+	}
+	if len(Reports(dir)) != 1 {
+		t.Errorf("Reports() = %v, want one", Reports(dir))
+	}
+}
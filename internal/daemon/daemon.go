@@ -0,0 +1,135 @@
+// Package daemon serves rendered status lines over a unix socket, so a
+// long-running process with warm caches can answer each status line request
+// instead of a cold one-shot run.
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrRunning is returned by Listen when another daemon owns the socket.
+var ErrRunning = errors.New("daemon already running")
+
+// Request asks the daemon to render a status line.
+type Request struct {
+	Input       json.RawMessage `json:"input"`        // statusLine JSON payload from stdin
+	Prefix      string          `json:"prefix"`       // -prefix flag
+	PrefixColor string          `json:"prefix_color"` // -prefix-color flag
+	Format      string          `json:"format"`       // -format flag; empty uses the config
+}
+
+// Response carries the rendered line or the reason rendering failed.
+type Response struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Handler renders a status line for a request. ctx is cancelled when the
+// daemon shuts down or the request times out.
+type Handler func(ctx context.Context, req Request) (string, error)
+
+// requestTimeout bounds how long a client connection may stay open.
+const requestTimeout = 10 * time.Second
+
+// Listen opens the unix socket at path. A socket left behind by a daemon
+// that exited without cleaning up is replaced; a live one yields ErrRunning.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w on %s", ErrRunning, path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// Serve answers requests on ln with handler until ctx is cancelled, then
+// closes ln (which removes the socket file).
+func Serve(ctx context.Context, ln net.Listener, handler Handler) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go serveConn(ctx, conn, handler)
+	}
+}
+
+// serveConn handles one newline-delimited JSON request on conn.
+func serveConn(ctx context.Context, conn net.Conn, handler Handler) {
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	var req Request
+	var resp Response
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+	if err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if resp.Output, err = handler(ctx, req); err != nil {
+		resp.Error = err.Error()
+	}
+
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		slog.Debug("failed to write daemon response", "err", err)
+	}
+}
+
+// Render asks the daemon listening on path to render req. It fails fast when
+// no daemon is running, so callers can fall back to rendering themselves.
+func Render(path string, req Request, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Output, nil
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// socketPath returns a short socket path; unix socket paths are limited to
+// about 100 bytes, which t.TempDir() can exceed.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "csd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "d.sock")
+}
+
+func startServer(t *testing.T, path string, handler Handler) {
+	t.Helper()
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, ln, handler) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+}
+
+func TestRender_RoundTrip(t *testing.T) {
+	path := socketPath(t)
+	startServer(t, path, func(_ context.Context, req Request) (string, error) {
+		return req.Prefix + ":" + string(req.Input), nil
+	})
+
+	got, err := Render(path, Request{Input: []byte(`{"a":1}`), Prefix: "dev"}, time.Second)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := `dev:{"a":1}`; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRender_HandlerError(t *testing.T) {
+	path := socketPath(t)
+	startServer(t, path, func(context.Context, Request) (string, error) {
+		return "", errors.New("boom")
+	})
+
+	_, err := Render(path, Request{Input: []byte(`{}`)}, time.Second)
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("Render() error = %v, want %q", err, "boom")
+	}
+}
+
+func TestRender_NoDaemon(t *testing.T) {
+	start := time.Now()
+	if _, err := Render(socketPath(t), Request{}, time.Second); err == nil {
+		t.Fatal("Render() should fail without a daemon")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Render() took %v, want it to fail fast", elapsed)
+	}
+}
+
+func TestListen_AlreadyRunning(t *testing.T) {
+	path := socketPath(t)
+	startServer(t, path, func(context.Context, Request) (string, error) { return "", nil })
+
+	_, err := Listen(path)
+	if !errors.Is(err, ErrRunning) {
+		t.Errorf("Listen() error = %v, want ErrRunning", err)
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	path := socketPath(t)
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ln.Close()
+}
+
+func TestServe_InvalidRequest(t *testing.T) {
+	path := socketPath(t)
+	startServer(t, path, func(context.Context, Request) (string, error) { return "unused", nil })
+
+	// Render always sends valid JSON, so talk to the socket directly
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("not json\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !strings.HasPrefix(resp.Error, "invalid request") {
+		t.Errorf("Error = %q, want invalid request", resp.Error)
+	}
+}
@@ -0,0 +1,367 @@
+// Package doctor diagnoses the environment claude-status runs in: the tools
+// it shells out to, the repository and transcript it reads, and its own
+// config, cache, and install.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/github"
+	"github.com/kostyay/claude-status/internal/install"
+	"github.com/kostyay/claude-status/internal/tasks"
+	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/tokens"
+
+	// Register task providers for the task tracker check
+	_ "github.com/kostyay/claude-status/internal/beads"
+	_ "github.com/kostyay/claude-status/internal/kt"
+	_ "github.com/kostyay/claude-status/internal/tk"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	Pass Status = iota // Working
+	Warn               // Optional feature unavailable
+	Fail               // Broken; the status line is missing something it should show
+)
+
+// symbol returns the marker printed before a result.
+func (s Status) symbol() string {
+	switch s {
+	case Pass:
+		return "✓"
+	case Warn:
+		return "!"
+	default:
+		return "✗"
+	}
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name   string // What was checked, e.g. "git"
+	Status Status
+	Detail string // What was found
+	Fix    string // How to fix a warning or failure
+}
+
+// ErrChecksFailed is returned by Run when at least one check failed.
+var ErrChecksFailed = errors.New("doctor found problems")
+
+// Env is what the checks inspect. DefaultEnv fills it for the current
+// process; tests substitute their own paths and commands.
+type Env struct {
+	WorkDir        string // Directory the status line runs in
+	TranscriptPath string // Transcript to read; empty picks WorkDir's newest
+	ClaudeDir      string // Claude Code config dir (~/.claude)
+	SettingsPath   string // Claude Code settings.json
+	BinaryPath     string // This binary, as settings should reference it
+	ConfigPath     string // claude-status config file
+	CacheDir       string // claude-status cache directory
+
+	LookPath func(file string) (string, error)
+	Output   func(name string, args ...string) ([]byte, error)
+	Getenv   func(key string) string
+}
+
+// DefaultEnv returns the environment for the current process in workDir.
+func DefaultEnv(workDir string) Env {
+	settingsPath := install.GetSettingsPath()
+	binaryPath, _ := install.ExecutablePath()
+	return Env{
+		WorkDir:      workDir,
+		ClaudeDir:    filepath.Dir(settingsPath),
+		SettingsPath: settingsPath,
+		BinaryPath:   binaryPath,
+		ConfigPath:   config.ConfigPath(),
+		CacheDir:     config.CacheDir(),
+		LookPath:     exec.LookPath,
+		Output: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).Output()
+		},
+		Getenv: os.Getenv,
+	}
+}
+
+// Check runs every check against env.
+func Check(ctx context.Context, env Env) []Result {
+	return []Result{
+		checkGit(env),
+		checkRepo(ctx, env),
+		checkGitHubAuth(env),
+		checkTaskTracker(env),
+		checkTranscript(env),
+		checkCacheDir(env),
+		checkConfig(env),
+		checkInstall(env),
+	}
+}
+
+// Run prints the result of every check to w. It returns ErrChecksFailed if
+// any check failed; warnings alone don't count.
+func Run(ctx context.Context, w io.Writer, env Env) error {
+	failed, warned := 0, 0
+	for _, r := range Check(ctx, env) {
+		fmt.Fprintf(w, "%s %s: %s\n", r.Status.symbol(), r.Name, r.Detail)
+		if r.Fix != "" {
+			fmt.Fprintf(w, "    fix: %s\n", r.Fix)
+		}
+		switch r.Status {
+		case Fail:
+			failed++
+		case Warn:
+			warned++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w (%d failed)", ErrChecksFailed, failed)
+	}
+	if warned > 0 {
+		fmt.Fprintf(w, "\nNo problems found; %d optional %s unavailable.\n", warned, plural(warned, "feature is", "features are"))
+		return nil
+	}
+	fmt.Fprintln(w, "\nAll checks passed.")
+	return nil
+}
+
+// plural picks singular when n is 1.
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
+
+func checkGit(env Env) Result {
+	r := Result{Name: "git"}
+	if _, err := env.LookPath("git"); err != nil {
+		r.Status, r.Detail = Warn, "git not found on PATH; repositories are read with the built-in go-git backend"
+		r.Fix = "install git; the built-in backend is slower on large repositories"
+		return r
+	}
+	out, err := env.Output("git", "--version")
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("git --version failed: %v", err)
+		r.Fix = "check your git installation"
+		return r
+	}
+	r.Detail = strings.TrimSpace(string(out))
+	return r
+}
+
+func checkRepo(ctx context.Context, env Env) Result {
+	r := Result{Name: "repository"}
+	cfg := loadConfig(env)
+	client, err := git.Open(ctx, env.WorkDir, cfg.GitBackend)
+	if err != nil {
+		r.Status, r.Detail = Warn, fmt.Sprintf("%s is not in a git repository", env.WorkDir)
+		r.Fix = "git segments only show inside a repository"
+		return r
+	}
+
+	remoteURL, err := client.RemoteURL(ctx)
+	if err != nil {
+		r.Detail = client.GitDir() + " (no origin remote, so no CI status)"
+		return r
+	}
+	host := cfg.GitHubHost
+	if owner, repo, ok := git.ParseGitHubRepoHost(remoteURL, host); ok {
+		r.Detail = fmt.Sprintf("%s (GitHub %s/%s)", client.GitDir(), owner, repo)
+		return r
+	}
+	if host == "" {
+		host = git.DefaultGitHubHost
+	}
+	r.Detail = fmt.Sprintf("%s (origin %s is not on %s, so no CI status)", client.GitDir(), remoteURL, host)
+	return r
+}
+
+// loadConfig returns the config the status line would use in env.WorkDir.
+func loadConfig(env Env) config.Config {
+	return config.WithProject(config.LoadFrom(env.ConfigPath), env.WorkDir)
+}
+
+// checkGitHubAuth looks for a token where the GitHub client does: the
+// environment, then github_token_command, then gh.
+func checkGitHubAuth(env Env) Result {
+	r := Result{Name: "github auth"}
+	cfg := loadConfig(env)
+	for _, name := range github.TokenEnvVars(cfg.GitHubHost) {
+		if strings.TrimSpace(env.Getenv(name)) != "" {
+			r.Detail = "token from $" + name
+			return r
+		}
+	}
+	if cfg.GitHubTokenCommand != "" {
+		out, err := env.Output("sh", "-c", cfg.GitHubTokenCommand)
+		if err != nil || strings.TrimSpace(string(out)) == "" {
+			r.Status, r.Detail = Fail, "github_token_command printed no token"
+			r.Fix = "check github_token_command in " + env.ConfigPath
+			return r
+		}
+		r.Detail = "github_token_command ok"
+		return r
+	}
+
+	if _, err := env.LookPath("gh"); err != nil {
+		r.Status, r.Detail = Warn, "gh not found on PATH"
+		r.Fix = "install the GitHub CLI (https://cli.github.com/) or set GH_TOKEN to show CI status"
+		return r
+	}
+	args := []string{"auth", "token"}
+	login := "gh auth login"
+	if host := cfg.GitHubHost; host != "" && host != git.DefaultGitHubHost {
+		args = append(args, "--hostname", host)
+		login += " --hostname " + host
+	}
+	out, err := env.Output("gh", args...)
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		r.Status, r.Detail = Fail, "gh auth token returned no token"
+		r.Fix = "run `" + login + "`"
+		return r
+	}
+	r.Detail = "gh auth token ok"
+	return r
+}
+
+// taskBinaries maps task provider names to the command each one runs.
+var taskBinaries = map[string]string{"kt": "kt", "tk": "tk", "beads": "bd"}
+
+func checkTaskTracker(env Env) Result {
+	r := Result{Name: "task tracker"}
+	provider := tasks.FindProvider(env.WorkDir, "")
+	if provider == nil {
+		r.Detail = "none in this directory or its parents (optional: kt, tk, or beads)"
+		return r
+	}
+
+	bin := taskBinaries[provider.Name()]
+	if _, err := env.LookPath(bin); err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s tasks found but %s is not on PATH", provider.Name(), bin)
+		r.Fix = fmt.Sprintf("install %s, or make sure it's on the PATH Claude Code runs with", bin)
+		return r
+	}
+	r.Detail = fmt.Sprintf("%s (%s)", provider.Name(), bin)
+	return r
+}
+
+func checkTranscript(env Env) Result {
+	r := Result{Name: "transcript"}
+	path := env.TranscriptPath
+	if path == "" {
+		path = tokens.NewestTranscript(env.ClaudeDir, env.WorkDir)
+		if path == "" {
+			r.Status, r.Detail = Warn, "no transcript found for this directory"
+			r.Fix = "start a Claude Code session here; token and context metrics come from its transcript"
+			return r
+		}
+	}
+
+	metrics, err := tokens.ParseTranscript(path)
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("cannot read %s: %v", path, err)
+		r.Fix = "check the transcript's permissions"
+		return r
+	}
+	r.Detail = fmt.Sprintf("%s (%s tokens)", path, tokens.FormatTokens(metrics.TotalTokens))
+	return r
+}
+
+func checkCacheDir(env Env) Result {
+	r := Result{Name: "cache"}
+	if err := os.MkdirAll(env.CacheDir, 0755); err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("cannot create %s: %v", env.CacheDir, err)
+		r.Fix = "check permissions on the parent directory"
+		return r
+	}
+
+	f, err := os.CreateTemp(env.CacheDir, ".doctor-*")
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s is not writable: %v", env.CacheDir, err)
+		r.Fix = "fix permissions with `chmod u+w " + env.CacheDir + "`"
+		return r
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	r.Detail = env.CacheDir + " is writable"
+	return r
+}
+
+func checkConfig(env Env) Result {
+	r := Result{Name: "config"}
+	if err := config.Validate(env.ConfigPath); err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s: %v", env.ConfigPath, err)
+		r.Fix = "fix the file; until then the defaults are used"
+		return r
+	}
+
+	project := config.FindProjectConfig(env.WorkDir)
+	if project != "" {
+		if err := config.Validate(project); err != nil {
+			r.Status, r.Detail = Fail, fmt.Sprintf("%s: %v", project, err)
+			r.Fix = "fix the project config; until then it is ignored"
+			return r
+		}
+	}
+
+	cfg := loadConfig(env)
+	if _, err := template.NewEngineWithSymbols(cfg.Template, cfg.IconSymbols()); err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("template does not parse: %v", err)
+		r.Fix = "fix \"template\" in " + env.ConfigPath + "; until then the default template is used"
+		if project != "" {
+			r.Fix = "fix \"template\" in " + env.ConfigPath + " or " + project + "; until then the default template is used"
+		}
+		return r
+	}
+
+	if _, err := os.Stat(env.ConfigPath); err != nil {
+		r.Detail = "no config file, using defaults"
+	} else {
+		r.Detail = env.ConfigPath + " is valid"
+	}
+	if project != "" {
+		r.Detail += "; project config " + project + " is valid"
+	}
+	return r
+}
+
+func checkInstall(env Env) Result {
+	r := Result{Name: "install"}
+	settings, err := install.ReadSettings(env.SettingsPath)
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s: %v", env.SettingsPath, err)
+		r.Fix = "fix the settings file so Claude Code can read it"
+		return r
+	}
+	if _, ok := settings["statusLine"]; !ok {
+		r.Status, r.Detail = Warn, "no statusLine in "+env.SettingsPath
+		r.Fix = "run `claude-status -install`"
+		return r
+	}
+
+	warning, err := install.CheckDrift(env.SettingsPath, env.BinaryPath)
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+	if warning != "" {
+		r.Status, r.Detail = Fail, warning
+		r.Fix = "run `claude-status -install` to point settings at " + env.BinaryPath
+		return r
+	}
+	r.Detail = "statusLine runs " + env.BinaryPath
+	return r
+}
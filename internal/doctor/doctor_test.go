@@ -0,0 +1,279 @@
+package doctor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kostyay/claude-status/internal/config"
+)
+
+// testEnv returns an Env rooted in temp dirs where every tool is installed
+// and gh is logged in.
+func testEnv(t *testing.T) Env {
+	t.Helper()
+	root := t.TempDir()
+	return Env{
+		WorkDir:      filepath.Join(root, "work"),
+		ClaudeDir:    filepath.Join(root, "claude"),
+		SettingsPath: filepath.Join(root, "claude", "settings.json"),
+		BinaryPath:   filepath.Join(root, "bin", "claude-status"),
+		ConfigPath:   filepath.Join(root, "config", "config.json"),
+		CacheDir:     filepath.Join(root, "cache"),
+		LookPath:     func(file string) (string, error) { return "/usr/bin/" + file, nil },
+		Getenv:       func(string) string { return "" },
+		Output: func(name string, args ...string) ([]byte, error) {
+			switch name {
+			case "git":
+				return []byte("git version 2.45.0\n"), nil
+			case "gh":
+				return []byte("gho_token\n"), nil
+			}
+			return nil, errors.New("unexpected command")
+		},
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// result returns the named check's result.
+func result(t *testing.T, env Env, name string) Result {
+	t.Helper()
+	for _, r := range Check(t.Context(), env) {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("no %q check", name)
+	return Result{}
+}
+
+func TestCheck_Git(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "git"); r.Status != Pass || r.Detail != "git version 2.45.0" {
+		t.Errorf("git = %+v, want pass with version", r)
+	}
+
+	env.LookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	if r := result(t, env, "git"); r.Status != Warn || r.Fix == "" {
+		t.Errorf("git = %+v, want warn with a fix (go-git still reads the repository)", r)
+	}
+}
+
+func TestCheck_GitHubAuth(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "github auth"); r.Status != Pass {
+		t.Errorf("github auth = %+v, want pass", r)
+	}
+
+	env.Output = func(name string, args ...string) ([]byte, error) { return nil, errors.New("exit status 1") }
+	if r := result(t, env, "github auth"); r.Status != Fail || !strings.Contains(r.Fix, "gh auth login") {
+		t.Errorf("github auth = %+v, want fail suggesting gh auth login", r)
+	}
+
+	env.LookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	if r := result(t, env, "github auth"); r.Status != Warn {
+		t.Errorf("github auth = %+v, want warn without gh", r)
+	}
+}
+
+func TestCheck_GitHubAuth_Enterprise(t *testing.T) {
+	env := testEnv(t)
+	writeFile(t, env.ConfigPath, `{"github_host": "github.mycorp.com"}`)
+
+	var gotArgs []string
+	env.Output = func(name string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return nil, errors.New("exit status 1")
+	}
+	r := result(t, env, "github auth")
+	if want := "auth token --hostname github.mycorp.com"; strings.Join(gotArgs, " ") != want {
+		t.Errorf("gh args = %q, want %q", gotArgs, want)
+	}
+	if !strings.Contains(r.Fix, "gh auth login --hostname github.mycorp.com") {
+		t.Errorf("github auth fix = %q, want a login for the enterprise host", r.Fix)
+	}
+}
+
+func TestCheck_GitHubAuth_TokenSources(t *testing.T) {
+	env := testEnv(t)
+	env.LookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	env.Getenv = func(key string) string {
+		if key == "GITHUB_TOKEN" {
+			return "ghp_env"
+		}
+		return ""
+	}
+	if r := result(t, env, "github auth"); r.Status != Pass || r.Detail != "token from $GITHUB_TOKEN" {
+		t.Errorf("github auth = %+v, want pass from the environment without gh", r)
+	}
+
+	env.Getenv = func(string) string { return "" }
+	writeFile(t, env.ConfigPath, `{"github_token_command": "pass show github"}`)
+	var gotArgs []string
+	env.Output = func(name string, args ...string) ([]byte, error) {
+		gotArgs = append([]string{name}, args...)
+		return []byte("ghp_command\n"), nil
+	}
+	if r := result(t, env, "github auth"); r.Status != Pass || r.Detail != "github_token_command ok" {
+		t.Errorf("github auth = %+v, want pass from the command", r)
+	}
+	if want := "sh -c pass show github"; strings.Join(gotArgs, " ") != want {
+		t.Errorf("command = %q, want %q", gotArgs, want)
+	}
+
+	env.Output = func(name string, args ...string) ([]byte, error) { return nil, errors.New("exit status 1") }
+	if r := result(t, env, "github auth"); r.Status != Fail || !strings.Contains(r.Fix, "github_token_command") {
+		t.Errorf("github auth = %+v, want fail pointing at github_token_command", r)
+	}
+}
+
+func TestCheck_TaskTracker(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "task tracker"); r.Status != Pass {
+		t.Errorf("task tracker = %+v, want pass with no tracker", r)
+	}
+
+	if err := os.MkdirAll(filepath.Join(env.WorkDir, ".beads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if r := result(t, env, "task tracker"); r.Status != Pass || r.Detail != "beads (bd)" {
+		t.Errorf("task tracker = %+v, want pass for beads", r)
+	}
+
+	env.LookPath = func(file string) (string, error) {
+		if file == "bd" {
+			return "", exec.ErrNotFound
+		}
+		return "/usr/bin/" + file, nil
+	}
+	if r := result(t, env, "task tracker"); r.Status != Fail || !strings.Contains(r.Detail, "bd is not on PATH") {
+		t.Errorf("task tracker = %+v, want fail for missing bd", r)
+	}
+}
+
+func TestCheck_TaskTrackerInRepoRoot(t *testing.T) {
+	env := testEnv(t)
+	for _, dir := range []string{".git", ".beads", "sub"} {
+		if err := os.MkdirAll(filepath.Join(env.WorkDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.WorkDir = filepath.Join(env.WorkDir, "sub")
+
+	if r := result(t, env, "task tracker"); r.Status != Pass || r.Detail != "beads (bd)" {
+		t.Errorf("task tracker = %+v, want beads found at the repo root", r)
+	}
+}
+
+func TestCheck_Transcript(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "transcript"); r.Status != Warn {
+		t.Errorf("transcript = %+v, want warn with no sessions", r)
+	}
+
+	// Claude Code names the project dir after the path with "/" and "." as "-"
+	env.WorkDir = "/home/me/my.project"
+	transcript := filepath.Join(env.ClaudeDir, "projects", "-home-me-my-project", "session.jsonl")
+	writeFile(t, transcript, `{"type":"assistant","message":{"role":"assistant","usage":{"input_tokens":1500}}}`+"\n")
+
+	r := result(t, env, "transcript")
+	if r.Status != Pass || r.Detail != transcript+" (1.5k tokens)" {
+		t.Errorf("transcript = %+v, want pass for %s", r, transcript)
+	}
+
+	env.TranscriptPath = filepath.Join(t.TempDir(), "missing.jsonl")
+	if r := result(t, env, "transcript"); r.Status != Fail {
+		t.Errorf("transcript = %+v, want fail for an unreadable transcript", r)
+	}
+}
+
+func TestCheck_CacheDir(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "cache"); r.Status != Pass {
+		t.Errorf("cache = %+v, want pass", r)
+	}
+
+	// A file where the directory should be
+	writeFile(t, env.CacheDir+"-file", "")
+	env.CacheDir += "-file"
+	if r := result(t, env, "cache"); r.Status != Fail {
+		t.Errorf("cache = %+v, want fail", r)
+	}
+}
+
+func TestCheck_Config(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "config"); r.Status != Pass || r.Detail != "no config file, using defaults" {
+		t.Errorf("config = %+v, want pass with defaults", r)
+	}
+
+	writeFile(t, env.ConfigPath, `{"github_workfow": "ci"}`)
+	if r := result(t, env, "config"); r.Status != Fail || !strings.Contains(r.Detail, "github_workfow") {
+		t.Errorf("config = %+v, want fail naming the unknown key", r)
+	}
+
+	writeFile(t, env.ConfigPath, `{"template": "{{.Model"}`)
+	if r := result(t, env, "config"); r.Status != Fail || !strings.Contains(r.Detail, "template") {
+		t.Errorf("config = %+v, want fail for the template", r)
+	}
+
+	writeFile(t, env.ConfigPath, `{}`)
+	project := filepath.Join(env.WorkDir, config.ProjectFile)
+	writeFile(t, project, `{"github_ttl": "soon"}`)
+	if r := result(t, env, "config"); r.Status != Fail || !strings.Contains(r.Detail, project) {
+		t.Errorf("config = %+v, want fail naming the project config", r)
+	}
+
+	writeFile(t, project, `{"github_ttl": 30}`)
+	if r := result(t, env, "config"); r.Status != Pass || !strings.Contains(r.Detail, "project config") {
+		t.Errorf("config = %+v, want pass mentioning the project config", r)
+	}
+}
+
+func TestCheck_Install(t *testing.T) {
+	env := testEnv(t)
+	if r := result(t, env, "install"); r.Status != Warn {
+		t.Errorf("install = %+v, want warn when not installed", r)
+	}
+
+	writeFile(t, env.BinaryPath, "#!/bin/sh\n")
+	if err := os.Chmod(env.BinaryPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, env.SettingsPath, `{"statusLine": {"type": "command", "command": "`+env.BinaryPath+`"}}`)
+	if r := result(t, env, "install"); r.Status != Pass {
+		t.Errorf("install = %+v, want pass", r)
+	}
+
+	writeFile(t, env.SettingsPath, `{"statusLine": {"type": "command", "command": "/old/bin/claude-status"}}`)
+	if r := result(t, env, "install"); r.Status != Fail || !strings.Contains(r.Fix, "-install") {
+		t.Errorf("install = %+v, want fail suggesting -install", r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	env := testEnv(t)
+	env.Output = func(name string, args ...string) ([]byte, error) { return nil, errors.New("exit status 1") }
+
+	var out bytes.Buffer
+	err := Run(t.Context(), &out, env)
+	if !errors.Is(err, ErrChecksFailed) {
+		t.Errorf("Run() error = %v, want ErrChecksFailed", err)
+	}
+	if !strings.Contains(out.String(), "✗ github auth: gh auth token returned no token\n    fix: run `gh auth login`\n") {
+		t.Errorf("Run() output missing github auth failure:\n%s", out.String())
+	}
+}
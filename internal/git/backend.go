@@ -0,0 +1,74 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// Backends selectable with the git_backend option.
+const (
+	BackendExec   = "exec"   // Run the git binary (Client)
+	BackendNative = "native" // Read the repository in-process (NativeClient)
+)
+
+// Repo is what both backends provide.
+type Repo interface {
+	Branch(ctx context.Context) (string, error)
+	Status(ctx context.Context) (string, error)
+	Summary(ctx context.Context) (Summary, error)
+	StashCount(ctx context.Context) (int, error)
+	DiffStats(ctx context.Context) (DiffStats, error)
+	AheadBehind(ctx context.Context) (UpstreamStatus, error)
+	RemoteURL(ctx context.Context) (string, error)
+	LastCommit(ctx context.Context) (Commit, error)
+	HeadSHA(ctx context.Context) (string, error)
+	DiffSince(ctx context.Context, rev string) (LineStats, error)
+	CommitsSince(ctx context.Context, t time.Time) (int, error)
+	Operation() string
+	DetachedName(ctx context.Context) (string, error)
+	SetDiffIgnore(patterns []string)
+	GitDir() string
+	HeadPath() string
+	IndexPath() string
+	FetchHeadPath() string
+	StashLogPath() string
+	RefPath(branch string) string
+}
+
+var (
+	_ Repo = (*Client)(nil)
+	_ Repo = (*NativeClient)(nil)
+)
+
+// Installed reports whether a git binary is on PATH. It is checked once per
+// process.
+var Installed = sync.OnceValue(func() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+})
+
+// Open opens the repository containing workDir with backend, falling back
+// to the other backend when the preferred one can't be used: exec without
+// a git binary, or native on a repository go-git can't read (e.g. one
+// using a newer repository format).
+func Open(ctx context.Context, workDir, backend string) (Repo, error) {
+	if backend != BackendNative && Installed() {
+		return NewClient(ctx, workDir)
+	}
+
+	client, err := NewNativeClient(workDir)
+	if err == nil {
+		return client, nil
+	}
+	if errors.Is(err, gogit.ErrRepositoryNotExists) || !Installed() {
+		return nil, err
+	}
+	slog.Debug("go-git can't open repository, using git", "workDir", workDir, "err", err)
+	return NewClient(ctx, workDir)
+}
@@ -3,8 +3,10 @@ package git
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -30,68 +32,235 @@ func (e *ExecCommander) Run(ctx context.Context, dir string, args ...string) (st
 
 // DiffStats holds git diff statistics.
 type DiffStats struct {
-	Additions     int // Lines added
-	Deletions     int // Lines deleted
-	NewFiles      int // Untracked or newly staged files
-	ModifiedFiles int // Modified files
-	DeletedFiles  int // Deleted files
-	UnstagedFiles int // Files with unstaged changes (need git add)
+	Additions         int            // Lines added
+	Deletions         int            // Lines deleted
+	StagedAdditions   int            // Lines added in the index (about to be committed)
+	StagedDeletions   int            // Lines deleted in the index
+	UnstagedAdditions int            // Lines added in the working tree only
+	UnstagedDeletions int            // Lines deleted in the working tree only
+	NewFiles          int            // Untracked or newly staged files
+	ModifiedFiles     int            // Modified files
+	DeletedFiles      int            // Deleted files
+	UnstagedFiles     int            // Files with unstaged changes (need git add)
+	Conflicts         int            // Files with unresolved merge conflicts
+	FileTypes         map[string]int // Changed file count by extension (".go", "Makefile")
+	LargestFile       string         // Tracked file with the most changed lines
+	LargestFileLines  int            // Lines added plus deleted in LargestFile
+	ChangedFiles      []ChangedFile  // Porcelain entries, capped at MaxChangedFiles
 }
 
-// Client provides git operations for a working directory.
+// LineStats counts changed lines.
+type LineStats struct {
+	Additions int
+	Deletions int
+}
+
+// EmptyTree is the hash of git's empty tree. Diffing against it counts every
+// line, so it stands in for HEAD before the first commit.
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// MaxChangedFiles caps DiffStats.ChangedFiles so huge change sets stay cheap
+// to cache and render.
+const MaxChangedFiles = 50
+
+// ChangedFile is one "git status --porcelain" entry.
+type ChangedFile struct {
+	Status string // Two-letter XY code, e.g. " M", "A ", "??"
+	Path   string // Repo-relative path (destination for renames)
+}
+
+// Summary is a repository's current branch and how many files have
+// uncommitted changes, as read from a single "git status" call.
+type Summary struct {
+	Branch  string // Current branch, or "HEAD" when detached
+	Changed int    // Files with staged, unstaged, or untracked changes
+}
+
+// Commit is the metadata of one commit.
+type Commit struct {
+	SHA     string    // Abbreviated hash
+	Subject string    // First line of the message
+	Time    time.Time // Committer date
+}
+
+// UpstreamStatus counts commits between HEAD and its upstream branch.
+type UpstreamStatus struct {
+	Ahead  int // Commits on HEAD not yet on the upstream (to push)
+	Behind int // Commits on the upstream not yet on HEAD (to pull)
+}
+
+// Diverged reports whether HEAD and its upstream each have commits the other
+// lacks, e.g. after the remote branch was rebased. A plain push is rejected
+// until the histories are reconciled or the push is forced.
+func (u UpstreamStatus) Diverged() bool {
+	return u.Ahead > 0 && u.Behind > 0
+}
+
+// commandTimeout caps each git command, on top of any deadline on the
+// context a method is called with.
+const commandTimeout = 2 * time.Second
+
+// Client provides git operations for a working directory. Its methods
+// stop the git commands they run when ctx is done.
 type Client struct {
-	workDir string
-	gitDir  string
-	cmd     Commander
+	repoDir
+	workDir  string
+	cmd      Commander
+	excludes []string // pathspecs excluded from DiffStats
 }
 
 // NewClient creates a new git client for the given working directory.
 // Returns an error if the directory is not a git repository.
-func NewClient(workDir string) (*Client, error) {
-	return NewClientWithCommander(workDir, &ExecCommander{})
+func NewClient(ctx context.Context, workDir string) (*Client, error) {
+	return NewClientWithCommander(ctx, workDir, &ExecCommander{})
 }
 
 // NewClientWithCommander creates a new git client with a custom commander.
-func NewClientWithCommander(workDir string, cmd Commander) (*Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func NewClientWithCommander(ctx context.Context, workDir string, cmd Commander) (*Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
-	gitDir, err := cmd.Run(ctx, workDir, "rev-parse", "--git-dir")
+	out, err := cmd.Run(ctx, workDir, "rev-parse", "--git-dir", "--git-common-dir")
 	if err != nil {
 		return nil, fmt.Errorf("not a git repository: %w", err)
 	}
+	gitDir, commonDir, _ := strings.Cut(out, "\n")
+	if commonDir == "" {
+		commonDir = gitDir
+	}
 
-	// Make gitDir absolute if it's relative
+	// Make both absolute if they're relative
 	if !filepath.IsAbs(gitDir) {
 		gitDir = filepath.Join(workDir, gitDir)
 	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(workDir, commonDir)
+	}
 
 	return &Client{
+		repoDir: repoDir{gitDir: gitDir, commonDir: filepath.Clean(commonDir)},
 		workDir: workDir,
-		gitDir:  gitDir,
 		cmd:     cmd,
 	}, nil
 }
 
+// repoDir locates the files in a repository's git dir that both backends
+// read directly or watch for cache invalidation. In a linked worktree the
+// two differ: HEAD, the index, and in-progress operations are per worktree
+// under .git/worktrees/<name>, while refs and the stash are shared in the
+// main repository's git dir.
+type repoDir struct {
+	gitDir    string // Per-worktree files
+	commonDir string // Shared files; the same as gitDir outside linked worktrees
+}
+
+// newRepoDir locates the common dir for gitDir from its "commondir" file,
+// which linked worktrees have and git rev-parse --git-common-dir reads.
+func newRepoDir(gitDir string) repoDir {
+	return repoDir{gitDir: gitDir, commonDir: CommonDir(gitDir)}
+}
+
+// CommonDir returns the git dir holding the refs shared by every worktree
+// of the repository whose (possibly per-worktree) git dir is gitDir.
+func CommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// WorkTreeRoot returns the root of the working tree whose git dir is gitDir,
+// or "" if it has none that can be told from gitDir alone (a bare repository
+// or one with a separate git dir). A linked worktree's git dir records where
+// its .git file is in "gitdir".
+func WorkTreeRoot(gitDir string) string {
+	if data, err := os.ReadFile(filepath.Join(gitDir, "gitdir")); err == nil {
+		dotGit := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(dotGit) {
+			dotGit = filepath.Join(gitDir, dotGit)
+		}
+		return filepath.Dir(filepath.Clean(dotGit))
+	}
+	if filepath.Base(gitDir) == ".git" {
+		return filepath.Dir(gitDir)
+	}
+	return ""
+}
+
 // GitDir returns the path to the .git directory.
-func (c *Client) GitDir() string {
-	return c.gitDir
+func (r repoDir) GitDir() string {
+	return r.gitDir
+}
+
+// SetDiffIgnore excludes files matching the given gitignore-style globs
+// (e.g. "package-lock.json", "vendor/", "*.pb.go") from DiffStats.
+func (c *Client) SetDiffIgnore(patterns []string) {
+	c.excludes = ignorePathspecs(patterns)
+}
+
+// ignorePathspecs converts gitignore-style globs into git exclude pathspecs
+// anchored at the repository root. Patterns without a slash match at any
+// depth, and a trailing slash matches everything under that directory.
+func ignorePathspecs(patterns []string) []string {
+	var specs []string
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.HasSuffix(p, "/") {
+			p += "**"
+		}
+		if strings.Contains(strings.TrimSuffix(p, "/**"), "/") {
+			p = strings.TrimPrefix(p, "/")
+		} else {
+			p = "**/" + p
+		}
+		specs = append(specs, ":(top,exclude,glob)"+p)
+	}
+	return specs
+}
+
+// withExcludes appends the configured exclude pathspecs to a git command.
+func (c *Client) withExcludes(args ...string) []string {
+	if len(c.excludes) == 0 {
+		return args
+	}
+	args = append(args, "--", ":/")
+	return append(args, c.excludes...)
 }
 
 // Branch returns the current branch name.
 // Returns "HEAD" for detached HEAD state.
-func (c *Client) Branch() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (c *Client) Branch(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
 	return c.cmd.Run(ctx, c.workDir, "rev-parse", "--abbrev-ref", "HEAD")
 }
 
+// DetachedName names a detached HEAD: the tag pointing at it, if there is
+// one, or its abbreviated hash.
+func (c *Client) DetachedName(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	if tag, err := c.cmd.Run(ctx, c.workDir, "describe", "--tags", "--exact-match", "HEAD"); err == nil && tag != "" {
+		return tag, nil
+	}
+	return c.cmd.Run(ctx, c.workDir, "rev-parse", "--short", "HEAD")
+}
+
 // Status returns a string representing uncommitted changes.
 // Returns empty string if the working tree is clean.
 // Returns "±N" where N is the number of changed files.
-func (c *Client) Status() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (c *Client) Status(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
 	out, err := c.cmd.Run(ctx, c.workDir, "status", "--porcelain")
@@ -107,41 +276,206 @@ func (c *Client) Status() (string, error) {
 	return fmt.Sprintf("±%d", len(lines)), nil
 }
 
+// Summary returns the current branch and number of changed files in one
+// git call, for repositories where only an overview is shown.
+func (c *Client) Summary(ctx context.Context) (Summary, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := c.cmd.Run(ctx, c.workDir, "status", "--porcelain", "--branch")
+	if err != nil {
+		return Summary{}, err
+	}
+	return parseStatusSummary(out), nil
+}
+
+// parseStatusSummary parses "status --porcelain --branch" output: a
+// "## branch...upstream [ahead N]" header followed by one line per changed file.
+func parseStatusSummary(output string) Summary {
+	var s Summary
+	for _, line := range strings.Split(output, "\n") {
+		header, ok := strings.CutPrefix(line, "## ")
+		if !ok {
+			if line != "" {
+				s.Changed++
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(header, "HEAD (no branch)"):
+			s.Branch = "HEAD"
+		case strings.HasPrefix(header, "No commits yet on "):
+			s.Branch = strings.TrimPrefix(header, "No commits yet on ")
+		default:
+			branch, _, _ := strings.Cut(header, "...")
+			branch, _, _ = strings.Cut(branch, " ")
+			s.Branch = branch
+		}
+	}
+	return s
+}
+
+// StashCount returns the number of stash entries.
+func (c *Client) StashCount(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := c.cmd.Run(ctx, c.workDir, "stash", "list")
+	if err != nil {
+		return 0, err
+	}
+	if out == "" {
+		return 0, nil
+	}
+	return strings.Count(out, "\n") + 1, nil
+}
+
+// HeadSHA returns the full hash of the commit HEAD points at, or EmptyTree
+// before the first commit.
+func (c *Client) HeadSHA(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	sha, err := c.cmd.Run(ctx, c.workDir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return EmptyTree, nil // HEAD doesn't resolve before the first commit
+	}
+	return sha, nil
+}
+
+// DiffSince returns the lines changed in the working tree, committed or not,
+// since rev. Untracked files aren't counted.
+func (c *Client) DiffSince(ctx context.Context, rev string) (LineStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := c.cmd.Run(ctx, c.workDir, c.withExcludes("diff", "--shortstat", rev)...)
+	if err != nil {
+		return LineStats{}, err
+	}
+	var stats LineStats
+	stats.Additions, stats.Deletions = parseShortstat(out)
+	return stats, nil
+}
+
+// CommitsSince counts the commits reachable from HEAD that were committed
+// after t.
+func (c *Client) CommitsSince(ctx context.Context, t time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := c.cmd.Run(ctx, c.workDir, "rev-list", "--count", "--since="+t.UTC().Format(time.RFC3339), "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// LastCommit returns the commit HEAD points at. Returns an error before the
+// first commit.
+func (c *Client) LastCommit(ctx context.Context) (Commit, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := c.cmd.Run(ctx, c.workDir, "log", "-1", "--format=%h%x00%ct%x00%s")
+	if err != nil {
+		return Commit{}, err
+	}
+	return parseLastCommit(out)
+}
+
+// parseLastCommit parses "log -1 --format=%h%x00%ct%x00%s" output.
+func parseLastCommit(output string) (Commit, error) {
+	fields := strings.SplitN(output, "\x00", 3)
+	if len(fields) != 3 {
+		return Commit{}, fmt.Errorf("unexpected git log output %q", output)
+	}
+	seconds, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return Commit{}, fmt.Errorf("invalid commit time %q: %w", fields[1], err)
+	}
+	return Commit{SHA: fields[0], Subject: fields[2], Time: time.Unix(seconds, 0)}, nil
+}
+
 // RemoteURL returns the URL of the origin remote.
-func (c *Client) RemoteURL() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (c *Client) RemoteURL(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
 	return c.cmd.Run(ctx, c.workDir, "remote", "get-url", "origin")
 }
 
+// AheadBehind counts commits between HEAD and its upstream branch. Returns an
+// error if the branch has no upstream (or HEAD is detached).
+func (c *Client) AheadBehind(ctx context.Context) (UpstreamStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	out, err := c.cmd.Run(ctx, c.workDir, "rev-list", "--left-right", "--count", "@{upstream}...HEAD")
+	if err != nil {
+		return UpstreamStatus{}, err
+	}
+	return parseLeftRightCount(out)
+}
+
+// parseLeftRightCount parses "rev-list --left-right --count" output for
+// "@{upstream}...HEAD": the left count is behind, the right count is ahead.
+func parseLeftRightCount(output string) (UpstreamStatus, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return UpstreamStatus{}, fmt.Errorf("unexpected rev-list output %q", output)
+	}
+	behind, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return UpstreamStatus{}, fmt.Errorf("unexpected rev-list output %q", output)
+	}
+	ahead, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return UpstreamStatus{}, fmt.Errorf("unexpected rev-list output %q", output)
+	}
+	return UpstreamStatus{Ahead: ahead, Behind: behind}, nil
+}
+
 // DiffStats returns statistics about uncommitted changes.
-// It combines staged and unstaged changes for line counts,
-// and parses file status for file type counts.
-func (c *Client) DiffStats() (DiffStats, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+// Line counts are reported per side (staged and unstaged) as well as combined,
+// and file type counts are parsed from the porcelain status.
+func (c *Client) DiffStats(ctx context.Context) (DiffStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
 	var stats DiffStats
 
 	// Get unstaged diff stats
-	unstaged, _ := c.cmd.Run(ctx, c.workDir, "diff", "--shortstat")
-	add1, del1 := parseShortstat(unstaged)
-	stats.Additions += add1
-	stats.Deletions += del1
+	unstaged, _ := c.cmd.Run(ctx, c.workDir, c.withExcludes("diff", "--shortstat")...)
+	stats.UnstagedAdditions, stats.UnstagedDeletions = parseShortstat(unstaged)
 
 	// Get staged diff stats
-	staged, _ := c.cmd.Run(ctx, c.workDir, "diff", "--shortstat", "--cached")
-	add2, del2 := parseShortstat(staged)
-	stats.Additions += add2
-	stats.Deletions += del2
+	staged, _ := c.cmd.Run(ctx, c.workDir, c.withExcludes("diff", "--shortstat", "--cached")...)
+	stats.StagedAdditions, stats.StagedDeletions = parseShortstat(staged)
+
+	stats.Additions = stats.StagedAdditions + stats.UnstagedAdditions
+	stats.Deletions = stats.StagedDeletions + stats.UnstagedDeletions
+
+	// Find the file with the most churn across staged and unstaged changes
+	churn := make(map[string]int)
+	unstagedFiles, _ := c.cmd.Run(ctx, c.workDir, c.withExcludes("diff", "--numstat")...)
+	parseNumstat(unstagedFiles, churn)
+	stagedFiles, _ := c.cmd.Run(ctx, c.workDir, c.withExcludes("diff", "--numstat", "--cached")...)
+	parseNumstat(stagedFiles, churn)
+	stats.LargestFile, stats.LargestFileLines = largestFile(churn)
 
 	// Get file type counts from status
-	statusOut, err := c.cmd.Run(ctx, c.workDir, "status", "--porcelain")
+	statusOut, err := c.cmd.Run(ctx, c.workDir, c.withExcludes("status", "--porcelain")...)
 	if err != nil {
 		return stats, err
 	}
 	stats.NewFiles, stats.ModifiedFiles, stats.DeletedFiles, stats.UnstagedFiles = parseStatusForTypes(statusOut)
+	stats.Conflicts = parseConflicts(statusOut)
+	stats.FileTypes = parseStatusExtensions(statusOut)
+	stats.ChangedFiles = parseChangedFiles(statusOut, MaxChangedFiles)
 
 	return stats, nil
 }
@@ -176,6 +510,52 @@ func parseShortstat(output string) (additions, deletions int) {
 	return additions, deletions
 }
 
+// parseNumstat adds per-file line changes from "git diff --numstat" output
+// to churn. Binary files (reported as "-") are skipped.
+// Example line: "42\t10\tinternal/git/git.go"
+func parseNumstat(output string, churn map[string]int) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, err1 := strconv.Atoi(strings.TrimSpace(fields[0]))
+		deleted, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		churn[numstatPath(fields[2])] += added + deleted
+	}
+}
+
+// numstatPath resolves rename notation in a numstat path to the new path:
+// "old.go => new.go" -> "new.go", "pkg/{a => b}/x.go" -> "pkg/b/x.go".
+func numstatPath(path string) string {
+	if open := strings.Index(path, "{"); open >= 0 {
+		if end := strings.Index(path[open:], "}"); end > 0 {
+			inner := path[open+1 : open+end]
+			if _, to, ok := strings.Cut(inner, " => "); ok {
+				return strings.ReplaceAll(path[:open]+to+path[open+end+1:], "//", "/")
+			}
+		}
+	}
+	if _, to, ok := strings.Cut(path, " => "); ok {
+		return to
+	}
+	return path
+}
+
+// largestFile returns the path with the most changed lines, breaking ties
+// alphabetically so the result is stable between renders.
+func largestFile(churn map[string]int) (path string, lines int) {
+	for p, n := range churn {
+		if n > lines || (n == lines && n > 0 && p < path) {
+			path, lines = p, n
+		}
+	}
+	return path, lines
+}
+
 // parseStatusForTypes parses "git status --porcelain" output for file type counts.
 // Returns (new, modified, deleted, unstaged) counts.
 // Unstaged = files that need "git add" (untracked, unstaged modifications, unstaged deletions).
@@ -230,46 +610,199 @@ func parseStatusForTypes(output string) (newFiles, modified, deleted, unstaged i
 	return newFiles, modified, deleted, unstaged
 }
 
+// parseConflicts counts the unmerged entries in "git status --porcelain"
+// output: both sides added (AA) or deleted (DD) the file, or either side
+// left it unmerged (U).
+func parseConflicts(output string) int {
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch xy := line[:2]; {
+		case xy == "AA", xy == "DD", xy[0] == 'U', xy[1] == 'U':
+			count++
+		}
+	}
+	return count
+}
+
+// parseStatusExtensions counts changed files in "git status --porcelain"
+// output by extension. Files without an extension are keyed by base name,
+// and untracked directories are skipped.
+func parseStatusExtensions(output string) map[string]int {
+	if output == "" {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, line := range strings.Split(output, "\n") {
+		path := statusPath(line)
+		if path == "" || strings.HasSuffix(path, "/") {
+			continue
+		}
+		ext := filepath.Ext(path)
+		if ext == "" {
+			ext = filepath.Base(path)
+		}
+		counts[ext]++
+	}
+	return counts
+}
+
+// parseChangedFiles returns up to limit entries from "git status --porcelain"
+// output. A status code whose leading space was trimmed by the commander is
+// restored using the path offset.
+func parseChangedFiles(output string, limit int) []ChangedFile {
+	if output == "" {
+		return nil
+	}
+
+	var files []ChangedFile
+	for _, line := range strings.Split(output, "\n") {
+		if len(files) >= limit {
+			break
+		}
+		path := statusPath(line)
+		if path == "" {
+			continue
+		}
+		code := line[:2]
+		if code[1] == ' ' && line[2] != ' ' {
+			// " M file" trimmed to "M file": the code is the unstaged column
+			code = " " + code[:1]
+		}
+		files = append(files, ChangedFile{Status: code, Path: path})
+	}
+	return files
+}
+
+// statusPath extracts the file path from a "git status --porcelain" line,
+// using the destination of renames and unquoting paths with special characters.
+// The leading space of the first line may have been trimmed by the commander.
+func statusPath(line string) string {
+	if len(line) < 3 {
+		return ""
+	}
+	path := strings.TrimSpace(line[2:])
+	if _, after, ok := strings.Cut(path, " -> "); ok {
+		path = after
+	}
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		path = unquoted
+	}
+	return path
+}
+
+// Operations in progress, as returned by Operation.
+const (
+	OpRebasing      = "rebasing"
+	OpMerging       = "merging"
+	OpCherryPicking = "cherry-picking"
+	OpReverting     = "reverting"
+	OpApplying      = "applying" // git am
+	OpBisecting     = "bisecting"
+)
+
+// operationMarkers maps the files git leaves in the git dir while an
+// operation is stopped partway to the operation, in the order they are
+// checked. A rebase that stops on a conflicting pick also leaves
+// CHERRY_PICK_HEAD, so rebases come first.
+var operationMarkers = []struct {
+	path string
+	op   string
+}{
+	{"rebase-merge", OpRebasing},
+	{filepath.Join("rebase-apply", "rebasing"), OpRebasing},
+	{filepath.Join("rebase-apply", "applying"), OpApplying},
+	{"MERGE_HEAD", OpMerging},
+	{"CHERRY_PICK_HEAD", OpCherryPicking},
+	{"REVERT_HEAD", OpReverting},
+	{"BISECT_LOG", OpBisecting},
+}
+
+// Operation returns the operation the repository is in the middle of, such
+// as OpRebasing, or "" if there is none. It only checks for files in the git
+// dir, so it is cheap enough to call on every render.
+func (r repoDir) Operation() string {
+	for _, m := range operationMarkers {
+		if _, err := os.Stat(filepath.Join(r.gitDir, m.path)); err == nil {
+			return m.op
+		}
+	}
+	return ""
+}
+
 // HeadPath returns the path to the HEAD file for cache invalidation.
-func (c *Client) HeadPath() string {
-	return filepath.Join(c.gitDir, "HEAD")
+func (r repoDir) HeadPath() string {
+	return filepath.Join(r.gitDir, "HEAD")
 }
 
 // IndexPath returns the path to the index file for cache invalidation.
-func (c *Client) IndexPath() string {
-	return filepath.Join(c.gitDir, "index")
+func (r repoDir) IndexPath() string {
+	return filepath.Join(r.gitDir, "index")
+}
+
+// FetchHeadPath returns the path to FETCH_HEAD, rewritten on every fetch.
+func (r repoDir) FetchHeadPath() string {
+	return filepath.Join(r.gitDir, "FETCH_HEAD")
 }
 
-// RefPath returns the path to the ref file for a branch.
-func (c *Client) RefPath(branch string) string {
-	return filepath.Join(c.gitDir, "refs", "heads", branch)
+// StashLogPath returns the path to the stash reflog, rewritten whenever an
+// entry is pushed, dropped, or popped. The stash is shared by all worktrees.
+func (r repoDir) StashLogPath() string {
+	return filepath.Join(r.commonDir, "logs", "refs", "stash")
 }
 
+// RefPath returns the path to the ref file for a branch, which is shared by
+// all worktrees.
+func (r repoDir) RefPath(branch string) string {
+	return filepath.Join(r.commonDir, "refs", "heads", branch)
+}
+
+// DefaultGitHubHost is the host ParseGitHubRepo matches.
+const DefaultGitHubHost = "github.com"
+
 // ParseGitHubRepo extracts owner and repo from a GitHub remote URL.
 // Supports both SSH (git@github.com:owner/repo.git) and HTTPS
 // (https://github.com/owner/repo.git) formats.
 // Returns empty strings and false if the URL is not a GitHub URL.
 func ParseGitHubRepo(remoteURL string) (owner, repo string, ok bool) {
-	// Handle SSH format: git@github.com:owner/repo.git
-	if strings.HasPrefix(remoteURL, "git@github.com:") {
-		path := strings.TrimPrefix(remoteURL, "git@github.com:")
-		path = strings.TrimSuffix(path, ".git")
-		parts := strings.SplitN(path, "/", 2)
-		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
-			return parts[0], parts[1], true
+	return ParseGitHubRepoHost(remoteURL, DefaultGitHubHost)
+}
+
+// ParseGitHubRepoHost is ParseGitHubRepo for remotes on host, such as a
+// GitHub Enterprise Server (git@github.mycorp.com:owner/repo.git). An empty
+// host means github.com.
+func ParseGitHubRepoHost(remoteURL, host string) (owner, repo string, ok bool) {
+	if host == "" {
+		host = DefaultGitHubHost
+	}
+
+	// Handle SSH format: git@host:owner/repo.git
+	if path, found := strings.CutPrefix(remoteURL, "git@"+host+":"); found {
+		if owner, repo, ok := splitRepoPath(path); ok {
+			return owner, repo, true
 		}
 	}
 
-	// Handle HTTPS format: https://github.com/owner/repo.git
-	if strings.Contains(remoteURL, "github.com/") {
-		idx := strings.Index(remoteURL, "github.com/")
-		path := remoteURL[idx+len("github.com/"):]
-		path = strings.TrimSuffix(path, ".git")
-		parts := strings.SplitN(path, "/", 2)
-		if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
-			return parts[0], parts[1], true
+	// Handle HTTPS format: https://host/owner/repo.git
+	if idx := strings.Index(remoteURL, host+"/"); idx >= 0 {
+		// Don't let github.com match notgithub.com
+		if idx == 0 || strings.ContainsRune("/@", rune(remoteURL[idx-1])) {
+			return splitRepoPath(remoteURL[idx+len(host)+1:])
 		}
 	}
 
 	return "", "", false
 }
+
+// splitRepoPath splits "owner/repo.git" into owner and repo.
+func splitRepoPath(path string) (owner, repo string, ok bool) {
+	path = strings.TrimSuffix(path, ".git")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
@@ -6,7 +6,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // mockCommander is a test double for Commander.
@@ -44,7 +46,7 @@ func TestNewGitClient_InRepo(t *testing.T) {
 		t.Skip("git not available")
 	}
 
-	client, err := NewClient(dir)
+	client, err := NewClient(t.Context(), dir)
 	if err != nil {
 		t.Fatalf("NewClient() error = %v", err)
 	}
@@ -61,7 +63,7 @@ func TestNewGitClient_InRepo(t *testing.T) {
 func TestNewGitClient_NotRepo(t *testing.T) {
 	dir := t.TempDir()
 
-	_, err := NewClient(dir)
+	_, err := NewClient(t.Context(), dir)
 	if err == nil {
 		t.Fatal("NewClient() expected error for non-repo")
 	}
@@ -72,12 +74,12 @@ func TestBranch_Main(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["rev-parse --abbrev-ref"] = "main"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	branch, err := client.Branch()
+	branch, err := client.Branch(t.Context())
 	if err != nil {
 		t.Fatalf("Branch() error = %v", err)
 	}
@@ -86,17 +88,38 @@ func TestBranch_Main(t *testing.T) {
 	}
 }
 
+func TestDetachedName(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["describe --tags"] = "v1.2.0"
+	mock.responses["rev-parse --short"] = "abc1234"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+	if got, err := client.DetachedName(t.Context()); err != nil || got != "v1.2.0" {
+		t.Errorf("DetachedName() at a tag = %q, %v; want v1.2.0", got, err)
+	}
+
+	// No tag points at HEAD
+	mock.errors["describe --tags"] = errors.New("exit status 128")
+	if got, err := client.DetachedName(t.Context()); err != nil || got != "abc1234" {
+		t.Errorf("DetachedName() = %q, %v; want abc1234", got, err)
+	}
+}
+
 func TestBranch_Feature(t *testing.T) {
 	mock := newMockCommander()
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["rev-parse --abbrev-ref"] = "feature/my-feature"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	branch, err := client.Branch()
+	branch, err := client.Branch(t.Context())
 	if err != nil {
 		t.Fatalf("Branch() error = %v", err)
 	}
@@ -110,12 +133,12 @@ func TestBranch_DetachedHead(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["rev-parse --abbrev-ref"] = "HEAD"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	branch, err := client.Branch()
+	branch, err := client.Branch(t.Context())
 	if err != nil {
 		t.Fatalf("Branch() error = %v", err)
 	}
@@ -129,12 +152,12 @@ func TestStatus_Clean(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["status --porcelain"] = ""
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	status, err := client.Status()
+	status, err := client.Status(t.Context())
 	if err != nil {
 		t.Fatalf("Status() error = %v", err)
 	}
@@ -148,12 +171,12 @@ func TestStatus_Uncommitted(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["status --porcelain"] = " M file1.go\n M file2.go\n?? file3.go"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	status, err := client.Status()
+	status, err := client.Status(t.Context())
 	if err != nil {
 		t.Fatalf("Status() error = %v", err)
 	}
@@ -167,12 +190,12 @@ func TestStatus_Staged(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["status --porcelain"] = "M  file1.go\nA  file2.go"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	status, err := client.Status()
+	status, err := client.Status(t.Context())
 	if err != nil {
 		t.Fatalf("Status() error = %v", err)
 	}
@@ -186,12 +209,12 @@ func TestStatus_Mixed(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["status --porcelain"] = "MM file1.go"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	status, err := client.Status()
+	status, err := client.Status(t.Context())
 	if err != nil {
 		t.Fatalf("Status() error = %v", err)
 	}
@@ -200,17 +223,178 @@ func TestStatus_Mixed(t *testing.T) {
 	}
 }
 
+func TestSummary(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["status --porcelain"] = "## feature...origin/feature [ahead 2]\n M file1.go\n?? file2.go"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+
+	summary, err := client.Summary(t.Context())
+	if err != nil {
+		t.Fatalf("Summary() error = %v", err)
+	}
+	if want := (Summary{Branch: "feature", Changed: 2}); summary != want {
+		t.Errorf("Summary() = %+v, want %+v", summary, want)
+	}
+}
+
+func TestParseStatusSummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Summary
+	}{
+		{"clean with upstream", "## main...origin/main", Summary{Branch: "main"}},
+		{"no upstream", "## main\nA  new.go", Summary{Branch: "main", Changed: 1}},
+		{"detached", "## HEAD (no branch)\n M a.go\n M b.go", Summary{Branch: "HEAD", Changed: 2}},
+		{"unborn branch", "## No commits yet on main\n?? a.go", Summary{Branch: "main", Changed: 1}},
+		{"gone upstream", "## feature...origin/feature [gone]", Summary{Branch: "feature"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStatusSummary(tt.output); got != tt.want {
+				t.Errorf("parseStatusSummary(%q) = %+v, want %+v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStashCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   int
+	}{
+		{"none", "", 0},
+		{"one", "stash@{0}: WIP on main: abc1234 initial", 1},
+		{"two", "stash@{0}: On main: snapshot\nstash@{1}: WIP on main: abc1234 initial", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockCommander()
+			mock.responses["rev-parse --git-dir"] = ".git"
+			mock.responses["stash list"] = tt.output
+
+			client, err := NewClientWithCommander(t.Context(), "/test", mock)
+			if err != nil {
+				t.Fatalf("NewClientWithCommander() error = %v", err)
+			}
+			got, err := client.StashCount(t.Context())
+			if err != nil {
+				t.Fatalf("StashCount() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("StashCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastCommit(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["log -1"] = "abc1234\x001717243200\x00fix: cache bug"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+	got, err := client.LastCommit(t.Context())
+	if err != nil {
+		t.Fatalf("LastCommit() error = %v", err)
+	}
+	want := Commit{SHA: "abc1234", Subject: "fix: cache bug", Time: time.Unix(1717243200, 0)}
+	if got != want {
+		t.Errorf("LastCommit() = %+v, want %+v", got, want)
+	}
+
+	// Before the first commit
+	mock.errors["log -1"] = errors.New("exit status 128")
+	if _, err := client.LastCommit(t.Context()); err == nil {
+		t.Error("LastCommit() with no commits error = nil, want error")
+	}
+}
+
+func TestHeadSHA(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["rev-parse --verify"] = "0123456789abcdef0123456789abcdef01234567"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+	if got, err := client.HeadSHA(t.Context()); err != nil || got != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("HeadSHA() = %q, %v", got, err)
+	}
+
+	// Before the first commit
+	mock.errors["rev-parse --verify"] = errors.New("exit status 1")
+	if got, err := client.HeadSHA(t.Context()); err != nil || got != EmptyTree {
+		t.Errorf("HeadSHA() with no commits = %q, %v; want EmptyTree", got, err)
+	}
+}
+
+func TestDiffSince(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["diff --shortstat"] = " 4 files changed, 120 insertions(+), 8 deletions(-)"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+	got, err := client.DiffSince(t.Context(), "abc1234")
+	if err != nil {
+		t.Fatalf("DiffSince() error = %v", err)
+	}
+	if want := (LineStats{Additions: 120, Deletions: 8}); got != want {
+		t.Errorf("DiffSince() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCommitsSince(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["rev-list --count"] = "3"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+	if got, err := client.CommitsSince(t.Context(), time.Now().Add(-time.Hour)); err != nil || got != 3 {
+		t.Errorf("CommitsSince() = %d, %v; want 3", got, err)
+	}
+
+	mock.errors["rev-list --count"] = errors.New("exit status 128")
+	if _, err := client.CommitsSince(t.Context(), time.Now()); err == nil {
+		t.Error("CommitsSince() with no commits error = nil, want error")
+	}
+}
+
+func TestParseLastCommit_Invalid(t *testing.T) {
+	for _, output := range []string{"", "abc1234", "abc1234\x00soon\x00msg"} {
+		if _, err := parseLastCommit(output); err == nil {
+			t.Errorf("parseLastCommit(%q) error = nil, want error", output)
+		}
+	}
+}
+
 func TestRemoteURL_SSH(t *testing.T) {
 	mock := newMockCommander()
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["remote get-url"] = "git@github.com:owner/repo.git"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	url, err := client.RemoteURL()
+	url, err := client.RemoteURL(t.Context())
 	if err != nil {
 		t.Fatalf("RemoteURL() error = %v", err)
 	}
@@ -224,12 +408,12 @@ func TestRemoteURL_HTTPS(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.responses["remote get-url"] = "https://github.com/owner/repo.git"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	url, err := client.RemoteURL()
+	url, err := client.RemoteURL(t.Context())
 	if err != nil {
 		t.Fatalf("RemoteURL() error = %v", err)
 	}
@@ -243,17 +427,93 @@ func TestRemoteURL_NoOrigin(t *testing.T) {
 	mock.responses["rev-parse --git-dir"] = ".git"
 	mock.errors["remote get-url"] = errors.New("fatal: No such remote 'origin'")
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	_, err = client.RemoteURL()
+	_, err = client.RemoteURL(t.Context())
 	if err == nil {
 		t.Error("RemoteURL() expected error for missing origin")
 	}
 }
 
+func TestAheadBehind(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.responses["rev-list --left-right"] = "1\t2"
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+
+	got, err := client.AheadBehind(t.Context())
+	if err != nil {
+		t.Fatalf("AheadBehind() error = %v", err)
+	}
+	if got != (UpstreamStatus{Ahead: 2, Behind: 1}) {
+		t.Errorf("AheadBehind() = %+v, want ahead 2 behind 1", got)
+	}
+}
+
+func TestAheadBehind_NoUpstream(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = ".git"
+	mock.errors["rev-list --left-right"] = errors.New("fatal: no upstream configured")
+
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+
+	if _, err := client.AheadBehind(t.Context()); err == nil {
+		t.Error("AheadBehind() should fail without an upstream")
+	}
+}
+
+func TestParseLeftRightCount(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    UpstreamStatus
+		wantErr bool
+	}{
+		{"0\t0", UpstreamStatus{}, false},
+		{"3\t0", UpstreamStatus{Behind: 3}, false},
+		{"0 5", UpstreamStatus{Ahead: 5}, false},
+		{"", UpstreamStatus{}, true},
+		{"x\t1", UpstreamStatus{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLeftRightCount(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLeftRightCount(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseLeftRightCount(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestUpstreamStatus_Diverged(t *testing.T) {
+	tests := []struct {
+		status UpstreamStatus
+		want   bool
+	}{
+		{UpstreamStatus{}, false},
+		{UpstreamStatus{Ahead: 2}, false},
+		{UpstreamStatus{Behind: 3}, false},
+		{UpstreamStatus{Ahead: 2, Behind: 3}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Diverged(); got != tt.want {
+			t.Errorf("%+v.Diverged() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
 func TestParseGitHubRepo_SSH(t *testing.T) {
 	owner, repo, ok := ParseGitHubRepo("git@github.com:myowner/myrepo.git")
 	if !ok {
@@ -324,11 +584,83 @@ func TestParseGitHubRepo_NonGitHub(t *testing.T) {
 	}
 }
 
+func TestParseGitHubRepoHost(t *testing.T) {
+	tests := []struct {
+		url    string
+		host   string
+		wantOK bool
+	}{
+		{"git@github.mycorp.com:owner/repo.git", "github.mycorp.com", true},
+		{"https://github.mycorp.com/owner/repo.git", "github.mycorp.com", true},
+		{"ssh://git@github.mycorp.com/owner/repo.git", "github.mycorp.com", true},
+		{"git@github.com:owner/repo.git", "", true},
+		{"git@github.com:owner/repo.git", "github.mycorp.com", false},
+		{"git@github.mycorp.com:owner/repo.git", "", false},
+		{"https://notgithub.com/owner/repo.git", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url+" on "+tt.host, func(t *testing.T) {
+			owner, repo, ok := ParseGitHubRepoHost(tt.url, tt.host)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseGitHubRepoHost() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (owner != "owner" || repo != "repo") {
+				t.Errorf("ParseGitHubRepoHost() = %q, %q; want owner, repo", owner, repo)
+			}
+		})
+	}
+}
+
+func TestOperation(t *testing.T) {
+	gitDir := t.TempDir()
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = gitDir
+
+	client, err := NewClientWithCommander(t.Context(), "/repo", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+	if got := client.Operation(); got != "" {
+		t.Errorf("Operation() = %q, want empty", got)
+	}
+
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"MERGE_HEAD"}, OpMerging},
+		{[]string{"CHERRY_PICK_HEAD"}, OpCherryPicking},
+		{[]string{"REVERT_HEAD"}, OpReverting},
+		{[]string{"BISECT_LOG"}, OpBisecting},
+		{[]string{"rebase-apply/applying"}, OpApplying},
+		{[]string{"rebase-apply/rebasing"}, OpRebasing},
+		// A rebase stopped on a conflicting pick
+		{[]string{"rebase-merge/done", "CHERRY_PICK_HEAD"}, OpRebasing},
+	}
+	for _, tt := range tests {
+		gitDir := t.TempDir()
+		for _, file := range tt.files {
+			path := filepath.Join(gitDir, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		client.gitDir = gitDir
+		if got := client.Operation(); got != tt.want {
+			t.Errorf("Operation() with %v = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
 func TestHeadPath(t *testing.T) {
 	mock := newMockCommander()
 	mock.responses["rev-parse --git-dir"] = "/repo/.git"
 
-	client, err := NewClientWithCommander("/repo", mock)
+	client, err := NewClientWithCommander(t.Context(), "/repo", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
@@ -344,7 +676,7 @@ func TestIndexPath(t *testing.T) {
 	mock := newMockCommander()
 	mock.responses["rev-parse --git-dir"] = "/repo/.git"
 
-	client, err := NewClientWithCommander("/repo", mock)
+	client, err := NewClientWithCommander(t.Context(), "/repo", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
@@ -360,7 +692,7 @@ func TestRefPath(t *testing.T) {
 	mock := newMockCommander()
 	mock.responses["rev-parse --git-dir"] = "/repo/.git"
 
-	client, err := NewClientWithCommander("/repo", mock)
+	client, err := NewClientWithCommander(t.Context(), "/repo", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
@@ -378,6 +710,48 @@ func TestRefPath(t *testing.T) {
 	}
 }
 
+func TestRefPath_Worktree(t *testing.T) {
+	mock := newMockCommander()
+	mock.responses["rev-parse --git-dir"] = "/repo/.git/worktrees/feature\n/repo/.git"
+
+	client, err := NewClientWithCommander(t.Context(), "/feature", mock)
+	if err != nil {
+		t.Fatalf("NewClientWithCommander() error = %v", err)
+	}
+
+	if got, want := client.HeadPath(), "/repo/.git/worktrees/feature/HEAD"; got != want {
+		t.Errorf("HeadPath() = %q, want %q", got, want)
+	}
+	if got, want := client.RefPath("feature"), "/repo/.git/refs/heads/feature"; got != want {
+		t.Errorf("RefPath(feature) = %q, want %q", got, want)
+	}
+}
+
+func TestWorkTreeRoot(t *testing.T) {
+	dir := t.TempDir()
+	linked := filepath.Join(dir, "repo", ".git", "worktrees", "feature")
+	if err := os.MkdirAll(linked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(linked, "gitdir"), []byte(filepath.Join(dir, "feature", ".git")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		gitDir string
+		want   string
+	}{
+		{filepath.Join(dir, "repo", ".git"), filepath.Join(dir, "repo")},
+		{linked, filepath.Join(dir, "feature")},
+		{filepath.Join(dir, "bare.git"), ""},
+	}
+	for _, tt := range tests {
+		if got := WorkTreeRoot(tt.gitDir); got != tt.want {
+			t.Errorf("WorkTreeRoot(%q) = %q, want %q", tt.gitDir, got, tt.want)
+		}
+	}
+}
+
 func TestParseShortstat(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -442,6 +816,16 @@ func TestParseShortstat(t *testing.T) {
 	}
 }
 
+func TestParseConflicts(t *testing.T) {
+	input := "UU both-modified.go\nAA both-added.go\nDD both-deleted.go\nAU added-by-us.go\nUD deleted-by-them.go\nM  staged.go\nD  deleted.go\n?? new.go"
+	if got := parseConflicts(input); got != 5 {
+		t.Errorf("parseConflicts() = %d, want 5", got)
+	}
+	if got := parseConflicts(""); got != 0 {
+		t.Errorf("parseConflicts(\"\") = %d, want 0", got)
+	}
+}
+
 func TestParseStatusForTypes(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -542,6 +926,115 @@ func TestParseStatusForTypes(t *testing.T) {
 	}
 }
 
+func TestParseStatusExtensions(t *testing.T) {
+	input := strings.Join([]string{
+		"M main.go",     // first line with leading space trimmed by the commander
+		" M util/io.go", // unstaged modification
+		"A  README.md",
+		"?? Makefile",
+		"?? newdir/",
+		"R  old.txt -> new.go",
+		`?? "with space.md"`,
+	}, "\n")
+
+	got := parseStatusExtensions(input)
+	want := map[string]int{".go": 3, ".md": 2, "Makefile": 1}
+	if len(got) != len(want) {
+		t.Fatalf("parseStatusExtensions() = %v, want %v", got, want)
+	}
+	for ext, n := range want {
+		if got[ext] != n {
+			t.Errorf("parseStatusExtensions()[%q] = %d, want %d", ext, got[ext], n)
+		}
+	}
+
+	if got := parseStatusExtensions(""); got != nil {
+		t.Errorf("parseStatusExtensions(\"\") = %v, want nil", got)
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	churn := make(map[string]int)
+	parseNumstat("42\t10\tinternal/git/git.go\n3\t1\tREADME.md\n-\t-\tlogo.png", churn)
+	parseNumstat("5\t0\tinternal/git/git.go\n1\t1\told.go => new.go", churn)
+
+	want := map[string]int{"internal/git/git.go": 57, "README.md": 4, "new.go": 2}
+	if len(churn) != len(want) {
+		t.Fatalf("churn = %v, want %v", churn, want)
+	}
+	for path, n := range want {
+		if churn[path] != n {
+			t.Errorf("churn[%q] = %d, want %d", path, churn[path], n)
+		}
+	}
+
+	path, lines := largestFile(churn)
+	if path != "internal/git/git.go" || lines != 57 {
+		t.Errorf("largestFile() = (%q, %d), want (internal/git/git.go, 57)", path, lines)
+	}
+}
+
+func TestNumstatPath(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"main.go", "main.go"},
+		{"old.go => new.go", "new.go"},
+		{"pkg/{a => b}/x.go", "pkg/b/x.go"},
+		{"pkg/{a => }/x.go", "pkg/x.go"},
+		{"{old => new}/x.go", "new/x.go"},
+	}
+	for _, tt := range tests {
+		if got := numstatPath(tt.in); got != tt.want {
+			t.Errorf("numstatPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLargestFile_Ties(t *testing.T) {
+	path, lines := largestFile(map[string]int{"b.go": 3, "a.go": 3})
+	if path != "a.go" || lines != 3 {
+		t.Errorf("largestFile() = (%q, %d), want (a.go, 3)", path, lines)
+	}
+	if path, lines := largestFile(nil); path != "" || lines != 0 {
+		t.Errorf("largestFile(nil) = (%q, %d), want empty", path, lines)
+	}
+}
+
+func TestParseChangedFiles(t *testing.T) {
+	input := strings.Join([]string{
+		"M main.go", // leading space trimmed by the commander
+		"M  staged.go",
+		"MM both.go",
+		"?? new.txt",
+		"R  old.go -> renamed.go",
+	}, "\n")
+
+	got := parseChangedFiles(input, 10)
+	want := []ChangedFile{
+		{Status: " M", Path: "main.go"},
+		{Status: "M ", Path: "staged.go"},
+		{Status: "MM", Path: "both.go"},
+		{Status: "??", Path: "new.txt"},
+		{Status: "R ", Path: "renamed.go"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseChangedFiles() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseChangedFiles()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := parseChangedFiles(input, 2); len(got) != 2 {
+		t.Errorf("parseChangedFiles(limit 2) returned %d entries, want 2", len(got))
+	}
+	if got := parseChangedFiles("", 10); got != nil {
+		t.Errorf("parseChangedFiles(\"\") = %v, want nil", got)
+	}
+}
+
 func TestDiffStats(t *testing.T) {
 	mock := newMockCommander()
 	mock.responses["rev-parse --git-dir"] = ".git"
@@ -549,12 +1042,12 @@ func TestDiffStats(t *testing.T) {
 	mock.responses["diff --shortstat"] = " 2 files changed, 20 insertions(+), 5 deletions(-)" // staged
 	mock.responses["status --porcelain"] = "?? new.go\nM  modified.go\n D deleted.go"
 
-	client, err := NewClientWithCommander("/test", mock)
+	client, err := NewClientWithCommander(t.Context(), "/test", mock)
 	if err != nil {
 		t.Fatalf("NewClientWithCommander() error = %v", err)
 	}
 
-	stats, err := client.DiffStats()
+	stats, err := client.DiffStats(t.Context())
 	if err != nil {
 		t.Fatalf("DiffStats() error = %v", err)
 	}
@@ -584,6 +1077,145 @@ func TestDiffStats(t *testing.T) {
 	}
 }
 
+func TestDiffStats_StagedVsUnstaged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init")
+	run("config", "user.email", "test@test.com")
+	run("config", "user.name", "Test")
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+	run("commit", "-m", "initial")
+
+	// Stage two added lines, then add one more line without staging it
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "a.txt")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\nfour\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	stats, err := client.DiffStats(t.Context())
+	if err != nil {
+		t.Fatalf("DiffStats() error = %v", err)
+	}
+
+	if stats.StagedAdditions != 2 {
+		t.Errorf("StagedAdditions = %d, want 2", stats.StagedAdditions)
+	}
+	if stats.UnstagedAdditions != 1 {
+		t.Errorf("UnstagedAdditions = %d, want 1", stats.UnstagedAdditions)
+	}
+	if stats.Additions != 3 {
+		t.Errorf("Additions = %d, want 3", stats.Additions)
+	}
+	if stats.StagedDeletions != 0 || stats.UnstagedDeletions != 0 {
+		t.Errorf("deletions = %d/%d, want 0/0", stats.StagedDeletions, stats.UnstagedDeletions)
+	}
+	if stats.LargestFile != "a.txt" || stats.LargestFileLines != 3 {
+		t.Errorf("LargestFile = (%q, %d), want (a.txt, 3)", stats.LargestFile, stats.LargestFileLines)
+	}
+}
+
+func TestIgnorePathspecs(t *testing.T) {
+	got := ignorePathspecs([]string{"package-lock.json", "vendor/", "/gen/*.go", "docs/api/", " ", "*.pb.go"})
+	want := []string{
+		":(top,exclude,glob)**/package-lock.json",
+		":(top,exclude,glob)**/vendor/**",
+		":(top,exclude,glob)gen/*.go",
+		":(top,exclude,glob)docs/api/**",
+		":(top,exclude,glob)**/*.pb.go",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ignorePathspecs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ignorePathspecs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiffStats_IgnorePatterns(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init")
+	run("config", "user.email", "test@test.com")
+	run("config", "user.name", "Test")
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+	run("commit", "-m", "initial")
+
+	// One real line of code alongside lockfile and vendored noise
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "web", "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "web", "package-lock.json"), []byte("{\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "web", "vendor", "lib.js"), []byte("a\nb\nc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", ".")
+
+	client, err := NewClient(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetDiffIgnore([]string{"package-lock.json", "vendor/"})
+
+	stats, err := client.DiffStats(t.Context())
+	if err != nil {
+		t.Fatalf("DiffStats() error = %v", err)
+	}
+
+	if stats.Additions != 2 {
+		t.Errorf("Additions = %d, want 2 (ignored files excluded)", stats.Additions)
+	}
+	if stats.NewFiles != 0 {
+		t.Errorf("NewFiles = %d, want 0 (ignored files excluded)", stats.NewFiles)
+	}
+	if stats.ModifiedFiles != 1 {
+		t.Errorf("ModifiedFiles = %d, want 1", stats.ModifiedFiles)
+	}
+}
+
 // Integration test using real git
 func TestIntegration_RealGitRepo(t *testing.T) {
 	// Skip if git is not available
@@ -629,13 +1261,13 @@ func TestIntegration_RealGitRepo(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	client, err := NewClient(dir)
+	client, err := NewClient(t.Context(), dir)
 	if err != nil {
 		t.Fatalf("NewClient() error = %v", err)
 	}
 
 	// Test branch (should be master or main depending on git config)
-	branch, err := client.Branch()
+	branch, err := client.Branch(t.Context())
 	if err != nil {
 		t.Fatalf("Branch() error = %v", err)
 	}
@@ -645,7 +1277,7 @@ func TestIntegration_RealGitRepo(t *testing.T) {
 	}
 
 	// Test status with uncommitted file
-	status, err := client.Status()
+	status, err := client.Status(t.Context())
 	if err != nil {
 		t.Fatalf("Status() error = %v", err)
 	}
@@ -0,0 +1,788 @@
+package git
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// NativeClient provides the same operations as Client by reading the
+// repository in-process with go-git, so it works where there is no git
+// binary (containers, minimal CI images). Results follow git's porcelain
+// output closely, but line endings are compared as stored (no autocrlf).
+// go-git calls can't be interrupted, so methods check ctx before reading
+// the working tree and between commits or files in longer walks. Methods
+// are safe for concurrent use, but run one at a time: go-git's repository
+// and its object caches aren't.
+type NativeClient struct {
+	repoDir
+	mu     sync.Mutex // Serializes use of repo and wt
+	root   string     // Top of the working tree
+	repo   *gogit.Repository
+	wt     *gogit.Worktree
+	ignore gitignore.Matcher // Paths excluded from DiffStats and DiffSince; nil excludes none
+}
+
+// NewNativeClient opens the repository containing workDir with go-git.
+// Returns an error if the directory is not in a git repository or the
+// repository has no working tree.
+func NewNativeClient(workDir string) (*NativeClient, error) {
+	repo, err := gogit.PlainOpenWithOptions(workDir, &gogit.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("no working tree: %w", err)
+	}
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, fmt.Errorf("unsupported repository storage %T", repo.Storer)
+	}
+
+	// Like git, also skip what the user's core.excludesFile ignores
+	if patterns, err := gitignore.LoadGlobalPatterns(osfs.New("/")); err == nil {
+		wt.Excludes = append(wt.Excludes, patterns...)
+	}
+
+	return &NativeClient{
+		repoDir: newRepoDir(storage.Filesystem().Root()),
+		root:    wt.Filesystem.Root(),
+		repo:    repo,
+		wt:      wt,
+	}, nil
+}
+
+// SetDiffIgnore excludes files matching the given gitignore-style globs
+// (e.g. "package-lock.json", "vendor/", "*.pb.go") from DiffStats.
+func (c *NativeClient) SetDiffIgnore(patterns []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ps []gitignore.Pattern
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			ps = append(ps, gitignore.ParsePattern(p, nil))
+		}
+	}
+	c.ignore = nil
+	if len(ps) > 0 {
+		c.ignore = gitignore.NewMatcher(ps)
+	}
+}
+
+// excluded reports whether SetDiffIgnore's patterns match path.
+func (c *NativeClient) excluded(path string) bool {
+	return c.ignore != nil && c.ignore.Match(strings.Split(path, "/"), false)
+}
+
+// Branch returns the current branch name.
+// Returns "HEAD" for detached HEAD state.
+func (c *NativeClient) Branch(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.branch()
+}
+
+// branch is Branch for callers holding mu.
+func (c *NativeClient) branch() (string, error) {
+	head, err := c.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target().Short(), nil
+	}
+	return "HEAD", nil
+}
+
+// DetachedName names a detached HEAD: the tag pointing at it, if there is
+// one, or its abbreviated hash.
+func (c *NativeClient) DetachedName(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	var tags []string
+	refs, err := c.repo.Tags()
+	if err == nil {
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			target := ref.Hash()
+			if tag, err := c.repo.TagObject(target); err == nil {
+				target = tag.Target // Annotated
+			}
+			if target == head.Hash() {
+				tags = append(tags, ref.Name().Short())
+			}
+			return nil
+		})
+	}
+	if len(tags) > 0 {
+		slices.Sort(tags) // Stable between renders
+		return tags[0], nil
+	}
+	return shortHash(head.Hash()), nil
+}
+
+// Status returns a string representing uncommitted changes.
+// Returns empty string if the working tree is clean.
+// Returns "±N" where N is the number of changed files.
+func (c *NativeClient) Status(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, err := c.status(ctx)
+	if err != nil {
+		return "", err
+	}
+	lines, err := c.porcelain(st, false)
+	if err != nil || len(lines) == 0 {
+		return "", err
+	}
+	return fmt.Sprintf("±%d", len(lines)), nil
+}
+
+// Summary returns the current branch and number of changed files.
+func (c *NativeClient) Summary(ctx context.Context) (Summary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	branch, err := c.branch()
+	if err != nil {
+		return Summary{}, err
+	}
+	st, err := c.status(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	lines, err := c.porcelain(st, false)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summary{Branch: branch, Changed: len(lines)}, nil
+}
+
+// status returns the working tree status with staged renames detected. go-git
+// reports a rename as a deletion and an addition; like git, a staged file
+// whose content matches a staged deletion is reported as Renamed from it.
+// Only exact renames are found, where git also pairs similar files.
+func (c *NativeClient) status(ctx context.Context) (gogit.Status, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	st, err := c.wt.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[plumbing.Hash]string) // HEAD blob of each staged deletion
+	if tree := c.headTree(); tree != nil {
+		for path, fs := range st {
+			if fs.Staging != gogit.Deleted || fs.Worktree != gogit.Unmodified {
+				continue
+			}
+			if f, err := tree.File(path); err == nil {
+				deleted[f.Hash] = path
+			}
+		}
+	}
+	if len(deleted) == 0 {
+		return st, nil
+	}
+
+	idx, err := c.repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	added := make([]string, 0, len(st))
+	for path, fs := range st {
+		if fs.Staging == gogit.Added {
+			added = append(added, path)
+		}
+	}
+	slices.Sort(added) // Pair duplicates the same way every render
+	for _, path := range added {
+		e, err := idx.Entry(path)
+		if err != nil {
+			continue
+		}
+		if from, ok := deleted[e.Hash]; ok {
+			st[path].Staging, st[path].Extra = gogit.Renamed, from
+			delete(st, from)
+			delete(deleted, e.Hash)
+		}
+	}
+	return st, nil
+}
+
+// porcelain renders st as "git status --porcelain" lines, sorted by path.
+// Untracked files are collapsed into their outermost untracked directory
+// ("dir/"), as git shows them. With excludes, SetDiffIgnore's patterns
+// are applied.
+func (c *NativeClient) porcelain(st gogit.Status, excludes bool) ([]string, error) {
+	idx, err := c.repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]bool) // Directories holding tracked files
+	for _, e := range idx.Entries {
+		for dir := e.Name; ; {
+			i := strings.LastIndex(dir, "/")
+			if i < 0 || tracked[dir[:i+1]] {
+				break
+			}
+			dir = dir[:i]
+			tracked[dir+"/"] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var lines []string
+	for path, fs := range st {
+		if fs.Staging == gogit.Unmodified && fs.Worktree == gogit.Unmodified {
+			continue
+		}
+		if excludes && c.excluded(path) {
+			continue
+		}
+		if fs.Worktree == gogit.Untracked {
+			path = untrackedDir(path, tracked)
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+		}
+		line := string([]byte{byte(fs.Staging), byte(fs.Worktree), ' '})
+		if fs.Staging == gogit.Renamed && fs.Extra != "" {
+			line += quotePath(fs.Extra) + " -> "
+		}
+		lines = append(lines, line+quotePath(path))
+	}
+	// Like git: changes to tracked files by path, then untracked files
+	slices.SortFunc(lines, func(a, b string) int {
+		if untrackedA, untrackedB := a[0] == '?', b[0] == '?'; untrackedA != untrackedB {
+			if untrackedA {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(statusPath(a), statusPath(b))
+	})
+	return lines, nil
+}
+
+// untrackedDir returns the outermost directory of path that holds no
+// tracked files, with a trailing slash, or path itself if there is none.
+func untrackedDir(path string, tracked map[string]bool) string {
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && !tracked[path[:i+1]] {
+			return path[:i+1]
+		}
+	}
+	return path
+}
+
+// quotePath quotes path the way porcelain output does when it contains
+// quotes, backslashes, or control characters.
+func quotePath(path string) string {
+	if quoted := strconv.Quote(path); quoted[1:len(quoted)-1] != path {
+		return quoted
+	}
+	return path
+}
+
+// StashCount returns the number of stash entries, one per line of the
+// stash reflog.
+func (c *NativeClient) StashCount(ctx context.Context) (int, error) {
+	data, err := os.ReadFile(c.StashLogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// HeadSHA returns the full hash of the commit HEAD points at, or EmptyTree
+// before the first commit.
+func (c *NativeClient) HeadSHA(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return EmptyTree, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
+}
+
+// DiffSince returns the lines changed in the working tree, committed or not,
+// since rev. Untracked files aren't counted.
+func (c *NativeClient) DiffSince(ctx context.Context, rev string) (LineStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	base := make(map[string]plumbing.Hash)
+	if rev != EmptyTree {
+		commit, err := c.repo.CommitObject(plumbing.NewHash(rev))
+		if err != nil {
+			return LineStats{}, err
+		}
+		tree, err := commit.Tree()
+		if err != nil {
+			return LineStats{}, err
+		}
+		walker := object.NewTreeWalker(tree, true, nil)
+		defer walker.Close()
+		for {
+			name, entry, err := walker.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return LineStats{}, err
+			}
+			if entry.Mode.IsFile() {
+				base[name] = entry.Hash
+			}
+		}
+	}
+
+	idx, err := c.repo.Storer.Index()
+	if err != nil {
+		return LineStats{}, err
+	}
+	st, err := c.status(ctx)
+	if err != nil {
+		return LineStats{}, err
+	}
+
+	// Files no longer tracked, by content, to pair with exact renames
+	tracked := make(map[string]bool, len(idx.Entries))
+	for _, e := range idx.Entries {
+		tracked[e.Name] = true
+	}
+	removed := make(map[plumbing.Hash]string)
+	for path, hash := range base {
+		if !tracked[path] {
+			removed[hash] = path
+		}
+	}
+
+	var stats LineStats
+	count := func(path string, from, to []byte) {
+		if !c.excluded(path) {
+			added, deleted := lineChanges(from, to)
+			stats.Additions += added
+			stats.Deletions += deleted
+		}
+	}
+	for _, e := range idx.Entries {
+		if err := ctx.Err(); err != nil {
+			return LineStats{}, err
+		}
+		// Merged entries are stage 0, whatever go-git's index.Merged says
+		if e.Stage != 0 || e.Mode == filemode.Submodule {
+			continue
+		}
+		fs, changed := st[e.Name]
+		changed = changed && fs.Worktree != gogit.Unmodified
+		old, inBase := base[e.Name]
+		delete(base, e.Name)
+		if !changed && inBase && old == e.Hash {
+			continue
+		}
+		if from, ok := removed[e.Hash]; ok && !changed && !inBase {
+			delete(removed, e.Hash) // Renamed without changes, as git diff detects
+			delete(base, from)
+			continue
+		}
+		var from, to []byte
+		if inBase {
+			from = c.blob(old)
+		}
+		if changed {
+			to = c.worktreeFile(e.Name)
+		} else {
+			to = c.blob(e.Hash)
+		}
+		count(e.Name, from, to)
+	}
+	for path, hash := range base {
+		count(path, c.blob(hash), nil) // Removed from the index
+	}
+	return stats, nil
+}
+
+// CommitsSince counts the commits reachable from HEAD that were committed
+// after t.
+func (c *NativeClient) CommitsSince(ctx context.Context, t time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.repo.Head()
+	if err != nil {
+		return 0, err
+	}
+	commits, err := c.repo.Log(&gogit.LogOptions{From: head.Hash(), Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if commit.Committer.When.Before(t) {
+			return storer.ErrStop
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// LastCommit returns the commit HEAD points at. Returns an error before the
+// first commit.
+func (c *NativeClient) LastCommit(ctx context.Context) (Commit, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.repo.Head()
+	if err != nil {
+		return Commit{}, err
+	}
+	commit, err := c.repo.CommitObject(head.Hash())
+	if err != nil {
+		return Commit{}, err
+	}
+	// Like %s: the first paragraph, joined onto one line
+	subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n\n")
+	return Commit{
+		SHA:     shortHash(commit.Hash),
+		Subject: strings.Join(strings.Fields(subject), " "),
+		Time:    commit.Committer.When,
+	}, nil
+}
+
+// RemoteURL returns the URL of the origin remote.
+func (c *NativeClient) RemoteURL(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remote, err := c.repo.Remote("origin")
+	if err != nil {
+		return "", err
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", errors.New("origin has no URL")
+	}
+	return urls[0], nil
+}
+
+// AheadBehind counts commits between HEAD and its upstream branch. Returns an
+// error if the branch has no upstream (or HEAD is detached).
+func (c *NativeClient) AheadBehind(ctx context.Context) (UpstreamStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.repo.Head()
+	if err != nil {
+		return UpstreamStatus{}, err
+	}
+	if !head.Name().IsBranch() {
+		return UpstreamStatus{}, errors.New("HEAD is detached")
+	}
+	cfg, err := c.repo.Config()
+	if err != nil {
+		return UpstreamStatus{}, err
+	}
+	branch, ok := cfg.Branches[head.Name().Short()]
+	if !ok || branch.Merge == "" {
+		return UpstreamStatus{}, fmt.Errorf("branch %s has no upstream", head.Name().Short())
+	}
+	upstreamName := branch.Merge // A local branch when the remote is "."
+	if branch.Remote != "." {
+		upstreamName = plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short())
+	}
+	upstream, err := c.repo.Reference(upstreamName, true)
+	if err != nil {
+		return UpstreamStatus{}, err
+	}
+
+	behind, ahead, err := c.leftRightCount(ctx, upstream.Hash(), head.Hash())
+	if err != nil {
+		return UpstreamStatus{}, err
+	}
+	return UpstreamStatus{Ahead: ahead, Behind: behind}, nil
+}
+
+// Sides of a left-right walk a commit is reachable from.
+const (
+	sideLeft uint8 = 1 << iota
+	sideRight
+	sideBoth = sideLeft | sideRight
+)
+
+// leftRightCount counts the commits reachable only from left and only from
+// right, like "rev-list --left-right --count left...right". Commits are
+// visited newest first and the walk stops once every pending commit is
+// reachable from both sides, so only the history since the merge base is
+// read.
+func (c *NativeClient) leftRightCount(ctx context.Context, left, right plumbing.Hash) (onlyLeft, onlyRight int, err error) {
+	sides := make(map[plumbing.Hash]uint8)
+	queue := &commitQueue{}
+	push := func(hash plumbing.Hash, side uint8) error {
+		if sides[hash]|side == sides[hash] {
+			return nil
+		}
+		commit, err := c.repo.CommitObject(hash)
+		if err != nil {
+			return err
+		}
+		sides[hash] |= side
+		heap.Push(queue, commit)
+		return nil
+	}
+	if err := push(left, sideLeft); err != nil {
+		return 0, 0, err
+	}
+	if err := push(right, sideRight); err != nil {
+		return 0, 0, err
+	}
+
+	for queue.Len() > 0 && queue.pending(sides) {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		commit := heap.Pop(queue).(*object.Commit)
+		for _, parent := range commit.ParentHashes {
+			if err := push(parent, sides[commit.Hash]); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	for _, side := range sides {
+		switch side {
+		case sideLeft:
+			onlyLeft++
+		case sideRight:
+			onlyRight++
+		}
+	}
+	return onlyLeft, onlyRight, nil
+}
+
+// commitQueue is a heap of commits, newest committer date first.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
+
+// pending reports whether any queued commit is still reachable from only
+// one side, so walking further could change the counts.
+func (q commitQueue) pending(sides map[plumbing.Hash]uint8) bool {
+	for _, commit := range q {
+		if sides[commit.Hash] != sideBoth {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffStats returns statistics about uncommitted changes.
+// Line counts are reported per side (staged and unstaged) as well as combined,
+// and file type counts are parsed from the porcelain status.
+func (c *NativeClient) DiffStats(ctx context.Context) (DiffStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stats DiffStats
+
+	st, err := c.status(ctx)
+	if err != nil {
+		return stats, err
+	}
+	idx, err := c.repo.Storer.Index()
+	if err != nil {
+		return stats, err
+	}
+	headFiles := c.headTree()
+
+	churn := make(map[string]int)
+	for path, fs := range st {
+		if fs.Worktree == gogit.Untracked || c.excluded(path) ||
+			fs.Staging == gogit.UpdatedButUnmerged || fs.Worktree == gogit.UpdatedButUnmerged {
+			continue
+		}
+		var indexed []byte
+		if e, err := idx.Entry(path); err == nil && e.Mode != filemode.Submodule {
+			indexed = c.blob(e.Hash)
+		}
+		if fs.Staging != gogit.Unmodified {
+			from := path
+			if fs.Staging == gogit.Renamed && fs.Extra != "" {
+				from = fs.Extra
+			}
+			var committed []byte
+			if headFiles != nil {
+				if f, err := headFiles.File(from); err == nil && f.Mode != filemode.Submodule {
+					committed = c.blob(f.Hash)
+				}
+			}
+			added, deleted := lineChanges(committed, indexed)
+			stats.StagedAdditions += added
+			stats.StagedDeletions += deleted
+			churn[path] += added + deleted
+		}
+		if fs.Worktree != gogit.Unmodified {
+			added, deleted := lineChanges(indexed, c.worktreeFile(path))
+			stats.UnstagedAdditions += added
+			stats.UnstagedDeletions += deleted
+			churn[path] += added + deleted
+		}
+	}
+	stats.Additions = stats.StagedAdditions + stats.UnstagedAdditions
+	stats.Deletions = stats.StagedDeletions + stats.UnstagedDeletions
+	stats.LargestFile, stats.LargestFileLines = largestFile(churn)
+
+	lines, err := c.porcelain(st, true)
+	if err != nil {
+		return stats, err
+	}
+	statusOut := strings.Join(lines, "\n")
+	stats.NewFiles, stats.ModifiedFiles, stats.DeletedFiles, stats.UnstagedFiles = parseStatusForTypes(statusOut)
+	stats.Conflicts = parseConflicts(statusOut)
+	stats.FileTypes = parseStatusExtensions(statusOut)
+	stats.ChangedFiles = parseChangedFiles(statusOut, MaxChangedFiles)
+
+	return stats, nil
+}
+
+// headTree returns HEAD's tree, or nil before the first commit.
+func (c *NativeClient) headTree() *object.Tree {
+	head, err := c.repo.Head()
+	if err != nil {
+		return nil
+	}
+	commit, err := c.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil
+	}
+	return tree
+}
+
+// blob returns the content of the blob hash, or nil if it can't be read.
+func (c *NativeClient) blob(hash plumbing.Hash) []byte {
+	b, err := c.repo.BlobObject(hash)
+	if err != nil {
+		return nil
+	}
+	r, err := b.Reader()
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	return data
+}
+
+// worktreeFile returns the content of the repo-relative path in the working
+// tree as git would hash it (a symlink's target rather than what it points
+// at), or nil if it was deleted.
+func (c *NativeClient) worktreeFile(path string) []byte {
+	full := filepath.Join(c.root, filepath.FromSlash(path))
+	if info, err := os.Lstat(full); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, _ := os.Readlink(full)
+		return []byte(target)
+	}
+	data, _ := os.ReadFile(full)
+	return data
+}
+
+// lineChanges counts the lines added and deleted going from one version of
+// a file to another, as "git diff --numstat" does. Binary files count as
+// no lines.
+func lineChanges(from, to []byte) (added, deleted int) {
+	if isBinary(from) || isBinary(to) {
+		return 0, 0
+	}
+	for _, d := range diff.Do(string(from), string(to)) {
+		lines := strings.Count(d.Text, "\n")
+		if !strings.HasSuffix(d.Text, "\n") {
+			lines++ // Last line without a newline
+		}
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			added += lines
+		case diffmatchpatch.DiffDelete:
+			deleted += lines
+		}
+	}
+	return added, deleted
+}
+
+// isBinary reports whether data looks binary to git: a NUL byte in the
+// first 8000 bytes.
+func isBinary(data []byte) bool {
+	return slices.Contains(data[:min(len(data), 8000)], 0)
+}
+
+// shortHash abbreviates hash to git's default seven characters.
+func shortHash(hash plumbing.Hash) string {
+	return hash.String()[:7]
+}
@@ -0,0 +1,383 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newParityRepo creates a repository exercising everything both backends
+// read: a tag, a stash, an upstream it has diverged from, an origin, and
+// staged, unstaged, renamed, untracked, ignored, and diff-ignored changes.
+// It returns the directory and the hash of the first commit.
+func newParityRepo(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	gitCmd := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	gitCmd("init", "--quiet")
+	gitCmd("symbolic-ref", "HEAD", "refs/heads/main")
+	gitCmd("config", "user.name", "Test")
+	gitCmd("config", "user.email", "test@example.com")
+	gitCmd("remote", "add", "origin", "git@github.com:owner/repo.git")
+
+	writeRepoFile(t, dir, "a.txt", "one\ntwo\nthree\n")
+	writeRepoFile(t, dir, "pkg/b.go", "package pkg\n")
+	writeRepoFile(t, dir, "old.go", "package main\n")
+	writeRepoFile(t, dir, "deps.lock", "v1\n")
+	writeRepoFile(t, dir, ".gitignore", "*.log\n")
+	gitCmd("add", ".")
+	gitCmd("commit", "--quiet", "-m", "initial")
+	first := gitCmd("rev-parse", "HEAD")
+	gitCmd("tag", "-a", "v1.0.0", "-m", "release")
+
+	// Diverge from a local upstream: one commit on each side
+	gitCmd("branch", "upstream")
+	gitCmd("branch", "--set-upstream-to=upstream")
+	gitCmd("checkout", "--quiet", "upstream")
+	writeRepoFile(t, dir, "theirs.txt", "theirs\n")
+	gitCmd("add", ".")
+	gitCmd("commit", "--quiet", "-m", "theirs")
+	gitCmd("checkout", "--quiet", "main")
+	writeRepoFile(t, dir, "ours.txt", "ours\n")
+	gitCmd("add", ".")
+	gitCmd("commit", "--quiet", "-m", "ours\n\nwith a body")
+
+	writeRepoFile(t, dir, "a.txt", "stashed\n")
+	gitCmd("stash", "--quiet")
+
+	// Staged first, so the exec client's trimmed output keeps every XY code
+	writeRepoFile(t, dir, ".gitignore", "*.log\n*.tmp\n")
+	gitCmd("add", ".gitignore")
+
+	writeRepoFile(t, dir, "a.txt", "one\n2\nthree\nfour\n")    // Unstaged
+	writeRepoFile(t, dir, "pkg/b.go", "package pkg\n\n// B\n") // Staged, then changed again
+	gitCmd("add", "pkg/b.go")
+	writeRepoFile(t, dir, "pkg/b.go", "package pkg\n")
+	writeRepoFile(t, dir, "staged.go", "package main\n\nfunc f() {}\n")
+	gitCmd("add", "staged.go")
+	gitCmd("mv", "old.go", "renamed.go")
+	gitCmd("rm", "--quiet", "ours.txt")
+	writeRepoFile(t, dir, "deps.lock", "v2\n")
+	writeRepoFile(t, dir, "untracked/x.txt", "x\n")
+	writeRepoFile(t, dir, "untracked/y.txt", "y\n")
+	writeRepoFile(t, dir, "pkg/new.go", "package pkg\n")
+	writeRepoFile(t, dir, "debug.log", "ignored\n")
+	return dir, first
+}
+
+func TestNativeClient_MatchesExec(t *testing.T) {
+	dir, first := newParityRepo(t)
+	execClient, err := NewClient(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	native, err := NewNativeClient(filepath.Join(dir, "pkg"))
+	if err != nil {
+		t.Fatalf("NewNativeClient() error = %v", err)
+	}
+	for _, c := range []Repo{execClient, native} {
+		c.SetDiffIgnore([]string{"*.lock"})
+	}
+
+	compare := func(name string, want, got any, wantErr, gotErr error) {
+		t.Helper()
+		if (wantErr == nil) != (gotErr == nil) {
+			t.Errorf("%s error = %v, exec error = %v", name, gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %+v, exec = %+v", name, got, want)
+		}
+	}
+
+	want, wantErr := execClient.Branch(t.Context())
+	got, gotErr := native.Branch(t.Context())
+	compare("Branch()", want, got, wantErr, gotErr)
+
+	want, wantErr = execClient.Status(t.Context())
+	got, gotErr = native.Status(t.Context())
+	compare("Status()", want, got, wantErr, gotErr)
+
+	wantSummary, wantErr := execClient.Summary(t.Context())
+	gotSummary, gotErr := native.Summary(t.Context())
+	compare("Summary()", wantSummary, gotSummary, wantErr, gotErr)
+
+	wantCount, wantErr := execClient.StashCount(t.Context())
+	gotCount, gotErr := native.StashCount(t.Context())
+	compare("StashCount()", wantCount, gotCount, wantErr, gotErr)
+
+	wantStats, wantErr := execClient.DiffStats(t.Context())
+	gotStats, gotErr := native.DiffStats(t.Context())
+	compare("DiffStats()", wantStats, gotStats, wantErr, gotErr)
+
+	wantUpstream, wantErr := execClient.AheadBehind(t.Context())
+	gotUpstream, gotErr := native.AheadBehind(t.Context())
+	compare("AheadBehind()", wantUpstream, gotUpstream, wantErr, gotErr)
+
+	want, wantErr = execClient.RemoteURL(t.Context())
+	got, gotErr = native.RemoteURL(t.Context())
+	compare("RemoteURL()", want, got, wantErr, gotErr)
+
+	wantCommit, wantErr := execClient.LastCommit(t.Context())
+	gotCommit, gotErr := native.LastCommit(t.Context())
+	if !gotCommit.Time.Equal(wantCommit.Time) {
+		t.Errorf("LastCommit().Time = %v, exec = %v", gotCommit.Time, wantCommit.Time)
+	}
+	gotCommit.Time = wantCommit.Time
+	compare("LastCommit()", wantCommit, gotCommit, wantErr, gotErr)
+
+	want, wantErr = execClient.HeadSHA(t.Context())
+	got, gotErr = native.HeadSHA(t.Context())
+	compare("HeadSHA()", want, got, wantErr, gotErr)
+
+	for _, rev := range []string{first, EmptyTree} {
+		wantLines, wantErr := execClient.DiffSince(t.Context(), rev)
+		gotLines, gotErr := native.DiffSince(t.Context(), rev)
+		compare("DiffSince("+rev[:7]+")", wantLines, gotLines, wantErr, gotErr)
+	}
+
+	wantCount, wantErr = execClient.CommitsSince(t.Context(), time.Now().Add(-time.Hour))
+	gotCount, gotErr = native.CommitsSince(t.Context(), time.Now().Add(-time.Hour))
+	compare("CommitsSince()", wantCount, gotCount, wantErr, gotErr)
+
+	compare("GitDir()", execClient.GitDir(), native.GitDir(), nil, nil)
+	compare("Operation()", execClient.Operation(), native.Operation(), nil, nil)
+}
+
+func TestNativeClient_Detached(t *testing.T) {
+	dir, first := newParityRepo(t)
+	cmd := exec.Command("git", "checkout", "--quiet", "--force", "--detach", first)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git checkout: %v\n%s", err, out)
+	}
+
+	native, err := NewNativeClient(dir)
+	if err != nil {
+		t.Fatalf("NewNativeClient() error = %v", err)
+	}
+	if branch, err := native.Branch(t.Context()); err != nil || branch != "HEAD" {
+		t.Errorf("Branch() = %q, %v; want HEAD", branch, err)
+	}
+	if name, err := native.DetachedName(t.Context()); err != nil || name != "v1.0.0" {
+		t.Errorf("DetachedName() = %q, %v; want the tag", name, err)
+	}
+	if _, err := native.AheadBehind(t.Context()); err == nil {
+		t.Error("AheadBehind() on a detached HEAD succeeded")
+	}
+
+	cmd = exec.Command("git", "tag", "--delete", "v1.0.0")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git tag: %v\n%s", err, out)
+	}
+	if name, err := native.DetachedName(t.Context()); err != nil || name != first[:7] {
+		t.Errorf("DetachedName() = %q, %v; want the short hash %s", name, err, first[:7])
+	}
+}
+
+func TestNativeClient_EmptyRepo(t *testing.T) {
+	dir := t.TempDir()
+	if err := exec.Command("git", "init", "--quiet", dir).Run(); err != nil {
+		t.Skip("git not available")
+	}
+	writeRepoFile(t, dir, "new.txt", "a\nb\n")
+
+	native, err := NewNativeClient(dir)
+	if err != nil {
+		t.Fatalf("NewNativeClient() error = %v", err)
+	}
+	if sha, err := native.HeadSHA(t.Context()); err != nil || sha != EmptyTree {
+		t.Errorf("HeadSHA() = %q, %v; want EmptyTree", sha, err)
+	}
+	if status, err := native.Status(t.Context()); err != nil || status != "±1" {
+		t.Errorf("Status() = %q, %v; want ±1", status, err)
+	}
+	if _, err := native.LastCommit(t.Context()); err == nil {
+		t.Error("LastCommit() before the first commit succeeded")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(t.Context(), t.TempDir(), BackendNative); err == nil {
+		t.Error("Open() outside a repository succeeded")
+	}
+
+	dir := t.TempDir()
+	if err := exec.Command("git", "init", "--quiet", dir).Run(); err != nil {
+		t.Skip("git not available")
+	}
+	if repo, err := Open(t.Context(), dir, BackendNative); err != nil {
+		t.Errorf("Open(native) error = %v", err)
+	} else if _, ok := repo.(*NativeClient); !ok {
+		t.Errorf("Open(native) = %T, want *NativeClient", repo)
+	}
+	if repo, err := Open(t.Context(), dir, BackendExec); err != nil {
+		t.Errorf("Open(exec) error = %v", err)
+	} else if _, ok := repo.(*Client); !ok {
+		t.Errorf("Open(exec) = %T, want *Client", repo)
+	}
+}
+
+func TestLineChanges(t *testing.T) {
+	tests := []struct {
+		name           string
+		from, to       string
+		added, deleted int
+	}{
+		{"new file", "", "a\nb\n", 2, 0},
+		{"deleted file", "a\nb\n", "", 0, 2},
+		{"changed line", "a\nb\nc\n", "a\nB\nc\n", 1, 1},
+		{"no trailing newline", "a", "a\nb", 2, 1}, // "a" gains a newline
+		{"binary", "a\x00b", "c\x00d", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, deleted := lineChanges([]byte(tt.from), []byte(tt.to))
+			if added != tt.added || deleted != tt.deleted {
+				t.Errorf("lineChanges() = +%d -%d, want +%d -%d", added, deleted, tt.added, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestUntrackedDir(t *testing.T) {
+	tracked := map[string]bool{"pkg/": true, "pkg/sub/": true}
+	tests := map[string]string{
+		"top.txt":         "top.txt",
+		"new/a.txt":       "new/",
+		"new/deep/a.txt":  "new/",
+		"pkg/a.go":        "pkg/a.go",
+		"pkg/other/a.go":  "pkg/other/",
+		"pkg/sub/deep/x":  "pkg/sub/deep/",
+		"pkg/sub/file.go": "pkg/sub/file.go",
+	}
+	for path, want := range tests {
+		if got := untrackedDir(path, tracked); got != want {
+			t.Errorf("untrackedDir(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWorktreePaths(t *testing.T) {
+	dir, _ := newParityRepo(t)
+	worktree := filepath.Join(t.TempDir(), "feature")
+	cmd := exec.Command("git", "worktree", "add", "--quiet", "-b", "feature", worktree)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("git worktree add: %v\n%s", err, out)
+	}
+	commonDir := filepath.Join(dir, ".git")
+	gitDir := filepath.Join(commonDir, "worktrees", "feature")
+
+	execClient, err := NewClient(t.Context(), worktree)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	native, err := NewNativeClient(worktree)
+	if err != nil {
+		t.Fatalf("NewNativeClient() error = %v", err)
+	}
+	for _, c := range []Repo{execClient, native} {
+		paths := map[string][2]string{
+			"GitDir()":        {c.GitDir(), gitDir},
+			"HeadPath()":      {c.HeadPath(), filepath.Join(gitDir, "HEAD")},
+			"IndexPath()":     {c.IndexPath(), filepath.Join(gitDir, "index")},
+			"FetchHeadPath()": {c.FetchHeadPath(), filepath.Join(gitDir, "FETCH_HEAD")},
+			"StashLogPath()":  {c.StashLogPath(), filepath.Join(commonDir, "logs", "refs", "stash")},
+			"RefPath()":       {c.RefPath("feature"), filepath.Join(commonDir, "refs", "heads", "feature")},
+		}
+		for name, p := range paths {
+			if got, want := resolveDir(p[0]), resolveDir(p[1]); got != want {
+				t.Errorf("%T.%s = %q, want %q", c, name, got, want)
+			}
+		}
+	}
+	if _, err := os.Stat(execClient.RefPath("feature")); err != nil {
+		t.Errorf("RefPath(feature) doesn't exist: %v", err)
+	}
+}
+
+// resolveDir resolves symlinks in the directory of path, as temp dirs may be
+// reached through one (e.g. /tmp on macOS).
+func resolveDir(path string) string {
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		return filepath.Join(dir, filepath.Base(path))
+	}
+	return path
+}
+
+func TestCancelledContext(t *testing.T) {
+	dir, first := newParityRepo(t)
+	execClient, err := NewClient(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	native, err := NewNativeClient(dir)
+	if err != nil {
+		t.Fatalf("NewNativeClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	for _, c := range []Repo{execClient, native} {
+		if _, err := c.Status(ctx); err == nil {
+			t.Errorf("%T.Status() with a cancelled context succeeded", c)
+		}
+		if _, err := c.DiffSince(ctx, first); err == nil {
+			t.Errorf("%T.DiffSince() with a cancelled context succeeded", c)
+		}
+		if _, err := c.AheadBehind(ctx); err == nil {
+			t.Errorf("%T.AheadBehind() with a cancelled context succeeded", c)
+		}
+	}
+}
+
+func TestNativeClient_Concurrent(t *testing.T) {
+	src, _ := newParityRepo(t)
+	// Cloned without hardlinks, objects are read from a packfile, whose
+	// shared readers race without serialization
+	dir := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", "--quiet", "--no-local", src, dir).CombinedOutput(); err != nil {
+		t.Skipf("git clone failed: %v\n%s", err, out)
+	}
+	writeRepoFile(t, dir, "a.txt", "changed\n")
+	native, err := NewNativeClient(dir)
+	if err != nil {
+		t.Fatalf("NewNativeClient() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, err := native.DiffStats(t.Context()); err != nil {
+				t.Errorf("DiffStats() error = %v", err)
+			}
+		})
+		wg.Go(func() {
+			if _, err := native.LastCommit(t.Context()); err != nil {
+				t.Errorf("LastCommit() error = %v", err)
+			}
+		})
+		wg.Go(func() {
+			if _, err := native.CommitsSince(t.Context(), time.Time{}); err != nil {
+				t.Errorf("CommitsSince() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the burst of file events a single git operation
+// (commit, checkout, add) produces into one notification.
+const watchDebounce = 50 * time.Millisecond
+
+// watchedFiles are the top-level git dir entries that affect cached git data.
+var watchedFiles = map[string]bool{
+	"HEAD":  true,
+	"index": true,
+}
+
+// watchedCommonFiles are the top-level common dir entries that do.
+var watchedCommonFiles = map[string]bool{
+	"packed-refs": true,
+}
+
+// Watch calls onChange whenever HEAD, the index, or any ref under gitDir
+// changes, so cached branch, status, and diff data can be refreshed before the
+// next render asks for it. In a linked worktree, refs are watched in the
+// shared common dir. Lock files are ignored and bursts of events are
+// debounced. Blocks until ctx is cancelled.
+func Watch(ctx context.Context, gitDir string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create git watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(gitDir); err != nil {
+		return fmt.Errorf("failed to watch git directory: %w", err)
+	}
+	commonDir := CommonDir(gitDir)
+	if commonDir != filepath.Clean(gitDir) {
+		if err := watcher.Add(commonDir); err != nil {
+			return fmt.Errorf("failed to watch git common directory: %w", err)
+		}
+	}
+	refsDir := filepath.Join(commonDir, "refs")
+	addDirs(watcher, refsDir)
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-pending:
+			pending = nil
+			onChange()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// fsnotify is not recursive; pick up new ref namespaces like refs/heads/feature/
+			if event.Has(fsnotify.Create) && strings.HasPrefix(event.Name, refsDir) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					addDirs(watcher, event.Name)
+				}
+			}
+			if !isWatchedGitPath(gitDir, commonDir, event.Name) {
+				continue
+			}
+			slog.Debug("git state changed", "path", event.Name, "op", event.Op.String())
+			pending = time.After(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("git watcher error", "err", err)
+		}
+	}
+}
+
+// isWatchedGitPath reports whether a changed path affects cached git data.
+func isWatchedGitPath(gitDir, commonDir, path string) bool {
+	if strings.HasSuffix(path, ".lock") {
+		return false
+	}
+	if strings.HasPrefix(path, filepath.Join(commonDir, "refs")+string(filepath.Separator)) {
+		return true
+	}
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	return dir == filepath.Clean(gitDir) && watchedFiles[name] ||
+		dir == filepath.Clean(commonDir) && watchedCommonFiles[name]
+}
+
+// addDirs adds root and every directory below it to the watcher.
+func addDirs(watcher *fsnotify.Watcher, root string) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			slog.Debug("failed to watch ref directory", "path", path, "err", err)
+		}
+		return nil
+	})
+}
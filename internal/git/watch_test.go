@@ -0,0 +1,127 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch_NotifiesOnGitChanges(t *testing.T) {
+	gitDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, gitDir, func() { changed <- struct{}{} })
+	}()
+
+	// Give the watcher time to register before writing
+	time.Sleep(100 * time.Millisecond)
+
+	expectChange := func(what string) {
+		t.Helper()
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Watch() did not report %s", what)
+		}
+	}
+	expectQuiet := func(what string) {
+		t.Helper()
+		select {
+		case <-changed:
+			t.Fatalf("Watch() reported %s", what)
+		case <-time.After(4 * watchDebounce):
+		}
+	}
+
+	// Lock files and unrelated files are ignored
+	if err := os.WriteFile(filepath.Join(gitDir, "index.lock"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "COMMIT_EDITMSG"), []byte("msg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectQuiet("a lock or unrelated file")
+
+	if err := os.WriteFile(filepath.Join(gitDir, "index"), []byte("idx"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("an index write")
+
+	// Branches with slashes live in new subdirectories, which must be picked up
+	featureDir := filepath.Join(gitDir, "refs", "heads", "feature")
+	if err := os.MkdirAll(featureDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Drain the notification for the directory itself, if any
+	select {
+	case <-changed:
+	case <-time.After(4 * watchDebounce):
+	}
+	if err := os.WriteFile(filepath.Join(featureDir, "login"), []byte("abc123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("a ref update in a new directory")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not return after cancel")
+	}
+}
+
+func TestIsWatchedGitPath(t *testing.T) {
+	gitDir := filepath.Join("/repo", ".git")
+	refsDir := filepath.Join(gitDir, "refs")
+	worktreeDir := filepath.Join(gitDir, "worktrees", "feature")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(gitDir, "HEAD"), true},
+		{filepath.Join(gitDir, "index"), true},
+		{filepath.Join(gitDir, "packed-refs"), true},
+		{filepath.Join(gitDir, "index.lock"), false},
+		{filepath.Join(gitDir, "ORIG_HEAD"), false},
+		{filepath.Join(refsDir, "heads", "main"), true},
+		{filepath.Join(refsDir, "heads", "main.lock"), false},
+		{filepath.Join(gitDir, "logs", "HEAD"), false},
+	}
+	for _, tt := range tests {
+		if got := isWatchedGitPath(gitDir, gitDir, tt.path); got != tt.want {
+			t.Errorf("isWatchedGitPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// A linked worktree has its own HEAD and index, but shares refs
+	worktreeTests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(worktreeDir, "HEAD"), true},
+		{filepath.Join(worktreeDir, "index"), true},
+		{filepath.Join(refsDir, "heads", "feature"), true},
+		{filepath.Join(gitDir, "packed-refs"), true},
+		{filepath.Join(gitDir, "HEAD"), false}, // The main worktree's
+		{filepath.Join(gitDir, "index"), false},
+	}
+	for _, tt := range worktreeTests {
+		if got := isWatchedGitPath(worktreeDir, gitDir, tt.path); got != tt.want {
+			t.Errorf("isWatchedGitPath(worktree, %q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
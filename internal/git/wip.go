@@ -0,0 +1,72 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNothingToSave is returned by CommitWIP and StashSnapshot when the
+// working tree has no uncommitted changes.
+var ErrNothingToSave = errors.New("no uncommitted changes to save")
+
+// wipTimeout bounds the commands that save work. Staging a large tree can
+// take far longer than the reads the status line makes.
+const wipTimeout = 30 * time.Second
+
+// CommitWIP stages every change, including untracked files, and commits it
+// on the current branch with message. Commit hooks are skipped: the point is
+// to save work quickly, not to pass checks. Returns the short commit hash.
+func (c *Client) CommitWIP(ctx context.Context, message string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wipTimeout)
+	defer cancel()
+
+	if err := c.requireChanges(ctx); err != nil {
+		return "", err
+	}
+	if _, err := c.cmd.Run(ctx, c.workDir, "add", "--all"); err != nil {
+		return "", fmt.Errorf("git add failed: %w", err)
+	}
+	if _, err := c.cmd.Run(ctx, c.workDir, "commit", "--no-verify", "--quiet", "-m", message); err != nil {
+		return "", fmt.Errorf("git commit failed: %w", err)
+	}
+	return c.cmd.Run(ctx, c.workDir, "rev-parse", "--short", "HEAD")
+}
+
+// StashSnapshot saves every change, including untracked files, as a stash
+// entry with message, then re-applies it so the working tree and index are
+// left as they were. Returns the short hash of the stash commit. If the
+// changes can't be re-applied they stay safe in stash@{0}, and the error
+// says so.
+func (c *Client) StashSnapshot(ctx context.Context, message string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, wipTimeout)
+	defer cancel()
+
+	if err := c.requireChanges(ctx); err != nil {
+		return "", err
+	}
+	if _, err := c.cmd.Run(ctx, c.workDir, "stash", "push", "--include-untracked", "--quiet", "-m", message); err != nil {
+		return "", fmt.Errorf("git stash failed: %w", err)
+	}
+	hash, err := c.cmd.Run(ctx, c.workDir, "rev-parse", "--short", "stash@{0}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve stash@{0}: %w", err)
+	}
+	if _, err := c.cmd.Run(ctx, c.workDir, "stash", "apply", "--index", "--quiet"); err != nil {
+		return hash, fmt.Errorf("changes saved in stash@{0} but not restored, run `git stash pop`: %w", err)
+	}
+	return hash, nil
+}
+
+// requireChanges returns ErrNothingToSave if the working tree is clean.
+func (c *Client) requireChanges(ctx context.Context) error {
+	out, err := c.cmd.Run(ctx, c.workDir, "status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("git status failed: %w", err)
+	}
+	if out == "" {
+		return ErrNothingToSave
+	}
+	return nil
+}
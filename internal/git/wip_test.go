@@ -0,0 +1,110 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newWIPRepo creates a repository with one commit, a modified tracked file,
+// and an untracked file.
+func newWIPRepo(t *testing.T) (*Client, string) {
+	t.Helper()
+	dir := t.TempDir()
+	gitCmd := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	gitCmd("init", "--quiet")
+	gitCmd("config", "user.name", "Test")
+	gitCmd("config", "user.email", "test@example.com")
+	writeRepoFile(t, dir, "main.go", "package main\n")
+	gitCmd("add", "main.go")
+	gitCmd("commit", "--quiet", "-m", "initial")
+
+	writeRepoFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeRepoFile(t, dir, "new.go", "package main\n")
+
+	client, err := NewClient(t.Context(), dir)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client, dir
+}
+
+func writeRepoFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommitWIP(t *testing.T) {
+	client, dir := newWIPRepo(t)
+
+	hash, err := client.CommitWIP(t.Context(), "WIP: save")
+	if err != nil {
+		t.Fatalf("CommitWIP() error = %v", err)
+	}
+	if hash == "" {
+		t.Error("CommitWIP() returned no hash")
+	}
+
+	status, err := client.Status(t.Context())
+	if err != nil || status != "" {
+		t.Errorf("Status() after CommitWIP = %q, %v; want clean", status, err)
+	}
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%s", "--name-only").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(out); !strings.Contains(got, "WIP: save") || !strings.Contains(got, "new.go") {
+		t.Errorf("last commit = %q, want the WIP message including new.go", got)
+	}
+
+	if _, err := client.CommitWIP(t.Context(), "WIP: again"); !errors.Is(err, ErrNothingToSave) {
+		t.Errorf("CommitWIP() on a clean tree error = %v, want ErrNothingToSave", err)
+	}
+}
+
+func TestStashSnapshot(t *testing.T) {
+	client, dir := newWIPRepo(t)
+
+	hash, err := client.StashSnapshot(t.Context(), "snapshot")
+	if err != nil {
+		t.Fatalf("StashSnapshot() error = %v", err)
+	}
+
+	// The working tree is untouched
+	if status, _ := client.Status(t.Context()); status != "±2" {
+		t.Errorf("Status() after StashSnapshot = %q, want ±2", status)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "new.go")); err != nil || string(data) != "package main\n" {
+		t.Errorf("new.go after StashSnapshot = %q, %v", data, err)
+	}
+
+	// And the stash holds the changes, untracked file included
+	out, err := exec.Command("git", "-C", dir, "stash", "list", "--format=%h %s").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(out); !strings.HasPrefix(got, hash) || !strings.Contains(got, "snapshot") {
+		t.Errorf("stash list = %q, want %s with the snapshot message", got, hash)
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", "stash@{0}^3:new.go").Run(); err != nil {
+		t.Errorf("stash does not include untracked new.go: %v", err)
+	}
+}
@@ -7,9 +7,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/kostyay/claude-status/internal/cistatus"
+	"github.com/kostyay/claude-status/internal/httpclient"
 )
 
 // Default timeout for GitHub API requests.
@@ -22,18 +29,24 @@ type HTTPClient interface {
 
 // TokenGetter is an interface for getting GitHub tokens.
 type TokenGetter interface {
-	GetToken() (string, error)
+	GetToken(ctx context.Context) (string, error)
 }
 
 // GHCLITokenGetter gets tokens from the gh CLI.
-type GHCLITokenGetter struct{}
+type GHCLITokenGetter struct {
+	Host string // GitHub Enterprise Server host; empty means github.com
+}
 
 // GetToken gets the GitHub token from the gh CLI.
-func (g *GHCLITokenGetter) GetToken() (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (g *GHCLITokenGetter) GetToken(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "gh", "auth", "token")
+	args := []string{"auth", "token"}
+	if g.Host != "" && g.Host != DefaultHost {
+		args = append(args, "--hostname", g.Host)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("gh auth token failed: %w", err)
@@ -41,22 +54,143 @@ func (g *GHCLITokenGetter) GetToken() (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
+// TokenEnvVars returns the environment variables a token for host is read
+// from, in order, matching gh: GH_TOKEN and GITHUB_TOKEN for github.com, and
+// GH_ENTERPRISE_TOKEN and GITHUB_ENTERPRISE_TOKEN for GitHub Enterprise Server.
+func TokenEnvVars(host string) []string {
+	if host != "" && host != DefaultHost {
+		return []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	return []string{"GH_TOKEN", "GITHUB_TOKEN"}
+}
+
+// EnvTokenGetter gets tokens from the environment variables TokenEnvVars
+// names. It returns an empty token if none is set.
+type EnvTokenGetter struct {
+	Host string // GitHub Enterprise Server host; empty means github.com
+}
+
+// GetToken gets the GitHub token from the environment.
+func (g *EnvTokenGetter) GetToken(_ context.Context) (string, error) {
+	for _, name := range TokenEnvVars(g.Host) {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token, nil
+		}
+	}
+	return "", nil
+}
+
+// tokenCommandTimeout bounds github_token_command, which may ask a password
+// manager.
+const tokenCommandTimeout = 5 * time.Second
+
+// CommandTokenGetter gets tokens from the output of a shell command, such
+// as `op read op://dev/github/token`.
+type CommandTokenGetter struct {
+	Command string // Run with sh -c; empty returns no token
+}
+
+// GetToken runs the command and returns what it prints.
+func (g *CommandTokenGetter) GetToken(ctx context.Context) (string, error) {
+	if g.Command == "" {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, tokenCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "sh", "-c", g.Command).Output()
+	if err != nil {
+		return "", fmt.Errorf("github_token_command failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// TokenChain gets a token from each TokenGetter in turn and returns the
+// first non-empty one. It fails with every source's error if none has a
+// token.
+type TokenChain []TokenGetter
+
+// GetToken returns the first token found.
+func (c TokenChain) GetToken(ctx context.Context) (string, error) {
+	var errs []error
+	for _, g := range c {
+		token, err := g.GetToken(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if token != "" {
+			return token, nil
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+// DefaultTokenGetter returns the token sources for host in order: the
+// environment, then tokenCommand if set, then `gh auth token`.
+func DefaultTokenGetter(host, tokenCommand string) TokenChain {
+	return TokenChain{
+		&EnvTokenGetter{Host: host},
+		&CommandTokenGetter{Command: tokenCommand},
+		&GHCLITokenGetter{Host: host},
+	}
+}
+
 // Client provides GitHub API operations.
 type Client struct {
-	token      string
-	httpClient HTTPClient
-	workflow   string
-	baseURL    string
+	token         string
+	httpClient    HTTPClient
+	workflows     []string
+	statusContext string // Commit status context; when set, replaces workflow lookup
+	aggregate     bool   // Combine all check runs and commit statuses instead of one workflow
+	pathFilter    string // Repo-relative directory; only runs touching it count
+	baseURL       string
+}
+
+// NewClient creates a new GitHub client. Requests go through the proxy set in
+// HTTPS_PROXY (unless excluded by NO_PROXY); caBundle, if set, is a PEM file
+// of extra CAs to trust.
+func NewClient(ctx context.Context, workflow, caBundle string) (*Client, error) {
+	return NewClientForHost(ctx, []string{workflow}, caBundle, "", "", "")
 }
 
-// NewClient creates a new GitHub client.
-func NewClient(workflow string) (*Client, error) {
-	return NewClientWithDeps(workflow, &http.Client{Timeout: 5 * time.Second}, &GHCLITokenGetter{})
+// NewClientForHost creates a GitHub client for host, a GitHub Enterprise
+// Server such as "github.mycorp.com" (empty means github.com). The token comes
+// from DefaultTokenGetter(host, tokenCommand) and requests go to apiURL, or to
+// APIURL(host) when apiURL is empty. Build status combines the latest runs
+// of every workflow in workflows.
+func NewClientForHost(ctx context.Context, workflows []string, caBundle, host, apiURL, tokenCommand string) (*Client, error) {
+	httpClient, err := httpclient.New(caBundle)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClientWithDeps(ctx, "", httpClient, DefaultTokenGetter(host, tokenCommand))
+	if err != nil {
+		return nil, err
+	}
+	c.SetWorkflows(workflows)
+	if apiURL == "" {
+		apiURL = APIURL(host)
+	}
+	c.SetBaseURL(apiURL)
+	return c, nil
+}
+
+// DefaultHost is the host of github.com remotes.
+const DefaultHost = "github.com"
+
+// APIURL returns the REST API root for host: https://api.github.com for
+// github.com (or ""), and https://<host>/api/v3 for GitHub Enterprise Server.
+func APIURL(host string) string {
+	if host == "" || host == DefaultHost {
+		return "https://api.github.com"
+	}
+	return "https://" + host + "/api/v3"
 }
 
 // NewClientWithDeps creates a new GitHub client with injected dependencies.
-func NewClientWithDeps(workflow string, httpClient HTTPClient, tokenGetter TokenGetter) (*Client, error) {
-	token, err := tokenGetter.GetToken()
+func NewClientWithDeps(ctx context.Context, workflow string, httpClient HTTPClient, tokenGetter TokenGetter) (*Client, error) {
+	token, err := tokenGetter.GetToken(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +202,7 @@ func NewClientWithDeps(workflow string, httpClient HTTPClient, tokenGetter Token
 	return &Client{
 		token:      token,
 		httpClient: httpClient,
-		workflow:   workflow,
+		workflows:  []string{workflow},
 		baseURL:    "https://api.github.com",
 	}, nil
 }
@@ -85,140 +219,820 @@ func NewClientWithToken(workflow, token string, httpClient HTTPClient) (*Client,
 	return &Client{
 		token:      token,
 		httpClient: httpClient,
-		workflow:   workflow,
+		workflows:  []string{workflow},
 		baseURL:    "https://api.github.com",
 	}, nil
 }
 
-// SetBaseURL sets the base URL for API requests (useful for testing).
+// SetWorkflows replaces the workflow whose runs are checked with several,
+// whose latest runs are fetched concurrently and combined.
+func (c *Client) SetWorkflows(workflows []string) {
+	c.workflows = slices.Clone(workflows)
+}
+
+// SetBaseURL sets the base URL for API requests, overriding the one derived
+// from the host (github_api_url, or a test server).
 func (c *Client) SetBaseURL(url string) {
-	c.baseURL = url
+	c.baseURL = strings.TrimSuffix(url, "/")
+}
+
+// SetStatusContext switches build status lookups from GitHub Actions workflow
+// runs to the combined commit status API, matching statuses by context name.
+func (c *Client) SetStatusContext(statusContext string) {
+	c.statusContext = statusContext
+}
+
+// SetAggregate switches build status lookups from a single named workflow to
+// the combined result of every check run and commit status on the branch head.
+// SetStatusContext takes precedence when both are set.
+func (c *Client) SetAggregate(aggregate bool) {
+	c.aggregate = aggregate
 }
 
-// BuildStatus represents the status of a GitHub workflow run.
-type BuildStatus string
+// SetPathFilter restricts workflow runs to those whose head commit changed files
+// under dir (repo-relative, e.g. "services/api"). Empty disables filtering.
+func (c *Client) SetPathFilter(dir string) {
+	c.pathFilter = strings.Trim(dir, "/")
+}
+
+// BuildStatus represents the status of a GitHub workflow run. It is the
+// state shared by all CI providers; render it with cistatus.Symbol.
+type BuildStatus = cistatus.Status
 
 const (
-	StatusSuccess BuildStatus = "success"
-	StatusFailure BuildStatus = "failure"
-	StatusPending BuildStatus = "pending"
-	StatusError   BuildStatus = "error"
+	StatusSuccess = cistatus.Success
+	StatusFailure = cistatus.Failure
+	StatusPending = cistatus.Pending
+	StatusError   = cistatus.Error
 )
 
-// GetBuildStatus fetches the latest build status for the configured workflow.
-func (c *Client) GetBuildStatus(owner, repo, branch string) (BuildStatus, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+// GetBuildStatus fetches the latest build status for the configured
+// workflows: any failing run fails the build, otherwise any unfinished one
+// leaves it pending. Workflows without a run on branch are left out.
+func (c *Client) GetBuildStatus(ctx context.Context, owner, repo, branch string) (BuildStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
 	defer cancel()
 
-	return c.GetBuildStatusWithContext(ctx, owner, repo, branch)
-}
+	if c.statusContext != "" {
+		return c.getCommitStatus(ctx, owner, repo, branch)
+	}
+	if c.aggregate {
+		return c.getAggregateStatus(ctx, owner, repo, branch)
+	}
 
-// GetBuildStatusWithContext fetches the latest build status with a custom context.
-func (c *Client) GetBuildStatusWithContext(ctx context.Context, owner, repo, branch string) (BuildStatus, error) {
-	// First, get the workflow ID
-	workflowID, err := c.getWorkflowID(ctx, owner, repo)
+	results, err := c.latestRuns(ctx, owner, repo, branch)
 	if err != nil {
 		return StatusError, err
 	}
+	var statuses []BuildStatus
+	for _, r := range results {
+		if r.err == nil {
+			statuses = append(statuses, runStatus(r.run))
+		}
+	}
+	if len(statuses) == 0 {
+		return StatusError, results[0].err
+	}
+	return cistatus.Combine(statuses...), nil
+}
 
-	// Then get the latest run for this workflow and branch
-	return c.getLatestRunStatus(ctx, owner, repo, workflowID, branch)
+// WorkflowStatus is the latest build status of one configured workflow.
+type WorkflowStatus struct {
+	Name   string      `json:"name"`   // Workflow as configured
+	Status BuildStatus `json:"status"` // StatusError if it has no run on the branch
 }
 
-func (c *Client) getWorkflowID(ctx context.Context, owner, repo string) (int64, error) {
-	apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/workflows", c.baseURL, owner, repo)
+// GetWorkflowStatuses returns the latest build status of each configured
+// workflow on branch, in configured order. It returns nil when build status
+// comes from commit statuses or all checks rather than workflows.
+func (c *Client) GetWorkflowStatuses(ctx context.Context, owner, repo, branch string) ([]WorkflowStatus, error) {
+	if c.statusContext != "" || c.aggregate {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	results, err := c.latestRuns(ctx, owner, repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]WorkflowStatus, len(results))
+	found := false
+	for i, r := range results {
+		statuses[i] = WorkflowStatus{Name: r.name, Status: StatusError}
+		if r.err == nil {
+			statuses[i].Status = runStatus(r.run)
+			found = true
+		}
+	}
+	if !found {
+		return nil, results[0].err
+	}
+	return statuses, nil
+}
+
+// workflowResult is the latest run of one configured workflow, or why there
+// isn't one.
+type workflowResult struct {
+	name string
+	run  workflowRun
+	err  error
+}
+
+// latestRuns returns the latest run on branch of each configured workflow,
+// in configured order, fetching the runs concurrently. It fails only if the
+// repository's workflows can't be listed.
+func (c *Client) latestRuns(ctx context.Context, owner, repo, branch string) ([]workflowResult, error) {
+	if len(c.workflows) == 0 {
+		return nil, errors.New("no workflow configured")
+	}
+	workflows, err := c.listWorkflows(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
 
+	results := make([]workflowResult, len(c.workflows))
+	var wg sync.WaitGroup
+	for i, name := range c.workflows {
+		results[i].name = name
+		id, ok := findWorkflow(workflows, name)
+		if !ok {
+			results[i].err = fmt.Errorf("workflow %q not found", name)
+			continue
+		}
+		wg.Go(func() {
+			results[i].run, results[i].err = c.getLatestRun(ctx, owner, repo, id, branch)
+		})
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// getJSON performs an authenticated GET request and decodes the JSON body into out.
+func (c *Client) getJSON(ctx context.Context, apiURL string, out any) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	c.setHeaders(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return httpclient.Explain(err)
 	}
 	defer resp.Body.Close()
 
+	if err := checkRateLimit(resp, time.Now()); err != nil {
+		return err
+	}
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("GitHub API request to %s returned %d", apiURL, resp.StatusCode)
+		return fmt.Errorf("GitHub API request to %s returned %d", apiURL, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", apiURL, err)
+	}
+	return nil
+}
+
+// RateLimitError is returned when GitHub refuses a request because a rate
+// limit was exceeded. Requests fail until Reset.
+type RateLimitError struct {
+	StatusCode int       // 403 or 429
+	Reset      time.Time // When requests are allowed again
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit exceeded (%d); resets at %s", e.StatusCode, e.Reset.Format(time.TimeOnly))
+}
+
+// secondaryRateLimitWait is how long to wait after a rate limit response
+// that doesn't say when it resets, as GitHub advises for secondary limits.
+const secondaryRateLimitWait = time.Minute
+
+// checkRateLimit returns a RateLimitError if resp is a rate limit response:
+// a 429, or a 403 with no requests remaining or a Retry-After. The reset
+// time comes from Retry-After, then X-RateLimit-Reset, then a minute after
+// now.
+func checkRateLimit(resp *http.Response, now time.Time) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""):
+	default:
+		return nil
+	}
+
+	reset := now.Add(secondaryRateLimitWait)
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		reset = now.Add(time.Duration(secs) * time.Second)
+	} else if unix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(unix, 0)
 	}
+	return &RateLimitError{StatusCode: resp.StatusCode, Reset: reset}
+}
+
+// workflow is the subset of a workflow returned by the workflows API.
+type workflow struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// listWorkflows returns the repository's workflows.
+func (c *Client) listWorkflows(ctx context.Context, owner, repo string) ([]workflow, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/workflows", c.baseURL, owner, repo)
 
 	var result struct {
-		Workflows []struct {
-			ID   int64  `json:"id"`
-			Name string `json:"name"`
-			Path string `json:"path"`
-		} `json:"workflows"`
+		Workflows []workflow `json:"workflows"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, fmt.Errorf("failed to decode workflows response: %w", err)
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return nil, err
 	}
+	return result.Workflows, nil
+}
 
-	workflowLower := strings.ToLower(c.workflow)
-	for _, w := range result.Workflows {
+// findWorkflow returns the ID of the workflow matching name by display name
+// or file name.
+func findWorkflow(workflows []workflow, name string) (int64, bool) {
+	nameLower := strings.ToLower(name)
+	for _, w := range workflows {
 		pathLower := strings.ToLower(w.Path)
-		if strings.EqualFold(w.Name, c.workflow) ||
-			strings.HasSuffix(pathLower, workflowLower+".yml") ||
-			strings.HasSuffix(pathLower, workflowLower+".yaml") {
-			return w.ID, nil
+		if strings.EqualFold(w.Name, name) ||
+			strings.HasSuffix(pathLower, nameLower+".yml") ||
+			strings.HasSuffix(pathLower, nameLower+".yaml") {
+			return w.ID, true
 		}
 	}
-
-	return 0, fmt.Errorf("workflow %q not found", c.workflow)
+	return 0, false
 }
 
-func (c *Client) getLatestRunStatus(ctx context.Context, owner, repo string, workflowID int64, branch string) (BuildStatus, error) {
-	apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%d/runs?branch=%s&per_page=1",
-		c.baseURL, owner, repo, workflowID, url.QueryEscape(branch))
+// getCommitStatus reads the combined commit status for ref and maps the
+// configured context's state to a BuildStatus.
+func (c *Client) getCommitStatus(ctx context.Context, owner, repo, ref string) (BuildStatus, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s/status?per_page=100",
+		c.baseURL, owner, repo, url.PathEscape(ref))
 
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
+	var result struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			State   string `json:"state"`
+		} `json:"statuses"`
+	}
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
 		return StatusError, err
 	}
-	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	for _, st := range result.Statuses {
+		if !strings.EqualFold(st.Context, c.statusContext) {
+			continue
+		}
+		switch st.State {
+		case "success":
+			return StatusSuccess, nil
+		case "failure", "error":
+			return StatusFailure, nil
+		case "pending":
+			return StatusPending, nil
+		default:
+			return StatusError, nil
+		}
+	}
+
+	return StatusError, fmt.Errorf("status context %q not found", c.statusContext)
+}
+
+// getAggregateStatus combines every check run and commit status on ref: any
+// failure fails the build, otherwise anything unfinished leaves it pending.
+func (c *Client) getAggregateStatus(ctx context.Context, owner, repo, ref string) (BuildStatus, error) {
+	runs, err := c.listCheckRuns(ctx, owner, repo, ref)
 	if err != nil {
 		return StatusError, err
 	}
-	defer resp.Body.Close()
+	summary := summarizeCheckRuns(runs)
 
-	if resp.StatusCode != http.StatusOK {
-		return StatusError, fmt.Errorf("GitHub API request to %s returned %d", apiURL, resp.StatusCode)
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s/status",
+		c.baseURL, owner, repo, url.PathEscape(ref))
+	var combined struct {
+		State      string `json:"state"`
+		TotalCount int    `json:"total_count"`
+	}
+	if err := c.getJSON(ctx, apiURL, &combined); err != nil {
+		return StatusError, err
+	}
+
+	switch {
+	case summary.Failed > 0 || (combined.TotalCount > 0 && (combined.State == "failure" || combined.State == "error")):
+		return StatusFailure, nil
+	case summary.Pending > 0 || (combined.TotalCount > 0 && combined.State == "pending"):
+		return StatusPending, nil
+	case summary.Total > 0 || combined.TotalCount > 0:
+		return StatusSuccess, nil
+	default:
+		return StatusError, fmt.Errorf("no checks reported for %s", ref)
+	}
+}
+
+// checkRun is the subset of a check run returned by the check-runs API.
+type checkRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// passed reports whether a completed check run counts as passing.
+func (r checkRun) passed() bool {
+	switch r.Conclusion {
+	case "success", "neutral", "skipped":
+		return true
+	default:
+		return false
 	}
+}
+
+// listCheckRuns returns the latest check run of each check on ref.
+func (c *Client) listCheckRuns(ctx context.Context, owner, repo, ref string) ([]checkRun, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s/check-runs?per_page=100",
+		c.baseURL, owner, repo, url.PathEscape(ref))
 
 	var result struct {
-		WorkflowRuns []struct {
-			Status     string `json:"status"`
-			Conclusion string `json:"conclusion"`
-		} `json:"workflow_runs"`
+		CheckRuns []checkRun `json:"check_runs"`
+	}
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return StatusError, fmt.Errorf("failed to decode workflow runs response: %w", err)
+	return result.CheckRuns, nil
+}
+
+// summarizeCheckRuns counts check runs by outcome.
+func summarizeCheckRuns(runs []checkRun) ChecksSummary {
+	var summary ChecksSummary
+	for _, run := range runs {
+		summary.Total++
+		switch {
+		case run.Status != "completed":
+			summary.Pending++
+		case run.passed():
+			summary.Passed++
+		default:
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+// workflowRun is the subset of a workflow run returned by the runs API.
+type workflowRun struct {
+	ID         int64  `json:"id"`
+	HeadSHA    string `json:"head_sha"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// pathFilterRunLimit caps how many recent runs are inspected when a path filter is set.
+const pathFilterRunLimit = 10
+
+func (c *Client) getLatestRun(ctx context.Context, owner, repo string, workflowID int64, branch string) (workflowRun, error) {
+	perPage := 1
+	if c.pathFilter != "" {
+		perPage = pathFilterRunLimit
+	}
+
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/workflows/%d/runs?branch=%s&per_page=%d",
+		c.baseURL, owner, repo, workflowID, url.QueryEscape(branch), perPage)
+
+	var result struct {
+		WorkflowRuns []workflowRun `json:"workflow_runs"`
+	}
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return workflowRun{}, err
 	}
 
 	if len(result.WorkflowRuns) == 0 {
-		return StatusError, fmt.Errorf("no workflow runs found")
+		return workflowRun{}, fmt.Errorf("no workflow runs found")
 	}
 
-	run := result.WorkflowRuns[0]
+	if c.pathFilter == "" {
+		return result.WorkflowRuns[0], nil
+	}
+
+	// Runs are newest first; pick the first whose commit touches the filtered path
+	for _, run := range result.WorkflowRuns {
+		touches, err := c.commitTouchesPath(ctx, owner, repo, run.HeadSHA)
+		if err != nil {
+			return workflowRun{}, err
+		}
+		if touches {
+			return run, nil
+		}
+	}
 
+	return workflowRun{}, fmt.Errorf("no workflow runs touching %q found", c.pathFilter)
+}
+
+// commitTouchesPath reports whether the commit changed any file under the path filter.
+func (c *Client) commitTouchesPath(ctx context.Context, owner, repo, sha string) (bool, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s", c.baseURL, owner, repo, sha)
+
+	var result struct {
+		Files []struct {
+			Filename string `json:"filename"`
+		} `json:"files"`
+	}
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return false, err
+	}
+
+	for _, f := range result.Files {
+		if f.Filename == c.pathFilter || strings.HasPrefix(f.Filename, c.pathFilter+"/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// repository is the subset of repository metadata used by the client.
+type repository struct {
+	DefaultBranch string `json:"default_branch"`
+	Private       bool   `json:"private"`
+	Owner         struct {
+		Login string `json:"login"`
+		Type  string `json:"type"` // "User" or "Organization"
+	} `json:"owner"`
+}
+
+func (c *Client) getRepository(ctx context.Context, owner, repo string) (repository, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, owner, repo)
+
+	var result repository
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return repository{}, err
+	}
+	return result, nil
+}
+
+// ActionsUsage reports GitHub Actions minutes for the account owning a private repo.
+type ActionsUsage struct {
+	MinutesUsed      int `json:"minutes_used"`
+	MinutesIncluded  int `json:"minutes_included"`
+	MinutesRemaining int `json:"minutes_remaining"`
+}
+
+// GetActionsUsage returns the Actions minutes quota of the repository owner's
+// account. Public repositories don't consume minutes, so they return a zero
+// ActionsUsage. Requires a token allowed to read the account's billing.
+func (c *Client) GetActionsUsage(ctx context.Context, owner, repo string) (ActionsUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	info, err := c.getRepository(ctx, owner, repo)
+	if err != nil {
+		return ActionsUsage{}, err
+	}
+	if !info.Private {
+		return ActionsUsage{}, nil
+	}
+
+	account := info.Owner.Login
+	if account == "" {
+		account = owner
+	}
+	scope := "users"
+	if info.Owner.Type == "Organization" {
+		scope = "orgs"
+	}
+	apiURL := fmt.Sprintf("%s/%s/%s/settings/billing/actions", c.baseURL, scope, account)
+
+	var result struct {
+		TotalMinutesUsed float64 `json:"total_minutes_used"`
+		IncludedMinutes  float64 `json:"included_minutes"`
+	}
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return ActionsUsage{}, err
+	}
+
+	usage := ActionsUsage{
+		MinutesUsed:     int(result.TotalMinutesUsed),
+		MinutesIncluded: int(result.IncludedMinutes),
+	}
+	usage.MinutesRemaining = max(usage.MinutesIncluded-usage.MinutesUsed, 0)
+	return usage, nil
+}
+
+// BranchStatus pairs a branch name with its build status.
+type BranchStatus struct {
+	Branch string      `json:"branch"`
+	Status BuildStatus `json:"status"`
+}
+
+// GetDefaultBranchStatus looks up the repository's default branch and returns
+// its latest build status.
+func (c *Client) GetDefaultBranchStatus(ctx context.Context, owner, repo string) (BranchStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	result, err := c.getRepository(ctx, owner, repo)
+	if err != nil {
+		return BranchStatus{}, err
+	}
+	if result.DefaultBranch == "" {
+		return BranchStatus{}, fmt.Errorf("default branch not reported for %s/%s", owner, repo)
+	}
+
+	status, err := c.GetBuildStatus(ctx, owner, repo, result.DefaultBranch)
+	if err != nil {
+		return BranchStatus{}, err
+	}
+	return BranchStatus{Branch: result.DefaultBranch, Status: status}, nil
+}
+
+// Run summarizes the latest workflow run for a branch.
+type Run struct {
+	ID     int64
+	Status BuildStatus
+	URL    string
+}
+
+// GetLatestRun returns the latest run of the configured workflow on branch.
+// With several workflows, it is the first one whose latest run failed, or
+// else the first one with a run.
+func (c *Client) GetLatestRun(ctx context.Context, owner, repo, branch string) (Run, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	run, err := c.reportedRun(ctx, owner, repo, branch)
+	if err != nil {
+		return Run{}, err
+	}
+
+	return Run{ID: run.ID, Status: runStatus(run), URL: run.HTMLURL}, nil
+}
+
+// reportedRun returns the run that speaks for the configured workflows on
+// branch: the first failed latest run, or else the first latest run.
+func (c *Client) reportedRun(ctx context.Context, owner, repo, branch string) (workflowRun, error) {
+	results, err := c.latestRuns(ctx, owner, repo, branch)
+	if err != nil {
+		return workflowRun{}, err
+	}
+
+	found := -1
+	for i, r := range results {
+		if r.err != nil {
+			continue
+		}
+		if runStatus(r.run) == StatusFailure {
+			return r.run, nil
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return workflowRun{}, results[0].err
+	}
+	return results[found].run, nil
+}
+
+// RerunFailedJobs re-runs the failed jobs of a workflow run.
+func (c *Client) RerunFailedJobs(ctx context.Context, owner, repo string, runID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d/rerun-failed-jobs", c.baseURL, owner, repo, runID)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return httpclient.Explain(err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkRateLimit(resp, time.Now()); err != nil {
+		return err
+	}
+	// GitHub answers 201 Created when the re-run is queued
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API request to %s returned %d", apiURL, resp.StatusCode)
+	}
+	return nil
+}
+
+// runStatus maps a workflow run's status and conclusion to a BuildStatus.
+func runStatus(run workflowRun) BuildStatus {
 	switch run.Status {
 	case "completed":
 		switch run.Conclusion {
 		case "success":
-			return StatusSuccess, nil
+			return StatusSuccess
 		case "failure", "timed_out", "cancelled":
-			return StatusFailure, nil
+			return StatusFailure
 		default:
-			return StatusError, nil
+			return StatusError
 		}
 	case "queued", "in_progress", "waiting":
-		return StatusPending, nil
+		return StatusPending
 	default:
-		return StatusError, nil
+		return StatusError
+	}
+}
+
+// FailedJob describes the first failing job of a failed workflow run.
+type FailedJob struct {
+	Name   string `json:"name"`    // Job name, e.g. "unit-tests"
+	RunURL string `json:"run_url"` // Browser URL of the workflow run
+}
+
+// GetFailedJob returns the first failed job of the latest run for the configured
+// workflow (the first failed one, with several). Returns a zero FailedJob if the
+// latest run did not fail.
+func (c *Client) GetFailedJob(ctx context.Context, owner, repo, branch string) (FailedJob, error) {
+	// Commit statuses carry no job breakdown
+	if c.statusContext != "" {
+		return FailedJob{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	if c.aggregate {
+		return c.getFailedCheckRun(ctx, owner, repo, branch)
+	}
+
+	run, err := c.reportedRun(ctx, owner, repo, branch)
+	if err != nil {
+		return FailedJob{}, err
+	}
+	if runStatus(run) != StatusFailure {
+		return FailedJob{}, nil
+	}
+
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/actions/runs/%d/jobs?filter=latest&per_page=100",
+		c.baseURL, owner, repo, run.ID)
+
+	var result struct {
+		Jobs []struct {
+			Name       string `json:"name"`
+			Conclusion string `json:"conclusion"`
+		} `json:"jobs"`
+	}
+	if err := c.getJSON(ctx, apiURL, &result); err != nil {
+		return FailedJob{}, err
+	}
+
+	for _, job := range result.Jobs {
+		switch job.Conclusion {
+		case "failure", "timed_out", "cancelled":
+			return FailedJob{Name: job.Name, RunURL: run.HTMLURL}, nil
+		}
+	}
+
+	// Run failed without a failing job (e.g. startup failure); still link the run.
+	return FailedJob{RunURL: run.HTMLURL}, nil
+}
+
+// getFailedCheckRun returns the first failed check run on ref, linking to
+// the check's own page since it may not belong to a workflow run.
+func (c *Client) getFailedCheckRun(ctx context.Context, owner, repo, ref string) (FailedJob, error) {
+	runs, err := c.listCheckRuns(ctx, owner, repo, ref)
+	if err != nil {
+		return FailedJob{}, err
+	}
+	for _, run := range runs {
+		if run.Status == "completed" && !run.passed() {
+			return FailedJob{Name: run.Name, RunURL: run.HTMLURL}, nil
+		}
+	}
+	return FailedJob{}, nil
+}
+
+// ChecksSummary counts the check runs on the head commit of a pull request.
+type ChecksSummary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Pending int `json:"pending"`
+	Total   int `json:"total"`
+}
+
+// GetChecksSummary returns check-run counts for the open pull request whose head
+// is branch. Returns a zero ChecksSummary if the branch has no open PR.
+func (c *Client) GetChecksSummary(ctx context.Context, owner, repo, branch string) (ChecksSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	pull, ok, err := c.findPullRequest(ctx, owner, repo, branch)
+	if err != nil || !ok {
+		return ChecksSummary{}, err
+	}
+
+	runs, err := c.listCheckRuns(ctx, owner, repo, pull.Head.SHA)
+	if err != nil {
+		return ChecksSummary{}, err
+	}
+	return summarizeCheckRuns(runs), nil
+}
+
+// openPull is the part of a pull request listing the client uses.
+type openPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Draft   bool   `json:"draft"`
+	Head    struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// findPullRequest returns the open pull request for branch. The bool is
+// false if there is none.
+func (c *Client) findPullRequest(ctx context.Context, owner, repo, branch string) (openPull, bool, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s&per_page=1",
+		c.baseURL, owner, repo, url.QueryEscape(owner+":"+branch))
+
+	var pulls []openPull
+	if err := c.getJSON(ctx, apiURL, &pulls); err != nil {
+		return openPull{}, false, err
+	}
+
+	if len(pulls) == 0 {
+		return openPull{}, false, nil
+	}
+	return pulls[0], true, nil
+}
+
+// PullRequest describes the open pull request for a branch.
+type PullRequest struct {
+	Number      int    `json:"number"`
+	URL         string `json:"url"`
+	State       string `json:"state"`        // "open" or "draft"
+	ReviewState string `json:"review_state"` // "approved", "changes_requested", or "review_required"
+	Mergeable   string `json:"mergeable"`    // GitHub's mergeable_state, e.g. "clean", "dirty" (conflicts), "blocked", "behind"
+}
+
+// GetPullRequest returns the open pull request whose head is branch, with its
+// review and merge state. Returns a zero PullRequest if there is none.
+func (c *Client) GetPullRequest(ctx context.Context, owner, repo, branch string) (PullRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	pull, ok, err := c.findPullRequest(ctx, owner, repo, branch)
+	if err != nil || !ok {
+		return PullRequest{}, err
+	}
+
+	pr := PullRequest{Number: pull.Number, URL: pull.HTMLURL, State: "open"}
+	if pull.Draft {
+		pr.State = "draft"
+	}
+
+	// Mergeability is only reported on the single-PR endpoint
+	var detail struct {
+		MergeableState string `json:"mergeable_state"`
+	}
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, pull.Number)
+	if err := c.getJSON(ctx, apiURL, &detail); err != nil {
+		return PullRequest{}, err
+	}
+	pr.Mergeable = detail.MergeableState
+
+	var reviews []struct {
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		State string `json:"state"`
+	}
+	apiURL = fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", c.baseURL, owner, repo, pull.Number)
+	if err := c.getJSON(ctx, apiURL, &reviews); err != nil {
+		return PullRequest{}, err
+	}
+
+	// Each reviewer's latest verdict counts; comments don't change it
+	latest := make(map[string]string)
+	for _, review := range reviews {
+		switch review.State {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[review.User.Login] = review.State
+		}
+	}
+	pr.ReviewState = "review_required"
+	for _, state := range latest {
+		if state == "CHANGES_REQUESTED" {
+			pr.ReviewState = "changes_requested"
+			break
+		}
+		if state == "APPROVED" {
+			pr.ReviewState = "approved"
+		}
 	}
+
+	return pr, nil
 }
 
 func (c *Client) setHeaders(req *http.Request) {
@@ -226,17 +1040,3 @@ func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 }
-
-// StatusToEmoji converts a BuildStatus to an emoji string.
-func StatusToEmoji(status BuildStatus) string {
-	switch status {
-	case StatusSuccess:
-		return "✅"
-	case StatusFailure:
-		return "❌"
-	case StatusPending:
-		return "🔄"
-	default:
-		return "⚠️"
-	}
-}
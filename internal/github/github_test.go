@@ -1,10 +1,14 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,14 +19,14 @@ type mockTokenGetter struct {
 	err   error
 }
 
-func (m *mockTokenGetter) GetToken() (string, error) {
+func (m *mockTokenGetter) GetToken(_ context.Context) (string, error) {
 	return m.token, m.err
 }
 
 func TestGetToken_Success(t *testing.T) {
 	// This tests the interface, actual gh CLI test would be integration test
 	getter := &mockTokenGetter{token: "test-token"}
-	token, err := getter.GetToken()
+	token, err := getter.GetToken(t.Context())
 	if err != nil {
 		t.Fatalf("GetToken() error = %v", err)
 	}
@@ -33,7 +37,7 @@ func TestGetToken_Success(t *testing.T) {
 
 func TestGetToken_NotLoggedIn(t *testing.T) {
 	getter := &mockTokenGetter{err: errors.New("gh auth token failed: not logged in")}
-	_, err := getter.GetToken()
+	_, err := getter.GetToken(t.Context())
 	if err == nil {
 		t.Error("GetToken() expected error")
 	}
@@ -41,12 +45,58 @@ func TestGetToken_NotLoggedIn(t *testing.T) {
 
 func TestGetToken_GhNotInstalled(t *testing.T) {
 	getter := &mockTokenGetter{err: errors.New("exec: \"gh\": executable file not found")}
-	_, err := getter.GetToken()
+	_, err := getter.GetToken(t.Context())
 	if err == nil {
 		t.Error("GetToken() expected error")
 	}
 }
 
+func TestEnvTokenGetter(t *testing.T) {
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "ghp_github")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghp_enterprise")
+
+	if token, _ := (&EnvTokenGetter{}).GetToken(t.Context()); token != "ghp_github" {
+		t.Errorf("GetToken() = %q, want GITHUB_TOKEN", token)
+	}
+	t.Setenv("GH_TOKEN", "ghp_gh")
+	if token, _ := (&EnvTokenGetter{}).GetToken(t.Context()); token != "ghp_gh" {
+		t.Errorf("GetToken() = %q, want GH_TOKEN to win", token)
+	}
+	if token, _ := (&EnvTokenGetter{Host: "github.mycorp.com"}).GetToken(t.Context()); token != "ghp_enterprise" {
+		t.Errorf("GetToken() for an enterprise host = %q, want GH_ENTERPRISE_TOKEN", token)
+	}
+}
+
+func TestCommandTokenGetter(t *testing.T) {
+	token, err := (&CommandTokenGetter{Command: "echo ghp_command"}).GetToken(t.Context())
+	if err != nil || token != "ghp_command" {
+		t.Errorf("GetToken() = %q, %v; want the command's output", token, err)
+	}
+	if _, err := (&CommandTokenGetter{Command: "exit 1"}).GetToken(t.Context()); err == nil {
+		t.Error("GetToken() of a failing command: error = nil")
+	}
+	if token, err := (&CommandTokenGetter{}).GetToken(t.Context()); token != "" || err != nil {
+		t.Errorf("GetToken() without a command = %q, %v; want no token", token, err)
+	}
+}
+
+func TestTokenChain(t *testing.T) {
+	chain := TokenChain{
+		&mockTokenGetter{},
+		&mockTokenGetter{err: errors.New("command failed")},
+		&mockTokenGetter{token: "from-gh"},
+	}
+	if token, err := chain.GetToken(t.Context()); err != nil || token != "from-gh" {
+		t.Errorf("GetToken() = %q, %v; want the first token found", token, err)
+	}
+
+	chain = TokenChain{&mockTokenGetter{err: errors.New("command failed")}, &mockTokenGetter{err: errors.New("gh failed")}}
+	if _, err := chain.GetToken(t.Context()); err == nil || !strings.Contains(err.Error(), "command failed") || !strings.Contains(err.Error(), "gh failed") {
+		t.Errorf("GetToken() error = %v, want every source's error", err)
+	}
+}
+
 func setupTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *Client) {
 	server := httptest.NewServer(handler)
 	t.Cleanup(server.Close)
@@ -91,7 +141,7 @@ func TestGetBuildStatus_Success(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -121,7 +171,7 @@ func TestGetBuildStatus_Failure(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -151,7 +201,7 @@ func TestGetBuildStatus_Pending(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -181,7 +231,7 @@ func TestGetBuildStatus_Queued(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -211,7 +261,7 @@ func TestGetBuildStatus_Cancelled(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -233,7 +283,7 @@ func TestGetBuildStatus_NoWorkflow(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	_, err := client.GetBuildStatus("owner", "repo", "main")
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected error for missing workflow")
 	}
@@ -258,20 +308,68 @@ func TestGetBuildStatus_NoRuns(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	_, err := client.GetBuildStatus("owner", "repo", "main")
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected error for no runs")
 	}
 }
 
 func TestGetBuildStatus_RateLimited(t *testing.T) {
+	reset := time.Now().Add(10 * time.Minute).Truncate(time.Second)
 	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
 		w.WriteHeader(http.StatusForbidden)
 	})
 
-	_, err := client.GetBuildStatus("owner", "repo", "main")
-	if err == nil {
-		t.Error("GetBuildStatus() expected error for rate limit")
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+	var rateLimit *RateLimitError
+	if !errors.As(err, &rateLimit) || !rateLimit.Reset.Equal(reset) {
+		t.Errorf("GetBuildStatus() error = %v, want a RateLimitError resetting at %v", err, reset)
+	}
+}
+
+func TestGetBuildStatus_Forbidden(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+	var rateLimit *RateLimitError
+	if err == nil || errors.As(err, &rateLimit) {
+		t.Errorf("GetBuildStatus() error = %v, want a plain 403 error", err)
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		status    int
+		headers   map[string]string
+		wantReset time.Time // zero: not a rate limit
+	}{
+		{"ok", http.StatusOK, nil, time.Time{}},
+		{"forbidden", http.StatusForbidden, nil, time.Time{}},
+		{"primary limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}, now.Add(time.Hour)},
+		{"secondary limit", http.StatusForbidden, map[string]string{"Retry-After": "30"}, now.Add(30 * time.Second)},
+		{"too many requests", http.StatusTooManyRequests, nil, now.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			err := checkRateLimit(resp, now)
+			var rateLimit *RateLimitError
+			switch {
+			case tt.wantReset.IsZero() && err != nil:
+				t.Errorf("checkRateLimit() = %v, want nil", err)
+			case !tt.wantReset.IsZero() && (!errors.As(err, &rateLimit) || !rateLimit.Reset.Equal(tt.wantReset)):
+				t.Errorf("checkRateLimit() = %v, want a reset at %v", err, tt.wantReset)
+			}
+		})
 	}
 }
 
@@ -280,7 +378,7 @@ func TestGetBuildStatus_NotFound(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	_, err := client.GetBuildStatus("owner", "repo", "main")
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected error for not found")
 	}
@@ -298,7 +396,7 @@ func TestGetBuildStatus_Timeout(t *testing.T) {
 	}
 	client.SetBaseURL(server.URL)
 
-	_, err = client.GetBuildStatus("owner", "repo", "main")
+	_, err = client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected timeout error")
 	}
@@ -312,29 +410,650 @@ func TestGetBuildStatus_NetworkError(t *testing.T) {
 	}
 	client.SetBaseURL("http://127.0.0.1:1") // Port 1 should fail
 
-	_, err = client.GetBuildStatus("owner", "repo", "main")
+	_, err = client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected network error")
 	}
 }
 
-func TestStatusToEmoji(t *testing.T) {
+func TestGetFailedJob(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/workflows":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflows": []map[string]interface{}{
+					{"id": 123, "name": "build_and_test", "path": ".github/workflows/build_and_test.yml"},
+				},
+			})
+		case "/repos/owner/repo/actions/workflows/123/runs":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflow_runs": []map[string]interface{}{
+					{"id": 42, "status": "completed", "conclusion": "failure", "html_url": "https://github.com/owner/repo/actions/runs/42"},
+				},
+			})
+		case "/repos/owner/repo/actions/runs/42/jobs":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jobs": []map[string]interface{}{
+					{"name": "lint", "conclusion": "success"},
+					{"name": "unit-tests", "conclusion": "failure"},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	job, err := client.GetFailedJob(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetFailedJob() error = %v", err)
+	}
+	if job.Name != "unit-tests" {
+		t.Errorf("GetFailedJob().Name = %q, want %q", job.Name, "unit-tests")
+	}
+	if job.RunURL != "https://github.com/owner/repo/actions/runs/42" {
+		t.Errorf("GetFailedJob().RunURL = %q, want run URL", job.RunURL)
+	}
+}
+
+func TestGetFailedJob_RunSucceeded(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/workflows":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflows": []map[string]interface{}{
+					{"id": 123, "name": "build_and_test", "path": ".github/workflows/build_and_test.yml"},
+				},
+			})
+		case "/repos/owner/repo/actions/workflows/123/runs":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflow_runs": []map[string]interface{}{
+					{"id": 42, "status": "completed", "conclusion": "success"},
+				},
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	job, err := client.GetFailedJob(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetFailedJob() error = %v", err)
+	}
+	if job != (FailedJob{}) {
+		t.Errorf("GetFailedJob() = %+v, want zero value", job)
+	}
+}
+
+func TestGetChecksSummary(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/pulls":
+			if got := r.URL.Query().Get("head"); got != "owner:feature" {
+				t.Errorf("head query = %q, want %q", got, "owner:feature")
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"head": map[string]interface{}{"sha": "abc123"}},
+			})
+		case "/repos/owner/repo/commits/abc123/check-runs":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"check_runs": []map[string]interface{}{
+					{"status": "completed", "conclusion": "success"},
+					{"status": "completed", "conclusion": "skipped"},
+					{"status": "completed", "conclusion": "failure"},
+					{"status": "in_progress"},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	summary, err := client.GetChecksSummary(t.Context(), "owner", "repo", "feature")
+	if err != nil {
+		t.Fatalf("GetChecksSummary() error = %v", err)
+	}
+	want := ChecksSummary{Passed: 2, Failed: 1, Pending: 1, Total: 4}
+	if summary != want {
+		t.Errorf("GetChecksSummary() = %+v, want %+v", summary, want)
+	}
+}
+
+func TestGetChecksSummary_NoPR(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/owner/repo/pulls" {
+			w.Write([]byte("[]"))
+			return
+		}
+		t.Errorf("unexpected request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	summary, err := client.GetChecksSummary(t.Context(), "owner", "repo", "feature")
+	if err != nil {
+		t.Fatalf("GetChecksSummary() error = %v", err)
+	}
+	if summary != (ChecksSummary{}) {
+		t.Errorf("GetChecksSummary() = %+v, want zero value", summary)
+	}
+}
+
+func TestGetPullRequest(t *testing.T) {
 	tests := []struct {
-		status BuildStatus
-		want   string
+		name    string
+		reviews []map[string]interface{}
+		want    string
 	}{
-		{StatusSuccess, "✅"},
-		{StatusFailure, "❌"},
-		{StatusPending, "🔄"},
-		{StatusError, "⚠️"},
-		{BuildStatus("unknown"), "⚠️"},
+		{"no reviews", nil, "review_required"},
+		{"approved", []map[string]interface{}{
+			{"user": map[string]interface{}{"login": "alice"}, "state": "COMMENTED"},
+			{"user": map[string]interface{}{"login": "alice"}, "state": "APPROVED"},
+		}, "approved"},
+		{"changes requested wins", []map[string]interface{}{
+			{"user": map[string]interface{}{"login": "alice"}, "state": "APPROVED"},
+			{"user": map[string]interface{}{"login": "bob"}, "state": "CHANGES_REQUESTED"},
+		}, "changes_requested"},
+		{"latest verdict per reviewer", []map[string]interface{}{
+			{"user": map[string]interface{}{"login": "bob"}, "state": "CHANGES_REQUESTED"},
+			{"user": map[string]interface{}{"login": "bob"}, "state": "APPROVED"},
+		}, "approved"},
+		{"dismissed approval", []map[string]interface{}{
+			{"user": map[string]interface{}{"login": "alice"}, "state": "APPROVED"},
+			{"user": map[string]interface{}{"login": "alice"}, "state": "DISMISSED"},
+		}, "review_required"},
 	}
 
 	for _, tt := range tests {
-		t.Run(string(tt.status), func(t *testing.T) {
-			got := StatusToEmoji(tt.status)
-			if got != tt.want {
-				t.Errorf("StatusToEmoji(%q) = %q, want %q", tt.status, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/owner/repo/pulls":
+					json.NewEncoder(w).Encode([]map[string]interface{}{
+						{"number": 123, "html_url": "https://github.com/owner/repo/pull/123", "draft": true},
+					})
+				case "/repos/owner/repo/pulls/123":
+					json.NewEncoder(w).Encode(map[string]interface{}{"mergeable_state": "dirty"})
+				case "/repos/owner/repo/pulls/123/reviews":
+					if tt.reviews == nil {
+						w.Write([]byte("[]"))
+						return
+					}
+					json.NewEncoder(w).Encode(tt.reviews)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			pr, err := client.GetPullRequest(t.Context(), "owner", "repo", "feature")
+			if err != nil {
+				t.Fatalf("GetPullRequest() error = %v", err)
+			}
+			want := PullRequest{
+				Number:      123,
+				URL:         "https://github.com/owner/repo/pull/123",
+				State:       "draft",
+				ReviewState: tt.want,
+				Mergeable:   "dirty",
+			}
+			if pr != want {
+				t.Errorf("GetPullRequest() = %+v, want %+v", pr, want)
+			}
+		})
+	}
+}
+
+func TestGetPullRequest_NoPR(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/owner/repo/pulls" {
+			w.Write([]byte("[]"))
+			return
+		}
+		t.Errorf("unexpected request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	pr, err := client.GetPullRequest(t.Context(), "owner", "repo", "feature")
+	if err != nil {
+		t.Fatalf("GetPullRequest() error = %v", err)
+	}
+	if pr != (PullRequest{}) {
+		t.Errorf("GetPullRequest() = %+v, want zero value", pr)
+	}
+}
+
+func TestGetLatestRunAndRerun(t *testing.T) {
+	rerunCalled := false
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/workflows":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflows": []map[string]interface{}{
+					{"id": 123, "name": "build_and_test", "path": ".github/workflows/build_and_test.yml"},
+				},
+			})
+		case "/repos/owner/repo/actions/workflows/123/runs":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflow_runs": []map[string]interface{}{
+					{"id": 42, "status": "completed", "conclusion": "failure", "html_url": "https://github.com/owner/repo/actions/runs/42"},
+				},
+			})
+		case "/repos/owner/repo/actions/runs/42/rerun-failed-jobs":
+			if r.Method != http.MethodPost {
+				t.Errorf("method = %s, want POST", r.Method)
+			}
+			rerunCalled = true
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	run, err := client.GetLatestRun(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetLatestRun() error = %v", err)
+	}
+	if run.ID != 42 || run.Status != StatusFailure {
+		t.Errorf("GetLatestRun() = %+v, want ID 42 with failure status", run)
+	}
+
+	if err := client.RerunFailedJobs(t.Context(), "owner", "repo", run.ID); err != nil {
+		t.Fatalf("RerunFailedJobs() error = %v", err)
+	}
+	if !rerunCalled {
+		t.Error("RerunFailedJobs() did not hit the rerun endpoint")
+	}
+}
+
+func TestRerunFailedJobs_Forbidden(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	if err := client.RerunFailedJobs(t.Context(), "owner", "repo", 42); err == nil {
+		t.Error("RerunFailedJobs() expected error on 403")
+	}
+}
+
+func TestGetBuildStatus_CommitStatusContext(t *testing.T) {
+	tests := []struct {
+		state string
+		want  BuildStatus
+	}{
+		{"success", StatusSuccess},
+		{"failure", StatusFailure},
+		{"error", StatusFailure},
+		{"pending", StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/owner/repo/commits/main/status" {
+					t.Errorf("unexpected request to %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"statuses": []map[string]interface{}{
+						{"context": "other", "state": "failure"},
+						{"context": "ci/jenkins", "state": tt.state},
+					},
+				})
+			})
+			client.SetStatusContext("ci/jenkins")
+
+			status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+			if err != nil {
+				t.Fatalf("GetBuildStatus() error = %v", err)
+			}
+			if status != tt.want {
+				t.Errorf("GetBuildStatus() = %q, want %q", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBuildStatus_CommitStatusContextMissing(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"statuses": []map[string]interface{}{
+				{"context": "other", "state": "success"},
+			},
+		})
+	})
+	client.SetStatusContext("ci/jenkins")
+
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+	if err == nil {
+		t.Error("GetBuildStatus() expected error for missing context")
+	}
+	if status != StatusError {
+		t.Errorf("GetBuildStatus() = %q, want %q", status, StatusError)
+	}
+}
+
+func TestGetBuildStatus_Aggregate(t *testing.T) {
+	type run = map[string]interface{}
+	tests := []struct {
+		name      string
+		checkRuns []run
+		state     string
+		statuses  int
+		want      BuildStatus
+		wantErr   bool
+	}{
+		{
+			name:      "all passing",
+			checkRuns: []run{{"status": "completed", "conclusion": "success"}, {"status": "completed", "conclusion": "skipped"}},
+			state:     "pending",
+			want:      StatusSuccess,
+		},
+		{
+			name:      "one check failed",
+			checkRuns: []run{{"status": "completed", "conclusion": "success"}, {"status": "completed", "conclusion": "failure"}},
+			state:     "pending",
+			want:      StatusFailure,
+		},
+		{
+			name:      "failure beats pending",
+			checkRuns: []run{{"status": "in_progress"}, {"status": "completed", "conclusion": "timed_out"}},
+			state:     "pending",
+			want:      StatusFailure,
+		},
+		{
+			name:      "check still running",
+			checkRuns: []run{{"status": "queued"}, {"status": "completed", "conclusion": "success"}},
+			state:     "pending",
+			want:      StatusPending,
+		},
+		{
+			name:      "commit status failed",
+			checkRuns: []run{{"status": "completed", "conclusion": "success"}},
+			state:     "failure",
+			statuses:  1,
+			want:      StatusFailure,
+		},
+		{
+			name:     "only commit statuses",
+			state:    "success",
+			statuses: 2,
+			want:     StatusSuccess,
+		},
+		{
+			name:    "no checks at all",
+			state:   "pending",
+			want:    StatusError,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/owner/repo/commits/main/check-runs":
+					json.NewEncoder(w).Encode(map[string]interface{}{"check_runs": tt.checkRuns})
+				case "/repos/owner/repo/commits/main/status":
+					json.NewEncoder(w).Encode(map[string]interface{}{"state": tt.state, "total_count": tt.statuses})
+				default:
+					t.Errorf("unexpected request to %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+			client.SetAggregate(true)
+
+			status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetBuildStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status != tt.want {
+				t.Errorf("GetBuildStatus() = %q, want %q", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFailedJob_Aggregate(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/commits/main/check-runs" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"check_runs": []map[string]interface{}{
+				{"name": "lint", "status": "completed", "conclusion": "success"},
+				{"name": "e2e", "status": "completed", "conclusion": "failure", "html_url": "https://github.com/owner/repo/runs/9"},
+			},
+		})
+	})
+	client.SetAggregate(true)
+
+	job, err := client.GetFailedJob(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetFailedJob() error = %v", err)
+	}
+	if job.Name != "e2e" || job.RunURL != "https://github.com/owner/repo/runs/9" {
+		t.Errorf("GetFailedJob() = %+v, want e2e with its check URL", job)
+	}
+}
+
+func TestGetBuildStatus_PathFilter(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/workflows":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflows": []map[string]interface{}{
+					{"id": 123, "name": "build_and_test", "path": ".github/workflows/build_and_test.yml"},
+				},
+			})
+		case "/repos/owner/repo/actions/workflows/123/runs":
+			if got := r.URL.Query().Get("per_page"); got != "10" {
+				t.Errorf("per_page = %q, want %q", got, "10")
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflow_runs": []map[string]interface{}{
+					{"id": 2, "head_sha": "newer", "status": "completed", "conclusion": "failure"},
+					{"id": 1, "head_sha": "older", "status": "completed", "conclusion": "success"},
+				},
+			})
+		case "/repos/owner/repo/commits/newer":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"files": []map[string]interface{}{{"filename": "services/web/main.go"}},
+			})
+		case "/repos/owner/repo/commits/older":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"files": []map[string]interface{}{{"filename": "services/api/handler.go"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	client.SetPathFilter("/services/api/")
+
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetBuildStatus() error = %v", err)
+	}
+	// The newer failing run only touched services/web, so the older run wins
+	if status != StatusSuccess {
+		t.Errorf("GetBuildStatus() = %q, want %q", status, StatusSuccess)
+	}
+}
+
+// multiWorkflowHandler serves three workflows: test passes, lint fails (run
+// 7), and deploy has never run.
+func multiWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/repos/owner/repo/actions/workflows":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"workflows": []map[string]interface{}{
+				{"id": 1, "name": "test", "path": ".github/workflows/test.yml"},
+				{"id": 2, "name": "lint", "path": ".github/workflows/lint.yml"},
+				{"id": 3, "name": "deploy", "path": ".github/workflows/deploy.yml"},
+			},
+		})
+	case "/repos/owner/repo/actions/workflows/1/runs":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"workflow_runs": []map[string]interface{}{{"id": 6, "status": "completed", "conclusion": "success"}},
+		})
+	case "/repos/owner/repo/actions/workflows/2/runs":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"workflow_runs": []map[string]interface{}{{"id": 7, "status": "completed", "conclusion": "failure", "html_url": "https://github.com/owner/repo/actions/runs/7"}},
+		})
+	case "/repos/owner/repo/actions/workflows/3/runs":
+		json.NewEncoder(w).Encode(map[string]interface{}{"workflow_runs": []map[string]interface{}{}})
+	case "/repos/owner/repo/actions/runs/7/jobs":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs": []map[string]interface{}{{"name": "golangci", "conclusion": "failure"}},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGetBuildStatus_Workflows(t *testing.T) {
+	_, client := setupTestServer(t, multiWorkflowHandler)
+
+	tests := []struct {
+		workflows []string
+		want      BuildStatus
+	}{
+		{[]string{"test"}, StatusSuccess},
+		{[]string{"test", "lint"}, StatusFailure},
+		{[]string{"test", "deploy", "missing"}, StatusSuccess}, // Workflows without runs don't count
+	}
+	for _, tt := range tests {
+		client.SetWorkflows(tt.workflows)
+		status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
+		if err != nil || status != tt.want {
+			t.Errorf("GetBuildStatus(%v) = %q, %v; want %q", tt.workflows, status, err, tt.want)
+		}
+	}
+
+	client.SetWorkflows([]string{"deploy", "missing"})
+	if _, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main"); err == nil {
+		t.Error("GetBuildStatus() without any runs: error = nil, want one")
+	}
+}
+
+func TestGetWorkflowStatuses(t *testing.T) {
+	_, client := setupTestServer(t, multiWorkflowHandler)
+	client.SetWorkflows([]string{"lint", "test", "deploy"})
+
+	got, err := client.GetWorkflowStatuses(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetWorkflowStatuses() error = %v", err)
+	}
+	want := []WorkflowStatus{
+		{Name: "lint", Status: StatusFailure},
+		{Name: "test", Status: StatusSuccess},
+		{Name: "deploy", Status: StatusError},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetWorkflowStatuses() = %+v, want %+v", got, want)
+	}
+
+	client.SetAggregate(true)
+	if got, err := client.GetWorkflowStatuses(t.Context(), "owner", "repo", "main"); got != nil || err != nil {
+		t.Errorf("GetWorkflowStatuses() with aggregate = %+v, %v; want nil", got, err)
+	}
+}
+
+func TestGetFailedJob_Workflows(t *testing.T) {
+	_, client := setupTestServer(t, multiWorkflowHandler)
+	client.SetWorkflows([]string{"test", "lint"})
+
+	job, err := client.GetFailedJob(t.Context(), "owner", "repo", "main")
+	if err != nil {
+		t.Fatalf("GetFailedJob() error = %v", err)
+	}
+	if job.Name != "golangci" || job.RunURL != "https://github.com/owner/repo/actions/runs/7" {
+		t.Errorf("GetFailedJob() = %+v, want the failing lint job", job)
+	}
+
+	run, err := client.GetLatestRun(t.Context(), "owner", "repo", "main")
+	if err != nil || run.ID != 7 {
+		t.Errorf("GetLatestRun() = %+v, %v; want the failed lint run", run, err)
+	}
+}
+
+func TestGetDefaultBranchStatus(t *testing.T) {
+	_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo":
+			json.NewEncoder(w).Encode(map[string]interface{}{"default_branch": "trunk"})
+		case "/repos/owner/repo/actions/workflows":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflows": []map[string]interface{}{
+					{"id": 123, "name": "build_and_test", "path": ".github/workflows/build_and_test.yml"},
+				},
+			})
+		case "/repos/owner/repo/actions/workflows/123/runs":
+			if got := r.URL.Query().Get("branch"); got != "trunk" {
+				t.Errorf("branch query = %q, want %q", got, "trunk")
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"workflow_runs": []map[string]interface{}{
+					{"status": "completed", "conclusion": "failure"},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	result, err := client.GetDefaultBranchStatus(t.Context(), "owner", "repo")
+	if err != nil {
+		t.Fatalf("GetDefaultBranchStatus() error = %v", err)
+	}
+	want := BranchStatus{Branch: "trunk", Status: StatusFailure}
+	if result != want {
+		t.Errorf("GetDefaultBranchStatus() = %+v, want %+v", result, want)
+	}
+}
+
+func TestGetActionsUsage(t *testing.T) {
+	tests := []struct {
+		name        string
+		private     bool
+		ownerType   string
+		billingPath string
+		want        ActionsUsage
+	}{
+		{"public repo", false, "User", "", ActionsUsage{}},
+		{"private user repo", true, "User", "/users/owner/settings/billing/actions",
+			ActionsUsage{MinutesUsed: 1500, MinutesIncluded: 2000, MinutesRemaining: 500}},
+		{"private org repo", true, "Organization", "/orgs/owner/settings/billing/actions",
+			ActionsUsage{MinutesUsed: 1500, MinutesIncluded: 2000, MinutesRemaining: 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/owner/repo":
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"private": tt.private,
+						"owner":   map[string]interface{}{"login": "owner", "type": tt.ownerType},
+					})
+				case tt.billingPath:
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"total_minutes_used": 1500,
+						"included_minutes":   2000,
+					})
+				default:
+					t.Errorf("unexpected request to %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			usage, err := client.GetActionsUsage(t.Context(), "owner", "repo")
+			if err != nil {
+				t.Fatalf("GetActionsUsage() error = %v", err)
+			}
+			if usage != tt.want {
+				t.Errorf("GetActionsUsage() = %+v, want %+v", usage, tt.want)
 			}
 		})
 	}
@@ -344,7 +1063,7 @@ func TestNewClientWithDeps(t *testing.T) {
 	tokenGetter := &mockTokenGetter{token: "test-token"}
 	httpClient := &http.Client{Timeout: 5 * time.Second}
 
-	client, err := NewClientWithDeps("build_and_test", httpClient, tokenGetter)
+	client, err := NewClientWithDeps(t.Context(), "build_and_test", httpClient, tokenGetter)
 	if err != nil {
 		t.Fatalf("NewClientWithDeps() error = %v", err)
 	}
@@ -353,11 +1072,45 @@ func TestNewClientWithDeps(t *testing.T) {
 	}
 }
 
+func TestAPIURL(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"", "https://api.github.com"},
+		{"github.com", "https://api.github.com"},
+		{"github.mycorp.com", "https://github.mycorp.com/api/v3"},
+	}
+
+	for _, tt := range tests {
+		if got := APIURL(tt.host); got != tt.want {
+			t.Errorf("APIURL(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSetBaseURL_Enterprise(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/owner/repo/actions/workflows" {
+			t.Errorf("request path = %q, want it under /api/v3", r.URL.Path)
+		}
+		w.Write([]byte(`{"workflows": []}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithToken("build_and_test", "token", server.Client())
+	client.SetBaseURL(server.URL + "/api/v3/")
+
+	if _, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main"); err == nil {
+		t.Error("GetBuildStatus() expected an error for a missing workflow")
+	}
+}
+
 func TestNewClientWithDeps_TokenError(t *testing.T) {
 	tokenGetter := &mockTokenGetter{err: errors.New("no token")}
 	httpClient := &http.Client{Timeout: 5 * time.Second}
 
-	_, err := NewClientWithDeps("build_and_test", httpClient, tokenGetter)
+	_, err := NewClientWithDeps(t.Context(), "build_and_test", httpClient, tokenGetter)
 	if err == nil {
 		t.Error("NewClientWithDeps() expected error when token getter fails")
 	}
@@ -367,7 +1120,7 @@ func TestNewClientWithDeps_EmptyToken(t *testing.T) {
 	tokenGetter := &mockTokenGetter{token: ""}
 	httpClient := &http.Client{Timeout: 5 * time.Second}
 
-	_, err := NewClientWithDeps("build_and_test", httpClient, tokenGetter)
+	_, err := NewClientWithDeps(t.Context(), "build_and_test", httpClient, tokenGetter)
 	if err == nil {
 		t.Error("NewClientWithDeps() expected error for empty token")
 	}
@@ -397,7 +1150,7 @@ func TestWorkflowFoundByPath(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -427,7 +1180,7 @@ func TestWorkflowFoundByYamlPath(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -445,7 +1198,7 @@ func TestGetBuildStatus_MalformedWorkflowsJSON(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	_, err := client.GetBuildStatus("owner", "repo", "main")
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected error for malformed workflows JSON")
 	}
@@ -468,7 +1221,7 @@ func TestGetBuildStatus_MalformedRunsJSON(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	_, err := client.GetBuildStatus("owner", "repo", "main")
+	_, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err == nil {
 		t.Error("GetBuildStatus() expected error for malformed runs JSON")
 	}
@@ -495,7 +1248,7 @@ func TestGetBuildStatus_Waiting(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
@@ -525,7 +1278,7 @@ func TestGetBuildStatus_TimedOut(t *testing.T) {
 		w.WriteHeader(http.StatusNotFound)
 	})
 
-	status, err := client.GetBuildStatus("owner", "repo", "main")
+	status, err := client.GetBuildStatus(t.Context(), "owner", "repo", "main")
 	if err != nil {
 		t.Fatalf("GetBuildStatus() error = %v", err)
 	}
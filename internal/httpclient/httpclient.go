@@ -0,0 +1,77 @@
+// Package httpclient builds the HTTP clients used for CI APIs, honoring
+// proxy environment variables and an optional custom CA bundle so the
+// status line keeps working behind corporate proxies.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrCABundle is returned by New when the configured CA bundle is unusable.
+var ErrCABundle = errors.New("invalid CA bundle")
+
+// Timeout bounds each API request; the status line can't wait longer.
+const Timeout = 5 * time.Second
+
+// New returns an HTTP client that routes through HTTPS_PROXY/HTTP_PROXY
+// (respecting NO_PROXY) and, when caBundle is set, trusts the PEM
+// certificates in that file in addition to the system roots.
+func New(caBundle string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if caBundle != "" {
+		pool, err := loadCABundle(caBundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{Timeout: Timeout, Transport: transport}, nil
+}
+
+// loadCABundle returns the system cert pool extended with the certificates in path.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCABundle, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%w: no PEM certificates in %s", ErrCABundle, path)
+	}
+	return pool, nil
+}
+
+// Explain annotates connection errors caused by proxies or TLS interception
+// with the setting that usually fixes them. Other errors are returned as is.
+func Explain(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var tlsErr *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) || errors.As(err, &tlsErr) {
+		return fmt.Errorf("%w (behind a TLS-intercepting proxy? set ca_bundle in config)", err)
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return fmt.Errorf("%w (check HTTPS_PROXY and NO_PROXY)", err)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeServerCA writes the test server's certificate as a PEM bundle.
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNew_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Without the bundle the self-signed server is rejected, with a hint
+	client, err := New("")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_, err = client.Get(server.URL)
+	if err == nil {
+		t.Fatal("Get() should fail for an untrusted certificate")
+	}
+	if err := Explain(err); !strings.Contains(err.Error(), "ca_bundle") {
+		t.Errorf("Explain() = %v, want a ca_bundle hint", err)
+	}
+
+	client, err = New(writeServerCA(t, server))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() with CA bundle error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNew_InvalidCABundle(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{notPEM, filepath.Join(t.TempDir(), "missing.pem")} {
+		if _, err := New(path); !errors.Is(err, ErrCABundle) {
+			t.Errorf("New(%q) error = %v, want ErrCABundle", path, err)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	proxyErr := &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("connection refused")}
+	if err := Explain(proxyErr); !strings.Contains(err.Error(), "HTTPS_PROXY") || !errors.Is(err, proxyErr) {
+		t.Errorf("Explain(proxy error) = %v, want a wrapped HTTPS_PROXY hint", err)
+	}
+
+	other := errors.New("boom")
+	if err := Explain(other); err != other {
+		t.Errorf("Explain(other) = %v, want it unchanged", err)
+	}
+	if Explain(nil) != nil {
+		t.Error("Explain(nil) should be nil")
+	}
+}
@@ -0,0 +1,122 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// binaryName is the file name statusLine and hook commands must run.
+const binaryName = "claude-status"
+
+// CheckDrift reports whether the claude-status commands in the settings at
+// settingsPath have gone stale, e.g. after the binary was moved or upgraded.
+// It returns a short warning, or "" if the statusLine command runs binaryPath
+// and every claude-status hook points at an executable. Settings that don't
+// run claude-status directly (such as a wrapper script) are not checked.
+func CheckDrift(settingsPath, binaryPath string) (string, error) {
+	settings, err := ReadSettings(settingsPath)
+	if err != nil {
+		return "", err
+	}
+
+	statusLine, _ := settings["statusLine"].(map[string]any)
+	command, _ := statusLine["command"].(string)
+	if bin, ok := commandBinary(command); ok {
+		if problem := checkExecutable(bin); problem != "" {
+			return "statusLine " + problem, nil
+		}
+		if !sameFile(bin, binaryPath) {
+			return fmt.Sprintf("statusLine runs %s, not %s", bin, binaryPath), nil
+		}
+	}
+
+	for _, command := range ownHookCommands(settings) {
+		bin, _ := strings.CutSuffix(command, " hook")
+		if problem := checkExecutable(expandHome(bin)); problem != "" {
+			return "hook " + problem, nil
+		}
+	}
+	return "", nil
+}
+
+// commandBinary extracts the claude-status binary from a statusLine command,
+// dropping any flags after it. The path itself may contain spaces, since
+// install writes it unquoted.
+func commandBinary(command string) (string, bool) {
+	i := strings.LastIndex(command, binaryName)
+	if i < 0 {
+		return "", false
+	}
+	bin := expandHome(command[:i+len(binaryName)])
+	if filepath.Base(bin) != binaryName {
+		return "", false
+	}
+	if !strings.ContainsRune(bin, filepath.Separator) {
+		// Bare name: the shell finds it on PATH
+		if path, err := exec.LookPath(bin); err == nil {
+			return path, true
+		}
+	}
+	return bin, true
+}
+
+// ownHookCommands returns the claude-status hook commands in settings.
+func ownHookCommands(settings map[string]any) []string {
+	var commands []string
+	hooks, _ := settings["hooks"].(map[string]any)
+	for _, event := range HookEvents {
+		groups, _ := hooks[event].([]any)
+		for _, group := range groups {
+			g, _ := group.(map[string]any)
+			entries, _ := g["hooks"].([]any)
+			for _, entry := range entries {
+				e, _ := entry.(map[string]any)
+				if cmd, _ := e["command"].(string); isOwnHook(cmd) {
+					commands = append(commands, cmd)
+				}
+			}
+		}
+	}
+	return commands
+}
+
+// checkExecutable describes why path can't be run, or returns "" if it can.
+func checkExecutable(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "binary " + path + " is missing"
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return "binary " + path + " is not executable"
+	}
+	return ""
+}
+
+// sameFile reports whether a and b name the same file, following symlinks.
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// expandHome expands a leading "~/" as the shell running the command would.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
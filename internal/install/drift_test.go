@@ -0,0 +1,105 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBinary creates an executable claude-status in a new directory.
+func writeBinary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), binaryName)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0755))
+	return path
+}
+
+// writeInstalledSettings writes settings as install would for binaryPath.
+func writeInstalledSettings(t *testing.T, binaryPath string, hooks bool) string {
+	t.Helper()
+	data, err := UpdateSettings(nil, binaryPath)
+	require.NoError(t, err)
+	if hooks {
+		data, err = UpdateHooks(data, binaryPath)
+		require.NoError(t, err)
+	}
+	path := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestCheckDrift_UpToDate(t *testing.T) {
+	bin := writeBinary(t)
+	settingsPath := writeInstalledSettings(t, bin, true)
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Empty(t, warning)
+}
+
+func TestCheckDrift_ThroughSymlink(t *testing.T) {
+	bin := writeBinary(t)
+	link := filepath.Join(t.TempDir(), binaryName)
+	require.NoError(t, os.Symlink(bin, link))
+	settingsPath := writeInstalledSettings(t, link, false)
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Empty(t, warning)
+}
+
+func TestCheckDrift_MovedBinary(t *testing.T) {
+	bin := writeBinary(t)
+	settingsPath := writeInstalledSettings(t, "/old/location/claude-status", false)
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Equal(t, "statusLine binary /old/location/claude-status is missing", warning)
+}
+
+func TestCheckDrift_OtherBinary(t *testing.T) {
+	bin := writeBinary(t)
+	other := writeBinary(t)
+	settingsPath := writeInstalledSettings(t, other+" -prefix dev", false)
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Equal(t, "statusLine runs "+other+", not "+bin, warning)
+}
+
+func TestCheckDrift_NotExecutable(t *testing.T) {
+	bin := writeBinary(t)
+	settingsPath := writeInstalledSettings(t, bin, false)
+	require.NoError(t, os.Chmod(bin, 0644))
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Equal(t, "statusLine binary "+bin+" is not executable", warning)
+}
+
+func TestCheckDrift_StaleHook(t *testing.T) {
+	bin := writeBinary(t)
+	settingsPath := writeInstalledSettings(t, "/old/location/claude-status", true)
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	data, err = UpdateSettings(data, bin)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(settingsPath, data, 0644))
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Equal(t, "hook binary /old/location/claude-status is missing", warning)
+}
+
+func TestCheckDrift_NotInstalled(t *testing.T) {
+	bin := writeBinary(t)
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(`{"statusLine": {"type": "command", "command": "~/bin/my-wrapper.sh"}}`), 0644))
+
+	warning, err := CheckDrift(settingsPath, bin)
+	require.NoError(t, err)
+	assert.Empty(t, warning)
+}
@@ -0,0 +1,181 @@
+package install
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// HookEvents are the Claude Code hook events claude-status registers for.
+// Both run "claude-status hook", which refreshes cached git, CI, and task
+// data so the next status line render is fast and current.
+var HookEvents = []string{"SessionStart", "Stop"}
+
+// hookCommand is the command registered for each hook event.
+func hookCommand(binaryPath string) string {
+	return binaryPath + " hook"
+}
+
+// isOwnHook reports whether command is a claude-status hook, possibly from
+// an earlier install at a different path.
+func isOwnHook(command string) bool {
+	bin, ok := strings.CutSuffix(command, " hook")
+	return ok && filepath.Base(bin) == "claude-status"
+}
+
+// UpdateHooks returns the settings file contents with a claude-status hook
+// registered for each of HookEvents. Existing hooks are kept; a claude-status
+// hook from an earlier install is repointed rather than duplicated. Only the
+// arrays of HookEvents are rewritten, so the rest of the file, including
+// other events, is preserved.
+func UpdateHooks(data []byte, binaryPath string) ([]byte, error) {
+	settings := make(map[string]any)
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(stripJSONC(data), &settings); err != nil {
+			return nil, fmt.Errorf("invalid JSON in settings file: %w", err)
+		}
+	}
+
+	hooks, ok := settings["hooks"].(map[string]any)
+	if !ok {
+		if _, exists := settings["hooks"]; exists {
+			return nil, fmt.Errorf(`"hooks" in settings file is not an object`)
+		}
+		hooks = make(map[string]any)
+	}
+
+	command := hookCommand(binaryPath)
+	for _, event := range HookEvents {
+		groups, _ := hooks[event].([]any)
+		if !setOwnHook(groups, command) {
+			groups = append(groups, map[string]any{
+				"hooks": []any{map[string]any{"type": "command", "command": command}},
+			})
+		}
+		hooks[event] = groups
+	}
+
+	if _, exists := settings["hooks"]; !exists {
+		return setTopLevelKey(data, "hooks", hooks)
+	}
+	for _, event := range HookEvents {
+		var err error
+		if data, err = editHooks(data, func(data []byte, open int, outer string) ([]byte, error) {
+			return setObjectKey(data, open, outer, event, hooks[event])
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// editHooks applies edit to the "hooks" object of the settings file
+// contents, at its offset and with the indent of its line.
+func editHooks(data []byte, edit func(data []byte, open int, outer string) ([]byte, error)) ([]byte, error) {
+	m, found, err := topLevelMember(data, "hooks")
+	if err != nil {
+		return nil, err
+	}
+	if !found || data[m.start] != '{' {
+		return nil, fmt.Errorf(`"hooks" in settings file is not an object`)
+	}
+	return edit(data, m.start, lineIndent(data, m.keyStart))
+}
+
+// setOwnHook points any claude-status hook in groups at command. Returns
+// false if groups has no claude-status hook.
+func setOwnHook(groups []any, command string) bool {
+	found := false
+	for _, group := range groups {
+		g, _ := group.(map[string]any)
+		entries, _ := g["hooks"].([]any)
+		for _, entry := range entries {
+			e, _ := entry.(map[string]any)
+			if cmd, _ := e["command"].(string); cmd == command || isOwnHook(cmd) {
+				e["command"] = command
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// RemoveHooks returns the settings file contents without claude-status hooks.
+// Groups and events left empty are dropped, and so is "hooks" itself if
+// nothing else is registered. Only the arrays of events that had one are
+// rewritten; other hooks are kept as they are.
+func RemoveHooks(data []byte) ([]byte, error) {
+	settings := make(map[string]any)
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(stripJSONC(data), &settings); err != nil {
+			return nil, fmt.Errorf("invalid JSON in settings file: %w", err)
+		}
+	}
+	hooks, ok := settings["hooks"].(map[string]any)
+	if !ok || len(ownHookCommands(settings)) == 0 {
+		return data, nil
+	}
+
+	var changed []string
+	for event, value := range hooks {
+		groups, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		removed := false
+		var kept []any
+		for _, group := range groups {
+			g, ok := group.(map[string]any)
+			entries, isList := g["hooks"].([]any)
+			if !ok || !isList {
+				kept = append(kept, group)
+				continue
+			}
+			var others []any
+			for _, entry := range entries {
+				e, _ := entry.(map[string]any)
+				if cmd, _ := e["command"].(string); !isOwnHook(cmd) {
+					others = append(others, entry)
+				} else {
+					removed = true
+				}
+			}
+			if len(others) > 0 {
+				g["hooks"] = others
+				kept = append(kept, g)
+			}
+		}
+		if !removed {
+			continue
+		}
+		changed = append(changed, event)
+		if len(kept) > 0 {
+			hooks[event] = kept
+		} else {
+			delete(hooks, event)
+		}
+	}
+
+	if len(hooks) == 0 {
+		out, _, err := removeTopLevelKey(data, "hooks")
+		return out, err
+	}
+	slices.Sort(changed)
+	for _, event := range changed {
+		var err error
+		data, err = editHooks(data, func(data []byte, open int, outer string) ([]byte, error) {
+			if kept, ok := hooks[event]; ok {
+				return setObjectKey(data, open, outer, event, kept)
+			}
+			out, _, err := removeObjectKey(data, open, event)
+			return out, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,154 @@
+package install
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookCommands returns the commands registered for event.
+func hookCommands(t *testing.T, data []byte, event string) []string {
+	t.Helper()
+	var settings struct {
+		Hooks map[string][]struct {
+			Matcher string `json:"matcher"`
+			Hooks   []struct {
+				Type    string `json:"type"`
+				Command string `json:"command"`
+			} `json:"hooks"`
+		} `json:"hooks"`
+	}
+	require.NoError(t, json.Unmarshal(stripJSONC(data), &settings))
+
+	var commands []string
+	for _, group := range settings.Hooks[event] {
+		for _, h := range group.Hooks {
+			commands = append(commands, h.Command)
+		}
+	}
+	return commands
+}
+
+func TestUpdateHooks_NewFile(t *testing.T) {
+	updated, err := UpdateHooks(nil, "/usr/local/bin/claude-status")
+	require.NoError(t, err)
+
+	for _, event := range HookEvents {
+		assert.Equal(t, []string{"/usr/local/bin/claude-status hook"}, hookCommands(t, updated, event), event)
+	}
+}
+
+func TestUpdateHooks_KeepsExistingHooks(t *testing.T) {
+	data := []byte(`{
+  // keep me
+  "model": "opus",
+  "hooks": {
+    "Stop": [{"matcher": "", "hooks": [{"type": "command", "command": "say done"}]}],
+    "PreToolUse": [{"matcher": "Bash", "hooks": [{"type": "command", "command": "lint"}]}]
+  }
+}
+`)
+
+	updated, err := UpdateHooks(data, "/bin/claude-status")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"say done", "/bin/claude-status hook"}, hookCommands(t, updated, "Stop"))
+	assert.Equal(t, []string{"/bin/claude-status hook"}, hookCommands(t, updated, "SessionStart"))
+	assert.Equal(t, []string{"lint"}, hookCommands(t, updated, "PreToolUse"))
+	assert.Contains(t, string(updated), "// keep me\n  \"model\": \"opus\",")
+}
+
+func TestUpdateHooks_RepointsAndIsIdempotent(t *testing.T) {
+	data := []byte(`{"hooks": {"Stop": [{"hooks": [{"type": "command", "command": "/old/dir/claude-status hook"}]}]}}`)
+
+	updated, err := UpdateHooks(data, "/new/dir/claude-status")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/new/dir/claude-status hook"}, hookCommands(t, updated, "Stop"))
+
+	again, err := UpdateHooks(updated, "/new/dir/claude-status")
+	require.NoError(t, err)
+	assert.Equal(t, string(updated), string(again))
+}
+
+func TestUpdateHooks_InvalidHooks(t *testing.T) {
+	_, err := UpdateHooks([]byte(`{"hooks": "nope"}`), "/bin/claude-status")
+	assert.Error(t, err)
+}
+
+func TestIsOwnHook(t *testing.T) {
+	assert.True(t, isOwnHook("/usr/local/bin/claude-status hook"))
+	assert.True(t, isOwnHook("/path with space/claude-status hook"))
+	assert.False(t, isOwnHook("/usr/local/bin/claude-status"))
+	assert.False(t, isOwnHook("/usr/local/bin/other hook"))
+}
+
+func TestRun_WithHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+
+	var output bytes.Buffer
+	err := Run(&output, strings.NewReader("y\n"), Options{Hooks: true})
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), `"SessionStart"`)
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "settings.json"))
+	require.NoError(t, err)
+	for _, event := range HookEvents {
+		commands := hookCommands(t, data, event)
+		require.Len(t, commands, 1, event)
+		assert.True(t, strings.HasSuffix(commands[0], " hook"), commands[0])
+	}
+}
+
+func TestRemoveHooks(t *testing.T) {
+	installed, err := UpdateHooks([]byte(`{
+  "hooks": {
+    "Stop": [{"hooks": [{"type": "command", "command": "notify-send done"}]}]
+  }
+}`), "/usr/local/bin/claude-status")
+	require.NoError(t, err)
+
+	updated, err := RemoveHooks(installed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"notify-send done"}, hookCommands(t, updated, "Stop"))
+	assert.Empty(t, hookCommands(t, updated, "SessionStart"))
+	assert.NotContains(t, string(updated), "SessionStart")
+
+	// Nothing else registered: "hooks" goes away entirely
+	installed, err = UpdateHooks([]byte("{\n  \"theme\": \"dark\"\n}\n"), "/usr/local/bin/claude-status")
+	require.NoError(t, err)
+	updated, err = RemoveHooks(installed)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"theme\": \"dark\"\n}\n", string(updated))
+}
+
+func TestUpdateHooks_EditsOnlyItsEvents(t *testing.T) {
+	data := []byte(`{
+  "hooks": {
+    // lint before every shell command
+    "PreToolUse": [{"matcher": "Bash", "hooks": [{"type": "command", "command": "lint"}]}],
+    "Stop": [{"hooks": [{"type": "command", "command": "say done"}]}]
+  }
+}
+`)
+	untouched := "    // lint before every shell command\n    \"PreToolUse\": [{\"matcher\": \"Bash\", \"hooks\": [{\"type\": \"command\", \"command\": \"lint\"}]}],\n"
+
+	updated, err := UpdateHooks(data, "/bin/claude-status")
+	require.NoError(t, err)
+	assert.Contains(t, string(updated), untouched)
+	assert.Contains(t, string(updated), "\n    \"SessionStart\": [\n      {\n")
+	assert.Equal(t, []string{"say done", "/bin/claude-status hook"}, hookCommands(t, updated, "Stop"))
+
+	removed, err := RemoveHooks(updated)
+	require.NoError(t, err)
+	assert.Contains(t, string(removed), untouched)
+	assert.NotContains(t, string(removed), "SessionStart")
+	assert.Equal(t, []string{"say done"}, hookCommands(t, removed, "Stop"))
+	assert.Equal(t, []string{"lint"}, hookCommands(t, removed, "PreToolUse"))
+}
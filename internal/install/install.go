@@ -3,7 +3,10 @@ package install
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -20,70 +23,205 @@ type StatusLine struct {
 	Padding int    `json:"padding"`
 }
 
-// Run executes the install flow: shows diff, prompts for confirmation, writes settings.
-func Run(w io.Writer, r io.Reader) error {
-	// Get the binary path
-	binaryPath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
+// Options selects optional parts of the install.
+type Options struct {
+	// Hooks also registers the claude-status hook for HookEvents.
+	Hooks bool
 
-	// Resolve symlinks to get the real path
-	binaryPath, err = filepath.EvalSymlinks(binaryPath)
-	if err != nil {
-		return fmt.Errorf("failed to resolve executable path: %w", err)
-	}
+	// Yes answers every prompt with yes, for unattended installs.
+	Yes bool
+}
 
-	// Get settings path
-	settingsPath := GetSettingsPath()
+// ErrChangesPending is returned by DryRun and UninstallDryRun when the
+// settings would change, so provisioning scripts can tell from the exit
+// status.
+var ErrChangesPending = errors.New("settings would change")
 
-	// Read existing settings
-	beforeSettings, err := ReadSettings(settingsPath)
+// Run executes the install flow: shows diff, prompts for confirmation, writes
+// settings, then verifies the installed command renders a status line.
+func Run(w io.Writer, r io.Reader, opts Options) error {
+	// Share one buffered reader across prompts so answers aren't lost
+	br := bufio.NewReader(r)
+
+	binaryPath, err := ExecutablePath()
 	if err != nil {
-		return fmt.Errorf("failed to read settings: %w", err)
+		return err
+	}
+	// Settings reference an accepted stable link instead, created only once
+	// the change is confirmed
+	var linkPath string
+	if isVersionedPath(binaryPath) {
+		linkPath, err = offerStableLink(w, br, binaryPath, opts.Yes)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Deep copy before settings for diff comparison
-	beforeCopy := deepCopySettings(beforeSettings)
-
-	// Update settings with statusLine
-	UpdateSettings(beforeSettings, binaryPath)
-	afterSettings := beforeSettings
+	p, err := plan(cmp.Or(linkPath, binaryPath), opts)
+	if err != nil {
+		return err
+	}
 
 	// Show diff
-	ShowDiff(w, settingsPath, beforeCopy, afterSettings)
+	ShowDiff(w, p.settingsPath, p.before, p.after)
 
 	// Prompt for confirmation
-	if !PromptConfirm(w, r) {
+	if !confirm(w, br, "Apply changes?", opts.Yes) {
 		fmt.Fprintln(w, "Installation cancelled.")
 		return nil
 	}
 
-	// Generate JSON for writing (with trailing newline for proper text file format)
-	afterJSON, err := json.MarshalIndent(afterSettings, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal settings: %w", err)
+	if linkPath != "" {
+		if err := createStableLink(w, linkPath, binaryPath); err != nil {
+			return err
+		}
+	}
+
+	// Keep a statusLine from another tool so -uninstall can restore it
+	if p.replaced != nil {
+		if err := saveBackup(backupPath(p.settingsPath), p.replaced); err != nil {
+			return fmt.Errorf("failed to back up the current statusLine: %w", err)
+		}
 	}
-	afterJSON = append(afterJSON, '\n')
 
 	// Write settings
-	if err := WriteSettings(settingsPath, afterJSON); err != nil {
+	if err := WriteSettings(p.settingsPath, p.after); err != nil {
 		return fmt.Errorf("failed to write settings: %w", err)
 	}
 
 	fmt.Fprintln(w, "Successfully installed claude-status!")
+	if p.replaced != nil {
+		fmt.Fprintf(w, "Saved the previous statusLine to %s; -uninstall restores it.\n", backupPath(p.settingsPath))
+	}
+
+	// Self-test the command settings now point at
+	fmt.Fprintf(w, "Verifying %s... ", p.binaryPath)
+	output, err := Verify(p.binaryPath)
+	if err != nil {
+		fmt.Fprintln(w, "failed")
+		return fmt.Errorf("installed, but the status line self-test failed: %w", err)
+	}
+	fmt.Fprintln(w, "ok")
+	fmt.Fprintf(w, "Sample output: %s\n", output)
 	return nil
 }
 
-// deepCopySettings creates a deep copy of settings map via JSON round-trip.
-func deepCopySettings(settings map[string]any) map[string]any {
-	data, _ := json.Marshal(settings)
-	var copy map[string]any
-	_ = json.Unmarshal(data, &copy)
-	if copy == nil {
-		return make(map[string]any)
+// DryRun prints the settings change install would make as a plain unified
+// diff, without prompting or writing, and returns ErrChangesPending if there
+// is one. Nothing is printed if the settings are already up to date.
+func DryRun(w io.Writer, opts Options) error {
+	binaryPath, err := ExecutablePath()
+	if err != nil {
+		return err
+	}
+	return dryRun(w, binaryPath, opts)
+}
+
+// dryRun is DryRun for the binary at binaryPath. Like Run with -y, a
+// version-specific binaryPath is referenced through the stable link, which
+// is up to date once it resolves to binaryPath.
+func dryRun(w io.Writer, binaryPath string, opts Options) error {
+	var linkPath string
+	if isVersionedPath(binaryPath) {
+		var err error
+		if linkPath, err = StableLinkPath(); err != nil {
+			return err
+		}
+	}
+
+	p, err := plan(cmp.Or(linkPath, binaryPath), opts)
+	if err != nil {
+		return err
+	}
+
+	err = printDryRun(w, p.settingsPath, p.before, p.after)
+	if linkPath != "" && !linksTo(linkPath, binaryPath) {
+		fmt.Fprintf(w, "Would link %s -> %s\n", linkPath, binaryPath)
+		return ErrChangesPending
+	}
+	return err
+}
+
+// printDryRun prints the diff from before to after and returns
+// ErrChangesPending if they differ.
+func printDryRun(w io.Writer, path string, before, after []byte) error {
+	diff := settingsDiff(path, path, before, after)
+	if diff == "" {
+		return nil
+	}
+	fmt.Fprint(w, diff)
+	return ErrChangesPending
+}
+
+// installPlan is the settings change an install would make.
+type installPlan struct {
+	settingsPath string // Claude Code settings.json
+	binaryPath   string // Command statusLine will run
+	before       []byte // Current settings file contents
+	after        []byte // Settings file contents after install
+	replaced     any    // Another tool's statusLine that install replaces, if any
+}
+
+// ExecutablePath returns the path settings should reference for this binary.
+// Symlinks are resolved unless that lands in a version-specific directory
+// and the invoked path (e.g. Homebrew's bin/ link) is stable.
+func ExecutablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	// Resolve symlinks to get the real path
+	resolved, err := filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve executable path: %w", err)
+	}
+
+	if isVersionedPath(resolved) && !isVersionedPath(exe) {
+		return exe, nil
+	}
+	return resolved, nil
+}
+
+// plan reads the current settings file and computes its new contents with
+// statusLine (and optionally hooks) pointing at binaryPath.
+func plan(binaryPath string, opts Options) (*installPlan, error) {
+	// Get settings path
+	settingsPath := GetSettingsPath()
+
+	// Read existing settings, validating them before editing
+	before, err := readSettingsFile(settingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	// Update settings with statusLine
+	after, err := UpdateSettings(before, binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
 	}
-	return copy
+
+	// Register hooks if requested
+	if opts.Hooks {
+		after, err = UpdateHooks(after, binaryPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update hooks: %w", err)
+		}
+	}
+
+	p := &installPlan{
+		settingsPath: settingsPath,
+		binaryPath:   binaryPath,
+		before:       before,
+		after:        after,
+	}
+	if !bytes.Equal(before, after) {
+		p.replaced, err = foreignStatusLine(before)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
 }
 
 // GetSettingsPath returns the path to Claude Code's settings.json.
@@ -101,61 +239,77 @@ func GetSettingsPath() string {
 }
 
 // ReadSettings reads and parses the settings file.
+// Comments and trailing commas (JSONC) are accepted.
 // Returns empty map if file doesn't exist.
 func ReadSettings(path string) (map[string]any, error) {
+	data, err := readSettingsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]any)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(stripJSONC(data), &settings); err != nil {
+		return nil, fmt.Errorf("invalid JSON in settings file: %w", err)
+	}
+	return settings, nil
+}
+
+// readSettingsFile returns the raw settings file contents after checking
+// they parse. Returns nil if the file doesn't exist.
+func readSettingsFile(path string) ([]byte, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return make(map[string]any), nil
+			return nil, nil
 		}
 		return nil, err
 	}
 
 	// Handle empty file
-	if len(data) == 0 {
-		return make(map[string]any), nil
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data, nil
 	}
 
 	var settings map[string]any
-	if err := json.Unmarshal(data, &settings); err != nil {
+	if err := json.Unmarshal(stripJSONC(data), &settings); err != nil {
 		return nil, fmt.Errorf("invalid JSON in settings file: %w", err)
 	}
-
-	return settings, nil
+	return data, nil
 }
 
-// UpdateSettings adds or updates the statusLine configuration.
-func UpdateSettings(settings map[string]any, binaryPath string) {
-	settings["statusLine"] = StatusLine{
+// UpdateSettings returns the settings file contents with statusLine set to
+// run binaryPath. Only the statusLine member is touched, so the order of the
+// other keys, their formatting, and any comments are preserved.
+func UpdateSettings(data []byte, binaryPath string) ([]byte, error) {
+	updated, err := setTopLevelKey(data, "statusLine", StatusLine{
 		Type:    "command",
 		Command: binaryPath,
 		Padding: 0,
+	})
+	if err != nil {
+		return nil, err
 	}
+
+	// Ensure a trailing newline for proper text file format
+	if !bytes.Equal(updated, data) && !bytes.HasSuffix(updated, []byte("\n")) {
+		updated = append(updated, '\n')
+	}
+	return updated, nil
 }
 
-// ShowDiff displays the diff between before and after settings as JSON.
-func ShowDiff(w io.Writer, path string, before, after map[string]any) {
+// ShowDiff displays the diff between the before and after settings file contents.
+func ShowDiff(w io.Writer, path string, before, after []byte) {
 	fmt.Fprintf(w, "Settings file: %s\n\n", path)
 
-	beforeJSON, _ := json.MarshalIndent(before, "", "  ")
-	afterJSON, _ := json.MarshalIndent(after, "", "  ")
-
-	if string(beforeJSON) == string(afterJSON) {
+	result := settingsDiff("before", "after", before, after)
+	if result == "" {
 		fmt.Fprintln(w, "No changes needed.")
 		return
 	}
 
-	// Generate unified diff
-	diff := difflib.UnifiedDiff{
-		A:        difflib.SplitLines(string(beforeJSON)),
-		B:        difflib.SplitLines(string(afterJSON)),
-		FromFile: "before",
-		ToFile:   "after",
-		Context:  3,
-	}
-
-	result, _ := difflib.GetUnifiedDiffString(diff)
-
 	// Colorize the diff output
 	for _, line := range strings.Split(result, "\n") {
 		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
@@ -171,9 +325,50 @@ func ShowDiff(w io.Writer, path string, before, after map[string]any) {
 	fmt.Fprintln(w)
 }
 
+// settingsDiff returns a unified diff of the before and after file contents,
+// or "" if they are identical.
+func settingsDiff(fromFile, toFile string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+
+	diff := difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	}
+
+	result, _ := difflib.GetUnifiedDiffString(diff)
+	return result
+}
+
+// splitLines splits file contents for diffing; an empty file has no lines.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return difflib.SplitLines(strings.TrimSuffix(string(data), "\n"))
+}
+
 // PromptConfirm asks the user to confirm the changes.
 func PromptConfirm(w io.Writer, r io.Reader) bool {
-	fmt.Fprint(w, "Apply changes? [y/N]: ")
+	return Confirm(w, r, "Apply changes?")
+}
+
+// confirm is Confirm, except that yes answers without reading r.
+func confirm(w io.Writer, r io.Reader, question string, yes bool) bool {
+	if yes {
+		fmt.Fprintf(w, "%s [y/N]: y (-y)\n", question)
+		return true
+	}
+	return Confirm(w, r, question)
+}
+
+// Confirm asks a yes/no question and returns true only for "y" or "yes".
+func Confirm(w io.Writer, r io.Reader, question string) bool {
+	fmt.Fprintf(w, "%s [y/N]: ", question)
 
 	reader := bufio.NewReader(r)
 	response, err := reader.ReadString('\n')
@@ -110,59 +110,156 @@ func TestReadSettings_PermissionDenied(t *testing.T) {
 }
 
 func TestUpdateSettings_NewKey(t *testing.T) {
-	settings := make(map[string]any)
 	binaryPath := "/usr/local/bin/claude-status"
 
-	UpdateSettings(settings, binaryPath)
+	updated, err := UpdateSettings(nil, binaryPath)
+	require.NoError(t, err)
 
-	statusLine, ok := settings["statusLine"].(StatusLine)
-	require.True(t, ok)
+	var settings map[string]StatusLine
+	require.NoError(t, json.Unmarshal(updated, &settings))
+	statusLine := settings["statusLine"]
 	assert.Equal(t, "command", statusLine.Type)
 	assert.Equal(t, binaryPath, statusLine.Command)
 	assert.Equal(t, 0, statusLine.Padding)
+	assert.True(t, bytes.HasSuffix(updated, []byte("}\n")), "new settings file should end with newline")
 }
 
 func TestUpdateSettings_ExistingKey(t *testing.T) {
-	settings := map[string]any{
-		"statusLine": map[string]any{
-			"type":    "command",
-			"command": "/old/path",
-		},
-	}
+	data := []byte(`{
+  "statusLine": {
+    "type": "command",
+    "command": "/old/path"
+  },
+  "theme": "dark"
+}
+`)
 	newPath := "/new/path/claude-status"
 
-	UpdateSettings(settings, newPath)
+	updated, err := UpdateSettings(data, newPath)
+	require.NoError(t, err)
 
-	statusLine, ok := settings["statusLine"].(StatusLine)
-	require.True(t, ok)
-	assert.Equal(t, newPath, statusLine.Command)
+	want := `{
+  "statusLine": {
+    "type": "command",
+    "command": "/new/path/claude-status",
+    "padding": 0
+  },
+  "theme": "dark"
+}
+`
+	assert.Equal(t, want, string(updated))
 }
 
 func TestUpdateSettings_PreservesOtherKeys(t *testing.T) {
-	settings := map[string]any{
-		"theme":    "dark",
-		"fontSize": 14,
-	}
+	data := []byte(`{
+  "theme": "dark",
+  "fontSize": 14
+}
+`)
 	binaryPath := "/usr/local/bin/claude-status"
 
-	UpdateSettings(settings, binaryPath)
+	updated, err := UpdateSettings(data, binaryPath)
+	require.NoError(t, err)
 
-	assert.Equal(t, "dark", settings["theme"])
-	assert.Equal(t, 14, settings["fontSize"])
-	assert.Contains(t, settings, "statusLine")
+	want := `{
+  "theme": "dark",
+  "fontSize": 14,
+  "statusLine": {
+    "type": "command",
+    "command": "/usr/local/bin/claude-status",
+    "padding": 0
+  }
+}
+`
+	assert.Equal(t, want, string(updated))
+}
+
+func TestUpdateSettings_PreservesCommentsAndOrder(t *testing.T) {
+	data := []byte(`// Claude Code settings
+{
+    /* model choice */
+    "model": "opus",
+    "permissions": {
+        "allow": ["Bash(go test:*)"], // keep tests fast
+    },
+    "env": {"FOO": "bar"}, // trailing comma is fine in JSONC
+}
+`)
+
+	updated, err := UpdateSettings(data, "/bin/claude-status")
+	require.NoError(t, err)
+
+	want := `// Claude Code settings
+{
+    /* model choice */
+    "model": "opus",
+    "permissions": {
+        "allow": ["Bash(go test:*)"], // keep tests fast
+    },
+    "env": {"FOO": "bar"}, // trailing comma is fine in JSONC
+    "statusLine": {
+        "type": "command",
+        "command": "/bin/claude-status",
+        "padding": 0
+    },
+}
+`
+	assert.Equal(t, want, string(updated))
+}
+
+func TestUpdateSettings_UnchangedWhenEqual(t *testing.T) {
+	data := []byte(`{"statusLine": {"command": "/bin/claude-status", "padding": 0, "type": "command"}}`)
+
+	updated, err := UpdateSettings(data, "/bin/claude-status")
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(updated))
+}
+
+func TestUpdateSettings_EmptyObject(t *testing.T) {
+	updated, err := UpdateSettings([]byte("{}\n"), "/bin/claude-status")
+	require.NoError(t, err)
+
+	want := `{
+  "statusLine": {
+    "type": "command",
+    "command": "/bin/claude-status",
+    "padding": 0
+  }
+}
+`
+	assert.Equal(t, want, string(updated))
+}
+
+func TestUpdateSettings_NotAnObject(t *testing.T) {
+	_, err := UpdateSettings([]byte(`["a"]`), "/bin/claude-status")
+	assert.Error(t, err)
+}
+
+func TestReadSettings_JSONC(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "settings.json")
+
+	content := `{
+  // comment with "quotes" and a, comma
+  "theme": "dark // not a comment", /* block */
+  "list": [1, 2,],
+}
+`
+	err := os.WriteFile(path, []byte(content), 0644)
+	require.NoError(t, err)
+
+	settings, err := ReadSettings(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "dark // not a comment", settings["theme"])
+	assert.Equal(t, []any{float64(1), float64(2)}, settings["list"])
 }
 
 func TestShowDiff(t *testing.T) {
 	var buf bytes.Buffer
 	path := "/home/user/.claude/settings.json"
-	before := map[string]any{}
-	after := map[string]any{
-		"statusLine": map[string]any{
-			"type":    "command",
-			"command": "/usr/bin/claude-status",
-			"padding": float64(0),
-		},
-	}
+	before := []byte("{\n  \"theme\": \"dark\"\n}\n")
+	after := []byte("{\n  \"theme\": \"dark\",\n  \"statusLine\": {}\n}\n")
 
 	ShowDiff(&buf, path, before, after)
 
@@ -176,7 +273,7 @@ func TestShowDiff(t *testing.T) {
 func TestShowDiff_NoChanges(t *testing.T) {
 	var buf bytes.Buffer
 	path := "/home/user/.claude/settings.json"
-	settings := map[string]any{"theme": "dark"}
+	settings := []byte(`{"theme": "dark"}`)
 
 	ShowDiff(&buf, path, settings, settings)
 
@@ -257,12 +354,13 @@ func TestRun_Integration_Confirm(t *testing.T) {
 	var output bytes.Buffer
 	input := strings.NewReader("y\n")
 
-	err = Run(&output, input)
+	err = Run(&output, input, Options{})
 	require.NoError(t, err)
 
 	// Verify output
 	assert.Contains(t, output.String(), "Settings file:")
 	assert.Contains(t, output.String(), "Successfully installed")
+	assert.Contains(t, output.String(), "Sample output: [Sonnet 4] | tmp")
 
 	// Verify file was updated
 	updatedData, err := os.ReadFile(settingsPath)
@@ -300,7 +398,7 @@ func TestRun_Integration_Cancel(t *testing.T) {
 	var output bytes.Buffer
 	input := strings.NewReader("n\n")
 
-	err = Run(&output, input)
+	err = Run(&output, input, Options{})
 	require.NoError(t, err)
 
 	// Verify output
@@ -326,7 +424,7 @@ func TestRun_Integration_NewFile(t *testing.T) {
 	var output bytes.Buffer
 	input := strings.NewReader("y\n")
 
-	err := Run(&output, input)
+	err := Run(&output, input, Options{})
 	require.NoError(t, err)
 
 	// Verify file was created
@@ -342,3 +440,72 @@ func TestRun_Integration_NewFile(t *testing.T) {
 
 	assert.Contains(t, settings, "statusLine")
 }
+
+func TestDryRun_PrintsDiffWithoutWriting(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+
+	original := []byte("{\n  \"theme\": \"dark\"\n}")
+	err := os.WriteFile(settingsPath, original, 0644)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	err = DryRun(&output, Options{})
+	require.ErrorIs(t, err, ErrChangesPending)
+
+	// Plain unified diff: labelled with the settings path, no ANSI colors, no prompt
+	out := output.String()
+	assert.Contains(t, out, "--- "+settingsPath)
+	assert.Contains(t, out, "+++ "+settingsPath)
+	assert.Contains(t, out, `+  "statusLine": {`)
+	assert.NotContains(t, out, "\033[")
+	assert.NotContains(t, out, "[y/N]")
+
+	// Settings file is untouched
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, original, data)
+}
+
+func TestDryRun_NoChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+
+	// Install once, then a dry run should report nothing
+	err := Run(&bytes.Buffer{}, strings.NewReader("y\n"), Options{})
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	err = DryRun(&output, Options{})
+	require.NoError(t, err)
+	assert.Empty(t, output.String())
+}
+
+func TestDryRun_NewFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+
+	var output bytes.Buffer
+	err := DryRun(&output, Options{})
+	require.ErrorIs(t, err, ErrChangesPending)
+
+	assert.Contains(t, output.String(), `+  "statusLine": {`)
+	assert.NoFileExists(t, filepath.Join(tmpDir, "settings.json"))
+}
+
+func TestRun_Yes(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+
+	// Nothing to read from: -y must not prompt
+	var output bytes.Buffer
+	err := Run(&output, strings.NewReader(""), Options{Yes: true})
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "Apply changes? [y/N]: y (-y)")
+
+	settings, err := ReadSettings(filepath.Join(tmpDir, "settings.json"))
+	require.NoError(t, err)
+	assert.Contains(t, settings, "statusLine")
+}
@@ -0,0 +1,447 @@
+package install
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// errUnexpectedEnd is returned when a settings file ends mid-value.
+var errUnexpectedEnd = errors.New("unexpected end of settings file")
+
+// jsoncScanner walks JSON with comments (JSONC), tracking a byte offset.
+// It understands just enough syntax to locate values without decoding them.
+type jsoncScanner struct {
+	data []byte
+	pos  int
+}
+
+// skipSpace advances past whitespace, // line comments, and /* block */ comments.
+func (s *jsoncScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch {
+		case isSpace(s.data[s.pos]):
+			s.pos++
+		case bytes.HasPrefix(s.data[s.pos:], []byte("//")):
+			end := bytes.IndexByte(s.data[s.pos:], '\n')
+			if end < 0 {
+				s.pos = len(s.data)
+				return
+			}
+			s.pos += end
+		case bytes.HasPrefix(s.data[s.pos:], []byte("/*")):
+			end := bytes.Index(s.data[s.pos+2:], []byte("*/"))
+			if end < 0 {
+				s.pos = len(s.data)
+				return
+			}
+			s.pos += 2 + end + 2
+		default:
+			return
+		}
+	}
+}
+
+// skipString advances past a string literal starting at the current offset.
+func (s *jsoncScanner) skipString() error {
+	for s.pos++; s.pos < len(s.data); s.pos++ {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos++
+		case '"':
+			s.pos++
+			return nil
+		}
+	}
+	return errUnexpectedEnd
+}
+
+// skipValue advances past one value: a string, a literal, or a nested
+// object or array (including any comments inside it).
+func (s *jsoncScanner) skipValue() error {
+	if s.pos >= len(s.data) {
+		return errUnexpectedEnd
+	}
+	switch s.data[s.pos] {
+	case '"':
+		return s.skipString()
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			s.skipSpace()
+			if s.pos >= len(s.data) {
+				break
+			}
+			switch s.data[s.pos] {
+			case '"':
+				if err := s.skipString(); err != nil {
+					return err
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			s.pos++
+			if depth == 0 {
+				return nil
+			}
+		}
+		return errUnexpectedEnd
+	default:
+		// Number, true, false, or null
+		start := s.pos
+		for s.pos < len(s.data) && !isSpace(s.data[s.pos]) && !bytes.ContainsRune([]byte(",}]/"), rune(s.data[s.pos])) {
+			s.pos++
+		}
+		if s.pos == start {
+			return fmt.Errorf("unexpected %q at offset %d", s.data[s.pos], s.pos)
+		}
+		return nil
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// stripJSONC returns standard JSON by blanking out comments and trailing
+// commas. Byte offsets and newlines are preserved so decode errors still
+// point at the right place.
+func stripJSONC(data []byte) []byte {
+	out := bytes.Clone(data)
+	s := &jsoncScanner{data: data}
+	for s.pos < len(data) {
+		start := s.pos
+		s.skipSpace()
+		for i := start; i < s.pos; i++ {
+			if !isSpace(out[i]) {
+				out[i] = ' '
+			}
+		}
+		if s.pos >= len(data) {
+			break
+		}
+
+		switch data[s.pos] {
+		case '"':
+			if s.skipString() != nil {
+				return out
+			}
+		case ',':
+			next := jsoncScanner{data: data, pos: s.pos + 1}
+			next.skipSpace()
+			if next.pos < len(data) && (data[next.pos] == '}' || data[next.pos] == ']') {
+				out[s.pos] = ' '
+			}
+			s.pos++
+		default:
+			s.pos++
+		}
+	}
+	return out
+}
+
+// member is a key of a JSONC object and the span of its value.
+type member struct {
+	name       string
+	keyStart   int // Offset of the key's opening quote
+	start, end int // Offsets of the value
+}
+
+// scanObject lists the members of the object opening at data[open] and
+// returns them with the offset of its closing brace.
+func scanObject(data []byte, open int) ([]member, int, error) {
+	s := &jsoncScanner{data: data, pos: open + 1}
+	var members []member
+	for {
+		s.skipSpace()
+		if s.pos >= len(data) {
+			return nil, 0, errUnexpectedEnd
+		}
+		c := data[s.pos]
+		if c == '}' {
+			return members, s.pos, nil
+		}
+		if c == ',' {
+			s.pos++
+			continue
+		}
+		if c != '"' {
+			return nil, 0, fmt.Errorf("unexpected %q at offset %d", c, s.pos)
+		}
+
+		m := member{keyStart: s.pos}
+		if err := s.skipString(); err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(data[m.keyStart:s.pos], &m.name); err != nil {
+			return nil, 0, fmt.Errorf("invalid key at offset %d: %w", m.keyStart, err)
+		}
+
+		s.skipSpace()
+		if s.pos >= len(data) || data[s.pos] != ':' {
+			return nil, 0, fmt.Errorf("expected ':' after key %q", m.name)
+		}
+		s.pos++
+		s.skipSpace()
+		m.start = s.pos
+		if err := s.skipValue(); err != nil {
+			return nil, 0, err
+		}
+		m.end = s.pos
+		members = append(members, m)
+	}
+}
+
+// rootObject returns the offset of the top-level object's opening brace.
+func rootObject(data []byte) (int, error) {
+	s := &jsoncScanner{data: data}
+	s.skipSpace()
+	if s.pos >= len(data) || data[s.pos] != '{' {
+		return 0, errors.New("settings file must contain a JSON object")
+	}
+	return s.pos, nil
+}
+
+// topLevelMember finds key in the top-level object of a JSONC document.
+func topLevelMember(data []byte, key string) (member, bool, error) {
+	open, err := rootObject(data)
+	if err != nil {
+		return member{}, false, err
+	}
+	members, _, err := scanObject(data, open)
+	if err != nil {
+		return member{}, false, err
+	}
+	for _, m := range members {
+		if m.name == key {
+			return m, true, nil
+		}
+	}
+	return member{}, false, nil
+}
+
+// setTopLevelKey sets key in the top-level object of a JSONC document to
+// value, editing only the bytes of that member. Key order, formatting, and
+// comments elsewhere in the document are left untouched. If key already
+// holds an equal value, data is returned unchanged.
+func setTopLevelKey(data []byte, key string, value any) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}\n")
+	}
+	open, err := rootObject(data)
+	if err != nil {
+		return nil, err
+	}
+	return setObjectKey(data, open, "", key, value)
+}
+
+// setObjectKey is setTopLevelKey for the object opening at data[open],
+// whose own line is indented by outer.
+func setObjectKey(data []byte, open int, outer, key string, value any) ([]byte, error) {
+	members, closing, err := scanObject(data, open)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indent new lines like the members already there, one level below outer
+	indent, unit := "", "  "
+	if len(members) > 0 {
+		indent = lineIndent(data, members[0].keyStart)
+	}
+	if level, ok := strings.CutPrefix(indent, outer); ok && level != "" {
+		unit = level
+	} else {
+		indent = outer + unit
+	}
+
+	for _, m := range members {
+		if m.name == key {
+			return replaceValue(data, m.start, m.end, value, indent, unit)
+		}
+	}
+
+	member, err := formatMember(key, value, indent, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if len(members) == 0 {
+		// Empty object: keep any comments inside it, replace bare whitespace
+		inner := data[open+1 : closing]
+		if len(bytes.TrimSpace(inner)) == 0 {
+			inner = []byte("\n" + outer)
+		}
+		out = append(out, data[:open+1]...)
+		out = append(out, '\n')
+		out = append(out, indent...)
+		out = append(out, member...)
+		out = append(out, inner...)
+		return append(out, data[closing:]...), nil
+	}
+
+	// Insert after the last member, past a trailing comma and any comment
+	// on the same line so the comment stays with the member it describes
+	lastEnd := members[len(members)-1].end
+	pos := lastEnd
+	for pos < len(data) && (data[pos] == ' ' || data[pos] == '\t') {
+		pos++
+	}
+	hasComma := pos < len(data) && data[pos] == ','
+	if hasComma {
+		pos++
+	}
+	insertAt := lineEnd(data, pos)
+
+	out = append(out, data[:lastEnd]...)
+	if !hasComma {
+		out = append(out, ',')
+	}
+	out = append(out, data[lastEnd:insertAt]...)
+	out = append(out, '\n')
+	out = append(out, indent...)
+	out = append(out, member...)
+	if hasComma {
+		out = append(out, ',')
+	}
+	return append(out, data[insertAt:]...), nil
+}
+
+// replaceValue swaps the bytes of an existing value, unless it already
+// decodes to the same thing as value. Its lines are indented from indent by
+// unit per level.
+func replaceValue(data []byte, start, end int, value any, indent, unit string) ([]byte, error) {
+	newJSON, err := json.MarshalIndent(value, indent, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing, wanted any
+	if json.Unmarshal(stripJSONC(data[start:end]), &existing) == nil &&
+		json.Unmarshal(newJSON, &wanted) == nil && reflect.DeepEqual(existing, wanted) {
+		return data, nil
+	}
+
+	out := append([]byte{}, data[:start]...)
+	out = append(out, newJSON...)
+	return append(out, data[end:]...), nil
+}
+
+// formatMember renders `"key": value` with value's lines indented from
+// indent by unit per level.
+func formatMember(key string, value any, indent, unit string) ([]byte, error) {
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	valueJSON, err := json.MarshalIndent(value, indent, unit)
+	if err != nil {
+		return nil, err
+	}
+	member := append(keyJSON, ": "...)
+	return append(member, valueJSON...), nil
+}
+
+// lineIndent returns the whitespace before pos on its line, or "" if
+// anything else precedes pos on that line.
+func lineIndent(data []byte, pos int) string {
+	start := bytes.LastIndexByte(data[:pos], '\n') + 1
+	prefix := data[start:pos]
+	if len(bytes.Trim(prefix, " \t")) != 0 {
+		return ""
+	}
+	return string(prefix)
+}
+
+// lineEnd returns the offset of the newline ending the line containing pos
+// if only whitespace or a comment follows pos on that line; otherwise pos.
+func lineEnd(data []byte, pos int) int {
+	s := &jsoncScanner{data: data, pos: pos}
+	for s.pos < len(data) && (data[s.pos] == ' ' || data[s.pos] == '\t' || data[s.pos] == '\r') {
+		s.pos++
+	}
+	if s.pos >= len(data) || data[s.pos] == '\n' {
+		return s.pos
+	}
+	if bytes.HasPrefix(data[s.pos:], []byte("//")) {
+		if end := bytes.IndexByte(data[s.pos:], '\n'); end >= 0 {
+			return s.pos + end
+		}
+		return len(data)
+	}
+	return pos
+}
+
+// removeTopLevelKey deletes key from the top-level object of a JSONC
+// document, along with its line if the member has the line to itself, and
+// reports whether it was there. The rest of the document is left untouched.
+func removeTopLevelKey(data []byte, key string) ([]byte, bool, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data, false, nil
+	}
+	open, err := rootObject(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return removeObjectKey(data, open, key)
+}
+
+// removeObjectKey is removeTopLevelKey for the object opening at data[open].
+func removeObjectKey(data []byte, open int, key string) ([]byte, bool, error) {
+	members, _, err := scanObject(data, open)
+	if err != nil {
+		return nil, false, err
+	}
+	i := slices.IndexFunc(members, func(m member) bool { return m.name == key })
+	if i < 0 {
+		return data, false, nil
+	}
+	keyStart := members[i].keyStart
+	prevEnd := -1
+	if i > 0 {
+		prevEnd = members[i-1].end
+	}
+
+	// Take the trailing comma and any comment on the same line with it
+	end := members[i].end
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	hasComma := end < len(data) && data[end] == ','
+	if hasComma {
+		end++
+	}
+	end = lineEnd(data, end)
+
+	start := keyStart
+	if lineIndent(data, keyStart) != "" || (keyStart > 0 && data[keyStart-1] == '\n') {
+		start = bytes.LastIndexByte(data[:keyStart], '\n') + 1
+		if end < len(data) && data[end] == '\n' {
+			end++
+		}
+	} else if hasComma {
+		// Mid-line: drop the space after the comma too
+		for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+			end++
+		}
+	}
+
+	out := append([]byte{}, data[:start]...)
+	out = append(out, data[end:]...)
+	if !hasComma && prevEnd >= 0 {
+		// The last member is gone, so the one before it loses its comma
+		comma := &jsoncScanner{data: out, pos: prevEnd}
+		comma.skipSpace()
+		if comma.pos < len(out) && out[comma.pos] == ',' {
+			out = append(out[:comma.pos], out[comma.pos+1:]...)
+		}
+	}
+	return out, true, nil
+}
@@ -0,0 +1,81 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTopLevelKey_SkipsTrickyValues(t *testing.T) {
+	// Braces, quotes, and comment markers inside strings must not confuse the scanner
+	data := []byte(`{
+  "hooks": {"cmd": "echo \"}\" // {"},
+  "url": "http://example.com/*not-a-comment*/",
+  "n": -1.5e3,
+  "ok": true
+}
+`)
+
+	updated, err := setTopLevelKey(data, "x", 1)
+	require.NoError(t, err)
+
+	want := `{
+  "hooks": {"cmd": "echo \"}\" // {"},
+  "url": "http://example.com/*not-a-comment*/",
+  "n": -1.5e3,
+  "ok": true,
+  "x": 1
+}
+`
+	assert.Equal(t, want, string(updated))
+}
+
+func TestSetTopLevelKey_ReplacesOnlyTargetValue(t *testing.T) {
+	data := []byte("{\n\t\"a\": 1,\n\t\"x\": [1, 2], // old\n\t\"b\": 2\n}")
+
+	updated, err := setTopLevelKey(data, "x", map[string]int{"k": 3})
+	require.NoError(t, err)
+
+	assert.Equal(t, "{\n\t\"a\": 1,\n\t\"x\": {\n\t\t\"k\": 3\n\t}, // old\n\t\"b\": 2\n}", string(updated))
+}
+
+func TestSetTopLevelKey_Errors(t *testing.T) {
+	for _, data := range []string{`{"a": `, `{"a" 1}`, `{"a": 1`, `{1: 2}`, `"str"`} {
+		_, err := setTopLevelKey([]byte(data), "x", 1)
+		assert.Error(t, err, "input %q", data)
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	in := "{\"a\": 1, // c\n/* b */ \"s\": \"/* keep */\",}"
+	want := "{\"a\": 1,     \n        \"s\": \"/* keep */\" }"
+	assert.Equal(t, want, string(stripJSONC([]byte(in))))
+}
+
+func TestRemoveTopLevelKey(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"middle", "{\n  \"a\": 1,\n  \"x\": {\"k\": 2}, // old\n  \"b\": 2\n}\n", "{\n  \"a\": 1,\n  \"b\": 2\n}\n"},
+		{"last", "{\n  \"a\": 1, // keep\n  \"x\": 2\n}\n", "{\n  \"a\": 1 // keep\n}\n"},
+		{"only", "{\n  \"x\": 2\n}\n", "{\n}\n"},
+		{"one line", `{"a": 1, "x": 2, "b": 3}`, `{"a": 1, "b": 3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, found, err := removeTopLevelKey([]byte(tt.in), "x")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, tt.want, string(updated))
+		})
+	}
+
+	data := []byte(`{"a": 1}`)
+	updated, found, err := removeTopLevelKey(data, "x")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, data, updated)
+}
@@ -0,0 +1,106 @@
+package install
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// versionSegment matches path components like "1.2.3", "v0.4.0", or
+// "claude-status@v1.2.0" that change on every upgrade.
+var versionSegment = regexp.MustCompile(`(^v?\d+\.\d+(\.\d+)?([-+_].*)?$)|(@v?\d+\.\d+)`)
+
+// isVersionedPath reports whether path lives in a directory that package
+// managers replace on upgrade (Homebrew's Cellar, the Nix store, or any
+// version-numbered directory), so referencing it from settings would break.
+func isVersionedPath(path string) bool {
+	if strings.HasPrefix(path, "/nix/store/") {
+		return true
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if segment == "Cellar" || versionSegment.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// StableLinkPath returns ~/.local/bin/claude-status, the symlink offered for
+// installs whose executable path changes on upgrade.
+func StableLinkPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "bin", "claude-status"), nil
+}
+
+// InstallSymlink points linkPath at target, replacing an existing symlink.
+// It refuses to overwrite a regular file.
+func InstallSymlink(linkPath, target string) error {
+	if info, err := os.Lstat(linkPath); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s already exists and is not a symlink", linkPath)
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return fmt.Errorf("failed to replace symlink: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Symlink(target, linkPath); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	return nil
+}
+
+// linksTo reports whether linkPath is a symlink resolving to target.
+func linksTo(linkPath, target string) bool {
+	if info, err := os.Lstat(linkPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(linkPath)
+	if err != nil {
+		return false
+	}
+	want, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return false
+	}
+	return resolved == want
+}
+
+// offerStableLink explains why binaryPath is fragile and offers to reference
+// a stable symlink instead, accepting without asking if yes is set. Returns
+// the link settings should use, or "" if declined. The link itself is left
+// to createStableLink, once the settings change is confirmed.
+func offerStableLink(w io.Writer, r io.Reader, binaryPath string, yes bool) (string, error) {
+	linkPath, err := StableLinkPath()
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(w, "The executable path looks version-specific and may disappear on upgrade:\n  %s\n", binaryPath)
+	accepted := confirm(w, r, fmt.Sprintf("Create %s pointing at it and use that in settings instead?", linkPath), yes)
+	fmt.Fprintln(w)
+	if !accepted {
+		return "", nil
+	}
+	return linkPath, nil
+}
+
+// createStableLink points the linkPath accepted in offerStableLink at
+// binaryPath.
+func createStableLink(w io.Writer, linkPath, binaryPath string) error {
+	if err := InstallSymlink(linkPath, binaryPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Linked %s -> %s\n", linkPath, binaryPath)
+	fmt.Fprintln(w, "After upgrading, re-run -install to repoint the link; settings won't need to change.")
+	return nil
+}
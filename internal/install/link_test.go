@@ -0,0 +1,130 @@
+package install
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVersionedPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/opt/homebrew/Cellar/claude-status/1.2.3/bin/claude-status", true},
+		{"/home/u/.local/share/mise/installs/go/1.22.1/bin/claude-status", true},
+		{"/home/u/go/pkg/mod/github.com/kostyay/claude-status@v0.4.0/claude-status", true},
+		{"/home/u/.asdf/installs/claude-status/v0.4.0-rc1/bin/claude-status", true},
+		{"/nix/store/abc123-claude-status/bin/claude-status", true},
+		{"/opt/homebrew/bin/claude-status", false},
+		{"/home/u/go/bin/claude-status", false},
+		{"/home/u/.local/bin/claude-status", false},
+		{"/home/u/src/claude-status.v2/claude-status", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isVersionedPath(tt.path), tt.path)
+	}
+}
+
+func TestInstallSymlink(t *testing.T) {
+	dir := t.TempDir()
+	linkPath := filepath.Join(dir, "bin", "claude-status")
+
+	require.NoError(t, InstallSymlink(linkPath, "/old/target"))
+	target, err := os.Readlink(linkPath)
+	require.NoError(t, err)
+	assert.Equal(t, "/old/target", target)
+
+	// Re-running repoints an existing symlink
+	require.NoError(t, InstallSymlink(linkPath, "/new/target"))
+	target, err = os.Readlink(linkPath)
+	require.NoError(t, err)
+	assert.Equal(t, "/new/target", target)
+}
+
+func TestInstallSymlink_RefusesRegularFile(t *testing.T) {
+	linkPath := filepath.Join(t.TempDir(), "claude-status")
+	require.NoError(t, os.WriteFile(linkPath, []byte("binary"), 0755))
+
+	err := InstallSymlink(linkPath, "/some/target")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a symlink")
+}
+
+func TestOfferStableLink(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	versioned := "/opt/homebrew/Cellar/claude-status/1.2.3/bin/claude-status"
+
+	t.Run("accepted", func(t *testing.T) {
+		var out bytes.Buffer
+		path, err := offerStableLink(&out, strings.NewReader("y\n"), versioned, false)
+		require.NoError(t, err)
+
+		want := filepath.Join(home, ".local", "bin", "claude-status")
+		assert.Equal(t, want, path)
+		assert.Contains(t, out.String(), "version-specific")
+
+		// Not linked until the settings change is confirmed
+		_, err = os.Lstat(want)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("yes", func(t *testing.T) {
+		var out bytes.Buffer
+		path, err := offerStableLink(&out, strings.NewReader(""), versioned, true)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(home, ".local", "bin", "claude-status"), path)
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		path, err := offerStableLink(&bytes.Buffer{}, strings.NewReader("n\n"), versioned, false)
+		require.NoError(t, err)
+		assert.Empty(t, path)
+	})
+}
+
+func TestCreateStableLink(t *testing.T) {
+	linkPath := filepath.Join(t.TempDir(), "bin", "claude-status")
+	versioned := "/opt/homebrew/Cellar/claude-status/1.2.3/bin/claude-status"
+
+	var out bytes.Buffer
+	require.NoError(t, createStableLink(&out, linkPath, versioned))
+	target, err := os.Readlink(linkPath)
+	require.NoError(t, err)
+	assert.Equal(t, versioned, target)
+	assert.Contains(t, out.String(), "re-run -install")
+}
+
+func TestDryRun_StableLink(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", configDir)
+	linkPath := filepath.Join(home, ".local", "bin", "claude-status")
+
+	// A version-specific binary that exists, so the link can resolve
+	versioned := filepath.Join(t.TempDir(), "Cellar", "claude-status", "1.2.3", "bin", "claude-status")
+	require.NoError(t, os.MkdirAll(filepath.Dir(versioned), 0755))
+	require.NoError(t, os.WriteFile(versioned, []byte("binary"), 0755))
+
+	// Plans the settings -y would write, and the missing link
+	var out bytes.Buffer
+	require.ErrorIs(t, dryRun(&out, versioned, Options{}), ErrChangesPending)
+	assert.Contains(t, out.String(), linkPath)
+	assert.Contains(t, out.String(), "Would link "+linkPath+" -> "+versioned)
+
+	// Up to date once settings use the link and it resolves to the binary
+	after, err := UpdateSettings(nil, linkPath)
+	require.NoError(t, err)
+	require.NoError(t, WriteSettings(GetSettingsPath(), after))
+	require.NoError(t, InstallSymlink(linkPath, versioned))
+	out.Reset()
+	require.NoError(t, dryRun(&out, versioned, Options{}))
+	assert.Empty(t, out.String())
+}
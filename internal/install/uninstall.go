@@ -0,0 +1,184 @@
+package install
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// backupName is the file next to settings.json where install keeps the
+// statusLine of another tool it replaced, for uninstall to restore.
+const backupName = "claude-status-statusline-backup.json"
+
+// backupPath returns the statusLine backup file for the settings at
+// settingsPath, so each Claude Code config dir keeps its own.
+func backupPath(settingsPath string) string {
+	return filepath.Join(filepath.Dir(settingsPath), backupName)
+}
+
+// foreignStatusLine returns the statusLine in the settings file contents if
+// it runs something other than claude-status, or nil.
+func foreignStatusLine(data []byte) (any, error) {
+	statusLine, err := currentStatusLine(data)
+	if err != nil || statusLine == nil {
+		return nil, err
+	}
+	if isOwnStatusLine(statusLine) {
+		return nil, nil
+	}
+	return statusLine, nil
+}
+
+// currentStatusLine returns the statusLine member of the settings file
+// contents, or nil if there is none.
+func currentStatusLine(data []byte) (any, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(stripJSONC(data), &settings); err != nil {
+		return nil, fmt.Errorf("invalid JSON in settings file: %w", err)
+	}
+	return settings["statusLine"], nil
+}
+
+// isOwnStatusLine reports whether a statusLine value runs claude-status, or
+// this binary under another name.
+func isOwnStatusLine(statusLine any) bool {
+	m, _ := statusLine.(map[string]any)
+	command, _ := m["command"].(string)
+	if _, ok := commandBinary(command); ok {
+		return true
+	}
+	exe, err := ExecutablePath()
+	return err == nil && command == exe
+}
+
+// saveBackup writes statusLine to path as JSON.
+func saveBackup(path string, statusLine any) error {
+	data, err := json.MarshalIndent(statusLine, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteSettings(path, append(data, '\n'))
+}
+
+// loadBackup returns the statusLine saved at path, or nil if there is none.
+func loadBackup(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var statusLine any
+	if err := json.Unmarshal(data, &statusLine); err != nil {
+		return nil, fmt.Errorf("invalid statusLine backup %s: %w", path, err)
+	}
+	return statusLine, nil
+}
+
+// uninstallPlan is the settings change an uninstall would make.
+type uninstallPlan struct {
+	settingsPath string // Claude Code settings.json
+	before       []byte // Current settings file contents
+	after        []byte // Settings file contents after uninstall
+	restored     bool   // Whether after has the backed-up statusLine
+	foreign      bool   // Whether statusLine runs something else and is kept
+}
+
+// planUninstall reads the current settings file and computes its contents
+// without claude-status: its statusLine is replaced with the one install
+// backed up, or removed if there is no backup, and its hooks are removed.
+// A statusLine that doesn't run claude-status is left alone.
+func planUninstall() (*uninstallPlan, error) {
+	settingsPath := GetSettingsPath()
+
+	before, err := readSettingsFile(settingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	statusLine, err := currentStatusLine(before)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &uninstallPlan{settingsPath: settingsPath, before: before, after: before}
+	switch {
+	case statusLine == nil:
+	case !isOwnStatusLine(statusLine):
+		p.foreign = true
+	default:
+		backup, err := loadBackup(backupPath(settingsPath))
+		if err != nil {
+			return nil, err
+		}
+		if backup != nil {
+			p.after, err = setTopLevelKey(before, "statusLine", backup)
+			p.restored = true
+		} else {
+			p.after, _, err = removeTopLevelKey(before, "statusLine")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update settings: %w", err)
+		}
+	}
+
+	p.after, err = RemoveHooks(p.after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update hooks: %w", err)
+	}
+	return p, nil
+}
+
+// Uninstall executes the uninstall flow, the reverse of Run: shows the diff
+// removing claude-status from the settings, prompts for confirmation unless
+// opts.Yes is set, and writes settings. A statusLine that install replaced is
+// restored. opts.Hooks is ignored; claude-status hooks are always removed.
+func Uninstall(w io.Writer, r io.Reader, opts Options) error {
+	p, err := planUninstall()
+	if err != nil {
+		return err
+	}
+	if p.foreign {
+		fmt.Fprintln(w, "statusLine doesn't run claude-status; leaving it as is.")
+	}
+	if bytes.Equal(p.before, p.after) {
+		fmt.Fprintf(w, "claude-status is not installed in %s.\n", p.settingsPath)
+		return nil
+	}
+
+	ShowDiff(w, p.settingsPath, p.before, p.after)
+	if !confirm(w, bufio.NewReader(r), "Apply changes?", opts.Yes) {
+		fmt.Fprintln(w, "Uninstall cancelled.")
+		return nil
+	}
+
+	if err := WriteSettings(p.settingsPath, p.after); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	if p.restored {
+		if err := os.Remove(backupPath(p.settingsPath)); err != nil {
+			return fmt.Errorf("restored the previous statusLine, but failed to remove its backup: %w", err)
+		}
+		fmt.Fprintln(w, "Restored the previous statusLine.")
+	}
+	fmt.Fprintln(w, "Successfully uninstalled claude-status.")
+	return nil
+}
+
+// UninstallDryRun prints the settings change uninstall would make as a plain
+// unified diff, without prompting or writing, and returns ErrChangesPending
+// if there is one.
+func UninstallDryRun(w io.Writer) error {
+	p, err := planUninstall()
+	if err != nil {
+		return err
+	}
+	return printDryRun(w, p.settingsPath, p.before, p.after)
+}
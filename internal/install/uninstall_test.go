@@ -0,0 +1,108 @@
+package install
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUninstall_RemovesStatusLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+
+	original := "{\n  \"theme\": \"dark\"\n}\n"
+	installed, err := UpdateSettings([]byte(original), "/usr/local/bin/claude-status")
+	require.NoError(t, err)
+	installed, err = UpdateHooks(installed, "/usr/local/bin/claude-status")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(settingsPath, installed, 0644))
+
+	var output bytes.Buffer
+	require.NoError(t, Uninstall(&output, strings.NewReader("y\n"), Options{}), Options{})
+	assert.Contains(t, output.String(), `-  "statusLine": {`)
+	assert.Contains(t, output.String(), "uninstalled")
+
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data))
+
+	output.Reset()
+	require.NoError(t, Uninstall(&output, strings.NewReader(""), Options{}), Options{})
+	assert.Contains(t, output.String(), "not installed")
+}
+
+func TestUninstall_RestoresBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+
+	original := "{\n  \"statusLine\": {\n    \"type\": \"command\",\n    \"command\": \"~/bin/my-prompt\"\n  }\n}\n"
+	require.NoError(t, os.WriteFile(settingsPath, []byte(original), 0644))
+
+	var output bytes.Buffer
+	require.NoError(t, Run(&output, strings.NewReader("y\n"), Options{}))
+	assert.Contains(t, output.String(), "Saved the previous statusLine")
+	assert.FileExists(t, backupPath(settingsPath))
+
+	output.Reset()
+	require.NoError(t, Uninstall(&output, strings.NewReader("y\n"), Options{}), Options{})
+	assert.Contains(t, output.String(), "Restored the previous statusLine")
+
+	settings, err := ReadSettings(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "~/bin/my-prompt", settings["statusLine"].(map[string]any)["command"])
+	assert.NoFileExists(t, backupPath(settingsPath))
+}
+
+func TestUninstall_CancelAndForeign(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+
+	installed := "{\n  \"statusLine\": {\"type\": \"command\", \"command\": \"/usr/local/bin/claude-status\"}\n}\n"
+	require.NoError(t, os.WriteFile(settingsPath, []byte(installed), 0644))
+
+	var output bytes.Buffer
+	require.NoError(t, Uninstall(&output, strings.NewReader("n\n"), Options{}), Options{})
+	assert.Contains(t, output.String(), "cancelled")
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, installed, string(data))
+
+	// Another tool's statusLine is never removed
+	foreign := "{\n  \"statusLine\": {\"type\": \"command\", \"command\": \"my-prompt\"}\n}\n"
+	require.NoError(t, os.WriteFile(settingsPath, []byte(foreign), 0644))
+	output.Reset()
+	require.NoError(t, Uninstall(&output, strings.NewReader("y\n"), Options{}), Options{})
+	assert.Contains(t, output.String(), "leaving it as is")
+	data, err = os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, foreign, string(data))
+}
+
+func TestUninstall_YesAndDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+	settingsPath := filepath.Join(tmpDir, "settings.json")
+
+	installed := "{\n  \"statusLine\": {\"type\": \"command\", \"command\": \"/usr/local/bin/claude-status\"}\n}\n"
+	require.NoError(t, os.WriteFile(settingsPath, []byte(installed), 0644))
+
+	var output bytes.Buffer
+	require.ErrorIs(t, UninstallDryRun(&output), ErrChangesPending)
+	assert.Contains(t, output.String(), `-  "statusLine"`)
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, installed, string(data))
+
+	require.NoError(t, Uninstall(&bytes.Buffer{}, strings.NewReader(""), Options{Yes: true}))
+	output.Reset()
+	require.NoError(t, UninstallDryRun(&output))
+	assert.Empty(t, output.String())
+}
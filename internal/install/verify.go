@@ -0,0 +1,53 @@
+package install
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// verifyTimeout bounds how long the self-test waits for the status line.
+const verifyTimeout = 10 * time.Second
+
+// samplePayload mimics the JSON Claude Code sends on stdin. The workspace is
+// the temp dir so the self-test needs no git repository or network access.
+func samplePayload() string {
+	return fmt.Sprintf(`{"model": {"id": "claude-sonnet-4-20250514", "display_name": "Sonnet 4"}, "workspace": {"current_dir": %q}, "version": "install-check"}`, os.TempDir())
+}
+
+// Verify runs binaryPath the way Claude Code will, piping a sample payload
+// to stdin, and returns the rendered status line. It fails if the command
+// cannot be started, times out, exits non-zero, or prints nothing.
+func Verify(binaryPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath)
+	cmd.Stdin = strings.NewReader(samplePayload())
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%s did not respond within %s", binaryPath, verifyTimeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s exited with code %d: %s", binaryPath, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("failed to run %s: %w", binaryPath, err)
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	if output == "" {
+		return "", fmt.Errorf("%s printed no status line", binaryPath)
+	}
+	return output, nil
+}
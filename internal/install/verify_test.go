@@ -0,0 +1,86 @@
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary stand in for claude-status: Run verifies the
+// install by executing os.Executable(), which during tests is this binary.
+func TestMain(m *testing.M) {
+	switch os.Getenv("INSTALL_TEST_STATUS_HELPER") {
+	case "ok":
+		input, _ := io.ReadAll(os.Stdin)
+		if !strings.Contains(string(input), `"current_dir"`) {
+			fmt.Fprintln(os.Stderr, "missing payload")
+			os.Exit(1)
+		}
+		fmt.Println("[Sonnet 4] | tmp")
+		os.Exit(0)
+	case "fail":
+		fmt.Println("[Claude] Unknown")
+		fmt.Fprintln(os.Stderr, "invalid status line input")
+		os.Exit(1)
+	case "silent":
+		os.Exit(0)
+	}
+
+	os.Setenv("INSTALL_TEST_STATUS_HELPER", "ok")
+	os.Exit(m.Run())
+}
+
+func TestVerify_Success(t *testing.T) {
+	output, err := Verify(os.Args[0])
+	require.NoError(t, err)
+	assert.Equal(t, "[Sonnet 4] | tmp", output)
+}
+
+func TestVerify_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		helper string
+		want   string
+	}{
+		{"non-zero exit", "fail", "exited with code 1: invalid status line input"},
+		{"no output", "silent", "printed no status line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INSTALL_TEST_STATUS_HELPER", tt.helper)
+
+			_, err := Verify(os.Args[0])
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestVerify_MissingBinary(t *testing.T) {
+	_, err := Verify(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to run")
+}
+
+func TestRun_VerifyFailureReported(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", tmpDir)
+	t.Setenv("INSTALL_TEST_STATUS_HELPER", "fail")
+
+	var output bytes.Buffer
+	err := Run(&output, strings.NewReader("y\n"), Options{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "self-test failed")
+	assert.Contains(t, output.String(), "Verifying")
+	// Settings are still written; the error tells the user the command is broken
+	assert.FileExists(t, filepath.Join(tmpDir, "settings.json"))
+}
@@ -1,11 +1,16 @@
 package kt
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/kostyay/claude-status/internal/tasks"
 )
@@ -54,18 +59,52 @@ type statsJSON struct {
 	Total      int `json:"total"`
 }
 
+// summaryJSON is the JSON response from kt summary --json, which newer kt
+// versions offer to report everything GetStats needs in one call.
+type summaryJSON struct {
+	statsJSON
+	Ready   int `json:"ready"`
+	Blocked int `json:"blocked"`
+}
+
+// noSummary holds the working directories whose kt rejected summary as an
+// unknown command, so kt versions without it aren't asked again for the
+// life of the process (e.g. the daemon).
+var noSummary sync.Map // workDir → struct{}
+
 // ticket represents a kt ticket from kt ready/blocked --json.
 type ticket struct {
 	ID    string `json:"id"`
 	Title string `json:"title"`
 }
 
-// GetStats runs kt commands and returns computed stats.
-func (c *Client) GetStats() (tasks.Stats, error) {
+// GetStats returns computed stats from a single kt summary call, or from kt
+// stats, ready, and blocked run concurrently on kt versions without summary.
+func (c *Client) GetStats(ctx context.Context) (tasks.Stats, error) {
+	if _, ok := noSummary.Load(c.workDir); !ok {
+		stats, err := c.summaryStats(ctx)
+		if err == nil {
+			return stats, nil
+		}
+		slog.Debug("kt summary failed, using kt stats", "err", err)
+		if unknownCommand(err) {
+			noSummary.Store(c.workDir, struct{}{})
+		}
+	}
+
+	var (
+		wg                             sync.WaitGroup
+		output, ready, blocked         []byte
+		statsErr, readyErr, blockedErr error
+	)
+	wg.Go(func() { output, statsErr = c.cmd.Output(ctx, "kt", "stats", "--json") })
+	wg.Go(func() { ready, readyErr = c.cmd.Output(ctx, "kt", "ready", "--json") })
+	wg.Go(func() { blocked, blockedErr = c.cmd.Output(ctx, "kt", "blocked", "--json") })
+	wg.Wait()
+
 	// Get basic stats
-	output, err := c.cmd.Output("kt", "stats", "--json")
-	if err != nil {
-		return tasks.Stats{}, fmt.Errorf("failed to run kt stats: %w", err)
+	if statsErr != nil {
+		return tasks.Stats{}, fmt.Errorf("failed to run kt stats: %w", statsErr)
 	}
 
 	var rawStats statsJSON
@@ -81,19 +120,17 @@ func (c *Client) GetStats() (tasks.Stats, error) {
 	}
 
 	// Get ready count
-	readyOutput, err := c.cmd.Output("kt", "ready", "--json")
-	if err == nil {
+	if readyErr == nil {
 		var readyTickets []ticket
-		if json.Unmarshal(readyOutput, &readyTickets) == nil {
+		if json.Unmarshal(ready, &readyTickets) == nil {
 			stats.ReadyIssues = len(readyTickets)
 		}
 	}
 
 	// Get blocked count
-	blockedOutput, err := c.cmd.Output("kt", "blocked", "--json")
-	if err == nil {
+	if blockedErr == nil {
 		var blockedTickets []ticket
-		if json.Unmarshal(blockedOutput, &blockedTickets) == nil {
+		if json.Unmarshal(blocked, &blockedTickets) == nil {
 			stats.BlockedIssues = len(blockedTickets)
 		}
 	}
@@ -101,9 +138,41 @@ func (c *Client) GetStats() (tasks.Stats, error) {
 	return stats, nil
 }
 
+// unknownCommand reports whether err is kt rejecting a command it doesn't
+// have, rather than a failure (or cancellation) of one it does.
+func unknownCommand(err error) bool {
+	msg := err.Error()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		msg = string(exitErr.Stderr)
+	}
+	return strings.Contains(strings.ToLower(msg), "unknown command")
+}
+
+// summaryStats returns stats from kt summary --json.
+func (c *Client) summaryStats(ctx context.Context) (tasks.Stats, error) {
+	output, err := c.cmd.Output(ctx, "kt", "summary", "--json")
+	if err != nil {
+		return tasks.Stats{}, fmt.Errorf("failed to run kt summary: %w", err)
+	}
+
+	var summary summaryJSON
+	if err := json.Unmarshal(output, &summary); err != nil {
+		return tasks.Stats{}, fmt.Errorf("failed to parse kt summary output: %w", err)
+	}
+	return tasks.Stats{
+		TotalIssues:      summary.Total,
+		OpenIssues:       summary.Open,
+		InProgressIssues: summary.InProgress,
+		ClosedIssues:     summary.Closed,
+		ReadyIssues:      summary.Ready,
+		BlockedIssues:    summary.Blocked,
+	}, nil
+}
+
 // GetNextTask returns the title of the next ready task, or empty if none.
-func (c *Client) GetNextTask() (string, error) {
-	output, err := c.cmd.Output("kt", "ready", "--json")
+func (c *Client) GetNextTask(ctx context.Context) (string, error) {
+	output, err := c.cmd.Output(ctx, "kt", "ready", "--json")
 	if err != nil {
 		return "", nil
 	}
@@ -1,8 +1,10 @@
 package kt
 
 import (
+	"context"
 	"errors"
 	"os"
+	"os/exec"
 	"testing"
 
 	"github.com/kostyay/claude-status/internal/tasks"
@@ -14,7 +16,7 @@ type mockCommander struct {
 	errs    map[string]error
 }
 
-func (m *mockCommander) Output(name string, args ...string) ([]byte, error) {
+func (m *mockCommander) Output(_ context.Context, name string, args ...string) ([]byte, error) {
 	key := name
 	for _, arg := range args {
 		key += " " + arg
@@ -78,7 +80,7 @@ func TestClient_GetStats(t *testing.T) {
 			}
 			client := NewClientWithCommander(cmd, "/test")
 
-			got, err := client.GetStats()
+			got, err := client.GetStats(t.Context())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetStats() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -92,6 +94,69 @@ func TestClient_GetStats(t *testing.T) {
 	}
 }
 
+func TestClient_GetStats_Summary(t *testing.T) {
+	noSummary.Delete("/test")
+	t.Cleanup(func() { noSummary.Delete("/test") })
+
+	cmd := &mockCommander{
+		outputs: map[string][]byte{
+			"kt summary --json": []byte(`{"open": 5, "in_progress": 2, "closed": 3, "total": 10, "ready": 2, "blocked": 1}`),
+		},
+		errs: map[string]error{
+			"kt stats --json": errors.New("not expected with kt summary"),
+		},
+	}
+	client := NewClientWithCommander(cmd, "/test")
+
+	got, err := client.GetStats(t.Context())
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	want := tasks.Stats{TotalIssues: 10, OpenIssues: 5, InProgressIssues: 2, ClosedIssues: 3, ReadyIssues: 2, BlockedIssues: 1}
+	if got != want {
+		t.Errorf("GetStats() = %+v, want %+v", got, want)
+	}
+
+	// A failing or cancelled summary falls back without giving up on it
+	for _, summaryErr := range []error{errors.New("exit status 1"), context.Canceled} {
+		cmd = &mockCommander{
+			outputs: map[string][]byte{
+				"kt stats --json": []byte(`{"open": 1, "total": 1}`),
+			},
+			errs: map[string]error{
+				"kt summary --json": summaryErr,
+			},
+		}
+		client = NewClientWithCommander(cmd, "/test")
+		if got, err := client.GetStats(t.Context()); err != nil || got.OpenIssues != 1 {
+			t.Errorf("GetStats() after summary error %v = %+v, %v; want 1 open", summaryErr, got, err)
+		}
+		if _, ok := noSummary.Load("/test"); ok {
+			t.Errorf("noSummary set after summary error %v", summaryErr)
+		}
+	}
+
+	// Without summary, fall back to the legacy calls and stop asking
+	cmd = &mockCommander{
+		outputs: map[string][]byte{
+			"kt stats --json": []byte(`{"open": 1, "total": 1}`),
+		},
+		errs: map[string]error{
+			"kt summary --json": errors.New("unknown command \"summary\""),
+		},
+	}
+	client = NewClientWithCommander(cmd, "/test")
+	if got, err := client.GetStats(t.Context()); err != nil || got.OpenIssues != 1 {
+		t.Errorf("GetStats() without summary = %+v, %v; want 1 open", got, err)
+	}
+	if _, ok := noSummary.Load("/test"); !ok {
+		t.Error("noSummary not set after kt rejected summary")
+	}
+	if _, ok := noSummary.Load("/other"); ok {
+		t.Error("noSummary set for another directory")
+	}
+}
+
 func TestClient_GetStats_CommandError(t *testing.T) {
 	cmd := &mockCommander{
 		errs: map[string]error{
@@ -100,7 +165,7 @@ func TestClient_GetStats_CommandError(t *testing.T) {
 	}
 	client := NewClientWithCommander(cmd, "/test")
 
-	_, err := client.GetStats()
+	_, err := client.GetStats(t.Context())
 	if err == nil {
 		t.Error("GetStats() expected error for command failure")
 	}
@@ -190,7 +255,7 @@ func TestClient_GetNextTask(t *testing.T) {
 			}
 			client := NewClientWithCommander(cmd, "/test")
 
-			got, err := client.GetNextTask()
+			got, err := client.GetNextTask(t.Context())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetNextTask() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -201,3 +266,14 @@ func TestClient_GetNextTask(t *testing.T) {
 		})
 	}
 }
+
+func TestUnknownCommand(t *testing.T) {
+	_, err := exec.Command("sh", "-c", `echo 'Error: unknown command "summary" for "kt"' >&2; exit 1`).Output()
+	if err == nil || !unknownCommand(err) {
+		t.Errorf("unknownCommand(%v) = false, want true for kt's stderr", err)
+	}
+	_, err = exec.Command("sh", "-c", `echo 'failed to read tickets' >&2; exit 1`).Output()
+	if err == nil || unknownCommand(err) {
+		t.Errorf("unknownCommand(%v) = true, want false for another failure", err)
+	}
+}
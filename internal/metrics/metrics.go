@@ -0,0 +1,96 @@
+// Package metrics writes each render's numbers in the node_exporter textfile
+// collector format, so Prometheus can graph Claude usage without another
+// service running.
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/cistatus"
+	"github.com/kostyay/claude-status/internal/mirror"
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// buildStates are the values of the build_status metric's state label.
+var buildStates = []cistatus.Status{cistatus.Success, cistatus.Failure, cistatus.Pending, cistatus.Error, cistatus.RateLimited}
+
+// labelEscaper escapes label values as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// unsafeFileChars matches what can't go in a session's file name.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// StaleAfter is how long a session's file is kept after its last render.
+// The collector keeps serving a file until it's removed, so ended sessions
+// would otherwise report their last numbers forever.
+const StaleAfter = time.Hour
+
+// Write writes the metrics for one render of session that took elapsed.
+// Sessions render independently, so each gets its own file next to path,
+// with the session ID before the extension (claude-status.prom becomes
+// claude-status.<session>.prom); without a session ID, path itself is
+// written. Files are written atomically, so the collector never reads a
+// partial one, and those of sessions idle for StaleAfter are removed.
+func Write(path, session string, data template.StatusData, elapsed time.Duration) error {
+	if err := mirror.WriteFile(sessionPath(path, session), Encode(session, data, elapsed)); err != nil {
+		return err
+	}
+	removeStale(path)
+	return nil
+}
+
+// sessionPath returns the file session's metrics are written to.
+func sessionPath(path, session string) string {
+	if session == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + unsafeFileChars.ReplaceAllString(session, "_") + ext
+}
+
+// removeStale removes the session files next to path that haven't been
+// written for StaleAfter.
+func removeStale(path string) {
+	ext := filepath.Ext(path)
+	matches, _ := filepath.Glob(strings.TrimSuffix(path, ext) + ".*" + ext)
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && time.Since(info.ModTime()) >= StaleAfter {
+			os.Remove(match)
+		}
+	}
+}
+
+// Encode renders the metrics in the Prometheus text exposition format.
+// Every series is labeled with the session and its model and directory.
+func Encode(session string, data template.StatusData, elapsed time.Duration) []byte {
+	labels := fmt.Sprintf(`session="%s",model="%s",dir="%s"`, labelEscaper.Replace(session), labelEscaper.Replace(data.Model), labelEscaper.Replace(data.Dir))
+
+	var b strings.Builder
+	metric := func(name, typ, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		fmt.Fprintf(&b, "%s{%s} %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	metric("claude_status_render_duration_seconds", "gauge", "How long the last status line render took.", elapsed.Seconds())
+	metric("claude_status_context_percent", "gauge", "Share of the context window in use (0-100).", data.ContextPct)
+	// A gauge: a new session starts again from zero
+	metric("claude_status_session_tokens", "gauge", "Tokens used this session.", float64(data.TokensTotal))
+
+	if data.GitHubBuild != "" {
+		name := "claude_status_build_status"
+		fmt.Fprintf(&b, "# HELP %s CI status of the current branch; the series for the current state is 1.\n# TYPE %s gauge\n", name, name)
+		for _, state := range buildStates {
+			value := 0
+			if string(state) == data.GitHubBuild {
+				value = 1
+			}
+			fmt.Fprintf(&b, "%s{%s,state=\"%s\"} %d\n", name, labels, state, value)
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+func TestEncode(t *testing.T) {
+	data := template.StatusData{
+		Model:       "Opus 4",
+		Dir:         `my "app"`,
+		ContextPct:  42.5,
+		TokensTotal: 156000,
+		GitHubBuild: "failure",
+	}
+
+	got := string(Encode("abc-123", data, 150*time.Millisecond))
+
+	labels := `session="abc-123",model="Opus 4",dir="my \"app\""`
+	for _, want := range []string{
+		"# TYPE claude_status_render_duration_seconds gauge\n",
+		"claude_status_render_duration_seconds{" + labels + "} 0.15\n",
+		"claude_status_context_percent{" + labels + "} 42.5\n",
+		"# TYPE claude_status_session_tokens gauge\n",
+		"claude_status_session_tokens{" + labels + "} 156000\n",
+		"claude_status_build_status{" + labels + `,state="failure"} 1` + "\n",
+		"claude_status_build_status{" + labels + `,state="success"} 0` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Encode() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestEncode_NoBuild(t *testing.T) {
+	got := string(Encode("", template.StatusData{Model: "Opus 4"}, time.Millisecond))
+	if strings.Contains(got, "claude_status_build_status") {
+		t.Errorf("Encode() has a build status without CI:\n%s", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "textfile", "claude.prom")
+	if err := Write(path, "", template.StatusData{Model: "Opus 4"}, time.Second); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "claude_status_render_duration_seconds{") {
+		t.Errorf("metrics file = %q", got)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("textfile dir has %d entries, want only the .prom file", len(entries))
+	}
+}
+
+func TestWrite_PerSession(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "textfile")
+	path := filepath.Join(dir, "claude.prom")
+	for _, session := range []string{"one", "two/../x"} {
+		if err := Write(path, session, template.StatusData{Model: "Opus 4"}, time.Second); err != nil {
+			t.Fatalf("Write(%q) error = %v", session, err)
+		}
+	}
+
+	// Each session keeps its own file, so one render doesn't drop the other's series
+	for _, name := range []string{"claude.one.prom", "claude.two____x.prom"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), `session="`) {
+			t.Errorf("%s = %q, want session-labeled series", name, got)
+		}
+	}
+
+	// A session idle for StaleAfter is removed by the next write
+	stale := time.Now().Add(-StaleAfter - time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "claude.one.prom"), stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(path, "two/../x", template.StatusData{}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "claude.one.prom")); !os.IsNotExist(err) {
+		t.Errorf("stale session file kept: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package mirror copies each rendered status to a file or named pipe, so
+// bars and window managers (polybar, sketchybar) can show the same
+// information as the status line.
+package mirror
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// Formats the mirror can be written in.
+const (
+	FormatText = "text" // The rendered line without ANSI escapes
+	FormatLine = "line" // The rendered line as printed
+	FormatJSON = "json" // The status data
+)
+
+// ansiEscape matches the SGR sequences templates emit for colors and bold.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// StripANSI removes color and style escapes from s.
+func StripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// Write writes the status to path in format, as one newline-terminated
+// line. A regular file is replaced atomically, so readers never see a
+// partial write. A FIFO gets a single write if something is reading it and
+// is skipped otherwise, so a closed bar doesn't block the status line.
+func Write(path, format string, data template.StatusData, output string) error {
+	content, err := encode(format, data, output)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		return writeFIFO(path, content)
+	}
+	return WriteFile(path, content)
+}
+
+// encode renders the mirror content in format.
+func encode(format string, data template.StatusData, output string) ([]byte, error) {
+	switch format {
+	case FormatText, "":
+		return []byte(StripANSI(output) + "\n"), nil
+	case FormatLine:
+		return []byte(output + "\n"), nil
+	case FormatJSON:
+		out, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode status data: %w", err)
+		}
+		return append(out, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown mirror format %q", format)
+	}
+}
+
+// writeFIFO writes content to the named pipe at path without waiting for a
+// reader, or for one that has fallen behind to drain the pipe: the line is
+// dropped instead.
+func writeFIFO(path string, content []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return nil // Nobody is reading
+	}
+	if err != nil {
+		return err
+	}
+	// f.Write would park on the poller until the pipe has room, so write
+	// once on the raw descriptor. Writes up to PIPE_BUF (at least 512 bytes)
+	// are never interleaved.
+	conn, err := f.SyscallConn()
+	if err == nil {
+		ctrlErr := conn.Write(func(fd uintptr) bool {
+			_, err = syscall.Write(int(fd), content)
+			return true
+		})
+		err = cmp.Or(ctrlErr, err)
+	}
+	if errors.Is(err, syscall.EAGAIN) {
+		err = nil // The pipe is full
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteFile replaces the file at path with content through a temp file in
+// the same directory, so readers never see a partial write and concurrent
+// sessions don't clobber each other's temp files.
+func WriteFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package mirror
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+const rendered = "\033[1m\033[38;5;30m[Sonnet 4]\033[0m | main"
+
+func TestWrite_Formats(t *testing.T) {
+	data := template.StatusData{Model: "Sonnet 4", GitBranch: "main"}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "[Sonnet 4] | main\n"},
+		{FormatText, "[Sonnet 4] | main\n"},
+		{FormatLine, rendered + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sub", "status.txt")
+			if err := Write(path, tt.format, data, rendered); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(path); string(got) != tt.want {
+				t.Errorf("mirror = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "status.json")
+	if err := Write(path, FormatJSON, data, rendered); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	var got template.StatusData
+	if err := json.Unmarshal(raw, &got); err != nil || got.Model != "Sonnet 4" || got.GitBranch != "main" {
+		t.Errorf("json mirror = %q (%v), want the status data", raw, err)
+	}
+
+	if err := Write(path, "yaml", data, rendered); err == nil {
+		t.Error("Write() with an unknown format succeeded")
+	}
+}
+
+func TestWrite_ReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.txt")
+	for _, line := range []string{"a much longer first line", "second"} {
+		if err := Write(path, FormatLine, template.StatusData{}, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != "second\n" {
+		t.Errorf("mirror = %q, want only the latest render", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("dir has %d entries, want no temp files left behind", len(entries))
+	}
+}
+
+func TestWrite_FIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+
+	// No reader: skipped without blocking
+	done := make(chan error, 1)
+	go func() { done <- Write(path, FormatText, template.StatusData{}, rendered) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Write() without a reader = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Write() blocked without a reader")
+	}
+
+	// O_RDWR keeps the open from waiting for a writer
+	reader, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if err := Write(path, FormatText, template.StatusData{}, rendered); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil || line != "[Sonnet 4] | main\n" {
+		t.Errorf("fifo read = %q, %v; want the stripped line", line, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("fifo replaced by a regular file: %v", err)
+	}
+}
+
+func TestWrite_FIFOFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	// A reader that never reads
+	reader, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	// Far more than a pipe buffer: once it fills, lines are dropped
+	done := make(chan error, 1)
+	go func() {
+		for range 10000 {
+			if err := Write(path, FormatText, template.StatusData{}, rendered); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Write() to a full pipe = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() blocked on a full pipe")
+	}
+}
@@ -0,0 +1,98 @@
+// Package platform reports the OS and architecture the status line runs on,
+// including whether it is translated by Rosetta or running in a container,
+// and which remote development environment, if any, that container is.
+package platform
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Info describes the runtime platform.
+type Info struct {
+	OS        string // runtime.GOOS, e.g. "darwin"
+	Arch      string // runtime.GOARCH of this process, e.g. "amd64" under Rosetta
+	Rosetta   bool   // Running as an x86_64 process translated by Rosetta 2
+	Container bool   // Running inside a Docker, Podman, or Kubernetes container
+
+	Environment string // Remote development environment (EnvCodespaces, ...); "" on the host
+}
+
+// Environments reported in Info.Environment, most specific first.
+const (
+	EnvCodespaces   = "codespaces"   // GitHub Codespaces
+	EnvGitpod       = "gitpod"       // Gitpod workspace
+	EnvDevcontainer = "devcontainer" // Dev container opened by VS Code or the devcontainer CLI
+	EnvContainer    = "container"    // Any other container
+)
+
+// Detect returns the platform info. It is computed once per process, since
+// none of it can change while running.
+var Detect = sync.OnceValue(func() Info {
+	container := isContainer("/")
+	return Info{
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Rosetta:     isRosetta(),
+		Container:   container,
+		Environment: environment(container),
+	}
+})
+
+// environmentMarkers are env vars set inside each remote development
+// environment, checked in order since Codespaces and Gitpod workspaces are
+// dev containers too.
+var environmentMarkers = []struct {
+	env  string
+	vars []string
+}{
+	{EnvCodespaces, []string{"CODESPACES", "CODESPACE_NAME"}},
+	{EnvGitpod, []string{"GITPOD_WORKSPACE_ID"}},
+	{EnvDevcontainer, []string{"REMOTE_CONTAINERS", "DEVCONTAINER", "DEVCONTAINER_CONFIG_PATH"}},
+}
+
+// environment names the remote development environment from its env vars,
+// falling back to EnvContainer in any other container and "" on the host.
+func environment(container bool) string {
+	for _, m := range environmentMarkers {
+		for _, v := range m.vars {
+			if os.Getenv(v) != "" {
+				return m.env
+			}
+		}
+	}
+	if container {
+		return EnvContainer
+	}
+	return ""
+}
+
+// containerCgroupMarkers appear in /proc/1/cgroup inside common container runtimes.
+var containerCgroupMarkers = []string{"docker", "kubepods", "containerd", "libpod", "lxc"}
+
+// isContainer reports whether the filesystem rooted at root looks like a
+// container: a runtime marker file, the "container" env var set by systemd
+// and Podman, or a container cgroup for PID 1.
+func isContainer(root string) bool {
+	if os.Getenv("container") != "" || os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	for _, marker := range []string{".dockerenv", "run/.containerenv"} {
+		if _, err := os.Stat(root + marker); err == nil {
+			return true
+		}
+	}
+
+	cgroup, err := os.ReadFile(root + "proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	for _, marker := range containerCgroupMarkers {
+		if strings.Contains(string(cgroup), marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	info := Detect()
+	if info.OS != runtime.GOOS || info.Arch != runtime.GOARCH {
+		t.Errorf("Detect() = %+v, want %s/%s", info, runtime.GOOS, runtime.GOARCH)
+	}
+	if runtime.GOOS != "darwin" && info.Rosetta {
+		t.Error("Rosetta should only be reported on macOS")
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	for _, v := range []string{"CODESPACES", "CODESPACE_NAME", "GITPOD_WORKSPACE_ID", "REMOTE_CONTAINERS", "DEVCONTAINER", "DEVCONTAINER_CONFIG_PATH"} {
+		t.Setenv(v, "")
+	}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		container bool
+		want      string
+	}{
+		{"host", nil, false, ""},
+		{"plain container", nil, true, EnvContainer},
+		{"codespaces", map[string]string{"CODESPACES": "true", "REMOTE_CONTAINERS": "true"}, true, EnvCodespaces},
+		{"gitpod", map[string]string{"GITPOD_WORKSPACE_ID": "abc-123"}, true, EnvGitpod},
+		{"vscode dev container", map[string]string{"REMOTE_CONTAINERS": "true"}, true, EnvDevcontainer},
+		{"devcontainer cli", map[string]string{"DEVCONTAINER": "true"}, false, EnvDevcontainer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := environment(tt.container); got != tt.want {
+				t.Errorf("environment(%v) = %q, want %q", tt.container, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsContainer(t *testing.T) {
+	t.Setenv("container", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	writeFile := func(t *testing.T, root, name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, root string)
+		want  bool
+	}{
+		{"bare host", func(t *testing.T, root string) {
+			writeFile(t, root, "proc/1/cgroup", "0::/init.scope\n")
+		}, false},
+		{"dockerenv", func(t *testing.T, root string) {
+			writeFile(t, root, ".dockerenv", "")
+		}, true},
+		{"podman", func(t *testing.T, root string) {
+			writeFile(t, root, "run/.containerenv", "")
+		}, true},
+		{"kubernetes cgroup", func(t *testing.T, root string) {
+			writeFile(t, root, "proc/1/cgroup", "12:memory:/kubepods/besteffort/pod123\n")
+		}, true},
+		{"container env", func(t *testing.T, root string) {
+			t.Setenv("container", "podman")
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir() + "/"
+			tt.setup(t, root)
+			if got := isContainer(root); got != tt.want {
+				t.Errorf("isContainer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
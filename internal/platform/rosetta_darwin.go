@@ -0,0 +1,9 @@
+package platform
+
+import "syscall"
+
+// isRosetta reports whether this process is translated by Rosetta 2.
+func isRosetta() bool {
+	translated, err := syscall.SysctlUint32("sysctl.proc_translated")
+	return err == nil && translated == 1
+}
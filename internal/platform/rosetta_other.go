@@ -0,0 +1,9 @@
+//go:build !darwin
+
+package platform
+
+// isRosetta reports whether this process is translated by Rosetta 2, which
+// only exists on macOS.
+func isRosetta() bool {
+	return false
+}
@@ -0,0 +1,76 @@
+// Package renderhook runs the user's commands around each status line
+// render, so the status can be mirrored elsewhere (LED lights, stream
+// overlays) without changing claude-status. Hooks see the StatusData as JSON
+// on stdin; what they print is discarded, since stdout is the status line.
+package renderhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// Stages a hook runs at, as set in CLAUDE_STATUS_HOOK.
+const (
+	PreRender  = "pre_render"  // After the data is gathered, before the template renders
+	PostRender = "post_render" // After the status line is printed
+)
+
+// DefaultTimeout bounds each hook command when no timeout is configured.
+const DefaultTimeout = time.Second
+
+// Run runs commands in order through sh, each with data as JSON on stdin
+// and CLAUDE_STATUS_HOOK set to stage. For PostRender, CLAUDE_STATUS_OUTPUT
+// holds the rendered line. A command still running after timeout, or when
+// ctx is done, is killed, and the commands after it are skipped. Failures
+// are logged, never returned: a broken hook mustn't break the status line.
+func Run(ctx context.Context, stage string, commands []string, data template.StatusData, output string, timeout time.Duration) {
+	if len(commands) == 0 {
+		return
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Warn("failed to encode status data for hooks", "err", err)
+		return
+	}
+	env := append(os.Environ(), "CLAUDE_STATUS_HOOK="+stage)
+	if stage == PostRender {
+		env = append(env, "CLAUDE_STATUS_OUTPUT="+output)
+	}
+
+	for i, command := range commands {
+		if ctx.Err() != nil {
+			slog.Warn("render hooks ran out of time, skipping the rest", "stage", stage, "skipped", len(commands)-i)
+			return
+		}
+		start := time.Now()
+		if err := runCommand(ctx, command, payload, env, timeout); err != nil {
+			slog.Warn("render hook failed", "stage", stage, "command", command, "err", err)
+			continue
+		}
+		slog.Debug("render hook finished", "stage", stage, "command", command, "elapsed", time.Since(start))
+	}
+}
+
+// runCommand runs command with payload on stdin.
+func runCommand(ctx context.Context, command string, payload []byte, env []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = env
+	// Don't wait on children that outlive the shell and keep stdin open
+	cmd.WaitDelay = 100 * time.Millisecond
+	return cmd.Run()
+}
@@ -0,0 +1,76 @@
+package renderhook
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	stdin := filepath.Join(dir, "stdin.json")
+	env := filepath.Join(dir, "env")
+
+	data := template.StatusData{Model: "Sonnet 4", GitBranch: "main"}
+	Run(t.Context(), PostRender, []string{
+		"cat > " + stdin,
+		"echo printed to the status line", // Discarded
+		"exit 1",                          // Logged; later hooks still run
+		`printf '%s|%s' "$CLAUDE_STATUS_HOOK" "$CLAUDE_STATUS_OUTPUT" > ` + env,
+	}, data, "[Sonnet 4] | main", time.Second)
+
+	raw, err := os.ReadFile(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got template.StatusData
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("hook stdin isn't StatusData JSON: %v", err)
+	}
+	if got.Model != "Sonnet 4" || got.GitBranch != "main" {
+		t.Errorf("hook stdin = %+v, want the status data", got)
+	}
+
+	if raw, err := os.ReadFile(env); err != nil || string(raw) != "post_render|[Sonnet 4] | main" {
+		t.Errorf("hook env = %q, %v; want the stage and output", raw, err)
+	}
+}
+
+func TestRun_PreRenderHasNoOutput(t *testing.T) {
+	env := filepath.Join(t.TempDir(), "env")
+	Run(t.Context(), PreRender, []string{`printf '%s|%s' "$CLAUDE_STATUS_HOOK" "${CLAUDE_STATUS_OUTPUT-unset}" > ` + env},
+		template.StatusData{}, "ignored", time.Second)
+
+	if raw, _ := os.ReadFile(env); strings.TrimSpace(string(raw)) != "pre_render|unset" {
+		t.Errorf("hook env = %q, want pre_render without an output", raw)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	start := time.Now()
+	Run(t.Context(), PreRender, []string{"sleep 5"}, template.StatusData{}, "", 100*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v, want the hook killed at its timeout", elapsed)
+	}
+}
+
+func TestRun_ContextBoundsAllHooks(t *testing.T) {
+	ran := filepath.Join(t.TempDir(), "ran")
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	Run(ctx, PostRender, []string{"sleep 5", "touch " + ran}, template.StatusData{}, "", 10*time.Second)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v, want the hook killed when ctx is done", elapsed)
+	}
+	if _, err := os.Stat(ran); err == nil {
+		t.Error("a hook after ctx was done still ran")
+	}
+}
@@ -0,0 +1,275 @@
+// Package sessions finds the transcripts Claude Code keeps under
+// ~/.claude/projects and archives or deletes old ones, so sessions of
+// projects long gone don't pile up on disk.
+package sessions
+
+import (
+	"archive/tar"
+	"cmp"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/tokens"
+)
+
+// Session is one session's transcript, and the directory Claude Code keeps
+// its subagent transcripts and tool results in, if it has one.
+type Session struct {
+	ID      string
+	Path    string    // The transcript
+	Extra   string    // The session's directory next to the transcript, or ""
+	Size    int64     // Bytes of the transcript and everything under Extra
+	ModTime time.Time // When the transcript was last written
+}
+
+// Project is a directory under ~/.claude/projects: the sessions started in
+// one working directory.
+type Project struct {
+	Dir      string // The project's directory under ~/.claude/projects
+	WorkDir  string // The working directory its sessions ran in, or "" if no transcript records it
+	Missing  bool   // WorkDir no longer exists
+	Sessions []Session
+}
+
+// Size returns the bytes taken by the project's sessions.
+func (p Project) Size() int64 {
+	var size int64
+	for _, s := range p.Sessions {
+		size += s.Size
+	}
+	return size
+}
+
+// LastActive returns when a session of the project was last written, or the
+// zero time if it has none.
+func (p Project) LastActive() time.Time {
+	var last time.Time
+	for _, s := range p.Sessions {
+		if s.ModTime.After(last) {
+			last = s.ModTime
+		}
+	}
+	return last
+}
+
+// Name returns the working directory the project's sessions ran in, falling
+// back to its directory name when no transcript records it.
+func (p Project) Name() string {
+	return cmp.Or(p.WorkDir, filepath.Base(p.Dir))
+}
+
+// Scan returns the projects under claudeDir/projects that have sessions,
+// largest first. A missing projects directory has none.
+func Scan(claudeDir string) ([]Project, error) {
+	root := filepath.Join(claudeDir, "projects")
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+
+	var projects []Project
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		p, err := scanProject(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Sessions) > 0 {
+			projects = append(projects, p)
+		}
+	}
+	slices.SortFunc(projects, func(a, b Project) int {
+		return cmp.Or(cmp.Compare(b.Size(), a.Size()), strings.Compare(a.Dir, b.Dir))
+	})
+	return projects, nil
+}
+
+func scanProject(dir string) (Project, error) {
+	p := Project{Dir: dir}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return p, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".jsonl")
+		if e.IsDir() || !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		s := Session{ID: id, Path: filepath.Join(dir, e.Name()), Size: info.Size(), ModTime: info.ModTime()}
+		if extra := filepath.Join(dir, id); isDir(extra) {
+			s.Extra = extra
+			s.Size += dirSize(extra)
+		}
+		p.Sessions = append(p.Sessions, s)
+	}
+
+	// The newest session is the likeliest to record a working directory, and
+	// the one whose record matters if the project moved.
+	slices.SortFunc(p.Sessions, func(a, b Session) int { return b.ModTime.Compare(a.ModTime) })
+	for _, s := range p.Sessions {
+		if workDir, err := tokens.SessionWorkDir(s.Path); err == nil {
+			p.WorkDir = workDir
+			_, err := os.Stat(workDir)
+			p.Missing = errors.Is(err, fs.ErrNotExist)
+			break
+		}
+	}
+	return p, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// dirSize returns the bytes of the regular files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// Delete removes the session's transcript and its directory.
+func Delete(s Session) error {
+	if s.Extra != "" {
+		if err := os.RemoveAll(s.Extra); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", s.Extra, err)
+		}
+	}
+	if err := os.Remove(s.Path); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", s.Path, err)
+	}
+	return nil
+}
+
+// Archive writes the session's transcript and its directory to
+// archiveDir/<project>/<session>.tar.gz, named as under ~/.claude/projects,
+// and then deletes them. It returns the archive's path.
+func Archive(p Project, s Session, archiveDir string) (string, error) {
+	dir := filepath.Join(archiveDir, filepath.Base(p.Dir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	path := filepath.Join(dir, s.ID+".tar.gz")
+
+	// Write to a temp file and rename, so an interrupted run never leaves a
+	// truncated archive behind a deleted transcript.
+	tmp, err := os.CreateTemp(dir, "."+s.ID+"-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeArchive(tmp, p.Dir, s); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to archive %s: %w", s.Path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to archive %s: %w", s.Path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to archive %s: %w", s.Path, err)
+	}
+	return path, Delete(s)
+}
+
+// writeArchive writes the session's files to w as a gzipped tar, with paths
+// relative to projectDir.
+func writeArchive(w io.Writer, projectDir string, s Session) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	paths := []string{s.Path}
+	if s.Extra != "" {
+		err := filepath.WalkDir(s.Extra, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, path := range paths {
+		if err := addFile(tw, projectDir, path); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, root, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.ToSlash(rel)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// FormatBytes formats a size in a human-readable way.
+// e.g., 1234 -> "1.2 kB", 1234567 -> "1.2 MB"
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
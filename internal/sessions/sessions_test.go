@@ -0,0 +1,176 @@
+package sessions
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeSession writes a transcript recording workDir under
+// claudeDir/projects/project, last written at modTime.
+func writeSession(t *testing.T, claudeDir, project, id, workDir string, modTime time.Time) string {
+	t.Helper()
+	dir := filepath.Join(claudeDir, "projects", project)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, id+".jsonl")
+	content := `{"type":"summary","summary":"Test session"}
+{"type":"user","cwd":"` + workDir + `","message":{"role":"user"}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScan(t *testing.T) {
+	claudeDir := t.TempDir()
+	if projects, err := Scan(claudeDir); err != nil || projects != nil {
+		t.Errorf("Scan() without projects = %v, %v; want nil, nil", projects, err)
+	}
+
+	workDir := t.TempDir()
+	gone := filepath.Join(t.TempDir(), "gone")
+	now := time.Now()
+
+	writeSession(t, claudeDir, "-work", "s1", workDir, now.Add(-time.Hour))
+	writeSession(t, claudeDir, "-work", "s2", workDir, now)
+	writeSession(t, claudeDir, "-gone", "s3", gone, now.Add(-48*time.Hour))
+	extra := filepath.Join(claudeDir, "projects", "-gone", "s3", "subagents")
+	if err := os.MkdirAll(extra, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extra, "agent-1.jsonl"), make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(claudeDir, "projects", "-empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := Scan(claudeDir)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Scan() = %d projects, want 2 (empty projects left out)", len(projects))
+	}
+
+	// Largest first: the subagent transcript counts toward its session
+	gp, wp := projects[0], projects[1]
+	if gp.WorkDir != gone || !gp.Missing {
+		t.Errorf("projects[0] = %q (missing %v), want %q missing", gp.WorkDir, gp.Missing, gone)
+	}
+	if len(gp.Sessions) != 1 || gp.Sessions[0].Extra == "" || gp.Sessions[0].Size < 1000 {
+		t.Errorf("projects[0].Sessions = %+v, want s3 with its directory counted", gp.Sessions)
+	}
+	if wp.WorkDir != workDir || wp.Missing || wp.Name() != workDir {
+		t.Errorf("projects[1] = %q (missing %v), want %q present", wp.WorkDir, wp.Missing, workDir)
+	}
+	if ids := []string{wp.Sessions[0].ID, wp.Sessions[1].ID}; !slices.Equal(ids, []string{"s2", "s1"}) {
+		t.Errorf("projects[1] sessions = %v, want newest first", ids)
+	}
+	if !wp.LastActive().Equal(wp.Sessions[0].ModTime) {
+		t.Errorf("LastActive() = %v, want %v", wp.LastActive(), wp.Sessions[0].ModTime)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	claudeDir := t.TempDir()
+	path := writeSession(t, claudeDir, "-old", "s1", "/old", time.Now())
+	extra := filepath.Join(claudeDir, "projects", "-old", "s1", "tool-results")
+	if err := os.MkdirAll(extra, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extra, "out.txt"), []byte("result"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := Scan(claudeDir)
+	if err != nil || len(projects) != 1 {
+		t.Fatalf("Scan() = %v, %v", projects, err)
+	}
+	archiveDir := t.TempDir()
+	archive, err := Archive(projects[0], projects[0].Sessions[0], archiveDir)
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if want := filepath.Join(archiveDir, "-old", "s1.tar.gz"); archive != want {
+		t.Errorf("Archive() = %q, want %q", archive, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("transcript still exists after Archive(): %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(extra)); !os.IsNotExist(err) {
+		t.Errorf("session directory still exists after Archive(): %v", err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if want := []string{"s1.jsonl", "s1/tool-results/out.txt"}; !slices.Equal(names, want) {
+		t.Errorf("archive holds %v, want %v", names, want)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	claudeDir := t.TempDir()
+	path := writeSession(t, claudeDir, "-old", "s1", "/old", time.Now())
+	extra := filepath.Join(claudeDir, "projects", "-old", "s1")
+	if err := os.MkdirAll(extra, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Delete(Session{ID: "s1", Path: path, Extra: extra}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	for _, p := range []string{path, extra} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Delete(): %v", p, err)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1234, "1.2 kB"},
+		{1_234_567, "1.2 MB"},
+		{5_000_000_000, "5.0 GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.in); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
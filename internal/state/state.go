@@ -0,0 +1,475 @@
+// Package state persists per-repository facts that must outlive the cache,
+// such as when the current branch was checked out. It lives in the data
+// directory, since clearing the cache shouldn't reset them.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// RepoState is what is remembered about one repository.
+type RepoState struct {
+	Branch      string    `json:"branch"`
+	BranchSince time.Time `json:"branch_since"`         // When Branch became the current branch
+	DirtySince  time.Time `json:"dirty_since,omitzero"` // When the working tree was first seen dirty; zero while clean
+}
+
+// TaskState is what is remembered about one task tracker's closed count.
+type TaskState struct {
+	Day      string `json:"day"`      // Local date the baseline is for, as 2006-01-02
+	Baseline int    `json:"baseline"` // Closed count at the start of Day
+	Closed   int    `json:"closed"`   // Closed count when last seen
+}
+
+// SessionState is a repository as a Claude Code session first saw it.
+type SessionState struct {
+	Base      string    `json:"base"`      // HEAD commit when the session started
+	Additions int       `json:"additions"` // Lines already added relative to Base
+	Deletions int       `json:"deletions"` // Lines already deleted relative to Base
+	Started   time.Time `json:"started"`
+}
+
+// sessionMaxAge is how long session snapshots are kept.
+const sessionMaxAge = 30 * 24 * time.Hour
+
+// ContextBuckets is how many equal ranges of usable context ContextUsage
+// counts turns in.
+const ContextBuckets = 10
+
+// ContextUsage is how full the usable context was across the turns of a
+// project's sessions.
+type ContextUsage struct {
+	Buckets [ContextBuckets]int64  `json:"buckets"` // Turns by usable context percentage, in 10% ranges
+	Turns   int64                  `json:"turns"`
+	Peak    float64                `json:"peak"` // Highest usable context percentage seen
+	Updated time.Time              `json:"updated"`
+	Recent  map[string]ContextSeen `json:"recent,omitempty"` // Last sample per session, so a turn rendered again isn't counted twice
+}
+
+// ContextSeen is the last usable context percentage recorded for a session.
+type ContextSeen struct {
+	Pct float64   `json:"pct"`
+	At  time.Time `json:"at"`
+}
+
+// contextRecentMaxAge is how long a session's last sample is remembered.
+const contextRecentMaxAge = 24 * time.Hour
+
+// Percentile returns the upper bound of the range holding the p-th
+// percentile (0-100) of turns, e.g. 50 when the median turn used 40-50% of
+// the usable context. It returns 0 if no turns were recorded.
+func (u ContextUsage) Percentile(p float64) float64 {
+	if u.Turns == 0 {
+		return 0
+	}
+	rank := max(int64(float64(u.Turns)*p/100+0.5), 1)
+	var seen int64
+	for i, n := range u.Buckets {
+		seen += n
+		if seen >= rank {
+			return float64((i + 1) * 100 / ContextBuckets)
+		}
+	}
+	return 100
+}
+
+// ShareAbove returns the fraction (0-1) of turns that used at least pct of
+// the usable context, counting whole ranges (pct is rounded down to one).
+func (u ContextUsage) ShareAbove(pct float64) float64 {
+	if u.Turns == 0 {
+		return 0
+	}
+	var n int64
+	for _, count := range u.Buckets[contextBucket(pct):] {
+		n += count
+	}
+	return float64(n) / float64(u.Turns)
+}
+
+// CostUsage is what one model's answers in one project cost on one day, as
+// estimated from the session transcripts.
+type CostUsage struct {
+	Day     string  `json:"day"` // Local date, YYYY-MM-DD
+	Project string  `json:"project"`
+	Model   string  `json:"model"`
+	Tokens  int64   `json:"tokens"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// ModelUsage is the usage of one model in a session transcript.
+type ModelUsage struct {
+	Tokens  int64   `json:"tokens"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// CostSeen is the usage of a session already counted in Costs.
+type CostSeen struct {
+	Models map[string]ModelUsage `json:"models"`
+	At     time.Time             `json:"at"`
+}
+
+// costMaxAge is how long daily cost entries are kept.
+const costMaxAge = 90 * 24 * time.Hour
+
+// contextBucket returns the Buckets index pct falls in.
+func contextBucket(pct float64) int {
+	return min(max(int(pct)*ContextBuckets/100, 0), ContextBuckets-1)
+}
+
+// File is the structure of the state file on disk.
+type File struct {
+	Repos    map[string]*RepoState    `json:"repos,omitempty"`    // keyed by git dir
+	Tasks    map[string]*TaskState    `json:"tasks,omitempty"`    // keyed by repository or tracker directory
+	Sessions map[string]*SessionState `json:"sessions,omitempty"` // keyed by session ID and git dir
+	Context  map[string]*ContextUsage `json:"context,omitempty"`  // keyed by project directory
+	Costs    []CostUsage              `json:"costs,omitempty"`
+	CostSeen map[string]*CostSeen     `json:"cost_seen,omitempty"` // keyed by session ID
+}
+
+// Store reads and updates the state file. Updates are serialized across
+// processes with a file lock, since concurrent renders share the file.
+type Store struct {
+	path string
+	lock *flock.Flock
+	now  func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return NewStoreWithClock(path, time.Now)
+}
+
+// NewStoreWithClock creates a store with a custom clock.
+func NewStoreWithClock(path string, now func() time.Time) *Store {
+	return &Store{
+		path: path,
+		lock: flock.New(path + ".lock"),
+		now:  now,
+	}
+}
+
+// BranchSince records branch as repo's current branch and returns when it
+// became current. A branch change is dated changedAt (e.g. the mtime of
+// HEAD, which checkout rewrites), or now if changedAt is zero or in the
+// future.
+func (s *Store) BranchSince(repo, branch string, changedAt time.Time) time.Time {
+	var since time.Time
+	s.update(func(f *File) bool {
+		if rs := f.Repos[repo]; rs != nil && rs.Branch == branch {
+			since = rs.BranchSince
+			return false
+		}
+
+		since = s.now()
+		if !changedAt.IsZero() && changedAt.Before(since) {
+			since = changedAt
+		}
+		if f.Repos == nil {
+			f.Repos = make(map[string]*RepoState)
+		}
+		rs := f.Repos[repo]
+		if rs == nil {
+			rs = &RepoState{}
+			f.Repos[repo] = rs
+		}
+		rs.Branch = branch
+		rs.BranchSince = since
+		return true
+	})
+	return since
+}
+
+// DirtySince records whether repo's working tree has uncommitted changes
+// and returns when it was first seen dirty, or the zero time if it is clean.
+// Committing or stashing everything resets the clock.
+func (s *Store) DirtySince(repo string, dirty bool) time.Time {
+	var since time.Time
+	s.update(func(f *File) bool {
+		rs := f.Repos[repo]
+		if !dirty {
+			if rs == nil || rs.DirtySince.IsZero() {
+				return false
+			}
+			rs.DirtySince = time.Time{}
+			return true
+		}
+		if rs != nil && !rs.DirtySince.IsZero() {
+			since = rs.DirtySince
+			return false
+		}
+
+		since = s.now()
+		if f.Repos == nil {
+			f.Repos = make(map[string]*RepoState)
+		}
+		if rs == nil {
+			rs = &RepoState{}
+			f.Repos[repo] = rs
+		}
+		rs.DirtySince = since
+		return true
+	})
+	return since
+}
+
+// ClosedToday records closed as the tracker's current closed-task count
+// and returns how many tasks were closed today, local time. The first count
+// seen on a new day is measured from the last count seen before it, so tasks
+// closed before the first render of the day still count. A count that drops
+// (reopened or deleted tasks) lowers the baseline rather than going negative.
+func (s *Store) ClosedToday(tracker string, closed int) int {
+	today := s.now().Format(time.DateOnly)
+	var count int
+	s.update(func(f *File) bool {
+		ts := f.Tasks[tracker]
+		if ts == nil {
+			if f.Tasks == nil {
+				f.Tasks = make(map[string]*TaskState)
+			}
+			ts = &TaskState{Day: today, Baseline: closed, Closed: closed}
+			f.Tasks[tracker] = ts
+			return true
+		}
+
+		before := *ts
+		if ts.Day != today {
+			ts.Day = today
+			ts.Baseline = ts.Closed
+		}
+		ts.Baseline = min(ts.Baseline, closed)
+		ts.Closed = closed
+		count = ts.Closed - ts.Baseline
+		return *ts != before
+	})
+	return count
+}
+
+// SessionStart returns the snapshot of repo taken when session first saw it,
+// taking one with snapshot if there is none. False means there is no snapshot
+// and snapshot failed. Snapshots older than a month are dropped as new ones
+// are taken. snapshot runs without the lock, which other renders wait on; if
+// another process records one meanwhile, that one is kept.
+func (s *Store) SessionStart(session, repo string, snapshot func() (SessionState, error)) (SessionState, bool) {
+	key := session + ":" + repo
+	if ss := s.load().Sessions[key]; ss != nil {
+		return *ss, true
+	}
+
+	taken, err := snapshot()
+	if err != nil {
+		slog.Debug("failed to snapshot session start", "session", session, "err", err)
+		return SessionState{}, false
+	}
+
+	var start SessionState
+	s.update(func(f *File) bool {
+		if ss := f.Sessions[key]; ss != nil {
+			start = *ss
+			return false
+		}
+
+		now := s.now()
+		taken.Started = now
+		for k, old := range f.Sessions {
+			if now.Sub(old.Started) > sessionMaxAge {
+				delete(f.Sessions, k)
+			}
+		}
+		if f.Sessions == nil {
+			f.Sessions = make(map[string]*SessionState)
+		}
+		f.Sessions[key] = &taken
+		start = taken
+		return true
+	})
+	return start, true
+}
+
+// RecordContext counts a turn of session in project that left pct (0-100)
+// of the usable context in use. A render showing the session's last
+// percentage again is the same turn and isn't counted.
+func (s *Store) RecordContext(project, session string, pct float64) {
+	now := s.now()
+	s.update(func(f *File) bool {
+		cu := f.Context[project]
+		if cu == nil {
+			if f.Context == nil {
+				f.Context = make(map[string]*ContextUsage)
+			}
+			cu = &ContextUsage{}
+			f.Context[project] = cu
+		}
+		if seen, ok := cu.Recent[session]; ok && seen.Pct == pct {
+			return false
+		}
+
+		for k, seen := range cu.Recent {
+			if now.Sub(seen.At) > contextRecentMaxAge {
+				delete(cu.Recent, k)
+			}
+		}
+		if cu.Recent == nil {
+			cu.Recent = make(map[string]ContextSeen)
+		}
+		cu.Recent[session] = ContextSeen{Pct: pct, At: now}
+		cu.Buckets[contextBucket(pct)]++
+		cu.Turns++
+		cu.Peak = max(cu.Peak, pct)
+		cu.Updated = now
+		return true
+	})
+}
+
+// ContextUsage returns the context usage recorded for each project.
+func (s *Store) ContextUsage() map[string]ContextUsage {
+	usage := make(map[string]ContextUsage)
+	for project, cu := range s.load().Context {
+		usage[project] = *cu
+	}
+	return usage
+}
+
+// ResetContext deletes the recorded context usage.
+func (s *Store) ResetContext() {
+	s.update(func(f *File) bool {
+		if f.Context == nil {
+			return false
+		}
+		f.Context = nil
+		return true
+	})
+}
+
+// RecordCost adds what session's transcript used since the last call to
+// today's cost of each model in project. models is the transcript's usage
+// so far by model; the first call for a session counts all of it.
+func (s *Store) RecordCost(project, session string, models map[string]ModelUsage) {
+	now := s.now()
+	day := now.Format(time.DateOnly)
+	s.update(func(f *File) bool {
+		var seen map[string]ModelUsage
+		if prev := f.CostSeen[session]; prev != nil {
+			seen = prev.Models
+		}
+
+		changed := false
+		for model, cur := range models {
+			prev := seen[model]
+			if cur.Tokens <= prev.Tokens {
+				// Nothing new, or a rewritten transcript already counted
+				continue
+			}
+			f.addCost(CostUsage{Day: day, Project: project, Model: model,
+				Tokens: cur.Tokens - prev.Tokens, CostUSD: max(cur.CostUSD-prev.CostUSD, 0)})
+			changed = true
+		}
+		if !changed {
+			return false
+		}
+
+		for k, old := range f.CostSeen {
+			if now.Sub(old.At) > sessionMaxAge {
+				delete(f.CostSeen, k)
+			}
+		}
+		oldest := now.Add(-costMaxAge).Format(time.DateOnly)
+		f.Costs = slices.DeleteFunc(f.Costs, func(c CostUsage) bool { return c.Day < oldest })
+
+		if f.CostSeen == nil {
+			f.CostSeen = make(map[string]*CostSeen)
+		}
+		f.CostSeen[session] = &CostSeen{Models: maps.Clone(models), At: now}
+		return true
+	})
+}
+
+// addCost adds c to the entry for its day, project, and model.
+func (f *File) addCost(c CostUsage) {
+	for i, e := range f.Costs {
+		if e.Day == c.Day && e.Project == c.Project && e.Model == c.Model {
+			f.Costs[i].Tokens += c.Tokens
+			f.Costs[i].CostUSD += c.CostUSD
+			return
+		}
+	}
+	f.Costs = append(f.Costs, c)
+}
+
+// Costs returns the daily cost entries recorded on or after since's date.
+func (s *Store) Costs(since time.Time) []CostUsage {
+	from := since.Format(time.DateOnly)
+	var costs []CostUsage
+	for _, c := range s.load().Costs {
+		if c.Day >= from {
+			costs = append(costs, c)
+		}
+	}
+	return costs
+}
+
+// update loads the state file under the file lock, applies fn, and saves
+// the file if fn reports a change.
+func (s *Store) update(fn func(f *File) bool) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		slog.Warn("failed to create state directory", "err", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	locked, err := s.lock.TryLockContext(ctx, 100*time.Millisecond)
+	if err != nil || !locked {
+		slog.Warn("state lock timeout, proceeding without lock", "err", err)
+	} else {
+		defer s.lock.Unlock()
+	}
+
+	f := s.load()
+	if fn(f) {
+		s.save(f)
+	}
+}
+
+// load reads the state file. A missing or corrupt file yields empty state.
+func (s *Store) load() *File {
+	var f File
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read state file", "path", s.path, "err", err)
+		}
+		return &f
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		slog.Warn("state file corrupted, resetting", "err", err)
+		return &File{}
+	}
+	return &f
+}
+
+// save writes the state file atomically.
+func (s *Store) save(f *File) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		slog.Error("failed to marshal state", "err", err)
+		return
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		slog.Error("failed to write state temp file", "err", err)
+		return
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		slog.Error("failed to rename state file", "err", err)
+		os.Remove(tmpPath)
+	}
+}
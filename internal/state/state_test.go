@@ -0,0 +1,318 @@
+package state
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+func TestBranchSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	// First sight of a branch is dated by when HEAD changed
+	checkout := now.Add(-3 * time.Hour)
+	if got := store.BranchSince("/repo/.git", "feature", checkout); !got.Equal(checkout) {
+		t.Errorf("BranchSince() = %v, want %v", got, checkout)
+	}
+
+	// Later renders keep the original time even as HEAD is rewritten
+	now = now.Add(24 * time.Hour)
+	if got := store.BranchSince("/repo/.git", "feature", now.Add(-time.Minute)); !got.Equal(checkout) {
+		t.Errorf("BranchSince() on same branch = %v, want %v", got, checkout)
+	}
+
+	// Switching branches restarts the clock
+	if got := store.BranchSince("/repo/.git", "main", time.Time{}); !got.Equal(now) {
+		t.Errorf("BranchSince() after switch = %v, want %v", got, now)
+	}
+
+	// Other repositories are tracked separately
+	if got := store.BranchSince("/other/.git", "feature", time.Time{}); !got.Equal(now) {
+		t.Errorf("BranchSince() for another repo = %v, want %v", got, now)
+	}
+
+	// State survives a new store (i.e. a new process)
+	reopened := NewStoreWithClock(path, func() time.Time { return now.Add(time.Hour) })
+	if got := reopened.BranchSince("/repo/.git", "main", time.Time{}); !got.Equal(now) {
+		t.Errorf("BranchSince() after reopen = %v, want %v", got, now)
+	}
+}
+
+func TestBranchSince_FutureChangeTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(filepath.Join(t.TempDir(), "state.json"), func() time.Time { return now })
+
+	// Clock skew must not produce a negative age
+	if got := store.BranchSince("/repo/.git", "feature", now.Add(time.Hour)); !got.Equal(now) {
+		t.Errorf("BranchSince() = %v, want %v", got, now)
+	}
+}
+
+func TestBranchSince_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	if got := store.BranchSince("/repo/.git", "feature", time.Time{}); !got.Equal(now) {
+		t.Errorf("BranchSince() = %v, want %v", got, now)
+	}
+}
+
+func TestDirtySince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	if got := store.DirtySince("/repo/.git", false); !got.IsZero() {
+		t.Errorf("DirtySince() while clean = %v, want zero", got)
+	}
+
+	// The first dirty render starts the clock; later ones keep it
+	start := now
+	if got := store.DirtySince("/repo/.git", true); !got.Equal(start) {
+		t.Errorf("DirtySince() = %v, want %v", got, start)
+	}
+	now = now.Add(3 * time.Hour)
+	if got := store.DirtySince("/repo/.git", true); !got.Equal(start) {
+		t.Errorf("DirtySince() while still dirty = %v, want %v", got, start)
+	}
+
+	// Tracking dirtiness leaves the branch alone, and vice versa
+	store.BranchSince("/repo/.git", "feature", time.Time{})
+	reopened := NewStoreWithClock(path, func() time.Time { return now })
+	if got := reopened.DirtySince("/repo/.git", true); !got.Equal(start) {
+		t.Errorf("DirtySince() after BranchSince = %v, want %v", got, start)
+	}
+
+	// Committing resets it
+	if got := store.DirtySince("/repo/.git", false); !got.IsZero() {
+		t.Errorf("DirtySince() after commit = %v, want zero", got)
+	}
+	now = now.Add(time.Hour)
+	if got := store.DirtySince("/repo/.git", true); !got.Equal(now) {
+		t.Errorf("DirtySince() after new changes = %v, want %v", got, now)
+	}
+}
+
+func TestClosedToday(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.Local)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	// The first count seen is the baseline
+	if got := store.ClosedToday("/repo/.git", 10); got != 0 {
+		t.Errorf("ClosedToday() first = %d, want 0", got)
+	}
+	now = now.Add(3 * time.Hour)
+	if got := store.ClosedToday("/repo/.git", 13); got != 3 {
+		t.Errorf("ClosedToday() = %d, want 3", got)
+	}
+
+	// A reopened task lowers the count, never below zero
+	if got := store.ClosedToday("/repo/.git", 9); got != 0 {
+		t.Errorf("ClosedToday() after reopening = %d, want 0", got)
+	}
+
+	// Tasks closed overnight count toward the new day
+	now = now.Add(24 * time.Hour)
+	reopened := NewStoreWithClock(path, func() time.Time { return now })
+	if got := reopened.ClosedToday("/repo/.git", 11); got != 2 {
+		t.Errorf("ClosedToday() next day = %d, want 2", got)
+	}
+
+	// Other trackers are counted separately
+	if got := reopened.ClosedToday("/other/.git", 50); got != 0 {
+		t.Errorf("ClosedToday() for another tracker = %d, want 0", got)
+	}
+}
+
+func TestSessionStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	snapshots := 0
+	snapshot := func(base string) func() (SessionState, error) {
+		return func() (SessionState, error) {
+			snapshots++
+			return SessionState{Base: base, Additions: 10, Deletions: 2}, nil
+		}
+	}
+
+	got, ok := store.SessionStart("s1", "/repo/.git", snapshot("aaa"))
+	if !ok || got.Base != "aaa" || got.Additions != 10 || !got.Started.Equal(now) {
+		t.Errorf("SessionStart() = %+v, %v; want the new snapshot", got, ok)
+	}
+
+	// Later renders keep the first snapshot
+	if got, _ := store.SessionStart("s1", "/repo/.git", snapshot("bbb")); got.Base != "aaa" {
+		t.Errorf("SessionStart() again = %+v, want base aaa", got)
+	}
+	if snapshots != 1 {
+		t.Errorf("snapshot taken %d times, want 1", snapshots)
+	}
+
+	// Another session or repository gets its own
+	if got, _ := store.SessionStart("s2", "/repo/.git", snapshot("bbb")); got.Base != "bbb" {
+		t.Errorf("SessionStart() for s2 = %+v, want base bbb", got)
+	}
+	if got, _ := store.SessionStart("s1", "/other/.git", snapshot("ccc")); got.Base != "ccc" {
+		t.Errorf("SessionStart() for another repo = %+v, want base ccc", got)
+	}
+
+	// A failed snapshot isn't stored
+	failing := func() (SessionState, error) { return SessionState{}, os.ErrNotExist }
+	if _, ok := store.SessionStart("s3", "/repo/.git", failing); ok {
+		t.Error("SessionStart() with a failing snapshot returned ok")
+	}
+
+	// Old sessions are dropped as new ones start
+	now = now.Add(31 * 24 * time.Hour)
+	store.SessionStart("s4", "/repo/.git", snapshot("ddd"))
+	if got, _ := store.SessionStart("s1", "/repo/.git", snapshot("eee")); got.Base != "eee" {
+		t.Errorf("SessionStart() after a month = %+v, want a new snapshot", got)
+	}
+}
+
+func TestSessionStart_SnapshotsOutsideLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := NewStore(path)
+	other := NewStore(path)
+
+	got, ok := store.SessionStart("s1", "/repo/.git", func() (SessionState, error) {
+		// Other renders aren't held up by the git calls a snapshot makes
+		lock := flock.New(path + ".lock")
+		if locked, err := lock.TryLock(); err != nil || !locked {
+			t.Errorf("state locked while snapshotting: %v, %v", locked, err)
+		} else {
+			lock.Unlock()
+		}
+
+		// Another process records the session first
+		other.SessionStart("s1", "/repo/.git", func() (SessionState, error) { return SessionState{Base: "first"}, nil })
+		return SessionState{Base: "second"}, nil
+	})
+	if !ok || got.Base != "first" {
+		t.Errorf("SessionStart() = %+v, %v; want the snapshot recorded first", got, ok)
+	}
+}
+
+func TestRecordContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	for _, pct := range []float64{12, 45, 45, 83, 91, 100} {
+		store.RecordContext("/repo", "s1", pct)
+	}
+	store.RecordContext("/repo", "s2", 45)
+	store.RecordContext("/other", "s3", 30)
+
+	usage := NewStoreWithClock(path, func() time.Time { return now }).ContextUsage()
+	got := usage["/repo"]
+	// The repeated 45 in s1 is the same turn rendered again; s2's isn't
+	want := [ContextBuckets]int64{1: 1, 4: 2, 8: 1, 9: 2}
+	if got.Buckets != want {
+		t.Errorf("Buckets = %v, want %v", got.Buckets, want)
+	}
+	if got.Turns != 6 || got.Peak != 100 || !got.Updated.Equal(now) {
+		t.Errorf("ContextUsage()[/repo] = %+v, want 6 turns peaking at 100", got)
+	}
+	if usage["/other"].Turns != 1 {
+		t.Errorf("ContextUsage()[/other].Turns = %d, want 1", usage["/other"].Turns)
+	}
+
+	// Sessions idle for a day are forgotten as others record
+	now = now.Add(25 * time.Hour)
+	store.RecordContext("/repo", "s2", 50)
+	if recent := store.ContextUsage()["/repo"].Recent; len(recent) != 1 {
+		t.Errorf("Recent = %v, want only s2", recent)
+	}
+
+	store.ResetContext()
+	if usage := store.ContextUsage(); len(usage) != 0 {
+		t.Errorf("ContextUsage() after reset = %v, want empty", usage)
+	}
+}
+
+func TestContextUsage_Percentile(t *testing.T) {
+	u := ContextUsage{Buckets: [ContextBuckets]int64{1: 5, 4: 3, 8: 1, 9: 1}, Turns: 10}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 20},
+		{50, 20},
+		{60, 50},
+		{90, 90},
+		{100, 100},
+	}
+	for _, tt := range tests {
+		if got := u.Percentile(tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := u.ShareAbove(80); got != 0.2 {
+		t.Errorf("ShareAbove(80) = %v, want 0.2", got)
+	}
+	if got := (ContextUsage{}).Percentile(50); got != 0 {
+		t.Errorf("Percentile() with no turns = %v, want 0", got)
+	}
+}
+
+func TestRecordCost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	// The first render of a session counts everything so far
+	store.RecordCost("/repo", "s1", map[string]ModelUsage{"opus": {Tokens: 1000, CostUSD: 1}})
+	// Later renders count only what was added, per model
+	store.RecordCost("/repo", "s1", map[string]ModelUsage{"opus": {Tokens: 1500, CostUSD: 1.5}, "haiku": {Tokens: 200, CostUSD: 0.01}})
+	store.RecordCost("/repo", "s1", map[string]ModelUsage{"opus": {Tokens: 1500, CostUSD: 1.5}, "haiku": {Tokens: 200, CostUSD: 0.01}})
+	store.RecordCost("/other", "s2", map[string]ModelUsage{"opus": {Tokens: 100, CostUSD: 0.1}})
+
+	// The next day's usage gets its own entry
+	now = now.Add(24 * time.Hour)
+	store.RecordCost("/repo", "s1", map[string]ModelUsage{"opus": {Tokens: 2500, CostUSD: 2.5}, "haiku": {Tokens: 200, CostUSD: 0.01}})
+
+	got := NewStoreWithClock(path, func() time.Time { return now }).Costs(time.Time{})
+	want := []CostUsage{
+		{Day: "2025-06-01", Project: "/repo", Model: "opus", Tokens: 1500, CostUSD: 1.5},
+		{Day: "2025-06-01", Project: "/repo", Model: "haiku", Tokens: 200, CostUSD: 0.01},
+		{Day: "2025-06-01", Project: "/other", Model: "opus", Tokens: 100, CostUSD: 0.1},
+		{Day: "2025-06-02", Project: "/repo", Model: "opus", Tokens: 1000, CostUSD: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Costs() = %+v, want %+v", got, want)
+	}
+	for _, w := range want {
+		if !slices.ContainsFunc(got, func(c CostUsage) bool {
+			return c.Day == w.Day && c.Project == w.Project && c.Model == w.Model && c.Tokens == w.Tokens && math.Abs(c.CostUSD-w.CostUSD) < 1e-9
+		}) {
+			t.Errorf("Costs() = %+v, missing %+v", got, w)
+		}
+	}
+
+	// since filters by day
+	if got := store.Costs(now); len(got) != 1 || got[0].Day != "2025-06-02" {
+		t.Errorf("Costs(today) = %+v, want only today's entry", got)
+	}
+
+	// Entries older than costMaxAge are dropped as new usage comes in
+	now = now.Add(costMaxAge + 24*time.Hour)
+	store.RecordCost("/repo", "s3", map[string]ModelUsage{"opus": {Tokens: 1, CostUSD: 0}})
+	if got := store.Costs(time.Time{}); len(got) != 1 {
+		t.Errorf("Costs() after %v = %+v, want only the new entry", costMaxAge, got)
+	}
+}
@@ -0,0 +1,79 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// inputPreviewLen is how much of the received stdin is echoed back in errors.
+const inputPreviewLen = 200
+
+// expectedInput describes the minimal stdin payload in error messages.
+const expectedInput = `{"model": {"display_name": "..."}, "workspace": {"current_dir": "/path/to/project"}}`
+
+// InputError describes why the JSON received on stdin could not be used.
+// Its message is multi-line and meant to be shown to users debugging their
+// statusLine hook wiring.
+type InputError struct {
+	Problem  string // What was wrong with the input
+	Received []byte // Up to the first 200 bytes of stdin
+	Total    int    // Total number of bytes received
+}
+
+// Error formats the problem together with what was expected and received.
+func (e *InputError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid status line input: %s\n", e.Problem)
+	fmt.Fprintf(&b, "  expected: a JSON object on stdin, e.g. %s\n", expectedInput)
+	if e.Total == 0 {
+		b.WriteString("  received: nothing (is claude-status configured as the statusLine command?)")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "  received (%d bytes): %q", e.Total, e.Received)
+	if e.Total > len(e.Received) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// ParseInput decodes the statusLine JSON payload and checks that required
+// fields are present. Errors are returned as *InputError.
+func ParseInput(data []byte) (Input, error) {
+	var input Input
+
+	newErr := func(problem string) *InputError {
+		return &InputError{
+			Problem:  problem,
+			Received: data[:min(len(data), inputPreviewLen)],
+			Total:    len(data),
+		}
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return input, newErr("empty input")
+	}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return input, newErr(fmt.Sprintf("malformed JSON at byte %d: %v", syntaxErr.Offset, err))
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return input, newErr(fmt.Sprintf("field %q should be %s, got JSON %s", typeErr.Field, typeErr.Type, typeErr.Value))
+		case errors.As(err, &typeErr):
+			return input, newErr(fmt.Sprintf("expected a JSON object, got JSON %s", typeErr.Value))
+		default:
+			return input, newErr(err.Error())
+		}
+	}
+
+	if input.Workspace.CurrentDir == "" {
+		return input, newErr(`missing required field "workspace.current_dir"`)
+	}
+
+	return input, nil
+}
@@ -0,0 +1,76 @@
+package status
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseInput_Valid(t *testing.T) {
+	input, err := ParseInput([]byte(`{"model": {"display_name": "Opus"}, "workspace": {"current_dir": "/tmp/proj", "added_dirs": ["/tmp/lib"]}, "version": "2.0.0"}`))
+	if err != nil {
+		t.Fatalf("ParseInput() error = %v", err)
+	}
+	if input.Model.DisplayName != "Opus" {
+		t.Errorf("Model.DisplayName = %q, want %q", input.Model.DisplayName, "Opus")
+	}
+	if input.Workspace.CurrentDir != "/tmp/proj" {
+		t.Errorf("Workspace.CurrentDir = %q, want %q", input.Workspace.CurrentDir, "/tmp/proj")
+	}
+	if len(input.Workspace.AddedDirs) != 1 || input.Workspace.AddedDirs[0] != "/tmp/lib" {
+		t.Errorf("Workspace.AddedDirs = %v, want [/tmp/lib]", input.Workspace.AddedDirs)
+	}
+}
+
+func TestParseInput_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantProblem string
+		wantMsg     string
+	}{
+		{"empty", "", "empty input", "received: nothing"},
+		{"whitespace", "  \n", "empty input", `received (3 bytes): "  \n"`},
+		{"malformed", "not valid json", "malformed JSON at byte 2", `received (14 bytes): "not valid json"`},
+		{"truncated", `{"model": {`, "malformed JSON", `received (11 bytes)`},
+		{"not an object", `["a"]`, "expected a JSON object, got JSON array", `received (5 bytes)`},
+		{"wrong field type", `{"workspace": {"current_dir": 42}}`, `field "workspace.current_dir" should be string, got JSON number`, ""},
+		{"missing current_dir", `{"model": {"display_name": "Opus"}}`, `missing required field "workspace.current_dir"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseInput([]byte(tt.input))
+			var inputErr *InputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("ParseInput() error = %v, want *InputError", err)
+			}
+			if !strings.HasPrefix(inputErr.Problem, tt.wantProblem) {
+				t.Errorf("Problem = %q, want prefix %q", inputErr.Problem, tt.wantProblem)
+			}
+			msg := err.Error()
+			if !strings.Contains(msg, "expected: a JSON object on stdin") {
+				t.Errorf("Error() missing expected shape:\n%s", msg)
+			}
+			if tt.wantMsg != "" && !strings.Contains(msg, tt.wantMsg) {
+				t.Errorf("Error() = %q, want it to contain %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParseInput_TruncatesPreview(t *testing.T) {
+	data := []byte("x" + strings.Repeat("y", 500))
+
+	_, err := ParseInput(data)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("ParseInput() error = %v, want *InputError", err)
+	}
+	if len(inputErr.Received) != inputPreviewLen {
+		t.Errorf("len(Received) = %d, want %d", len(inputErr.Received), inputPreviewLen)
+	}
+	if !strings.Contains(err.Error(), "received (501 bytes)") || !strings.HasSuffix(err.Error(), `"...`) {
+		t.Errorf("Error() should report total size and mark truncation:\n%s", err.Error())
+	}
+}
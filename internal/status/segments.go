@@ -0,0 +1,297 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/crash"
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// Segment names, as used in the segment_timeouts config.
+const (
+	SegmentTokens      = "tokens"
+	SegmentTasks       = "tasks"
+	SegmentGitBranch   = "git_branch"
+	SegmentGitStatus   = "git_status"
+	SegmentGitUpstream = "git_upstream"
+	SegmentGitDiff     = "git_diff"
+	SegmentGitStash    = "git_stash"
+	SegmentGitCommit   = "git_commit"
+	SegmentGitHub      = "github"
+	SegmentInstall     = "install"
+	SegmentWorkspaces  = "workspaces"
+)
+
+// SegmentGroupGit names the git segments and the GitHub segment that builds
+// on them, so segment_timeouts can give the whole chain one deadline.
+const SegmentGroupGit = "git"
+
+// segmentGroups maps segment names to their group. The tokens and tasks
+// segments are groups of their own.
+var segmentGroups = map[string]string{
+	SegmentGitBranch:   SegmentGroupGit,
+	SegmentGitStatus:   SegmentGroupGit,
+	SegmentGitUpstream: SegmentGroupGit,
+	SegmentGitDiff:     SegmentGroupGit,
+	SegmentGitStash:    SegmentGroupGit,
+	SegmentGitCommit:   SegmentGroupGit,
+	SegmentGitHub:      SegmentGroupGit,
+	SegmentWorkspaces:  SegmentGroupGit,
+}
+
+// segment fills its part of StatusData. Each segment writes into its own
+// StatusData, so segments can run concurrently without sharing state.
+type segment struct {
+	name  string
+	fetch func(ctx context.Context, data *template.StatusData)
+}
+
+// segmentResult is a finished segment's output.
+type segmentResult struct {
+	name    string
+	data    template.StatusData
+	elapsed time.Duration
+	failed  bool
+}
+
+// SegmentTiming is how one segment fared in a render.
+type SegmentTiming struct {
+	Name     string
+	Duration time.Duration // Until the segment finished or was given up on
+	Failed   bool          // Missed its deadline, panicked, or still running at the render deadline
+}
+
+// segments returns the segments to fetch for bs.
+func (b *Builder) segments(bs *build) []segment {
+	input := bs.input
+	segs := []segment{
+		{SegmentTokens, func(_ context.Context, data *template.StatusData) { b.populateTokenMetrics(data, input) }},
+		{SegmentTasks, func(ctx context.Context, data *template.StatusData) { b.fetchTaskStats(ctx, data, bs) }},
+	}
+	if b.settingsPath != "" {
+		segs = append(segs, segment{SegmentInstall, func(_ context.Context, data *template.StatusData) { b.checkInstall(data) }})
+	}
+	if len(input.Workspace.AddedDirs) > 0 && b.openRepo != nil {
+		segs = append(segs, segment{SegmentWorkspaces, func(ctx context.Context, data *template.StatusData) {
+			b.fetchWorkspaces(ctx, data, input.Workspace)
+		}})
+	}
+	if b.git == nil {
+		return segs
+	}
+
+	currentBranch := func(ctx context.Context) (string, error) {
+		return b.cache.GetGitBranch(b.git.HeadPath(), func() (string, error) { return b.git.Branch(ctx) })
+	}
+	return append(segs,
+		segment{SegmentGitBranch, func(ctx context.Context, data *template.StatusData) {
+			data.GitOperation = b.git.Operation()
+			branch, err := currentBranch(ctx)
+			if err == nil && branch != "" {
+				data.GitBranch = branch
+				if branch == "HEAD" {
+					data.GitDetached = true
+					if name, err := b.cache.GetGitHeadName(b.git.HeadPath(), func() (string, error) { return b.git.DetachedName(ctx) }); err == nil && name != "" {
+						data.GitBranch = name
+					}
+				}
+				// Mid-rebase or detached, HEAD isn't a branch switch worth dating
+				if !data.GitDetached && data.GitOperation == "" {
+					b.populateBranchAge(data, branch)
+				}
+			}
+		}},
+		segment{SegmentGitStatus, func(ctx context.Context, data *template.StatusData) {
+			status, err := b.cache.GetGitStatus(b.git.IndexPath(), func() (string, error) { return b.git.Status(ctx) })
+			if err != nil {
+				return
+			}
+			data.GitStatus = status
+			b.populateDirtySince(data, status != "")
+		}},
+		segment{SegmentGitUpstream, func(ctx context.Context, data *template.StatusData) {
+			branch, err := currentBranch(ctx)
+			if err != nil || branch == "" {
+				return
+			}
+			upstream, err := b.cache.GetGitUpstream(b.git.RefPath(branch), b.git.FetchHeadPath(), func() (git.UpstreamStatus, error) {
+				return b.git.AheadBehind(ctx)
+			})
+			if err != nil {
+				slog.Debug("no upstream counts", "branch", branch, "err", err)
+				return
+			}
+			data.GitAhead = upstream.Ahead
+			data.GitBehind = upstream.Behind
+			data.ForcePushNeeded = upstream.Diverged()
+		}},
+		segment{SegmentGitDiff, func(ctx context.Context, data *template.StatusData) {
+			diffStats, err := b.cache.GetGitDiffStats(b.git.IndexPath(), func() (git.DiffStats, error) { return b.git.DiffStats(ctx) })
+			if err == nil {
+				b.populateDiffStats(data, diffStats)
+			}
+			b.populateSessionDiff(ctx, data, input.SessionID)
+		}},
+		segment{SegmentGitStash, func(ctx context.Context, data *template.StatusData) {
+			count, err := b.cache.GetGitStashCount(b.git.StashLogPath(), func() (int, error) { return b.git.StashCount(ctx) })
+			if err == nil {
+				data.GitStash = count
+			}
+		}},
+		segment{SegmentGitCommit, func(ctx context.Context, data *template.StatusData) {
+			branch, err := currentBranch(ctx)
+			if err != nil {
+				return
+			}
+			refPath := ""
+			if branch != "" && branch != "HEAD" { // A detached HEAD has no branch ref
+				refPath = b.git.RefPath(branch)
+			}
+			commit, err := b.cache.GetGitLastCommit(b.git.HeadPath(), refPath, func() (git.Commit, error) { return b.git.LastCommit(ctx) })
+			if err != nil {
+				slog.Debug("no last commit", "err", err)
+				return
+			}
+			b.populateLastCommit(data, commit)
+			b.populateSessionCommits(ctx, data, input.TranscriptPath, refPath)
+		}},
+		segment{SegmentGitHub, func(ctx context.Context, data *template.StatusData) {
+			// Looked up again rather than waiting on the git_branch segment;
+			// the branch is cached, so this is cheap
+			branch, err := currentBranch(ctx)
+			if err == nil && branch != "" {
+				b.fetchGitHubStatus(ctx, data, branch, bs.revalidate)
+			}
+		}},
+	)
+}
+
+// fetchSegments runs segs concurrently and merges their results into data.
+// It returns each segment's timing.
+//
+// A segment's timeout in segment_timeouts is a soft deadline: past it, the
+// segment is left out of the status line, but its fetch keeps going so the
+// result is cached for the next render (see Wait). The render deadline is
+// hard: then the line renders with whatever finished, and fetches still
+// running are cancelled so their git and API calls stop, as they are when
+// ctx is cancelled.
+func (b *Builder) fetchSegments(ctx context.Context, data *template.StatusData, segs []segment) []SegmentTiming {
+	var cancel context.CancelFunc
+	var deadline <-chan time.Time
+	if b.config.RenderDeadline > 0 {
+		renderDeadline := time.Duration(b.config.RenderDeadline) * time.Millisecond
+		ctx, cancel = context.WithTimeout(ctx, renderDeadline)
+		timer := time.NewTimer(renderDeadline)
+		defer timer.Stop()
+		deadline = timer.C
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	start := time.Now()
+	results := make(chan segmentResult, len(segs))
+	var fetches sync.WaitGroup
+	for _, seg := range segs {
+		done := make(chan segmentResult, 1)
+		fetches.Go(func() {
+			data, ok := fetchSafely(ctx, seg)
+			done <- segmentResult{name: seg.name, data: data, elapsed: time.Since(start), failed: !ok}
+		})
+		go b.awaitSegment(seg, start, done, results)
+	}
+	b.inflight.Add(1)
+	go func() {
+		defer b.inflight.Done()
+		fetches.Wait()
+		cancel()
+	}()
+
+	timings := make([]SegmentTiming, 0, len(segs))
+	finished := make(map[string]bool, len(segs))
+	for pending := len(segs); pending > 0; pending-- {
+		select {
+		case res := <-results:
+			mergeStatusData(data, res.data)
+			finished[res.name] = true
+			timings = append(timings, SegmentTiming{Name: res.name, Duration: res.elapsed, Failed: res.failed})
+		case <-deadline:
+			slog.Debug("render deadline exceeded, rendering partial status", "pending", pending)
+			for _, seg := range segs {
+				if !finished[seg.name] {
+					timings = append(timings, SegmentTiming{Name: seg.name, Duration: time.Since(start), Failed: true})
+				}
+			}
+			return timings
+		}
+	}
+	return timings
+}
+
+// Wait blocks until the fetches of earlier builds finish, including those
+// that missed their soft deadline, so a one-shot render can flush their
+// results to the cache before exiting. The render deadline bounds the wait.
+func (b *Builder) Wait() {
+	b.inflight.Wait()
+}
+
+// awaitSegment forwards seg's result from done, unless the segment's soft
+// deadline passes first. Either way exactly one result is sent.
+func (b *Builder) awaitSegment(seg segment, start time.Time, done <-chan segmentResult, results chan<- segmentResult) {
+	timeout := b.segmentTimeout(seg.name)
+	if timeout <= 0 {
+		results <- <-done
+		return
+	}
+
+	timer := time.NewTimer(timeout - time.Since(start))
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		results <- res
+	case <-timer.C:
+		slog.Debug("segment missed its deadline, rendering without it", "segment", seg.name, "timeout", timeout)
+		results <- segmentResult{name: seg.name, elapsed: time.Since(start), failed: true}
+	}
+}
+
+// segmentTimeout returns the soft deadline for the named segment: its own
+// entry in segment_timeouts, or else its group's. 0 means none.
+func (b *Builder) segmentTimeout(name string) time.Duration {
+	ms, ok := b.config.SegmentTimeouts[name]
+	if !ok {
+		ms = b.config.SegmentTimeouts[segmentGroups[name]]
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// fetchSafely fetches seg, recovering from a panic so that one broken
+// provider leaves only its own segment blank. ok is false after a panic,
+// which is reported to config.CrashDir.
+func fetchSafely(ctx context.Context, seg segment) (data template.StatusData, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			crash.Panic(config.CrashDir(), fmt.Sprintf("segment %s: %v", seg.name, r))
+			data, ok = template.StatusData{}, false
+		}
+	}()
+	seg.fetch(ctx, &data)
+	return data, true
+}
+
+// mergeStatusData copies the non-zero fields of src into dst.
+func mergeStatusData(dst *template.StatusData, src template.StatusData) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src)
+	for i := range sv.NumField() {
+		if f := sv.Field(i); !f.IsZero() {
+			dv.Field(i).Set(f)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/kostyay/claude-status/internal/cache"
+	"github.com/kostyay/claude-status/internal/config"
+	"github.com/kostyay/claude-status/internal/crash"
+	"github.com/kostyay/claude-status/internal/git"
+	"github.com/kostyay/claude-status/internal/github"
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// slowGitHubProvider blocks GetBuildStatus until release is closed or its
+// context is cancelled, which closes cancelled.
+type slowGitHubProvider struct {
+	mockGitHubProvider
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func (m *slowGitHubProvider) GetBuildStatus(ctx context.Context, owner, repo, branch string) (github.BuildStatus, error) {
+	select {
+	case <-m.release:
+		return github.StatusSuccess, nil
+	case <-ctx.Done():
+		close(m.cancelled)
+		return "", ctx.Err()
+	}
+}
+
+func slowGitHubBuilder(t *testing.T, cfg config.Config) (*Builder, *slowGitHubProvider) {
+	t.Helper()
+	gh := &slowGitHubProvider{release: make(chan struct{}), cancelled: make(chan struct{})}
+	t.Cleanup(func() { close(gh.release) })
+
+	gitMock := &mockGitProvider{
+		branch:    "main",
+		status:    "±",
+		diffStats: git.DiffStats{Additions: 3},
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+	cache := &mockCacheProvider{fetchBranch: true, fetchStatus: true, fetchDiffStats: true, fetchBuild: true}
+	return NewBuilderWithDeps(&cfg, cache, gitMock, gh, nil, ""), gh
+}
+
+func TestBuild_SegmentTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.SegmentTimeouts = map[string]int{SegmentGitHub: 20}
+	builder, _ := slowGitHubBuilder(t, cfg)
+
+	start := time.Now()
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Build() took %v, want it to give up on the slow segment", elapsed)
+	}
+
+	if data.GitHubStatus != "" {
+		t.Errorf("GitHubStatus = %q, want empty (timed out)", data.GitHubStatus)
+	}
+	if data.GitBranch != "main" || data.GitStatus != "±" || data.GitAdditions != 3 {
+		t.Errorf("fast segments missing: branch=%q status=%q additions=%d", data.GitBranch, data.GitStatus, data.GitAdditions)
+	}
+}
+
+func TestBuild_RenderDeadline(t *testing.T) {
+	cfg := config.Default()
+	cfg.RenderDeadline = 50
+	builder, gh := slowGitHubBuilder(t, cfg)
+
+	start := time.Now()
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Build() took %v, want it to stop at the render deadline", elapsed)
+	}
+
+	if data.GitHubStatus != "" {
+		t.Errorf("GitHubStatus = %q, want empty (past deadline)", data.GitHubStatus)
+	}
+	if data.GitBranch != "main" {
+		t.Errorf("GitBranch = %q, want %q", data.GitBranch, "main")
+	}
+
+	// The abandoned API call is cancelled rather than left running
+	select {
+	case <-gh.cancelled:
+	case <-time.After(time.Second):
+		t.Error("slow GitHub call wasn't cancelled at the render deadline")
+	}
+}
+
+func TestBuild_SoftDeadline(t *testing.T) {
+	cfg := config.Default()
+	cfg.SegmentTimeouts = map[string]int{SegmentGroupGit: 20}
+	cfg.RenderDeadline = 300
+	builder, gh := slowGitHubBuilder(t, cfg)
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.GitHubStatus != "" {
+		t.Errorf("GitHubStatus = %q, want empty (past its group's deadline)", data.GitHubStatus)
+	}
+
+	// Past its soft deadline the call keeps going, so it can still be
+	// cached, until the render deadline
+	select {
+	case <-gh.cancelled:
+		t.Fatal("slow GitHub call was cancelled at its soft deadline")
+	default:
+	}
+	select {
+	case <-gh.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("slow GitHub call wasn't cancelled at the render deadline")
+	}
+}
+
+func TestBuild_SoftDeadlineCachesResult(t *testing.T) {
+	cfg := config.Default()
+	cfg.SegmentTimeouts = map[string]int{SegmentGitHub: 20}
+	cfg.RenderDeadline = 5000
+	gitMock := &mockGitProvider{branch: "main", remoteURL: "git@github.com:owner/repo.git", gitDir: "/repo/.git"}
+	gh := &slowGitHubProvider{release: make(chan struct{}), cancelled: make(chan struct{})}
+	input := Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}}
+
+	dir := t.TempDir()
+	manager := cache.NewManager(dir)
+	manager.Batch()
+	builder := NewBuilderWithDeps(&cfg, manager, gitMock, gh, nil, "")
+
+	data := builder.Build(t.Context(), input)
+	if data.GitHubStatus != "" {
+		t.Fatalf("GitHubStatus = %q, want empty (past its soft deadline)", data.GitHubStatus)
+	}
+
+	close(gh.release)
+	builder.Wait()
+	manager.Flush()
+	select {
+	case <-gh.cancelled:
+		t.Fatal("slow GitHub call was cancelled at its soft deadline")
+	default:
+	}
+
+	// The next render finds the late result without asking GitHub again
+	next := NewBuilderWithDeps(&cfg, cache.NewManager(dir), gitMock, &mockGitHubProvider{status: github.StatusFailure}, nil, "")
+	if data := next.Build(t.Context(), input); data.GitHubStatus != "✅" {
+		t.Errorf("GitHubStatus = %q, want the cached ✅", data.GitHubStatus)
+	}
+}
+
+func TestBuild_CancelStopsSoftTimedOutFetches(t *testing.T) {
+	cfg := config.Default()
+	cfg.SegmentTimeouts = map[string]int{SegmentGitHub: 20}
+	builder, gh := slowGitHubBuilder(t, cfg)
+
+	// Past its soft deadline the fetch still answers to the caller's
+	// context, e.g. on SIGTERM or daemon shutdown
+	ctx, cancel := context.WithCancel(t.Context())
+	builder.Build(ctx, Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	cancel()
+	select {
+	case <-gh.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("slow GitHub call wasn't cancelled with its context")
+	}
+	builder.Wait()
+}
+
+func TestSegmentTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.SegmentTimeouts = map[string]int{SegmentGroupGit: 500, SegmentGitHub: 800, SegmentGitStash: 0}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, nil, nil, nil, "")
+
+	tests := []struct {
+		segment string
+		want    time.Duration
+	}{
+		{SegmentGitHub, 800 * time.Millisecond},    // Its own entry wins
+		{SegmentGitStatus, 500 * time.Millisecond}, // Falls back to its group
+		{SegmentGitStash, 0},                       // 0 lifts the group's deadline
+		{SegmentTokens, 0},                         // No entry for it or its group
+	}
+	for _, tt := range tests {
+		if got := builder.segmentTimeout(tt.segment); got != tt.want {
+			t.Errorf("segmentTimeout(%q) = %v, want %v", tt.segment, got, tt.want)
+		}
+	}
+}
+
+func TestBuildWithTimings(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  func(*config.Config)
+	}{
+		{"segment timeout", func(cfg *config.Config) { cfg.SegmentTimeouts = map[string]int{SegmentGitHub: 20} }},
+		{"render deadline", func(cfg *config.Config) { cfg.RenderDeadline = 50 }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			tt.cfg(&cfg)
+			builder, _ := slowGitHubBuilder(t, cfg)
+
+			_, timings := builder.BuildWithTimings(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+
+			failed := make(map[string]bool)
+			for _, timing := range timings {
+				failed[timing.Name] = timing.Failed
+			}
+			if len(failed) != len(timings) || !failed[SegmentGitHub] {
+				t.Errorf("timings = %+v, want one per segment with github failed", timings)
+			}
+			if failed[SegmentGitBranch] {
+				t.Errorf("timings = %+v, want git_branch to succeed", timings)
+			}
+		})
+	}
+}
+
+// panickingGitHubProvider panics instead of returning a build status.
+type panickingGitHubProvider struct {
+	mockGitHubProvider
+}
+
+func (m *panickingGitHubProvider) GetBuildStatus(_ context.Context, owner, repo, branch string) (github.BuildStatus, error) {
+	panic("boom")
+}
+
+func TestBuild_SegmentPanic(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	cfg := config.Default()
+	gitMock := &mockGitProvider{branch: "main", remoteURL: "git@github.com:owner/repo.git", gitDir: "/repo/.git"}
+	cache := &mockCacheProvider{fetchBranch: true, fetchBuild: true}
+	builder := NewBuilderWithDeps(&cfg, cache, gitMock, &panickingGitHubProvider{}, nil, "")
+
+	data, timings := builder.BuildWithTimings(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.GitBranch != "main" || data.GitHubStatus != "" {
+		t.Errorf("branch=%q github=%q, want the branch without CI status", data.GitBranch, data.GitHubStatus)
+	}
+	for _, timing := range timings {
+		if timing.Failed != (timing.Name == SegmentGitHub) {
+			t.Errorf("timing %+v, want only github failed", timing)
+		}
+	}
+	if reports := crash.Reports(config.CrashDir()); len(reports) != 1 {
+		t.Errorf("crash reports = %v, want one", reports)
+	}
+}
+
+func TestMergeStatusData(t *testing.T) {
+	dst := template.StatusData{Model: "Opus", GitBranch: "main"}
+	mergeStatusData(&dst, template.StatusData{GitStatus: "±", TasksReady: 2})
+
+	if dst.Model != "Opus" || dst.GitBranch != "main" {
+		t.Errorf("existing fields overwritten: %+v", dst)
+	}
+	if dst.GitStatus != "±" || dst.TasksReady != 2 {
+		t.Errorf("merged fields missing: GitStatus=%q TasksReady=%d", dst.GitStatus, dst.TasksReady)
+	}
+}
@@ -1,15 +1,28 @@
 package status
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"log/slog"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/kostyay/claude-status/internal/cache"
+	"github.com/kostyay/claude-status/internal/cistatus"
 	"github.com/kostyay/claude-status/internal/config"
 	"github.com/kostyay/claude-status/internal/git"
 	"github.com/kostyay/claude-status/internal/github"
+	"github.com/kostyay/claude-status/internal/httpclient"
+	"github.com/kostyay/claude-status/internal/install"
+	"github.com/kostyay/claude-status/internal/platform"
+	"github.com/kostyay/claude-status/internal/state"
 	"github.com/kostyay/claude-status/internal/tasks"
 	"github.com/kostyay/claude-status/internal/template"
 	"github.com/kostyay/claude-status/internal/tokens"
@@ -22,11 +35,28 @@ import (
 
 // Input represents the JSON input from stdin.
 type Input struct {
-	Model          ModelInfo     `json:"model"`
-	Workspace      WorkspaceInfo `json:"workspace"`
-	Version        string        `json:"version"`
-	SessionID      string        `json:"session_id"`
-	TranscriptPath string        `json:"transcript_path"`
+	Model             ModelInfo     `json:"model"`
+	Workspace         WorkspaceInfo `json:"workspace"`
+	Version           string        `json:"version"`
+	SessionID         string        `json:"session_id"`
+	TranscriptPath    string        `json:"transcript_path"`
+	Cost              CostInfo      `json:"cost"`
+	Exceeds200kTokens bool          `json:"exceeds_200k_tokens"` // Context is past 200k tokens, where long-context pricing starts
+	OutputStyle       NamedInfo     `json:"output_style"`        // Active output style, e.g. "Explanatory"
+	Agent             NamedInfo     `json:"agent"`               // Agent the session runs as (--agent), if any
+}
+
+// NamedInfo is an input object identified by its name.
+type NamedInfo struct {
+	Name string `json:"name"`
+}
+
+// CostInfo is Claude Code's own accounting for the session so far. Fields
+// are zero when an older Claude Code doesn't send them.
+type CostInfo struct {
+	TotalCostUSD       float64 `json:"total_cost_usd"`
+	TotalDurationMS    int64   `json:"total_duration_ms"`     // Wall-clock time since the session started
+	TotalAPIDurationMS int64   `json:"total_api_duration_ms"` // Time spent waiting on the API
 }
 
 // ModelInfo contains information about the model.
@@ -37,34 +67,73 @@ type ModelInfo struct {
 
 // WorkspaceInfo contains workspace information.
 type WorkspaceInfo struct {
-	CurrentDir string `json:"current_dir"`
+	CurrentDir string   `json:"current_dir"`
+	ProjectDir string   `json:"project_dir"` // Where Claude Code was started; bounds the task tracker search
+	AddedDirs  []string `json:"added_dirs"`  // Additional working directories (--add-dir, /add-dir)
 }
 
 // GitProvider is an interface for git operations.
 type GitProvider interface {
-	Branch() (string, error)
-	Status() (string, error)
-	DiffStats() (git.DiffStats, error)
-	RemoteURL() (string, error)
+	Branch(ctx context.Context) (string, error)
+	Status(ctx context.Context) (string, error)
+	Summary(ctx context.Context) (git.Summary, error)
+	StashCount(ctx context.Context) (int, error)
+	DiffStats(ctx context.Context) (git.DiffStats, error)
+	AheadBehind(ctx context.Context) (git.UpstreamStatus, error)
+	RemoteURL(ctx context.Context) (string, error)
+	LastCommit(ctx context.Context) (git.Commit, error)
+	HeadSHA(ctx context.Context) (string, error)
+	DiffSince(ctx context.Context, rev string) (git.LineStats, error)
+	CommitsSince(ctx context.Context, t time.Time) (int, error)
+	Operation() string
+	DetachedName(ctx context.Context) (string, error)
 	GitDir() string
 	HeadPath() string
 	IndexPath() string
+	FetchHeadPath() string
+	StashLogPath() string
 	RefPath(branch string) string
 }
 
 // GitHubProvider is an interface for GitHub operations.
 type GitHubProvider interface {
-	GetBuildStatus(owner, repo, branch string) (github.BuildStatus, error)
+	GetBuildStatus(ctx context.Context, owner, repo, branch string) (github.BuildStatus, error)
+	GetFailedJob(ctx context.Context, owner, repo, branch string) (github.FailedJob, error)
+	GetChecksSummary(ctx context.Context, owner, repo, branch string) (github.ChecksSummary, error)
+	GetPullRequest(ctx context.Context, owner, repo, branch string) (github.PullRequest, error)
+	GetDefaultBranchStatus(ctx context.Context, owner, repo string) (github.BranchStatus, error)
+	GetActionsUsage(ctx context.Context, owner, repo string) (github.ActionsUsage, error)
+	GetWorkflowStatuses(ctx context.Context, owner, repo, branch string) ([]github.WorkflowStatus, error)
 }
 
 // CacheProvider is an interface for cache operations.
 type CacheProvider interface {
 	GetGitBranch(headPath string, fetchFn func() (string, error)) (string, error)
 	GetGitStatus(indexPath string, fetchFn func() (string, error)) (string, error)
+	GetGitUpstream(refPath, fetchHeadPath string, fetchFn func() (git.UpstreamStatus, error)) (git.UpstreamStatus, error)
 	GetGitDiffStats(indexPath string, fetchFn func() (git.DiffStats, error)) (git.DiffStats, error)
 	GetGitHubBuild(refPath, branch string, ttl time.Duration, fetchFn func() (github.BuildStatus, error)) (github.BuildStatus, error)
+	GetGitHubBuildHistory(refPath, branch string) []github.BuildStatus
+	GitHubBuildAge(refPath, branch string) (time.Duration, bool)
+	GitHubRateLimit(host string) (time.Time, bool)
+	SetGitHubRateLimit(host string, until time.Time)
+	GetGitHubFailedJob(refPath, branch string, ttl time.Duration, fetchFn func() (github.FailedJob, error)) (github.FailedJob, error)
+	GetGitHubWorkflows(refPath, branch string, ttl time.Duration, fetchFn func() ([]github.WorkflowStatus, error)) ([]github.WorkflowStatus, error)
+	GetGitHubPR(refPath, branch string, ttl time.Duration, fetchFn func() (github.PullRequest, error)) (github.PullRequest, error)
+	GetGitHubChecks(refPath, branch string, ttl time.Duration, fetchFn func() (github.ChecksSummary, error)) (github.ChecksSummary, error)
+	GetGitHubDefaultBuild(repo string, ttl time.Duration, fetchFn func() (github.BranchStatus, error)) (github.BranchStatus, error)
+	GetActionsUsage(repo string, ttl time.Duration, fetchFn func() (github.ActionsUsage, error)) (github.ActionsUsage, error)
 	GetTaskStats(workDir string, ttl time.Duration, fetchFn func() (tasks.Stats, error)) (tasks.Stats, error)
+	RecordContextSample(session string, length int64) []int64
 	GetNextTask(workDir string, ttl time.Duration, fetchFn func() (string, error)) (string, error)
+	GetInstallWarning(settingsPath, binaryPath string, fetchFn func() (string, error)) (string, error)
+	GetGitSummary(gitDir string, fetchFn func() (git.Summary, error)) (git.Summary, error)
+	GetGitStashCount(stashLogPath string, fetchFn func() (int, error)) (int, error)
+	GetGitLastCommit(headPath, refPath string, fetchFn func() (git.Commit, error)) (git.Commit, error)
+	GetGitDiffSince(indexPath, base string, fetchFn func() (git.LineStats, error)) (git.LineStats, error)
+	GetGitCommitsSince(headPath, refPath string, since time.Time, fetchFn func() (int, error)) (int, error)
+	GetGitHeadName(headPath string, fetchFn func() (string, error)) (string, error)
+	GetTranscriptMetrics(path string, parseFn func(offset int64, prev tokens.Metrics) (tokens.Metrics, int64, error)) (tokens.Metrics, error)
 	EnsureDir() error
 }
 
@@ -74,17 +143,42 @@ type Builder struct {
 	cache        CacheProvider
 	git          GitProvider
 	gh           GitHubProvider
+	ghMu         sync.Mutex // Guards the lazy creation of gh
 	taskProvider tasks.Provider
+	findTasks    bool // Select the task provider from each input's directories
 	workDir      string
-	prefix       string // User-provided prefix text
-	prefixColor  string // ANSI color code for prefix
+	settingsPath string                                                     // Claude Code settings checked for install drift; empty skips the check
+	state        *state.Store                                               // Persistent per-repo state (branch age, context usage); nil skips it
+	openRepo     func(ctx context.Context, dir string) (GitProvider, error) // Opens additional workspace dirs; nil skips them
+	prefix       string                                                     // User-provided prefix text
+	prefixColor  string                                                     // ANSI color code for prefix
+	inflight     sync.WaitGroup                                             // Segment fetches still running, possibly past Build; see Wait
+}
+
+// build is the state of a single Build. Segments read it rather than the
+// Builder, whose next Build may begin while a segment that missed its soft
+// deadline is still running.
+type build struct {
+	input        Input
+	taskProvider tasks.Provider
+	taskDir      string // Where the task provider was found; keys its cache entries
+	revalidate   func() // See BuildOptions.Revalidate
+}
+
+// BuildOptions adjusts a single Build.
+type BuildOptions struct {
+	// Revalidate enables stale-while-revalidate for the GitHub segment: when
+	// the cached build status has outlived github_ttl, the cached values are
+	// rendered and Revalidate is called, which should refresh them without
+	// blocking, instead of waiting on the API. Nil waits for the API.
+	Revalidate func()
 }
 
 // ErrNilConfig is returned when a nil config is provided to NewBuilder.
 var ErrNilConfig = errors.New("config cannot be nil")
 
 // NewBuilder creates a new status builder.
-func NewBuilder(cfg *config.Config, workDir string) (*Builder, error) {
+func NewBuilder(ctx context.Context, cfg *config.Config, workDir string) (*Builder, error) {
 	if cfg == nil {
 		return nil, ErrNilConfig
 	}
@@ -95,23 +189,38 @@ func NewBuilder(cfg *config.Config, workDir string) (*Builder, error) {
 		return nil, err
 	}
 
+	return NewBuilderWithCache(ctx, cfg, cacheManager, workDir), nil
+}
+
+// NewBuilderWithCache creates a status builder for workDir that shares an
+// existing cache, e.g. one kept warm by a long-running daemon.
+func NewBuilderWithCache(ctx context.Context, cfg *config.Config, cache CacheProvider, workDir string) *Builder {
+	// Key caches and trackers by the real path, however the project was reached
+	workDir = canonicalDir(workDir)
+
 	b := &Builder{
-		config:  cfg,
-		cache:   cacheManager,
-		workDir: workDir,
+		config:       cfg,
+		cache:        cache,
+		workDir:      workDir,
+		settingsPath: install.GetSettingsPath(),
+		state:        state.NewStore(config.StatePath()),
+		openRepo: func(ctx context.Context, dir string) (GitProvider, error) {
+			return git.Open(ctx, dir, cfg.GitBackend)
+		},
 	}
 
 	// Try to initialize git client (may fail if not in git repo)
-	if gitClient, err := git.NewClient(workDir); err == nil {
+	if gitClient, err := git.Open(ctx, workDir, cfg.GitBackend); err == nil {
+		gitClient.SetDiffIgnore(cfg.DiffIgnore)
 		b.git = gitClient
 	} else {
 		slog.Debug("git client initialization skipped", "workDir", workDir, "err", err)
 	}
 
-	// Initialize task tracker via registry (priority: kt > tk > beads)
-	b.taskProvider = tasks.SelectProvider(workDir)
+	// The task tracker is found per input, which says where the project starts
+	b.findTasks = true
 
-	return b, nil
+	return b
 }
 
 // NewBuilderWithDeps creates a new status builder with injected dependencies.
@@ -126,63 +235,86 @@ func NewBuilderWithDeps(cfg *config.Config, cache CacheProvider, git GitProvider
 	}
 }
 
-// Build constructs StatusData from the input.
-func (b *Builder) Build(input Input) template.StatusData {
+// Build constructs StatusData from the input. Cancelling ctx stops the git,
+// GitHub, and task commands still running.
+func (b *Builder) Build(ctx context.Context, input Input) template.StatusData {
+	data, _ := b.BuildWithTimings(ctx, input)
+	return data
+}
+
+// BuildWithTimings is Build that also reports how long each segment took and
+// which ones were cut off by a timeout or the render deadline.
+func (b *Builder) BuildWithTimings(ctx context.Context, input Input) (template.StatusData, []SegmentTiming) {
+	return b.BuildWithOptions(ctx, input, BuildOptions{})
+}
+
+// BuildWithOptions is BuildWithTimings adjusted by opts.
+func (b *Builder) BuildWithOptions(ctx context.Context, input Input, opts BuildOptions) (template.StatusData, []SegmentTiming) {
 	data := template.StatusData{
 		Prefix:      b.prefix,
 		PrefixColor: b.prefixColor,
 		Model:       input.Model.DisplayName,
 		Dir:         filepath.Base(input.Workspace.CurrentDir),
 		Version:     input.Version,
+		OutputStyle: input.OutputStyle.Name,
+		Agent:       input.Agent.Name,
 	}
 
 	if data.Model == "" {
 		data.Model = "Claude"
 	}
 
-	// Parse token metrics from transcript
-	b.populateTokenMetrics(&data, input)
-
-	// Get task stats (cached with TTL) - independent of git
-	b.fetchTaskStats(&data)
-
-	if b.git == nil {
-		return data
+	bs := &build{input: input, taskProvider: b.taskProvider, revalidate: opts.Revalidate}
+	if b.findTasks {
+		// Registry priority (kt > tk > beads) within the nearest directory with a tracker
+		bs.taskProvider, bs.taskDir = tasks.FindProviderDir(b.workDir, canonicalDir(input.Workspace.ProjectDir))
+		if p, ok := bs.taskProvider.(tasks.NextTaskConfigurable); ok {
+			p.SetNextTaskOptions(tasks.NextTaskOptions{Order: b.config.BeadsNextOrder, Label: b.config.BeadsNextLabel})
+		}
+		if p, ok := bs.taskProvider.(tasks.QueryConfigurable); ok {
+			p.SetQueryOptions(tasks.QueryOptions{Command: b.config.TKCommand, Statuses: b.config.TKStatuses, Blocked: b.config.TKBlocked})
+		}
 	}
 
-	// Get git branch (cached)
-	branch, err := b.cache.GetGitBranch(b.git.HeadPath(), b.git.Branch)
-	if err == nil && branch != "" {
-		data.GitBranch = branch
-	}
+	info := platform.Detect()
+	data.OS = info.OS
+	data.Arch = info.Arch
+	data.Rosetta = info.Rosetta
+	data.Container = info.Container
+	data.Environment = info.Environment
 
-	// Get git status (cached)
-	status, err := b.cache.GetGitStatus(b.git.IndexPath(), b.git.Status)
-	if err == nil && status != "" {
-		data.GitStatus = status
-	}
+	// Segments are independent, so fetch them concurrently; whatever
+	// finishes before the render deadline is shown
+	timings := b.fetchSegments(ctx, &data, b.segments(bs))
+	data.ProtectedBranchDirty = data.GitStatus != "" && b.isProtected(data.GitBranch)
+	populateSessionTotals(&data, input)
 
-	// Get git diff stats (cached)
-	diffStats, err := b.cache.GetGitDiffStats(b.git.IndexPath(), b.git.DiffStats)
-	if err == nil {
-		b.populateDiffStats(&data, diffStats)
-	}
+	return data, timings
+}
 
-	// Get GitHub build status (cached with TTL)
-	if data.GitBranch != "" {
-		b.fetchGitHubStatus(&data, data.GitBranch)
+// isProtected reports whether branch matches one of the protected_branches.
+func (b *Builder) isProtected(branch string) bool {
+	if branch == "" {
+		return false
 	}
-
-	return data
+	for _, pattern := range b.config.ProtectedBranches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // populateTokenMetrics parses the transcript and populates token metrics.
+// Only lines appended since the last render are parsed.
 func (b *Builder) populateTokenMetrics(data *template.StatusData, input Input) {
 	if input.TranscriptPath == "" {
 		return
 	}
 
-	metrics, err := tokens.ParseTranscript(input.TranscriptPath)
+	metrics, err := b.cache.GetTranscriptMetrics(input.TranscriptPath, func(offset int64, prev tokens.Metrics) (tokens.Metrics, int64, error) {
+		return tokens.ParseTranscriptFrom(input.TranscriptPath, offset, prev)
+	})
 	if err != nil {
 		slog.Debug("failed to parse transcript", "path", input.TranscriptPath, "err", err)
 		return
@@ -199,6 +331,215 @@ func (b *Builder) populateTokenMetrics(data *template.StatusData, input Input) {
 	data.ContextLength = metrics.ContextLength
 	data.ContextPct = metrics.ContextPercentage(ctxCfg)
 	data.ContextPctUse = metrics.ContextPercentageUsable(ctxCfg)
+	if metrics.CostUSD > 0 {
+		data.CostUSD = metrics.CostUSD
+		data.Cost = tokens.FormatCost(metrics.CostUSD)
+	}
+
+	session := input.SessionID
+	if session == "" {
+		session = input.TranscriptPath
+	}
+	// Persist a sample per turn so the session's context growth can be charted
+	if metrics.ContextLength > 0 {
+		data.ContextHistory = b.cache.RecordContextSample(session, metrics.ContextLength)
+	}
+	b.recordUsage(input, session, metrics, data.ContextPctUse)
+}
+
+// recordUsage adds the turn to its project's context usage and cost, which
+// `claude-status usage` reports on.
+func (b *Builder) recordUsage(input Input, session string, metrics tokens.Metrics, pct float64) {
+	if b.state == nil {
+		return
+	}
+	project := b.usageProject(input)
+	if project == "" {
+		return
+	}
+
+	if metrics.ContextLength > 0 {
+		b.state.RecordContext(project, session, pct)
+	}
+	if len(metrics.Models) > 0 {
+		models := make(map[string]state.ModelUsage, len(metrics.Models))
+		for model, mu := range metrics.Models {
+			models[model] = state.ModelUsage{Tokens: mu.Tokens, CostUSD: mu.CostUSD}
+		}
+		b.state.RecordCost(project, session, models)
+	}
+}
+
+// usageProject returns the project usage is recorded under: the directory
+// Claude Code was started in with symlinks resolved, or the root of the
+// repository it is in, so a session started in a subdirectory counts toward
+// the repository.
+func (b *Builder) usageProject(input Input) string {
+	project := canonicalDir(cmp.Or(input.Workspace.ProjectDir, input.Workspace.CurrentDir))
+	if project == "" || b.git == nil {
+		return project
+	}
+	if root := canonicalDir(git.WorkTreeRoot(b.git.GitDir())); root != "" && tasks.IsWithin(project, root) {
+		return root
+	}
+	return project
+}
+
+// canonicalDir returns dir absolute with symlinks resolved, so a project
+// reached through a symlink shares cache and usage entries with its real
+// path. A dir that can't be resolved, e.g. because it no longer exists, is
+// returned cleaned.
+func canonicalDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return filepath.Clean(dir)
+}
+
+// populateSessionTotals sets what Claude Code reports about the session. Its
+// cost is what the session is billed, so it replaces the transcript estimate.
+func populateSessionTotals(data *template.StatusData, input Input) {
+	if input.Cost.TotalCostUSD > 0 {
+		data.CostUSD = input.Cost.TotalCostUSD
+		data.Cost = tokens.FormatCost(input.Cost.TotalCostUSD)
+	}
+	if input.Cost.TotalDurationMS > 0 {
+		d := time.Duration(input.Cost.TotalDurationMS) * time.Millisecond
+		data.SessionDuration = template.FormatAge(d)
+		data.SessionMinutes = int(d / time.Minute)
+	}
+	data.ContextOver200k = input.Exceeds200kTokens
+}
+
+// populateBranchAge records when branch became current in this repository
+// and sets how long it has been.
+func (b *Builder) populateBranchAge(data *template.StatusData, branch string) {
+	if b.state == nil {
+		return
+	}
+
+	// Checkout rewrites HEAD, so its mtime dates a switch made while no
+	// status line was rendering
+	var changedAt time.Time
+	if info, err := os.Stat(b.git.HeadPath()); err == nil {
+		changedAt = info.ModTime()
+	}
+
+	age := time.Since(b.state.BranchSince(b.git.GitDir(), branch, changedAt))
+	data.BranchAge = template.FormatAge(age)
+	data.BranchAgeDays = int(age / (24 * time.Hour))
+}
+
+// populateDirtySince records whether this repository has uncommitted
+// changes and sets how long they have been uncommitted.
+func (b *Builder) populateDirtySince(data *template.StatusData, dirty bool) {
+	if b.state == nil {
+		return
+	}
+
+	since := b.state.DirtySince(b.git.GitDir(), dirty)
+	if since.IsZero() {
+		return
+	}
+	age := time.Since(since)
+	data.DirtySince = template.FormatAge(age)
+	data.DirtyMinutes = int(age / time.Minute)
+}
+
+// populateSessionDiff sets the lines changed during session: the changes
+// since the commit HEAD was on when the session started, less those already
+// uncommitted then. Commits made during the session still count.
+func (b *Builder) populateSessionDiff(ctx context.Context, data *template.StatusData, session string) {
+	if b.state == nil || session == "" {
+		return
+	}
+
+	start, ok := b.state.SessionStart(session, b.git.GitDir(), func() (state.SessionState, error) {
+		base, err := b.git.HeadSHA(ctx)
+		if err != nil {
+			return state.SessionState{}, err
+		}
+		stats, err := b.git.DiffSince(ctx, base)
+		if err != nil {
+			return state.SessionState{}, err
+		}
+		return state.SessionState{Base: base, Additions: stats.Additions, Deletions: stats.Deletions}, nil
+	})
+	if !ok {
+		return
+	}
+
+	stats, err := b.cache.GetGitDiffSince(b.git.IndexPath(), start.Base, func() (git.LineStats, error) {
+		return b.git.DiffSince(ctx, start.Base)
+	})
+	if err != nil {
+		slog.Debug("no session diff", "base", start.Base, "err", err)
+		return
+	}
+	data.SessionAdditions = max(stats.Additions-start.Additions, 0)
+	data.SessionDeletions = max(stats.Deletions-start.Deletions, 0)
+}
+
+// fetchWorkspaces summarizes uncommitted changes across the current and
+// additional workspace directories. Directories in the same repository are
+// counted once; directories outside any repository are skipped.
+func (b *Builder) fetchWorkspaces(ctx context.Context, data *template.StatusData, workspace WorkspaceInfo) {
+	seen := make(map[string]bool)
+	add := func(dir string, repo GitProvider) {
+		if seen[repo.GitDir()] {
+			return
+		}
+		seen[repo.GitDir()] = true
+
+		summary, err := b.cache.GetGitSummary(repo.GitDir(), func() (git.Summary, error) { return repo.Summary(ctx) })
+		if err != nil {
+			slog.Debug("failed to summarize workspace", "dir", dir, "err", err)
+			return
+		}
+		data.Workspaces = append(data.Workspaces, template.WorkspaceDir{
+			Dir:     filepath.Base(dir),
+			Branch:  summary.Branch,
+			Changed: summary.Changed,
+		})
+		data.WorkspaceChanged += summary.Changed
+	}
+
+	if b.git != nil {
+		add(workspace.CurrentDir, b.git)
+	}
+	for _, dir := range workspace.AddedDirs {
+		repo, err := b.openRepo(ctx, dir)
+		if err != nil {
+			slog.Debug("additional directory is not a git repository", "dir", dir, "err", err)
+			continue
+		}
+		add(dir, repo)
+	}
+}
+
+// checkInstall warns when Claude Code settings no longer run this binary,
+// e.g. after it was moved or upgraded to a new path.
+func (b *Builder) checkInstall(data *template.StatusData) {
+	binaryPath, err := install.ExecutablePath()
+	if err != nil {
+		slog.Debug("failed to get executable path", "err", err)
+		return
+	}
+
+	warning, err := b.cache.GetInstallWarning(b.settingsPath, binaryPath, func() (string, error) {
+		return install.CheckDrift(b.settingsPath, binaryPath)
+	})
+	if err != nil {
+		slog.Debug("failed to check install", "settings", b.settingsPath, "err", err)
+		return
+	}
+	data.InstallWarning = warning
 }
 
 // populateDiffStats populates git diff statistics into StatusData.
@@ -206,57 +547,374 @@ func (b *Builder) populateDiffStats(data *template.StatusData, stats git.DiffSta
 	// Raw values only (formatting is done in templates via fmtSigned)
 	data.GitAdditions = stats.Additions
 	data.GitDeletions = stats.Deletions
+	data.GitStagedAdditions = stats.StagedAdditions
+	data.GitStagedDeletions = stats.StagedDeletions
+	data.GitUnstagedAdditions = stats.UnstagedAdditions
+	data.GitUnstagedDeletions = stats.UnstagedDeletions
 	data.GitNewFiles = stats.NewFiles
 	data.GitModifiedFiles = stats.ModifiedFiles
 	data.GitDeletedFiles = stats.DeletedFiles
 	data.GitUnstagedFiles = stats.UnstagedFiles
+	data.GitConflicts = stats.Conflicts
+	data.GitFileTypes = fileTypeCounts(stats.FileTypes)
+	data.GitLargestFile = truncatePath(stats.LargestFile, largestFileMaxLen)
+	data.GitLargestFileLines = stats.LargestFileLines
+	for _, f := range stats.ChangedFiles {
+		data.GitChangedFiles = append(data.GitChangedFiles, template.ChangedFile{Status: f.Status, Path: f.Path})
+	}
+}
+
+// largestFileMaxLen caps the displayed length of GitLargestFile.
+const largestFileMaxLen = 30
+
+// lastCommitMsgLen is how many runes of the last commit subject are shown.
+const lastCommitMsgLen = 50
+
+// populateLastCommit sets the last commit fields from commit.
+func (b *Builder) populateLastCommit(data *template.StatusData, commit git.Commit) {
+	data.GitLastCommitSHA = commit.SHA
+	data.GitLastCommitMsg = template.Truncate(commit.Subject, lastCommitMsgLen)
+	data.GitLastCommitAge = template.FormatAge(time.Since(commit.Time))
+}
+
+// populateSessionCommits sets how many commits were made since the session
+// in the transcript started.
+func (b *Builder) populateSessionCommits(ctx context.Context, data *template.StatusData, transcriptPath, refPath string) {
+	if transcriptPath == "" {
+		return
+	}
+	started, err := tokens.SessionStart(transcriptPath)
+	if err != nil {
+		slog.Debug("no session start", "path", transcriptPath, "err", err)
+		return
+	}
+	count, err := b.cache.GetGitCommitsSince(b.git.HeadPath(), refPath, started, func() (int, error) {
+		return b.git.CommitsSince(ctx, started)
+	})
+	if err == nil {
+		data.SessionCommits = count
+	}
+}
+
+// truncatePath shortens path to at most maxLen runes by dropping leading
+// directories: "internal/cache/cache.go" -> "…/cache/cache.go".
+func truncatePath(path string, maxLen int) string {
+	if utf8.RuneCountInString(path) <= maxLen {
+		return path
+	}
+	parts := strings.Split(path, "/")
+	for len(parts) > 1 {
+		parts = parts[1:]
+		short := "…/" + strings.Join(parts, "/")
+		if utf8.RuneCountInString(short) <= maxLen {
+			return short
+		}
+	}
+	// A single file name that is still too long keeps its tail (and extension)
+	runes := []rune(parts[0])
+	return "…" + string(runes[len(runes)-(maxLen-1):])
+}
+
+// fileTypeCounts orders per-extension counts by frequency, then extension.
+func fileTypeCounts(types map[string]int) []template.FileTypeCount {
+	if len(types) == 0 {
+		return nil
+	}
+	counts := make([]template.FileTypeCount, 0, len(types))
+	for ext, n := range types {
+		counts = append(counts, template.FileTypeCount{Ext: ext, Count: n})
+	}
+	slices.SortFunc(counts, func(a, b template.FileTypeCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Ext, b.Ext)
+	})
+	return counts
 }
 
-func (b *Builder) fetchGitHubStatus(data *template.StatusData, branch string) {
+func (b *Builder) fetchGitHubStatus(ctx context.Context, data *template.StatusData, branch string, revalidate func()) {
 	// Get remote URL
-	remoteURL, err := b.git.RemoteURL()
+	remoteURL, err := b.git.RemoteURL(ctx)
 	if err != nil {
 		slog.Debug("failed to get remote URL", "err", err)
 		return
 	}
 
 	// Parse owner/repo
-	owner, repo, ok := git.ParseGitHubRepo(remoteURL)
+	owner, repo, ok := git.ParseGitHubRepoHost(remoteURL, b.config.GitHubHost)
 	if !ok {
 		slog.Debug("not a GitHub repository", "remoteURL", remoteURL)
 		return
 	}
 
 	// Lazily initialize GitHub client if needed
-	if b.gh == nil {
-		ghClient, err := github.NewClient(b.config.GitHubWorkflow)
-		if err != nil {
+	if _, err := b.githubClient(ctx); err != nil {
+		if errors.Is(err, httpclient.ErrCABundle) {
+			// A bad ca_bundle would otherwise hide the GitHub segment without a trace
+			slog.Warn("failed to create GitHub client", "err", err)
+		} else {
 			slog.Debug("failed to create GitHub client", "err", err)
-			return
 		}
-		b.gh = ghClient
+		return
 	}
 
 	// Get build status with caching
 	ttl := time.Duration(b.config.GitHubTTL) * time.Second
+	prTTL := time.Duration(b.config.GitHubPRTTL) * time.Second
 	refPath := b.git.RefPath(branch)
 
-	buildStatus, err := b.cache.GetGitHubBuild(refPath, branch, ttl, func() (github.BuildStatus, error) {
-		return b.gh.GetBuildStatus(owner, repo, branch)
-	})
+	// Stale-while-revalidate: once the build status outlives its TTL, render
+	// what is cached and leave the refresh to the background
+	if revalidate != nil {
+		if age, ok := b.cache.GitHubBuildAge(refPath, branch); ok && age >= ttl && age < maxStale {
+			slog.Debug("serving stale GitHub status while revalidating", "branch", branch, "age", age)
+			revalidate()
+			ttl, prTTL = maxStale, maxStale
+		}
+	}
+
+	if b.config.GitHubDefaultBranch {
+		b.fetchDefaultBranchStatus(ctx, data, owner, repo, ttl)
+	}
+	if b.config.GitHubUsage {
+		b.fetchActionsUsage(ctx, data, owner, repo)
+	}
+
+	if b.config.GitHubPR {
+		b.fetchPullRequest(ctx, data, owner, repo, branch, refPath, prTTL)
+		// PR checks are independent of the workflow run, so fetch them first
+		b.fetchChecks(ctx, data, owner, repo, branch, refPath, ttl)
+	}
+
+	workflows := b.fetchWorkflowStatuses(ctx, data, owner, repo, branch, refPath, ttl)
+	buildStatus, err := b.cache.GetGitHubBuild(refPath, branch, ttl, unlessRateLimited(b, func() (github.BuildStatus, error) {
+		// The workflows were just fetched; combine them rather than ask again
+		if len(workflows) > 0 {
+			statuses := make([]github.BuildStatus, len(workflows))
+			for i, w := range workflows {
+				statuses[i] = w.Status
+			}
+			return cistatus.Combine(statuses...), nil
+		}
+		return b.gh.GetBuildStatus(ctx, owner, repo, branch)
+	}))
 	if err != nil {
 		slog.Debug("failed to get GitHub build status", "owner", owner, "repo", repo, "branch", branch, "err", err)
+		if b.noteRateLimit(err) {
+			b.showRateLimited(data)
+		}
+		return
+	}
+
+	data.GitHubStatus = cistatus.Symbol(buildStatus, b.config.IconSymbols())
+	data.GitHubBuild = string(buildStatus)
+	for _, result := range b.cache.GetGitHubBuildHistory(refPath, branch) {
+		data.GitHubHistory = append(data.GitHubHistory, string(result))
+	}
+
+	if buildStatus != github.StatusFailure {
+		return
+	}
+
+	// Failed run: find out which job broke so the status line can name it
+	failedJob, err := b.cache.GetGitHubFailedJob(refPath, branch, ttl, unlessRateLimited(b, func() (github.FailedJob, error) {
+		return b.gh.GetFailedJob(ctx, owner, repo, branch)
+	}))
+	if err != nil {
+		b.noteRateLimit(err)
+		slog.Debug("failed to get failed job", "owner", owner, "repo", repo, "branch", branch, "err", err)
+		return
+	}
+	data.GitHubFailedJob = failedJob.Name
+	data.GitHubRunURL = failedJob.RunURL
+}
+
+// fetchWorkflowStatuses populates each workflow's build status when
+// github_workflow lists several, and returns them.
+func (b *Builder) fetchWorkflowStatuses(ctx context.Context, data *template.StatusData, owner, repo, branch, refPath string, ttl time.Duration) []github.WorkflowStatus {
+	if len(b.config.GitHubWorkflow) < 2 {
+		return nil
+	}
+	workflows, err := b.cache.GetGitHubWorkflows(refPath, branch, ttl, unlessRateLimited(b, func() ([]github.WorkflowStatus, error) {
+		return b.gh.GetWorkflowStatuses(ctx, owner, repo, branch)
+	}))
+	if err != nil {
+		b.noteRateLimit(err)
+		slog.Debug("failed to get workflow statuses", "owner", owner, "repo", repo, "branch", branch, "err", err)
+		return nil
+	}
+
+	symbols := b.config.IconSymbols()
+	for _, w := range workflows {
+		data.GitHubWorkflows = append(data.GitHubWorkflows, template.WorkflowStatus{
+			Name:   w.Name,
+			Status: cistatus.Symbol(w.Status, symbols),
+			Build:  string(w.Status),
+		})
+	}
+	return workflows
+}
+
+// fetchChecks populates the check-run counts of branch's open pull request.
+func (b *Builder) fetchChecks(ctx context.Context, data *template.StatusData, owner, repo, branch, refPath string, ttl time.Duration) {
+	checks, err := b.cache.GetGitHubChecks(refPath, branch, ttl, unlessRateLimited(b, func() (github.ChecksSummary, error) {
+		return b.gh.GetChecksSummary(ctx, owner, repo, branch)
+	}))
+	if err != nil {
+		b.noteRateLimit(err)
+		slog.Debug("failed to get PR checks", "owner", owner, "repo", repo, "branch", branch, "err", err)
+		return
+	}
+	data.ChecksPassed = checks.Passed
+	data.ChecksFailed = checks.Failed
+	data.ChecksPending = checks.Pending
+	data.ChecksTotal = checks.Total
+}
+
+// fetchPullRequest populates the open pull request for branch (cached with
+// its own TTL, since reviews change independently of CI).
+func (b *Builder) fetchPullRequest(ctx context.Context, data *template.StatusData, owner, repo, branch, refPath string, ttl time.Duration) {
+	pr, err := b.cache.GetGitHubPR(refPath, branch, ttl, unlessRateLimited(b, func() (github.PullRequest, error) {
+		return b.gh.GetPullRequest(ctx, owner, repo, branch)
+	}))
+	if err != nil {
+		b.noteRateLimit(err)
+		slog.Debug("failed to get pull request", "owner", owner, "repo", repo, "branch", branch, "err", err)
+		return
+	}
+
+	data.PRNumber = pr.Number
+	data.PRURL = pr.URL
+	data.PRState = pr.State
+	data.PRReviewState = pr.ReviewState
+	data.PRMergeable = pr.Mergeable
+}
+
+// fetchDefaultBranchStatus populates the default branch's build status.
+func (b *Builder) fetchDefaultBranchStatus(ctx context.Context, data *template.StatusData, owner, repo string, ttl time.Duration) {
+	mainStatus, err := b.cache.GetGitHubDefaultBuild(owner+"/"+repo, ttl, unlessRateLimited(b, func() (github.BranchStatus, error) {
+		return b.gh.GetDefaultBranchStatus(ctx, owner, repo)
+	}))
+	if err != nil {
+		b.noteRateLimit(err)
+		slog.Debug("failed to get default branch build status", "owner", owner, "repo", repo, "err", err)
 		return
 	}
 
-	data.GitHubStatus = github.StatusToEmoji(buildStatus)
+	data.GitHubMainBranch = mainStatus.Branch
+	data.GitHubMainStatus = cistatus.Symbol(mainStatus.Status, b.config.IconSymbols())
+}
+
+// fetchActionsUsage populates remaining Actions minutes (cached with a long TTL).
+func (b *Builder) fetchActionsUsage(ctx context.Context, data *template.StatusData, owner, repo string) {
+	ttl := time.Duration(b.config.GitHubUsageTTL) * time.Second
+	usage, err := b.cache.GetActionsUsage(owner+"/"+repo, ttl, unlessRateLimited(b, func() (github.ActionsUsage, error) {
+		return b.gh.GetActionsUsage(ctx, owner, repo)
+	}))
+	if err != nil {
+		b.noteRateLimit(err)
+		slog.Debug("failed to get Actions usage", "owner", owner, "repo", repo, "err", err)
+		return
+	}
+
+	data.ActionsMinutesUsed = usage.MinutesUsed
+	data.ActionsMinutesIncluded = usage.MinutesIncluded
+	data.ActionsMinutesRemaining = usage.MinutesRemaining
+}
+
+// Rate limit backoff bounds: a reset claimed to be sooner is waited out for
+// at least minRateLimitBackoff, and one further out is retried after
+// maxRateLimitBackoff in case the limit was lifted early.
+const (
+	minRateLimitBackoff = time.Minute
+	maxRateLimitBackoff = time.Hour
+)
+
+// githubHost returns the GitHub host rate limits are tracked for.
+func (b *Builder) githubHost() string {
+	return cmp.Or(b.config.GitHubHost, github.DefaultHost)
+}
+
+// errRateLimited fails fetches held back by a rate limit already noted.
+var errRateLimited = errors.New("GitHub rate limited")
+
+// unlessRateLimited wraps a GitHub fetch so that, until a noted rate limit
+// resets, it fails instead of asking: every request would be refused, but
+// values still cached are served.
+func unlessRateLimited[T any](b *Builder, fetch func() (T, error)) func() (T, error) {
+	return func() (T, error) {
+		if _, limited := b.cache.GitHubRateLimit(b.githubHost()); limited {
+			var zero T
+			return zero, errRateLimited
+		}
+		return fetch()
+	}
+}
+
+// noteRateLimit records a rate limit GitHub refused a request with, so
+// renders until it resets don't ask again. It reports whether err was one,
+// or a fetch held back by one.
+func (b *Builder) noteRateLimit(err error) bool {
+	if errors.Is(err, errRateLimited) {
+		return true
+	}
+	var rateLimit *github.RateLimitError
+	if !errors.As(err, &rateLimit) {
+		return false
+	}
+	wait := min(max(time.Until(rateLimit.Reset), minRateLimitBackoff), maxRateLimitBackoff)
+	b.cache.SetGitHubRateLimit(b.githubHost(), time.Now().Add(wait))
+	return true
+}
+
+// showRateLimited sets the build status to rate limited, so the segment
+// says why it has nothing newer rather than disappearing.
+func (b *Builder) showRateLimited(data *template.StatusData) {
+	data.GitHubStatus = cistatus.Symbol(cistatus.RateLimited, b.config.IconSymbols())
+	data.GitHubBuild = string(cistatus.RateLimited)
+}
+
+// GitDir returns the git directory of the builder's repository, or "" if
+// workDir is not in a git repository.
+func (b *Builder) GitDir() string {
+	if b.git == nil {
+		return ""
+	}
+	return b.git.GitDir()
 }
 
 // SetGitHubClient sets the GitHub client (for lazy initialization or testing).
 func (b *Builder) SetGitHubClient(gh GitHubProvider) {
+	b.ghMu.Lock()
+	defer b.ghMu.Unlock()
 	b.gh = gh
 }
 
+// githubClient returns the GitHub client, creating it on first use. Builds
+// running at once share it.
+func (b *Builder) githubClient(ctx context.Context) (GitHubProvider, error) {
+	b.ghMu.Lock()
+	defer b.ghMu.Unlock()
+
+	if b.gh != nil {
+		return b.gh, nil
+	}
+	ghClient, err := github.NewClientForHost(ctx, b.config.GitHubWorkflow, b.config.CABundle, b.config.GitHubHost, b.config.GitHubAPIURL, b.config.GitHubTokenCommand)
+	if err != nil {
+		return nil, err
+	}
+	ghClient.SetStatusContext(b.config.GitHubStatusContext)
+	ghClient.SetAggregate(b.config.GitHubAggregate)
+	ghClient.SetPathFilter(b.config.GitHubPathFilter)
+	b.gh = ghClient
+	return ghClient, nil
+}
+
+// maxStale is the oldest GitHub status rendered while revalidating; anything
+// older is fetched in the foreground as if there were no cached value.
+const maxStale = time.Hour
+
 // SetPrefix sets a prefix to be displayed at the start of the status line.
 func (b *Builder) SetPrefix(prefix string) {
 	b.prefix = prefix
@@ -267,23 +925,27 @@ func (b *Builder) SetPrefixColor(color string) {
 	b.prefixColor = color
 }
 
-// fetchTaskStats fetches task stats and populates the data.
-func (b *Builder) fetchTaskStats(data *template.StatusData) {
-	if b.taskProvider == nil {
+// fetchTaskStats fetches the stats of bs's task provider and populates the
+// data.
+func (b *Builder) fetchTaskStats(ctx context.Context, data *template.StatusData, bs *build) {
+	provider := bs.taskProvider
+	if provider == nil {
 		return
 	}
 
 	ttl := time.Duration(b.config.TasksTTL) * time.Second
-	stats, err := b.cache.GetTaskStats(b.workDir, ttl, b.taskProvider.GetStats)
+	// Subdirectories finding the same tracker share its entries
+	taskDir := cmp.Or(bs.taskDir, b.workDir)
+	stats, err := b.cache.GetTaskStats(taskDir, ttl, func() (tasks.Stats, error) { return provider.GetStats(ctx) })
 	if err != nil {
 		slog.Debug("failed to get task stats", "err", err)
 		return
 	}
 
-	b.populateTaskStats(data, stats)
+	b.populateTaskStats(data, stats, provider.Name(), taskDir)
 
 	// Get next task (cached with same TTL as stats)
-	nextTask, err := b.cache.GetNextTask(b.workDir, ttl, b.taskProvider.GetNextTask)
+	nextTask, err := b.cache.GetNextTask(taskDir, ttl, func() (string, error) { return provider.GetNextTask(ctx) })
 	if err != nil {
 		slog.Debug("failed to get next task", "err", err)
 		return
@@ -291,10 +953,11 @@ func (b *Builder) fetchTaskStats(data *template.StatusData) {
 	data.TasksNextTask = nextTask
 }
 
-// populateTaskStats populates task statistics into StatusData.
-func (b *Builder) populateTaskStats(data *template.StatusData, stats tasks.Stats) {
+// populateTaskStats populates statistics of the named task provider, found
+// in taskDir, into StatusData.
+func (b *Builder) populateTaskStats(data *template.StatusData, stats tasks.Stats, provider, taskDir string) {
 	data.HasTasks = true
-	data.TaskProvider = b.taskProvider.Name()
+	data.TaskProvider = provider
 
 	// Raw values only (formatting is done in templates)
 	data.TasksTotal = stats.TotalIssues
@@ -302,4 +965,13 @@ func (b *Builder) populateTaskStats(data *template.StatusData, stats tasks.Stats
 	data.TasksReady = stats.ReadyIssues
 	data.TasksInProgress = stats.InProgressIssues
 	data.TasksBlocked = stats.BlockedIssues
+
+	// The daily counter lives in the state file, so it outlasts the cache
+	if b.state != nil {
+		tracker := taskDir
+		if b.git != nil {
+			tracker = b.git.GitDir()
+		}
+		data.TasksClosedToday = b.state.ClosedToday(tracker, stats.ClosedIssues)
+	}
 }
@@ -1,15 +1,23 @@
 package status
 
 import (
+	"context"
 	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/kostyay/claude-status/internal/config"
 	"github.com/kostyay/claude-status/internal/git"
 	"github.com/kostyay/claude-status/internal/github"
+	"github.com/kostyay/claude-status/internal/state"
 	"github.com/kostyay/claude-status/internal/tasks"
+	"github.com/kostyay/claude-status/internal/template"
+	"github.com/kostyay/claude-status/internal/tokens"
 )
 
 // mockGitProvider is a test double for GitProvider.
@@ -20,32 +28,96 @@ type mockGitProvider struct {
 	statusErr    error
 	diffStats    git.DiffStats
 	diffStatsErr error
+	upstream     git.UpstreamStatus
+	upstreamErr  error
 	remoteURL    string
 	remoteErr    error
 	gitDir       string
+	summary      git.Summary
+	stashCount   int
+	lastCommit   git.Commit
+	headSHA      string
+	diffSince    git.LineStats
+	commitsSince int
+	since        time.Time
+	operation    string
+	detachedName string
 }
 
-func (m *mockGitProvider) Branch() (string, error)           { return m.branch, m.branchErr }
-func (m *mockGitProvider) Status() (string, error)           { return m.status, m.statusErr }
-func (m *mockGitProvider) DiffStats() (git.DiffStats, error) { return m.diffStats, m.diffStatsErr }
-func (m *mockGitProvider) RemoteURL() (string, error)        { return m.remoteURL, m.remoteErr }
-func (m *mockGitProvider) GitDir() string                    { return m.gitDir }
-func (m *mockGitProvider) HeadPath() string                  { return m.gitDir + "/HEAD" }
-func (m *mockGitProvider) IndexPath() string                 { return m.gitDir + "/index" }
+func (m *mockGitProvider) Branch(context.Context) (string, error)       { return m.branch, m.branchErr }
+func (m *mockGitProvider) Status(context.Context) (string, error)       { return m.status, m.statusErr }
+func (m *mockGitProvider) Summary(context.Context) (git.Summary, error) { return m.summary, nil }
+func (m *mockGitProvider) StashCount(context.Context) (int, error)      { return m.stashCount, nil }
+func (m *mockGitProvider) StashLogPath() string                         { return m.gitDir + "/logs/refs/stash" }
+func (m *mockGitProvider) DiffStats(context.Context) (git.DiffStats, error) {
+	return m.diffStats, m.diffStatsErr
+}
+func (m *mockGitProvider) RemoteURL(context.Context) (string, error)      { return m.remoteURL, m.remoteErr }
+func (m *mockGitProvider) LastCommit(context.Context) (git.Commit, error) { return m.lastCommit, nil }
+func (m *mockGitProvider) DetachedName(context.Context) (string, error)   { return m.detachedName, nil }
+func (m *mockGitProvider) Operation() string                              { return m.operation }
+func (m *mockGitProvider) HeadSHA(context.Context) (string, error)        { return m.headSHA, nil }
+func (m *mockGitProvider) DiffSince(_ context.Context, rev string) (git.LineStats, error) {
+	return m.diffSince, nil
+}
+func (m *mockGitProvider) AheadBehind(context.Context) (git.UpstreamStatus, error) {
+	return m.upstream, m.upstreamErr
+}
+func (m *mockGitProvider) FetchHeadPath() string { return m.gitDir + "/FETCH_HEAD" }
+func (m *mockGitProvider) GitDir() string        { return m.gitDir }
+func (m *mockGitProvider) HeadPath() string      { return m.gitDir + "/HEAD" }
+func (m *mockGitProvider) IndexPath() string     { return m.gitDir + "/index" }
 func (m *mockGitProvider) RefPath(branch string) string {
 	return m.gitDir + "/refs/heads/" + branch
 }
 
+func (m *mockGitProvider) CommitsSince(_ context.Context, t time.Time) (int, error) {
+	m.since = t
+	return m.commitsSince, nil
+}
+
 // mockGitHubProvider is a test double for GitHubProvider.
 type mockGitHubProvider struct {
-	status github.BuildStatus
-	err    error
+	status    github.BuildStatus
+	err       error
+	failedJob github.FailedJob
+	checks    github.ChecksSummary
+	mainState github.BranchStatus
+	usage     github.ActionsUsage
+	pr        github.PullRequest
+	workflows []github.WorkflowStatus
+	calls     int // GetBuildStatus calls
 }
 
-func (m *mockGitHubProvider) GetBuildStatus(owner, repo, branch string) (github.BuildStatus, error) {
+func (m *mockGitHubProvider) GetBuildStatus(_ context.Context, owner, repo, branch string) (github.BuildStatus, error) {
+	m.calls++
 	return m.status, m.err
 }
 
+func (m *mockGitHubProvider) GetFailedJob(_ context.Context, owner, repo, branch string) (github.FailedJob, error) {
+	return m.failedJob, nil
+}
+
+func (m *mockGitHubProvider) GetChecksSummary(_ context.Context, owner, repo, branch string) (github.ChecksSummary, error) {
+	return m.checks, m.err
+}
+
+func (m *mockGitHubProvider) GetPullRequest(_ context.Context, owner, repo, branch string) (github.PullRequest, error) {
+	return m.pr, m.err
+}
+
+func (m *mockGitHubProvider) GetDefaultBranchStatus(_ context.Context, owner, repo string) (github.BranchStatus, error) {
+	return m.mainState, m.err
+}
+
+func (m *mockGitHubProvider) GetActionsUsage(_ context.Context, owner, repo string) (github.ActionsUsage, error) {
+	return m.usage, m.err
+}
+
+func (m *mockGitHubProvider) GetWorkflowStatuses(_ context.Context, owner, repo, branch string) ([]github.WorkflowStatus, error) {
+	return m.workflows, m.err
+}
+
 // mockCacheProvider is a test double for CacheProvider.
 type mockCacheProvider struct {
 	branchValue    string
@@ -53,6 +125,11 @@ type mockCacheProvider struct {
 	diffStatsValue git.DiffStats
 	buildStatus    github.BuildStatus
 	buildErr       error
+	buildHistory   []github.BuildStatus
+	buildAge       time.Duration // Age GitHubBuildAge reports; zero means no entry
+	buildTTL       time.Duration // TTL of the last GetGitHubBuild call
+	contextSamples []int64
+	rateLimits     map[string]time.Time // SetGitHubRateLimit calls by host
 	taskStats      tasks.Stats
 	fetchBranch    bool
 	fetchStatus    bool
@@ -77,6 +154,10 @@ func (m *mockCacheProvider) GetGitStatus(indexPath string, fetchFn func() (strin
 	return m.statusValue, nil
 }
 
+func (m *mockCacheProvider) GetGitUpstream(refPath, fetchHeadPath string, fetchFn func() (git.UpstreamStatus, error)) (git.UpstreamStatus, error) {
+	return fetchFn()
+}
+
 func (m *mockCacheProvider) GetGitDiffStats(indexPath string, fetchFn func() (git.DiffStats, error)) (git.DiffStats, error) {
 	if m.fetchDiffStats {
 		return fetchFn()
@@ -85,12 +166,95 @@ func (m *mockCacheProvider) GetGitDiffStats(indexPath string, fetchFn func() (gi
 }
 
 func (m *mockCacheProvider) GetGitHubBuild(refPath, branch string, ttl time.Duration, fetchFn func() (github.BuildStatus, error)) (github.BuildStatus, error) {
+	m.buildTTL = ttl
 	if m.fetchBuild {
 		return fetchFn()
 	}
 	return m.buildStatus, m.buildErr
 }
 
+func (m *mockCacheProvider) RecordContextSample(session string, length int64) []int64 {
+	m.contextSamples = append(m.contextSamples, length)
+	return m.contextSamples
+}
+
+func (m *mockCacheProvider) GetGitHubBuildHistory(refPath, branch string) []github.BuildStatus {
+	return m.buildHistory
+}
+
+func (m *mockCacheProvider) GitHubBuildAge(refPath, branch string) (time.Duration, bool) {
+	return m.buildAge, m.buildAge > 0
+}
+
+func (m *mockCacheProvider) GitHubRateLimit(host string) (time.Time, bool) {
+	until, ok := m.rateLimits[host]
+	return until, ok && time.Now().Before(until)
+}
+
+func (m *mockCacheProvider) SetGitHubRateLimit(host string, until time.Time) {
+	if m.rateLimits == nil {
+		m.rateLimits = make(map[string]time.Time)
+	}
+	m.rateLimits[host] = until
+}
+
+func (m *mockCacheProvider) GetGitHubFailedJob(refPath, branch string, ttl time.Duration, fetchFn func() (github.FailedJob, error)) (github.FailedJob, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitHubWorkflows(refPath, branch string, ttl time.Duration, fetchFn func() ([]github.WorkflowStatus, error)) ([]github.WorkflowStatus, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetInstallWarning(settingsPath, binaryPath string, fetchFn func() (string, error)) (string, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitSummary(gitDir string, fetchFn func() (git.Summary, error)) (git.Summary, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitStashCount(stashLogPath string, fetchFn func() (int, error)) (int, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitDiffSince(indexPath, base string, fetchFn func() (git.LineStats, error)) (git.LineStats, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitCommitsSince(headPath, refPath string, since time.Time, fetchFn func() (int, error)) (int, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitHeadName(headPath string, fetchFn func() (string, error)) (string, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitLastCommit(headPath, refPath string, fetchFn func() (git.Commit, error)) (git.Commit, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetTranscriptMetrics(path string, parseFn func(offset int64, prev tokens.Metrics) (tokens.Metrics, int64, error)) (tokens.Metrics, error) {
+	metrics, _, err := parseFn(0, tokens.Metrics{})
+	return metrics, err
+}
+
+func (m *mockCacheProvider) GetGitHubPR(refPath, branch string, ttl time.Duration, fetchFn func() (github.PullRequest, error)) (github.PullRequest, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitHubChecks(refPath, branch string, ttl time.Duration, fetchFn func() (github.ChecksSummary, error)) (github.ChecksSummary, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetGitHubDefaultBuild(repo string, ttl time.Duration, fetchFn func() (github.BranchStatus, error)) (github.BranchStatus, error) {
+	return fetchFn()
+}
+
+func (m *mockCacheProvider) GetActionsUsage(repo string, ttl time.Duration, fetchFn func() (github.ActionsUsage, error)) (github.ActionsUsage, error) {
+	return fetchFn()
+}
+
 func (m *mockCacheProvider) GetTaskStats(workDir string, ttl time.Duration, fetchFn func() (tasks.Stats, error)) (tasks.Stats, error) {
 	if m.fetchTasks {
 		return fetchFn()
@@ -119,11 +283,11 @@ func (m *mockTaskProvider) Available() bool {
 	return m.available
 }
 
-func (m *mockTaskProvider) GetStats() (tasks.Stats, error) {
+func (m *mockTaskProvider) GetStats(context.Context) (tasks.Stats, error) {
 	return m.stats, m.err
 }
 
-func (m *mockTaskProvider) GetNextTask() (string, error) {
+func (m *mockTaskProvider) GetNextTask(context.Context) (string, error) {
 	return m.nextTask, nil
 }
 
@@ -153,7 +317,7 @@ func TestBuild_AllData(t *testing.T) {
 		Version:   "1.0.0",
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.Model != "Claude" {
 		t.Errorf("Model = %q, want %q", data.Model, "Claude")
@@ -170,6 +334,9 @@ func TestBuild_AllData(t *testing.T) {
 	if data.GitHubStatus != "✅" {
 		t.Errorf("GitHubStatus = %q, want %q", data.GitHubStatus, "✅")
 	}
+	if data.GitHubBuild != "success" {
+		t.Errorf("GitHubBuild = %q, want %q", data.GitHubBuild, "success")
+	}
 	if data.Version != "1.0.0" {
 		t.Errorf("Version = %q, want %q", data.Version, "1.0.0")
 	}
@@ -188,7 +355,7 @@ func TestBuild_NoGit(t *testing.T) {
 		Version:   "1.0.0",
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.Model != "Claude" {
 		t.Errorf("Model = %q, want %q", data.Model, "Claude")
@@ -229,7 +396,7 @@ func TestBuild_GitNoGitHub(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.GitBranch != "main" {
 		t.Errorf("GitBranch = %q, want %q", data.GitBranch, "main")
@@ -242,6 +409,126 @@ func TestBuild_GitNoGitHub(t *testing.T) {
 	}
 }
 
+func TestBuild_StagedUnstagedDiffStats(t *testing.T) {
+	cfg := config.Default()
+
+	cache := &mockCacheProvider{
+		branchValue: "main",
+		diffStatsValue: git.DiffStats{
+			LargestFile:       "internal/template/template_functions.go",
+			LargestFileLines:  25,
+			ChangedFiles:      []git.ChangedFile{{Status: "M ", Path: "a.go"}},
+			Additions:         30,
+			Deletions:         7,
+			StagedAdditions:   20,
+			StagedDeletions:   5,
+			UnstagedAdditions: 10,
+			UnstagedDeletions: 2,
+		},
+	}
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@gitlab.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, nil, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+
+	if data.GitAdditions != 30 || data.GitDeletions != 7 {
+		t.Errorf("combined = +%d,-%d, want +30,-7", data.GitAdditions, data.GitDeletions)
+	}
+	if data.GitStagedAdditions != 20 || data.GitStagedDeletions != 5 {
+		t.Errorf("staged = +%d,-%d, want +20,-5", data.GitStagedAdditions, data.GitStagedDeletions)
+	}
+	if data.GitUnstagedAdditions != 10 || data.GitUnstagedDeletions != 2 {
+		t.Errorf("unstaged = +%d,-%d, want +10,-2", data.GitUnstagedAdditions, data.GitUnstagedDeletions)
+	}
+	if len(data.GitChangedFiles) != 1 || data.GitChangedFiles[0].String() != "M  a.go" {
+		t.Errorf("GitChangedFiles = %v, want [M  a.go]", data.GitChangedFiles)
+	}
+	if data.GitLargestFile != "…/template_functions.go" || data.GitLargestFileLines != 25 {
+		t.Errorf("largest file = (%q, %d), want (…/template_functions.go, 25)", data.GitLargestFile, data.GitLargestFileLines)
+	}
+}
+
+func TestBuild_AheadBehind(t *testing.T) {
+	cfg := config.Default()
+
+	git := &mockGitProvider{
+		branch:   "feature",
+		upstream: git.UpstreamStatus{Ahead: 2, Behind: 1},
+		gitDir:   "/repo/.git",
+	}
+	cache := &mockCacheProvider{fetchBranch: true}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, nil, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+
+	if data.GitAhead != 2 || data.GitBehind != 1 {
+		t.Errorf("GitAhead, GitBehind = %d, %d; want 2, 1", data.GitAhead, data.GitBehind)
+	}
+	if !data.ForcePushNeeded {
+		t.Error("ForcePushNeeded should be set when the branch has diverged")
+	}
+
+	git.upstream.Behind = 0
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.ForcePushNeeded {
+		t.Error("ForcePushNeeded should not be set when only ahead")
+	}
+}
+
+func TestBuild_NoUpstream(t *testing.T) {
+	cfg := config.Default()
+
+	git := &mockGitProvider{
+		branch:      "feature",
+		upstreamErr: errors.New("no upstream configured"),
+		gitDir:      "/repo/.git",
+	}
+	cache := &mockCacheProvider{fetchBranch: true}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, nil, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+
+	if data.GitBranch != "feature" || data.GitAhead != 0 || data.GitBehind != 0 {
+		t.Errorf("branch=%q ahead=%d behind=%d; want feature, 0, 0", data.GitBranch, data.GitAhead, data.GitBehind)
+	}
+}
+
+func TestFileTypeCounts(t *testing.T) {
+	got := fileTypeCounts(map[string]int{".md": 1, ".go": 4, "Makefile": 1})
+	want := []template.FileTypeCount{{Ext: ".go", Count: 4}, {Ext: ".md", Count: 1}, {Ext: "Makefile", Count: 1}}
+	if !slices.Equal(got, want) {
+		t.Errorf("fileTypeCounts() = %v, want %v", got, want)
+	}
+	if got := fileTypeCounts(nil); got != nil {
+		t.Errorf("fileTypeCounts(nil) = %v, want nil", got)
+	}
+}
+
+func TestTruncatePath(t *testing.T) {
+	tests := []struct {
+		path string
+		max  int
+		want string
+	}{
+		{"main.go", 30, "main.go"},
+		{"internal/cache/cache.go", 23, "internal/cache/cache.go"},
+		{"internal/cache/cache.go", 20, "…/cache/cache.go"},
+		{"internal/cache/cache.go", 12, "…/cache.go"},
+		{"a/very_long_generated_file_name.pb.go", 12, "…_name.pb.go"},
+		{"", 10, ""},
+	}
+	for _, tt := range tests {
+		if got := truncatePath(tt.path, tt.max); got != tt.want {
+			t.Errorf("truncatePath(%q, %d) = %q, want %q", tt.path, tt.max, got, tt.want)
+		}
+	}
+}
+
 func TestBuild_GitHubFailure(t *testing.T) {
 	cfg := config.Default()
 
@@ -266,7 +553,7 @@ func TestBuild_GitHubFailure(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Should still have git data
 	if data.GitBranch != "main" {
@@ -278,6 +565,343 @@ func TestBuild_GitHubFailure(t *testing.T) {
 	}
 }
 
+func TestBuild_GitHubRateLimited(t *testing.T) {
+	cfg := config.Default()
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+	gh := &mockGitHubProvider{err: &github.RateLimitError{StatusCode: 403, Reset: time.Now().Add(10 * time.Minute)}}
+	cache := &mockCacheProvider{branchValue: "main", fetchBuild: true}
+	builder := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "")
+	input := Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}}
+
+	// A refused request shows the rate limited symbol instead of nothing
+	data := builder.Build(t.Context(), input)
+	if data.GitHubStatus != "⏳" || data.GitHubBuild != "rate_limited" {
+		t.Errorf("GitHubStatus = %q (%q), want ⏳ (rate_limited)", data.GitHubStatus, data.GitHubBuild)
+	}
+	until, ok := cache.rateLimits["github.com"]
+	if !ok || time.Until(until) < 9*time.Minute || time.Until(until) > 10*time.Minute {
+		t.Errorf("rate limit recorded until %v, want the reset 10 minutes out", until)
+	}
+
+	// Later renders don't ask until the limit resets
+	calls := gh.calls
+	data = builder.Build(t.Context(), input)
+	if gh.calls != calls {
+		t.Errorf("GetBuildStatus called %d more times while rate limited, want 0", gh.calls-calls)
+	}
+	if data.GitHubStatus != "⏳" {
+		t.Errorf("GitHubStatus while rate limited = %q, want ⏳", data.GitHubStatus)
+	}
+
+	// A status still cached is served while rate limited
+	cache.fetchBuild, cache.buildStatus = false, github.StatusSuccess
+	data = builder.Build(t.Context(), input)
+	if data.GitHubStatus != "✅" {
+		t.Errorf("GitHubStatus cached while rate limited = %q, want ✅", data.GitHubStatus)
+	}
+	cache.fetchBuild = true
+
+	// A reset claimed to be much further out is retried sooner
+	cache.rateLimits = nil
+	gh.err = &github.RateLimitError{StatusCode: 429, Reset: time.Now().Add(24 * time.Hour)}
+	builder.Build(t.Context(), input)
+	if until := cache.rateLimits["github.com"]; time.Until(until) > maxRateLimitBackoff {
+		t.Errorf("rate limit recorded until %v, want at most %v out", until, maxRateLimitBackoff)
+	}
+}
+
+func TestBuild_GitHubFailedJob(t *testing.T) {
+	cfg := config.Default()
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	gh := &mockGitHubProvider{
+		status:    github.StatusFailure,
+		failedJob: github.FailedJob{Name: "unit-tests", RunURL: "https://github.com/owner/repo/actions/runs/1"},
+	}
+
+	cache := &mockCacheProvider{
+		branchValue: "main",
+		fetchBuild:  true,
+	}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"}})
+
+	if data.GitHubStatus != "❌" {
+		t.Errorf("GitHubStatus = %q, want %q", data.GitHubStatus, "❌")
+	}
+	if data.GitHubFailedJob != "unit-tests" {
+		t.Errorf("GitHubFailedJob = %q, want %q", data.GitHubFailedJob, "unit-tests")
+	}
+	if data.GitHubRunURL != "https://github.com/owner/repo/actions/runs/1" {
+		t.Errorf("GitHubRunURL = %q, want run URL", data.GitHubRunURL)
+	}
+}
+
+func TestBuild_GitHubWorkflows(t *testing.T) {
+	cfg := config.Default()
+	cfg.GitHubWorkflow = config.Workflows{"test", "lint"}
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	// The combined status comes from the workflows, not another lookup
+	gh := &mockGitHubProvider{
+		status: github.StatusSuccess,
+		workflows: []github.WorkflowStatus{
+			{Name: "test", Status: github.StatusSuccess},
+			{Name: "lint", Status: github.StatusPending},
+		},
+	}
+
+	cache := &mockCacheProvider{
+		branchValue: "main",
+		fetchBuild:  true,
+	}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"}})
+
+	if data.GitHubStatus != "🔄" || data.GitHubBuild != "pending" {
+		t.Errorf("GitHubStatus = %q (%q), want the combined pending status", data.GitHubStatus, data.GitHubBuild)
+	}
+	want := []template.WorkflowStatus{
+		{Name: "test", Status: "✅", Build: "success"},
+		{Name: "lint", Status: "🔄", Build: "pending"},
+	}
+	if !reflect.DeepEqual(data.GitHubWorkflows, want) {
+		t.Errorf("GitHubWorkflows = %+v, want %+v", data.GitHubWorkflows, want)
+	}
+
+	cfg.GitHubWorkflow = config.Workflows{"test"}
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"}})
+	if data.GitHubWorkflows != nil || data.GitHubStatus != "✅" {
+		t.Errorf("one workflow: GitHubWorkflows = %+v, GitHubStatus = %q; want none and the workflow's own status", data.GitHubWorkflows, data.GitHubStatus)
+	}
+}
+
+func TestBuild_GitHubSuccessSkipsFailedJob(t *testing.T) {
+	cfg := config.Default()
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	gh := &mockGitHubProvider{
+		status:    github.StatusSuccess,
+		failedJob: github.FailedJob{Name: "should-not-appear"},
+	}
+
+	cache := &mockCacheProvider{
+		branchValue: "main",
+		fetchBuild:  true,
+	}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"}})
+
+	if data.GitHubFailedJob != "" {
+		t.Errorf("GitHubFailedJob = %q, want empty for successful build", data.GitHubFailedJob)
+	}
+}
+
+func TestBuild_GitHubHistory(t *testing.T) {
+	cfg := config.Default()
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	cache := &mockCacheProvider{
+		branchValue:  "main",
+		buildStatus:  github.StatusSuccess,
+		buildHistory: []github.BuildStatus{github.StatusFailure, github.StatusSuccess},
+	}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, &mockGitHubProvider{}, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"}})
+
+	if want := []string{"failure", "success"}; !slices.Equal(data.GitHubHistory, want) {
+		t.Errorf("GitHubHistory = %v, want %v", data.GitHubHistory, want)
+	}
+}
+
+func TestBuild_GitHubStaleWhileRevalidate(t *testing.T) {
+	cfg := config.Default()
+	ttl := time.Duration(cfg.GitHubTTL) * time.Second
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	tests := []struct {
+		name           string
+		age            time.Duration
+		wantRevalidate bool
+	}{
+		{"fresh", ttl / 2, false},
+		{"stale", ttl + time.Second, true},
+		{"too stale", maxStale, false},
+		{"not cached", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &mockCacheProvider{branchValue: "main", buildStatus: github.StatusSuccess, buildAge: tt.age}
+			builder := NewBuilderWithDeps(&cfg, cache, git, &mockGitHubProvider{}, nil, "")
+			revalidated := 0
+			opts := BuildOptions{Revalidate: func() { revalidated++ }}
+
+			data, _ := builder.BuildWithOptions(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}}, opts)
+
+			if data.GitHubStatus != "✅" {
+				t.Errorf("GitHubStatus = %q, want the cached status", data.GitHubStatus)
+			}
+			if got := revalidated == 1; got != tt.wantRevalidate {
+				t.Errorf("revalidate called %d times, want revalidation %v", revalidated, tt.wantRevalidate)
+			}
+			// A stale status must be served from cache rather than refetched
+			if wantTTL := ttl; tt.wantRevalidate && cache.buildTTL == wantTTL || !tt.wantRevalidate && cache.buildTTL != wantTTL {
+				t.Errorf("GetGitHubBuild ttl = %v with revalidation %v", cache.buildTTL, tt.wantRevalidate)
+			}
+		})
+	}
+}
+
+func TestBuild_PullRequest(t *testing.T) {
+	cfg := config.Default()
+
+	git := &mockGitProvider{
+		branch:    "feature",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	gh := &mockGitHubProvider{
+		status: github.StatusSuccess,
+		pr:     github.PullRequest{Number: 123, URL: "https://github.com/owner/repo/pull/123", State: "open", ReviewState: "approved", Mergeable: "clean"},
+	}
+
+	cache := &mockCacheProvider{branchValue: "feature", fetchBuild: true}
+
+	// Disabled by default
+	data := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "").Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.PRNumber != 0 {
+		t.Errorf("PRNumber = %d, want 0 when disabled", data.PRNumber)
+	}
+
+	cfg.GitHubPR = true
+	data = NewBuilderWithDeps(&cfg, cache, git, gh, nil, "").Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.PRNumber != 123 || data.PRState != "open" || data.PRReviewState != "approved" || data.PRMergeable != "clean" {
+		t.Errorf("PR fields = %d %q %q %q, want 123 open approved clean", data.PRNumber, data.PRState, data.PRReviewState, data.PRMergeable)
+	}
+	if data.PRURL != "https://github.com/owner/repo/pull/123" {
+		t.Errorf("PRURL = %q", data.PRURL)
+	}
+}
+
+func TestBuild_ChecksSummary(t *testing.T) {
+	cfg := config.Default()
+	cfg.GitHubPR = true
+
+	git := &mockGitProvider{
+		branch:    "feature",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	gh := &mockGitHubProvider{
+		status: github.StatusPending,
+		checks: github.ChecksSummary{Passed: 7, Failed: 1, Pending: 1, Total: 9},
+	}
+
+	cache := &mockCacheProvider{branchValue: "feature", fetchBuild: true}
+
+	builder := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "")
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/path/to/myproject"}})
+
+	if data.ChecksPassed != 7 || data.ChecksFailed != 1 || data.ChecksPending != 1 || data.ChecksTotal != 9 {
+		t.Errorf("Checks = %d/%d/%d of %d, want 7/1/1 of 9",
+			data.ChecksPassed, data.ChecksFailed, data.ChecksPending, data.ChecksTotal)
+	}
+}
+
+func TestBuild_DefaultBranchStatus(t *testing.T) {
+	git := &mockGitProvider{
+		branch:    "feature",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+
+	gh := &mockGitHubProvider{
+		status:    github.StatusSuccess,
+		mainState: github.BranchStatus{Branch: "main", Status: github.StatusFailure},
+	}
+
+	cache := &mockCacheProvider{branchValue: "feature", fetchBuild: true}
+
+	// Disabled by default
+	cfg := config.Default()
+	data := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "").Build(t.Context(), Input{})
+	if data.GitHubMainStatus != "" {
+		t.Errorf("GitHubMainStatus = %q, want empty when disabled", data.GitHubMainStatus)
+	}
+
+	cfg.GitHubDefaultBranch = true
+	data = NewBuilderWithDeps(&cfg, cache, git, gh, nil, "").Build(t.Context(), Input{})
+	if data.GitHubMainBranch != "main" {
+		t.Errorf("GitHubMainBranch = %q, want %q", data.GitHubMainBranch, "main")
+	}
+	if data.GitHubMainStatus != "❌" {
+		t.Errorf("GitHubMainStatus = %q, want %q", data.GitHubMainStatus, "❌")
+	}
+	if data.GitHubStatus != "✅" {
+		t.Errorf("GitHubStatus = %q, want %q", data.GitHubStatus, "✅")
+	}
+}
+
+func TestBuild_ActionsUsage(t *testing.T) {
+	cfg := config.Default()
+	cfg.GitHubUsage = true
+
+	git := &mockGitProvider{
+		branch:    "main",
+		remoteURL: "git@github.com:owner/repo.git",
+		gitDir:    "/repo/.git",
+	}
+	gh := &mockGitHubProvider{
+		status: github.StatusSuccess,
+		usage:  github.ActionsUsage{MinutesUsed: 1800, MinutesIncluded: 2000, MinutesRemaining: 200},
+	}
+	cache := &mockCacheProvider{branchValue: "main", fetchBuild: true}
+
+	data := NewBuilderWithDeps(&cfg, cache, git, gh, nil, "").Build(t.Context(), Input{})
+
+	if data.ActionsMinutesRemaining != 200 {
+		t.Errorf("ActionsMinutesRemaining = %d, want 200", data.ActionsMinutesRemaining)
+	}
+	if data.ActionsMinutesIncluded != 2000 {
+		t.Errorf("ActionsMinutesIncluded = %d, want 2000", data.ActionsMinutesIncluded)
+	}
+}
+
 func TestBuild_CacheHit(t *testing.T) {
 	cfg := config.Default()
 
@@ -307,7 +931,7 @@ func TestBuild_CacheHit(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.GitBranch != "cached-branch" {
 		t.Errorf("GitBranch = %q, want %q (from cache)", data.GitBranch, "cached-branch")
@@ -346,7 +970,7 @@ func TestBuild_CacheMiss(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.GitBranch != "fresh-branch" {
 		t.Errorf("GitBranch = %q, want %q (fresh fetch)", data.GitBranch, "fresh-branch")
@@ -384,7 +1008,7 @@ func TestBuild_PartialFailure(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Should still have branch
 	if data.GitBranch != "main" {
@@ -411,7 +1035,7 @@ func TestBuild_DefaultModel(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.Model != "Claude" {
 		t.Errorf("Model = %q, want %q (default)", data.Model, "Claude")
@@ -429,7 +1053,7 @@ func TestBuild_DirBasename(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/very/long/path/to/myproject"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.Dir != "myproject" {
 		t.Errorf("Dir = %q, want %q (basename only)", data.Dir, "myproject")
@@ -437,7 +1061,7 @@ func TestBuild_DirBasename(t *testing.T) {
 }
 
 func TestNewBuilder_NilConfig(t *testing.T) {
-	_, err := NewBuilder(nil, "/tmp")
+	_, err := NewBuilder(t.Context(), nil, "/tmp")
 	if err == nil {
 		t.Error("NewBuilder() expected error for nil config")
 	}
@@ -446,18 +1070,322 @@ func TestNewBuilder_NilConfig(t *testing.T) {
 	}
 }
 
+func TestBuild_InstallWarning(t *testing.T) {
+	cfg := config.Default()
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, nil, nil, nil, "")
+
+	// Without a settings path the check is skipped
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.InstallWarning != "" {
+		t.Errorf("InstallWarning = %q, want empty", data.InstallWarning)
+	}
+
+	builder.settingsPath = t.TempDir() + "/settings.json"
+	content := `{"statusLine": {"type": "command", "command": "/moved/away/claude-status"}}`
+	if err := writeTestFile(builder.settingsPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.InstallWarning != "statusLine binary /moved/away/claude-status is missing" {
+		t.Errorf("InstallWarning = %q, want the missing binary", data.InstallWarning)
+	}
+}
+
+func TestBuild_BranchAge(t *testing.T) {
+	cfg := config.Default()
+	gitDir := t.TempDir()
+
+	// Checked out three days ago
+	headPath := gitDir + "/HEAD"
+	if err := writeTestFile(headPath, "ref: refs/heads/feature\n"); err != nil {
+		t.Fatal(err)
+	}
+	checkout := time.Now().Add(-(3*24 + 1) * time.Hour)
+	if err := os.Chtimes(headPath, checkout, checkout); err != nil {
+		t.Fatal(err)
+	}
+
+	git := &mockGitProvider{branch: "feature", gitDir: gitDir}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{branchValue: "feature"}, git, nil, nil, "")
+
+	// Without a state store the age is not tracked
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.BranchAge != "" {
+		t.Errorf("BranchAge = %q, want empty without a state store", data.BranchAge)
+	}
+
+	builder.state = state.NewStore(t.TempDir() + "/state.json")
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.BranchAge != "3d" || data.BranchAgeDays != 3 {
+		t.Errorf("BranchAge = %q (%d days), want 3d", data.BranchAge, data.BranchAgeDays)
+	}
+
+	// Not tracked mid-operation or on a detached HEAD
+	git.operation = "rebase"
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.BranchAge != "" {
+		t.Errorf("BranchAge = %q, want empty during a rebase", data.BranchAge)
+	}
+	git.operation = ""
+	builder.cache = &mockCacheProvider{branchValue: "HEAD"}
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.BranchAge != "" {
+		t.Errorf("BranchAge = %q, want empty on a detached HEAD", data.BranchAge)
+	}
+}
+
+func TestBuild_DirtySince(t *testing.T) {
+	cfg := config.Default()
+	gitDir := t.TempDir()
+	statePath := t.TempDir() + "/state.json"
+
+	// First seen dirty two and a half hours ago
+	earlier := time.Now().Add(-150 * time.Minute)
+	state.NewStoreWithClock(statePath, func() time.Time { return earlier }).DirtySince(gitDir, true)
+
+	cache := &mockCacheProvider{statusValue: "±3"}
+	builder := NewBuilderWithDeps(&cfg, cache, &mockGitProvider{gitDir: gitDir}, nil, nil, "")
+	builder.state = state.NewStore(statePath)
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.DirtySince != "2h" || data.DirtyMinutes != 150 {
+		t.Errorf("DirtySince = %q (%d minutes), want 2h (150 minutes)", data.DirtySince, data.DirtyMinutes)
+	}
+
+	// A clean tree clears it
+	cache.statusValue = ""
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.DirtySince != "" || data.DirtyMinutes != 0 {
+		t.Errorf("DirtySince = %q (%d minutes), want empty when clean", data.DirtySince, data.DirtyMinutes)
+	}
+}
+
+func TestBuild_ProtectedBranchDirty(t *testing.T) {
+	tests := []struct {
+		name      string
+		branch    string
+		status    string
+		protected []string
+		want      bool
+	}{
+		{"dirty main", "main", "±3", nil, true},
+		{"clean main", "main", "", nil, false},
+		{"dirty feature", "feature/login", "±3", nil, false},
+		{"glob", "release/1.2", "±1", []string{"release/*"}, true},
+		{"turned off", "main", "±3", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			if tt.protected != nil {
+				cfg.ProtectedBranches = tt.protected
+			}
+			cache := &mockCacheProvider{branchValue: tt.branch, statusValue: tt.status}
+			builder := NewBuilderWithDeps(&cfg, cache, &mockGitProvider{gitDir: "/repo/.git"}, nil, nil, "/repo")
+
+			data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+			if data.ProtectedBranchDirty != tt.want {
+				t.Errorf("ProtectedBranchDirty = %v, want %v", data.ProtectedBranchDirty, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_TasksClosedToday(t *testing.T) {
+	cfg := config.Default()
+	statePath := t.TempDir() + "/state.json"
+	state.NewStore(statePath).ClosedToday("/project", 4)
+
+	taskProvider := &mockTaskProvider{name: "test", available: true, stats: tasks.Stats{ClosedIssues: 6}}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{fetchTasks: true}, nil, nil, taskProvider, "/project")
+	builder.state = state.NewStore(statePath)
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.TasksClosedToday != 2 {
+		t.Errorf("TasksClosedToday = %d, want 2", data.TasksClosedToday)
+	}
+}
+
+func TestBuild_GitStash(t *testing.T) {
+	cfg := config.Default()
+	git := &mockGitProvider{branch: "main", gitDir: "/repo/.git", stashCount: 2}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, git, nil, nil, "/repo")
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.GitStash != 2 {
+		t.Errorf("GitStash = %d, want 2", data.GitStash)
+	}
+}
+
+func TestBuild_LastCommit(t *testing.T) {
+	cfg := config.Default()
+	gitMock := &mockGitProvider{branch: "main", gitDir: "/repo/.git", lastCommit: git.Commit{
+		SHA:     "abc1234",
+		Subject: "fix: cache bug where entries for deleted branches were never pruned from disk",
+		Time:    time.Now().Add(-3 * time.Minute),
+	}}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, gitMock, nil, nil, "/repo")
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.GitLastCommitSHA != "abc1234" {
+		t.Errorf("GitLastCommitSHA = %q, want abc1234", data.GitLastCommitSHA)
+	}
+	if want := "fix: cache bug where entries for deleted branches…"; data.GitLastCommitMsg != want {
+		t.Errorf("GitLastCommitMsg = %q, want %q", data.GitLastCommitMsg, want)
+	}
+	if data.GitLastCommitAge != "3m" {
+		t.Errorf("GitLastCommitAge = %q, want 3m", data.GitLastCommitAge)
+	}
+}
+
+func TestBuild_GitConflicts(t *testing.T) {
+	cfg := config.Default()
+	gitMock := &mockGitProvider{branch: "HEAD", gitDir: "/repo/.git", operation: git.OpMerging,
+		diffStats: git.DiffStats{ModifiedFiles: 1, Conflicts: 3}}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{fetchDiffStats: true}, gitMock, nil, nil, "/repo")
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.GitConflicts != 3 {
+		t.Errorf("GitConflicts = %d, want 3", data.GitConflicts)
+	}
+}
+
+func TestBuild_DetachedHead(t *testing.T) {
+	cfg := config.Default()
+	gitMock := &mockGitProvider{gitDir: "/repo/.git", detachedName: "v1.2.0"}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{branchValue: "HEAD"}, gitMock, nil, nil, "/repo")
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.GitBranch != "v1.2.0" || !data.GitDetached {
+		t.Errorf("GitBranch = %q, GitDetached = %v; want v1.2.0 and detached", data.GitBranch, data.GitDetached)
+	}
+
+	builder = NewBuilderWithDeps(&cfg, &mockCacheProvider{branchValue: "main"}, gitMock, nil, nil, "/repo")
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.GitBranch != "main" || data.GitDetached {
+		t.Errorf("GitBranch = %q, GitDetached = %v; want main and not detached", data.GitBranch, data.GitDetached)
+	}
+}
+
+func TestBuild_GitOperation(t *testing.T) {
+	cfg := config.Default()
+	gitMock := &mockGitProvider{branch: "HEAD", gitDir: "/repo/.git", operation: git.OpRebasing}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, gitMock, nil, nil, "/repo")
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.GitOperation != "rebasing" {
+		t.Errorf("GitOperation = %q, want rebasing", data.GitOperation)
+	}
+}
+
+func TestBuild_SessionCommits(t *testing.T) {
+	cfg := config.Default()
+	transcript := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(transcript, []byte(`{"type":"user","timestamp":"2025-06-01T12:00:00Z"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitMock := &mockGitProvider{branch: "main", gitDir: "/repo/.git", commitsSince: 3, lastCommit: git.Commit{SHA: "abc1234"}}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, gitMock, nil, nil, "/repo")
+
+	data := builder.Build(t.Context(), Input{TranscriptPath: transcript, Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.SessionCommits != 3 {
+		t.Errorf("SessionCommits = %d, want 3", data.SessionCommits)
+	}
+	if want := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC); !gitMock.since.Equal(want) {
+		t.Errorf("CommitsSince(%v), want the transcript start %v", gitMock.since, want)
+	}
+}
+
+func TestBuild_SessionDiff(t *testing.T) {
+	cfg := config.Default()
+	gitMock := &mockGitProvider{branch: "main", gitDir: "/repo/.git", headSHA: "aaa",
+		diffSince: git.LineStats{Additions: 12, Deletions: 4}}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, gitMock, nil, nil, "/repo")
+	builder.state = state.NewStore(t.TempDir() + "/state.json")
+	input := Input{SessionID: "s1", Workspace: WorkspaceInfo{CurrentDir: "/repo"}}
+
+	// Changes already uncommitted when the session started don't count
+	data := builder.Build(t.Context(), input)
+	if data.SessionAdditions != 0 || data.SessionDeletions != 0 {
+		t.Errorf("Session = +%d -%d at start, want 0", data.SessionAdditions, data.SessionDeletions)
+	}
+
+	// Committing moves HEAD, but the session keeps diffing against its start
+	gitMock.headSHA = "bbb"
+	gitMock.diffSince = git.LineStats{Additions: 50, Deletions: 10}
+	data = builder.Build(t.Context(), input)
+	if data.SessionAdditions != 38 || data.SessionDeletions != 6 {
+		t.Errorf("Session = +%d -%d, want +38 -6", data.SessionAdditions, data.SessionDeletions)
+	}
+
+	// Without a session ID nothing is tracked
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.SessionAdditions != 0 {
+		t.Errorf("SessionAdditions = %d without a session, want 0", data.SessionAdditions)
+	}
+}
+
+func TestBuild_Workspaces(t *testing.T) {
+	cfg := config.Default()
+	main := &mockGitProvider{gitDir: "/repo/.git", summary: git.Summary{Branch: "feature", Changed: 3}}
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, main, nil, nil, "/repo")
+	repos := map[string]GitProvider{
+		"/libs/shared": &mockGitProvider{gitDir: "/libs/shared/.git", summary: git.Summary{Branch: "main", Changed: 2}},
+		"/repo/docs":   main, // Same repository as the current dir
+	}
+	builder.openRepo = func(_ context.Context, dir string) (GitProvider, error) {
+		if repo, ok := repos[dir]; ok {
+			return repo, nil
+		}
+		return nil, errors.New("not a git repository")
+	}
+
+	// Without additional directories only the usual git fields are set
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/repo"}})
+	if data.Workspaces != nil || data.WorkspaceChanged != 0 {
+		t.Errorf("Workspaces = %v (%d changed), want none", data.Workspaces, data.WorkspaceChanged)
+	}
+
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{
+		CurrentDir: "/repo",
+		AddedDirs:  []string{"/libs/shared", "/repo/docs", "/tmp/notes"},
+	}})
+	want := []template.WorkspaceDir{
+		{Dir: "repo", Branch: "feature", Changed: 3},
+		{Dir: "shared", Branch: "main", Changed: 2},
+	}
+	if !reflect.DeepEqual(data.Workspaces, want) {
+		t.Errorf("Workspaces = %+v, want %+v", data.Workspaces, want)
+	}
+	if data.WorkspaceChanged != 5 {
+		t.Errorf("WorkspaceChanged = %d, want 5", data.WorkspaceChanged)
+	}
+}
+
+func TestBuild_Platform(t *testing.T) {
+	cfg := config.Default()
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, nil, nil, nil, "")
+
+	data := builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.OS != runtime.GOOS || data.Arch != runtime.GOARCH {
+		t.Errorf("OS/Arch = %s/%s, want %s/%s", data.OS, data.Arch, runtime.GOOS, runtime.GOARCH)
+	}
+}
+
 func TestBuild_TokenMetrics(t *testing.T) {
 	cfg := config.Default()
 	cache := &mockCacheProvider{}
 
 	builder := NewBuilderWithDeps(&cfg, cache, nil, nil, nil, "")
+	builder.state = state.NewStore(t.TempDir() + "/state.json")
 
 	// Create a temporary transcript file
 	tmpDir := t.TempDir()
 	transcriptPath := tmpDir + "/transcript.jsonl"
 
 	jsonlContent := `{"type":"summary","summary":"Test session"}
-{"parentUuid":"123","isSidechain":false,"type":"assistant","message":{"role":"assistant","usage":{"input_tokens":10000,"output_tokens":5000,"cache_read_input_tokens":30000,"cache_creation_input_tokens":5000}}}
+{"parentUuid":"123","isSidechain":false,"type":"assistant","message":{"role":"assistant","model":"claude-opus-4-5-20251101","usage":{"input_tokens":10000,"output_tokens":5000,"cache_read_input_tokens":30000,"cache_creation_input_tokens":5000}}}
 `
 	if err := writeTestFile(transcriptPath, jsonlContent); err != nil {
 		t.Fatalf("Failed to write test file: %v", err)
@@ -469,7 +1397,7 @@ func TestBuild_TokenMetrics(t *testing.T) {
 		TranscriptPath: transcriptPath,
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Check token metrics are populated (raw values)
 	if data.TokensInput != 10000 {
@@ -489,6 +1417,135 @@ func TestBuild_TokenMetrics(t *testing.T) {
 	if data.ContextPct == 0 {
 		t.Error("ContextPct should not be zero")
 	}
+
+	// Opus 4.5: 10k input ($0.05) + 5k output ($0.125) + 5k cache write ($0.03125) + 30k cache read ($0.015)
+	if data.Cost != "$0.22" || data.CostUSD < 0.2212 || data.CostUSD > 0.2213 {
+		t.Errorf("Cost = %q (%v), want $0.22 (0.22125)", data.Cost, data.CostUSD)
+	}
+
+	// The context length is sampled into the session history
+	if len(data.ContextHistory) != 1 || data.ContextHistory[0] != data.ContextLength {
+		t.Errorf("ContextHistory = %v, want [%d]", data.ContextHistory, data.ContextLength)
+	}
+
+	// And the turn counts toward the project's context usage
+	if usage := builder.state.ContextUsage()["/project"]; usage.Turns != 1 || usage.Peak != data.ContextPctUse {
+		t.Errorf("ContextUsage()[/project] = %+v, want 1 turn at %v%%", usage, data.ContextPctUse)
+	}
+	// And its cost toward the project's daily cost by model
+	if costs := builder.state.Costs(time.Now()); len(costs) != 1 || costs[0].Project != "/project" ||
+		costs[0].Model != "claude-opus-4-5-20251101" || costs[0].Tokens != 50000 || costs[0].CostUSD != data.CostUSD {
+		t.Errorf("Costs() = %+v, want 50k opus tokens in /project costing %v", costs, data.CostUSD)
+	}
+}
+
+func TestUsageProject(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(dir, "repo")
+	sub := filepath.Join(repo, "services", "api")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	repoGit := &mockGitProvider{gitDir: filepath.Join(repo, ".git")}
+	tests := []struct {
+		name      string
+		git       GitProvider
+		workspace WorkspaceInfo
+		want      string
+	}{
+		{"symlinked subdirectory", repoGit, WorkspaceInfo{ProjectDir: filepath.Join(link, "services", "api")}, repo},
+		{"current dir without project dir", repoGit, WorkspaceInfo{CurrentDir: sub}, repo},
+		{"project outside the repository", repoGit, WorkspaceInfo{ProjectDir: dir}, dir},
+		{"no repository", nil, WorkspaceInfo{ProjectDir: filepath.Join(link, "services")}, filepath.Join(repo, "services")},
+		{"missing directory", nil, WorkspaceInfo{ProjectDir: "/gone/project/"}, "/gone/project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, tt.git, nil, nil, "")
+			if got := builder.usageProject(Input{Workspace: tt.workspace}); got != tt.want {
+				t.Errorf("usageProject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBuilderWithCache_ResolvesSymlinks(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "target")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	if got := NewBuilderWithCache(t.Context(), &cfg, &mockCacheProvider{}, link).workDir; got != target {
+		t.Errorf("workDir = %q, want the resolved %q", got, target)
+	}
+}
+
+func TestBuild_SessionTotals(t *testing.T) {
+	cfg := config.Default()
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, nil, nil, nil, "")
+
+	input, err := ParseInput([]byte(`{
+		"workspace": {"current_dir": "/project"},
+		"cost": {"total_cost_usd": 3.456, "total_duration_ms": 5400000, "total_api_duration_ms": 1200000},
+		"exceeds_200k_tokens": true
+	}`))
+	if err != nil {
+		t.Fatalf("ParseInput() error = %v", err)
+	}
+
+	data := builder.Build(t.Context(), input)
+	if data.CostUSD != 3.456 || data.Cost != "$3.46" {
+		t.Errorf("Cost = %q (%v), want $3.46 (3.456)", data.Cost, data.CostUSD)
+	}
+	if data.SessionDuration != "1h" || data.SessionMinutes != 90 {
+		t.Errorf("SessionDuration = %q (%d min), want 1h (90 min)", data.SessionDuration, data.SessionMinutes)
+	}
+	if !data.ContextOver200k {
+		t.Error("ContextOver200k = false, want true")
+	}
+
+	// Without them, nothing is set
+	data = builder.Build(t.Context(), Input{Workspace: WorkspaceInfo{CurrentDir: "/project"}})
+	if data.Cost != "" || data.SessionDuration != "" || data.ContextOver200k {
+		t.Errorf("Cost = %q, SessionDuration = %q, ContextOver200k = %v; want all empty", data.Cost, data.SessionDuration, data.ContextOver200k)
+	}
+}
+
+func TestBuild_OutputStyleAndAgent(t *testing.T) {
+	cfg := config.Default()
+	builder := NewBuilderWithDeps(&cfg, &mockCacheProvider{}, nil, nil, nil, "")
+
+	input, err := ParseInput([]byte(`{
+		"workspace": {"current_dir": "/project"},
+		"output_style": {"name": "Explanatory"},
+		"agent": {"name": "reviewer"}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseInput() error = %v", err)
+	}
+
+	data := builder.Build(t.Context(), input)
+	if data.OutputStyle != "Explanatory" || data.Agent != "reviewer" {
+		t.Errorf("OutputStyle = %q, Agent = %q; want Explanatory, reviewer", data.OutputStyle, data.Agent)
+	}
 }
 
 func TestBuild_TokenMetrics_EmptyPath(t *testing.T) {
@@ -503,7 +1560,7 @@ func TestBuild_TokenMetrics_EmptyPath(t *testing.T) {
 		TranscriptPath: "", // Empty path
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Token metrics should be zero
 	if data.TokensInput != 0 {
@@ -523,7 +1580,7 @@ func TestBuild_TokenMetrics_InvalidPath(t *testing.T) {
 		TranscriptPath: "/nonexistent/path/transcript.jsonl",
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Token metrics should be zero (silent fail)
 	if data.TokensInput != 0 {
@@ -561,7 +1618,7 @@ func TestBuild_TaskStats(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Check task stats are populated (raw values)
 	if !data.HasTasks {
@@ -596,7 +1653,7 @@ func TestBuild_NoTasks(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.HasTasks {
 		t.Error("HasTasks should be false when task provider is nil")
@@ -618,7 +1675,7 @@ func TestSetPrefix_Simple(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Prefix is stored as plain string (color applied by template)
 	if data.Prefix != "WORK" {
@@ -640,7 +1697,7 @@ func TestSetPrefix_SpecialChars(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Braces are preserved as literal text (no template parsing)
 	if data.Prefix != "{{WORK}}" {
@@ -660,7 +1717,7 @@ func TestSetPrefix_Empty(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	if data.Prefix != "" {
 		t.Errorf("Prefix = %q, want empty", data.Prefix)
@@ -693,7 +1750,7 @@ func TestBuild_TasksZeroValues(t *testing.T) {
 		Workspace: WorkspaceInfo{CurrentDir: "/project"},
 	}
 
-	data := builder.Build(input)
+	data := builder.Build(t.Context(), input)
 
 	// Should have HasTasks true even with zero values
 	if !data.HasTasks {
@@ -0,0 +1,174 @@
+// Package statuslog records rendered status lines when logging is enabled.
+// Claude Code renders on nearly every keystroke, so entries are appended to a
+// small spool file and folded into the log, a JSON array, at most once per
+// FlushInterval rather than rewriting the log on every render.
+package statuslog
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// FlushInterval is the minimum time between rewrites of the log file.
+const FlushInterval = time.Second
+
+// lockTimeout is how long Append and Flush wait for another process's lock.
+var lockTimeout = 5 * time.Second
+
+// Log appends entries to the log file at a path. Appends and flushes are
+// serialized across processes with a file lock.
+type Log struct {
+	path string
+	lock *flock.Flock
+	now  func() time.Time
+}
+
+// New creates a log backed by the file at path.
+func New(path string) *Log {
+	return NewWithClock(path, time.Now)
+}
+
+// NewWithClock creates a log with a custom clock.
+func NewWithClock(path string, now func() time.Time) *Log {
+	return &Log{
+		path: path,
+		lock: flock.New(path + ".lock"),
+		now:  now,
+	}
+}
+
+// SpoolPath returns the file entries wait in until the next flush.
+func (l *Log) SpoolPath() string {
+	return l.path + ".pending"
+}
+
+// Append records entry. It is written to the log file itself only if the
+// log hasn't been rewritten within FlushInterval; otherwise it waits in the
+// spool for a later Append or Flush.
+func (l *Log) Append(entry any) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	unlock, err := l.acquire()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(l.SpoolPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if !l.due() {
+		return nil
+	}
+	return l.flush()
+}
+
+// Flush folds spooled entries into the log file now. The last renders of a
+// burst leave entries spooled until the next one, so processes that outlive
+// renders (the daemon, the Stop hook) call it.
+func (l *Log) Flush() error {
+	unlock, err := l.acquire()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return l.flush()
+}
+
+// acquire takes the file lock, giving up after lockTimeout rather than
+// blocking a render: writing without it could lose another process's
+// entries. It returns the function that releases it.
+func (l *Log) acquire() (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+	locked, err := l.lock.TryLockContext(ctx, 10*time.Millisecond)
+	if err != nil || !locked {
+		return nil, fmt.Errorf("failed to lock log: %w", cmp.Or(err, ctx.Err()))
+	}
+	return func() { l.lock.Unlock() }, nil
+}
+
+// due reports whether the log file was last written FlushInterval or more ago.
+func (l *Log) due() bool {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return true
+	}
+	return l.now().Sub(info.ModTime()) >= FlushInterval
+}
+
+// flush appends the spooled entries to the log file and empties the spool.
+// The caller holds the lock.
+func (l *Log) flush() error {
+	spool, err := os.ReadFile(l.SpoolPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	entries := l.load()
+	for line := range bytes.Lines(spool) {
+		line = bytes.TrimSpace(line)
+		if !json.Valid(line) {
+			// A write cut short by a crash; the rest of the spool is fine
+			continue
+		}
+		entries = append(entries, json.RawMessage(line))
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal log data: %w", err)
+	}
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Remove(l.SpoolPath())
+}
+
+// load reads the entries already in the log file. A missing or corrupt file
+// yields none.
+func (l *Log) load() []json.RawMessage {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// Log file corrupted, start fresh
+		slog.Warn("log file corrupted, starting fresh", "err", err)
+		return nil
+	}
+	return entries
+}
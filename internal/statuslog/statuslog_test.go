@@ -0,0 +1,132 @@
+package statuslog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+type entry struct {
+	N int `json:"n"`
+}
+
+// readLog returns the entries in the log file.
+func readLog(t *testing.T, path string) []entry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("log is not a JSON array: %v\n%s", err, data)
+	}
+	return entries
+}
+
+func TestAppend_Debounced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "status_line.json")
+	now := time.Now()
+	log := NewWithClock(path, func() time.Time { return now })
+
+	// The first entry creates the log
+	if err := log.Append(entry{1}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if got := readLog(t, path); len(got) != 1 {
+		t.Fatalf("log = %v, want the first entry", got)
+	}
+
+	// Renders within FlushInterval only spool
+	info, _ := os.Stat(path)
+	now = info.ModTime().Add(FlushInterval / 2)
+	log.Append(entry{2})
+	log.Append(entry{3})
+	if got := readLog(t, path); len(got) != 1 {
+		t.Errorf("log = %v, want entries 2 and 3 still spooled", got)
+	}
+
+	// The next render after the interval flushes everything in order
+	now = info.ModTime().Add(FlushInterval)
+	log.Append(entry{4})
+	got := readLog(t, path)
+	if len(got) != 4 || got[1].N != 2 || got[3].N != 4 {
+		t.Errorf("log = %v, want entries 1-4", got)
+	}
+	if _, err := os.Stat(log.SpoolPath()); !os.IsNotExist(err) {
+		t.Errorf("spool still exists after flush: %v", err)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status_line.json")
+	now := time.Now()
+	log := NewWithClock(path, func() time.Time { return now })
+	log.Append(entry{1})
+	log.Append(entry{2})
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := readLog(t, path); len(got) != 2 {
+		t.Errorf("log = %v, want both entries", got)
+	}
+
+	// Nothing spooled: a no-op
+	if err := log.Flush(); err != nil {
+		t.Errorf("Flush() with an empty spool error = %v", err)
+	}
+}
+
+func TestFlush_SkipsTruncatedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status_line.json")
+	log := New(path)
+	if err := os.WriteFile(log.SpoolPath(), []byte("{\"n\":1}\n{\"n\":\n{\"n\":3}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := readLog(t, path); len(got) != 2 || got[1].N != 3 {
+		t.Errorf("log = %v, want entries 1 and 3", got)
+	}
+}
+
+func TestFlush_CorruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status_line.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := New(path)
+	log.Append(entry{1})
+	log.Flush()
+	if got := readLog(t, path); len(got) != 1 {
+		t.Errorf("log = %v, want a fresh log with the new entry", got)
+	}
+}
+
+func TestAppend_LockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status_line.json")
+	lockTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { lockTimeout = 5 * time.Second })
+
+	// Another process holds the lock
+	other := flock.New(path + ".lock")
+	if locked, err := other.TryLock(); err != nil || !locked {
+		t.Fatalf("TryLock() = %v, %v", locked, err)
+	}
+	defer other.Unlock()
+
+	if err := New(path).Append(entry{N: 1}); err == nil {
+		t.Error("Append() without the lock succeeded, want an error")
+	}
+	if _, err := os.Stat(path + ".pending"); !os.IsNotExist(err) {
+		t.Errorf("spool written without the lock: %v", err)
+	}
+}
@@ -2,7 +2,10 @@ package tasks
 
 import (
 	"log/slog"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 )
 
 // ProviderFactory creates a Provider for a given working directory.
@@ -48,3 +51,59 @@ func SelectProvider(workDir string) Provider {
 	slog.Debug("no task tracker found", "workDir", workDir)
 	return nil
 }
+
+// FindProvider is SelectProvider for workDir or, failing that, the nearest
+// parent with a task tracker, so tasks show up when Claude Code is working in
+// a subdirectory. The search stops at projectDir (Claude Code's project root)
+// when workDir is inside it, and otherwise at the repository root.
+func FindProvider(workDir, projectDir string) Provider {
+	provider, _ := FindProviderDir(workDir, projectDir)
+	return provider
+}
+
+// FindProviderDir is FindProvider that also returns the directory the
+// tracker was found in, which every subdirectory searching up to it shares.
+func FindProviderDir(workDir, projectDir string) (Provider, string) {
+	for _, dir := range searchDirs(workDir, projectDir) {
+		if provider := SelectProvider(dir); provider != nil {
+			return provider, dir
+		}
+	}
+	return nil, ""
+}
+
+// searchDirs lists workDir and its parents up to projectDir, if workDir is
+// inside it, or else up to the repository root: the first directory with a
+// .git entry, which is a file in linked worktrees. Outside of both, only
+// workDir is searched.
+func searchDirs(workDir, projectDir string) []string {
+	dir := filepath.Clean(workDir)
+	stop := ""
+	if projectDir != "" && IsWithin(dir, filepath.Clean(projectDir)) {
+		stop = filepath.Clean(projectDir)
+	}
+
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if dir == stop {
+			return dirs
+		}
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil && stop == "" {
+			return dirs
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// Reached / without finding a boundary
+			return dirs[:1]
+		}
+		dir = parent
+	}
+}
+
+// IsWithin reports whether dir is root or one of its subdirectories. Both
+// are compared as given, so callers resolve symlinks first if they matter.
+func IsWithin(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -1,6 +1,9 @@
 package tasks
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -33,10 +36,10 @@ type mockProvider struct {
 	available bool
 }
 
-func (m *mockProvider) Name() string                    { return m.name }
-func (m *mockProvider) Available() bool                 { return m.available }
-func (m *mockProvider) GetStats() (Stats, error)        { return Stats{}, nil }
-func (m *mockProvider) GetNextTask() (string, error)    { return "", nil }
+func (m *mockProvider) Name() string                                { return m.name }
+func (m *mockProvider) Available() bool                             { return m.available }
+func (m *mockProvider) GetStats(context.Context) (Stats, error)     { return Stats{}, nil }
+func (m *mockProvider) GetNextTask(context.Context) (string, error) { return "", nil }
 
 func TestSelectProvider_Priority(t *testing.T) {
 	// Save and restore original registry
@@ -102,3 +105,143 @@ func TestSelectProvider_None(t *testing.T) {
 		t.Errorf("SelectProvider() = %v, want nil", provider)
 	}
 }
+
+// registerMarker registers a provider that is available in directories
+// containing marker, and returns the directory it was created for.
+func registerMarker(priority int, name, marker string) *string {
+	var root string
+	RegisterWithPriority(priority, func(workDir string) Provider {
+		_, err := os.Stat(filepath.Join(workDir, marker))
+		if err == nil {
+			root = workDir
+		}
+		return &mockProvider{name: name, available: err == nil}
+	})
+	return &root
+}
+
+func mkdirs(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindProvider_WalksToRepoRoot(t *testing.T) {
+	origRegistry := registry
+	registry = nil
+	defer func() { registry = origRegistry }()
+	root := registerMarker(PriorityBeads, "beads", ".beads")
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	sub := filepath.Join(repo, "internal", "pkg")
+	mkdirs(t, sub, filepath.Join(repo, ".git"), filepath.Join(repo, ".beads"))
+
+	provider := FindProvider(sub, "")
+	if provider == nil || provider.Name() != "beads" {
+		t.Fatalf("FindProvider() = %v, want beads", provider)
+	}
+	if *root != repo {
+		t.Errorf("provider created for %q, want the repo root %q", *root, repo)
+	}
+	if _, dir := FindProviderDir(sub, ""); dir != repo {
+		t.Errorf("FindProviderDir() dir = %q, want the repo root %q", dir, repo)
+	}
+}
+
+func TestFindProvider_WorktreeRoot(t *testing.T) {
+	origRegistry := registry
+	registry = nil
+	defer func() { registry = origRegistry }()
+	registerMarker(PriorityBeads, "beads", ".beads")
+
+	// A linked worktree has a .git file, and the search must not go past it
+	parent := t.TempDir()
+	worktree := filepath.Join(parent, "wt")
+	sub := filepath.Join(worktree, "sub")
+	mkdirs(t, sub, filepath.Join(parent, ".beads"))
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: /elsewhere\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if provider := FindProvider(sub, ""); provider != nil {
+		t.Errorf("FindProvider() = %q, want nil above the worktree root", provider.Name())
+	}
+}
+
+func TestFindProvider_NearestWins(t *testing.T) {
+	origRegistry := registry
+	registry = nil
+	defer func() { registry = origRegistry }()
+	registerMarker(PriorityKT, "kt", ".ktickets")
+	registerMarker(PriorityBeads, "beads", ".beads")
+
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "service")
+	mkdirs(t, filepath.Join(repo, ".git"), filepath.Join(repo, ".ktickets"), filepath.Join(sub, ".beads"))
+
+	if provider := FindProvider(sub, ""); provider == nil || provider.Name() != "beads" {
+		t.Errorf("FindProvider() = %v, want the nearer beads", provider)
+	}
+}
+
+func TestFindProvider_ProjectDir(t *testing.T) {
+	origRegistry := registry
+	registry = nil
+	defer func() { registry = origRegistry }()
+	registerMarker(PriorityBeads, "beads", ".beads")
+
+	// Claude Code was started above the repository, where the tracker lives
+	project := t.TempDir()
+	repo := filepath.Join(project, "repo")
+	mkdirs(t, filepath.Join(repo, ".git"), filepath.Join(project, ".beads"))
+
+	if provider := FindProvider(repo, project); provider == nil {
+		t.Error("FindProvider() = nil, want the tracker in project_dir")
+	}
+	if provider := FindProvider(repo, ""); provider != nil {
+		t.Errorf("FindProvider() without project_dir = %q, want nil past the repo root", provider.Name())
+	}
+
+	// A project_dir that doesn't contain the working dir is ignored
+	if provider := FindProvider(repo, filepath.Join(project, "other")); provider != nil {
+		t.Errorf("FindProvider() = %q, want nil for an unrelated project_dir", provider.Name())
+	}
+}
+
+func TestFindProvider_OutsideRepo(t *testing.T) {
+	origRegistry := registry
+	registry = nil
+	defer func() { registry = origRegistry }()
+	registerMarker(PriorityBeads, "beads", ".beads")
+
+	// Without a repository or project to bound the search, parents are not searched
+	parent := t.TempDir()
+	sub := filepath.Join(parent, "sub")
+	mkdirs(t, sub, filepath.Join(parent, ".beads"))
+
+	if provider := FindProvider(sub, ""); provider != nil {
+		t.Errorf("FindProvider() = %q, want nil outside a repository", provider.Name())
+	}
+}
+
+func TestIsWithin(t *testing.T) {
+	root := filepath.Join("/", "repo")
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{root, true},
+		{filepath.Join(root, "sub", "dir"), true},
+		{filepath.Join("/", "repo-other"), false}, // Shares a prefix, not a parent
+		{filepath.Join("/", "..repo"), false},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		if got := IsWithin(tt.dir, root); got != tt.want {
+			t.Errorf("IsWithin(%q, %q) = %v, want %v", tt.dir, root, got, tt.want)
+		}
+	}
+}
@@ -25,15 +25,65 @@ type Provider interface {
 	Available() bool
 
 	// GetStats returns task statistics.
-	GetStats() (Stats, error)
+	GetStats(ctx context.Context) (Stats, error)
 
 	// GetNextTask returns the title of the next ready task, or empty if none.
-	GetNextTask() (string, error)
+	GetNextTask(ctx context.Context) (string, error)
 }
 
-// Commander is an interface for executing commands.
+// Orders for NextTaskOptions.Order.
+const (
+	OrderFirst    = "first"    // The tracker's own order
+	OrderPriority = "priority" // Highest priority first, oldest among equals
+	OrderOldest   = "oldest"   // Earliest created first
+	OrderNewest   = "newest"   // Latest created first
+)
+
+// NextTaskOptions chooses which ready task GetNextTask returns.
+type NextTaskOptions struct {
+	Order string // One of the Order constants; "" means OrderFirst
+	Label string // Only tasks with this label are considered; "" considers all
+}
+
+// NextTaskConfigurable is implemented by providers that support
+// NextTaskOptions; others always return their first ready task.
+type NextTaskConfigurable interface {
+	SetNextTaskOptions(opts NextTaskOptions)
+}
+
+// Canonical ticket statuses, which QueryOptions.Statuses maps a tracker's
+// own statuses to.
+const (
+	StatusOpen       = "open"
+	StatusInProgress = "in_progress"
+	StatusClosed     = "closed"
+	StatusBlocked    = "blocked" // Counted as open, and as blocked unless Blocked is BlockedByDeps
+)
+
+// Definitions of a blocked ticket for QueryOptions.Blocked.
+const (
+	BlockedByDeps   = "deps"   // An active ticket with a dependency that isn't closed
+	BlockedByStatus = "status" // A ticket whose status maps to StatusBlocked
+	BlockedByEither = "either" // Either of the above
+)
+
+// QueryOptions adapts a tracker that reports tickets as JSON lines (tk) to
+// forks with their own command and workflow.
+type QueryOptions struct {
+	Command  []string          // Command and args that print the tickets; nil means the tracker's default
+	Statuses map[string]string // The tracker's statuses to the Status constants; others are used as is
+	Blocked  string            // One of the BlockedBy constants; "" means BlockedByDeps
+}
+
+// QueryConfigurable is implemented by providers that support QueryOptions.
+type QueryConfigurable interface {
+	SetQueryOptions(opts QueryOptions)
+}
+
+// Commander is an interface for executing commands. Commands are stopped
+// when ctx is done.
 type Commander interface {
-	Output(name string, args ...string) ([]byte, error)
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
 }
 
 // commandTimeout is the maximum time to wait for task commands.
@@ -45,8 +95,8 @@ type DefaultCommander struct {
 }
 
 // Output runs a command and returns its output with a timeout.
-func (d DefaultCommander) Output(name string, args ...string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+func (d DefaultCommander) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, name, args...)
@@ -0,0 +1,245 @@
+// Package telemetry keeps opt-in, local-only performance statistics: how long
+// renders take and how often each segment times out. Nothing leaves the
+// machine; `claude-status perf` prints the numbers for bug reports.
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// BucketBounds are the upper bounds of the latency histogram buckets. Slower
+// samples land in one extra overflow bucket.
+var BucketBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// Histogram counts latency samples per bucket of BucketBounds.
+type Histogram struct {
+	Counts []int64 `json:"counts"` // One per bucket, then the overflow bucket
+	Total  int64   `json:"total"`
+	SumMS  float64 `json:"sum_ms"`
+}
+
+// Add records one sample.
+func (h *Histogram) Add(d time.Duration) {
+	if len(h.Counts) != len(BucketBounds)+1 {
+		h.Counts = make([]int64, len(BucketBounds)+1)
+	}
+	i, _ := slices.BinarySearch(BucketBounds, d)
+	h.Counts[i]++
+	h.Total++
+	h.SumMS += float64(d) / float64(time.Millisecond)
+}
+
+// Percentile returns the upper bound of the bucket holding the p-th
+// percentile (0-100), so the true value is at most that. ok is false if
+// there are no samples; over is true if it falls in the overflow bucket.
+func (h Histogram) Percentile(p float64) (bound time.Duration, over, ok bool) {
+	if h.Total == 0 {
+		return 0, false, false
+	}
+	rank := int64(float64(h.Total)*p/100 + 0.5)
+	rank = max(rank, 1)
+	var seen int64
+	for i, n := range h.Counts {
+		seen += n
+		if seen >= rank {
+			if i == len(BucketBounds) {
+				return BucketBounds[len(BucketBounds)-1], true, true
+			}
+			return BucketBounds[i], false, true
+		}
+	}
+	return BucketBounds[len(BucketBounds)-1], true, true
+}
+
+// SegmentStats is what is known about one segment across renders.
+type SegmentStats struct {
+	Failures int64     `json:"failures"` // Timed out or cut off by the render deadline
+	Latency  Histogram `json:"latency"`
+}
+
+// Stats is the structure of the telemetry file on disk.
+type Stats struct {
+	Since    time.Time                `json:"since"` // First sample since the last reset
+	Renders  Histogram                `json:"renders"`
+	Segments map[string]*SegmentStats `json:"segments,omitempty"`
+}
+
+// Segment is one segment's outcome in a render.
+type Segment struct {
+	Name     string
+	Duration time.Duration
+	Failed   bool
+}
+
+// Store records renders in the telemetry file. Updates are serialized across
+// processes with a file lock; a render that can't take it right away drops
+// its sample rather than wait.
+type Store struct {
+	path string
+	lock *flock.Flock
+	now  func() time.Time
+}
+
+// NewStore creates a store backed by the file at path.
+func NewStore(path string) *Store {
+	return NewStoreWithClock(path, time.Now)
+}
+
+// NewStoreWithClock creates a store with a custom clock.
+func NewStoreWithClock(path string, now func() time.Time) *Store {
+	return &Store{
+		path: path,
+		lock: flock.New(path + ".lock"),
+		now:  now,
+	}
+}
+
+// Record adds a render that took total, with the given segment outcomes.
+func (s *Store) Record(total time.Duration, segments []Segment) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		slog.Debug("failed to create telemetry directory", "err", err)
+		return
+	}
+	locked, err := s.lock.TryLock()
+	if err != nil || !locked {
+		slog.Debug("telemetry file busy, dropping sample", "err", err)
+		return
+	}
+	defer s.lock.Unlock()
+
+	stats := s.Load()
+	if stats.Since.IsZero() {
+		stats.Since = s.now()
+	}
+	stats.Renders.Add(total)
+	for _, seg := range segments {
+		if stats.Segments == nil {
+			stats.Segments = make(map[string]*SegmentStats)
+		}
+		ss := stats.Segments[seg.Name]
+		if ss == nil {
+			ss = &SegmentStats{}
+			stats.Segments[seg.Name] = ss
+		}
+		ss.Latency.Add(seg.Duration)
+		if seg.Failed {
+			ss.Failures++
+		}
+	}
+	s.save(stats)
+}
+
+// Load reads the telemetry file. A missing or corrupt file yields no stats.
+func (s *Store) Load() Stats {
+	var stats Stats
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return stats
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		slog.Warn("telemetry file corrupted, resetting", "err", err)
+		return Stats{}
+	}
+	return stats
+}
+
+// Reset deletes the recorded stats.
+func (s *Store) Reset() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// save writes the telemetry file atomically.
+func (s *Store) save(stats Stats) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		slog.Error("failed to marshal telemetry", "err", err)
+		return
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		slog.Debug("failed to write telemetry temp file", "err", err)
+		return
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		slog.Debug("failed to rename telemetry file", "err", err)
+		os.Remove(tmpPath)
+	}
+}
+
+// Report prints stats as a table of latency percentiles and failure rates.
+func Report(w io.Writer, stats Stats) {
+	if stats.Renders.Total == 0 {
+		fmt.Fprintln(w, "No renders recorded yet.")
+		return
+	}
+
+	fmt.Fprintf(w, "%d renders since %s\n\n", stats.Renders.Total, stats.Since.Format("2006-01-02 15:04"))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tsamples\tfailed\tp50\tp90\tp99\tmean\t")
+	writeRow(tw, "render", stats.Renders, -1)
+
+	names := make([]string, 0, len(stats.Segments))
+	for name := range stats.Segments {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		ss := stats.Segments[name]
+		writeRow(tw, name, ss.Latency, ss.Failures)
+	}
+	tw.Flush()
+}
+
+// writeRow prints one histogram; failures < 0 leaves the failed column blank.
+func writeRow(w io.Writer, name string, h Histogram, failures int64) {
+	failed := ""
+	if failures >= 0 {
+		failed = fmt.Sprintf("%.1f%%", 100*float64(failures)/float64(max(h.Total, 1)))
+	}
+	fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", name, h.Total, failed,
+		formatPercentile(h, 50), formatPercentile(h, 90), formatPercentile(h, 99),
+		formatMS(h.SumMS/float64(max(h.Total, 1))))
+}
+
+// formatPercentile prints a percentile's bucket bound, e.g. "≤20ms" or ">5s".
+func formatPercentile(h Histogram, p float64) string {
+	bound, over, ok := h.Percentile(p)
+	switch {
+	case !ok:
+		return "-"
+	case over:
+		return ">" + bound.String()
+	}
+	return "≤" + bound.String()
+}
+
+// formatMS prints milliseconds with one decimal, e.g. "12.5ms".
+func formatMS(ms float64) string {
+	return fmt.Sprintf("%.1fms", ms)
+}
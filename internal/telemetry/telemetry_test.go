@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Percentile(t *testing.T) {
+	var h Histogram
+	if _, _, ok := h.Percentile(50); ok {
+		t.Error("Percentile() ok = true for an empty histogram")
+	}
+
+	for range 90 {
+		h.Add(3 * time.Millisecond)
+	}
+	for range 9 {
+		h.Add(150 * time.Millisecond)
+	}
+	h.Add(time.Minute)
+
+	tests := []struct {
+		p     float64
+		bound time.Duration
+		over  bool
+	}{
+		{50, 5 * time.Millisecond, false},
+		{90, 5 * time.Millisecond, false},
+		{99, 200 * time.Millisecond, false},
+		{100, 5 * time.Second, true},
+	}
+	for _, tt := range tests {
+		bound, over, ok := h.Percentile(tt.p)
+		if !ok || bound != tt.bound || over != tt.over {
+			t.Errorf("Percentile(%v) = %v, %v, %v; want %v, %v", tt.p, bound, over, ok, tt.bound, tt.over)
+		}
+	}
+}
+
+func TestHistogram_BucketEdge(t *testing.T) {
+	var h Histogram
+	h.Add(10 * time.Millisecond)
+	if bound, _, _ := h.Percentile(50); bound != 10*time.Millisecond {
+		t.Errorf("Percentile(50) = %v, want a sample on a bound to count in that bucket", bound)
+	}
+}
+
+func TestStore_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "perf.json")
+	now := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	store := NewStoreWithClock(path, func() time.Time { return now })
+
+	store.Record(40*time.Millisecond, []Segment{
+		{Name: "git_status", Duration: 8 * time.Millisecond},
+		{Name: "github", Duration: 30 * time.Millisecond, Failed: true},
+	})
+	store.Record(15*time.Millisecond, []Segment{
+		{Name: "git_status", Duration: 4 * time.Millisecond},
+		{Name: "github", Duration: 2 * time.Millisecond},
+	})
+
+	stats := store.Load()
+	if stats.Renders.Total != 2 || !stats.Since.Equal(now) {
+		t.Errorf("renders = %d since %v, want 2 since %v", stats.Renders.Total, stats.Since, now)
+	}
+	if gh := stats.Segments["github"]; gh == nil || gh.Latency.Total != 2 || gh.Failures != 1 {
+		t.Errorf("github = %+v, want 2 samples and 1 failure", gh)
+	}
+
+	var out bytes.Buffer
+	Report(&out, stats)
+	for _, want := range []string{"2 renders since 2026-10-01 09:30", "render", "git_status", "50.0%"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Report() missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := store.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if stats := store.Load(); stats.Renders.Total != 0 {
+		t.Errorf("Load() after Reset() = %+v, want empty", stats)
+	}
+}
+
+func TestReport_Empty(t *testing.T) {
+	var out bytes.Buffer
+	Report(&out, Stats{})
+	if out.String() != "No renders recorded yet.\n" {
+		t.Errorf("Report() = %q", out.String())
+	}
+}
@@ -0,0 +1,95 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Fixture file extensions: NAME.json holds the StatusData to render (the same
+// shape --format json prints) and NAME.expected the line it should render to.
+const (
+	FixtureDataExt     = ".json"
+	FixtureExpectedExt = ".expected"
+)
+
+// ErrNoFixtures is returned by RunFixtures when dir holds no fixtures.
+var ErrNoFixtures = errors.New("no template fixtures found")
+
+// FixtureResult is the outcome of rendering one fixture.
+type FixtureResult struct {
+	Name string // Fixture file name without extension
+	Want string // Expected output; empty if missing
+	Got  string // Rendered output
+	Err  error  // Why the fixture couldn't be rendered or compared
+}
+
+// Passed reports whether the fixture rendered to its expected output.
+func (r FixtureResult) Passed() bool {
+	return r.Err == nil && r.Got == r.Want
+}
+
+// RunFixtures renders every fixture in dir with e and compares the output to
+// its expected file. With update, expected files are (re)written from the
+// output instead, so a suite can be bootstrapped or accepted after a change.
+func RunFixtures(e *Engine, dir string, update bool) ([]FixtureResult, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+FixtureDataExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoFixtures, dir)
+	}
+
+	results := make([]FixtureResult, 0, len(paths))
+	for _, path := range paths {
+		results = append(results, runFixture(e, path, update))
+	}
+	return results, nil
+}
+
+// runFixture renders the fixture at path, the data file of the pair.
+func runFixture(e *Engine, path string, update bool) FixtureResult {
+	base := strings.TrimSuffix(path, FixtureDataExt)
+	r := FixtureResult{Name: filepath.Base(base)}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	// Reject unknown fields: a misspelled one would silently render as empty
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	var data StatusData
+	if err := dec.Decode(&data); err != nil {
+		r.Err = fmt.Errorf("invalid fixture: %w", err)
+		return r
+	}
+	if r.Got, err = e.Render(data); err != nil {
+		r.Err = fmt.Errorf("render failed: %w", err)
+		return r
+	}
+
+	expectedPath := base + FixtureExpectedExt
+	if update {
+		if err := os.WriteFile(expectedPath, []byte(r.Got+"\n"), 0644); err != nil {
+			r.Err = err
+		}
+		r.Want = r.Got
+		return r
+	}
+
+	expected, err := os.ReadFile(expectedPath)
+	if err != nil {
+		r.Err = fmt.Errorf("no expected output (run with -update to create %s)", filepath.Base(expectedPath))
+		return r
+	}
+	// Editors end files with a newline the status line doesn't have
+	r.Want = strings.TrimSuffix(string(expected), "\n")
+	return r
+}
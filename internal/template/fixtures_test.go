@@ -0,0 +1,84 @@
+package template
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFixture(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunFixtures(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "clean.json", `{"Model": "Opus 4", "GitBranch": "main"}`)
+	writeFixture(t, dir, "clean.expected", "Opus 4 main\n")
+	writeFixture(t, dir, "changed.json", `{"Model": "Opus 4", "GitBranch": "feature"}`)
+	writeFixture(t, dir, "changed.expected", "Opus 4 main\n")
+	writeFixture(t, dir, "typo.json", `{"Modle": "Opus 4"}`)
+	writeFixture(t, dir, "new.json", `{"Model": "Sonnet 4"}`)
+
+	engine, err := NewEngine(`{{.Model}} {{.GitBranch}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := RunFixtures(engine, dir, false)
+	if err != nil {
+		t.Fatalf("RunFixtures() error = %v", err)
+	}
+	byName := make(map[string]FixtureResult)
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+	if len(byName) != 4 {
+		t.Fatalf("RunFixtures() returned %d results, want 4", len(results))
+	}
+
+	if r := byName["clean"]; !r.Passed() {
+		t.Errorf("clean = %+v, want pass", r)
+	}
+	if r := byName["changed"]; r.Passed() || r.Got != "Opus 4 feature" || r.Want != "Opus 4 main" {
+		t.Errorf("changed = %+v, want a mismatch", r)
+	}
+	if r := byName["typo"]; r.Err == nil || !strings.Contains(r.Err.Error(), "Modle") {
+		t.Errorf("typo = %+v, want an error naming the unknown field", r)
+	}
+	if r := byName["new"]; r.Err == nil || !strings.Contains(r.Err.Error(), "-update") {
+		t.Errorf("new = %+v, want an error suggesting -update", r)
+	}
+}
+
+func TestRunFixtures_Update(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "new.json", `{"Model": "Sonnet 4"}`)
+
+	engine, err := NewEngine(`[{{.Model}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunFixtures(engine, dir, true); err != nil {
+		t.Fatalf("RunFixtures(update) error = %v", err)
+	}
+
+	results, err := RunFixtures(engine, dir, false)
+	if err != nil || len(results) != 1 || !results[0].Passed() {
+		t.Errorf("RunFixtures() after update = %+v, %v; want a pass", results, err)
+	}
+}
+
+func TestRunFixtures_Empty(t *testing.T) {
+	engine, err := NewEngine(`{{.Model}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunFixtures(engine, t.TempDir(), false); !errors.Is(err, ErrNoFixtures) {
+		t.Errorf("RunFixtures() error = %v, want ErrNoFixtures", err)
+	}
+}
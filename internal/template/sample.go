@@ -0,0 +1,119 @@
+package template
+
+import "github.com/kostyay/claude-status/internal/tokens"
+
+// SampleData returns realistic status data for previewing templates: a
+// mid-session feature branch with uncommitted changes, a failing CI run on an
+// open pull request, and a task tracker with work queued. Fields that only
+// show up in unusual setups (prefix, install warning, Rosetta, containers)
+// are left empty.
+func SampleData() StatusData {
+	return StatusData{
+		Model:   "Opus 4",
+		Dir:     "claude-status",
+		Version: "1.0.100",
+		OS:      "darwin",
+		Arch:    "arm64",
+
+		OutputStyle: "Explanatory",
+		Agent:       "reviewer",
+
+		GitBranch:            "feature/preview",
+		GitStatus:            "±5",
+		GitAdditions:         128,
+		GitDeletions:         37,
+		GitStagedAdditions:   96,
+		GitStagedDeletions:   20,
+		GitUnstagedAdditions: 32,
+		GitUnstagedDeletions: 17,
+		GitNewFiles:          2,
+		GitModifiedFiles:     3,
+		GitUnstagedFiles:     2,
+		GitFileTypes: []FileTypeCount{
+			{Ext: ".go", Count: 4},
+			{Ext: ".md", Count: 1},
+		},
+		GitLargestFile:      "…/template/sample.go",
+		GitLargestFileLines: 74,
+		GitChangedFiles: []ChangedFile{
+			{Status: "A ", Path: "internal/template/sample.go"},
+			{Status: "A ", Path: "internal/template/sample_test.go"},
+			{Status: "M ", Path: "cmd/claude-status/commands.go"},
+			{Status: " M", Path: "cmd/claude-status/commands_test.go"},
+			{Status: " M", Path: "README.md"},
+		},
+		Workspaces: []WorkspaceDir{
+			{Dir: "claude-status", Branch: "feature/preview", Changed: 5},
+			{Dir: "docs-site", Branch: "main", Changed: 2},
+		},
+		WorkspaceChanged:     7,
+		GitStash:             1,
+		GitDetached:          true,
+		GitOperation:         "rebasing",
+		GitConflicts:         3,
+		GitLastCommitSHA:     "abc1234",
+		GitLastCommitMsg:     "fix: cache bug",
+		GitLastCommitAge:     "3m",
+		SessionAdditions:     214,
+		SessionDeletions:     37,
+		SessionCommits:       3,
+		GitAhead:             2,
+		GitBehind:            1,
+		ForcePushNeeded:      true,
+		BranchAge:            "3d",
+		BranchAgeDays:        3,
+		DirtySince:           "2h",
+		DirtyMinutes:         135,
+		ProtectedBranchDirty: true,
+
+		GitHubStatus:    "❌",
+		GitHubBuild:     "failure",
+		GitHubFailedJob: "unit-tests",
+		GitHubRunURL:    "https://github.com/kostyay/claude-status/actions/runs/123456789",
+		GitHubHistory:   []string{"success", "success", "failure", "success", "failure"},
+		GitHubWorkflows: []WorkflowStatus{
+			{Name: "test", Status: "✅", Build: "success"},
+			{Name: "lint", Status: "❌", Build: "failure"},
+		},
+		GitHubMainBranch: "main",
+		GitHubMainStatus: "✅",
+
+		ActionsMinutesUsed:      1240,
+		ActionsMinutesIncluded:  3000,
+		ActionsMinutesRemaining: 1760,
+
+		PRNumber:      42,
+		PRURL:         "https://github.com/kostyay/claude-status/pull/42",
+		PRState:       "open",
+		PRReviewState: "review_required",
+		PRMergeable:   "blocked",
+		ChecksPassed:  5,
+		ChecksFailed:  1,
+		ChecksPending: 1,
+		ChecksTotal:   7,
+
+		TokensInput:    45200,
+		TokensOutput:   12800,
+		TokensCached:   98000,
+		TokensTotal:    156000,
+		ContextLength:  86400,
+		ContextPct:     43.2,
+		ContextPctUse:  54,
+		ContextHistory: []int64{12000, 25000, 31000, 48000, 52000, 61000, 74000, 86400},
+		CostUSD:        1.87,
+		Cost:           tokens.FormatCost(1.87),
+
+		SessionDuration: "42m",
+		SessionMinutes:  42,
+
+		HasTasks:         true,
+		TaskProvider:     "kt",
+		TasksTotal:       24,
+		TasksOpen:        9,
+		TasksReady:       4,
+		TasksInProgress:  2,
+		TasksBlocked:     3,
+		TasksNextTask:    "Add template preview",
+		TasksClosedToday: 5,
+	}
+}
@@ -0,0 +1,40 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSampleData_Populated(t *testing.T) {
+	// Fields deliberately left empty; everything else should have a value so
+	// previews exercise the whole template
+	unset := map[string]bool{
+		"Prefix": true, "PrefixColor": true, "InstallWarning": true,
+		"Rosetta": true, "Container": true, "Environment": true, "GitDeletedFiles": true, "ContextOver200k": true,
+	}
+
+	v := reflect.ValueOf(SampleData())
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Name
+		if unset[name] {
+			continue
+		}
+		if v.Field(i).IsZero() {
+			t.Errorf("SampleData().%s is empty", name)
+		}
+	}
+}
+
+func TestSampleData_Renders(t *testing.T) {
+	engine, err := NewEngine(`{{.Model}} {{.GitBranch}} {{fmtTokens .TokensTotal}} {{.Cost}} {{.TasksReady}}`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	got, err := engine.Render(SampleData())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "Opus 4 feature/preview 156k $1.87 4"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
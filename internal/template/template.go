@@ -2,9 +2,19 @@ package template
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/tokens"
 )
 
 // ANSI color codes
@@ -31,8 +41,235 @@ var ColorMap = map[string]string{
 	"gray":    colorGray,
 }
 
+// Color themes selectable with the "theme" config option. ThemeAuto is
+// resolved to ThemeDark or ThemeLight before rendering.
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemeAuto  = "auto"
+)
+
+// Palettes maps each concrete theme to the codes behind the named colors.
+// The light palette swaps the basic ANSI colors, several of which (yellow,
+// cyan, gray) wash out on a white background, for darker 256-color shades.
+var Palettes = map[string]map[string]string{
+	ThemeDark: ColorMap,
+	ThemeLight: {
+		"cyan":    "\033[38;5;30m",
+		"blue":    "\033[38;5;25m",
+		"green":   "\033[38;5;28m",
+		"yellow":  "\033[38;5;130m",
+		"red":     "\033[38;5;160m",
+		"magenta": "\033[38;5;127m",
+		"gray":    "\033[38;5;242m",
+	},
+}
+
+// ColorCode resolves a color name from ColorMap or a "#rrggbb" / "#rgb" hex
+// value (rendered as a 24-bit foreground color) to its ANSI code.
+func ColorCode(name string) (string, bool) {
+	return paletteCode(ColorMap, name)
+}
+
+// paletteCode is ColorCode with named colors looked up in palette.
+func paletteCode(palette map[string]string, name string) (string, bool) {
+	if code, ok := palette[name]; ok {
+		return code, true
+	}
+	r, g, b, ok := parseHex(name)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm", r, g, b), true
+}
+
+// parseHex parses "#rrggbb" or the shorthand "#rgb".
+func parseHex(s string) (r, g, b uint8, ok bool) {
+	hex, found := strings.CutPrefix(s, "#")
+	if !found {
+		return 0, 0, 0, false
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), true
+}
+
+// ansi256 returns the SGR sequence for a 256-color palette index, with layer
+// 38 for foreground or 48 for background. Out-of-range indexes render as "".
+func ansi256(layer int, n any) string {
+	i := toFloat(n)
+	if i < 0 || i > 255 || i != float64(int(i)) {
+		return ""
+	}
+	return fmt.Sprintf("\033[%d;5;%dm", layer, int(i))
+}
+
+// DefaultSymbols maps symbol names to the emoji used by the built-in templates.
+// Templates look them up with {{sym "name"}}; config can override any entry.
+var DefaultSymbols = map[string]string{
+	"dir":      "📁",
+	"branch":   "🌿",
+	"new":      "✨",
+	"modified": "📝",
+	"deleted":  "🗑",
+	"unstaged": "⚡",
+	"context":  "📊",
+	"tokens":   "📈",
+	"tasks":    "📋",
+	"stash":    "📦",
+	"conflict": "⚠",
+	"sep":      " | ",
+
+	"codespaces":   "☁",
+	"gitpod":       "🍊",
+	"devcontainer": "🧰",
+	"container":    "🐳",
+}
+
+// Icon themes selectable with the "icons" config option.
+const (
+	IconsEmoji    = "emoji"
+	IconsNerdFont = "nerdfont"
+	IconsASCII    = "ascii"
+)
+
+// IconThemes maps each icon theme to the symbols it replaces, covering both
+// template markers and build states ("success", "failure", ...). Names a theme
+// leaves out keep their default; the emoji theme is the defaults.
+var IconThemes = map[string]map[string]string{
+	IconsEmoji: {},
+	IconsNerdFont: {
+		"dir":      "\uf07b", // nf-fa-folder
+		"branch":   "\ue0a0", // nf-pl-branch
+		"new":      "\uf067", // nf-fa-plus
+		"modified": "\uf040", // nf-fa-pencil
+		"deleted":  "\uf1f8", // nf-fa-trash
+		"unstaged": "\uf0e7", // nf-fa-bolt
+		"context":  "\uf080", // nf-fa-bar_chart
+		"tokens":   "\uf201", // nf-fa-line_chart
+		"tasks":    "\uf0ae", // nf-fa-tasks
+		"stash":    "\uf187", // nf-fa-archive
+		"conflict": "\uf071", // nf-fa-warning
+		"success":  "\uf00c", // nf-fa-check
+		"failure":  "\uf00d", // nf-fa-times
+		"pending":  "\uf017", // nf-fa-clock_o
+		"error":    "\uf071", // nf-fa-warning
+
+		"rate_limited": "\uf252", // nf-fa-hourglass_half
+		"codespaces":   "\uf09b", // nf-fa-github
+		"gitpod":       "\uf0c2", // nf-fa-cloud
+		"devcontainer": "\uf1b3", // nf-fa-cubes
+		"container":    "\uf308", // nf-linux-docker
+	},
+	IconsASCII: {
+		"dir":      "~",
+		"branch":   "@",
+		"new":      "+",
+		"modified": "*",
+		"deleted":  "x",
+		"unstaged": "!",
+		"context":  "ctx",
+		"tokens":   "tok",
+		"tasks":    "#",
+		"stash":    "$",
+		"conflict": "!!",
+		"success":  "ok",
+		"failure":  "FAIL",
+		"pending":  "..",
+		"error":    "ERR",
+
+		"rate_limited": "WAIT",
+		"codespaces":   "[cs]",
+		"gitpod":       "[gp]",
+		"devcontainer": "[dev]",
+		"container":    "[ctr]",
+	},
+}
+
+// ThemeSymbols returns the symbol overrides for an icon theme with overrides
+// applied on top, ready for NewEngineWithSymbols and cistatus.Symbol.
+// An unknown or empty theme is the emoji theme.
+func ThemeSymbols(theme string, overrides map[string]string) map[string]string {
+	themed := IconThemes[theme]
+	if len(themed) == 0 {
+		return overrides
+	}
+	symbols := maps.Clone(themed)
+	maps.Copy(symbols, overrides)
+	return symbols
+}
+
+// segmentMarker is emitted by {{sep}} and resolved after rendering. It is an
+// ASCII record separator, which never appears in normal status line text.
+const segmentMarker = "\x1e"
+
+// ansiPattern matches ANSI SGR escape sequences (colors, bold, reset).
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// FileTypeCount is the number of changed files sharing an extension.
+// It prints as ".go:4", so a list can be rendered with join.
+type FileTypeCount struct {
+	Ext   string // Extension including the dot, or base name if none
+	Count int    // Number of changed files
+}
+
+// String formats the count as "ext:count".
+func (f FileTypeCount) String() string {
+	return fmt.Sprintf("%s:%d", f.Ext, f.Count)
+}
+
+// ChangedFile is one dirty file from git status.
+// It prints as "M  path/to/file.go".
+type ChangedFile struct {
+	Status string // Two-letter porcelain code (XY), e.g. " M", "A ", "??"
+	Path   string // Repo-relative path
+}
+
+// String formats the entry like a "git status --short" line.
+func (c ChangedFile) String() string {
+	return c.Status + " " + c.Path
+}
+
+// WorkspaceDir is the repository of one workspace directory.
+// It prints as "api ±3", or just "api" when clean, so a list can be
+// rendered with join.
+type WorkspaceDir struct {
+	Dir     string // Directory basename
+	Branch  string // Current branch
+	Changed int    // Files with uncommitted changes
+}
+
+// String formats the directory with its changed-file count.
+func (w WorkspaceDir) String() string {
+	if w.Changed == 0 {
+		return w.Dir
+	}
+	return fmt.Sprintf("%s ±%d", w.Dir, w.Changed)
+}
+
+// WorkflowStatus is the latest build of one of several GitHub workflows.
+// It prints as "lint ❌", so a list can be rendered with join.
+type WorkflowStatus struct {
+	Name   string // Workflow as configured in github_workflow
+	Status string // Build status emoji
+	Build  string // "success", "failure", "pending", "error", or "rate_limited"
+}
+
+// String formats the workflow with its status emoji.
+func (w WorkflowStatus) String() string {
+	return w.Name + " " + w.Status
+}
+
 // StatusData holds all the data available for template rendering.
-// All values are raw; use template functions (fmtTokens, fmtPct, fmtSigned) for formatting.
+// All values are raw; use template functions (fmtTokens, fmtPct, fmtCost, fmtSigned) for formatting.
 type StatusData struct {
 	Prefix       string // User-provided prefix text
 	PrefixColor  string // ANSI color code for prefix (from --prefix-color flag)
@@ -41,34 +278,123 @@ type StatusData struct {
 	GitBranch    string // Current git branch (empty if not in git repo)
 	GitStatus    string // Git status like "±3" (empty if clean)
 	GitHubStatus string // GitHub build status emoji (empty if unavailable)
+	GitHubBuild  string // GitHub build status as "success", "failure", "pending", "error", or "rate_limited" (empty if unavailable)
 	Version      string // Claude Code version
+	OutputStyle  string // Active output style, e.g. "default", "Explanatory" (empty if not reported)
+	Agent        string // Agent the session runs as (empty if none)
+
+	// InstallWarning describes a stale install, e.g. settings.json running a
+	// binary that was moved (empty if the install is current)
+	InstallWarning string
+
+	// Platform the status line (and so the session's shell) runs on
+	OS        string // Operating system, e.g. "darwin", "linux"
+	Arch      string // Process architecture, e.g. "arm64"; "amd64" under Rosetta
+	Rosetta   bool   // Translated by Rosetta 2 on Apple silicon
+	Container bool   // Running inside a container (Docker, Podman, Kubernetes)
+
+	// Remote development environment: "codespaces", "gitpod", "devcontainer",
+	// or "container" for any other container; empty on the host. Each name is
+	// also a symbol, so {{sym .Environment}} renders its icon.
+	Environment string
+
+	// GitHub failure detail (populated only when the latest run failed)
+	GitHubFailedJob string // Name of the first failed job (e.g., "unit-tests")
+	GitHubRunURL    string // Browser URL of the failed workflow run
+
+	// Recent completed builds of the current branch, oldest first
+	// ("success" or "failure"; at most 10). Render with sparkline.
+	GitHubHistory []string
+
+	// Each workflow's latest build, in configured order (populated when
+	// github_workflow lists several; GitHubStatus combines them)
+	GitHubWorkflows []WorkflowStatus
+
+	// Default branch CI (populated when github_default_branch is enabled)
+	GitHubMainBranch string // Default branch name (e.g., "main")
+	GitHubMainStatus string // Default branch build status emoji
+
+	// GitHub Actions minutes (populated for private repos when github_usage is enabled)
+	ActionsMinutesUsed      int // Minutes used this billing cycle
+	ActionsMinutesIncluded  int // Minutes included in the plan (0 if unknown)
+	ActionsMinutesRemaining int // Included minutes left this billing cycle
+
+	// Open pull request for the current branch (populated when github_pr is enabled; PRNumber is 0 if none)
+	PRNumber      int    // Pull request number
+	PRURL         string // Browser URL of the pull request
+	PRState       string // "open" or "draft"
+	PRReviewState string // "approved", "changes_requested", or "review_required"
+	PRMergeable   string // GitHub mergeable_state: "clean", "dirty" (conflicts), "blocked", "behind", ...
+
+	// Pull request check runs for the current branch (populated when github_pr is enabled; zero if no open PR)
+	ChecksPassed  int // Successful, neutral, or skipped checks
+	ChecksFailed  int // Failed, cancelled, or timed-out checks
+	ChecksPending int // Queued or in-progress checks
+	ChecksTotal   int // All checks on the PR head commit
 
 	// Git diff stats (raw values - use fmtSigned for display)
-	GitAdditions     int // Line additions count
-	GitDeletions     int // Line deletions count
-	GitNewFiles      int // New files count
-	GitModifiedFiles int // Modified files count
-	GitDeletedFiles  int // Deleted files count
-	GitUnstagedFiles int // Unstaged files count
+	GitAdditions         int             // Line additions count (staged + unstaged)
+	GitDeletions         int             // Line deletions count (staged + unstaged)
+	GitStagedAdditions   int             // Line additions in the index
+	GitStagedDeletions   int             // Line deletions in the index
+	GitUnstagedAdditions int             // Line additions not yet staged
+	GitUnstagedDeletions int             // Line deletions not yet staged
+	GitNewFiles          int             // New files count
+	GitModifiedFiles     int             // Modified files count
+	GitDeletedFiles      int             // Deleted files count
+	GitUnstagedFiles     int             // Unstaged files count
+	GitConflicts         int             // Files with unresolved merge conflicts
+	GitFileTypes         []FileTypeCount // Changed files by extension, most frequent first
+	GitLargestFile       string          // Path of the most-changed file (leading dirs truncated)
+	GitLargestFileLines  int             // Lines added plus deleted in GitLargestFile
+	GitChangedFiles      []ChangedFile   // Dirty files from git status (at most 50)
+	GitStash             int             // Stash entries
+	GitDetached          bool            // HEAD is detached; GitBranch is then the tag at HEAD or its short hash
+	GitOperation         string          // Operation in progress: "rebasing", "merging", "cherry-picking", "reverting", "applying", "bisecting", or empty
+	GitLastCommitSHA     string          // Abbreviated hash of the HEAD commit (empty before the first commit)
+	GitLastCommitMsg     string          // Subject of the HEAD commit, truncated to 50 runes
+	GitLastCommitAge     string          // Time since the HEAD commit, e.g. "3m"
+	SessionAdditions     int             // Lines added during this session, committed or not
+	SessionDeletions     int             // Lines deleted during this session, committed or not
+	SessionCommits       int             // Commits made since this session started
+	GitAhead             int             // Commits not yet pushed to the upstream branch
+	GitBehind            int             // Upstream commits not yet pulled
+	ForcePushNeeded      bool            // Branch and upstream have diverged (both ahead and behind); a plain push will be rejected
+	Workspaces           []WorkspaceDir  // Repositories of the current and additional directories (set only with additional directories)
+	WorkspaceChanged     int             // Changed files summed across Workspaces
+	BranchAge            string          // Time on the current branch, e.g. "3d" (empty outside git, detached, or mid-operation)
+	BranchAgeDays        int             // Whole days on the current branch, for thresholds
+	DirtySince           string          // How long changes have been uncommitted, e.g. "2h" (empty if clean)
+	DirtyMinutes         int             // Whole minutes changes have been uncommitted; color with dirtyColor
+	ProtectedBranchDirty bool            // Uncommitted changes on a branch in protected_branches (main, master by default)
 
 	// Token metrics (raw values - use fmtTokens for display)
-	TokensInput   int64   // Input tokens
-	TokensOutput  int64   // Output tokens
-	TokensCached  int64   // Cached tokens
-	TokensTotal   int64   // Total tokens
-	ContextLength int64   // Current context length
-	ContextPct    float64 // Context percentage (0-100)
-	ContextPctUse float64 // Usable context percentage (0-100)
+	TokensInput    int64   // Input tokens
+	TokensOutput   int64   // Output tokens
+	TokensCached   int64   // Cached tokens
+	TokensTotal    int64   // Total tokens
+	ContextLength  int64   // Current context length
+	ContextPct     float64 // Context percentage (0-100)
+	ContextPctUse  float64 // Usable context percentage (0-100)
+	ContextHistory []int64 // Context length after each turn this session, oldest first (at most 20)
+	CostUSD        float64 // Session cost in USD as Claude Code reports it, else estimated from the transcript (use fmtCost for display)
+	Cost           string  // CostUSD formatted, e.g. "$1.23" (empty if no priced usage)
+
+	// Session totals reported by Claude Code (empty if it doesn't send them)
+	SessionDuration string // Wall-clock time since the session started, e.g. "42m"
+	SessionMinutes  int    // Whole minutes since the session started, for thresholds
+	ContextOver200k bool   // Context is past 200k tokens, where long-context pricing starts
 
 	// Task stats (raw values) - populated by kt, tk, or beads
-	TaskProvider    string // Provider name: "kt", "tk", or "beads"
-	TasksTotal      int    // Total issues
-	TasksOpen       int    // Open issues
-	TasksReady      int    // Ready to work issues
-	TasksInProgress int    // In progress issues
-	TasksBlocked    int    // Blocked issues
-	TasksNextTask   string // Title of next ready task, or empty if none
-	HasTasks        bool   // Whether task system is available
+	TaskProvider     string // Provider name: "kt", "tk", or "beads"
+	TasksTotal       int    // Total issues
+	TasksOpen        int    // Open issues
+	TasksReady       int    // Ready to work issues
+	TasksInProgress  int    // In progress issues
+	TasksBlocked     int    // Blocked issues
+	TasksNextTask    string // Title of next ready task, or empty if none
+	TasksClosedToday int    // Tasks closed since local midnight; survives clearing the cache
+	HasTasks         bool   // Whether task system is available
 }
 
 // FormatTokens formats a token count in a human-readable way.
@@ -89,37 +415,207 @@ func formatWithSuffix(f float64, suffix string) string {
 	return s + suffix
 }
 
-// funcs is the template function map with color helpers and formatters.
-var funcs = template.FuncMap{
-	"cyan":    func() string { return colorCyan },
-	"blue":    func() string { return colorBlue },
-	"green":   func() string { return colorGreen },
-	"yellow":  func() string { return colorYellow },
-	"red":     func() string { return colorRed },
-	"magenta": func() string { return colorMagenta },
-	"gray":    func() string { return colorGray },
-	"reset":   func() string { return colorReset },
-	"bold":    func() string { return colorBold },
-
-	// Context percentage color: green < 50%, yellow 50-80%, red > 80%
-	"ctxColor": func(pct float64) string {
-		if pct >= 80 {
-			return colorRed
+// FormatAge formats a duration in its largest whole unit: 90s -> "1m",
+// 5h20m -> "5h", 76h -> "3d". Durations under a minute are "0m".
+func FormatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dm", max(int(d/time.Minute), 0))
+	}
+}
+
+// toFloat converts any numeric template value to float64 so helpers accept
+// int, int64, and float64 fields alike. Non-numeric values convert to 0.
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
+
+// listItems returns the elements of any slice or array as []any.
+// Non-list values (including nil) yield an empty list.
+func listItems(list any) []any {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	items := make([]any, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items
+}
+
+// joinList joins list elements with sep: join ", " ["a" "b"] -> "a, b"
+func joinList(sep string, list any) string {
+	items := listItems(list)
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, sep)
+}
+
+// firstItem returns the first list element, or "" for an empty list.
+func firstItem(list any) any {
+	items := listItems(list)
+	if len(items) == 0 {
+		return ""
+	}
+	return items[0]
+}
+
+// limitList returns at most n elements of list.
+func limitList(n int, list any) []any {
+	items := listItems(list)
+	if n < 0 {
+		n = 0
+	}
+	if len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+// Truncate shortens s to at most maxLen runes, ending in "…" if cut.
+func Truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	if maxLen <= 0 {
+		return ""
+	}
+	return strings.TrimRight(string(runes[:maxLen-1]), " ") + "…"
+}
+
+// maxRepeat bounds the count repeat and the padding helpers take, so a
+// typo'd or computed count can't allocate enough to stall the render.
+const maxRepeat = 1000
+
+// repeat returns s repeated n times, clamped to 0..maxRepeat.
+func repeat(n int, s string) string {
+	return strings.Repeat(s, min(max(n, 0), maxRepeat))
+}
+
+// padLeft right-aligns s in a field n columns wide.
+func padLeft(n int, s string) string {
+	return repeat(n-DisplayWidth(s), " ") + s
+}
+
+// padRight left-aligns s in a field n columns wide.
+func padRight(n int, s string) string {
+	return s + repeat(n-DisplayWidth(s), " ")
+}
+
+// sparkLevels are the sparkline bars from lowest to highest.
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// sparkBlocks maps build results to sparkline bars; failures stand tallest
+// so they catch the eye.
+var sparkBlocks = map[string]string{
+	"success": "▁",
+	"pending": "▃",
+	"error":   "▃",
+	"failure": "█",
+}
+
+// sparkline renders a list as bars. Build results map to fixed heights:
+// ["success" "failure" "success"] -> "▁█▁" (unknown results render as a
+// space). Numbers are scaled from zero to the largest: [10 20 40 5] -> "▂▄█▁".
+func sparkline(list any) string {
+	items := listItems(list)
+	var top float64
+	for _, item := range items {
+		top = max(top, toFloat(item))
+	}
+
+	var b strings.Builder
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			if block, ok := sparkBlocks[s]; ok {
+				b.WriteString(block)
+			} else {
+				b.WriteByte(' ')
+			}
+			continue
 		}
-		if pct >= 50 {
-			return colorYellow
+		level := 0
+		if top > 0 {
+			level = int(toFloat(item) / top * float64(len(sparkLevels)-1))
 		}
-		return colorGreen
+		b.WriteRune(sparkLevels[max(level, 0)])
+	}
+	return b.String()
+}
+
+// funcs is the template function map with formatters and the colors that
+// don't depend on the palette; see paletteFuncs for the rest.
+var funcs = template.FuncMap{
+	"reset": func() string { return colorReset },
+	"bold":  func() string { return colorBold },
+
+	"bg": func(hex string) string {
+		r, g, b, ok := parseHex(hex)
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("\033[48;2;%d;%d;%dm", r, g, b)
 	},
+	"color256": func(n any) string { return ansi256(38, n) },
+	"bg256":    func(n any) string { return ansi256(48, n) },
 
 	// fmtTokens formats token counts: 10500 -> "10.5k", 1234567 -> "1.2M"
 	"fmtTokens": FormatTokens,
 
+	// fmtCost formats a USD amount: 1.234 -> "$1.23"
+	"fmtCost": tokens.FormatCost,
+
 	// fmtPct formats a percentage: 45.2 -> "45.2%"
 	"fmtPct": func(pct float64) string {
 		return fmt.Sprintf("%.1f%%", pct)
 	},
 
+	// plural picks a label by count: plural 1 "task" "tasks" -> "task", plural 3 ... -> "tasks"
+	"plural": func(n any, singular, pluralForm string) string {
+		if toFloat(n) == 1 {
+			return singular
+		}
+		return pluralForm
+	},
+
+	// sep marks a segment boundary; it renders as the "sep" symbol only
+	// between non-empty segments: {{.Model}}{{sep}}{{.GitBranch}}{{sep}}{{.GitHubStatus}}
+	"sep": func() string { return segmentMarker },
+
+	// List helpers take the list last so they chain: {{.Items | limit 3 | join ", "}}
+	"join":  joinList,
+	"first": firstItem,
+	"limit": limitList,
+
+	// sparkline draws build or context history as bars: {{sparkline .GitHubHistory}} -> "▁▁█▁"
+	"sparkline": sparkline,
+
+	// String helpers also take the string last: {{.GitBranch | truncate 20 | padRight 20}}
+	"truncate": func(n int, s string) string { return Truncate(s, n) },
+	"trunc":    func(n int, s string) string { return TruncateWidth(s, n) },
+	"padLeft":  padLeft,
+	"padRight": padRight,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"repeat":   repeat,
+
 	// fmtSigned formats an integer with + prefix for positive: 42 -> "+42", -5 -> "-5"
 	"fmtSigned": func(n int) string {
 		if n > 0 {
@@ -129,18 +625,155 @@ var funcs = template.FuncMap{
 	},
 }
 
+// paletteFuncs returns the named color helpers drawing from palette, which
+// must hold every color in ColorMap.
+func paletteFuncs(palette map[string]string) template.FuncMap {
+	named := func(name string) func() string {
+		return func() string { return palette[name] }
+	}
+	return template.FuncMap{
+		"cyan":    named("cyan"),
+		"blue":    named("blue"),
+		"green":   named("green"),
+		"yellow":  named("yellow"),
+		"red":     named("red"),
+		"magenta": named("magenta"),
+		"gray":    named("gray"),
+
+		// Colors beyond the named ones; invalid values render as "":
+		// {{color "#ff8800"}}, {{bg "#222"}}, {{color256 208}}, {{bg256 236}}
+		"color": func(name string) string {
+			code, _ := paletteCode(palette, name)
+			return code
+		},
+
+		// Context percentage color: green < 50%, yellow 50-80%, red > 80%
+		"ctxColor": func(pct float64) string {
+			if pct >= 80 {
+				return palette["red"]
+			}
+			if pct >= 50 {
+				return palette["yellow"]
+			}
+			return palette["green"]
+		},
+
+		// Uncommitted-work color: green < 1h, yellow 1-4h, red > 4h
+		"dirtyColor": func(minutes int) string {
+			if minutes >= 240 {
+				return palette["red"]
+			}
+			if minutes >= 60 {
+				return palette["yellow"]
+			}
+			return palette["green"]
+		},
+	}
+}
+
+// symbolFunc returns the "sym" template function, resolving names against
+// overrides first and DefaultSymbols second. Unknown names render as "".
+func symbolFunc(overrides map[string]string) func(string) string {
+	return func(name string) string {
+		if s, ok := overrides[name]; ok {
+			return s
+		}
+		return DefaultSymbols[name]
+	}
+}
+
 // Engine renders status lines using Go templates.
 type Engine struct {
-	tmpl *template.Template
+	tmpl      *template.Template
+	separator string // Text that replaces {{sep}} between non-empty segments
 }
 
 // NewEngine creates a new template engine with the given template string.
 func NewEngine(templateStr string) (*Engine, error) {
-	tmpl, err := template.New("status").Funcs(funcs).Parse(templateStr)
+	return NewEngineWithSymbols(templateStr, nil)
+}
+
+// NewEngineWithSymbols creates a new template engine whose {{sym}} function
+// (and its alias {{icon}}) prefers the given symbol overrides over
+// DefaultSymbols.
+func NewEngineWithSymbols(templateStr string, symbols map[string]string) (*Engine, error) {
+	return NewEngineWithPalette(templateStr, symbols, nil)
+}
+
+// NewEngineWithPalette is NewEngineWithSymbols with the named colors taken
+// from palette (see Palettes). A nil palette means the dark one.
+func NewEngineWithPalette(templateStr string, symbols, palette map[string]string) (*Engine, error) {
+	if palette == nil {
+		palette = Palettes[ThemeDark]
+	}
+	sym := symbolFunc(symbols)
+	tmpl, err := template.New("status").
+		Funcs(funcs).
+		Funcs(paletteFuncs(palette)).
+		Funcs(template.FuncMap{"sym": sym, "icon": sym}).
+		Parse(templateStr)
 	if err != nil {
 		return nil, err
 	}
-	return &Engine{tmpl: tmpl}, nil
+	return &Engine{tmpl: tmpl, separator: sym("sep")}, nil
+}
+
+// maxCompiled bounds the compiled memo. Only a few templates are live at once
+// (the configured one and the default it falls back to), so this is
+// generous; when full, the memo starts over.
+const maxCompiled = 8
+
+// compiled memoizes parsed engines by content hash so the daemon renders
+// repeatedly without re-parsing an unchanged template. It is process-local
+// and nothing is persisted: a parsed template can't be serialized, so a
+// one-shot CLI render parses its template once either way.
+var compiled = struct {
+	sync.Mutex
+	entries map[[sha256.Size]byte]compiledEntry
+}{entries: make(map[[sha256.Size]byte]compiledEntry)}
+
+// compiledEntry is a cached parse result; invalid templates cache their error.
+type compiledEntry struct {
+	engine *Engine
+	err    error
+}
+
+// Compile returns an Engine for the template, symbol overrides, and palette,
+// reusing one this process parsed earlier when all are unchanged. Parse
+// errors are cached too.
+func Compile(templateStr string, symbols, palette map[string]string) (*Engine, error) {
+	key := compileKey(templateStr, symbols, palette)
+
+	compiled.Lock()
+	defer compiled.Unlock()
+
+	if entry, ok := compiled.entries[key]; ok {
+		return entry.engine, entry.err
+	}
+
+	engine, err := NewEngineWithPalette(templateStr, symbols, palette)
+	if len(compiled.entries) >= maxCompiled {
+		clear(compiled.entries)
+	}
+	compiled.entries[key] = compiledEntry{engine: engine, err: err}
+	return engine, err
+}
+
+// compileKey hashes the template together with its symbol overrides and
+// palette in a stable order.
+func compileKey(templateStr string, symbols, palette map[string]string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(templateStr))
+	for _, name := range slices.Sorted(maps.Keys(symbols)) {
+		fmt.Fprintf(h, "\x00%s=%s", name, symbols[name])
+	}
+	h.Write([]byte{1})
+	for _, name := range slices.Sorted(maps.Keys(palette)) {
+		fmt.Fprintf(h, "\x00%s=%s", name, palette[name])
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
 }
 
 // Render executes the template with the given data and returns the result.
@@ -149,5 +782,30 @@ func (e *Engine) Render(data StatusData) (string, error) {
 	if err := e.tmpl.Execute(&buf, data); err != nil {
 		return "", err
 	}
-	return buf.String(), nil
+	return joinSegments(buf.String(), e.separator), nil
+}
+
+// joinSegments replaces {{sep}} markers with separator, but only between
+// segments that have visible content. Segments holding nothing but ANSI codes
+// and whitespace keep their codes (so colors still reset) without a separator.
+func joinSegments(out, separator string) string {
+	if !strings.Contains(out, segmentMarker) {
+		return out
+	}
+
+	var b strings.Builder
+	seenVisible := false
+	for _, seg := range strings.Split(out, segmentMarker) {
+		codes := ansiPattern.FindAllString(seg, -1)
+		if strings.TrimSpace(ansiPattern.ReplaceAllString(seg, "")) == "" {
+			b.WriteString(strings.Join(codes, ""))
+			continue
+		}
+		if seenVisible {
+			b.WriteString(separator)
+		}
+		b.WriteString(seg)
+		seenVisible = true
+	}
+	return b.String()
 }
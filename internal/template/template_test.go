@@ -1,8 +1,10 @@
 package template
 
 import (
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewEngine_ValidTemplate(t *testing.T) {
@@ -384,3 +386,569 @@ func TestRender_ContextPercentageWithColor(t *testing.T) {
 		t.Error("Missing reset code")
 	}
 }
+
+func TestSymFunction(t *testing.T) {
+	tests := []struct {
+		name    string
+		symbols map[string]string
+		want    string
+	}{
+		{"defaults", nil, "🌿 📋 "},
+		{"override", map[string]string{"branch": "B"}, "B 📋 "},
+		{"unknown name", map[string]string{}, "🌿 📋 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngineWithSymbols(`{{sym "branch"}} {{sym "tasks"}} {{sym "nope"}}`, tt.symbols)
+			if err != nil {
+				t.Fatalf("NewEngineWithSymbols() error = %v", err)
+			}
+			result, err := engine.Render(StatusData{})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluralFunction(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data StatusData
+		want string
+	}{
+		{"one int", `{{.TasksReady}} ready {{plural .TasksReady "task" "tasks"}}`, StatusData{TasksReady: 1}, "1 ready task"},
+		{"many int", `{{.TasksReady}} ready {{plural .TasksReady "task" "tasks"}}`, StatusData{TasksReady: 3}, "3 ready tasks"},
+		{"zero int", `{{plural .TasksReady "task" "tasks"}}`, StatusData{}, "tasks"},
+		{"int64", `{{plural .TokensInput "token" "tokens"}}`, StatusData{TokensInput: 1}, "token"},
+		{"literal", `{{plural 2 "file" "files"}}`, StatusData{}, "files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(tt.tmpl)
+			if err != nil {
+				t.Fatalf("NewEngine() error = %v", err)
+			}
+			result, err := engine.Render(tt.data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data StatusData
+		want string
+	}{
+		{"truncate long", `{{.GitBranch | truncate 10}}`, StatusData{GitBranch: "feature/login-page"}, "feature/l…"},
+		{"truncate short", `{{truncate 10 .GitBranch}}`, StatusData{GitBranch: "main"}, "main"},
+		{"truncate trims space", `{{truncate 5 "ab cd ef"}}`, StatusData{}, "ab c…"},
+		{"truncate runes", `{{truncate 3 "日本語です"}}`, StatusData{}, "日本…"},
+		{"truncate zero", `{{truncate 0 "main"}}`, StatusData{}, ""},
+		{"padLeft", `[{{.TasksNextTask | padLeft 6}}]`, StatusData{TasksNextTask: "fix"}, "[   fix]"},
+		{"padRight", `[{{padRight 6 "fix"}}]`, StatusData{}, "[fix   ]"},
+		{"pad already wide", `[{{padRight 2 "fix"}}]`, StatusData{}, "[fix]"},
+		{"pad after truncate", `[{{.GitBranch | truncate 8 | padRight 8}}]`, StatusData{GitBranch: "main"}, "[main    ]"},
+		{"upper", `{{upper .Model}}`, StatusData{Model: "Opus"}, "OPUS"},
+		{"lower", `{{.Model | lower}}`, StatusData{Model: "Opus"}, "opus"},
+		{"repeat", `{{repeat 3 "─"}}`, StatusData{}, "───"},
+		{"repeat negative", `[{{"-" | repeat -1}}]`, StatusData{}, "[]"},
+		{"repeat clamped", `{{repeat 1000000000 "x"}}`, StatusData{}, strings.Repeat("x", maxRepeat)},
+		{"pad clamped", `{{padLeft 1000000000 "x"}}`, StatusData{}, strings.Repeat(" ", maxRepeat) + "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(tt.tmpl)
+			if err != nil {
+				t.Fatalf("NewEngine() error = %v", err)
+			}
+			result, err := engine.Render(tt.data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-time.Minute, "0m"},
+		{30 * time.Second, "0m"},
+		{90 * time.Second, "1m"},
+		{5*time.Hour + 20*time.Minute, "5h"},
+		{76 * time.Hour, "3d"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatAge(tt.d); got != tt.want {
+			t.Errorf("FormatAge(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestFmtCostFunction(t *testing.T) {
+	tests := []struct {
+		cost float64
+		want string
+	}{
+		{0, "$0.00"},
+		{0.001, "<$0.01"},
+		{3.456, "$3.46"},
+	}
+
+	engine, err := NewEngine(`{{fmtCost .CostUSD}}`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	for _, tt := range tests {
+		result, err := engine.Render(StatusData{CostUSD: tt.cost})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if result != tt.want {
+			t.Errorf("fmtCost %v = %q, want %q", tt.cost, result, tt.want)
+		}
+	}
+}
+
+func TestListFunctions(t *testing.T) {
+	list := []string{"lint", "unit-tests", "e2e"}
+
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"join", `{{join ", " .}}`, "lint, unit-tests, e2e"},
+		{"first", `{{first .}}`, "lint"},
+		{"limit", `{{limit 2 . | join "/"}}`, "lint/unit-tests"},
+		{"limit larger than list", `{{limit 10 . | join "/"}}`, "lint/unit-tests/e2e"},
+		{"limit negative", `{{limit -1 . | join "/"}}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(tt.tmpl)
+			if err != nil {
+				t.Fatalf("NewEngine() error = %v", err)
+			}
+			var buf strings.Builder
+			if err := engine.tmpl.Execute(&buf, list); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Execute() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestListFunctions_EmptyAndNonList(t *testing.T) {
+	if got := joinList(",", nil); got != "" {
+		t.Errorf("joinList(nil) = %q, want empty", got)
+	}
+	if got := firstItem([]int{}); got != "" {
+		t.Errorf("firstItem(empty) = %v, want empty", got)
+	}
+	if got := limitList(2, "not a list"); len(got) != 0 {
+		t.Errorf("limitList(non-list) = %v, want empty", got)
+	}
+	if got := joinList("+", []int{1, 2}); got != "1+2" {
+		t.Errorf("joinList(ints) = %q, want %q", got, "1+2")
+	}
+}
+
+func TestRender_Sparkline(t *testing.T) {
+	engine, err := NewEngine(`{{sparkline .GitHubHistory}}`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	result, err := engine.Render(StatusData{GitHubHistory: []string{"success", "success", "failure", "pending", "success", "bogus"}})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "▁▁█▃▁ "; result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+
+	if got := sparkline(nil); got != "" {
+		t.Errorf("sparkline(nil) = %q, want empty", got)
+	}
+}
+
+func TestSparkline_Numbers(t *testing.T) {
+	tests := []struct {
+		name string
+		list any
+		want string
+	}{
+		{"growth and compact", []int64{10, 20, 40, 5}, "▂▄█▁"},
+		{"all zero", []int{0, 0}, "▁▁"},
+		{"floats", []float64{50, 100}, "▄█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sparkline(tt.list); got != tt.want {
+				t.Errorf("sparkline(%v) = %q, want %q", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_FileTypes(t *testing.T) {
+	engine, err := NewEngine(`{{join " " .GitFileTypes}}|{{range .GitFileTypes}}[{{.Ext}}={{.Count}}]{{end}}`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	result, err := engine.Render(StatusData{
+		GitFileTypes: []FileTypeCount{{Ext: ".go", Count: 4}, {Ext: ".md", Count: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	want := ".go:4 .md:1|[.go=4][.md=1]"
+	if result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
+
+func TestRender_ChangedFiles(t *testing.T) {
+	engine, err := NewEngine(`{{range .GitChangedFiles}}[{{.}}]{{end}} {{(first .GitChangedFiles).Path}}`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	result, err := engine.Render(StatusData{
+		GitChangedFiles: []ChangedFile{{Status: " M", Path: "main.go"}, {Status: "??", Path: "new.txt"}},
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	want := "[ M main.go][?? new.txt] main.go"
+	if result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
+
+func TestRender_Workspaces(t *testing.T) {
+	engine, err := NewEngine(`±{{.WorkspaceChanged}} ({{join ", " .Workspaces}})`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	result, err := engine.Render(StatusData{
+		Workspaces:       []WorkspaceDir{{Dir: "api", Branch: "main", Changed: 3}, {Dir: "docs", Branch: "main"}},
+		WorkspaceChanged: 3,
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	want := "±3 (api ±3, docs)"
+	if result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
+
+func TestSepFunction(t *testing.T) {
+	tmpl := `{{cyan}}[{{.Model}}]{{reset}}{{sep}}{{if .GitBranch}}{{green}}{{.GitBranch}}{{reset}}{{end}}{{sep}}{{.GitHubStatus}}{{sep}}{{.Version}}`
+
+	tests := []struct {
+		name    string
+		symbols map[string]string
+		data    StatusData
+		want    string
+	}{
+		{
+			name: "all segments",
+			data: StatusData{Model: "Claude", GitBranch: "main", GitHubStatus: "✅", Version: "1.0"},
+			want: colorCyan + "[Claude]" + colorReset + " | " + colorGreen + "main" + colorReset + " | ✅ | 1.0",
+		},
+		{
+			name: "empty middle segments",
+			data: StatusData{Model: "Claude", Version: "1.0"},
+			want: colorCyan + "[Claude]" + colorReset + " | 1.0",
+		},
+		{
+			name: "trailing empty segments",
+			data: StatusData{Model: "Claude"},
+			want: colorCyan + "[Claude]" + colorReset,
+		},
+		{
+			name:    "custom separator",
+			symbols: map[string]string{"sep": " · "},
+			data:    StatusData{Model: "Claude", GitHubStatus: "✅"},
+			want:    colorCyan + "[Claude]" + colorReset + " · ✅",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngineWithSymbols(tmpl, tt.symbols)
+			if err != nil {
+				t.Fatalf("NewEngineWithSymbols() error = %v", err)
+			}
+			result, err := engine.Render(tt.data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Render() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestSepFunction_KeepsColorCodesOfEmptySegments(t *testing.T) {
+	engine, err := NewEngine(`a{{sep}}{{red}} {{reset}}{{sep}}b`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	result, err := engine.Render(StatusData{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "a" + colorRed + colorReset + " | b"
+	if result != want {
+		t.Errorf("Render() = %q, want %q", result, want)
+	}
+}
+
+func TestCompile_ReusesEngine(t *testing.T) {
+	first, err := Compile(`{{.Model}} {{sym "branch"}}`, map[string]string{"branch": "B", "dir": "D"}, nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	second, err := Compile(`{{.Model}} {{sym "branch"}}`, map[string]string{"dir": "D", "branch": "B"}, nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if first != second {
+		t.Error("Compile() returned a new engine for an unchanged template")
+	}
+
+	other, err := Compile(`{{.Model}} {{sym "branch"}}`, map[string]string{"branch": "X"}, nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if other == first {
+		t.Error("Compile() reused an engine across different symbols")
+	}
+	result, _ := other.Render(StatusData{Model: "Claude"})
+	if result != "Claude X" {
+		t.Errorf("Render() = %q, want %q", result, "Claude X")
+	}
+}
+
+func TestCompile_CachesErrors(t *testing.T) {
+	_, err1 := Compile(`{{.Model`, nil, nil)
+	_, err2 := Compile(`{{.Model`, nil, nil)
+	if err1 == nil || err2 == nil {
+		t.Fatal("Compile() expected error for invalid template")
+	}
+	if err1 != err2 {
+		t.Error("Compile() re-parsed an invalid template instead of returning the cached error")
+	}
+}
+
+func TestCompile_BoundsMemo(t *testing.T) {
+	for i := range 3 * maxCompiled {
+		if _, err := Compile("{{.Model}} "+strconv.Itoa(i), nil, nil); err != nil {
+			t.Fatalf("Compile() error = %v", err)
+		}
+	}
+
+	compiled.Lock()
+	defer compiled.Unlock()
+	if n := len(compiled.entries); n > maxCompiled {
+		t.Errorf("memo holds %d engines, want at most %d", n, maxCompiled)
+	}
+}
+
+func TestNewEngineWithPalette(t *testing.T) {
+	light := Palettes[ThemeLight]
+	engine, err := NewEngineWithPalette(`{{yellow}}|{{color "gray"}}|{{ctxColor .ContextPct}}|{{reset}}`, nil, light)
+	if err != nil {
+		t.Fatalf("NewEngineWithPalette() error = %v", err)
+	}
+	got, err := engine.Render(StatusData{ContextPct: 90})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := light["yellow"] + "|" + light["gray"] + "|" + light["red"] + "|" + colorReset
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	for name := range ColorMap {
+		if light[name] == "" || light[name] == ColorMap[name] {
+			t.Errorf("light palette %s = %q, want its own shade", name, light[name])
+		}
+	}
+
+	dark, _ := Compile(`{{yellow}}`, nil, Palettes[ThemeDark])
+	lightEngine, _ := Compile(`{{yellow}}`, nil, light)
+	if dark == lightEngine {
+		t.Error("Compile() reused an engine across palettes")
+	}
+}
+
+func TestDirtyColor(t *testing.T) {
+	engine, err := NewEngine(`{{dirtyColor .DirtyMinutes}}`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		minutes   int
+		wantColor string
+	}{
+		{0, colorGreen},
+		{59, colorGreen},
+		{60, colorYellow},
+		{239, colorYellow},
+		{240, colorRed},
+	}
+	for _, tt := range tests {
+		got, err := engine.Render(StatusData{DirtyMinutes: tt.minutes})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if got != tt.wantColor {
+			t.Errorf("dirtyColor(%d) = %q, want %q", tt.minutes, got, tt.wantColor)
+		}
+	}
+}
+
+func TestTruecolorFunctions(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{color "#ff8800"}}`, "\033[38;2;255;136;0m"},
+		{`{{color "#F80"}}`, "\033[38;2;255;136;0m"},
+		{`{{color "cyan"}}`, colorCyan},
+		{`{{color "#ff88"}}`, ""},
+		{`{{color "orange"}}`, ""},
+		{`{{bg "#222222"}}`, "\033[48;2;34;34;34m"},
+		{`{{bg "cyan"}}`, ""},
+		{`{{color256 208}}`, "\033[38;5;208m"},
+		{`{{bg256 236}}`, "\033[48;5;236m"},
+		{`{{color256 256}}`, ""},
+		{`{{color256 .GitAhead}}`, "\033[38;5;3m"},
+	}
+
+	for _, tt := range tests {
+		engine, err := NewEngine(tt.tmpl)
+		if err != nil {
+			t.Fatalf("NewEngine(%s) error = %v", tt.tmpl, err)
+		}
+		got, err := engine.Render(StatusData{GitAhead: 3})
+		if err != nil {
+			t.Fatalf("Render(%s) error = %v", tt.tmpl, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestColorCode(t *testing.T) {
+	if code, ok := ColorCode("blue"); !ok || code != colorBlue {
+		t.Errorf("ColorCode(blue) = %q, %v; want %q", code, ok, colorBlue)
+	}
+	if code, ok := ColorCode("#000080"); !ok || code != "\033[38;2;0;0;128m" {
+		t.Errorf("ColorCode(#000080) = %q, %v", code, ok)
+	}
+	if _, ok := ColorCode("#zzzzzz"); ok {
+		t.Error("ColorCode(#zzzzzz) ok = true, want false")
+	}
+}
+
+func TestIconThemes(t *testing.T) {
+	tests := []struct {
+		theme string
+		want  string
+	}{
+		{IconsEmoji, "🌿 main"},
+		{IconsNerdFont, "\ue0a0 main"},
+		{IconsASCII, "@ main"},
+		{"", "🌿 main"},
+	}
+
+	for _, tt := range tests {
+		engine, err := NewEngineWithSymbols(`{{icon "branch"}} {{.GitBranch}}`, ThemeSymbols(tt.theme, nil))
+		if err != nil {
+			t.Fatalf("NewEngineWithSymbols() error = %v", err)
+		}
+		got, err := engine.Render(StatusData{GitBranch: "main"})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("theme %q: got %q, want %q", tt.theme, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironmentSymbol(t *testing.T) {
+	tests := []struct {
+		theme       string
+		environment string
+		want        string
+	}{
+		{IconsEmoji, "codespaces", "[☁ codespaces]"},
+		{IconsEmoji, "container", "[🐳 container]"},
+		{IconsASCII, "devcontainer", "[[dev] devcontainer]"},
+		{IconsEmoji, "", "[]"},
+	}
+
+	for _, tt := range tests {
+		engine, err := NewEngineWithSymbols(`[{{with .Environment}}{{sym .}} {{.}}{{end}}]`, ThemeSymbols(tt.theme, nil))
+		if err != nil {
+			t.Fatalf("NewEngineWithSymbols() error = %v", err)
+		}
+		got, err := engine.Render(StatusData{Environment: tt.environment})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("theme %q, environment %q: got %q, want %q", tt.theme, tt.environment, got, tt.want)
+		}
+	}
+}
+
+func TestThemeSymbols_Overrides(t *testing.T) {
+	symbols := ThemeSymbols(IconsASCII, map[string]string{"dir": "in"})
+	if symbols["dir"] != "in" || symbols["branch"] != "@" {
+		t.Errorf("ThemeSymbols() = %v, want the override over the theme", symbols)
+	}
+	if IconThemes[IconsASCII]["dir"] != "~" {
+		t.Error("ThemeSymbols() modified the theme table")
+	}
+}
@@ -0,0 +1,73 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// DisplayWidth returns how many terminal columns s takes up: wide characters
+// and emoji count as two, and color escapes as none.
+func DisplayWidth(s string) int {
+	width := 0
+	state := -1
+	for s != "" {
+		if n := escapeLen(s); n > 0 {
+			s, state = s[n:], -1
+			continue
+		}
+		var w int
+		_, s, w, state = uniseg.FirstGraphemeClusterInString(s, state)
+		width += w
+	}
+	return width
+}
+
+// TruncateWidth shortens s to at most width terminal columns, ending in "…"
+// if cut. Color escapes are kept without counting toward the width, and a
+// reset follows the "…" so a color that was cut off doesn't leak.
+func TruncateWidth(s string, width int) string {
+	if DisplayWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	used, colored := 0, false
+	state := -1
+	for s != "" {
+		if n := escapeLen(s); n > 0 {
+			b.WriteString(s[:n])
+			s, state, colored = s[n:], -1, true
+			continue
+		}
+		cluster, rest, w, newState := uniseg.FirstGraphemeClusterInString(s, state)
+		if used+w > width-1 { // Leave a column for the "…"
+			break
+		}
+		b.WriteString(cluster)
+		used += w
+		s, state = rest, newState
+	}
+	b.WriteString("…")
+	if colored {
+		b.WriteString(colorReset)
+	}
+	return b.String()
+}
+
+// escapeLen returns the length of the ANSI escape sequence (e.g. a color
+// code like "\033[31m") that s starts with, or 0 if it doesn't start with one.
+func escapeLen(s string) int {
+	if !strings.HasPrefix(s, "\033[") {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if c := s[i]; c >= 0x40 && c <= 0x7e {
+			return i + 1
+		}
+	}
+	return 0
+}
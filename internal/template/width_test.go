@@ -0,0 +1,66 @@
+package template
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"main", 4},
+		{"日本語", 6},
+		{"✅ ok", 5},
+		{"👨‍👩‍👧", 2}, // One ZWJ sequence
+		{"\033[32mmain\033[0m", 4},
+		{"\033[38;2;255;136;0m🌿\033[0m x", 4},
+	}
+	for _, tt := range tests {
+		if got := DisplayWidth(tt.s); got != tt.want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  string
+	}{
+		{"fits", "main", 4, "main"},
+		{"cut", "feature/login", 8, "feature…"},
+		{"wide characters", "日本語です", 5, "日本…"},
+		{"wide character at the edge", "日本語です", 4, "日…"},
+		{"emoji", "🌿 main ✅", 6, "🌿 ma…"},
+		{"colors don't count", "\033[32mmain\033[0m", 4, "\033[32mmain\033[0m"},
+		{"cut color is reset", "\033[32mfeature/login\033[0m", 5, "\033[32mfeat…\033[0m"},
+		{"zero", "main", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateWidth(tt.s, tt.width)
+			if got != tt.want {
+				t.Errorf("TruncateWidth(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+			if w := DisplayWidth(got); w > tt.width {
+				t.Errorf("DisplayWidth(%q) = %d, over %d", got, w, tt.width)
+			}
+		})
+	}
+}
+
+func TestTruncFunction(t *testing.T) {
+	engine, err := NewEngine(`[{{.GitBranch | trunc 6}}] [{{padLeft 4 "日本"}}]`)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	got, err := engine.Render(StatusData{GitBranch: "日本語です"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := "[日本…] [日本]"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+// Package theme decides whether the status line renders with the dark or the
+// light palette. A fixed "dark" or "light" theme is used as is; "auto" follows
+// a configured time-of-day schedule, the terminal's COLORFGBG variable, or the
+// background last detected with an OSC 11 query, in that order.
+package theme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// Schedule switches to the light palette during the day. Both times are
+// local "HH:MM"; a schedule whose Dark time is earlier than its Light time
+// wraps past midnight. An empty schedule is unset.
+type Schedule struct {
+	Light string `json:"light"` // When the light palette starts
+	Dark  string `json:"dark"`  // When the dark palette starts
+}
+
+// IsZero reports whether neither time is set.
+func (s Schedule) IsZero() bool {
+	return s.Light == "" && s.Dark == ""
+}
+
+// Validate checks that both times are set and parse as "HH:MM".
+func (s Schedule) Validate() error {
+	if _, err := parseClock(s.Light); err != nil {
+		return fmt.Errorf("light: %w", err)
+	}
+	if _, err := parseClock(s.Dark); err != nil {
+		return fmt.Errorf("dark: %w", err)
+	}
+	return nil
+}
+
+// At returns the theme the schedule selects at t, or false if the schedule
+// is unset or invalid.
+func (s Schedule) At(t time.Time) (string, bool) {
+	light, err := parseClock(s.Light)
+	if err != nil {
+		return "", false
+	}
+	dark, err := parseClock(s.Dark)
+	if err != nil {
+		return "", false
+	}
+	now := t.Hour()*60 + t.Minute()
+	inLight := now >= light && now < dark
+	if dark < light {
+		inLight = now >= light || now < dark
+	}
+	if inLight {
+		return template.ThemeLight, true
+	}
+	return template.ThemeDark, true
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Env is what Resolve consults for the "auto" theme.
+type Env struct {
+	Getenv    func(string) string
+	Now       func() time.Time
+	SavedPath string // Background saved by Save; "" skips it
+}
+
+// DefaultEnv reads the process environment, the wall clock, and the
+// background saved at savedPath.
+func DefaultEnv(savedPath string) Env {
+	return Env{Getenv: os.Getenv, Now: time.Now, SavedPath: savedPath}
+}
+
+// Resolve returns the concrete theme (template.ThemeDark or
+// template.ThemeLight) for the configured one, and where it came from.
+// Unknown themes, and "auto" with nothing to go on, fall back to dark.
+func Resolve(theme string, schedule Schedule, env Env) (resolved, source string) {
+	switch theme {
+	case template.ThemeDark, template.ThemeLight:
+		return theme, "config"
+	case template.ThemeAuto:
+	default:
+		return template.ThemeDark, "default"
+	}
+
+	if t, ok := schedule.At(env.Now()); ok {
+		return t, "schedule"
+	}
+	if t, ok := FromColorFGBG(env.Getenv("COLORFGBG")); ok {
+		return t, "COLORFGBG"
+	}
+	if env.SavedPath != "" {
+		if t, err := Load(env.SavedPath); err == nil {
+			return t, "detected"
+		}
+	}
+	return template.ThemeDark, "default"
+}
+
+// FromColorFGBG reads the background from a COLORFGBG value such as "15;0"
+// or "0;default;15", set by rxvt, Konsole, iTerm2, and others. The last
+// field is an ANSI color index: white (7) and the bright colors other than
+// bright black (9-15) are light backgrounds.
+func FromColorFGBG(value string) (string, bool) {
+	if value == "" {
+		return "", false
+	}
+	fields := strings.Split(value, ";")
+	bg, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || bg < 0 || bg > 15 {
+		return "", false
+	}
+	if bg == 7 || bg >= 9 {
+		return template.ThemeLight, true
+	}
+	return template.ThemeDark, true
+}
+
+// ParseOSC11 reads the background from a terminal's reply to an OSC 11
+// query, e.g. "\x1b]11;rgb:ffff/ffff/ffff\x1b\\". Each channel may have one
+// to four hex digits. Backgrounds with a relative luminance above one half
+// are light.
+func ParseOSC11(reply []byte) (string, bool) {
+	_, rest, ok := bytes.Cut(reply, []byte("rgb:"))
+	if !ok {
+		return "", false
+	}
+	rest = bytes.TrimRight(rest, "\x07\x1b\\")
+	channels := strings.Split(string(rest), "/")
+	if len(channels) != 3 {
+		return "", false
+	}
+	var rgb [3]float64
+	for i, c := range channels {
+		if len(c) == 0 || len(c) > 4 {
+			return "", false
+		}
+		v, err := strconv.ParseUint(c, 16, 16)
+		if err != nil {
+			return "", false
+		}
+		rgb[i] = float64(v) / float64(uint64(1)<<(4*len(c))-1)
+	}
+	if 0.2126*rgb[0]+0.7152*rgb[1]+0.0722*rgb[2] > 0.5 {
+		return template.ThemeLight, true
+	}
+	return template.ThemeDark, true
+}
+
+// osc11Query asks the terminal for its background color.
+const osc11Query = "\x1b]11;?\x1b\\"
+
+// Query sends an OSC 11 query to the terminal tty and returns the background
+// it reports. The tty is switched to raw mode with stty for the duration so
+// the reply isn't echoed. Terminals that don't answer within timeout (many
+// don't support the query) return an error.
+//
+// Only call this from an interactive command: while Claude Code is running it
+// owns the terminal, and the reply would be read as keystrokes.
+func Query(tty *os.File, timeout time.Duration) (string, error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return "", err
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return "", err
+	}
+	defer stty(tty, strings.TrimSpace(saved))
+
+	if _, err := tty.WriteString(osc11Query); err != nil {
+		return "", err
+	}
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return "", fmt.Errorf("terminal does not support read timeouts: %w", err)
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for len(reply) < 256 {
+		n, err := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if bytes.HasSuffix(reply, []byte("\x07")) || bytes.HasSuffix(reply, []byte("\x1b\\")) {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return "", errors.New("terminal did not answer the background color query")
+			}
+			return "", err
+		}
+	}
+	theme, ok := ParseOSC11(reply)
+	if !ok {
+		return "", fmt.Errorf("unrecognized background color reply %q", reply)
+	}
+	return theme, nil
+}
+
+// stty runs stty with args against tty and returns its output.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// Save records a detected background at path for Resolve to use.
+func Save(path, theme string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(theme+"\n"), 0644)
+}
+
+// Load returns the background saved at path.
+func Load(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	theme := strings.TrimSpace(string(data))
+	if theme != template.ThemeDark && theme != template.ThemeLight {
+		return "", fmt.Errorf("%s: unknown background %q", path, theme)
+	}
+	return theme, nil
+}
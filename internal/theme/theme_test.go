@@ -0,0 +1,146 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kostyay/claude-status/internal/template"
+)
+
+// at returns a time on an arbitrary day at hh:mm local time.
+func at(hh, mm int) time.Time {
+	return time.Date(2025, 6, 1, hh, mm, 0, 0, time.Local)
+}
+
+func TestSchedule_At(t *testing.T) {
+	day := Schedule{Light: "07:00", Dark: "19:30"}
+	night := Schedule{Light: "22:00", Dark: "06:00"} // Wraps past midnight
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		now      time.Time
+		want     string
+	}{
+		{"morning", day, at(7, 0), template.ThemeLight},
+		{"before light", day, at(6, 59), template.ThemeDark},
+		{"evening", day, at(19, 30), template.ThemeDark},
+		{"wrapped late", night, at(23, 0), template.ThemeLight},
+		{"wrapped early", night, at(5, 59), template.ThemeLight},
+		{"wrapped day", night, at(12, 0), template.ThemeDark},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.schedule.At(tt.now)
+			if !ok || got != tt.want {
+				t.Errorf("At(%s) = %q, %v; want %q", tt.now.Format("15:04"), got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := (Schedule{}).At(at(12, 0)); ok {
+		t.Error("At() on an unset schedule returned ok")
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	if err := (Schedule{Light: "07:00", Dark: "19:00"}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := (Schedule{Light: "07:00"}).Validate(); err == nil {
+		t.Error("Validate() without a dark time = nil, want error")
+	}
+	if err := (Schedule{Light: "25:00", Dark: "19:00"}).Validate(); err == nil {
+		t.Error("Validate() with 25:00 = nil, want error")
+	}
+}
+
+func TestFromColorFGBG(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{"15;0", template.ThemeDark, true},
+		{"0;15", template.ThemeLight, true},
+		{"0;default;7", template.ThemeLight, true},
+		{"7;8", template.ThemeDark, true},
+		{"", "", false},
+		{"15;default", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FromColorFGBG(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("FromColorFGBG(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseOSC11(t *testing.T) {
+	tests := []struct {
+		reply  string
+		want   string
+		wantOK bool
+	}{
+		{"\x1b]11;rgb:ffff/ffff/ffff\x1b\\", template.ThemeLight, true},
+		{"\x1b]11;rgb:0000/0000/0000\x07", template.ThemeDark, true},
+		{"\x1b]11;rgb:fd/f6/e3\x07", template.ThemeLight, true}, // Solarized light
+		{"\x1b]11;rgb:00/2b/36\x07", template.ThemeDark, true},  // Solarized dark
+		{"\x1b]11;rgb:ffff/ffff\x07", "", false},
+		{"garbage", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseOSC11([]byte(tt.reply))
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseOSC11(%q) = %q, %v; want %q, %v", tt.reply, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	saved := filepath.Join(t.TempDir(), "background")
+	env := Env{
+		Getenv:    func(string) string { return "" },
+		Now:       func() time.Time { return at(12, 0) },
+		SavedPath: saved,
+	}
+	schedule := Schedule{Light: "07:00", Dark: "19:00"}
+
+	check := func(name, theme string, schedule Schedule, wantTheme, wantSource string) {
+		t.Helper()
+		got, source := Resolve(theme, schedule, env)
+		if got != wantTheme || source != wantSource {
+			t.Errorf("%s: Resolve(%q) = %q from %q, want %q from %q", name, theme, got, source, wantTheme, wantSource)
+		}
+	}
+
+	check("fixed", template.ThemeLight, schedule, template.ThemeLight, "config")
+	check("unset", "", schedule, template.ThemeDark, "default")
+	check("schedule", template.ThemeAuto, schedule, template.ThemeLight, "schedule")
+	check("nothing detected", template.ThemeAuto, Schedule{}, template.ThemeDark, "default")
+
+	if err := Save(saved, template.ThemeLight); err != nil {
+		t.Fatal(err)
+	}
+	check("saved", template.ThemeAuto, Schedule{}, template.ThemeLight, "detected")
+
+	env.Getenv = func(key string) string {
+		if key == "COLORFGBG" {
+			return "15;0"
+		}
+		return ""
+	}
+	check("COLORFGBG over saved", template.ThemeAuto, Schedule{}, template.ThemeDark, "COLORFGBG")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "background")
+	if err := os.WriteFile(path, []byte("purple\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load() of an unknown background = nil, want error")
+	}
+}
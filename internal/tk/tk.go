@@ -3,6 +3,7 @@ package tk
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -17,6 +18,7 @@ import (
 type Client struct {
 	cmd     tasks.Commander
 	workDir string
+	opts    tasks.QueryOptions
 }
 
 // NewClient creates a new tk client for the given working directory.
@@ -40,6 +42,23 @@ func (c *Client) Name() string {
 	return "tk"
 }
 
+// SetQueryOptions sets the query command, status mapping, and blocked
+// definition, for forks of tk with custom workflows.
+func (c *Client) SetQueryOptions(opts tasks.QueryOptions) {
+	c.opts = opts
+}
+
+// defaultCommand lists every ticket as JSON lines.
+var defaultCommand = []string{"tk", "query"}
+
+// command returns the configured query command, or tk query.
+func (c *Client) command() []string {
+	if len(c.opts.Command) > 0 {
+		return c.opts.Command
+	}
+	return defaultCommand
+}
+
 // Available checks if tk is available in the working directory.
 func (c *Client) Available() bool {
 	_, err := os.Stat(filepath.Join(c.workDir, ".tickets"))
@@ -57,11 +76,13 @@ type ticket struct {
 	Deps   []string `json:"deps"`
 }
 
-// GetStats runs `tk query` and computes stats from JSONL output.
-func (c *Client) GetStats() (tasks.Stats, error) {
-	output, err := c.cmd.Output("tk", "query")
+// GetStats runs `tk query`, or the configured command, and computes stats
+// from its JSONL output.
+func (c *Client) GetStats(ctx context.Context) (tasks.Stats, error) {
+	command := c.command()
+	output, err := c.cmd.Output(ctx, command[0], command[1:]...)
 	if err != nil {
-		return tasks.Stats{}, fmt.Errorf("failed to run tk query: %w", err)
+		return tasks.Stats{}, fmt.Errorf("failed to run %s: %w", strings.Join(command, " "), err)
 	}
 
 	tickets, err := parseJSONL(output)
@@ -69,7 +90,7 @@ func (c *Client) GetStats() (tasks.Stats, error) {
 		return tasks.Stats{}, fmt.Errorf("failed to parse tk query output: %w", err)
 	}
 
-	return computeStats(tickets), nil
+	return computeStats(tickets, c.opts), nil
 }
 
 // parseJSONL parses JSONL output (one JSON object per line).
@@ -93,35 +114,40 @@ func parseJSONL(data []byte) ([]ticket, error) {
 	return tickets, nil
 }
 
-// computeStats calculates stats from tickets.
+// computeStats calculates stats from tickets, with statuses mapped by
+// opts.Statuses. With the default BlockedByDeps:
 // Ready = (open OR in_progress) AND (deps empty OR all deps closed)
 // Blocked = (open OR in_progress) AND (has dep that is not closed)
-func computeStats(tickets []ticket) tasks.Stats {
+// BlockedByStatus counts tickets with the blocked status as blocked instead,
+// and BlockedByEither counts both.
+func computeStats(tickets []ticket, opts tasks.QueryOptions) tasks.Stats {
 	// Build status map for dep resolution
 	statusMap := make(map[string]string)
 	for _, t := range tickets {
-		statusMap[t.ID] = t.Status
+		statusMap[t.ID] = canonicalStatus(t.Status, opts.Statuses)
 	}
 
 	var stats tasks.Stats
 	stats.TotalIssues = len(tickets)
 
 	for _, t := range tickets {
-		switch t.Status {
-		case "open":
+		status := statusMap[t.ID]
+		switch status {
+		case tasks.StatusOpen, tasks.StatusBlocked:
 			stats.OpenIssues++
-		case "in_progress":
+		case tasks.StatusInProgress:
 			stats.InProgressIssues++
-		case "closed":
+		case tasks.StatusClosed:
 			stats.ClosedIssues++
+		default:
+			// Only compute ready/blocked for active tickets
+			continue
 		}
-
-		// Only compute ready/blocked for active tickets
-		if t.Status != "open" && t.Status != "in_progress" {
+		if status == tasks.StatusClosed {
 			continue
 		}
 
-		if isBlocked(t, statusMap) {
+		if isBlocked(t, status, statusMap, opts.Blocked) {
 			stats.BlockedIssues++
 		} else {
 			stats.ReadyIssues++
@@ -131,15 +157,34 @@ func computeStats(tickets []ticket) tasks.Stats {
 	return stats
 }
 
-// isBlocked returns true if ticket has any unresolved dependency.
-func isBlocked(t ticket, statusMap map[string]string) bool {
+// canonicalStatus maps a ticket's status through statuses, if it's there.
+func canonicalStatus(status string, statuses map[string]string) string {
+	if mapped, ok := statuses[status]; ok {
+		return mapped
+	}
+	return status
+}
+
+// isBlocked returns true if an active ticket is blocked under the blocked
+// definition: by its status, or by any unresolved dependency.
+func isBlocked(t ticket, status string, statusMap map[string]string, blocked string) bool {
+	byStatus := status == tasks.StatusBlocked
+	switch blocked {
+	case tasks.BlockedByStatus:
+		return byStatus
+	case tasks.BlockedByEither:
+		if byStatus {
+			return true
+		}
+	}
+
 	for _, depID := range t.Deps {
 		depStatus, exists := statusMap[depID]
 		if !exists {
 			// Unknown dep - consider blocked
 			continue
 		}
-		if depStatus != "closed" {
+		if depStatus != tasks.StatusClosed {
 			return true
 		}
 	}
@@ -147,9 +192,11 @@ func isBlocked(t ticket, statusMap map[string]string) bool {
 }
 
 // GetNextTask returns the title of the next ready task, or empty if none.
+// It runs `ready` on the query command's program, so a fork configured with
+// its own command is asked too.
 // Parses output format: `pp-461d  [P2][open] - Task title here`
-func (c *Client) GetNextTask() (string, error) {
-	output, err := c.cmd.Output("tk", "ready")
+func (c *Client) GetNextTask(ctx context.Context) (string, error) {
+	output, err := c.cmd.Output(ctx, c.command()[0], "ready")
 	if err != nil {
 		// tk ready exits non-zero when no ready tickets
 		return "", nil
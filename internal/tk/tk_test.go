@@ -1,8 +1,10 @@
 package tk
 
 import (
+	"context"
 	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/kostyay/claude-status/internal/tasks"
@@ -12,9 +14,11 @@ import (
 type mockCommander struct {
 	output []byte
 	err    error
+	calls  []string // Each command line run
 }
 
-func (m *mockCommander) Output(name string, args ...string) ([]byte, error) {
+func (m *mockCommander) Output(_ context.Context, name string, args ...string) ([]byte, error) {
+	m.calls = append(m.calls, strings.Join(append([]string{name}, args...), " "))
 	return m.output, m.err
 }
 
@@ -87,7 +91,7 @@ func TestClient_GetStats(t *testing.T) {
 			cmd := &mockCommander{output: []byte(tt.output)}
 			client := NewClientWithCommander(cmd, "/test")
 
-			got, err := client.GetStats()
+			got, err := client.GetStats(t.Context())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetStats() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -105,7 +109,7 @@ func TestClient_GetStats_CommandError(t *testing.T) {
 	cmd := &mockCommander{err: errors.New("command failed")}
 	client := NewClientWithCommander(cmd, "/test")
 
-	_, err := client.GetStats()
+	_, err := client.GetStats(t.Context())
 	if err == nil {
 		t.Error("GetStats() expected error for command failure")
 	}
@@ -187,7 +191,7 @@ func TestClient_GetNextTask(t *testing.T) {
 			cmd := &mockCommander{output: []byte(tt.output), err: tt.err}
 			client := NewClientWithCommander(cmd, "/test")
 
-			got, err := client.GetNextTask()
+			got, err := client.GetNextTask(t.Context())
 			if err != nil {
 				t.Errorf("GetNextTask() unexpected error = %v", err)
 				return
@@ -207,7 +211,7 @@ func TestComputeStats_BlockedChain(t *testing.T) {
 		{ID: "t-003", Status: "open", Deps: []string{"t-002"}},
 	}
 
-	stats := computeStats(tickets)
+	stats := computeStats(tickets, tasks.QueryOptions{})
 
 	if stats.TotalIssues != 3 {
 		t.Errorf("TotalIssues = %d, want 3", stats.TotalIssues)
@@ -227,7 +231,7 @@ func TestComputeStats_InProgressBlocked(t *testing.T) {
 		{ID: "t-002", Status: "in_progress", Deps: []string{"t-001"}},
 	}
 
-	stats := computeStats(tickets)
+	stats := computeStats(tickets, tasks.QueryOptions{})
 
 	if stats.InProgressIssues != 1 {
 		t.Errorf("InProgressIssues = %d, want 1", stats.InProgressIssues)
@@ -239,3 +243,58 @@ func TestComputeStats_InProgressBlocked(t *testing.T) {
 		t.Errorf("ReadyIssues = %d, want 1 (t-001)", stats.ReadyIssues)
 	}
 }
+
+func TestClient_QueryOptions(t *testing.T) {
+	// A fork with a review step and its own blocked status
+	output := `{"id":"t-001","title":"Task 1","status":"todo","deps":[]}
+{"id":"t-002","title":"Task 2","status":"doing","deps":[]}
+{"id":"t-003","title":"Task 3","status":"review","deps":[]}
+{"id":"t-004","title":"Task 4","status":"done","deps":[]}
+{"id":"t-005","title":"Task 5","status":"waiting","deps":[]}
+{"id":"t-006","title":"Task 6","status":"todo","deps":["t-001"]}`
+	statuses := map[string]string{
+		"todo":    tasks.StatusOpen,
+		"doing":   tasks.StatusInProgress,
+		"review":  tasks.StatusInProgress,
+		"done":    tasks.StatusClosed,
+		"waiting": tasks.StatusBlocked,
+	}
+
+	tests := []struct {
+		blocked string
+		want    tasks.Stats
+	}{
+		{tasks.BlockedByDeps, tasks.Stats{TotalIssues: 6, OpenIssues: 3, InProgressIssues: 2, ClosedIssues: 1, BlockedIssues: 1, ReadyIssues: 4}},
+		{tasks.BlockedByStatus, tasks.Stats{TotalIssues: 6, OpenIssues: 3, InProgressIssues: 2, ClosedIssues: 1, BlockedIssues: 1, ReadyIssues: 4}},
+		{tasks.BlockedByEither, tasks.Stats{TotalIssues: 6, OpenIssues: 3, InProgressIssues: 2, ClosedIssues: 1, BlockedIssues: 2, ReadyIssues: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.blocked, func(t *testing.T) {
+			cmd := &mockCommander{output: []byte(output)}
+			client := NewClientWithCommander(cmd, "/test")
+			client.SetQueryOptions(tasks.QueryOptions{
+				Command:  []string{"tkx", "query", "--all"},
+				Statuses: statuses,
+				Blocked:  tt.blocked,
+			})
+
+			got, err := client.GetStats(t.Context())
+			if err != nil {
+				t.Fatalf("GetStats() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetStats() = %+v, want %+v", got, tt.want)
+			}
+			if len(cmd.calls) != 1 || cmd.calls[0] != "tkx query --all" {
+				t.Errorf("ran %q, want the configured command", cmd.calls)
+			}
+
+			if _, err := client.GetNextTask(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			if cmd.calls[1] != "tkx ready" {
+				t.Errorf("GetNextTask() ran %q, want tkx ready", cmd.calls[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package tokens
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pricing holds per-million-token prices in USD for a model.
+type Pricing struct {
+	Input      float64 // Uncached input tokens
+	Output     float64 // Output tokens
+	CacheWrite float64 // Cache creation input tokens (5-minute TTL)
+	CacheRead  float64 // Cache read input tokens
+}
+
+// pricingTable maps model ID prefixes to their list prices. Lookups use the
+// longest matching prefix, so "claude-opus-4-5" wins over "claude-opus-4".
+var pricingTable = map[string]Pricing{
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+	"claude-opus-4-1":   {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03},
+}
+
+// GetPricing returns the prices for modelID (e.g. "claude-sonnet-4-5-20250929[1m]").
+// It reports false for models missing from the pricing table.
+func GetPricing(modelID string) (Pricing, bool) {
+	id := strings.ToLower(modelID)
+	var best string
+	for prefix := range pricingTable {
+		if strings.HasPrefix(id, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Pricing{}, false
+	}
+	return pricingTable[best], true
+}
+
+// cost returns the USD cost of one message's usage.
+func (p Pricing) cost(u *usage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheCreationInputTokens)*p.CacheWrite +
+		float64(u.CacheReadInputTokens)*p.CacheRead) / 1_000_000
+}
+
+// FormatCost formats a USD amount: 1.234 -> "$1.23", 0.004 -> "<$0.01".
+func FormatCost(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
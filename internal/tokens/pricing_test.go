@@ -0,0 +1,88 @@
+package tokens
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetPricing(t *testing.T) {
+	tests := []struct {
+		modelID   string
+		wantInput float64
+		wantOK    bool
+	}{
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"claude-opus-4-20250514", 15, true},
+		{"claude-sonnet-4-5-20250929[1m]", 3, true},
+		{"Claude-Haiku-4-5-20251001", 1, true},
+		{"claude-3-5-haiku-20241022", 0.80, true},
+		{"gpt-4o", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.modelID, func(t *testing.T) {
+			p, ok := GetPricing(tt.modelID)
+			if ok != tt.wantOK || p.Input != tt.wantInput {
+				t.Errorf("GetPricing(%q) = %v, %v; want Input %v, %v", tt.modelID, p, ok, tt.wantInput, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	tests := []struct {
+		usd  float64
+		want string
+	}{
+		{0, "$0.00"},
+		{0.004, "<$0.01"},
+		{0.01, "$0.01"},
+		{1.234, "$1.23"},
+		{12.5, "$12.50"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatCost(tt.usd); got != tt.want {
+			t.Errorf("FormatCost(%v) = %q, want %q", tt.usd, got, tt.want)
+		}
+	}
+}
+
+func TestParseTranscript_Cost(t *testing.T) {
+	transcriptPath := filepath.Join(t.TempDir(), "cost.jsonl")
+
+	// Sonnet turn, then a switch to Opus 4.5, then a message from an unpriced model
+	jsonlContent := `{"isSidechain":false,"type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4-5-20250929","usage":{"input_tokens":1000000,"output_tokens":100000,"cache_read_input_tokens":1000000,"cache_creation_input_tokens":0}}}
+{"isSidechain":false,"type":"assistant","message":{"role":"assistant","model":"claude-opus-4-5-20251101","usage":{"input_tokens":0,"output_tokens":0,"cache_read_input_tokens":0,"cache_creation_input_tokens":1000000}}}
+{"isSidechain":false,"type":"assistant","message":{"role":"assistant","model":"<synthetic>","usage":{"input_tokens":1000000,"output_tokens":0}}}
+`
+	if err := os.WriteFile(transcriptPath, []byte(jsonlContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	metrics, err := ParseTranscript(transcriptPath)
+	if err != nil {
+		t.Fatalf("ParseTranscript() error = %v", err)
+	}
+
+	// Sonnet: $3 input + $1.50 output + $0.30 cache read; Opus 4.5: $6.25 cache write
+	want := 3 + 1.5 + 0.3 + 6.25
+	if math.Abs(metrics.CostUSD-want) > 1e-9 {
+		t.Errorf("CostUSD = %v, want %v", metrics.CostUSD, want)
+	}
+
+	// The split by model adds up to the same
+	if got := metrics.Models["claude-sonnet-4-5-20250929"]; got.Tokens != 2_100_000 || math.Abs(got.CostUSD-4.8) > 1e-9 {
+		t.Errorf("Models[sonnet] = %+v, want 2.1M tokens costing $4.80", got)
+	}
+	if got := metrics.Models["claude-opus-4-5-20251101"]; got.Tokens != 1_000_000 || math.Abs(got.CostUSD-6.25) > 1e-9 {
+		t.Errorf("Models[opus] = %+v, want 1M tokens costing $6.25", got)
+	}
+	if got := metrics.Models["<synthetic>"]; got.Tokens != 1_000_000 || got.CostUSD != 0 {
+		t.Errorf("Models[<synthetic>] = %+v, want 1M tokens costing nothing", got)
+	}
+}
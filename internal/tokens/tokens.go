@@ -4,17 +4,31 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // Metrics holds token usage statistics parsed from a transcript.
 type Metrics struct {
-	InputTokens   int64 // Total input tokens used
-	OutputTokens  int64 // Total output tokens generated
-	CachedTokens  int64 // Total cached tokens (read + creation)
-	TotalTokens   int64 // Sum of all tokens
-	ContextLength int64 // Current context window size (last message's input + cache)
+	InputTokens   int64   // Total input tokens used
+	OutputTokens  int64   // Total output tokens generated
+	CachedTokens  int64   // Total cached tokens (read + creation)
+	TotalTokens   int64   // Sum of all tokens
+	ContextLength int64   // Current context window size (last message's input + cache)
+	CostUSD       float64 // Estimated cost at list prices (messages from unpriced models count as 0)
+
+	Models map[string]ModelUsage // Usage by the model ID each message was answered by
+}
+
+// ModelUsage is the part of a transcript's usage answered by one model.
+type ModelUsage struct {
+	Tokens  int64   // Input, output, and cached tokens
+	CostUSD float64 // Estimated cost at list prices; 0 for unpriced models
 }
 
 // ContextConfig holds model-specific context limits.
@@ -48,6 +62,7 @@ type transcriptLine struct {
 // message represents the message field in a transcript line.
 type message struct {
 	Role  string `json:"role"`
+	Model string `json:"model"`
 	Usage *usage `json:"usage"`
 }
 
@@ -62,61 +77,151 @@ type usage struct {
 // ParseTranscript reads a JSONL transcript file and calculates token metrics.
 // It skips sidechain messages (agent messages) and non-assistant messages.
 func ParseTranscript(path string) (Metrics, error) {
+	m, _, err := ParseTranscriptFrom(path, 0, Metrics{})
+	return m, err
+}
+
+// ParseTranscriptFrom continues parsing the transcript at path from byte
+// offset, adding what it finds to prev, the metrics of everything before
+// offset. It returns the updated metrics and the offset to resume from, so
+// repeated calls on a growing transcript only read the appended lines. A
+// transcript shorter than offset was rewritten and is parsed from the start.
+// A final line still being written is left for the next call.
+func ParseTranscriptFrom(path string, offset int64, prev Metrics) (Metrics, int64, error) {
 	if path == "" {
-		return Metrics{}, nil
+		return Metrics{}, 0, nil
 	}
 
 	file, err := os.Open(path)
 	if err != nil {
-		return Metrics{}, err
+		return Metrics{}, 0, err
 	}
 	defer file.Close()
 
-	var m Metrics
-	var lastContextLength int64
-
-	scanner := bufio.NewScanner(file)
-	// Increase buffer size for large lines (some messages can be very long)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	info, err := file.Stat()
+	if err != nil {
+		return Metrics{}, 0, err
+	}
+	if info.Size() < offset {
+		offset, prev = 0, Metrics{}
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return Metrics{}, 0, err
+	}
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+	m := prev
+	m.Models = maps.Clone(prev.Models)
+	// Large buffer since some messages can be very long
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return Metrics{}, 0, err
 		}
-
-		var entry transcriptLine
-		if err := json.Unmarshal(line, &entry); err != nil {
-			// Skip malformed lines
-			continue
+		complete := err == nil
+		if !complete && !json.Valid(line) {
+			// Nothing left, or a line Claude Code is still writing
+			break
 		}
 
-		// Skip sidechain (agent) messages and non-message entries
-		if entry.IsSidechain || entry.Message == nil || entry.Message.Usage == nil {
-			continue
+		m.add(line)
+		offset += int64(len(line))
+		if !complete {
+			break
 		}
+	}
+
+	m.TotalTokens = m.InputTokens + m.OutputTokens + m.CachedTokens
+	return m, offset, nil
+}
+
+// add accumulates the usage recorded on one transcript line.
+func (m *Metrics) add(line []byte) {
+	var entry transcriptLine
+	if err := json.Unmarshal(line, &entry); err != nil {
+		// Skip malformed and empty lines
+		return
+	}
+
+	// Skip sidechain (agent) messages and non-message entries
+	if entry.IsSidechain || entry.Message == nil || entry.Message.Usage == nil {
+		return
+	}
 
-		u := entry.Message.Usage
+	u := entry.Message.Usage
 
-		// Accumulate tokens
-		m.InputTokens += u.InputTokens
-		m.OutputTokens += u.OutputTokens
-		m.CachedTokens += u.CacheReadInputTokens + u.CacheCreationInputTokens
+	// Accumulate tokens
+	m.InputTokens += u.InputTokens
+	m.OutputTokens += u.OutputTokens
+	m.CachedTokens += u.CacheReadInputTokens + u.CacheCreationInputTokens
 
-		// Context length is the input + cached tokens for the most recent message
-		// This represents the current context window size
-		lastContextLength = u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+	// Price each message by its own model, since /model can switch mid-session
+	var cost float64
+	if p, ok := GetPricing(entry.Message.Model); ok {
+		cost = p.cost(u)
+		m.CostUSD += cost
 	}
+	if m.Models == nil {
+		m.Models = make(map[string]ModelUsage)
+	}
+	mu := m.Models[entry.Message.Model]
+	mu.Tokens += u.InputTokens + u.OutputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+	mu.CostUSD += cost
+	m.Models[entry.Message.Model] = mu
+
+	// Context length is the input + cached tokens for the most recent message
+	// This represents the current context window size
+	m.ContextLength = u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+}
+
+// SessionStart returns when the session in the transcript at path started:
+// the timestamp of its first timestamped entry.
+func SessionStart(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
 
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && !entry.Timestamp.IsZero() {
+			return entry.Timestamp, nil
+		}
+	}
 	if err := scanner.Err(); err != nil {
-		return Metrics{}, err
+		return time.Time{}, err
 	}
+	return time.Time{}, fmt.Errorf("%s: no timestamped entries", path)
+}
 
-	m.TotalTokens = m.InputTokens + m.OutputTokens + m.CachedTokens
-	m.ContextLength = lastContextLength
+// SessionWorkDir returns the directory the session in the transcript at path
+// ran in: the cwd of its first entry that records one.
+func SessionWorkDir(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
 
-	return m, nil
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Cwd string `json:"cwd"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Cwd != "" {
+			return entry.Cwd, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s: no entries with a working directory", path)
 }
 
 // ContextPercentage calculates the percentage of max context used.
@@ -163,3 +268,39 @@ func formatWithSuffix(f float64, suffix string) string {
 	s = strings.TrimSuffix(s, ".0")
 	return s + suffix
 }
+
+// nonAlphanumeric matches the characters Claude Code replaces with "-" when
+// naming a project's transcript directory.
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// ProjectDir returns the directory under claudeDir (~/.claude) that Claude
+// Code keeps the transcripts of sessions started in workDir in.
+func ProjectDir(claudeDir, workDir string) string {
+	return filepath.Join(claudeDir, "projects", nonAlphanumeric.ReplaceAllString(workDir, "-"))
+}
+
+// NewestTranscript returns the most recently written transcript of a session
+// started in workDir, or "" if there is none.
+func NewestTranscript(claudeDir, workDir string) string {
+	dir := ProjectDir(claudeDir, workDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	var newest string
+	var newestMod int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if mod := info.ModTime().UnixNano(); newest == "" || mod > newestMod {
+			newest, newestMod = filepath.Join(dir, e.Name()), mod
+		}
+	}
+	return newest
+}
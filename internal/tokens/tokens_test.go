@@ -3,7 +3,9 @@ package tokens
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetContextConfig(t *testing.T) {
@@ -291,3 +293,150 @@ func TestParseTranscript_UserMessagesIgnored(t *testing.T) {
 		t.Errorf("OutputTokens = %d, want 50 (user messages should be ignored)", metrics.OutputTokens)
 	}
 }
+
+func TestParseTranscriptFrom_Incremental(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	first := `{"type":"assistant","message":{"role":"assistant","usage":{"input_tokens":100,"output_tokens":50}}}` + "\n"
+	second := `{"type":"assistant","message":{"role":"assistant","usage":{"input_tokens":200,"output_tokens":25,"cache_read_input_tokens":300}}}`
+	if err := os.WriteFile(path, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, offset, err := ParseTranscriptFrom(path, 0, Metrics{})
+	if err != nil {
+		t.Fatalf("ParseTranscriptFrom() error = %v", err)
+	}
+	if offset != int64(len(first)) || m.InputTokens != 100 {
+		t.Fatalf("ParseTranscriptFrom() = %+v at %d, want 100 input at %d", m, offset, len(first))
+	}
+
+	// A line still being written is left for later
+	appendFile(t, path, second[:40])
+	m, offset, err = ParseTranscriptFrom(path, offset, m)
+	if err != nil {
+		t.Fatalf("ParseTranscriptFrom() error = %v", err)
+	}
+	if offset != int64(len(first)) || m.InputTokens != 100 {
+		t.Errorf("ParseTranscriptFrom() with partial line = %+v at %d, want it unread", m, offset)
+	}
+
+	// Once complete, only the new line is added
+	appendFile(t, path, second[40:]+"\n")
+	m, offset, err = ParseTranscriptFrom(path, offset, m)
+	if err != nil {
+		t.Fatalf("ParseTranscriptFrom() error = %v", err)
+	}
+	want := Metrics{InputTokens: 300, OutputTokens: 75, CachedTokens: 300, TotalTokens: 675, ContextLength: 500,
+		Models: map[string]ModelUsage{"": {Tokens: 675}}}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("ParseTranscriptFrom() = %+v, want %+v", m, want)
+	}
+	if full, _ := ParseTranscript(path); !reflect.DeepEqual(full, m) {
+		t.Errorf("incremental metrics %+v differ from a full parse %+v", m, full)
+	}
+
+	// A rewritten, shorter transcript is parsed from the start
+	if err := os.WriteFile(path, []byte(first), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, offset, err = ParseTranscriptFrom(path, offset, m)
+	if err != nil {
+		t.Fatalf("ParseTranscriptFrom() error = %v", err)
+	}
+	if offset != int64(len(first)) || m.InputTokens != 100 {
+		t.Errorf("ParseTranscriptFrom() after rewrite = %+v at %d, want 100 input at %d", m, offset, len(first))
+	}
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"summary","summary":"Test session"}
+not valid json at all
+{"type":"user","timestamp":"2025-06-01T12:00:00.000Z","message":{"role":"user"}}
+{"type":"assistant","timestamp":"2025-06-01T12:05:00.000Z","message":{"role":"assistant"}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SessionStart(path)
+	if err != nil {
+		t.Fatalf("SessionStart() error = %v", err)
+	}
+	if want := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("SessionStart() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"type":"summary","summary":"Test session"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SessionStart(path); err == nil {
+		t.Error("SessionStart() without timestamps error = nil, want error")
+	}
+}
+
+func TestSessionWorkDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	content := `{"type":"summary","summary":"Test session"}
+{"type":"user","cwd":"/home/me/project","message":{"role":"user"}}
+{"type":"assistant","cwd":"/home/me/project/sub","message":{"role":"assistant"}}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SessionWorkDir(path)
+	if err != nil {
+		t.Fatalf("SessionWorkDir() error = %v", err)
+	}
+	if want := "/home/me/project"; got != want {
+		t.Errorf("SessionWorkDir() = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"type":"summary","summary":"Test session"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SessionWorkDir(path); err == nil {
+		t.Error("SessionWorkDir() without cwd error = nil, want error")
+	}
+}
+
+func TestNewestTranscript(t *testing.T) {
+	claudeDir := t.TempDir()
+	if got := NewestTranscript(claudeDir, "/home/me/my.project"); got != "" {
+		t.Errorf("NewestTranscript() without transcripts = %q, want empty", got)
+	}
+
+	dir := ProjectDir(claudeDir, "/home/me/my.project")
+	if want := filepath.Join(claudeDir, "projects", "-home-me-my-project"); dir != want {
+		t.Errorf("ProjectDir() = %q, want %q", dir, want)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	for name, mod := range map[string]time.Time{"old.jsonl": old, "new.jsonl": time.Now(), "notes.txt": time.Now().Add(time.Hour)} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := NewestTranscript(claudeDir, "/home/me/my.project"), filepath.Join(dir, "new.jsonl"); got != want {
+		t.Errorf("NewestTranscript() = %q, want %q", got, want)
+	}
+}